@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/joho/godotenv/autoload"
+
+	"cinema.log.server.golang/internal/database"
+	"cinema.log.server.golang/internal/films"
+	"cinema.log.server.golang/internal/graph"
+	"cinema.log.server.golang/internal/imports"
+	"cinema.log.server.golang/internal/metadata"
+	"cinema.log.server.golang/internal/ratings"
+	"cinema.log.server.golang/internal/recommender"
+	"cinema.log.server.golang/internal/reviews"
+	"cinema.log.server.golang/internal/utils"
+)
+
+// Imports a Letterboxd export for an existing cinema.log user, e.g.
+//
+//	go run cmd/letterboxd-import/main.go -user <uuid> -ratings ratings.csv -reviews reviews.csv -diary diary.csv
+func main() {
+	userFlag := flag.String("user", "", "id of the cinema.log user to import into")
+	ratingsPath := flag.String("ratings", "", "path to ratings.csv from the letterboxd export")
+	reviewsPath := flag.String("reviews", "", "path to reviews.csv from the letterboxd export")
+	diaryPath := flag.String("diary", "", "path to diary.csv from the letterboxd export")
+	asJSON := flag.Bool("json", false, "print the full import report as json")
+	flag.Parse()
+
+	userId, err := utils.ParseUUID(*userFlag)
+	if err != nil {
+		log.Fatalf("-user must be a valid user id: %v", err)
+	}
+
+	var files imports.LetterboxdFiles
+	paths := []struct {
+		path string
+		dst  *io.Reader
+	}{
+		{*ratingsPath, &files.Ratings},
+		{*reviewsPath, &files.Reviews},
+		{*diaryPath, &files.Diary},
+	}
+	for _, p := range paths {
+		if p.path == "" {
+			continue
+		}
+		file, err := os.Open(p.path)
+		if err != nil {
+			log.Fatalf("could not open %s: %v", p.path, err)
+		}
+		defer file.Close()
+		*p.dst = file
+	}
+
+	db := database.New()
+
+	// Wire up dependencies the same way as the server: Database -> Store -> Service
+	ratingService := ratings.NewService(ratings.NewStore(db))
+	filmStore := films.NewStore(db)
+	graphService := graph.NewService(graph.NewStore(db), filmStore)
+	metadataProvider, err := metadata.NewFromEnv()
+	if err != nil {
+		log.Fatalf("could not set up film metadata: %v", err)
+	}
+	filmService := films.NewService(filmStore, graphService, recommender.NewService(recommender.NewStore(db)), metadataProvider)
+	reviewService := reviews.NewService(reviews.NewStore(db))
+	importService := imports.NewService(filmService, reviewService, ratingService, graphService)
+
+	report, err := importService.ImportLetterboxd(context.Background(), userId, files)
+	if err != nil {
+		log.Fatalf("import failed: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("could not encode report: %v", err)
+		}
+		return
+	}
+
+	for _, result := range report.Results {
+		fmt.Printf("%-9s %s (%s) %v", result.Status, result.Name, result.Year, result.Rows)
+		if result.Message != "" {
+			fmt.Printf(" - %s", result.Message)
+		}
+		fmt.Println()
+		for _, candidate := range result.Candidates {
+			fmt.Printf("          candidate: tmdb %d %s (%s)\n", candidate.ExternalID, candidate.Title, candidate.ReleaseYear)
+		}
+	}
+	fmt.Printf("\nimported %d, skipped %d, ambiguous %d, failed %d\n",
+		report.Imported, report.Skipped, report.Ambiguous, report.Failed)
+}
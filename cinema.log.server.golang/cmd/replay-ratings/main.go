@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/joho/godotenv/autoload"
+
+	"cinema.log.server.golang/internal/database"
+	"cinema.log.server.golang/internal/ratings"
+	"cinema.log.server.golang/internal/users"
+	"cinema.log.server.golang/internal/utils"
+)
+
+// Recomputes ratings from comparison history after a change to the rating maths, e.g.
+//
+//	go run cmd/replay-ratings/main.go -all -dry-run
+func main() {
+	userFlag := flag.String("user", "", "id of the user whose ratings should be replayed")
+	all := flag.Bool("all", false, "replay ratings for every user")
+	dryRun := flag.Bool("dry-run", false, "report how ratings would move without saving anything")
+	asJSON := flag.Bool("json", false, "print the full replay reports as json")
+	flag.Parse()
+
+	if (*userFlag == "") == !*all {
+		log.Fatal("exactly one of -user or -all is required")
+	}
+
+	db := database.New()
+	ctx := context.Background()
+
+	// Wire up dependencies the same way as the server: Database -> Store -> Service
+	ratingService := ratings.NewService(ratings.NewStore(db))
+	userService := users.NewService(users.NewStore(db))
+
+	var userIds []uuid.UUID
+	if *all {
+		allUsers, err := userService.GetAllUsers(ctx)
+		if err != nil {
+			log.Fatalf("could not load users: %v", err)
+		}
+		for _, user := range allUsers {
+			userIds = append(userIds, user.ID)
+		}
+	} else {
+		userId, err := utils.ParseUUID(*userFlag)
+		if err != nil {
+			log.Fatalf("-user must be a valid user id: %v", err)
+		}
+		userIds = append(userIds, userId)
+	}
+
+	var reports []*ratings.ReplayReport
+	for _, userId := range userIds {
+		report, err := ratingService.ReplayRatings(ctx, userId, *dryRun)
+		if err != nil {
+			log.Fatalf("replay failed for user %s: %v", userId, err)
+		}
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			log.Fatalf("could not encode reports: %v", err)
+		}
+		return
+	}
+
+	if *dryRun {
+		fmt.Println("dry run, nothing was saved")
+	}
+	for _, report := range reports {
+		fmt.Printf("\nuser %s (%s): replayed %d comparisons, skipped %d\n",
+			report.UserId, report.Engine, report.ComparisonsReplayed, report.ComparisonsSkipped)
+		for _, film := range report.Films {
+			if film.RatingChange == 0 && film.RankChange == 0 {
+				continue
+			}
+			fmt.Printf("  #%-4d %-40s %6.0f -> %6.0f (%+.0f), rank %d -> %d\n",
+				film.NewRank, film.FilmTitle, film.OldRating, film.NewRating, film.RatingChange, film.OldRank, film.NewRank)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/joho/godotenv/autoload"
+
+	"cinema.log.server.golang/internal/archive"
+	"cinema.log.server.golang/internal/database"
+	"cinema.log.server.golang/internal/films"
+	"cinema.log.server.golang/internal/graph"
+	"cinema.log.server.golang/internal/metadata"
+	"cinema.log.server.golang/internal/ratings"
+	"cinema.log.server.golang/internal/recommender"
+	"cinema.log.server.golang/internal/reviews"
+	"cinema.log.server.golang/internal/users"
+	"cinema.log.server.golang/internal/utils"
+)
+
+// Restores a cinema.log export archive, e.g.
+//
+//	go run cmd/restore-archive/main.go -file cinema-log-export-2025-03-01.zip -dry-run
+//
+// Without -user the archive is restored into the user it was exported from, recreating them if needed.
+func main() {
+	filePath := flag.String("file", "", "path to the export archive")
+	userFlag := flag.String("user", "", "id of an existing user to restore into instead of the archive's own user")
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing anything")
+	asJSON := flag.Bool("json", false, "print the full restore report as json")
+	flag.Parse()
+
+	if *filePath == "" {
+		log.Fatal("-file is required")
+	}
+
+	opts := archive.RestoreOptions{DryRun: *dryRun}
+	if *userFlag != "" {
+		userId, err := utils.ParseUUID(*userFlag)
+		if err != nil {
+			log.Fatalf("-user must be a valid user id: %v", err)
+		}
+		opts.UserID = userId
+	}
+
+	file, err := os.Open(*filePath)
+	if err != nil {
+		log.Fatalf("could not open %s: %v", *filePath, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		log.Fatalf("could not read %s: %v", *filePath, err)
+	}
+
+	db := database.New()
+
+	// Wire up dependencies the same way as the server: Database -> Store -> Service
+	ratingService := ratings.NewService(ratings.NewStore(db))
+	filmStore := films.NewStore(db)
+	graphService := graph.NewService(graph.NewStore(db), filmStore)
+	metadataProvider, err := metadata.NewFromEnv()
+	if err != nil {
+		log.Fatalf("could not set up film metadata: %v", err)
+	}
+	filmService := films.NewService(filmStore, graphService, recommender.NewService(recommender.NewStore(db)), metadataProvider)
+	reviewService := reviews.NewService(reviews.NewStore(db))
+	userService := users.NewService(users.NewStore(db))
+	archiveService := archive.NewService(archive.NewStore(db), userService, filmService, reviewService, ratingService, graphService)
+
+	report, err := archiveService.RestoreUser(context.Background(), file, info.Size(), opts)
+	if err != nil {
+		log.Fatalf("restore failed: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("could not encode report: %v", err)
+		}
+		return
+	}
+
+	if report.DryRun {
+		fmt.Println("dry run, nothing was written")
+	}
+	fmt.Printf("user %s", report.UserID)
+	if report.UserCreated {
+		fmt.Print(" (created)")
+	}
+	fmt.Println()
+
+	sections := []struct {
+		name  string
+		count archive.SectionCount
+	}{
+		{"films", report.Films},
+		{"reviews", report.Reviews},
+		{"ratings", report.Ratings},
+		{"comparisons", report.Comparisons},
+		{"recommendations", report.Recommendations},
+		{"graph nodes", report.GraphNodes},
+		{"graph edges", report.GraphEdges},
+	}
+	for _, s := range sections {
+		fmt.Printf("%-16s created %d (remapped %d), existing %d, skipped %d\n",
+			s.name, s.count.Created, s.count.Remapped, s.count.Existing, s.count.Skipped)
+	}
+	for _, warning := range report.Warnings {
+		fmt.Printf("warning: %s\n", warning)
+	}
+}
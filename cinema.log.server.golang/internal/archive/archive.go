@@ -0,0 +1,254 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+// SchemaVersion is bumped whenever the layout or fields of the archive change,
+// so an import can tell which version of the format it has been given
+const SchemaVersion = 3
+
+const ManifestFileName = "manifest.json"
+
+// Sections of the archive, each one is written as <section>.json and <section>.csv
+const (
+	SectionUser            = "user"
+	SectionFilms           = "films"
+	SectionReviews         = "reviews"
+	SectionRatings         = "ratings"
+	SectionComparisons     = "comparisons"
+	SectionRecommendations = "recommendations"
+	SectionReasons         = "recommendation_reasons"
+	SectionGraphNodes      = "graph_nodes"
+	SectionGraphEdges      = "graph_edges"
+)
+
+// Sections that were added after the first version of the archive, older archives don't have them
+var sectionSince = map[string]int{
+	SectionReasons: 3,
+}
+
+// Archive is everything cinema.log holds for a single user
+type Archive struct {
+	Manifest        Manifest                            `json:"manifest"`
+	User            domain.User                         `json:"user"`
+	Films           []domain.Film                       `json:"films"`
+	Reviews         []domain.Review                     `json:"reviews"`
+	Ratings         []domain.UserFilmRatingDetail       `json:"ratings"`
+	Comparisons     []domain.ComparisonHistory          `json:"comparisons"`
+	Recommendations []domain.FilmRecommendation         `json:"recommendations"`
+	Reasons         []domain.StoredRecommendationReason `json:"recommendationReasons"`
+	GraphNodes      []domain.FilmGraphNode              `json:"graphNodes"`
+	GraphEdges      []domain.FilmGraphEdge              `json:"graphEdges"`
+}
+
+type Manifest struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	ExportedAt    time.Time      `json:"exportedAt"`
+	UserID        uuid.UUID      `json:"userId"`
+	Files         []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Name    string `json:"name"`
+	Section string `json:"section"`
+	Format  string `json:"format"`
+	Records int    `json:"records"`
+}
+
+type section struct {
+	name    string
+	records int
+	data    any
+	header  []string
+	rows    [][]string
+}
+
+// WriteArchive writes the archive as a zip containing a manifest and a json and csv file per section.
+// The json files are the source of truth for imports, the csv files are there for spreadsheets.
+func WriteArchive(w io.Writer, archive *Archive) error {
+	sections := archive.sections()
+
+	manifest := Manifest{
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    archive.Manifest.ExportedAt,
+		UserID:        archive.User.ID,
+		Files:         make([]ManifestFile, 0, len(sections)*2),
+	}
+	for _, s := range sections {
+		manifest.Files = append(manifest.Files,
+			ManifestFile{Name: s.name + ".json", Section: s.name, Format: "json", Records: s.records},
+			ManifestFile{Name: s.name + ".csv", Section: s.name, Format: "csv", Records: s.records},
+		)
+	}
+	archive.Manifest = manifest
+
+	zw := zip.NewWriter(w)
+
+	if err := writeJSON(zw, ManifestFileName, manifest); err != nil {
+		return err
+	}
+
+	for _, s := range sections {
+		if err := writeJSON(zw, s.name+".json", s.data); err != nil {
+			return err
+		}
+		if err := writeCSV(zw, s.name+".csv", s.header, s.rows); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func writeCSV(zw *zip.Writer, name string, header []string, rows [][]string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	cw := csv.NewWriter(f)
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (a *Archive) sections() []section {
+	user := a.User
+	userRow := []string{user.ID.String(), formatInt64Ptr(user.GithubId), formatStringPtr(user.GoogleId),
+		user.Name, user.Username, user.ProfilePicURL, formatTime(user.CreatedAt), formatTime(user.UpdatedAt)}
+
+	films := make([][]string, 0, len(a.Films))
+	for _, f := range a.Films {
+		films = append(films, []string{f.ID.String(), strconv.Itoa(f.ExternalID), f.Title, f.Description, f.PosterUrl, f.ReleaseYear})
+	}
+
+	reviews := make([][]string, 0, len(a.Reviews))
+	for _, r := range a.Reviews {
+		reviews = append(reviews, []string{r.ID.String(), r.FilmId.String(), formatTime(r.Date), formatFloat(float64(r.Rating)), r.Content})
+	}
+
+	ratings := make([][]string, 0, len(a.Ratings))
+	for i, r := range a.Ratings {
+		ratings = append(ratings, []string{strconv.Itoa(i + 1), r.Rating.ID.String(), r.Rating.FilmId.String(), r.FilmTitle, r.FilmReleaseYear,
+			formatFloat(r.Rating.EloRating), strconv.Itoa(r.Rating.NumberOfComparisons), formatFloat(float64(r.Rating.InitialRating)),
+			formatFloat(r.Rating.KConstantValue), formatTime(r.Rating.LastUpdated),
+			formatFloat(r.Rating.RatingDeviation), formatFloat(r.Rating.Volatility)})
+	}
+
+	comparisons := make([][]string, 0, len(a.Comparisons))
+	for _, c := range a.Comparisons {
+		comparisons = append(comparisons, []string{c.ID.String(), c.FilmAId.String(), c.FilmBId.String(), c.WinningFilmId.String(),
+			strconv.FormatBool(c.WasEqual), formatTime(c.ComparisonDate)})
+	}
+
+	recommendations := make([][]string, 0, len(a.Recommendations))
+	for _, r := range a.Recommendations {
+		recommendations = append(recommendations, []string{r.ID.String(), strconv.Itoa(r.ExternalFilmID), strconv.FormatBool(r.HasSeen),
+			strconv.FormatBool(r.HasBeenRecommended), strconv.FormatBool(r.RecommendationsGenerated),
+			formatTimePtr(r.RecommendedAt), formatTimePtr(r.DismissedAt), formatTimePtr(r.SnoozedUntil)})
+	}
+
+	reasons := make([][]string, 0, len(a.Reasons))
+	for _, r := range a.Reasons {
+		basedOn := make([]string, 0, len(r.BasedOn))
+		for _, seed := range r.BasedOn {
+			basedOn = append(basedOn, seed.FilmID.String())
+		}
+		reasons = append(reasons, []string{r.ID.String(), strconv.Itoa(r.ExternalFilmID), r.Source, r.Detail, formatFloat(r.Score),
+			strconv.Itoa(r.SeedsAgreed), formatTime(r.RecommendedAt), strings.Join(basedOn, " ")})
+	}
+
+	nodes := make([][]string, 0, len(a.GraphNodes))
+	for _, n := range a.GraphNodes {
+		nodes = append(nodes, []string{strconv.Itoa(n.ExternalFilmID), n.Title})
+	}
+
+	edges := make([][]string, 0, len(a.GraphEdges))
+	for _, e := range a.GraphEdges {
+		edges = append(edges, []string{e.EdgeId.String(), strconv.Itoa(e.FromFilmID), strconv.Itoa(e.ToFilmID)})
+	}
+
+	return []section{
+		{SectionUser, 1, a.User,
+			[]string{"user_id", "github_id", "google_id", "name", "username", "profile_pic_url", "created_at", "updated_at"},
+			[][]string{userRow}},
+		{SectionFilms, len(a.Films), a.Films,
+			[]string{"film_id", "external_id", "title", "description", "poster_url", "release_year"}, films},
+		{SectionReviews, len(a.Reviews), a.Reviews,
+			[]string{"review_id", "film_id", "date", "rating", "content"}, reviews},
+		{SectionRatings, len(a.Ratings), a.Ratings,
+			[]string{"rank", "user_film_rating_id", "film_id", "title", "release_year", "elo_rating", "number_of_comparisons",
+				"initial_rating", "k_constant_value", "last_updated", "rating_deviation", "volatility"}, ratings},
+		{SectionComparisons, len(a.Comparisons), a.Comparisons,
+			[]string{"comparison_history_id", "film_a_id", "film_b_id", "winning_film_id", "was_equal", "comparison_date"}, comparisons},
+		{SectionRecommendations, len(a.Recommendations), a.Recommendations,
+			[]string{"film_recommendation_id", "external_film_id", "has_seen", "has_been_recommended", "recommendations_generated",
+				"recommended_at", "dismissed_at", "snoozed_until"}, recommendations},
+		{SectionReasons, len(a.Reasons), a.Reasons,
+			[]string{"recommendation_reason_id", "external_film_id", "source", "detail", "score", "seeds_agreed", "recommended_at",
+				"based_on_film_ids"}, reasons},
+		{SectionGraphNodes, len(a.GraphNodes), a.GraphNodes,
+			[]string{"external_film_id", "title"}, nodes},
+		{SectionGraphEdges, len(a.GraphEdges), a.GraphEdges,
+			[]string{"edge_id", "from_film_id", "to_film_id"}, edges},
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatInt64Ptr(i *int64) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.FormatInt(*i, 10)
+}
+
+func formatStringPtr(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
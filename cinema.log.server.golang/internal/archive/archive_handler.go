@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Exports are mostly json, even a large library with its comparison history is a few MB
+const maxArchiveSize = 50 << 20
+
+type Handler struct {
+	ArchiveService ArchiveService
+}
+
+type ArchiveService interface {
+	ExportUser(ctx context.Context, userId uuid.UUID) (*Archive, error)
+	RestoreUser(ctx context.Context, r io.ReaderAt, size int64, opts RestoreOptions) (*RestoreReport, error)
+}
+
+func NewHandler(archiveService ArchiveService) *Handler {
+	return &Handler{
+		ArchiveService: archiveService,
+	}
+}
+
+// ExportUserData streams a zip of everything held for the authenticated user
+func (h *Handler) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	archive, err := h.ArchiveService.ExportUser(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to export user data", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("cinema-log-export-%s.zip", archive.Manifest.ExportedAt.Format(time.DateOnly))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// Headers have been sent by the time the zip fails, so all we can do is log and cut the response short
+	if err := WriteArchive(w, archive); err != nil {
+		log.Printf("failed to write export for user %s: %v", user.ID, err)
+	}
+}
+
+// RestoreUserData restores an export archive, sent as the "archive" file of a multipart form, into the
+// authenticated user. With ?dryRun=true nothing is written and the report shows what would have changed.
+func (h *Handler) RestoreUserData(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveSize)
+	if err := r.ParseMultipartForm(maxArchiveSize); err != nil {
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		http.Error(w, "archive file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	opts := RestoreOptions{
+		UserID: user.ID,
+		DryRun: r.URL.Query().Get("dryRun") == "true",
+	}
+
+	report, err := h.ArchiveService.RestoreUser(r.Context(), file, header.Size, opts)
+	if err != nil {
+		if errors.Is(err, ErrInvalidArchive) || errors.Is(err, ErrUnsupportedSchemaVersion) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to restore user data", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, report)
+}
@@ -0,0 +1,202 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"github.com/google/uuid"
+)
+
+type mockArchiveService struct {
+	exportUserFunc  func(ctx context.Context, userId uuid.UUID) (*Archive, error)
+	restoreUserFunc func(ctx context.Context, r io.ReaderAt, size int64, opts RestoreOptions) (*RestoreReport, error)
+}
+
+func (m *mockArchiveService) ExportUser(ctx context.Context, userId uuid.UUID) (*Archive, error) {
+	if m.exportUserFunc != nil {
+		return m.exportUserFunc(ctx, userId)
+	}
+	return newTestArchive(), nil
+}
+
+func (m *mockArchiveService) RestoreUser(ctx context.Context, r io.ReaderAt, size int64, opts RestoreOptions) (*RestoreReport, error) {
+	if m.restoreUserFunc != nil {
+		return m.restoreUserFunc(ctx, r, size, opts)
+	}
+	return &RestoreReport{}, nil
+}
+
+func newRestoreRequest(t *testing.T, target string, archive []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if archive != nil {
+		part, err := writer.CreateFormFile("archive", "export.zip")
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		part.Write(archive)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, target, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func withUser(req *http.Request, user *domain.User) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+}
+
+func TestHandler_ExportUserData_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockArchiveService{})
+	req := httptest.NewRequest(http.MethodGet, "/users/me/export", nil)
+	w := httptest.NewRecorder()
+
+	handler.ExportUserData(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_ExportUserData_Success(t *testing.T) {
+	user := &domain.User{ID: uuid.New()}
+	handler := NewHandler(&mockArchiveService{
+		exportUserFunc: func(ctx context.Context, userId uuid.UUID) (*Archive, error) {
+			if userId != user.ID {
+				t.Errorf("expected user %v, got %v", user.ID, userId)
+			}
+			return newTestArchive(), nil
+		},
+	})
+	req := withUser(httptest.NewRequest(http.MethodGet, "/users/me/export", nil), user)
+	w := httptest.NewRecorder()
+
+	handler.ExportUserData(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Header().Get("Content-Type") != "application/zip" {
+		t.Errorf("expected application/zip, got %s", w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Content-Disposition") != `attachment; filename="cinema-log-export-2025-03-01.zip"` {
+		t.Errorf("unexpected Content-Disposition %s", w.Header().Get("Content-Disposition"))
+	}
+
+	files := readZip(t, w.Body.Bytes())
+	if _, ok := files[ManifestFileName]; !ok {
+		t.Error("expected manifest in response zip")
+	}
+}
+
+func TestHandler_ExportUserData_ServiceError(t *testing.T) {
+	handler := NewHandler(&mockArchiveService{
+		exportUserFunc: func(ctx context.Context, userId uuid.UUID) (*Archive, error) {
+			return nil, errors.New("database down")
+		},
+	})
+	req := withUser(httptest.NewRequest(http.MethodGet, "/users/me/export", nil), &domain.User{ID: uuid.New()})
+	w := httptest.NewRecorder()
+
+	handler.ExportUserData(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestHandler_RestoreUserData_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockArchiveService{})
+	req := newRestoreRequest(t, "/users/me/import", []byte("zip"))
+	w := httptest.NewRecorder()
+
+	handler.RestoreUserData(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_RestoreUserData_MissingArchive(t *testing.T) {
+	handler := NewHandler(&mockArchiveService{})
+	req := withUser(newRestoreRequest(t, "/users/me/import", nil), &domain.User{ID: uuid.New()})
+	w := httptest.NewRecorder()
+
+	handler.RestoreUserData(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHandler_RestoreUserData_DryRun(t *testing.T) {
+	user := &domain.User{ID: uuid.New()}
+	handler := NewHandler(&mockArchiveService{
+		restoreUserFunc: func(ctx context.Context, r io.ReaderAt, size int64, opts RestoreOptions) (*RestoreReport, error) {
+			if opts.UserID != user.ID {
+				t.Errorf("expected restore into %v, got %v", user.ID, opts.UserID)
+			}
+			if !opts.DryRun {
+				t.Error("expected dry run")
+			}
+			if size != 3 {
+				t.Errorf("expected archive size 3, got %d", size)
+			}
+			return &RestoreReport{DryRun: true, UserID: user.ID, Films: SectionCount{Created: 2}}, nil
+		},
+	})
+	req := withUser(newRestoreRequest(t, "/users/me/import?dryRun=true", []byte("zip")), user)
+	w := httptest.NewRecorder()
+
+	handler.RestoreUserData(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var report RestoreReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !report.DryRun || report.Films.Created != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestHandler_RestoreUserData_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{ErrInvalidArchive, http.StatusBadRequest},
+		{ErrUnsupportedSchemaVersion, http.StatusBadRequest},
+		{errors.New("database down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			handler := NewHandler(&mockArchiveService{
+				restoreUserFunc: func(ctx context.Context, r io.ReaderAt, size int64, opts RestoreOptions) (*RestoreReport, error) {
+					return nil, tt.err
+				},
+			})
+			req := withUser(newRestoreRequest(t, "/users/me/import", []byte("zip")), &domain.User{ID: uuid.New()})
+			w := httptest.NewRecorder()
+
+			handler.RestoreUserData(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/films"
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	Store         Store
+	UserService   UserService
+	FilmService   FilmService
+	ReviewService ReviewService
+	RatingService RatingService
+	GraphService  GraphService
+}
+
+type Store interface {
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+	GetRestoreState(ctx context.Context, tx *sql.Tx, archive *Archive, userId uuid.UUID) (*RestoreState, error)
+	ApplyRestore(ctx context.Context, tx *sql.Tx, plan *RestorePlan) error
+}
+
+type UserService interface {
+	GetUserById(ctx context.Context, id uuid.UUID) (*domain.User, error)
+}
+
+type FilmService interface {
+	GetFilmsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.Film, error)
+	GetFilmRecommendationsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.FilmRecommendation, error)
+	GetRecommendationReasonsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.StoredRecommendationReason, error)
+	CreateFilmFromProvider(ctx context.Context, externalId int) (*domain.Film, error)
+}
+
+type ReviewService interface {
+	GetAllReviewsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.Review, error)
+}
+
+type RatingService interface {
+	GetRatingsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error)
+	GetComparisonHistory(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error)
+}
+
+type GraphService interface {
+	GetUserGraph(ctx context.Context, userID uuid.UUID) ([]domain.FilmGraphNode, []domain.FilmGraphEdge, error)
+}
+
+func NewService(store Store, userService UserService, filmService FilmService, reviewService ReviewService,
+	ratingService RatingService, graphService GraphService) *Service {
+	return &Service{
+		Store:         store,
+		UserService:   userService,
+		FilmService:   filmService,
+		ReviewService: reviewService,
+		RatingService: ratingService,
+		GraphService:  graphService,
+	}
+}
+
+// ExportUser gathers everything held for a user into an Archive ready to be written out.
+// Everything is loaded up front so a failure can still be reported before any of the zip is sent.
+func (s *Service) ExportUser(ctx context.Context, userId uuid.UUID) (*Archive, error) {
+	user, err := s.UserService.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	films, err := s.FilmService.GetFilmsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := s.ReviewService.GetAllReviewsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings, err := s.RatingService.GetRatingsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	comparisons, err := s.RatingService.GetComparisonHistory(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations, err := s.FilmService.GetFilmRecommendationsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons, err := s.FilmService.GetRecommendationReasonsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, edges, err := s.GraphService.GetUserGraph(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{
+		Manifest: Manifest{
+			SchemaVersion: SchemaVersion,
+			ExportedAt:    time.Now().UTC(),
+			UserID:        user.ID,
+		},
+		User:            *user,
+		Films:           nonNil(films),
+		Reviews:         nonNil(reviews),
+		Ratings:         nonNil(ratings),
+		Comparisons:     nonNil(comparisons),
+		Recommendations: nonNil(recommendations),
+		Reasons:         nonNil(reasons),
+		GraphNodes:      nonNil(nodes),
+		GraphEdges:      nonNil(edges),
+	}, nil
+}
+
+// RestoreUser rebuilds an account from an export archive in a single transaction.
+// Without a target user in the options the archive is restored into the user it was exported from,
+// creating them if they no longer exist.
+func (s *Service) RestoreUser(ctx context.Context, r io.ReaderAt, size int64, opts RestoreOptions) (*RestoreReport, error) {
+	archive, err := ReadArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	userId := opts.UserID
+	if userId == uuid.Nil {
+		userId = archive.User.ID
+	}
+
+	tx, err := s.Store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	state, err := s.Store.GetRestoreState(ctx, tx, archive, userId)
+	if err != nil {
+		return nil, err
+	}
+	// Only the user the archive belongs to can be recreated, anyone else has to exist already
+	if !state.UserExists && userId != archive.User.ID {
+		return nil, ErrRestoreUserNotFound
+	}
+
+	if !opts.DryRun {
+		if err := s.resolveFilms(ctx, archive, state); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := planRestore(archive, userId, state)
+	plan.Report.DryRun = opts.DryRun
+	if opts.DryRun {
+		return &plan.Report, nil
+	}
+
+	if err := s.Store.ApplyRestore(ctx, tx, plan); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &plan.Report, nil
+}
+
+// resolveFilms creates the archive's films that don't exist yet from the metadata provider, looked up by their
+// TMDB id, so the shared films table only ever holds what the provider says about a film. Films the provider
+// doesn't know are marked so the restore skips them.
+func (s *Service) resolveFilms(ctx context.Context, archive *Archive, state *RestoreState) error {
+	state.ResolvedFilms = make(map[int]bool)
+	state.UnknownFilms = make(map[int]bool)
+	for _, film := range archive.Films {
+		if _, ok := state.FilmsByExternalId[film.ExternalID]; ok || state.UnknownFilms[film.ExternalID] {
+			continue
+		}
+
+		created, err := s.FilmService.CreateFilmFromProvider(ctx, film.ExternalID)
+		if err == films.ErrFilmNotFound {
+			state.UnknownFilms[film.ExternalID] = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		state.FilmsByExternalId[film.ExternalID] = created.ID
+		state.ResolvedFilms[film.ExternalID] = true
+	}
+	return nil
+}
+
+// nonNil makes sure empty sections are written as [] rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/films"
+	"github.com/google/uuid"
+)
+
+type mockUserService struct {
+	getUserByIdFunc func(ctx context.Context, id uuid.UUID) (*domain.User, error)
+}
+
+func (m *mockUserService) GetUserById(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	if m.getUserByIdFunc != nil {
+		return m.getUserByIdFunc(ctx, id)
+	}
+	return &domain.User{ID: id}, nil
+}
+
+type mockFilmService struct {
+	getFilmsByUserIdFunc               func(ctx context.Context, userId uuid.UUID) ([]domain.Film, error)
+	getFilmRecommendationsByUserIdFunc func(ctx context.Context, userId uuid.UUID) ([]domain.FilmRecommendation, error)
+	getRecommendationReasonsFunc       func(ctx context.Context, userId uuid.UUID) ([]domain.StoredRecommendationReason, error)
+	createFilmFromProviderFunc         func(ctx context.Context, externalId int) (*domain.Film, error)
+}
+
+func (m *mockFilmService) GetFilmsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.Film, error) {
+	if m.getFilmsByUserIdFunc != nil {
+		return m.getFilmsByUserIdFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+func (m *mockFilmService) GetFilmRecommendationsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.FilmRecommendation, error) {
+	if m.getFilmRecommendationsByUserIdFunc != nil {
+		return m.getFilmRecommendationsByUserIdFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+func (m *mockFilmService) GetRecommendationReasonsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.StoredRecommendationReason, error) {
+	if m.getRecommendationReasonsFunc != nil {
+		return m.getRecommendationReasonsFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+func (m *mockFilmService) CreateFilmFromProvider(ctx context.Context, externalId int) (*domain.Film, error) {
+	if m.createFilmFromProviderFunc != nil {
+		return m.createFilmFromProviderFunc(ctx, externalId)
+	}
+	return &domain.Film{ID: uuid.New(), ExternalID: externalId}, nil
+}
+
+type mockReviewService struct {
+	getAllReviewsByUserIdFunc func(ctx context.Context, userId uuid.UUID) ([]domain.Review, error)
+}
+
+func (m *mockReviewService) GetAllReviewsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.Review, error) {
+	if m.getAllReviewsByUserIdFunc != nil {
+		return m.getAllReviewsByUserIdFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+type mockRatingService struct {
+	getRatingsByUserIdFunc   func(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error)
+	getComparisonHistoryFunc func(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error)
+}
+
+func (m *mockRatingService) GetRatingsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
+	if m.getRatingsByUserIdFunc != nil {
+		return m.getRatingsByUserIdFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+func (m *mockRatingService) GetComparisonHistory(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error) {
+	if m.getComparisonHistoryFunc != nil {
+		return m.getComparisonHistoryFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+type mockGraphService struct {
+	getUserGraphFunc func(ctx context.Context, userID uuid.UUID) ([]domain.FilmGraphNode, []domain.FilmGraphEdge, error)
+}
+
+func (m *mockGraphService) GetUserGraph(ctx context.Context, userID uuid.UUID) ([]domain.FilmGraphNode, []domain.FilmGraphEdge, error) {
+	if m.getUserGraphFunc != nil {
+		return m.getUserGraphFunc(ctx, userID)
+	}
+	return nil, nil, nil
+}
+
+func TestService_ExportUser(t *testing.T) {
+	userId := uuid.New()
+	film := domain.Film{ID: uuid.New(), ExternalID: 949, Title: "Heat"}
+	service := NewService(nil, &mockUserService{}, &mockFilmService{
+		getFilmsByUserIdFunc: func(ctx context.Context, id uuid.UUID) ([]domain.Film, error) {
+			return []domain.Film{film}, nil
+		},
+	}, &mockReviewService{}, &mockRatingService{}, &mockGraphService{})
+
+	archive, err := service.ExportUser(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if archive.User.ID != userId || archive.Manifest.UserID != userId {
+		t.Errorf("expected archive for user %v, got %v", userId, archive.User.ID)
+	}
+	if archive.Manifest.SchemaVersion != SchemaVersion {
+		t.Errorf("expected schema version %d, got %d", SchemaVersion, archive.Manifest.SchemaVersion)
+	}
+	if len(archive.Films) != 1 || archive.Films[0].ID != film.ID {
+		t.Errorf("unexpected films %+v", archive.Films)
+	}
+	if archive.Reviews == nil || archive.Comparisons == nil || archive.Reasons == nil || archive.GraphEdges == nil {
+		t.Error("expected empty sections to be non-nil")
+	}
+}
+
+func TestService_ExportUser_Error(t *testing.T) {
+	service := NewService(nil, &mockUserService{}, &mockFilmService{}, &mockReviewService{}, &mockRatingService{
+		getComparisonHistoryFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error) {
+			return nil, errors.New("database down")
+		},
+	}, &mockGraphService{})
+
+	if _, err := service.ExportUser(context.Background(), uuid.New()); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestService_RestoreUser_InvalidArchive(t *testing.T) {
+	service := NewService(nil, &mockUserService{}, &mockFilmService{}, &mockReviewService{}, &mockRatingService{}, &mockGraphService{})
+
+	_, err := service.RestoreUser(context.Background(), bytes.NewReader([]byte("not a zip")), 9, RestoreOptions{})
+	if !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("expected ErrInvalidArchive, got %v", err)
+	}
+}
+
+func TestService_ResolveFilms(t *testing.T) {
+	archive := newTestArchive()
+	unknown := domain.Film{ID: uuid.New(), ExternalID: 1, Title: "Not on TMDB"}
+	existing := domain.Film{ID: uuid.New(), ExternalID: 2, Title: "Already stored"}
+	archive.Films = append(archive.Films, unknown, existing)
+	providerFilm := domain.Film{ID: uuid.New(), ExternalID: 949, Title: "Heat"}
+
+	var lookedUp []int
+	service := NewService(nil, &mockUserService{}, &mockFilmService{
+		createFilmFromProviderFunc: func(ctx context.Context, externalId int) (*domain.Film, error) {
+			lookedUp = append(lookedUp, externalId)
+			if externalId == unknown.ExternalID {
+				return nil, films.ErrFilmNotFound
+			}
+			return &providerFilm, nil
+		},
+	}, &mockReviewService{}, &mockRatingService{}, &mockGraphService{})
+
+	state := emptyRestoreState()
+	state.FilmsByExternalId[existing.ExternalID] = existing.ID
+	if err := service.resolveFilms(context.Background(), archive, state); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(lookedUp) != 2 {
+		t.Errorf("expected only the films that don't exist to be looked up, got %v", lookedUp)
+	}
+	if state.FilmsByExternalId[949] != providerFilm.ID || !state.ResolvedFilms[949] || !state.UnknownFilms[unknown.ExternalID] {
+		t.Errorf("unexpected state after resolving %+v", state)
+	}
+
+	// The provider being down fails the restore rather than dropping the films
+	errProvider := errors.New("tmdb is down")
+	service.FilmService = &mockFilmService{
+		createFilmFromProviderFunc: func(ctx context.Context, externalId int) (*domain.Film, error) {
+			return nil, errProvider
+		},
+	}
+	if err := service.resolveFilms(context.Background(), archive, emptyRestoreState()); err != errProvider {
+		t.Errorf("expected %v, got %v", errProvider, err)
+	}
+}
@@ -0,0 +1,378 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) Store {
+	return &store{
+		db: db,
+	}
+}
+
+// BeginTx starts a new transaction
+func (s *store) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return s.db.BeginTx(ctx, nil)
+}
+
+// GetRestoreState loads everything already in the database that a restore of the archive into userId could clash with
+func (s *store) GetRestoreState(ctx context.Context, tx *sql.Tx, archive *Archive, userId uuid.UUID) (*RestoreState, error) {
+	state := &RestoreState{
+		TakenIds: make(map[string]map[uuid.UUID]bool),
+	}
+
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE user_id = $1)`, userId).Scan(&state.UserExists)
+	if err != nil {
+		return nil, err
+	}
+	if archive.User.GithubId != nil {
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM user_identities WHERE provider = 'github' AND subject = $1 AND user_id <> $2)`,
+			strconv.FormatInt(*archive.User.GithubId, 10), userId).Scan(&state.GithubIdTaken)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if archive.User.GoogleId != nil {
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM user_identities WHERE provider = 'google' AND subject = $1 AND user_id <> $2)`,
+			*archive.User.GoogleId, userId).Scan(&state.GoogleIdTaken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Films are shared between users so they are matched on their TMDB id
+	var externalIds []int64
+	for _, film := range archive.Films {
+		externalIds = append(externalIds, int64(film.ExternalID))
+	}
+	for _, recommendation := range archive.Recommendations {
+		externalIds = append(externalIds, int64(recommendation.ExternalFilmID))
+	}
+	for _, reason := range archive.Reasons {
+		externalIds = append(externalIds, int64(reason.ExternalFilmID))
+	}
+	for _, node := range archive.GraphNodes {
+		externalIds = append(externalIds, int64(node.ExternalFilmID))
+	}
+	state.FilmsByExternalId = make(map[int]uuid.UUID)
+	err = queryEach(ctx, tx, `SELECT external_id, film_id FROM films WHERE external_id = ANY($1)`, []any{externalIds}, func(rows *sql.Rows) error {
+		var externalId int
+		var filmId uuid.UUID
+		if err := rows.Scan(&externalId, &filmId); err != nil {
+			return err
+		}
+		state.FilmsByExternalId[externalId] = filmId
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Find which of the archive's ids are already taken
+	var reviewIds, ratingIds, comparisonIds, recommendationIds, reasonIds, edgeIds []uuid.UUID
+	for _, review := range archive.Reviews {
+		reviewIds = append(reviewIds, review.ID)
+	}
+	for _, rating := range archive.Ratings {
+		ratingIds = append(ratingIds, rating.Rating.ID)
+	}
+	for _, comparison := range archive.Comparisons {
+		comparisonIds = append(comparisonIds, comparison.ID)
+	}
+	for _, recommendation := range archive.Recommendations {
+		recommendationIds = append(recommendationIds, recommendation.ID)
+	}
+	for _, reason := range archive.Reasons {
+		reasonIds = append(reasonIds, reason.ID)
+	}
+	for _, edge := range archive.GraphEdges {
+		edgeIds = append(edgeIds, edge.EdgeId)
+	}
+
+	takenIdQueries := []struct {
+		section string
+		query   string
+		ids     []uuid.UUID
+	}{
+		{SectionReviews, `SELECT review_id FROM reviews WHERE review_id = ANY($1)`, reviewIds},
+		{SectionRatings, `SELECT user_film_rating_id FROM user_film_ratings WHERE user_film_rating_id = ANY($1)`, ratingIds},
+		{SectionComparisons, `SELECT comparison_history_id FROM comparison_histories WHERE comparison_history_id = ANY($1)`, comparisonIds},
+		{SectionRecommendations, `SELECT film_recommendation_id FROM film_recommendation WHERE film_recommendation_id = ANY($1)`, recommendationIds},
+		{SectionReasons, `SELECT recommendation_reason_id FROM recommendation_reasons WHERE recommendation_reason_id = ANY($1)`, reasonIds},
+		{SectionGraphEdges, `SELECT edge_id FROM film_graph_edges WHERE edge_id = ANY($1)`, edgeIds},
+	}
+	for _, q := range takenIdQueries {
+		taken := make(map[uuid.UUID]bool)
+		if len(q.ids) > 0 {
+			err := queryEach(ctx, tx, q.query, []any{q.ids}, func(rows *sql.Rows) error {
+				var id uuid.UUID
+				if err := rows.Scan(&id); err != nil {
+					return err
+				}
+				taken[id] = true
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		state.TakenIds[q.section] = taken
+	}
+
+	// Load what the user already has
+	state.ReviewKeys = make(map[string]bool)
+	err = queryEach(ctx, tx, `SELECT film_id, date FROM reviews WHERE user_id = $1`, []any{userId}, func(rows *sql.Rows) error {
+		var filmId uuid.UUID
+		var date time.Time
+		if err := rows.Scan(&filmId, &date); err != nil {
+			return err
+		}
+		state.ReviewKeys[reviewKey(filmId, date)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state.RatedFilms = make(map[uuid.UUID]bool)
+	err = queryEach(ctx, tx, `SELECT film_id FROM user_film_ratings WHERE user_id = $1`, []any{userId}, func(rows *sql.Rows) error {
+		var filmId uuid.UUID
+		if err := rows.Scan(&filmId); err != nil {
+			return err
+		}
+		state.RatedFilms[filmId] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state.ComparisonKeys = make(map[string]bool)
+	err = queryEach(ctx, tx, `SELECT film_a_film_id, film_b_film_id, comparison_date FROM comparison_histories WHERE user_id = $1`,
+		[]any{userId}, func(rows *sql.Rows) error {
+			var filmAId, filmBId uuid.UUID
+			var date time.Time
+			if err := rows.Scan(&filmAId, &filmBId, &date); err != nil {
+				return err
+			}
+			state.ComparisonKeys[comparisonKey(filmAId, filmBId, date)] = true
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	state.RecommendedFilms = make(map[int]bool)
+	err = queryEach(ctx, tx, `SELECT external_film_id FROM film_recommendation WHERE user_id = $1`, []any{userId}, func(rows *sql.Rows) error {
+		var externalId int
+		if err := rows.Scan(&externalId); err != nil {
+			return err
+		}
+		state.RecommendedFilms[externalId] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state.ReasonKeys = make(map[string]bool)
+	err = queryEach(ctx, tx, `SELECT external_film_id, source FROM recommendation_reasons WHERE user_id = $1`, []any{userId}, func(rows *sql.Rows) error {
+		var externalId int
+		var source string
+		if err := rows.Scan(&externalId, &source); err != nil {
+			return err
+		}
+		state.ReasonKeys[reasonKey(externalId, source)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state.GraphNodes = make(map[int]bool)
+	err = queryEach(ctx, tx, `SELECT external_film_id FROM film_graph_nodes WHERE user_id = $1`, []any{userId}, func(rows *sql.Rows) error {
+		var externalId int
+		if err := rows.Scan(&externalId); err != nil {
+			return err
+		}
+		state.GraphNodes[externalId] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state.GraphEdges = make(map[string]bool)
+	err = queryEach(ctx, tx, `SELECT from_film_id, to_film_id FROM film_graph_edges WHERE user_id = $1`, []any{userId}, func(rows *sql.Rows) error {
+		var from, to int
+		if err := rows.Scan(&from, &to); err != nil {
+			return err
+		}
+		state.GraphEdges[edgeKey(from, to)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// ApplyRestore inserts every row of the plan. Order matters as later tables reference users and films, the
+// films themselves already exist as they come from the metadata provider rather than the archive.
+func (s *store) ApplyRestore(ctx context.Context, tx *sql.Tx, plan *RestorePlan) error {
+	if plan.User != nil {
+		user := plan.User
+		query := /* sql */ `
+			INSERT INTO users (user_id, name, username, profile_pic_url, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`
+		_, err := tx.ExecContext(ctx, query, user.ID, user.Name, user.Username, user.ProfilePicURL, user.CreatedAt, user.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		identityQuery := /* sql */ `
+			INSERT INTO user_identities (user_id, provider, subject, provider_username, linked_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+		if user.GithubId != nil {
+			_, err := tx.ExecContext(ctx, identityQuery, user.ID, "github", strconv.FormatInt(*user.GithubId, 10), user.Username, user.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		if user.GoogleId != nil {
+			_, err := tx.ExecContext(ctx, identityQuery, user.ID, "google", *user.GoogleId, user.Username, user.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, review := range plan.Reviews {
+		query := /* sql */ `
+			INSERT INTO reviews (review_id, content, date, rating, film_id, user_id)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`
+		_, err := tx.ExecContext(ctx, query, review.ID, review.Content, review.Date, review.Rating, review.FilmId, review.UserId)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, rating := range plan.Ratings {
+		query := /* sql */ `
+			INSERT INTO user_film_ratings (user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value, rating_deviation, volatility)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`
+		_, err := tx.ExecContext(ctx, query, rating.ID, rating.UserId, rating.FilmId, rating.EloRating, rating.NumberOfComparisons, rating.LastUpdated, rating.InitialRating, rating.KConstantValue,
+			rating.RatingDeviation, rating.Volatility)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, comparison := range plan.Comparisons {
+		var winningFilmId *uuid.UUID
+		if comparison.WinningFilmId != uuid.Nil {
+			winningFilmId = &comparison.WinningFilmId
+		}
+		query := /* sql */ `
+			INSERT INTO comparison_histories (comparison_history_id, user_id, film_a_film_id, film_b_film_id, winning_film_film_id, comparison_date, was_equal)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`
+		_, err := tx.ExecContext(ctx, query, comparison.ID, comparison.UserId, comparison.FilmAId, comparison.FilmBId, winningFilmId, comparison.ComparisonDate, comparison.WasEqual)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, recommendation := range plan.Recommendations {
+		query := /* sql */ `
+			INSERT INTO film_recommendation (film_recommendation_id, user_id, external_film_id, has_seen, has_been_recommended, recommendations_generated,
+				recommended_at, dismissed_at, snoozed_until)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`
+		_, err := tx.ExecContext(ctx, query, recommendation.ID, recommendation.UserID, recommendation.ExternalFilmID, recommendation.HasSeen,
+			recommendation.HasBeenRecommended, recommendation.RecommendationsGenerated,
+			recommendation.RecommendedAt, recommendation.DismissedAt, recommendation.SnoozedUntil)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, reason := range plan.Reasons {
+		query := /* sql */ `
+			INSERT INTO recommendation_reasons (recommendation_reason_id, user_id, external_film_id, source, detail, score, seeds_agreed, recommended_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`
+		_, err := tx.ExecContext(ctx, query, reason.ID, plan.Report.UserID, reason.ExternalFilmID, reason.Source, reason.Detail, reason.Score,
+			reason.SeedsAgreed, reason.RecommendedAt)
+		if err != nil {
+			return err
+		}
+
+		for _, seed := range reason.BasedOn {
+			var tmdbRank sql.NullInt64
+			if seed.TmdbRank > 0 {
+				tmdbRank = sql.NullInt64{Int64: int64(seed.TmdbRank), Valid: true}
+			}
+			seedQuery := /* sql */ `
+				INSERT INTO recommendation_reason_seeds (recommendation_reason_id, seed_film_id, elo_rating, tmdb_rank)
+				VALUES ($1, $2, $3, $4)
+			`
+			if _, err := tx.ExecContext(ctx, seedQuery, reason.ID, seed.FilmID, seed.EloRating, tmdbRank); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, node := range plan.GraphNodes {
+		query := /* sql */ `
+			INSERT INTO film_graph_nodes (user_id, external_film_id, title)
+			VALUES ($1, $2, $3)
+		`
+		_, err := tx.ExecContext(ctx, query, node.UserID, node.ExternalFilmID, node.Title)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, edge := range plan.GraphEdges {
+		query := /* sql */ `
+			INSERT INTO film_graph_edges (user_id, edge_id, from_film_id, to_film_id)
+			VALUES ($1, $2, $3, $4)
+		`
+		_, err := tx.ExecContext(ctx, query, edge.UserID, edge.EdgeId, edge.FromFilmID, edge.ToFilmID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func queryEach(ctx context.Context, tx *sql.Tx, query string, args []any, scan func(rows *sql.Rows) error) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,193 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	testDB      *sql.DB
+	testDbSetup *utils.TestDatabase
+)
+
+func TestMain(m *testing.M) {
+	var err error
+	testDbSetup, err = utils.StartTestPostgres()
+	if err != nil {
+		log.Fatalf("could not start test database: %v", err)
+	}
+
+	testDB = testDbSetup.DB
+
+	code := m.Run()
+
+	testDbSetup.Close()
+	os.Exit(code)
+}
+
+func newRestoreArchive() []byte {
+	userId := uuid.New()
+	filmA := domain.Film{ID: uuid.New(), ExternalID: int(uuid.New().ID() % 2147483647), Title: "Film A", Description: "A", PosterUrl: "/a.jpg", ReleaseYear: "1995"}
+	filmB := domain.Film{ID: uuid.New(), ExternalID: int(uuid.New().ID() % 2147483647), Title: "Film B", Description: "B", PosterUrl: "/b.jpg", ReleaseYear: "2000"}
+	githubId := int64(uuid.New().ID() % 2147483647)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	archive := &Archive{
+		Manifest: Manifest{ExportedAt: now},
+		User:     domain.User{ID: userId, GithubId: &githubId, Name: "Restored User", Username: "restored", CreatedAt: now, UpdatedAt: now},
+		Films:    []domain.Film{filmA, filmB},
+		Reviews: []domain.Review{
+			{ID: uuid.New(), Content: "Great", Date: now, Rating: 4.5, FilmId: filmA.ID, UserId: userId},
+		},
+		Ratings: []domain.UserFilmRatingDetail{
+			{Rating: domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: filmA.ID, EloRating: 1100, NumberOfComparisons: 1, LastUpdated: now, InitialRating: 4.5, KConstantValue: 40}},
+			{Rating: domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: filmB.ID, EloRating: 1000, NumberOfComparisons: 1, LastUpdated: now, InitialRating: 3, KConstantValue: 40}},
+		},
+		Comparisons: []domain.ComparisonHistory{
+			{ID: uuid.New(), UserId: userId, FilmAId: filmA.ID, FilmBId: filmB.ID, WinningFilmId: filmA.ID, ComparisonDate: now},
+		},
+		Recommendations: []domain.FilmRecommendation{
+			{ID: uuid.New(), UserID: userId, ExternalFilmID: filmA.ExternalID, HasSeen: true},
+			{ID: uuid.New(), UserID: userId, ExternalFilmID: filmB.ExternalID, HasBeenRecommended: true, RecommendedAt: &now, DismissedAt: &now},
+		},
+		Reasons: []domain.StoredRecommendationReason{
+			{ID: uuid.New(), ExternalFilmID: filmB.ExternalID, Source: domain.RecommendationSourceTMDB, Detail: "Because you liked Film A",
+				SeedsAgreed: 1, RecommendedAt: now, BasedOn: []domain.RecommendedFrom{{FilmID: filmA.ID, TmdbRank: 1}}},
+		},
+		GraphNodes: []domain.FilmGraphNode{
+			{UserID: userId, ExternalFilmID: filmA.ExternalID, Title: filmA.Title},
+			{UserID: userId, ExternalFilmID: filmB.ExternalID, Title: filmB.Title},
+		},
+		GraphEdges: []domain.FilmGraphEdge{
+			{UserID: userId, EdgeId: uuid.New(), FromFilmID: filmA.ExternalID, ToFilmID: filmB.ExternalID},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, archive); err != nil {
+		log.Fatalf("could not write archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func countRows(t *testing.T, query string, args ...any) int {
+	var count int
+	if err := testDB.QueryRow(query, args...).Scan(&count); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	return count
+}
+
+// providerFilms stands in for the metadata provider, creating films under the title it knows them by
+func providerFilms() *mockFilmService {
+	return &mockFilmService{
+		createFilmFromProviderFunc: func(ctx context.Context, externalId int) (*domain.Film, error) {
+			film := domain.Film{ID: uuid.New(), ExternalID: externalId, Title: "Provider title"}
+			_, err := testDB.ExecContext(ctx, `INSERT INTO films (film_id, external_id, title) VALUES ($1, $2, $3)`, film.ID, film.ExternalID, film.Title)
+			if err != nil {
+				return nil, err
+			}
+			return &film, nil
+		},
+	}
+}
+
+func TestStore_RestoreUser(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(NewStore(testDB), nil, providerFilms(), nil, nil, nil)
+	data := newRestoreArchive()
+	archive, _ := ReadArchive(bytes.NewReader(data), int64(len(data)))
+	userId := archive.User.ID
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		report, err := service.RestoreUser(ctx, bytes.NewReader(data), int64(len(data)), RestoreOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !report.UserCreated || report.Films.Created != 2 || report.Comparisons.Created != 1 {
+			t.Errorf("unexpected dry run report %+v", report)
+		}
+		if countRows(t, `SELECT COUNT(*) FROM users WHERE user_id = $1`, userId) != 0 {
+			t.Error("expected dry run not to create the user")
+		}
+	})
+
+	t.Run("restores a deleted user", func(t *testing.T) {
+		report, err := service.RestoreUser(ctx, bytes.NewReader(data), int64(len(data)), RestoreOptions{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !report.UserCreated || report.Films.Created != 2 {
+			t.Errorf("expected user and films to be created, got %+v", report)
+		}
+		// Films come from the metadata provider rather than the archive
+		if countRows(t, `SELECT COUNT(*) FROM films WHERE external_id = $1 AND title = 'Provider title'`, archive.Films[0].ExternalID) != 1 {
+			t.Error("expected the film to be created from the metadata provider")
+		}
+		if countRows(t, `SELECT COUNT(*) FROM user_film_ratings WHERE user_id = $1`, userId) != 2 {
+			t.Error("expected 2 ratings to be restored")
+		}
+		if countRows(t, `SELECT COUNT(*) FROM comparison_histories WHERE user_id = $1`, userId) != 1 {
+			t.Error("expected comparison history to be restored")
+		}
+		if countRows(t, `SELECT COUNT(*) FROM film_graph_edges WHERE user_id = $1`, userId) != 1 {
+			t.Error("expected graph edge to be restored")
+		}
+		// A dismissed film stays out of the inbox and keeps why it was recommended
+		if countRows(t, `SELECT COUNT(*) FROM film_recommendation WHERE user_id = $1 AND dismissed_at IS NOT NULL AND recommended_at IS NOT NULL`, userId) != 1 {
+			t.Error("expected the dismissed recommendation to be restored")
+		}
+		if countRows(t, `SELECT COUNT(*) FROM recommendation_reasons r JOIN recommendation_reason_seeds s ON s.recommendation_reason_id = r.recommendation_reason_id
+			WHERE r.user_id = $1`, userId) != 1 {
+			t.Error("expected the recommendation reason and its seed to be restored")
+		}
+	})
+
+	t.Run("restoring again changes nothing", func(t *testing.T) {
+		report, err := service.RestoreUser(ctx, bytes.NewReader(data), int64(len(data)), RestoreOptions{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if report.UserCreated || report.Reviews.Created != 0 || report.Reviews.Existing != 1 || report.Comparisons.Existing != 1 || report.Reasons.Existing != 1 {
+			t.Errorf("expected everything to already exist, got %+v", report)
+		}
+		if countRows(t, `SELECT COUNT(*) FROM reviews WHERE user_id = $1`, userId) != 1 {
+			t.Error("expected review not to be duplicated")
+		}
+	})
+
+	t.Run("merges into another user with remapped ids", func(t *testing.T) {
+		otherUser := uuid.New()
+		_, err := testDB.Exec(`INSERT INTO users (user_id, name, username) VALUES ($1, 'Other', 'other')`, otherUser)
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+
+		report, err := service.RestoreUser(ctx, bytes.NewReader(data), int64(len(data)), RestoreOptions{UserID: otherUser})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if report.Films.Existing != 2 || report.Reviews.Remapped != 1 || report.Ratings.Remapped != 2 || report.Reasons.Remapped != 1 {
+			t.Errorf("expected films to be matched and rows remapped, got %+v", report)
+		}
+		if countRows(t, `SELECT COUNT(*) FROM user_film_ratings WHERE user_id = $1`, otherUser) != 2 {
+			t.Error("expected ratings to be merged into the other user")
+		}
+	})
+
+	t.Run("unknown target user", func(t *testing.T) {
+		_, err := service.RestoreUser(ctx, bytes.NewReader(data), int64(len(data)), RestoreOptions{UserID: uuid.New()})
+		if err != ErrRestoreUserNotFound {
+			t.Errorf("expected ErrRestoreUserNotFound, got %v", err)
+		}
+	})
+}
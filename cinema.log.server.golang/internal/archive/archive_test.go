@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = content
+	}
+	return files
+}
+
+func newTestArchive() *Archive {
+	userId := uuid.New()
+	filmId := uuid.New()
+	exportedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	snoozedUntil := exportedAt.AddDate(0, 0, 7)
+
+	return &Archive{
+		Manifest: Manifest{ExportedAt: exportedAt},
+		User:     domain.User{ID: userId, Name: "Test User", Username: "testuser"},
+		Films:    []domain.Film{{ID: filmId, ExternalID: 949, Title: "Heat, the film", ReleaseYear: "1995"}},
+		Reviews: []domain.Review{
+			{ID: uuid.New(), Content: "Great", Date: exportedAt, Rating: 4.5, FilmId: filmId, UserId: userId},
+		},
+		Ratings: []domain.UserFilmRatingDetail{
+			{Rating: domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: filmId, EloRating: 1050}, FilmTitle: "Heat, the film"},
+		},
+		Comparisons: []domain.ComparisonHistory{},
+		Recommendations: []domain.FilmRecommendation{
+			{ID: uuid.New(), UserID: userId, ExternalFilmID: 949, HasBeenRecommended: true, RecommendedAt: &exportedAt, SnoozedUntil: &snoozedUntil},
+		},
+		Reasons: []domain.StoredRecommendationReason{{
+			ID: uuid.New(), ExternalFilmID: 949, Source: domain.RecommendationSourceTMDB, Detail: "Because you liked Ronin",
+			SeedsAgreed: 2, RecommendedAt: exportedAt,
+			BasedOn: []domain.RecommendedFrom{{FilmID: filmId, Title: "Heat, the film", TmdbRank: 1}, {FilmID: uuid.New(), Title: "Ronin", TmdbRank: 2}},
+		}},
+		GraphNodes: []domain.FilmGraphNode{{UserID: userId, ExternalFilmID: 949, Title: "Heat, the film"}},
+		GraphEdges: []domain.FilmGraphEdge{},
+	}
+}
+
+func TestWriteArchive_ContainsManifestAndEverySection(t *testing.T) {
+	archive := newTestArchive()
+	var buf bytes.Buffer
+
+	if err := WriteArchive(&buf, archive); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	files := readZip(t, buf.Bytes())
+
+	var manifest Manifest
+	if err := json.Unmarshal(files[ManifestFileName], &manifest); err != nil {
+		t.Fatalf("failed to decode manifest: %v", err)
+	}
+	if manifest.SchemaVersion != SchemaVersion {
+		t.Errorf("expected schema version %d, got %d", SchemaVersion, manifest.SchemaVersion)
+	}
+	if manifest.UserID != archive.User.ID {
+		t.Errorf("expected manifest user %v, got %v", archive.User.ID, manifest.UserID)
+	}
+	if len(manifest.Files) != 18 {
+		t.Errorf("expected 18 files in manifest, got %d", len(manifest.Files))
+	}
+	for _, f := range manifest.Files {
+		if _, ok := files[f.Name]; !ok {
+			t.Errorf("manifest lists %s but it is not in the zip", f.Name)
+		}
+	}
+}
+
+func TestWriteArchive_JSONAndCSVAgree(t *testing.T) {
+	archive := newTestArchive()
+	var buf bytes.Buffer
+
+	if err := WriteArchive(&buf, archive); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	files := readZip(t, buf.Bytes())
+
+	var films []domain.Film
+	if err := json.Unmarshal(files["films.json"], &films); err != nil {
+		t.Fatalf("failed to decode films.json: %v", err)
+	}
+	if len(films) != 1 || films[0].ID != archive.Films[0].ID {
+		t.Errorf("unexpected films.json contents: %+v", films)
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(files["films.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read films.csv: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected header and 1 row, got %d rows", len(rows))
+	}
+	if rows[1][2] != "Heat, the film" {
+		t.Errorf("expected title with comma to survive csv, got %q", rows[1][2])
+	}
+
+	comparisons, err := csv.NewReader(bytes.NewReader(files["comparisons.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read comparisons.csv: %v", err)
+	}
+	if len(comparisons) != 1 {
+		t.Errorf("expected only a header for empty comparisons, got %d rows", len(comparisons))
+	}
+	if string(bytes.TrimSpace(files["comparisons.json"])) != "[]" {
+		t.Errorf("expected empty comparisons to be written as [], got %s", files["comparisons.json"])
+	}
+
+	recommendations, err := csv.NewReader(bytes.NewReader(files["recommendations.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read recommendations.csv: %v", err)
+	}
+	if len(recommendations) != 2 || recommendations[1][5] != "2025-03-01T12:00:00Z" || recommendations[1][6] != "" || recommendations[1][7] != "2025-03-08T12:00:00Z" {
+		t.Errorf("expected when the film was recommended and snoozed until, got %v", recommendations)
+	}
+
+	reasons, err := csv.NewReader(bytes.NewReader(files["recommendation_reasons.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read recommendation_reasons.csv: %v", err)
+	}
+	if len(reasons) != 2 || reasons[1][2] != domain.RecommendationSourceTMDB || len(strings.Fields(reasons[1][7])) != 2 {
+		t.Errorf("expected the reason with both films it was based on, got %v", reasons)
+	}
+}
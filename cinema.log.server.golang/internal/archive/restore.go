@@ -0,0 +1,372 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidArchive           = errors.New("invalid cinema.log export archive")
+	ErrUnsupportedSchemaVersion = errors.New("unsupported export archive schema version")
+	ErrRestoreUserNotFound      = errors.New("user to restore into does not exist")
+)
+
+// ReadArchive reads an archive written by WriteArchive. Only the json files are read,
+// the csv copies are for people rather than for restoring.
+func ReadArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	archive := &Archive{}
+	if err := readJSON(files, ManifestFileName, &archive.Manifest); err != nil {
+		return nil, err
+	}
+	if archive.Manifest.SchemaVersion < 1 || archive.Manifest.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSchemaVersion, archive.Manifest.SchemaVersion)
+	}
+
+	sections := []struct {
+		name string
+		dst  any
+	}{
+		{SectionUser, &archive.User},
+		{SectionFilms, &archive.Films},
+		{SectionReviews, &archive.Reviews},
+		{SectionRatings, &archive.Ratings},
+		{SectionComparisons, &archive.Comparisons},
+		{SectionRecommendations, &archive.Recommendations},
+		{SectionReasons, &archive.Reasons},
+		{SectionGraphNodes, &archive.GraphNodes},
+		{SectionGraphEdges, &archive.GraphEdges},
+	}
+	for _, s := range sections {
+		if archive.Manifest.SchemaVersion < sectionSince[s.name] {
+			continue
+		}
+		if err := readJSON(files, s.name+".json", s.dst); err != nil {
+			return nil, err
+		}
+	}
+
+	return archive, nil
+}
+
+func readJSON(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%w: missing %s", ErrInvalidArchive, name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+	}
+	return nil
+}
+
+type RestoreOptions struct {
+	// UserID restores into an existing user instead of the user the archive was exported from
+	UserID uuid.UUID
+	// DryRun works out everything the restore would do and then rolls it back
+	DryRun bool
+}
+
+// SectionCount tallies what happened to the rows of one section. Existing rows were already
+// there and were left alone, Remapped rows were created under a new id because theirs was taken.
+type SectionCount struct {
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+	Remapped int `json:"remapped"`
+	Skipped  int `json:"skipped"`
+}
+
+type IdRemap struct {
+	Section string    `json:"section"`
+	From    uuid.UUID `json:"from"`
+	To      uuid.UUID `json:"to"`
+}
+
+type RestoreReport struct {
+	DryRun          bool         `json:"dryRun"`
+	UserID          uuid.UUID    `json:"userId"`
+	UserCreated     bool         `json:"userCreated"`
+	Films           SectionCount `json:"films"`
+	Reviews         SectionCount `json:"reviews"`
+	Ratings         SectionCount `json:"ratings"`
+	Comparisons     SectionCount `json:"comparisons"`
+	Recommendations SectionCount `json:"recommendations"`
+	Reasons         SectionCount `json:"recommendationReasons"`
+	GraphNodes      SectionCount `json:"graphNodes"`
+	GraphEdges      SectionCount `json:"graphEdges"`
+	Remaps          []IdRemap    `json:"remaps"`
+	Warnings        []string     `json:"warnings"`
+}
+
+// RestoreState is what is already in the database that a restore has to work around
+type RestoreState struct {
+	UserExists    bool
+	GithubIdTaken bool
+	GoogleIdTaken bool
+
+	// Films that already exist, keyed by TMDB id
+	FilmsByExternalId map[int]uuid.UUID
+	// Films the archive needed that were created from the metadata provider for this restore, keyed by TMDB id
+	ResolvedFilms map[int]bool
+	// Films the metadata provider doesn't know, keyed by TMDB id
+	UnknownFilms map[int]bool
+	// Ids from the archive that are already in use, per section
+	TakenIds map[string]map[uuid.UUID]bool
+
+	// What the target user already has, so restoring the same archive twice is a no-op
+	ReviewKeys       map[string]bool
+	RatedFilms       map[uuid.UUID]bool
+	ComparisonKeys   map[string]bool
+	RecommendedFilms map[int]bool
+	ReasonKeys       map[string]bool
+	GraphNodes       map[int]bool
+	GraphEdges       map[string]bool
+}
+
+// RestorePlan holds the rows to insert, already remapped onto the target user and existing films
+type RestorePlan struct {
+	Report          RestoreReport
+	User            *domain.User
+	Reviews         []domain.Review
+	Ratings         []domain.UserFilmRating
+	Comparisons     []domain.ComparisonHistory
+	Recommendations []domain.FilmRecommendation
+	Reasons         []domain.StoredRecommendationReason
+	GraphNodes      []domain.FilmGraphNode
+	GraphEdges      []domain.FilmGraphEdge
+}
+
+func reviewKey(filmId uuid.UUID, date time.Time) string {
+	return filmId.String() + "|" + date.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
+
+func comparisonKey(filmAId, filmBId uuid.UUID, date time.Time) string {
+	return filmAId.String() + "|" + filmBId.String() + "|" + date.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
+
+func reasonKey(externalFilmId int, source string) string {
+	return strconv.Itoa(externalFilmId) + "|" + source
+}
+
+func edgeKey(from, to int) string {
+	return strconv.Itoa(from) + "|" + strconv.Itoa(to)
+}
+
+// planRestore works out which rows of the archive need inserting for userId given what is already there.
+// Films are matched on their TMDB id and are never inserted from the archive, ones that don't exist yet have
+// been created from the metadata provider by the time the plan is made, or would be on a dry run.
+// Every other row is skipped if the user already has it and given a fresh id if its own is taken by someone else.
+func planRestore(archive *Archive, userId uuid.UUID, state *RestoreState) *RestorePlan {
+	plan := &RestorePlan{
+		Report: RestoreReport{UserID: userId, Remaps: []IdRemap{}, Warnings: []string{}},
+	}
+	report := &plan.Report
+
+	freshId := func(section string, id uuid.UUID) (uuid.UUID, bool) {
+		if id != uuid.Nil && !state.TakenIds[section][id] {
+			return id, false
+		}
+		newId := uuid.New()
+		if id != uuid.Nil {
+			report.Remaps = append(report.Remaps, IdRemap{Section: section, From: id, To: newId})
+		}
+		return newId, true
+	}
+	count := func(c *SectionCount, remapped bool) {
+		c.Created++
+		if remapped {
+			c.Remapped++
+		}
+	}
+
+	if !state.UserExists {
+		user := archive.User
+		user.ID = userId
+		if state.GithubIdTaken {
+			user.GithubId = nil
+			report.Warnings = append(report.Warnings, "github account is linked to another user and was not restored")
+		}
+		if state.GoogleIdTaken {
+			user.GoogleId = nil
+			report.Warnings = append(report.Warnings, "google account is linked to another user and was not restored")
+		}
+		plan.User = &user
+		report.UserCreated = true
+	}
+
+	// Map every film in the archive onto the film id it will have after the restore
+	filmIds := make(map[uuid.UUID]uuid.UUID)
+	externalIds := make(map[int]bool)
+	for externalId := range state.FilmsByExternalId {
+		externalIds[externalId] = true
+	}
+	for _, film := range archive.Films {
+		if existingId, ok := state.FilmsByExternalId[film.ExternalID]; ok {
+			filmIds[film.ID] = existingId
+			if existingId != film.ID {
+				report.Remaps = append(report.Remaps, IdRemap{Section: SectionFilms, From: film.ID, To: existingId})
+			}
+			if state.ResolvedFilms[film.ExternalID] {
+				count(&report.Films, existingId != film.ID)
+			} else {
+				report.Films.Existing++
+			}
+			continue
+		}
+		if state.UnknownFilms[film.ExternalID] {
+			// Everything else for the film is skipped with it
+			report.Films.Skipped++
+			report.Warnings = append(report.Warnings, fmt.Sprintf("film %d (%s) is not known to the metadata provider and was not restored", film.ExternalID, film.Title))
+			continue
+		}
+		// Only a dry run gets here, the film would be created from the metadata provider
+		filmIds[film.ID] = film.ID
+		externalIds[film.ExternalID] = true
+		report.Films.Created++
+	}
+
+	for _, review := range archive.Reviews {
+		filmId, ok := filmIds[review.FilmId]
+		if !ok {
+			report.Reviews.Skipped++
+			report.Warnings = append(report.Warnings, fmt.Sprintf("review %s refers to a film missing from the archive", review.ID))
+			continue
+		}
+		if state.ReviewKeys[reviewKey(filmId, review.Date)] {
+			report.Reviews.Existing++
+			continue
+		}
+		id, remapped := freshId(SectionReviews, review.ID)
+		review.ID, review.FilmId, review.UserId = id, filmId, userId
+		plan.Reviews = append(plan.Reviews, review)
+		count(&report.Reviews, remapped)
+	}
+
+	for _, detail := range archive.Ratings {
+		rating := detail.Rating
+		filmId, ok := filmIds[rating.FilmId]
+		if !ok {
+			report.Ratings.Skipped++
+			report.Warnings = append(report.Warnings, fmt.Sprintf("rating for %s refers to a film missing from the archive", detail.FilmTitle))
+			continue
+		}
+		if state.RatedFilms[filmId] {
+			report.Ratings.Existing++
+			continue
+		}
+		id, remapped := freshId(SectionRatings, rating.ID)
+		rating.ID, rating.FilmId, rating.UserId = id, filmId, userId
+		plan.Ratings = append(plan.Ratings, rating)
+		count(&report.Ratings, remapped)
+	}
+
+	for _, comparison := range archive.Comparisons {
+		filmAId, okA := filmIds[comparison.FilmAId]
+		filmBId, okB := filmIds[comparison.FilmBId]
+		winningFilmId, okWinner := filmIds[comparison.WinningFilmId]
+		if !okA || !okB || (!okWinner && comparison.WinningFilmId != uuid.Nil) {
+			report.Comparisons.Skipped++
+			report.Warnings = append(report.Warnings, fmt.Sprintf("comparison %s refers to a film missing from the archive", comparison.ID))
+			continue
+		}
+		if state.ComparisonKeys[comparisonKey(filmAId, filmBId, comparison.ComparisonDate)] {
+			report.Comparisons.Existing++
+			continue
+		}
+		id, remapped := freshId(SectionComparisons, comparison.ID)
+		comparison.ID, comparison.UserId = id, userId
+		comparison.FilmAId, comparison.FilmBId, comparison.WinningFilmId = filmAId, filmBId, winningFilmId
+		plan.Comparisons = append(plan.Comparisons, comparison)
+		count(&report.Comparisons, remapped)
+	}
+
+	for _, recommendation := range archive.Recommendations {
+		if !externalIds[recommendation.ExternalFilmID] {
+			report.Recommendations.Skipped++
+			report.Warnings = append(report.Warnings, fmt.Sprintf("recommendation for tmdb film %d has no matching film", recommendation.ExternalFilmID))
+			continue
+		}
+		if state.RecommendedFilms[recommendation.ExternalFilmID] {
+			report.Recommendations.Existing++
+			continue
+		}
+		id, remapped := freshId(SectionRecommendations, recommendation.ID)
+		recommendation.ID, recommendation.UserID = id, userId
+		plan.Recommendations = append(plan.Recommendations, recommendation)
+		count(&report.Recommendations, remapped)
+	}
+
+	for _, reason := range archive.Reasons {
+		if !externalIds[reason.ExternalFilmID] {
+			report.Reasons.Skipped++
+			report.Warnings = append(report.Warnings, fmt.Sprintf("recommendation reason for tmdb film %d has no matching film", reason.ExternalFilmID))
+			continue
+		}
+		if state.ReasonKeys[reasonKey(reason.ExternalFilmID, reason.Source)] {
+			report.Reasons.Existing++
+			continue
+		}
+		// Seeds whose film isn't in the archive are dropped, the reason still says where the film came from
+		basedOn := make([]domain.RecommendedFrom, 0, len(reason.BasedOn))
+		for _, seed := range reason.BasedOn {
+			if filmId, ok := filmIds[seed.FilmID]; ok {
+				seed.FilmID = filmId
+				basedOn = append(basedOn, seed)
+			}
+		}
+		id, remapped := freshId(SectionReasons, reason.ID)
+		reason.ID, reason.BasedOn = id, basedOn
+		plan.Reasons = append(plan.Reasons, reason)
+		count(&report.Reasons, remapped)
+	}
+
+	for _, node := range archive.GraphNodes {
+		if !externalIds[node.ExternalFilmID] {
+			report.GraphNodes.Skipped++
+			report.Warnings = append(report.Warnings, fmt.Sprintf("graph node for tmdb film %d has no matching film", node.ExternalFilmID))
+			continue
+		}
+		if state.GraphNodes[node.ExternalFilmID] {
+			report.GraphNodes.Existing++
+			continue
+		}
+		node.UserID = userId
+		plan.GraphNodes = append(plan.GraphNodes, node)
+		report.GraphNodes.Created++
+	}
+
+	for _, edge := range archive.GraphEdges {
+		if state.GraphEdges[edgeKey(edge.FromFilmID, edge.ToFilmID)] {
+			report.GraphEdges.Existing++
+			continue
+		}
+		id, remapped := freshId(SectionGraphEdges, edge.EdgeId)
+		edge.EdgeId, edge.UserID = id, userId
+		plan.GraphEdges = append(plan.GraphEdges, edge)
+		count(&report.GraphEdges, remapped)
+	}
+
+	return plan
+}
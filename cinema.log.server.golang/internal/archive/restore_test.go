@@ -0,0 +1,266 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+func emptyRestoreState() *RestoreState {
+	return &RestoreState{
+		UserExists:        true,
+		FilmsByExternalId: map[int]uuid.UUID{},
+		TakenIds:          map[string]map[uuid.UUID]bool{},
+		ReviewKeys:        map[string]bool{},
+		RatedFilms:        map[uuid.UUID]bool{},
+		ComparisonKeys:    map[string]bool{},
+		RecommendedFilms:  map[int]bool{},
+		ReasonKeys:        map[string]bool{},
+		GraphNodes:        map[int]bool{},
+		GraphEdges:        map[string]bool{},
+		ResolvedFilms:     map[int]bool{},
+		UnknownFilms:      map[int]bool{},
+	}
+}
+
+func TestReadArchive_RoundTrip(t *testing.T) {
+	original := newTestArchive()
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, original); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	archive, err := ReadArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if archive.Manifest.SchemaVersion != SchemaVersion {
+		t.Errorf("expected schema version %d, got %d", SchemaVersion, archive.Manifest.SchemaVersion)
+	}
+	if archive.User.ID != original.User.ID {
+		t.Errorf("expected user %v, got %v", original.User.ID, archive.User.ID)
+	}
+	if len(archive.Films) != 1 || archive.Films[0].ExternalID != 949 {
+		t.Errorf("unexpected films %+v", archive.Films)
+	}
+	if len(archive.Reviews) != 1 || !archive.Reviews[0].Date.Equal(original.Reviews[0].Date) {
+		t.Errorf("unexpected reviews %+v", archive.Reviews)
+	}
+	if len(archive.Ratings) != 1 || archive.Ratings[0].Rating.EloRating != 1050 {
+		t.Errorf("unexpected ratings %+v", archive.Ratings)
+	}
+	recommendation := archive.Recommendations[0]
+	if recommendation.SnoozedUntil == nil || !recommendation.SnoozedUntil.Equal(*original.Recommendations[0].SnoozedUntil) || recommendation.DismissedAt != nil {
+		t.Errorf("expected the snooze to survive, got %+v", recommendation)
+	}
+	if len(archive.Reasons) != 1 || len(archive.Reasons[0].BasedOn) != 2 || archive.Reasons[0].Detail != original.Reasons[0].Detail {
+		t.Errorf("unexpected recommendation reasons %+v", archive.Reasons)
+	}
+}
+
+func TestReadArchive_OlderSchemaVersion(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{ManifestFileName: `{"schemaVersion": 2}`, "user.json": "{}"} {
+		f, _ := zw.Create(name)
+		f.Write([]byte(content))
+	}
+	for _, section := range []string{SectionFilms, SectionReviews, SectionRatings, SectionComparisons, SectionRecommendations, SectionGraphNodes, SectionGraphEdges} {
+		f, _ := zw.Create(section + ".json")
+		f.Write([]byte("[]"))
+	}
+	zw.Close()
+
+	// Version 2 archives were written before recommendation reasons were exported
+	archive, err := ReadArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(archive.Reasons) != 0 {
+		t.Errorf("expected no recommendation reasons, got %+v", archive.Reasons)
+	}
+}
+
+func TestReadArchive_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected error
+	}{
+		{"missing manifest", map[string]string{"user.json": "{}"}, ErrInvalidArchive},
+		{"future schema version", map[string]string{ManifestFileName: `{"schemaVersion": 99}`}, ErrUnsupportedSchemaVersion},
+		{"missing section", map[string]string{ManifestFileName: `{"schemaVersion": 1}`, "user.json": "{}"}, ErrInvalidArchive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			for name, content := range tt.files {
+				f, _ := zw.Create(name)
+				f.Write([]byte(content))
+			}
+			zw.Close()
+
+			_, err := ReadArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	if _, err := ReadArchive(bytes.NewReader([]byte("not a zip")), 9); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("expected ErrInvalidArchive for non zip, got %v", err)
+	}
+}
+
+func TestPlanRestore_IntoEmptyAccount(t *testing.T) {
+	archive := newTestArchive()
+	state := emptyRestoreState()
+	state.UserExists = false
+
+	plan := planRestore(archive, archive.User.ID, state)
+
+	if plan.User == nil || !plan.Report.UserCreated {
+		t.Fatal("expected user to be recreated")
+	}
+	if plan.Report.Films.Created != 1 {
+		t.Errorf("expected the film to be reported as created, got %+v", plan.Report.Films)
+	}
+	if len(plan.Reviews) != 1 || len(plan.Ratings) != 1 || len(plan.GraphNodes) != 1 {
+		t.Errorf("expected every row to be restored, got %d reviews %d ratings %d nodes",
+			len(plan.Reviews), len(plan.Ratings), len(plan.GraphNodes))
+	}
+	if len(plan.Report.Remaps) != 0 {
+		t.Errorf("expected no remaps, got %+v", plan.Report.Remaps)
+	}
+	if len(plan.Recommendations) != 1 || plan.Recommendations[0].SnoozedUntil == nil {
+		t.Errorf("expected the recommendation to keep its snooze, got %+v", plan.Recommendations)
+	}
+	// The reason keeps the film it was based on that's in the archive and drops the one that isn't
+	if len(plan.Reasons) != 1 || len(plan.Reasons[0].BasedOn) != 1 || plan.Reasons[0].BasedOn[0].FilmID != archive.Films[0].ID {
+		t.Errorf("expected the reason with only the seed in the archive, got %+v", plan.Reasons)
+	}
+}
+
+func TestPlanRestore_MatchesFilmsAndRemapsCollidingIds(t *testing.T) {
+	archive := newTestArchive()
+	targetUser := uuid.New()
+	existingFilmId := uuid.New()
+	state := emptyRestoreState()
+	state.FilmsByExternalId[949] = existingFilmId
+	state.TakenIds[SectionReviews] = map[uuid.UUID]bool{archive.Reviews[0].ID: true}
+
+	plan := planRestore(archive, targetUser, state)
+
+	if plan.User != nil {
+		t.Error("expected existing user not to be recreated")
+	}
+	if plan.Report.Films.Existing != 1 {
+		t.Errorf("expected film to be matched on external id, got %+v", plan.Report.Films)
+	}
+
+	review := plan.Reviews[0]
+	if review.ID == archive.Reviews[0].ID {
+		t.Error("expected colliding review id to be remapped")
+	}
+	if review.FilmId != existingFilmId || review.UserId != targetUser {
+		t.Errorf("expected review to point at existing film and target user, got %+v", review)
+	}
+	if plan.Report.Reviews.Remapped != 1 {
+		t.Errorf("expected 1 remapped review, got %d", plan.Report.Reviews.Remapped)
+	}
+	if plan.Ratings[0].FilmId != existingFilmId || plan.Ratings[0].UserId != targetUser {
+		t.Errorf("expected rating to be remapped, got %+v", plan.Ratings[0])
+	}
+	if plan.Reasons[0].BasedOn[0].FilmID != existingFilmId {
+		t.Errorf("expected the reason's seed to point at the existing film, got %+v", plan.Reasons[0].BasedOn)
+	}
+}
+
+func TestPlanRestore_FilmsFromProvider(t *testing.T) {
+	archive := newTestArchive()
+	film := archive.Films[0]
+	providerFilmId := uuid.New()
+	state := emptyRestoreState()
+	state.FilmsByExternalId[film.ExternalID] = providerFilmId
+	state.ResolvedFilms[film.ExternalID] = true
+
+	plan := planRestore(archive, archive.User.ID, state)
+
+	if plan.Report.Films.Created != 1 || plan.Report.Films.Remapped != 1 || plan.Report.Films.Existing != 0 {
+		t.Errorf("expected the film created from the provider to be reported as created, got %+v", plan.Report.Films)
+	}
+	if plan.Reviews[0].FilmId != providerFilmId || plan.Ratings[0].FilmId != providerFilmId {
+		t.Errorf("expected rows to point at the provider's film, got %+v and %+v", plan.Reviews[0], plan.Ratings[0])
+	}
+
+	// Films the provider doesn't know are skipped along with everything for them
+	state = emptyRestoreState()
+	state.UnknownFilms[film.ExternalID] = true
+
+	plan = planRestore(archive, archive.User.ID, state)
+
+	if plan.Report.Films.Skipped != 1 || len(plan.Report.Warnings) == 0 || !strings.Contains(plan.Report.Warnings[0], "not known to the metadata provider") {
+		t.Errorf("expected the unknown film to be skipped with a warning, got %+v", plan.Report)
+	}
+	if len(plan.Reviews) != 0 || len(plan.Ratings) != 0 || len(plan.Recommendations) != 0 || len(plan.Reasons) != 0 {
+		t.Errorf("expected nothing for the unknown film to be restored, got %+v", plan)
+	}
+}
+
+func TestPlanRestore_SkipsWhatTheUserAlreadyHas(t *testing.T) {
+	archive := newTestArchive()
+	film := archive.Films[0]
+	state := emptyRestoreState()
+	state.FilmsByExternalId[film.ExternalID] = film.ID
+	state.ReviewKeys[reviewKey(film.ID, archive.Reviews[0].Date)] = true
+	state.RatedFilms[film.ID] = true
+	state.GraphNodes[film.ExternalID] = true
+	state.ReasonKeys[reasonKey(film.ExternalID, domain.RecommendationSourceTMDB)] = true
+
+	plan := planRestore(archive, archive.User.ID, state)
+
+	if len(plan.Reviews) != 0 || len(plan.Ratings) != 0 || len(plan.GraphNodes) != 0 || len(plan.Reasons) != 0 {
+		t.Errorf("expected nothing to restore, got %d reviews %d ratings %d nodes %d reasons",
+			len(plan.Reviews), len(plan.Ratings), len(plan.GraphNodes), len(plan.Reasons))
+	}
+	if plan.Report.Reviews.Existing != 1 || plan.Report.Ratings.Existing != 1 || plan.Report.GraphNodes.Existing != 1 || plan.Report.Reasons.Existing != 1 {
+		t.Errorf("expected rows to be reported as existing, got %+v", plan.Report)
+	}
+}
+
+func TestPlanRestore_DropsLinkedAccountsOwnedByOthers(t *testing.T) {
+	archive := newTestArchive()
+	githubId := int64(42)
+	archive.User.GithubId = &githubId
+	state := emptyRestoreState()
+	state.UserExists = false
+	state.GithubIdTaken = true
+
+	plan := planRestore(archive, archive.User.ID, state)
+
+	if plan.User.GithubId != nil {
+		t.Error("expected github id to be dropped")
+	}
+	if len(plan.Report.Warnings) != 1 {
+		t.Errorf("expected a warning, got %v", plan.Report.Warnings)
+	}
+}
+
+func TestPlanRestore_SkipsRowsForMissingFilms(t *testing.T) {
+	archive := newTestArchive()
+	archive.Reviews[0].FilmId = uuid.New()
+	state := emptyRestoreState()
+
+	plan := planRestore(archive, archive.User.ID, state)
+
+	if len(plan.Reviews) != 0 || plan.Report.Reviews.Skipped != 1 {
+		t.Errorf("expected review to be skipped, got %+v", plan.Report.Reviews)
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	Issuer   = "cinema.log.server.golang"
+	Audience = "cinema.log.client"
+
+	// DefaultClockSkew is how far the clocks of whoever issued and whoever checks a token can drift
+	// apart, override it with TOKEN_CLOCK_SKEW
+	DefaultClockSkew = 30 * time.Second
+)
+
+var (
+	ErrWrongTokenType = errors.New("wrong token type")
+)
+
+// TokenType says what a token is for. Access and refresh tokens are signed with the same key, so
+// without it one could be used in place of the other.
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+	TokenTypeMerge   TokenType = "merge"
+)
+
+// Claims are the claims in every token the server signs. The registered claims carry the user's id
+// as the subject and the token's own id as jti.
+type Claims struct {
+	Type     TokenType `json:"typ"`
+	Name     string    `json:"name"`
+	Username string    `json:"username"`
+	// MergeFrom is the account a merge token lets the subject merge into their own
+	MergeFrom string `json:"mergeFrom,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// newClaims builds the claims for a token of the given type
+func newClaims(typ TokenType, subject, name, username, id string, issuedAt, expiresAt time.Time) *Claims {
+	return &Claims{
+		Type:     typ,
+		Name:     name,
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   subject,
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{Audience},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			NotBefore: jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+}
+
+// parseClaims verifies a token's signature, issuer, audience and lifetime, then checks that it's the
+// type the caller expects
+func parseClaims(tkn string, typ TokenType, keys *KeySet, clockSkew time.Duration) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tkn, claims, keys.keyFunc,
+		jwt.WithValidMethods(keys.methods()),
+		jwt.WithIssuer(Issuer),
+		jwt.WithAudience(Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	if claims.Type != typ {
+		return nil, ErrWrongTokenType
+	}
+	if claims.ID == "" || claims.Subject == "" {
+		return nil, fmt.Errorf("invalid token claims: missing jti or subject")
+	}
+
+	return claims, nil
+}
+
+// clockSkewFromEnv reads TOKEN_CLOCK_SKEW as a duration like "1m", falling back to the default when
+// it's missing or can't be used
+func clockSkewFromEnv() time.Duration {
+	value := os.Getenv("TOKEN_CLOCK_SKEW")
+	if value == "" {
+		return DefaultClockSkew
+	}
+
+	clockSkew, err := time.ParseDuration(value)
+	if err != nil || clockSkew < 0 {
+		log.Printf("invalid TOKEN_CLOCK_SKEW %q, using %v", value, DefaultClockSkew)
+		return DefaultClockSkew
+	}
+	return clockSkew
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func signClaims(t *testing.T, claims *Claims) string {
+	t.Helper()
+	token, err := testKeys.sign(claims)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestAuthService_TokenTypesAreNotInterchangeable(t *testing.T) {
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+
+	accessToken, refreshToken, err := service.GenerateJWT(context.Background(), user)
+	if err != nil {
+		t.Fatalf("failed to generate tokens: %v", err)
+	}
+
+	if _, err := service.ValidateAccessToken(refreshToken); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("expected a refresh token to be rejected as an access token, got %v", err)
+	}
+	if _, err := service.ValidateRefreshToken(accessToken); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("expected an access token to be rejected as a refresh token, got %v", err)
+	}
+	if _, err := service.RefreshTokens(context.Background(), accessToken); err == nil {
+		t.Error("expected an access token not to be refreshable")
+	}
+	if _, err := service.ValidateRefreshToken(refreshToken); err != nil {
+		t.Errorf("expected the refresh token to be valid, got %v", err)
+	}
+}
+
+func TestAuthService_ValidateAccessToken_Claims(t *testing.T) {
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
+	now := time.Now()
+	subject := uuid.NewString()
+
+	valid := newClaims(TokenTypeAccess, subject, "Test", "test", uuid.NewString(), now, now.Add(time.Hour))
+	if _, err := service.ValidateAccessToken(signClaims(t, valid)); err != nil {
+		t.Fatalf("expected the token to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims *Claims)
+	}{
+		{"wrong audience", func(claims *Claims) { claims.Audience = jwt.ClaimStrings{"someone.else"} }},
+		{"wrong issuer", func(claims *Claims) { claims.Issuer = "someone.else" }},
+		{"no type", func(claims *Claims) { claims.Type = "" }},
+		{"no jti", func(claims *Claims) { claims.ID = "" }},
+		{"no expiry", func(claims *Claims) { claims.ExpiresAt = nil }},
+		{"expired", func(claims *Claims) { claims.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) }},
+		{"issued in the future", func(claims *Claims) { claims.IssuedAt = jwt.NewNumericDate(now.Add(time.Hour)) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := newClaims(TokenTypeAccess, subject, "Test", "test", uuid.NewString(), now, now.Add(time.Hour))
+			tt.modify(claims)
+			if _, err := service.ValidateAccessToken(signClaims(t, claims)); err == nil {
+				t.Error("expected the token to be rejected")
+			}
+		})
+	}
+}
+
+func TestAuthService_ValidateAccessToken_ClockSkew(t *testing.T) {
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
+	now := time.Now()
+
+	// Expired 10 seconds ago, as far as this server's clock is concerned
+	claims := newClaims(TokenTypeAccess, uuid.NewString(), "Test", "test", uuid.NewString(), now.Add(-time.Hour), now.Add(-10*time.Second))
+	token := signClaims(t, claims)
+
+	service.clockSkew = 30 * time.Second
+	if _, err := service.ValidateAccessToken(token); err != nil {
+		t.Errorf("expected the token to be allowed within the clock skew, got %v", err)
+	}
+
+	service.clockSkew = 0
+	if _, err := service.ValidateAccessToken(token); err == nil {
+		t.Error("expected the token to be rejected without clock skew")
+	}
+}
+
+func TestClockSkewFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", DefaultClockSkew},
+		{"2m", 2 * time.Minute},
+		{"0s", 0},
+		{"soon", DefaultClockSkew},
+		{"-5s", DefaultClockSkew},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("TOKEN_CLOCK_SKEW", tt.value)
+		if got := clockSkewFromEnv(); got != tt.want {
+			t.Errorf("clockSkewFromEnv() with %q = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
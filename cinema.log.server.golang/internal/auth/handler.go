@@ -1,11 +1,20 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/users"
+	"cinema.log.server.golang/internal/utils"
 	"github.com/dghubble/gologin/v2"
 	"github.com/dghubble/gologin/v2/github"
 	"github.com/dghubble/gologin/v2/google"
@@ -52,6 +61,23 @@ var googleConf *oauth2.Config = &oauth2.Config{
 	Endpoint:     oauth2google.Endpoint,
 }
 
+// Linking a login to the account that's already logged in goes through the same providers with its own callbacks
+var linkConf *oauth2.Config = &oauth2.Config{
+	ClientID:     GithubClientID,
+	ClientSecret: GithubClientSecret,
+	RedirectURL:  BackendURL + "/auth/github-link-callback",
+	Scopes:       conf.Scopes,
+	Endpoint:     oauth2github.Endpoint,
+}
+
+var googleLinkConf *oauth2.Config = &oauth2.Config{
+	ClientID:     GoogleClientID,
+	ClientSecret: GoogleClientSecret,
+	RedirectURL:  BackendURL + "/auth/google-link-callback",
+	Scopes:       googleConf.Scopes,
+	Endpoint:     oauth2google.Endpoint,
+}
+
 // Cookie configuration for OAuth state parameter
 // Automatically adjusts based on ENVIRONMENT variable
 var cookieConf gologin.CookieConfig = gologin.CookieConfig{
@@ -63,13 +89,19 @@ var cookieConf gologin.CookieConfig = gologin.CookieConfig{
 	SameSite: getCookieSameSite(),
 }
 
+// oidcLoginCookie keeps the state, nonce and PKCE verifier of an OpenID Connect login while the user
+// is at the provider
+const oidcLoginCookie = "oidc_login"
+
 type Handler struct {
-	authService *AuthService
+	authService   *AuthService
+	oidcProviders []*OIDCProvider
 }
 
-func NewHandler(authService *AuthService) *Handler {
+func NewHandler(authService *AuthService, oidcProviders []*OIDCProvider) *Handler {
 	return &Handler{
-		authService: authService,
+		authService:   authService,
+		oidcProviders: oidcProviders,
 	}
 }
 
@@ -89,14 +121,71 @@ func (h *Handler) GoogleCallback() http.Handler {
 	return google.StateHandler(cookieConf, google.CallbackHandler(googleConf, http.HandlerFunc(h.googleCallbackHandler), nil))
 }
 
+func (h *Handler) LinkGithub() http.Handler {
+	return github.StateHandler(cookieConf, github.LoginHandler(linkConf, nil))
+}
+
+func (h *Handler) LinkGithubCallback() http.Handler {
+	return github.StateHandler(cookieConf, github.CallbackHandler(linkConf, http.HandlerFunc(h.githubLinkCallbackHandler), nil))
+}
+
+func (h *Handler) LinkGoogle() http.Handler {
+	return google.StateHandler(cookieConf, google.LoginHandler(googleLinkConf, nil))
+}
+
+func (h *Handler) LinkGoogleCallback() http.Handler {
+	return google.StateHandler(cookieConf, google.CallbackHandler(googleLinkConf, http.HandlerFunc(h.googleLinkCallbackHandler), nil))
+}
+
+// OIDCProviders are the OpenID Connect providers from config, each needs its routes registered
+func (h *Handler) OIDCProviders() []*OIDCProvider {
+	return h.oidcProviders
+}
+
+func (h *Handler) OIDCLogin(provider *OIDCProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.oidcRedirect(w, r, provider, provider.CallbackPath(), "auth")
+	})
+}
+
+func (h *Handler) OIDCCallback(provider *OIDCProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.oidcCallbackHandler(w, r, provider)
+	})
+}
+
+func (h *Handler) OIDCLink(provider *OIDCProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.oidcRedirect(w, r, provider, provider.LinkCallbackPath(), "link")
+	})
+}
+
+func (h *Handler) OIDCLinkCallback(provider *OIDCProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.oidcLinkCallbackHandler(w, r, provider)
+	})
+}
+
+func (h *Handler) Merge() http.Handler {
+	return http.HandlerFunc(h.mergeHandler)
+}
+
 func (h *Handler) Logout() http.Handler {
 	return http.HandlerFunc(h.logoutHandler)
 }
 
+func (h *Handler) LogoutEverywhere() http.Handler {
+	return http.HandlerFunc(h.logoutEverywhereHandler)
+}
+
 func (h *Handler) RefreshToken() http.Handler {
 	return http.HandlerFunc(h.refreshTokenHandler)
 }
 
+func (h *Handler) JWKS() http.Handler {
+	return http.HandlerFunc(h.jwksHandler)
+}
+
 func (h *Handler) Me() http.Handler {
 	return http.HandlerFunc(h.meHandler)
 }
@@ -109,7 +198,7 @@ func (h *Handler) meHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.authService.ValidateJWT(cookie.Value)
+	user, err := h.authService.ValidateAccessToken(cookie.Value)
 	if err != nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
@@ -121,7 +210,43 @@ func (h *Handler) meHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
+// jwksHandler publishes the public keys tokens are signed with. Keys only change on a deploy, so
+// verifiers can cache them for a while and refetch when they see a kid they don't know.
+func (h *Handler) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.SendJSON(w, h.authService.JWKS())
+}
+
 func (h *Handler) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	// Revoke the refresh token so it can't be used again, the cookies are cleared either way
+	if cookie, err := r.Cookie("cinema-log-refresh-token"); err == nil {
+		if err := h.authService.Logout(r.Context(), cookie.Value); err != nil {
+			log.Printf("failed to revoke refresh token on logout: %v", err)
+		}
+	}
+
+	h.clearCookies(w)
+}
+
+// logoutEverywhereHandler revokes every refresh token the authenticated user has, on every device
+func (h *Handler) logoutEverywhereHandler(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.authService.LogoutEverywhere(r.Context(), user.ID); err != nil {
+		http.Error(w, "Failed to log out everywhere", http.StatusInternalServerError)
+		return
+	}
+
+	h.clearCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (*Handler) clearCookies(w http.ResponseWriter) {
 	// Clear cookies - settings must match how they were set
 	http.SetCookie(w, &http.Cookie{
 		Name:     "cinema-log-access-token",
@@ -189,25 +314,230 @@ func (h *Handler) googleCallbackHandler(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, fmt.Sprintf("%s/profile/%s", frontendURL, jwtResponse.User.ID), http.StatusTemporaryRedirect)
 }
 
-func (h *Handler) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("cinema-log-refresh-token")
+func (h *Handler) githubLinkCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	githubUser, err := github.UserFromContext(r.Context())
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		http.Redirect(w, r, fmt.Sprintf("%s/login?error=github_link_failed", os.Getenv("FRONTEND_URL")), http.StatusTemporaryRedirect)
+		return
+	}
+
+	h.linkIdentity(w, r, &domain.UserIdentity{
+		Provider:         users.ProviderGithub,
+		Subject:          strconv.FormatInt(githubUser.GetID(), 10),
+		ProviderUsername: githubUser.GetLogin(),
+	})
+}
+
+func (h *Handler) googleLinkCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	googleUser, err := google.UserFromContext(r.Context())
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s/login?error=google_link_failed", os.Getenv("FRONTEND_URL")), http.StatusTemporaryRedirect)
+		return
+	}
+
+	h.linkIdentity(w, r, &domain.UserIdentity{
+		Provider:         users.ProviderGoogle,
+		Subject:          googleUser.Id,
+		ProviderUsername: googleUser.Email,
+	})
+}
+
+// oidcRedirect sends the user to log in at the provider, remembering what the callback will need to
+// check the login it comes back with is this one
+func (h *Handler) oidcRedirect(w http.ResponseWriter, r *http.Request, provider *OIDCProvider, callbackPath string, flow string) {
+	login := &oidcLogin{State: rand.Text(), Nonce: rand.Text(), Verifier: oauth2.GenerateVerifier()}
+	authURL, err := provider.authCodeURL(r.Context(), callbackPath, login)
+	if err != nil {
+		log.Printf("failed to start %s login: %v", provider.Name(), err)
+		http.Redirect(w, r, fmt.Sprintf("%s/login?error=%s_%s_failed", os.Getenv("FRONTEND_URL"), provider.Name(), flow), http.StatusTemporaryRedirect)
+		return
+	}
+
+	value, err := json.Marshal(login)
+	if err != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   getCookieSecure(),
+		SameSite: getCookieSameSite(),
+		MaxAge:   cookieConf.MaxAge,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// oidcFinish checks the provider redirected back to the login we started and exchanges its code for
+// verified ID token claims. The login cookie is cleared either way, it can only be used once.
+func (h *Handler) oidcFinish(w http.ResponseWriter, r *http.Request, provider *OIDCProvider, callbackPath string) (*OIDCClaims, error) {
+	cookie, err := r.Cookie(oidcLoginCookie)
+	if err != nil {
+		return nil, ErrInvalidOIDCState
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   getCookieSecure(),
+		SameSite: getCookieSameSite(),
+	})
+
+	login := &oidcLogin{}
+	value, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || json.Unmarshal(value, login) != nil || login.State == "" {
+		return nil, ErrInvalidOIDCState
+	}
+	query := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(login.State)) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+	if providerErr := query.Get("error"); providerErr != "" {
+		return nil, fmt.Errorf("provider returned %s: %s", providerErr, query.Get("error_description"))
+	}
+
+	return provider.exchange(r.Context(), callbackPath, query.Get("code"), login)
+}
+
+func (h *Handler) oidcCallbackHandler(w http.ResponseWriter, r *http.Request, provider *OIDCProvider) {
+	ctx := r.Context()
+	frontendURL := os.Getenv("FRONTEND_URL")
+	claims, err := h.oidcFinish(w, r, provider, provider.CallbackPath())
+	if err != nil {
+		log.Printf("%s login failed: %v", provider.Name(), err)
+		http.Redirect(w, r, fmt.Sprintf("%s/login?error=%s_auth_failed", frontendURL, provider.Name()), http.StatusTemporaryRedirect)
+		return
+	}
+
+	jwtResponse, err := h.authService.HandleOIDCCallback(ctx, provider.Name(), claims)
+	if err != nil {
+		log.Printf("%s login failed: %v", provider.Name(), err)
+		http.Redirect(w, r, fmt.Sprintf("%s/login?error=%s_auth_failed", frontendURL, provider.Name()), http.StatusTemporaryRedirect)
 		return
 	}
 
-	user, err := h.authService.ValidateRefreshToken(cookie.Value)
+	h.setCookies(w, jwtResponse.Jwt, jwtResponse.RefreshToken)
+
+	// Redirect to user profile: http://localhost:4200/profile/{userId}
+	http.Redirect(w, r, fmt.Sprintf("%s/profile/%s", frontendURL, jwtResponse.User.ID), http.StatusTemporaryRedirect)
+}
+
+func (h *Handler) oidcLinkCallbackHandler(w http.ResponseWriter, r *http.Request, provider *OIDCProvider) {
+	claims, err := h.oidcFinish(w, r, provider, provider.LinkCallbackPath())
+	if err != nil {
+		log.Printf("%s link failed: %v", provider.Name(), err)
+		http.Redirect(w, r, fmt.Sprintf("%s/login?error=%s_link_failed", os.Getenv("FRONTEND_URL"), provider.Name()), http.StatusTemporaryRedirect)
+		return
+	}
+
+	h.linkIdentity(w, r, claims.identity(provider.Name()))
+}
+
+// linkIdentity links the login the provider just verified to the logged in user and redirects back to their
+// profile. If the login belongs to another account a merge token cookie is set, for POST /auth/merge to
+// confirm with.
+func (h *Handler) linkIdentity(w http.ResponseWriter, r *http.Request, identity *domain.UserIdentity) {
+	frontendURL := os.Getenv("FRONTEND_URL")
+
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s/login?error=%s_link_failed", frontendURL, identity.Provider), http.StatusTemporaryRedirect)
+		return
+	}
+	profileURL := fmt.Sprintf("%s/profile/%s?link=%s", frontendURL, user.ID, identity.Provider)
+
+	err := h.authService.LinkIdentity(r.Context(), user, identity)
+	if err == users.ErrIdentityLinkedToOtherUser {
+		mergeToken, err := h.authService.MergeToken(r.Context(), user, identity)
+		if err != nil {
+			http.Redirect(w, r, profileURL+"&error=link_failed", http.StatusTemporaryRedirect)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     "cinema-log-merge-token",
+			Value:    mergeToken,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   getCookieSecure(),
+			SameSite: getCookieSameSite(),
+			MaxAge:   int(MergeTokenTTL.Seconds()),
+		})
+		http.Redirect(w, r, profileURL+"&merge=required", http.StatusTemporaryRedirect)
+		return
+	}
+	if err == users.ErrProviderAlreadyLinked {
+		http.Redirect(w, r, profileURL+"&error=already_linked", http.StatusTemporaryRedirect)
+		return
+	}
+	if err != nil {
+		http.Redirect(w, r, profileURL+"&error=link_failed", http.StatusTemporaryRedirect)
+		return
+	}
+
+	http.Redirect(w, r, profileURL, http.StatusTemporaryRedirect)
+}
+
+// mergeHandler merges the account from the merge token cookie into the authenticated user's
+func (h *Handler) mergeHandler(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cookie, err := r.Cookie("cinema-log-merge-token")
+	if err != nil {
+		http.Error(w, ErrInvalidMergeToken.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.MergeAccounts(r.Context(), user, cookie.Value); err != nil {
+		if err == ErrInvalidMergeToken || err == users.ErrMergeSameUser {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err == users.ErrUserNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("failed to merge accounts: %v", err)
+		http.Error(w, "Failed to merge accounts", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "cinema-log-merge-token",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   getCookieSecure(),
+		SameSite: getCookieSameSite(),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("cinema-log-refresh-token")
 	if err != nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	jwt, refreshToken, err := h.authService.GenerateJWT(user)
+	jwtResponse, err := h.authService.RefreshTokens(r.Context(), cookie.Value)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err == ErrRefreshTokenReused {
+			// The family has been revoked, the cookies are no use to anyone now
+			h.clearCookies(w)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	h.setCookies(w, jwt, refreshToken)
+	h.setCookies(w, jwtResponse.Jwt, jwtResponse.RefreshToken)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -0,0 +1,359 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrNoSigningKeys      = errors.New("no token keys, set TOKEN_KEYS_DIR, TOKEN_KEYS or TOKEN_SECRET")
+	ErrNoSigningKey       = errors.New("no signing key, set TOKEN_SIGNING_KEY_ID to one of the private keys")
+	ErrUnknownKeyId       = errors.New("unknown key id")
+	ErrUnsupportedKeyType = errors.New("keys must be Ed25519 or RSA of at least 2048 bits")
+)
+
+const minRSAKeyBits = 2048
+
+// Key is one key tokens are signed or verified with. Asymmetric keys have an id that goes in the kid
+// header, the HS256 TOKEN_SECRET doesn't and only verifies tokens without one.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	private crypto.Signer // nil for keys that only verify
+	public  crypto.PublicKey
+	secret  []byte
+}
+
+// KeySet is the key new tokens are signed with and every key tokens are still accepted from. Rotating
+// is adding a new private key, making it the signing key, and removing the old one once the tokens it
+// signed have expired.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	legacy  *Key
+}
+
+// NewHMACKeySet signs and verifies with a shared HS256 secret, how tokens were signed before there
+// were asymmetric keys
+func NewHMACKeySet(secret []byte) *KeySet {
+	key := &Key{Method: jwt.SigningMethodHS256, secret: secret}
+	return &KeySet{signing: key, keys: map[string]*Key{}, legacy: key}
+}
+
+// NewKeySetFromEnv loads PEM keys from the files in TOKEN_KEYS_DIR, named <kid>.pem, or from the PEM
+// blocks in TOKEN_KEYS, which need a "kid" header. Private keys can sign, public keys only verify.
+// TOKEN_SIGNING_KEY_ID picks the signing key and can be left out when there's only one private key.
+//
+// Without either, tokens are signed with TOKEN_SECRET as before. With them, TOKEN_SECRET still
+// verifies tokens that have no kid so nobody is logged out by the switch.
+func NewKeySetFromEnv() (*KeySet, error) {
+	var keys []*Key
+	var err error
+	if dir := os.Getenv("TOKEN_KEYS_DIR"); dir != "" {
+		keys, err = loadKeyDir(dir)
+	} else if pemKeys := os.Getenv("TOKEN_KEYS"); pemKeys != "" {
+		keys, err = parsePEMKeys([]byte(pemKeys))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret := os.Getenv("TOKEN_SECRET")
+	if len(keys) == 0 {
+		if secret == "" {
+			return nil, ErrNoSigningKeys
+		}
+		return NewHMACKeySet([]byte(secret)), nil
+	}
+
+	keySet, err := NewKeySet(keys, os.Getenv("TOKEN_SIGNING_KEY_ID"))
+	if err != nil {
+		return nil, err
+	}
+	if secret != "" {
+		keySet.legacy = &Key{Method: jwt.SigningMethodHS256, secret: []byte(secret)}
+	}
+	return keySet, nil
+}
+
+// NewKeySet signs with the key with the given id, or the only private key when the id is empty
+func NewKeySet(keys []*Key, signingKeyId string) (*KeySet, error) {
+	keySet := &KeySet{keys: map[string]*Key{}}
+	var private []*Key
+	for _, key := range keys {
+		if _, ok := keySet.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		keySet.keys[key.ID] = key
+		if key.private != nil {
+			private = append(private, key)
+		}
+	}
+
+	if signingKeyId == "" {
+		if len(private) != 1 {
+			return nil, ErrNoSigningKey
+		}
+		keySet.signing = private[0]
+		return keySet, nil
+	}
+
+	key, ok := keySet.keys[signingKeyId]
+	if !ok || key.private == nil {
+		return nil, fmt.Errorf("%w: %q isn't a private key", ErrNoSigningKey, signingKeyId)
+	}
+	keySet.signing = key
+	return keySet, nil
+}
+
+// NewKey wraps an Ed25519 or RSA private or public key
+func NewKey(id string, key any) (*Key, error) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, public: k}, nil
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, ErrUnsupportedKeyType
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, private: k, public: k.Public()}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, ErrUnsupportedKeyType
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, public: k}, nil
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+}
+
+// loadKeyDir reads every .pem file in the directory, the file name without the extension is the kid
+func loadKeyDir(dir string) ([]*Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var keys []*Key
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key: %w", err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM block", path)
+		}
+
+		key, err := parsePEMBlock(strings.TrimSuffix(filepath.Base(path), ".pem"), block)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parsePEMKeys reads every PEM block, each needs a kid header
+func parsePEMKeys(data []byte) ([]*Key, error) {
+	var keys []*Key
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		kid := block.Headers["kid"]
+		if kid == "" {
+			return nil, fmt.Errorf("TOKEN_KEYS: %s block without a kid header", block.Type)
+		}
+		key, err := parsePEMBlock(kid, block)
+		if err != nil {
+			return nil, fmt.Errorf("TOKEN_KEYS: %s: %w", kid, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func parsePEMBlock(kid string, block *pem.Block) (*Key, error) {
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q, keys must be PKCS#8 or PKIX", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(kid, key)
+}
+
+// sign signs the claims with the signing key, putting its id in the kid header
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	if ks.signing.secret != nil {
+		return token.SignedString(ks.signing.secret)
+	}
+
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.private)
+}
+
+// keyFunc finds the key a token was signed with from its kid header. The key's own algorithm has to
+// match the token's, so a public key can't be used as an HMAC secret.
+func (ks *KeySet) keyFunc(token *jwt.Token) (any, error) {
+	key := ks.legacy
+	if kid, ok := token.Header["kid"]; ok {
+		id, _ := kid.(string)
+		key = ks.keys[id]
+	}
+	if key == nil {
+		return nil, ErrUnknownKeyId
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("token signed with %s, key %q is %s", token.Method.Alg(), key.ID, key.Method.Alg())
+	}
+
+	if key.secret != nil {
+		return key.secret, nil
+	}
+	return key.public, nil
+}
+
+// methods are the algorithms tokens can be signed with
+func (ks *KeySet) methods() []string {
+	var methods []string
+	seen := map[string]bool{}
+	for _, key := range ks.allKeys() {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+func (ks *KeySet) allKeys() []*Key {
+	keys := make([]*Key, 0, len(ks.keys)+1)
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	if ks.legacy != nil {
+		keys = append(keys, ks.legacy)
+	}
+	return keys
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS is every public key tokens are accepted from, sorted by id. The HS256 secret is never published.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		jwk := JWK{Kid: key.ID, Alg: key.Method.Alg(), Use: "sig"}
+		switch public := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv, jwk.X = "OKP", "Ed25519", base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
+
+// publicKey decodes a JWK someone else published, like an OpenID Connect provider. RSA, EC and Ed25519
+// keys are supported.
+func (jwk JWK) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, ErrUnsupportedKeyType
+		}
+		return key, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid EC point")
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("invalid EC point")
+		}
+		return key, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if jwk.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKeyType
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newEd25519Key(t *testing.T, id string) *Key {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, err := NewKey(id, private)
+	if err != nil {
+		t.Fatalf("failed to wrap key: %v", err)
+	}
+	return key
+}
+
+func newRSAKey(t *testing.T, id string) *Key {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, err := NewKey(id, private)
+	if err != nil {
+		t.Fatalf("failed to wrap key: %v", err)
+	}
+	return key
+}
+
+func mustKeySet(t *testing.T, keys []*Key, signingKeyId string) *KeySet {
+	t.Helper()
+	keySet, err := NewKeySet(keys, signingKeyId)
+	if err != nil {
+		t.Fatalf("failed to build key set: %v", err)
+	}
+	return keySet
+}
+
+func TestKeySet_SignAndVerify(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+
+	for _, key := range []*Key{newEd25519Key(t, "ed-1"), newRSAKey(t, "rsa-1")} {
+		t.Run(key.Method.Alg(), func(t *testing.T) {
+			service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, mustKeySet(t, []*Key{key}, ""))
+
+			accessToken, _, err := service.GenerateJWT(context.Background(), user)
+			if err != nil {
+				t.Fatalf("failed to generate tokens: %v", err)
+			}
+			if got, err := service.ValidateAccessToken(accessToken); err != nil || got.ID != user.ID {
+				t.Errorf("expected the token to be valid for %v, got %v with %v", user.ID, got, err)
+			}
+			token, _, err := jwt.NewParser().ParseUnverified(accessToken, &Claims{})
+			if err != nil || token.Header["kid"] != key.ID {
+				t.Errorf("expected a kid header for %q", key.ID)
+			}
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	oldKey, newKey := newEd25519Key(t, "2026-01"), newEd25519Key(t, "2026-10")
+
+	// Tokens signed before the rotation, with the old key
+	before := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, mustKeySet(t, []*Key{oldKey}, ""))
+	oldToken, _, err := before.GenerateJWT(context.Background(), user)
+	if err != nil {
+		t.Fatalf("failed to generate tokens: %v", err)
+	}
+
+	// The new key signs, the old one is still accepted
+	during := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, mustKeySet(t, []*Key{oldKey, newKey}, "2026-10"))
+	if _, err := during.ValidateAccessToken(oldToken); err != nil {
+		t.Errorf("expected a token from the old key to still be valid, got %v", err)
+	}
+
+	// Once the old key is removed its tokens are rejected
+	after := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, mustKeySet(t, []*Key{newKey}, ""))
+	if _, err := after.ValidateAccessToken(oldToken); !errors.Is(err, ErrUnknownKeyId) {
+		t.Errorf("expected %v, got %v", ErrUnknownKeyId, err)
+	}
+}
+
+func TestKeySet_RejectsAlgorithmConfusion(t *testing.T) {
+	key := newRSAKey(t, "rsa-1")
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, mustKeySet(t, []*Key{key}, ""))
+
+	// An HS256 token using the published RSA public key as its secret
+	publicKey := pemBlock(t, "PUBLIC KEY", key.public, "")
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	forged.Header["kid"] = key.ID
+	forgedToken, err := forged.SignedString(publicKey)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	if _, err := service.ValidateAccessToken(forgedToken); err == nil {
+		t.Error("expected an HS256 token to be rejected by an RSA key")
+	}
+}
+
+func TestNewKeySet_SigningKey(t *testing.T) {
+	first, second := newEd25519Key(t, "a"), newEd25519Key(t, "b")
+	publicOnly, _ := NewKey("c", first.public)
+
+	if _, err := NewKeySet([]*Key{first, second}, ""); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("expected %v with two private keys and no id, got %v", ErrNoSigningKey, err)
+	}
+	if _, err := NewKeySet([]*Key{first, publicOnly}, "c"); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("expected %v for a public key, got %v", ErrNoSigningKey, err)
+	}
+	if _, err := NewKeySet([]*Key{first, first}, "a"); err == nil {
+		t.Error("expected an error for duplicate key ids")
+	}
+	if keySet, err := NewKeySet([]*Key{first, second}, "b"); err != nil || keySet.signing != second {
+		t.Errorf("expected b to sign, got %v", err)
+	}
+}
+
+func TestNewKeySetFromEnv_Dir(t *testing.T) {
+	dir := t.TempDir()
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	writePEM(t, filepath.Join(dir, "current.pem"), "PRIVATE KEY", private)
+	retired, _, _ := ed25519.GenerateKey(rand.Reader)
+	writePEM(t, filepath.Join(dir, "retired.pem"), "PUBLIC KEY", retired)
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not a key"), 0o600)
+
+	t.Setenv("TOKEN_KEYS_DIR", dir)
+	t.Setenv("TOKEN_SIGNING_KEY_ID", "")
+	t.Setenv("TOKEN_SECRET", "")
+
+	keySet, err := NewKeySetFromEnv()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keySet.signing.ID != "current" || len(keySet.keys) != 2 || keySet.legacy != nil {
+		t.Errorf("expected current to sign and retired to verify, got %+v", keySet)
+	}
+}
+
+func TestNewKeySetFromEnv_PEM(t *testing.T) {
+	_, first, _ := ed25519.GenerateKey(rand.Reader)
+	_, second, _ := ed25519.GenerateKey(rand.Reader)
+	pemKeys := string(pemBlock(t, "PRIVATE KEY", first, "2026-01")) + string(pemBlock(t, "PRIVATE KEY", second, "2026-10"))
+
+	t.Setenv("TOKEN_KEYS_DIR", "")
+	t.Setenv("TOKEN_KEYS", pemKeys)
+	t.Setenv("TOKEN_SIGNING_KEY_ID", "2026-10")
+	t.Setenv("TOKEN_SECRET", "test-secret-key")
+
+	keySet, err := NewKeySetFromEnv()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keySet.signing.ID != "2026-10" || len(keySet.keys) != 2 {
+		t.Errorf("expected 2026-10 to sign, got %+v", keySet)
+	}
+
+	// Tokens signed with TOKEN_SECRET before the switch are still accepted
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, keySet)
+	legacyToken := signClaims(t, validClaims())
+	if _, err := service.ValidateAccessToken(legacyToken); err != nil {
+		t.Errorf("expected a legacy token to be valid, got %v", err)
+	}
+
+	t.Setenv("TOKEN_KEYS", string(pemBlock(t, "PRIVATE KEY", first, "")))
+	if _, err := NewKeySetFromEnv(); err == nil {
+		t.Error("expected an error for a block without a kid")
+	}
+}
+
+func TestHandler_JWKS(t *testing.T) {
+	edKey, rsaKey := newEd25519Key(t, "ed-1"), newRSAKey(t, "rsa-1")
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, mustKeySet(t, []*Key{rsaKey, edKey}, "ed-1"))
+	handler := NewHandler(service, nil)
+
+	w := httptest.NewRecorder()
+	handler.JWKS().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var jwks JWKS
+	if err := json.NewDecoder(w.Body).Decode(&jwks); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %+v", jwks.Keys)
+	}
+	edJWK, rsaJWK := jwks.Keys[0], jwks.Keys[1]
+	if edJWK.Kid != "ed-1" || edJWK.Kty != "OKP" || edJWK.Crv != "Ed25519" || edJWK.Alg != "EdDSA" || edJWK.X == "" {
+		t.Errorf("unexpected Ed25519 key %+v", edJWK)
+	}
+	if rsaJWK.Kid != "rsa-1" || rsaJWK.Kty != "RSA" || rsaJWK.Alg != "RS256" || rsaJWK.N == "" || rsaJWK.E != "AQAB" {
+		t.Errorf("unexpected RSA key %+v", rsaJWK)
+	}
+
+	// The shared secret is never published
+	if jwks := testKeys.JWKS(); len(jwks.Keys) != 0 {
+		t.Errorf("expected no keys for an HS256 key set, got %+v", jwks.Keys)
+	}
+}
+
+func validClaims() *Claims {
+	return newClaims(TokenTypeAccess, uuid.NewString(), "Test", "test", uuid.NewString(), time.Now(), time.Now().Add(time.Hour))
+}
+
+func pemBlock(t *testing.T, blockType string, key any, kid string) []byte {
+	t.Helper()
+	var der []byte
+	var err error
+	if blockType == "PRIVATE KEY" {
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+	} else {
+		der, err = x509.MarshalPKIXPublicKey(key)
+	}
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	block := &pem.Block{Type: blockType, Bytes: der}
+	if kid != "" {
+		block.Headers = map[string]string{"kid": kid}
+	}
+	return pem.EncodeToMemory(block)
+}
+
+func writePEM(t *testing.T, path, blockType string, key any) {
+	t.Helper()
+	if err := os.WriteFile(path, pemBlock(t, blockType, key, ""), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
@@ -0,0 +1,420 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/users"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrInvalidOIDCConfig = errors.New("invalid OIDC provider config")
+	ErrOIDCDiscovery     = errors.New("OIDC discovery failed")
+	ErrInvalidOIDCState  = errors.New("invalid OIDC login state")
+	ErrInvalidIDToken    = errors.New("invalid ID token")
+)
+
+const (
+	// How long a provider's discovery document is used before it's fetched again
+	oidcDiscoveryTTL = time.Hour
+	// An ID token with a kid we don't know makes us refetch the provider's keys, at most this often
+	oidcKeysMinRefresh = time.Minute
+	oidcClientTimeout  = 10 * time.Second
+	// Discovery documents and key sets are small, anything bigger isn't one
+	oidcMaxResponseBytes = 1 << 20
+)
+
+// Provider names go in the routes and in user_identities.provider
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// The routes under /auth that aren't an OpenID Connect provider's, see Server.RegisterRoutes. A provider
+// whose routes land on one of them would take it over.
+var fixedAuthPaths = []string{
+	"/auth/github-login", "/auth/github-callback", "/auth/github-link", "/auth/github-link-callback",
+	"/auth/google-login", "/auth/google-callback", "/auth/google-link", "/auth/google-link-callback",
+	"/auth/merge", "/auth/logout", "/auth/logout-all", "/auth/refresh-token", "/auth/me",
+	"/auth/dev/login", "/auth/dev/google-login",
+}
+
+// The algorithms ID tokens can be signed with. HMAC isn't one of them, the client secret isn't a key.
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// OIDCProviderConfig is one OpenID Connect provider, like a company's Keycloak realm
+type OIDCProviderConfig struct {
+	// Name is used in the routes, /auth/{name}-login and so on, and as the provider of the logins it creates
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	Scopes       []string `json:"scopes"`
+}
+
+// OIDCProvider logs users in with an OpenID Connect provider. Its endpoints come from the issuer's
+// discovery document and ID tokens are verified with the keys it publishes, both fetched the first
+// time someone logs in and cached.
+type OIDCProvider struct {
+	config          OIDCProviderConfig
+	client          *http.Client
+	clockSkew       time.Duration
+	keysMinRefresh  time.Duration
+	mu              sync.Mutex
+	discovery       *oidcDiscovery
+	discoveredAt    time.Time
+	keys            map[string]crypto.PublicKey
+	keysRefreshedAt time.Time
+}
+
+// oidcDiscovery is the part of the discovery document at {issuer}/.well-known/openid-configuration we use
+type oidcDiscovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// OIDCClaims are the ID token claims a user is logged in with
+type OIDCClaims struct {
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Email             string `json:"email"`
+	Picture           string `json:"picture"`
+	jwt.RegisteredClaims
+}
+
+// oidcLogin is what the callback needs to finish a login, kept in a cookie while the user is at the provider
+type oidcLogin struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// NewOIDCProvidersFromEnv reads a JSON list of OIDCProviderConfig from the file at OIDC_PROVIDERS_FILE,
+// or from OIDC_PROVIDERS. ${VAR} in the issuer, client id and client secret is expanded, so secrets can
+// stay in the environment. Without either there are no OpenID Connect providers.
+func NewOIDCProvidersFromEnv() ([]*OIDCProvider, error) {
+	raw := []byte(os.Getenv("OIDC_PROVIDERS"))
+	if path := os.Getenv("OIDC_PROVIDERS_FILE"); path != "" {
+		var err error
+		raw, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OIDC providers: %w", err)
+		}
+	}
+	if strings.TrimSpace(string(raw)) == "" {
+		return nil, nil
+	}
+
+	var configs []OIDCProviderConfig
+	if err := json.Unmarshal(raw, &configs); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOIDCConfig, err)
+	}
+
+	client := &http.Client{Timeout: oidcClientTimeout}
+	providers := make([]*OIDCProvider, 0, len(configs))
+	seen := map[string]bool{}
+	// Which provider each route belongs to, a provider called a-link would get the a provider's link callback
+	pathOwners := map[string]string{}
+	for _, config := range configs {
+		if seen[config.Name] {
+			return nil, fmt.Errorf("%w: duplicate provider %q", ErrInvalidOIDCConfig, config.Name)
+		}
+		seen[config.Name] = true
+
+		config.Issuer = os.ExpandEnv(config.Issuer)
+		config.ClientID = os.ExpandEnv(config.ClientID)
+		config.ClientSecret = os.ExpandEnv(config.ClientSecret)
+		provider, err := NewOIDCProvider(config, client)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range provider.paths() {
+			if owner, ok := pathOwners[path]; ok {
+				return nil, fmt.Errorf("%w: %q and %q both use %s", ErrInvalidOIDCConfig, owner, config.Name, path)
+			}
+			pathOwners[path] = config.Name
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// NewOIDCProvider checks the config, nothing is fetched from the provider until someone logs in
+func NewOIDCProvider(config OIDCProviderConfig, client *http.Client) (*OIDCProvider, error) {
+	if !oidcProviderName.MatchString(config.Name) {
+		return nil, fmt.Errorf("%w: name %q must be lowercase letters, digits and dashes", ErrInvalidOIDCConfig, config.Name)
+	}
+	// GitHub and Google have their own routes and logins
+	if config.Name == users.ProviderGithub || config.Name == users.ProviderGoogle {
+		return nil, fmt.Errorf("%w: name %q is taken", ErrInvalidOIDCConfig, config.Name)
+	}
+	issuer, err := url.Parse(config.Issuer)
+	if err != nil || issuer.Host == "" || (issuer.Scheme != "https" && issuer.Scheme != "http") {
+		return nil, fmt.Errorf("%w: %s needs an issuer URL", ErrInvalidOIDCConfig, config.Name)
+	}
+	if issuer.Scheme != "https" && isProduction() {
+		return nil, fmt.Errorf("%w: %s issuer must be https", ErrInvalidOIDCConfig, config.Name)
+	}
+	if config.ClientID == "" {
+		return nil, fmt.Errorf("%w: %s needs a client id", ErrInvalidOIDCConfig, config.Name)
+	}
+
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"profile", "email"}
+	}
+	if !slices.Contains(config.Scopes, "openid") {
+		config.Scopes = append([]string{"openid"}, config.Scopes...)
+	}
+
+	provider := &OIDCProvider{
+		config:         config,
+		client:         client,
+		clockSkew:      clockSkewFromEnv(),
+		keysMinRefresh: oidcKeysMinRefresh,
+	}
+	for _, path := range provider.paths() {
+		if slices.Contains(fixedAuthPaths, path) {
+			return nil, fmt.Errorf("%w: name %q would use %s", ErrInvalidOIDCConfig, config.Name, path)
+		}
+	}
+	return provider, nil
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+func (p *OIDCProvider) LoginPath() string {
+	return "/auth/" + p.config.Name + "-login"
+}
+
+func (p *OIDCProvider) CallbackPath() string {
+	return "/auth/" + p.config.Name + "-callback"
+}
+
+func (p *OIDCProvider) LinkPath() string {
+	return "/auth/" + p.config.Name + "-link"
+}
+
+func (p *OIDCProvider) LinkCallbackPath() string {
+	return "/auth/" + p.config.Name + "-link-callback"
+}
+
+func (p *OIDCProvider) paths() []string {
+	return []string{p.LoginPath(), p.CallbackPath(), p.LinkPath(), p.LinkCallbackPath()}
+}
+
+// authCodeURL is where to send the user to log in. The nonce comes back in the ID token and the PKCE
+// challenge is checked against the verifier when the code is exchanged, so a code or token from some
+// other login can't be used to finish this one.
+func (p *OIDCProvider) authCodeURL(ctx context.Context, callbackPath string, login *oidcLogin) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2Config(discovery, callbackPath).AuthCodeURL(login.State,
+		oauth2.S256ChallengeOption(login.Verifier),
+		oauth2.SetAuthURLParam("nonce", login.Nonce),
+	), nil
+}
+
+// exchange swaps the code the provider redirected back with for an ID token and verifies it
+func (p *OIDCProvider) exchange(ctx context.Context, callbackPath string, code string, login *oidcLogin) (*OIDCClaims, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauth2Config(discovery, callbackPath).Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.client),
+		code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in the token response", ErrInvalidIDToken)
+	}
+
+	return p.verifyIDToken(ctx, discovery, rawIDToken, login.Nonce)
+}
+
+func (p *OIDCProvider) oauth2Config(discovery *oidcDiscovery, callbackPath string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  BackendURL + callbackPath,
+		Scopes:       p.config.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+	}
+}
+
+// verifyIDToken checks the ID token was signed by the provider, for us, for this login
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, discovery *oidcDiscovery, rawIDToken string, nonce string) (*OIDCClaims, error) {
+	claims := &OIDCClaims{}
+	keyFunc := func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, discovery.JWKSURI, kid)
+	}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, keyFunc,
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(p.clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce doesn't match", ErrInvalidIDToken)
+	}
+	// A token for several clients has to say it was issued to us
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w: issued to %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+
+	return claims, nil
+}
+
+// discover fetches the issuer's discovery document, or returns the cached one
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil && time.Since(p.discoveredAt) < oidcDiscoveryTTL {
+		return p.discovery, nil
+	}
+
+	discovery := &oidcDiscovery{}
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.config.Issuer, "/")+"/.well-known/openid-configuration", discovery); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCDiscovery, err)
+	}
+	// The issuer has to be the one configured exactly, it's what the ID tokens are checked against
+	if discovery.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("%w: issuer is %q, expected %q", ErrOIDCDiscovery, discovery.Issuer, p.config.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: missing endpoints", ErrOIDCDiscovery)
+	}
+	if len(discovery.CodeChallengeMethods) > 0 && !slices.Contains(discovery.CodeChallengeMethods, "S256") {
+		return nil, fmt.Errorf("%w: provider doesn't support PKCE with S256", ErrOIDCDiscovery)
+	}
+
+	p.discovery, p.discoveredAt = discovery, time.Now()
+	return discovery, nil
+}
+
+// publicKey finds the provider's key with the given id. A kid we haven't seen is most likely a key the
+// provider has just rotated in, so the keys are fetched again unless they were fetched very recently.
+func (p *OIDCProvider) publicKey(ctx context.Context, jwksURI string, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, ok := p.lookupKey(kid)
+	if !ok && time.Since(p.keysRefreshedAt) >= p.keysMinRefresh {
+		var jwks JWKS
+		if err := p.getJSON(ctx, jwksURI, &jwks); err != nil {
+			return nil, fmt.Errorf("failed to fetch provider keys: %w", err)
+		}
+		keys := map[string]crypto.PublicKey{}
+		for _, jwk := range jwks.Keys {
+			// Providers publish encryption keys alongside signing keys, and keys we can't use
+			if jwk.Use != "" && jwk.Use != "sig" {
+				continue
+			}
+			if public, err := jwk.publicKey(); err == nil {
+				keys[jwk.Kid] = public
+			}
+		}
+		p.keys, p.keysRefreshedAt = keys, time.Now()
+		key, ok = p.lookupKey(kid)
+	}
+	if !ok {
+		return nil, ErrUnknownKeyId
+	}
+	return key, nil
+}
+
+// lookupKey finds a key by id. A token without a kid is only accepted when the provider has one key.
+func (p *OIDCProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" {
+		if len(p.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseBytes)).Decode(v)
+}
+
+// identity is the login the ID token is for. The provider's name is the provider, so the same subject
+// from two providers is two different logins.
+func (c *OIDCClaims) identity(provider string) *domain.UserIdentity {
+	return &domain.UserIdentity{
+		Provider:         provider,
+		Subject:          c.Subject,
+		ProviderUsername: c.username(),
+	}
+}
+
+func (c *OIDCClaims) username() string {
+	if c.PreferredUsername != "" {
+		return c.PreferredUsername
+	}
+	if c.Email != "" {
+		return c.Email
+	}
+	return c.Subject
+}
+
+func (c *OIDCClaims) displayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.username()
+}
@@ -0,0 +1,437 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// mockOIDCServer is an OpenID Connect provider that logs the same user in straight away. It checks the
+// requests it gets the way a real provider would, PKCE included.
+type mockOIDCServer struct {
+	*httptest.Server
+	clientID     string
+	clientSecret string
+	user         OIDCClaims
+
+	mu sync.Mutex
+	// What the discovery document says, the server's own URL and S256 unless a test changes them
+	issuer           string
+	challengeMethods []string
+	// keys are published, signingKey signs ID tokens
+	keys          []*Key
+	signingKey    *Key
+	modifyIDToken func(claims *OIDCClaims)
+	requests      map[string]url.Values
+	keyFetches    int
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	t.Helper()
+	key := newRSAKey(t, "rsa-1")
+	m := &mockOIDCServer{
+		clientID:         "cinema-log",
+		clientSecret:     "client-secret",
+		challengeMethods: []string{"S256"},
+		keys:             []*Key{key},
+		signingKey:       key,
+		requests:         map[string]url.Values{},
+		user: OIDCClaims{
+			Name:              "Kim Keycloak",
+			PreferredUsername: "kim",
+			Email:             "kim@example.com",
+			RegisteredClaims:  jwt.RegisteredClaims{Subject: "f0e1d2c3-b4a5"},
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", m.discoveryHandler)
+	mux.HandleFunc("GET /authorize", m.authorizeHandler)
+	mux.HandleFunc("POST /token", m.tokenHandler)
+	mux.HandleFunc("GET /jwks", m.jwksHandler)
+	m.Server = httptest.NewServer(mux)
+	m.issuer = m.URL
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockOIDCServer) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                           m.issuer,
+		"authorization_endpoint":           m.URL + "/authorize",
+		"token_endpoint":                   m.URL + "/token",
+		"jwks_uri":                         m.URL + "/jwks",
+		"code_challenge_methods_supported": m.challengeMethods,
+	})
+}
+
+func (m *mockOIDCServer) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != m.clientID || query.Get("response_type") != "code" ||
+		!slices.Contains(strings.Fields(query.Get("scope")), "openid") ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" || query.Get("nonce") == "" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	m.mu.Lock()
+	m.requests[code] = query
+	m.mu.Unlock()
+
+	redirectURL, _ := url.Parse(query.Get("redirect_uri"))
+	redirectURL.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+}
+
+func (m *mockOIDCServer) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != m.clientID || clientSecret != m.clientSecret {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Codes can only be used once
+	request, ok := m.requests[r.PostForm.Get("code")]
+	delete(m.requests, r.PostForm.Get("code"))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != request.Get("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(challenge[:]) != request.Get("code_challenge") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := m.user
+	claims.Nonce = request.Get("nonce")
+	claims.Issuer = m.issuer
+	claims.Audience = jwt.ClaimStrings{m.clientID}
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(5 * time.Minute))
+	if m.modifyIDToken != nil {
+		m.modifyIDToken(&claims)
+	}
+	idToken := jwt.NewWithClaims(m.signingKey.Method, &claims)
+	idToken.Header["kid"] = m.signingKey.ID
+	signed, err := idToken.SignedString(m.signingKey.private)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (m *mockOIDCServer) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keyFetches++
+	keySet, _ := NewKeySet(m.keys, m.keys[0].ID)
+	json.NewEncoder(w).Encode(keySet.JWKS())
+}
+
+func (m *mockOIDCServer) fetches() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.keyFetches
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func newOIDCTestHandler(t *testing.T, m *mockOIDCServer, userService *mockUserService) (*Handler, *OIDCProvider) {
+	t.Helper()
+	provider, err := NewOIDCProvider(OIDCProviderConfig{
+		Name:         "corp",
+		Issuer:       m.URL,
+		ClientID:     m.clientID,
+		ClientSecret: m.clientSecret,
+	}, m.Client())
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	service := NewService(userService, newMockRefreshTokenStore(), nil, testKeys)
+	return NewHandler(service, []*OIDCProvider{provider}), provider
+}
+
+// login goes through a whole login: the redirect to the provider, the provider sending the user straight
+// back, and the callback. modify can change the callback request before it's handled.
+func (m *mockOIDCServer) login(t *testing.T, start http.Handler, callback http.Handler, path string,
+	modify func(r *http.Request) *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	start.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected a redirect to the provider, got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("failed to reach the provider: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected the provider to redirect back, got %d", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, resp.Header.Get("Location"), nil)
+	for _, cookie := range w.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	if modify != nil {
+		req = modify(req)
+	}
+	w = httptest.NewRecorder()
+	callback.ServeHTTP(w, req)
+	return w
+}
+
+func responseCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name && cookie.MaxAge >= 0 {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestOIDC_Login(t *testing.T) {
+	m := newMockOIDCServer(t)
+	userId := uuid.New()
+	var identity *domain.UserIdentity
+	var name string
+	userService := &mockUserService{
+		getOrCreateUserByIdentityFunc: func(ctx context.Context, i *domain.UserIdentity, n, username, profilePicURL string) (*domain.User, error) {
+			identity, name = i, n
+			return &domain.User{ID: userId, Name: n, Username: username}, nil
+		},
+	}
+	handler, provider := newOIDCTestHandler(t, m, userService)
+
+	w := m.login(t, handler.OIDCLogin(provider), handler.OIDCCallback(provider), provider.LoginPath(), nil)
+
+	if w.Code != http.StatusTemporaryRedirect || !strings.HasSuffix(w.Header().Get("Location"), "/profile/"+userId.String()) {
+		t.Fatalf("expected a redirect to the profile, got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	if identity == nil || identity.Provider != "corp" || identity.Subject != "f0e1d2c3-b4a5" || identity.ProviderUsername != "kim" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+	if name != "Kim Keycloak" {
+		t.Errorf("expected the name from the ID token, got %q", name)
+	}
+
+	accessToken := responseCookie(w, "cinema-log-access-token")
+	if accessToken == nil {
+		t.Fatal("expected an access token cookie")
+	}
+	if user, err := handler.authService.ValidateAccessToken(accessToken.Value); err != nil || user.ID != userId {
+		t.Errorf("expected an access token for %v, got %v with %v", userId, user, err)
+	}
+	if responseCookie(w, oidcLoginCookie) != nil {
+		t.Error("expected the login cookie to be cleared")
+	}
+}
+
+func TestOIDC_Login_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(t *testing.T, m *mockOIDCServer)
+		modify func(r *http.Request) *http.Request
+	}{
+		{name: "nonce from another login", setup: func(t *testing.T, m *mockOIDCServer) {
+			m.modifyIDToken = func(claims *OIDCClaims) { claims.Nonce = "replayed" }
+		}},
+		{name: "token for another client", setup: func(t *testing.T, m *mockOIDCServer) {
+			m.modifyIDToken = func(claims *OIDCClaims) { claims.Audience = jwt.ClaimStrings{"someone-else"} }
+		}},
+		{name: "token for several clients", setup: func(t *testing.T, m *mockOIDCServer) {
+			m.modifyIDToken = func(claims *OIDCClaims) { claims.Audience = append(claims.Audience, "someone-else") }
+		}},
+		{name: "token from another issuer", setup: func(t *testing.T, m *mockOIDCServer) {
+			m.modifyIDToken = func(claims *OIDCClaims) { claims.Issuer = "https://elsewhere.example.com" }
+		}},
+		{name: "expired token", setup: func(t *testing.T, m *mockOIDCServer) {
+			m.modifyIDToken = func(claims *OIDCClaims) { claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }
+		}},
+		{name: "token without a subject", setup: func(t *testing.T, m *mockOIDCServer) {
+			m.modifyIDToken = func(claims *OIDCClaims) { claims.Subject = "" }
+		}},
+		{name: "token signed with another key under a published kid", setup: func(t *testing.T, m *mockOIDCServer) {
+			m.signingKey = newEd25519Key(t, "rsa-1")
+		}},
+		{name: "state from another login", modify: func(r *http.Request) *http.Request {
+			query := r.URL.Query()
+			query.Set("state", "other")
+			r.URL.RawQuery = query.Encode()
+			return r
+		}},
+		{name: "no login cookie", modify: func(r *http.Request) *http.Request {
+			r.Header.Del("Cookie")
+			return r
+		}},
+		{name: "verifier from another login", modify: func(r *http.Request) *http.Request {
+			cookie, _ := r.Cookie(oidcLoginCookie)
+			value, _ := base64.RawURLEncoding.DecodeString(cookie.Value)
+			login := &oidcLogin{}
+			json.Unmarshal(value, login)
+			login.Verifier = rand.Text() + rand.Text()
+			value, _ = json.Marshal(login)
+			r.Header.Del("Cookie")
+			r.AddCookie(&http.Cookie{Name: oidcLoginCookie, Value: base64.RawURLEncoding.EncodeToString(value)})
+			return r
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockOIDCServer(t)
+			if tt.setup != nil {
+				tt.setup(t, m)
+			}
+			userService := &mockUserService{
+				getOrCreateUserByIdentityFunc: func(ctx context.Context, i *domain.UserIdentity, n, username, profilePicURL string) (*domain.User, error) {
+					t.Error("expected no user to be logged in")
+					return &domain.User{ID: uuid.New()}, nil
+				},
+			}
+			handler, provider := newOIDCTestHandler(t, m, userService)
+
+			w := m.login(t, handler.OIDCLogin(provider), handler.OIDCCallback(provider), provider.LoginPath(), tt.modify)
+
+			if w.Code != http.StatusTemporaryRedirect || !strings.HasSuffix(w.Header().Get("Location"), "/login?error=corp_auth_failed") {
+				t.Errorf("expected a redirect to the login error, got %d to %q", w.Code, w.Header().Get("Location"))
+			}
+			if responseCookie(w, "cinema-log-access-token") != nil {
+				t.Error("expected no access token cookie")
+			}
+		})
+	}
+}
+
+func TestOIDC_Discovery_Rejected(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(m *mockOIDCServer)
+	}{
+		{"issuer doesn't match", func(m *mockOIDCServer) { m.issuer = "https://elsewhere.example.com" }},
+		{"no PKCE", func(m *mockOIDCServer) { m.challengeMethods = []string{"plain"} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockOIDCServer(t)
+			tt.setup(m)
+			handler, provider := newOIDCTestHandler(t, m, &mockUserService{})
+
+			w := httptest.NewRecorder()
+			handler.OIDCLogin(provider).ServeHTTP(w, httptest.NewRequest(http.MethodGet, provider.LoginPath(), nil))
+
+			if w.Code != http.StatusTemporaryRedirect || !strings.HasSuffix(w.Header().Get("Location"), "/login?error=corp_auth_failed") {
+				t.Errorf("expected a redirect to the login error, got %d to %q", w.Code, w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestOIDC_KeyRotation(t *testing.T) {
+	m := newMockOIDCServer(t)
+	handler, provider := newOIDCTestHandler(t, m, &mockUserService{})
+
+	if !loggedIn(t, m, handler, provider) || !loggedIn(t, m, handler, provider) {
+		t.Fatal("expected logging in to succeed")
+	}
+	if fetches := m.fetches(); fetches != 1 {
+		t.Errorf("expected the keys to be fetched once and cached, got %d fetches", fetches)
+	}
+
+	// The provider rotates in a new key
+	newKey := newEd25519Key(t, "ed-2")
+	m.mu.Lock()
+	m.keys, m.signingKey = append(m.keys, newKey), newKey
+	m.mu.Unlock()
+
+	// The keys were fetched moments ago, so an unknown kid doesn't make us fetch them again yet
+	if loggedIn(t, m, handler, provider) {
+		t.Error("expected the keys not to be refetched so soon")
+	}
+
+	provider.keysMinRefresh = 0
+	if !loggedIn(t, m, handler, provider) {
+		t.Error("expected a token from the new key to be accepted once the keys are refetched")
+	}
+	if fetches := m.fetches(); fetches != 2 {
+		t.Errorf("expected the keys to be fetched again, got %d fetches", fetches)
+	}
+}
+
+func loggedIn(t *testing.T, m *mockOIDCServer, handler *Handler, provider *OIDCProvider) bool {
+	t.Helper()
+	w := m.login(t, handler.OIDCLogin(provider), handler.OIDCCallback(provider), provider.LoginPath(), nil)
+	return responseCookie(w, "cinema-log-access-token") != nil
+}
+
+func TestOIDC_Link(t *testing.T) {
+	m := newMockOIDCServer(t)
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	var linked *domain.UserIdentity
+	userService := &mockUserService{
+		linkIdentityFunc: func(ctx context.Context, userId uuid.UUID, identity *domain.UserIdentity) error {
+			if userId != user.ID {
+				t.Errorf("expected the login to be linked to %v, got %v", user.ID, userId)
+			}
+			linked = identity
+			return nil
+		},
+	}
+	handler, provider := newOIDCTestHandler(t, m, userService)
+
+	w := m.login(t, handler.OIDCLink(provider), handler.OIDCLinkCallback(provider), provider.LinkPath(), func(r *http.Request) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), middleware.KeyUser, user))
+	})
+
+	if w.Code != http.StatusTemporaryRedirect || !strings.HasSuffix(w.Header().Get("Location"), "/profile/"+user.ID.String()+"?link=corp") {
+		t.Errorf("expected a redirect to the profile, got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	if linked == nil || linked.Provider != "corp" || linked.Subject != "f0e1d2c3-b4a5" {
+		t.Errorf("unexpected identity %+v", linked)
+	}
+	if responseCookie(w, "cinema-log-access-token") != nil {
+		t.Error("expected linking not to log anyone in")
+	}
+}
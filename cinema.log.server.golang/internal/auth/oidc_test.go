@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNewOIDCProvidersFromEnv(t *testing.T) {
+	providers := `[{"name": "corp", "issuer": "https://sso.example.com/realms/corp", "clientId": "cinema-log", "clientSecret": "${CORP_CLIENT_SECRET}"}]`
+	t.Setenv("CORP_CLIENT_SECRET", "s3cret")
+	t.Setenv("OIDC_PROVIDERS_FILE", "")
+	t.Setenv("OIDC_PROVIDERS", providers)
+
+	loaded, err := NewOIDCProvidersFromEnv()
+	if err != nil || len(loaded) != 1 {
+		t.Fatalf("expected one provider, got %v with %v", loaded, err)
+	}
+	provider := loaded[0]
+	if provider.config.ClientSecret != "s3cret" {
+		t.Errorf("expected the client secret to be expanded, got %q", provider.config.ClientSecret)
+	}
+	if !slices.Equal(provider.config.Scopes, []string{"openid", "profile", "email"}) {
+		t.Errorf("unexpected default scopes %v", provider.config.Scopes)
+	}
+	if provider.LoginPath() != "/auth/corp-login" || provider.LinkCallbackPath() != "/auth/corp-link-callback" {
+		t.Errorf("unexpected routes %q and %q", provider.LoginPath(), provider.LinkCallbackPath())
+	}
+
+	// The file takes precedence
+	path := filepath.Join(t.TempDir(), "oidc.json")
+	os.WriteFile(path, []byte(`[{"name": "a", "issuer": "https://a.example.com", "clientId": "x"}, {"name": "b", "issuer": "https://b.example.com", "clientId": "y", "scopes": ["groups"]}]`), 0o600)
+	t.Setenv("OIDC_PROVIDERS_FILE", path)
+	loaded, err = NewOIDCProvidersFromEnv()
+	if err != nil || len(loaded) != 2 || loaded[1].Name() != "b" {
+		t.Fatalf("expected the providers from the file, got %v with %v", loaded, err)
+	}
+	if !slices.Equal(loaded[1].config.Scopes, []string{"openid", "groups"}) {
+		t.Errorf("expected openid to be added to the scopes, got %v", loaded[1].config.Scopes)
+	}
+
+	t.Setenv("OIDC_PROVIDERS_FILE", "")
+	t.Setenv("OIDC_PROVIDERS", "")
+	if loaded, err := NewOIDCProvidersFromEnv(); err != nil || loaded != nil {
+		t.Errorf("expected no providers, got %v with %v", loaded, err)
+	}
+
+	t.Setenv("OIDC_PROVIDERS", `[{"name": "a", "issuer": "https://a.example.com", "clientId": "x"}, {"name": "a", "issuer": "https://b.example.com", "clientId": "y"}]`)
+	if _, err := NewOIDCProvidersFromEnv(); !errors.Is(err, ErrInvalidOIDCConfig) {
+		t.Errorf("expected %v for duplicate names, got %v", ErrInvalidOIDCConfig, err)
+	}
+
+	// a-link's callback is a's link callback
+	t.Setenv("OIDC_PROVIDERS", `[{"name": "a", "issuer": "https://a.example.com", "clientId": "x"}, {"name": "a-link", "issuer": "https://b.example.com", "clientId": "y"}]`)
+	if _, err := NewOIDCProvidersFromEnv(); !errors.Is(err, ErrInvalidOIDCConfig) {
+		t.Errorf("expected %v for clashing routes, got %v", ErrInvalidOIDCConfig, err)
+	}
+}
+
+func TestNewOIDCProvider_InvalidConfig(t *testing.T) {
+	valid := OIDCProviderConfig{Name: "corp", Issuer: "https://sso.example.com/realms/corp", ClientID: "cinema-log"}
+	if _, err := NewOIDCProvider(valid, http.DefaultClient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(config *OIDCProviderConfig)
+	}{
+		{"name with spaces", func(config *OIDCProviderConfig) { config.Name = "Corp SSO" }},
+		{"name taken by GitHub", func(config *OIDCProviderConfig) { config.Name = "github" }},
+		{"name taken by Google", func(config *OIDCProviderConfig) { config.Name = "google" }},
+		{"callback taken by GitHub linking", func(config *OIDCProviderConfig) { config.Name = "github-link" }},
+		{"callback taken by Google linking", func(config *OIDCProviderConfig) { config.Name = "google-link" }},
+		{"no issuer", func(config *OIDCProviderConfig) { config.Issuer = "" }},
+		{"relative issuer", func(config *OIDCProviderConfig) { config.Issuer = "sso.example.com" }},
+		{"no client id", func(config *OIDCProviderConfig) { config.ClientID = "" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+			if _, err := NewOIDCProvider(config, http.DefaultClient); !errors.Is(err, ErrInvalidOIDCConfig) {
+				t.Errorf("expected %v, got %v", ErrInvalidOIDCConfig, err)
+			}
+		})
+	}
+
+	t.Run("http issuer in production", func(t *testing.T) {
+		t.Setenv("ENVIRONMENT", "production")
+		config := valid
+		config.Issuer = "http://sso.example.com"
+		if _, err := NewOIDCProvider(config, http.DefaultClient); !errors.Is(err, ErrInvalidOIDCConfig) {
+			t.Errorf("expected %v, got %v", ErrInvalidOIDCConfig, err)
+		}
+	})
+}
+
+func TestJWK_publicKey(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecJWK := JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+		Y:   base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()),
+	}
+	public, err := ecJWK.publicKey()
+	if err != nil || !ecKey.PublicKey.Equal(public) {
+		t.Errorf("expected the EC key back, got %v with %v", public, err)
+	}
+
+	// The keys we publish ourselves decode too
+	for _, jwk := range mustKeySet(t, []*Key{newRSAKey(t, "rsa"), newEd25519Key(t, "ed")}, "ed").JWKS().Keys {
+		if _, err := jwk.publicKey(); err != nil {
+			t.Errorf("expected %s key to decode, got %v", jwk.Kty, err)
+		}
+	}
+
+	smallRSA, _ := rsa.GenerateKey(rand.Reader, 1024)
+	offCurve := ecJWK
+	offCurve.Y = offCurve.X
+	invalid := map[string]JWK{
+		"small RSA key":       {Kty: "RSA", N: base64.RawURLEncoding.EncodeToString(smallRSA.N.Bytes()), E: "AQAB"},
+		"point off the curve": offCurve,
+		"unknown curve":       {Kty: "EC", Crv: "P-192", X: ecJWK.X, Y: ecJWK.Y},
+		"unknown type":        {Kty: "oct"},
+	}
+	for name, jwk := range invalid {
+		if _, err := jwk.publicKey(); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+}
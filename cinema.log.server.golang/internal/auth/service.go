@@ -2,22 +2,53 @@ package auth
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/ratings"
 	"cinema.log.server.golang/internal/users"
 	"cinema.log.server.golang/internal/utils"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/go-github/v52/github"
+	"github.com/google/uuid"
 	google2 "google.golang.org/api/oauth2/v2"
 )
 
-var tokenSecret = os.Getenv("TOKEN_SECRET")
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+	ErrInvalidMergeToken   = errors.New("invalid merge token")
+)
+
+const (
+	AccessTokenTTL  = 24 * time.Hour
+	RefreshTokenTTL = 7 * 24 * time.Hour
+	// How long after proving they own another account a user has to confirm merging it
+	MergeTokenTTL = 10 * time.Minute
+)
 
 type AuthService struct {
-	userService users.UserService
+	userService   users.UserService
+	refreshTokens RefreshTokenStore
+	ratings       RatingReplayer
+	keys          *KeySet
+	clockSkew     time.Duration
+}
+
+// RatingReplayer recomputes a user's ratings from their comparisons, which a merge leaves out of date
+type RatingReplayer interface {
+	ReplayRatings(ctx context.Context, userId uuid.UUID, dryRun bool) (*ratings.ReplayReport, error)
+}
+
+type RefreshTokenStore interface {
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, tokenId uuid.UUID, next *domain.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyId uuid.UUID, revokedAt time.Time) error
+	RevokeUserRefreshTokens(ctx context.Context, userId uuid.UUID, revokedAt time.Time) error
 }
 
 type JwtResponse struct {
@@ -26,9 +57,13 @@ type JwtResponse struct {
 	RefreshToken string
 }
 
-func NewService(userService users.UserService) *AuthService {
+func NewService(userService users.UserService, refreshTokens RefreshTokenStore, ratings RatingReplayer, keys *KeySet) *AuthService {
 	return &AuthService{
-		userService: userService,
+		userService:   userService,
+		refreshTokens: refreshTokens,
+		ratings:       ratings,
+		keys:          keys,
+		clockSkew:     clockSkewFromEnv(),
 	}
 }
 
@@ -40,7 +75,7 @@ func (s *AuthService) HandleGithubCallback(ctx context.Context, githubUser *gith
 		return nil, fmt.Errorf("failed to get or create user: %w", err)
 	}
 
-	jwt, refreshToken, err := s.GenerateJWT(user)
+	jwt, refreshToken, err := s.GenerateJWT(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate JWT: %w", err)
 	}
@@ -60,7 +95,7 @@ func (s *AuthService) HandleGoogleCallback(ctx context.Context, googleUser *goog
 		return nil, fmt.Errorf("failed to get or create user: %w", err)
 	}
 
-	jwt, refreshToken, err := s.GenerateJWT(user)
+	jwt, refreshToken, err := s.GenerateJWT(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate JWT: %w", err)
 	}
@@ -72,99 +107,224 @@ func (s *AuthService) HandleGoogleCallback(ctx context.Context, googleUser *goog
 	}, nil
 }
 
-func (s *AuthService) GenerateJWT(user *domain.User) (string, string, error) {
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":       user.ID.String(),
-		"name":     user.Name,
-		"username": user.Username,
-		"iss":      "cinema.log.server.golang",
-		"aud":      "cinema.log.client",
-		"exp":      time.Now().Add(time.Hour * 24).Unix(), // 1 day expiration
-	})
+// HandleOIDCCallback logs in with a verified ID token from one of the OpenID Connect providers
+func (s *AuthService) HandleOIDCCallback(ctx context.Context, provider string, claims *OIDCClaims) (*JwtResponse, error) {
+	identity := claims.identity(provider)
+	user, err := s.userService.GetOrCreateUserByIdentity(ctx, identity,
+		claims.displayName(), identity.ProviderUsername, claims.Picture)
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":       user.ID.String(),
-		"name":     user.Name,
-		"username": user.Username,
-		"iss":      "cinema.log.server.golang",
-		"aud":      "cinema.log.client",
-		"exp":      time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 day expiration
-	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get or create user: %w", err)
+	}
 
-	// Sign and get the complete encoded token as a string using the secret
-	jwtTokenString, err := jwtToken.SignedString([]byte(tokenSecret))
+	jwt, refreshToken, err := s.GenerateJWT(ctx, user)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to sign JWT token: %w", err)
+		return nil, fmt.Errorf("failed to generate JWT: %w", err)
 	}
 
-	refreshTokenString, err := refreshToken.SignedString([]byte(tokenSecret))
+	return &JwtResponse{
+		User:         user,
+		Jwt:          jwt,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// GenerateJWT signs an access token and a refresh token for a user who has just logged in. The refresh
+// token starts a new family that every later refresh rotates within.
+func (s *AuthService) GenerateJWT(ctx context.Context, user *domain.User) (string, string, error) {
+	jwtTokenString, refreshTokenString, record, err := s.newTokens(user, uuid.New())
 	if err != nil {
-		return "", "", fmt.Errorf("failed to sign refresh token: %w", err)
+		return "", "", err
+	}
+
+	if err := s.refreshTokens.CreateRefreshToken(ctx, record); err != nil {
+		return "", "", fmt.Errorf("failed to store refresh token: %w", err)
 	}
 
 	return jwtTokenString, refreshTokenString, nil
 }
 
-func (s *AuthService) ValidateJWT(tkn string) (*domain.User, error) {
-	ctx := context.Background()
-	token, err := jwt.Parse(tkn, func(token *jwt.Token) (any, error) {
-		return []byte(tokenSecret), nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+// newTokens signs a new pair of tokens and returns the record for the refresh token, which isn't stored yet
+func (s *AuthService) newTokens(user *domain.User, familyId uuid.UUID) (string, string, *domain.RefreshToken, error) {
+	now := time.Now()
+	record := &domain.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		FamilyID:  familyId,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(RefreshTokenTTL),
+	}
+
+	subject := user.ID.String()
+	jwtTokenString, err := s.keys.sign(newClaims(TokenTypeAccess, subject, user.Name, user.Username, uuid.NewString(), now, now.Add(AccessTokenTTL)))
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to sign JWT token: %w", err)
+	}
+
+	// The refresh token's jti is the id of its record, which also makes every refresh token unique so
+	// its hash identifies it
+	refreshTokenString, err := s.keys.sign(newClaims(TokenTypeRefresh, subject, user.Name, user.Username, record.ID.String(), now, record.ExpiresAt))
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to sign refresh token: %w", err)
+	}
+	record.TokenHash = hashToken(refreshTokenString)
+
+	return jwtTokenString, refreshTokenString, record, nil
+}
+
+// hashToken is how a refresh token is looked up without storing it
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshTokens swaps a refresh token for a new pair of tokens. The refresh token is rotated so it can
+// only be used once, and if a token that's already been rotated comes back the whole family is
+// revoked, whoever holds it and whoever rotated it both have to log in again.
+func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*JwtResponse, error) {
+	user, err := s.ValidateRefreshToken(refreshToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, ok := claims["id"].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid token claims: missing user ID")
+	record, err := s.refreshTokens.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if err == ErrRefreshTokenNotFound {
+			return nil, ErrInvalidRefreshToken
 		}
+		return nil, err
+	}
+	if record.UserID != user.ID || record.RevokedAt != nil || time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if record.RotatedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, record)
+	}
 
-		userUuid, err := utils.ParseUUID(userID)
-		if err != nil {
-			return nil, fmt.Errorf("invalid token claims: %w", err)
+	jwtTokenString, refreshTokenString, next, err := s.newTokens(user, record.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another request may have rotated the token since it was read
+	if err := s.refreshTokens.RotateRefreshToken(ctx, record.ID, next); err != nil {
+		if err == ErrRefreshTokenReused {
+			return nil, s.revokeReusedFamily(ctx, record)
 		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
 
-		user, err := s.userService.GetUserById(ctx, userUuid)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get user by ID: %w", err)
+	return &JwtResponse{
+		User:         user,
+		Jwt:          jwtTokenString,
+		RefreshToken: refreshTokenString,
+	}, nil
+}
+
+func (s *AuthService) revokeReusedFamily(ctx context.Context, record *domain.RefreshToken) error {
+	if err := s.refreshTokens.RevokeRefreshTokenFamily(ctx, record.FamilyID, time.Now()); err != nil {
+		return fmt.Errorf("failed to revoke reused refresh token family: %w", err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// Logout revokes the refresh token's family so it can't be refreshed again. Tokens the server
+// doesn't know about have nothing to revoke.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+
+	record, err := s.refreshTokens.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if err == ErrRefreshTokenNotFound {
+			return nil
 		}
+		return err
+	}
+
+	return s.refreshTokens.RevokeRefreshTokenFamily(ctx, record.FamilyID, time.Now())
+}
+
+// LogoutEverywhere revokes every refresh token the user has. Access tokens already handed out stay
+// valid until they expire.
+func (s *AuthService) LogoutEverywhere(ctx context.Context, userId uuid.UUID) error {
+	return s.refreshTokens.RevokeUserRefreshTokens(ctx, userId, time.Now())
+}
 
-		return user, nil
+// LinkIdentity adds another login to the user's account
+func (s *AuthService) LinkIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error {
+	return s.userService.LinkIdentity(ctx, user.ID, identity)
+}
+
+// MergeToken is for when the login being linked already belongs to another account. Logging in with it
+// proves the user owns that account too, the token lets them confirm merging it into their own.
+func (s *AuthService) MergeToken(ctx context.Context, user *domain.User, identity *domain.UserIdentity) (string, error) {
+	owner, err := s.userService.GetUserByIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return "", err
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	now := time.Now()
+	claims := newClaims(TokenTypeMerge, user.ID.String(), user.Name, user.Username, uuid.NewString(), now, now.Add(MergeTokenTTL))
+	claims.MergeFrom = owner.ID.String()
+	return s.keys.sign(claims)
 }
 
-func (s *AuthService) ValidateRefreshToken(tkn string) (*domain.User, error) {
-	ctx := context.Background()
-	token, err := jwt.Parse(tkn, func(token *jwt.Token) (any, error) {
-		return []byte(tokenSecret), nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+// MergeAccounts merges the account in the merge token into the user's own. Everything the other account
+// has moves over, the other account is deleted and the user's ratings are replayed from the combined
+// comparisons.
+func (s *AuthService) MergeAccounts(ctx context.Context, user *domain.User, mergeToken string) error {
+	claims, err := parseClaims(mergeToken, TokenTypeMerge, s.keys, s.clockSkew)
+	if err != nil || claims.Subject != user.ID.String() {
+		return ErrInvalidMergeToken
+	}
+	sourceId, err := utils.ParseUUID(claims.MergeFrom)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
+		return ErrInvalidMergeToken
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, ok := claims["id"].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid token claims: missing user ID")
-		}
+	if err := s.userService.MergeUsers(ctx, sourceId, user.ID); err != nil {
+		return err
+	}
 
-		userUuid, err := utils.ParseUUID(userID)
-		if err != nil {
-			return nil, fmt.Errorf("invalid token claims: %w", err)
-		}
+	if _, err := s.ratings.ReplayRatings(ctx, user.ID, false); err != nil {
+		return fmt.Errorf("accounts merged but ratings could not be replayed: %w", err)
+	}
+	return nil
+}
 
-		user, err := s.userService.GetUserById(ctx, userUuid)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get user by ID: %w", err)
-		}
+// ValidateAccessToken returns the user an access token was issued to. Refresh tokens are rejected.
+func (s *AuthService) ValidateAccessToken(tkn string) (*domain.User, error) {
+	claims, err := parseClaims(tkn, TokenTypeAccess, s.keys, s.clockSkew)
+	if err != nil {
+		return nil, err
+	}
+	return s.userFromClaims(claims)
+}
+
+// ValidateRefreshToken returns the user a refresh token was issued to. Access tokens are rejected.
+// This only checks the token itself, RefreshTokens also checks it hasn't been rotated or revoked.
+func (s *AuthService) ValidateRefreshToken(tkn string) (*domain.User, error) {
+	claims, err := parseClaims(tkn, TokenTypeRefresh, s.keys, s.clockSkew)
+	if err != nil {
+		return nil, err
+	}
+	return s.userFromClaims(claims)
+}
+
+func (s *AuthService) userFromClaims(claims *Claims) (*domain.User, error) {
+	userUuid, err := utils.ParseUUID(claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
 
-		return user, nil
+	user, err := s.userService.GetUserById(context.Background(), userUuid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	return user, nil
 }
 
 func (s *AuthService) HandleDevLogin(ctx context.Context) (*JwtResponse, error) {
@@ -173,7 +333,7 @@ func (s *AuthService) HandleDevLogin(ctx context.Context) (*JwtResponse, error)
 		return nil, fmt.Errorf("failed to get first user: %w", err)
 	}
 
-	jwt, refreshToken, err := s.GenerateJWT(user)
+	jwt, refreshToken, err := s.GenerateJWT(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate JWT: %w", err)
 	}
@@ -191,7 +351,7 @@ func (s *AuthService) HandleDevGoogleLogin(ctx context.Context) (*JwtResponse, e
 		return nil, fmt.Errorf("failed to get or create dev google user: %w", err)
 	}
 
-	jwt, refreshToken, err := s.GenerateJWT(user)
+	jwt, refreshToken, err := s.GenerateJWT(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate JWT: %w", err)
 	}
@@ -202,3 +362,8 @@ func (s *AuthService) HandleDevGoogleLogin(ctx context.Context) (*JwtResponse, e
 		RefreshToken: refreshToken,
 	}, nil
 }
+
+// JWKS is the public keys other services can verify our tokens with
+func (s *AuthService) JWKS() JWKS {
+	return s.keys.JWKS()
+}
@@ -5,12 +5,19 @@ import (
 	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/ratings"
 	"github.com/google/uuid"
 )
 
-type mockUserService struct{}
+type mockUserService struct {
+	getOrCreateUserByIdentityFunc func(ctx context.Context, identity *domain.UserIdentity, name, username, profilePicURL string) (*domain.User, error)
+	getUserByIdentityFunc         func(ctx context.Context, provider string, subject string) (*domain.User, error)
+	linkIdentityFunc              func(ctx context.Context, userId uuid.UUID, identity *domain.UserIdentity) error
+	mergeUsersFunc                func(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error
+}
 
 func (m *mockUserService) GetOrCreateUserByGithubId(ctx context.Context, githubId int64, name, username, profilePicURL string) (*domain.User, error) {
 	return &domain.User{ID: uuid.New(), Name: name, Username: username}, nil
@@ -40,6 +47,87 @@ func (m *mockUserService) DeleteUser(ctx context.Context, userId uuid.UUID) erro
 	return errors.New("not implemented")
 }
 
+func (m *mockUserService) GetOrCreateUserByIdentity(ctx context.Context, identity *domain.UserIdentity, name, username, profilePicURL string) (*domain.User, error) {
+	if m.getOrCreateUserByIdentityFunc != nil {
+		return m.getOrCreateUserByIdentityFunc(ctx, identity, name, username, profilePicURL)
+	}
+	return &domain.User{ID: uuid.New(), Name: name, Username: username}, nil
+}
+
+func (m *mockUserService) GetUserByIdentity(ctx context.Context, provider string, subject string) (*domain.User, error) {
+	return m.getUserByIdentityFunc(ctx, provider, subject)
+}
+
+func (m *mockUserService) GetIdentities(ctx context.Context, userId uuid.UUID) ([]domain.UserIdentity, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockUserService) LinkIdentity(ctx context.Context, userId uuid.UUID, identity *domain.UserIdentity) error {
+	return m.linkIdentityFunc(ctx, userId, identity)
+}
+
+func (m *mockUserService) UnlinkIdentity(ctx context.Context, userId uuid.UUID, provider string) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockUserService) MergeUsers(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error {
+	return m.mergeUsersFunc(ctx, sourceId, targetId)
+}
+
+// mockRefreshTokenStore keeps refresh tokens in memory
+type mockRefreshTokenStore struct {
+	tokens map[uuid.UUID]*domain.RefreshToken
+}
+
+func newMockRefreshTokenStore() *mockRefreshTokenStore {
+	return &mockRefreshTokenStore{tokens: map[uuid.UUID]*domain.RefreshToken{}}
+}
+
+func (m *mockRefreshTokenStore) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	m.tokens[token.ID] = token
+	return nil
+}
+
+func (m *mockRefreshTokenStore) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, ErrRefreshTokenNotFound
+}
+
+func (m *mockRefreshTokenStore) RotateRefreshToken(ctx context.Context, tokenId uuid.UUID, next *domain.RefreshToken) error {
+	token := m.tokens[tokenId]
+	if token.RotatedAt != nil || token.RevokedAt != nil {
+		return ErrRefreshTokenReused
+	}
+	token.RotatedAt, token.ReplacedByID = &next.IssuedAt, &next.ID
+	m.tokens[next.ID] = next
+	return nil
+}
+
+func (m *mockRefreshTokenStore) RevokeRefreshTokenFamily(ctx context.Context, familyId uuid.UUID, revokedAt time.Time) error {
+	for _, token := range m.tokens {
+		if token.FamilyID == familyId && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (m *mockRefreshTokenStore) RevokeUserRefreshTokens(ctx context.Context, userId uuid.UUID, revokedAt time.Time) error {
+	for _, token := range m.tokens {
+		if token.UserID == userId && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+var testKeys = NewHMACKeySet([]byte("test-secret-key"))
+
 func TestMain(m *testing.M) {
 	os.Setenv("TOKEN_SECRET", "test-secret-key")
 	code := m.Run()
@@ -47,14 +135,14 @@ func TestMain(m *testing.M) {
 }
 
 func TestAuthService_GenerateJWT(t *testing.T) {
-	service := NewService(&mockUserService{})
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
 	user := &domain.User{
 		ID:       uuid.New(),
 		Name:     "Test User",
 		Username: "testuser",
 	}
 
-	jwtToken, refreshToken, err := service.GenerateJWT(user)
+	jwtToken, refreshToken, err := service.GenerateJWT(context.Background(), user)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -68,7 +156,7 @@ func TestAuthService_GenerateJWT(t *testing.T) {
 	}
 }
 
-func TestAuthService_ValidateJWT_Success(t *testing.T) {
+func TestAuthService_ValidateAccessToken_Success(t *testing.T) {
 	userId := uuid.New()
 	expectedUser := &domain.User{
 		ID:       userId,
@@ -76,14 +164,14 @@ func TestAuthService_ValidateJWT_Success(t *testing.T) {
 		Username: "testuser",
 	}
 
-	service := NewService(&mockUserService{})
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
 
-	jwtToken, _, err := service.GenerateJWT(expectedUser)
+	jwtToken, _, err := service.GenerateJWT(context.Background(), expectedUser)
 	if err != nil {
 		t.Fatalf("failed to generate JWT: %v", err)
 	}
 
-	user, err := service.ValidateJWT(jwtToken)
+	user, err := service.ValidateAccessToken(jwtToken)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -93,75 +181,56 @@ func TestAuthService_ValidateJWT_Success(t *testing.T) {
 	}
 }
 
-func TestAuthService_ValidateJWT_InvalidToken(t *testing.T) {
-	service := NewService(&mockUserService{})
+func TestAuthService_ValidateAccessToken_InvalidToken(t *testing.T) {
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
 
-	_, err := service.ValidateJWT("invalid.token.string")
+	_, err := service.ValidateAccessToken("invalid.token.string")
 	if err == nil {
 		t.Fatal("expected error for invalid token")
 	}
 }
 
-func TestAuthService_ValidateJWT_EmptyToken(t *testing.T) {
-	service := NewService(&mockUserService{})
+func TestAuthService_ValidateAccessToken_EmptyToken(t *testing.T) {
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
 
-	_, err := service.ValidateJWT("")
+	_, err := service.ValidateAccessToken("")
 	if err == nil {
 		t.Fatal("expected error for empty token")
 	}
 }
 
-func TestAuthService_GenerateJWT_NoSecret(t *testing.T) {
-	// Save current secret
-	oldSecret := os.Getenv("TOKEN_SECRET")
-	os.Unsetenv("TOKEN_SECRET")
-	defer os.Setenv("TOKEN_SECRET", oldSecret)
+func TestNewKeySetFromEnv_NoSecret(t *testing.T) {
+	t.Setenv("TOKEN_SECRET", "")
+	t.Setenv("TOKEN_KEYS_DIR", "")
+	t.Setenv("TOKEN_KEYS", "")
 
-	service := NewService(&mockUserService{})
-	user := &domain.User{
-		ID:       uuid.New(),
-		Name:     "Test User",
-		Username: "testuser",
+	if _, err := NewKeySetFromEnv(); err != ErrNoSigningKeys {
+		t.Errorf("expected %v, got %v", ErrNoSigningKeys, err)
 	}
-
-	// Note: The implementation may use a default secret or handle this case
-	// so this might not always fail
-	_, _, err := service.GenerateJWT(user)
-	// We just verify that the function was called
-	// The actual behavior depends on implementation details
-	t.Logf("Generate JWT result with no TOKEN_SECRET: %v", err)
 }
 
-func TestAuthService_ValidateJWT_NoSecret(t *testing.T) {
-	// First generate a token with secret
-	service := NewService(&mockUserService{})
+func TestAuthService_ValidateAccessToken_OtherSecret(t *testing.T) {
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
 	user := &domain.User{
 		ID:       uuid.New(),
 		Name:     "Test User",
 		Username: "testuser",
 	}
 
-	jwtToken, _, err := service.GenerateJWT(user)
+	jwtToken, _, err := service.GenerateJWT(context.Background(), user)
 	if err != nil {
 		t.Fatalf("failed to generate JWT: %v", err)
 	}
 
-	// Now try to validate with no secret
-	oldSecret := os.Getenv("TOKEN_SECRET")
-	os.Unsetenv("TOKEN_SECRET")
-	defer os.Setenv("TOKEN_SECRET", oldSecret)
-
-	// Note: The implementation may use a default or cached secret,
-	// so this might not always fail
-	_, err = service.ValidateJWT(jwtToken)
-	// We just verify that validation was attempted
-	// The actual behavior depends on implementation details
-	t.Logf("Validation result with no TOKEN_SECRET: %v", err)
+	other := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, NewHMACKeySet([]byte("another-secret")))
+	if _, err := other.ValidateAccessToken(jwtToken); err == nil {
+		t.Error("expected a token signed with another secret to be rejected")
+	}
 }
 
 func TestAuthService_NewService(t *testing.T) {
 	mockService := &mockUserService{}
-	service := NewService(mockService)
+	service := NewService(mockService, newMockRefreshTokenStore(), nil, testKeys)
 
 	if service == nil {
 		t.Fatal("expected non-nil service")
@@ -169,3 +238,180 @@ func TestAuthService_NewService(t *testing.T) {
 	// Note: userService is unexported, so we can't directly access it
 	// We just verify that NewService returns a non-nil value
 }
+
+func TestAuthService_RefreshTokens_Rotates(t *testing.T) {
+	store := newMockRefreshTokenStore()
+	service := NewService(&mockUserService{}, store, nil, testKeys)
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+
+	_, refreshToken, err := service.GenerateJWT(context.Background(), user)
+	if err != nil {
+		t.Fatalf("failed to generate JWT: %v", err)
+	}
+
+	refreshed, err := service.RefreshTokens(context.Background(), refreshToken)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if refreshed.User.ID != user.ID || refreshed.Jwt == "" || refreshed.RefreshToken == refreshToken {
+		t.Errorf("expected a new pair of tokens for the user, got %+v", refreshed)
+	}
+
+	// The new token carries on the same family and can be refreshed in turn
+	first, _ := store.GetRefreshTokenByHash(context.Background(), hashToken(refreshToken))
+	second, _ := store.GetRefreshTokenByHash(context.Background(), hashToken(refreshed.RefreshToken))
+	if first.RotatedAt == nil || *first.ReplacedByID != second.ID || first.FamilyID != second.FamilyID {
+		t.Errorf("expected the first token to be rotated into the second, got %+v and %+v", first, second)
+	}
+	if _, err := service.RefreshTokens(context.Background(), refreshed.RefreshToken); err != nil {
+		t.Errorf("expected the rotated token to refresh, got %v", err)
+	}
+}
+
+func TestAuthService_RefreshTokens_ReuseRevokesFamily(t *testing.T) {
+	store := newMockRefreshTokenStore()
+	service := NewService(&mockUserService{}, store, nil, testKeys)
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+
+	_, stolen, _ := service.GenerateJWT(context.Background(), user)
+	_, otherDevice, _ := service.GenerateJWT(context.Background(), user)
+	refreshed, err := service.RefreshTokens(context.Background(), stolen)
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+
+	if _, err := service.RefreshTokens(context.Background(), stolen); err != ErrRefreshTokenReused {
+		t.Fatalf("expected %v, got %v", ErrRefreshTokenReused, err)
+	}
+	if _, err := service.RefreshTokens(context.Background(), refreshed.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("expected the whole family to be revoked, got %v", err)
+	}
+	if _, err := service.RefreshTokens(context.Background(), otherDevice); err != nil {
+		t.Errorf("expected other logins to be left alone, got %v", err)
+	}
+}
+
+func TestAuthService_RefreshTokens_Invalid(t *testing.T) {
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+
+	// Signed by us but never stored, like the stateless refresh tokens handed out before rotation
+	_, unknown, _, err := service.newTokens(user, uuid.New())
+	if err != nil {
+		t.Fatalf("failed to sign tokens: %v", err)
+	}
+	if _, err := service.RefreshTokens(context.Background(), unknown); err != ErrInvalidRefreshToken {
+		t.Errorf("expected %v, got %v", ErrInvalidRefreshToken, err)
+	}
+
+	if _, err := service.RefreshTokens(context.Background(), "invalid.token.string"); err == nil {
+		t.Error("expected an error for a malformed token")
+	}
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+
+	_, refreshToken, _ := service.GenerateJWT(context.Background(), user)
+	_, otherDevice, _ := service.GenerateJWT(context.Background(), user)
+	if err := service.Logout(context.Background(), refreshToken); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := service.RefreshTokens(context.Background(), refreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("expected the logged out token to be revoked, got %v", err)
+	}
+	if _, err := service.RefreshTokens(context.Background(), otherDevice); err != nil {
+		t.Errorf("expected other logins to be left alone, got %v", err)
+	}
+
+	if err := service.Logout(context.Background(), "unknown"); err != nil {
+		t.Errorf("expected logging out an unknown token to do nothing, got %v", err)
+	}
+}
+
+func TestAuthService_LogoutEverywhere(t *testing.T) {
+	service := NewService(&mockUserService{}, newMockRefreshTokenStore(), nil, testKeys)
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+
+	_, laptop, _ := service.GenerateJWT(context.Background(), user)
+	_, phone, _ := service.GenerateJWT(context.Background(), user)
+	if err := service.LogoutEverywhere(context.Background(), user.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, token := range []string{laptop, phone} {
+		if _, err := service.RefreshTokens(context.Background(), token); err != ErrInvalidRefreshToken {
+			t.Errorf("expected every token to be revoked, got %v", err)
+		}
+	}
+}
+
+type mockRatingReplayer struct {
+	replayed []uuid.UUID
+}
+
+func (m *mockRatingReplayer) ReplayRatings(ctx context.Context, userId uuid.UUID, dryRun bool) (*ratings.ReplayReport, error) {
+	m.replayed = append(m.replayed, userId)
+	return &ratings.ReplayReport{}, nil
+}
+
+func TestAuthService_MergeAccounts(t *testing.T) {
+	me := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	other := &domain.User{ID: uuid.New(), Name: "Other User", Username: "otheruser"}
+	identity := &domain.UserIdentity{Provider: "google", Subject: "google-subject"}
+
+	var merged [2]uuid.UUID
+	userService := &mockUserService{
+		getUserByIdentityFunc: func(ctx context.Context, provider string, subject string) (*domain.User, error) {
+			return other, nil
+		},
+		mergeUsersFunc: func(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error {
+			merged = [2]uuid.UUID{sourceId, targetId}
+			return nil
+		},
+	}
+	replayer := &mockRatingReplayer{}
+	service := NewService(userService, newMockRefreshTokenStore(), replayer, testKeys)
+
+	mergeToken, err := service.MergeToken(context.Background(), me, identity)
+	if err != nil {
+		t.Fatalf("failed to create merge token: %v", err)
+	}
+
+	// Only the user the token was issued to can use it
+	if err := service.MergeAccounts(context.Background(), other, mergeToken); err != ErrInvalidMergeToken {
+		t.Errorf("expected %v for another user, got %v", ErrInvalidMergeToken, err)
+	}
+
+	if err := service.MergeAccounts(context.Background(), me, mergeToken); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if merged != [2]uuid.UUID{other.ID, me.ID} {
+		t.Errorf("expected %v to be merged into %v, got %v", other.ID, me.ID, merged)
+	}
+	if len(replayer.replayed) != 1 || replayer.replayed[0] != me.ID {
+		t.Errorf("expected the merged ratings to be replayed, got %v", replayer.replayed)
+	}
+}
+
+func TestAuthService_MergeAccounts_OtherTokens(t *testing.T) {
+	me := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	userService := &mockUserService{
+		mergeUsersFunc: func(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error {
+			t.Error("expected nothing to be merged")
+			return nil
+		},
+	}
+	service := NewService(userService, newMockRefreshTokenStore(), &mockRatingReplayer{}, testKeys)
+
+	accessToken, refreshToken, err := service.GenerateJWT(context.Background(), me)
+	if err != nil {
+		t.Fatalf("failed to generate tokens: %v", err)
+	}
+	for _, token := range []string{accessToken, refreshToken, "invalid.token.string"} {
+		if err := service.MergeAccounts(context.Background(), me, token); err != ErrInvalidMergeToken {
+			t.Errorf("expected %v, got %v", ErrInvalidMergeToken, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) RefreshTokenStore {
+	return &store{
+		db: db,
+	}
+}
+
+func (s *store) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	query := /* sql */ `
+		INSERT INTO refresh_tokens (refresh_token_id, user_id, family_id, token_hash, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := s.db.ExecContext(ctx, query, token.ID, token.UserID, token.FamilyID, token.TokenHash, token.IssuedAt, token.ExpiresAt)
+	return err
+}
+
+func (s *store) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	query := /* sql */ `
+		SELECT refresh_token_id, user_id, family_id, token_hash, issued_at, expires_at, rotated_at, replaced_by_id, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	var token domain.RefreshToken
+	err := s.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.IssuedAt,
+		&token.ExpiresAt,
+		&token.RotatedAt,
+		&token.ReplacedByID,
+		&token.RevokedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// RotateRefreshToken marks the token as rotated and stores the one replacing it. Only a token that's
+// still live can be rotated, if another request got there first this is ErrRefreshTokenReused and
+// nothing changes.
+func (s *store) RotateRefreshToken(ctx context.Context, tokenId uuid.UUID, next *domain.RefreshToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := /* sql */ `
+		UPDATE refresh_tokens
+		SET rotated_at = $2, replaced_by_id = $3
+		WHERE refresh_token_id = $1
+		AND rotated_at IS NULL
+		AND revoked_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, tokenId, next.IssuedAt, next.ID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	insertQuery := /* sql */ `
+		INSERT INTO refresh_tokens (refresh_token_id, user_id, family_id, token_hash, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = tx.ExecContext(ctx, insertQuery, next.ID, next.UserID, next.FamilyID, next.TokenHash, next.IssuedAt, next.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeRefreshTokenFamily revokes every token rotated from the same login
+func (s *store) RevokeRefreshTokenFamily(ctx context.Context, familyId uuid.UUID, revokedAt time.Time) error {
+	query := /* sql */ `
+		UPDATE refresh_tokens
+		SET revoked_at = $2
+		WHERE family_id = $1
+		AND revoked_at IS NULL
+	`
+	_, err := s.db.ExecContext(ctx, query, familyId, revokedAt)
+	return err
+}
+
+// RevokeUserRefreshTokens revokes every token the user has, logging them out everywhere
+func (s *store) RevokeUserRefreshTokens(ctx context.Context, userId uuid.UUID, revokedAt time.Time) error {
+	query := /* sql */ `
+		UPDATE refresh_tokens
+		SET revoked_at = $2
+		WHERE user_id = $1
+		AND revoked_at IS NULL
+	`
+	_, err := s.db.ExecContext(ctx, query, userId, revokedAt)
+	return err
+}
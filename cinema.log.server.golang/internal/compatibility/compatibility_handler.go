@@ -0,0 +1,57 @@
+package compatibility
+
+import (
+	"context"
+	"net/http"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/users"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	CompatibilityService CompatibilityService
+}
+
+type CompatibilityService interface {
+	GetCompatibility(ctx context.Context, userId uuid.UUID, otherUserId uuid.UUID) (*domain.Compatibility, error)
+}
+
+func NewHandler(compatibilityService CompatibilityService) *Handler {
+	return &Handler{
+		CompatibilityService: compatibilityService,
+	}
+}
+
+// GetCompatibility compares the authenticated user's taste with the user in the path
+func (h *Handler) GetCompatibility(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	otherUserId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	compatibility, err := h.CompatibilityService.GetCompatibility(r.Context(), user.ID, otherUserId)
+	if err != nil {
+		switch err {
+		case ErrCompareSelf:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case users.ErrUserNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to get compatibility", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.SendJSON(w, compatibility)
+}
@@ -0,0 +1,84 @@
+package compatibility
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/users"
+	"github.com/google/uuid"
+)
+
+type mockCompatibilityService struct {
+	getCompatibilityFunc func(ctx context.Context, userId uuid.UUID, otherUserId uuid.UUID) (*domain.Compatibility, error)
+}
+
+func (m *mockCompatibilityService) GetCompatibility(ctx context.Context, userId uuid.UUID, otherUserId uuid.UUID) (*domain.Compatibility, error) {
+	return m.getCompatibilityFunc(ctx, userId, otherUserId)
+}
+
+func TestHandler_GetCompatibility(t *testing.T) {
+	me, friend := uuid.New(), uuid.New()
+	score := 82
+	handler := NewHandler(&mockCompatibilityService{
+		getCompatibilityFunc: func(ctx context.Context, userId uuid.UUID, otherUserId uuid.UUID) (*domain.Compatibility, error) {
+			return &domain.Compatibility{UserID: userId, OtherUserID: otherUserId, Score: &score}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+friend.String()+"/compatibility", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, &domain.User{ID: me}))
+	req.SetPathValue("id", friend.String())
+	w := httptest.NewRecorder()
+	handler.GetCompatibility(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var compatibility domain.Compatibility
+	if err := json.NewDecoder(w.Body).Decode(&compatibility); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if compatibility.UserID != me || compatibility.OtherUserID != friend || *compatibility.Score != 82 {
+		t.Errorf("unexpected compatibility %+v", compatibility)
+	}
+}
+
+func TestHandler_GetCompatibility_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		err      error
+		expected int
+	}{
+		{"invalid id", "abc", nil, http.StatusBadRequest},
+		{"self", uuid.NewString(), ErrCompareSelf, http.StatusBadRequest},
+		{"unknown user", uuid.NewString(), users.ErrUserNotFound, http.StatusNotFound},
+		{"store failure", uuid.NewString(), errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockCompatibilityService{
+				getCompatibilityFunc: func(ctx context.Context, userId uuid.UUID, otherUserId uuid.UUID) (*domain.Compatibility, error) {
+					return nil, tt.err
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/users/"+tt.id+"/compatibility", nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, &domain.User{ID: uuid.New()}))
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+			handler.GetCompatibility(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
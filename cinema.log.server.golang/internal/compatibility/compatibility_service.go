@@ -0,0 +1,196 @@
+package compatibility
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCompareSelf = errors.New("can't compare a user's taste with their own")
+)
+
+const (
+	// Fewer shared films than this and the correlations say more about chance than taste
+	MinSharedFilms = 5
+	// Shared films needed for medium and high confidence
+	MediumConfidenceFilms = 15
+	HighConfidenceFilms   = 40
+
+	// How many films to show that the users agree and disagree on most
+	HighlightCount = 5
+)
+
+type Service struct {
+	RatingService RatingService
+	UserService   UserService
+}
+
+type RatingService interface {
+	GetRatingsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error)
+}
+
+type UserService interface {
+	GetUserById(ctx context.Context, id uuid.UUID) (*domain.User, error)
+}
+
+func NewService(ratingService RatingService, userService UserService) *Service {
+	return &Service{
+		RatingService: ratingService,
+		UserService:   userService,
+	}
+}
+
+// GetCompatibility compares how userId and otherUserId rank the films they've both rated
+func (s *Service) GetCompatibility(ctx context.Context, userId uuid.UUID, otherUserId uuid.UUID) (*domain.Compatibility, error) {
+	if userId == otherUserId {
+		return nil, ErrCompareSelf
+	}
+	// Makes sure the other user exists, an unknown user is users.ErrUserNotFound
+	if _, err := s.UserService.GetUserById(ctx, otherUserId); err != nil {
+		return nil, err
+	}
+
+	ratings, err := s.RatingService.GetRatingsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	otherRatings, err := s.RatingService.GetRatingsByUserId(ctx, otherUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return compareRatings(userId, otherUserId, ratings, otherRatings), nil
+}
+
+func compareRatings(userId uuid.UUID, otherUserId uuid.UUID, ratings []domain.UserFilmRatingDetail, otherRatings []domain.UserFilmRatingDetail) *domain.Compatibility {
+	otherByFilm := make(map[uuid.UUID]domain.UserFilmRating, len(otherRatings))
+	for _, detail := range otherRatings {
+		otherByFilm[detail.Rating.FilmId] = detail.Rating
+	}
+
+	shared := []domain.SharedFilmRank{}
+	for _, detail := range ratings {
+		other, ok := otherByFilm[detail.Rating.FilmId]
+		if !ok {
+			continue
+		}
+		shared = append(shared, domain.SharedFilmRank{
+			FilmID:         detail.Rating.FilmId,
+			FilmTitle:      detail.FilmTitle,
+			FilmPosterURL:  detail.FilmPosterURL,
+			EloRating:      detail.Rating.EloRating,
+			OtherEloRating: other.EloRating,
+		})
+	}
+
+	compatibility := &domain.Compatibility{
+		UserID:        userId,
+		OtherUserID:   otherUserId,
+		SharedFilms:   len(shared),
+		Confidence:    confidence(len(shared)),
+		Agreements:    []domain.SharedFilmRank{},
+		Disagreements: []domain.SharedFilmRank{},
+	}
+	if len(shared) == 0 {
+		return compatibility
+	}
+
+	rankSharedFilms(shared)
+	compatibility.Agreements, compatibility.Disagreements = highlights(shared)
+
+	if len(shared) < MinSharedFilms {
+		return compatibility
+	}
+
+	elo, otherElo := make([]float64, len(shared)), make([]float64, len(shared))
+	for i, film := range shared {
+		elo[i], otherElo[i] = film.EloRating, film.OtherEloRating
+	}
+	rho, tau := spearman(elo, otherElo), kendallTauB(elo, otherElo)
+	low, high := spearmanInterval(rho, len(shared))
+	score := int(math.Round((rho + 1) / 2 * 100))
+
+	compatibility.Score = &score
+	compatibility.Spearman = &rho
+	compatibility.KendallTau = &tau
+	compatibility.SpearmanLow = &low
+	compatibility.SpearmanHigh = &high
+
+	return compatibility
+}
+
+// rankSharedFilms numbers the shared films 1 to n in each user's order, ties are broken by film id
+// so the same ratings always get the same ranks
+func rankSharedFilms(shared []domain.SharedFilmRank) {
+	rankBy := func(elo func(domain.SharedFilmRank) float64, set func(*domain.SharedFilmRank, int)) {
+		order := make([]int, len(shared))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool {
+			filmA, filmB := shared[order[a]], shared[order[b]]
+			if elo(filmA) != elo(filmB) {
+				return elo(filmA) > elo(filmB)
+			}
+			return filmA.FilmID.String() < filmB.FilmID.String()
+		})
+		for rank, i := range order {
+			set(&shared[i], rank+1)
+		}
+	}
+
+	rankBy(func(f domain.SharedFilmRank) float64 { return f.EloRating }, func(f *domain.SharedFilmRank, rank int) { f.Rank = rank })
+	rankBy(func(f domain.SharedFilmRank) float64 { return f.OtherEloRating }, func(f *domain.SharedFilmRank, rank int) { f.OtherRank = rank })
+}
+
+// highlights picks the films ranked closest together and furthest apart. Among films ranked equally
+// close the ones both users like more come first, a film ranked the same by both is never a disagreement
+// and no film is both.
+func highlights(shared []domain.SharedFilmRank) ([]domain.SharedFilmRank, []domain.SharedFilmRank) {
+	gap := func(film domain.SharedFilmRank) int {
+		if film.Rank > film.OtherRank {
+			return film.Rank - film.OtherRank
+		}
+		return film.OtherRank - film.Rank
+	}
+
+	byGap := make([]domain.SharedFilmRank, len(shared))
+	copy(byGap, shared)
+	sort.SliceStable(byGap, func(a, b int) bool {
+		if gap(byGap[a]) != gap(byGap[b]) {
+			return gap(byGap[a]) < gap(byGap[b])
+		}
+		return byGap[a].Rank+byGap[a].OtherRank < byGap[b].Rank+byGap[b].OtherRank
+	})
+
+	// With too few films for both lists to be full they split them, so no film is in both
+	disagreements := []domain.SharedFilmRank{}
+	for i := len(byGap) - 1; i >= 0 && len(disagreements) < min(HighlightCount, len(byGap)/2); i-- {
+		if gap(byGap[i]) == 0 {
+			break
+		}
+		disagreements = append(disagreements, byGap[i])
+	}
+
+	agreements := byGap[:min(HighlightCount, len(byGap)-len(disagreements))]
+
+	return agreements, disagreements
+}
+
+func confidence(sharedFilms int) string {
+	switch {
+	case sharedFilms < MinSharedFilms:
+		return domain.CompatibilityConfidenceNone
+	case sharedFilms < MediumConfidenceFilms:
+		return domain.CompatibilityConfidenceLow
+	case sharedFilms < HighConfidenceFilms:
+		return domain.CompatibilityConfidenceMedium
+	default:
+		return domain.CompatibilityConfidenceHigh
+	}
+}
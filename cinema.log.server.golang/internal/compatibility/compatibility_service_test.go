@@ -0,0 +1,170 @@
+package compatibility
+
+import (
+	"context"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/users"
+	"github.com/google/uuid"
+)
+
+type mockRatingService struct {
+	ratings map[uuid.UUID][]domain.UserFilmRatingDetail
+}
+
+func (m *mockRatingService) GetRatingsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
+	return m.ratings[userId], nil
+}
+
+type mockUserService struct {
+	users map[uuid.UUID]bool
+}
+
+func (m *mockUserService) GetUserById(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	if !m.users[id] {
+		return nil, users.ErrUserNotFound
+	}
+	return &domain.User{ID: id}, nil
+}
+
+func ratingsFor(films []uuid.UUID, elo ...float64) []domain.UserFilmRatingDetail {
+	details := []domain.UserFilmRatingDetail{}
+	for i, rating := range elo {
+		details = append(details, domain.UserFilmRatingDetail{Rating: domain.UserFilmRating{FilmId: films[i], EloRating: rating}, FilmTitle: "Film"})
+	}
+	return details
+}
+
+func TestService_GetCompatibility(t *testing.T) {
+	me, friend := uuid.New(), uuid.New()
+	films := make([]uuid.UUID, 7)
+	for i := range films {
+		films[i] = uuid.New()
+	}
+
+	// We agree on everything apart from the last two films, which we rank the other way round. The
+	// friend's last film is one I haven't rated.
+	ratingService := &mockRatingService{ratings: map[uuid.UUID][]domain.UserFilmRatingDetail{
+		me:     ratingsFor(films, 1300, 1200, 1100, 1000, 900, 800),
+		friend: ratingsFor(films, 1150, 1100, 1050, 1000, 940, 960, 1200),
+	}}
+	service := NewService(ratingService, &mockUserService{users: map[uuid.UUID]bool{friend: true}})
+
+	compatibility, err := service.GetCompatibility(context.Background(), me, friend)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if compatibility.SharedFilms != 6 || compatibility.Confidence != domain.CompatibilityConfidenceLow {
+		t.Errorf("expected 6 shared films with low confidence, got %d and %s", compatibility.SharedFilms, compatibility.Confidence)
+	}
+	if compatibility.Spearman == nil || *compatibility.Spearman <= 0.8 || *compatibility.Spearman >= 1 {
+		t.Errorf("expected a strong but not perfect spearman, got %v", compatibility.Spearman)
+	}
+	if compatibility.Score == nil || *compatibility.Score <= 90 || *compatibility.KendallTau >= *compatibility.Spearman {
+		t.Errorf("unexpected score %v and kendall %v", compatibility.Score, compatibility.KendallTau)
+	}
+	if *compatibility.SpearmanLow >= *compatibility.Spearman || *compatibility.SpearmanHigh <= *compatibility.Spearman {
+		t.Errorf("expected the interval to contain spearman, got %.3f to %.3f", *compatibility.SpearmanLow, *compatibility.SpearmanHigh)
+	}
+
+	if len(compatibility.Agreements) != 4 || compatibility.Agreements[0].FilmID != films[0] {
+		t.Errorf("expected our shared favourite to lead the agreements, got %+v", compatibility.Agreements)
+	}
+	if len(compatibility.Disagreements) != 2 {
+		t.Fatalf("expected only the swapped films as disagreements, got %+v", compatibility.Disagreements)
+	}
+	for _, film := range compatibility.Disagreements {
+		if film.FilmID != films[4] && film.FilmID != films[5] {
+			t.Errorf("unexpected disagreement %+v", film)
+		}
+	}
+}
+
+func TestService_GetCompatibility_TooFewSharedFilms(t *testing.T) {
+	me, friend := uuid.New(), uuid.New()
+	films := []uuid.UUID{uuid.New(), uuid.New()}
+	ratingService := &mockRatingService{ratings: map[uuid.UUID][]domain.UserFilmRatingDetail{
+		me:     ratingsFor(films, 1100, 1000),
+		friend: ratingsFor(films, 1000, 1100),
+	}}
+	service := NewService(ratingService, &mockUserService{users: map[uuid.UUID]bool{friend: true}})
+
+	compatibility, err := service.GetCompatibility(context.Background(), me, friend)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if compatibility.Score != nil || compatibility.Spearman != nil || compatibility.Confidence != domain.CompatibilityConfidenceNone {
+		t.Errorf("expected no score from two films, got %+v", compatibility)
+	}
+	if len(compatibility.Agreements) != 1 || len(compatibility.Disagreements) != 1 {
+		t.Errorf("expected the shared films to still be shown, got %+v and %+v", compatibility.Agreements, compatibility.Disagreements)
+	}
+}
+
+func TestService_GetCompatibility_HighlightsAreDisjoint(t *testing.T) {
+	me, friend := uuid.New(), uuid.New()
+	films := make([]uuid.UUID, 6)
+	for i := range films {
+		films[i] = uuid.New()
+	}
+
+	// Every film is ranked differently by the two of us, too few for both lists to be full
+	ratingService := &mockRatingService{ratings: map[uuid.UUID][]domain.UserFilmRatingDetail{
+		me:     ratingsFor(films, 1600, 1500, 1400, 1300, 1200, 1100),
+		friend: ratingsFor(films, 1500, 1600, 1100, 1200, 1400, 1300),
+	}}
+	service := NewService(ratingService, &mockUserService{users: map[uuid.UUID]bool{friend: true}})
+
+	compatibility, err := service.GetCompatibility(context.Background(), me, friend)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(compatibility.Agreements) != 3 || len(compatibility.Disagreements) != 3 {
+		t.Fatalf("expected the films to be split between the lists, got %+v and %+v", compatibility.Agreements, compatibility.Disagreements)
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, film := range compatibility.Agreements {
+		seen[film.FilmID] = true
+	}
+	for _, film := range compatibility.Disagreements {
+		if seen[film.FilmID] {
+			t.Errorf("expected no film to be both an agreement and a disagreement, got %+v", film)
+		}
+	}
+	if compatibility.Disagreements[0].FilmID != films[2] {
+		t.Errorf("expected the film we're furthest apart on to lead the disagreements, got %+v", compatibility.Disagreements[0])
+	}
+}
+
+func TestService_GetCompatibility_Errors(t *testing.T) {
+	me := uuid.New()
+	service := NewService(&mockRatingService{}, &mockUserService{users: map[uuid.UUID]bool{me: true}})
+
+	if _, err := service.GetCompatibility(context.Background(), me, me); err != ErrCompareSelf {
+		t.Errorf("expected ErrCompareSelf, got %v", err)
+	}
+	if _, err := service.GetCompatibility(context.Background(), me, uuid.New()); err != users.ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		sharedFilms int
+		expected    string
+	}{
+		{0, domain.CompatibilityConfidenceNone},
+		{MinSharedFilms, domain.CompatibilityConfidenceLow},
+		{MediumConfidenceFilms, domain.CompatibilityConfidenceMedium},
+		{HighConfidenceFilms, domain.CompatibilityConfidenceHigh},
+	}
+
+	for _, tt := range tests {
+		if got := confidence(tt.sharedFilms); got != tt.expected {
+			t.Errorf("expected %s for %d films, got %s", tt.expected, tt.sharedFilms, got)
+		}
+	}
+}
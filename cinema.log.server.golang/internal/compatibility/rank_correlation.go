@@ -0,0 +1,107 @@
+package compatibility
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+Rank correlation
+---
+Two users' elo ratings aren't on the same scale, one user's 1200 can be another's 1050, so
+compatibility only looks at the order each user puts the shared films in.
+
+Spearman's rho is the Pearson correlation of the two sets of ranks, and Kendall's tau-b is the
+share of film pairs both users put the same way round minus the share they put opposite ways
+round. Both run from -1 (opposite orders) to 1 (the same order) and both give tied films the
+average of the ranks they span, so a tie is neither an agreement nor a disagreement.
+*/
+
+// averageRanks ranks values highest first, 1 being the highest, with ties sharing the average of their ranks
+func averageRanks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return values[order[a]] > values[order[b]]
+	})
+
+	ranks := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start
+		for end+1 < len(order) && values[order[end+1]] == values[order[start]] {
+			end++
+		}
+		// positions start..end are tied, ranks are 1 based
+		rank := float64(start+end)/2 + 1
+		for i := start; i <= end; i++ {
+			ranks[order[i]] = rank
+		}
+		start = end + 1
+	}
+	return ranks
+}
+
+// spearman is the correlation between the ranks of x and y, 0 when either has every value tied
+func spearman(x, y []float64) float64 {
+	rankX, rankY := averageRanks(x), averageRanks(y)
+	n := float64(len(x))
+
+	var meanX, meanY float64
+	for i := range rankX {
+		meanX += rankX[i]
+		meanY += rankY[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var covariance, varianceX, varianceY float64
+	for i := range rankX {
+		dx, dy := rankX[i]-meanX, rankY[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
+
+// kendallTauB compares every pair, with the tau-b correction for ties. 0 when either has every value tied
+func kendallTauB(x, y []float64) float64 {
+	var concordant, discordant, tiedX, tiedY float64
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			dx, dy := x[i]-x[j], y[i]-y[j]
+			switch {
+			case dx == 0 && dy == 0:
+				// tied for both, counts towards neither
+			case dx == 0:
+				tiedX++
+			case dy == 0:
+				tiedY++
+			case (dx > 0) == (dy > 0):
+				concordant++
+			default:
+				discordant++
+			}
+		}
+	}
+
+	denominator := math.Sqrt((concordant + discordant + tiedX) * (concordant + discordant + tiedY))
+	if denominator == 0 {
+		return 0
+	}
+	return (concordant - discordant) / denominator
+}
+
+// spearmanInterval is the 95% confidence interval for a spearman correlation over n films, using the
+// Fisher transformation with the Bonett-Wright standard error. n must be more than 3.
+func spearmanInterval(rho float64, n int) (float64, float64) {
+	// atanh is infinite at ±1, so a perfect correlation is pulled in just enough to have an interval
+	z := math.Atanh(math.Max(-0.9999, math.Min(0.9999, rho)))
+	margin := 1.96 * math.Sqrt((1+rho*rho/2)/float64(n-3))
+	return math.Tanh(z - margin), math.Tanh(z + margin)
+}
@@ -0,0 +1,58 @@
+package compatibility
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAverageRanks(t *testing.T) {
+	ranks := averageRanks([]float64{1000, 1200, 1200, 900})
+	expected := []float64{3, 1.5, 1.5, 4}
+	for i := range expected {
+		if ranks[i] != expected[i] {
+			t.Errorf("expected ranks %v, got %v", expected, ranks)
+			break
+		}
+	}
+}
+
+func TestRankCorrelations(t *testing.T) {
+	tests := []struct {
+		name     string
+		x, y     []float64
+		spearman float64
+		kendall  float64
+	}{
+		{"same order", []float64{1, 2, 3, 4}, []float64{10, 20, 30, 40}, 1, 1},
+		{"opposite order", []float64{1, 2, 3, 4}, []float64{40, 30, 20, 10}, -1, -1},
+		{"one swap", []float64{1, 2, 3}, []float64{1, 3, 2}, 0.5, 1.0 / 3},
+		{"all tied", []float64{1, 2, 3}, []float64{5, 5, 5}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spearman(tt.x, tt.y); math.Abs(got-tt.spearman) > 1e-9 {
+				t.Errorf("expected spearman %.3f, got %.3f", tt.spearman, got)
+			}
+			if got := kendallTauB(tt.x, tt.y); math.Abs(got-tt.kendall) > 1e-9 {
+				t.Errorf("expected kendall %.3f, got %.3f", tt.kendall, got)
+			}
+		})
+	}
+}
+
+func TestSpearmanInterval(t *testing.T) {
+	low, high := spearmanInterval(0.5, 10)
+	if low >= 0.5 || high <= 0.5 || low < -1 || high > 1 {
+		t.Errorf("expected an interval around 0.5, got %.3f to %.3f", low, high)
+	}
+
+	narrowLow, narrowHigh := spearmanInterval(0.5, 100)
+	if narrowHigh-narrowLow >= high-low {
+		t.Error("expected more shared films to narrow the interval")
+	}
+
+	if low, high := spearmanInterval(1, 10); math.IsNaN(low) || math.IsInf(high, 0) || high > 1 {
+		t.Errorf("expected a finite interval for a perfect correlation, got %.3f to %.3f", low, high)
+	}
+}
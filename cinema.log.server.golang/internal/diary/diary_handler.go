@@ -0,0 +1,133 @@
+package diary
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	DiaryService DiaryService
+}
+
+type DiaryService interface {
+	GetDiary(ctx context.Context, userId uuid.UUID, year int, month int) ([]domain.DiaryEntryDetail, error)
+	LogWatch(ctx context.Context, userId uuid.UUID, req LogWatchRequest) (*LoggedWatch, error)
+	DeleteDiaryEntry(ctx context.Context, userId uuid.UUID, entryId uuid.UUID) error
+}
+
+func NewHandler(diaryService DiaryService) *Handler {
+	return &Handler{
+		DiaryService: diaryService,
+	}
+}
+
+// GetDiary lists the user's diary for the year in the "year" query param, defaulting to this year,
+// narrowed down to one month when "month" is set
+func (h *Handler) GetDiary(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	year := time.Now().Year()
+	if yearStr := r.URL.Query().Get("year"); yearStr != "" {
+		parsed, err := strconv.Atoi(yearStr)
+		if err != nil || parsed < 1 {
+			http.Error(w, "invalid year", http.StatusBadRequest)
+			return
+		}
+		year = parsed
+	}
+
+	month := 0
+	if monthStr := r.URL.Query().Get("month"); monthStr != "" {
+		parsed, err := strconv.Atoi(monthStr)
+		if err != nil {
+			http.Error(w, "invalid month", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	entries, err := h.DiaryService.GetDiary(r.Context(), user.ID, year, month)
+	if err != nil {
+		if err == ErrInvalidPeriod {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to get diary", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, entries)
+}
+
+func (h *Handler) LogWatch(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req LogWatchRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.FilmId == uuid.Nil {
+		http.Error(w, "filmId is required", http.StatusBadRequest)
+		return
+	}
+
+	logged, err := h.DiaryService.LogWatch(r.Context(), user.ID, req)
+	if err != nil {
+		switch err {
+		case ErrInvalidFormat, ErrFutureWatchDate:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case ErrFilmNotFound, ErrReviewNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to log watch", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	utils.SendJSON(w, logged)
+}
+
+func (h *Handler) DeleteDiaryEntry(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	entryId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid diary entry ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DiaryService.DeleteDiaryEntry(r.Context(), user.ID, entryId); err != nil {
+		if err == ErrEntryNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete diary entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
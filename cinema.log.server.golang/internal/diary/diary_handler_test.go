@@ -0,0 +1,185 @@
+package diary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"github.com/google/uuid"
+)
+
+type mockDiaryService struct {
+	getDiaryFunc         func(ctx context.Context, userId uuid.UUID, year int, month int) ([]domain.DiaryEntryDetail, error)
+	logWatchFunc         func(ctx context.Context, userId uuid.UUID, req LogWatchRequest) (*LoggedWatch, error)
+	deleteDiaryEntryFunc func(ctx context.Context, userId uuid.UUID, entryId uuid.UUID) error
+}
+
+func (m *mockDiaryService) GetDiary(ctx context.Context, userId uuid.UUID, year int, month int) ([]domain.DiaryEntryDetail, error) {
+	if m.getDiaryFunc != nil {
+		return m.getDiaryFunc(ctx, userId, year, month)
+	}
+	return []domain.DiaryEntryDetail{}, nil
+}
+
+func (m *mockDiaryService) LogWatch(ctx context.Context, userId uuid.UUID, req LogWatchRequest) (*LoggedWatch, error) {
+	if m.logWatchFunc != nil {
+		return m.logWatchFunc(ctx, userId, req)
+	}
+	return &LoggedWatch{Entry: domain.DiaryEntry{ID: uuid.New(), UserID: userId, FilmID: req.FilmId, Format: req.Format}}, nil
+}
+
+func (m *mockDiaryService) DeleteDiaryEntry(ctx context.Context, userId uuid.UUID, entryId uuid.UUID) error {
+	if m.deleteDiaryEntryFunc != nil {
+		return m.deleteDiaryEntryFunc(ctx, userId, entryId)
+	}
+	return nil
+}
+
+func withUser(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), middleware.KeyUser, &domain.User{ID: uuid.New()}))
+}
+
+func TestHandler_GetDiary(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		year     int
+		month    int
+		expected int
+	}{
+		{"defaults to this year", "", time.Now().Year(), 0, http.StatusOK},
+		{"year", "?year=2024", 2024, 0, http.StatusOK},
+		{"month", "?year=2024&month=3", 2024, 3, http.StatusOK},
+		{"invalid year", "?year=last", 0, 0, http.StatusBadRequest},
+		{"invalid month", "?month=march", 0, 0, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var year, month int
+			handler := NewHandler(&mockDiaryService{
+				getDiaryFunc: func(ctx context.Context, userId uuid.UUID, y int, m int) ([]domain.DiaryEntryDetail, error) {
+					year, month = y, m
+					return []domain.DiaryEntryDetail{}, nil
+				},
+			})
+
+			req := withUser(httptest.NewRequest(http.MethodGet, "/diary"+tt.query, nil))
+			w := httptest.NewRecorder()
+			handler.GetDiary(w, req)
+
+			if w.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d", tt.expected, w.Code)
+			}
+			if tt.expected == http.StatusOK && (year != tt.year || month != tt.month) {
+				t.Errorf("expected %d/%d, got %d/%d", tt.month, tt.year, month, year)
+			}
+		})
+	}
+}
+
+func TestHandler_GetDiary_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockDiaryService{})
+
+	w := httptest.NewRecorder()
+	handler.GetDiary(w, httptest.NewRequest(http.MethodGet, "/diary", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_LogWatch(t *testing.T) {
+	filmId := uuid.New()
+	handler := NewHandler(&mockDiaryService{})
+
+	body, _ := json.Marshal(LogWatchRequest{FilmId: filmId, Format: domain.DiaryFormatCinema})
+	req := withUser(httptest.NewRequest(http.MethodPost, "/diary", bytes.NewReader(body)))
+	w := httptest.NewRecorder()
+	handler.LogWatch(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d, body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var logged LoggedWatch
+	if err := json.NewDecoder(w.Body).Decode(&logged); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if logged.Entry.FilmID != filmId {
+		t.Errorf("expected entry for %v, got %+v", filmId, logged.Entry)
+	}
+}
+
+func TestHandler_LogWatch_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		expected int
+	}{
+		{"invalid json", "{", nil, http.StatusBadRequest},
+		{"missing film", `{"format": "cinema"}`, nil, http.StatusBadRequest},
+		{"invalid format", `{"filmId": "` + uuid.NewString() + `", "format": "vhs"}`, ErrInvalidFormat, http.StatusBadRequest},
+		{"future date", `{"filmId": "` + uuid.NewString() + `", "format": "cinema"}`, ErrFutureWatchDate, http.StatusBadRequest},
+		{"unknown film", `{"filmId": "` + uuid.NewString() + `", "format": "cinema"}`, ErrFilmNotFound, http.StatusNotFound},
+		{"unknown review", `{"filmId": "` + uuid.NewString() + `", "format": "cinema"}`, ErrReviewNotFound, http.StatusNotFound},
+		{"store failure", `{"filmId": "` + uuid.NewString() + `", "format": "cinema"}`, errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockDiaryService{
+				logWatchFunc: func(ctx context.Context, userId uuid.UUID, req LogWatchRequest) (*LoggedWatch, error) {
+					return nil, tt.err
+				},
+			})
+
+			req := withUser(httptest.NewRequest(http.MethodPost, "/diary", bytes.NewBufferString(tt.body)))
+			w := httptest.NewRecorder()
+			handler.LogWatch(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_DeleteDiaryEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		err      error
+		expected int
+	}{
+		{"deleted", uuid.NewString(), nil, http.StatusNoContent},
+		{"not found", uuid.NewString(), ErrEntryNotFound, http.StatusNotFound},
+		{"invalid id", "abc", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockDiaryService{
+				deleteDiaryEntryFunc: func(ctx context.Context, userId uuid.UUID, entryId uuid.UUID) error {
+					return tt.err
+				},
+			})
+
+			req := withUser(httptest.NewRequest(http.MethodDelete, "/diary/"+tt.id, nil))
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+			handler.DeleteDiaryEntry(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package diary
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/ratings"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEntryNotFound   = errors.New("diary entry not found")
+	ErrFilmNotFound    = errors.New("film not found")
+	ErrReviewNotFound  = errors.New("review not found for this film")
+	ErrInvalidFormat   = errors.New("format must be one of cinema, streaming or physical")
+	ErrInvalidPeriod   = errors.New("month must be between 1 and 12")
+	ErrFutureWatchDate = errors.New("watched date cannot be in the future")
+)
+
+type Service struct {
+	DiaryStore    DiaryStore
+	RatingService RatingService
+}
+
+type DiaryStore interface {
+	GetDiary(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]domain.DiaryEntryDetail, error)
+	HasWatchedBefore(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, before time.Time) (bool, error)
+	CreateDiaryEntry(ctx context.Context, entry domain.DiaryEntry) (*domain.DiaryEntry, error)
+	DeleteDiaryEntry(ctx context.Context, userId uuid.UUID, entryId uuid.UUID) error
+}
+
+type RatingService interface {
+	StartRecompareRound(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
+	GetNextComparison(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*ratings.NextComparison, error)
+}
+
+func NewService(diaryStore DiaryStore, ratingService RatingService) *Service {
+	return &Service{
+		DiaryStore:    diaryStore,
+		RatingService: ratingService,
+	}
+}
+
+// LogWatchRequest is a viewing to add to the diary
+type LogWatchRequest struct {
+	FilmId      uuid.UUID  `json:"filmId"`
+	WatchedDate time.Time  `json:"watchedDate"` // defaults to today
+	Rewatch     bool       `json:"rewatch"`     // worked out from the diary and reviews when not set
+	Format      string     `json:"format"`      // cinema, streaming or physical
+	ReviewId    *uuid.UUID `json:"reviewId"`
+	Recompare   bool       `json:"recompare"` // on a rewatch, start a fresh round of comparisons for the film
+}
+
+// LoggedWatch is the new diary entry, with the first comparison of a fresh round when one was asked for
+type LoggedWatch struct {
+	Entry          domain.DiaryEntry       `json:"entry"`
+	NextComparison *ratings.NextComparison `json:"nextComparison,omitempty"`
+}
+
+// GetDiary returns the user's diary for a year, or a single month of it when month is not 0
+func (s *Service) GetDiary(ctx context.Context, userId uuid.UUID, year int, month int) ([]domain.DiaryEntryDetail, error) {
+	if month < 0 || month > 12 {
+		return nil, ErrInvalidPeriod
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+	if month != 0 {
+		from = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 1, 0)
+	}
+
+	return s.DiaryStore.GetDiary(ctx, userId, from, to)
+}
+
+// LogWatch adds a viewing to the user's diary. Watching a film the user has logged or reviewed before
+// counts as a rewatch. A rewatch with Recompare set starts a fresh round of comparisons for the film, so it
+// can be compared again with films it was compared with before, and comes back with the first of them.
+func (s *Service) LogWatch(ctx context.Context, userId uuid.UUID, req LogWatchRequest) (*LoggedWatch, error) {
+	switch req.Format {
+	case domain.DiaryFormatCinema, domain.DiaryFormatStreaming, domain.DiaryFormatPhysical:
+	default:
+		return nil, ErrInvalidFormat
+	}
+
+	now := time.Now()
+	watchedDate := req.WatchedDate
+	if watchedDate.IsZero() {
+		watchedDate = now
+	}
+	if watchedDate.After(now) {
+		return nil, ErrFutureWatchDate
+	}
+	// Diary entries are kept by day
+	watchedDate = time.Date(watchedDate.Year(), watchedDate.Month(), watchedDate.Day(), 0, 0, 0, 0, time.UTC)
+
+	rewatch := req.Rewatch
+	if !rewatch {
+		watchedBefore, err := s.DiaryStore.HasWatchedBefore(ctx, userId, req.FilmId, watchedDate)
+		if err != nil {
+			return nil, err
+		}
+		rewatch = watchedBefore
+	}
+
+	entry, err := s.DiaryStore.CreateDiaryEntry(ctx, domain.DiaryEntry{
+		ID:          uuid.New(),
+		UserID:      userId,
+		FilmID:      req.FilmId,
+		WatchedDate: watchedDate,
+		Rewatch:     rewatch,
+		Format:      req.Format,
+		ReviewID:    req.ReviewId,
+		CreatedAt:   now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logged := &LoggedWatch{Entry: *entry}
+	if entry.Rewatch && req.Recompare {
+		logged.NextComparison = s.recompare(ctx, userId, entry.FilmID)
+	}
+
+	return logged, nil
+}
+
+// recompare starts a fresh round of comparisons for a rewatched film and returns the first comparison in it.
+// The entry is already saved, so a film that isn't ranked yet or has nothing left to compare just gets no comparison.
+func (s *Service) recompare(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) *ratings.NextComparison {
+	if err := s.RatingService.StartRecompareRound(ctx, userId, filmId); err != nil {
+		if err != ratings.ErrRatingNotFound {
+			log.Printf("Failed to start a new round of comparisons for rewatch: %v", err)
+		}
+		return nil
+	}
+
+	next, err := s.RatingService.GetNextComparison(ctx, userId, filmId)
+	switch err {
+	case nil:
+		return next
+	case ratings.ErrRatingNotFound, ratings.ErrNoComparisonAvailable:
+	default:
+		log.Printf("Failed to get next comparison for rewatch: %v", err)
+	}
+	return nil
+}
+
+func (s *Service) DeleteDiaryEntry(ctx context.Context, userId uuid.UUID, entryId uuid.UUID) error {
+	return s.DiaryStore.DeleteDiaryEntry(ctx, userId, entryId)
+}
@@ -0,0 +1,217 @@
+package diary
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/ratings"
+	"github.com/google/uuid"
+)
+
+type mockDiaryStore struct {
+	getDiaryFunc         func(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]domain.DiaryEntryDetail, error)
+	hasWatchedBeforeFunc func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, before time.Time) (bool, error)
+	createDiaryEntryFunc func(ctx context.Context, entry domain.DiaryEntry) (*domain.DiaryEntry, error)
+	deleteDiaryEntryFunc func(ctx context.Context, userId uuid.UUID, entryId uuid.UUID) error
+}
+
+func (m *mockDiaryStore) GetDiary(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]domain.DiaryEntryDetail, error) {
+	if m.getDiaryFunc != nil {
+		return m.getDiaryFunc(ctx, userId, from, to)
+	}
+	return []domain.DiaryEntryDetail{}, nil
+}
+
+func (m *mockDiaryStore) HasWatchedBefore(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, before time.Time) (bool, error) {
+	if m.hasWatchedBeforeFunc != nil {
+		return m.hasWatchedBeforeFunc(ctx, userId, filmId, before)
+	}
+	return false, nil
+}
+
+func (m *mockDiaryStore) CreateDiaryEntry(ctx context.Context, entry domain.DiaryEntry) (*domain.DiaryEntry, error) {
+	if m.createDiaryEntryFunc != nil {
+		return m.createDiaryEntryFunc(ctx, entry)
+	}
+	return &entry, nil
+}
+
+func (m *mockDiaryStore) DeleteDiaryEntry(ctx context.Context, userId uuid.UUID, entryId uuid.UUID) error {
+	if m.deleteDiaryEntryFunc != nil {
+		return m.deleteDiaryEntryFunc(ctx, userId, entryId)
+	}
+	return nil
+}
+
+type mockRatingService struct {
+	startRecompareRoundFunc func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
+	getNextComparisonFunc   func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*ratings.NextComparison, error)
+}
+
+func (m *mockRatingService) StartRecompareRound(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	if m.startRecompareRoundFunc != nil {
+		return m.startRecompareRoundFunc(ctx, userId, filmId)
+	}
+	return nil
+}
+
+func (m *mockRatingService) GetNextComparison(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*ratings.NextComparison, error) {
+	if m.getNextComparisonFunc != nil {
+		return m.getNextComparisonFunc(ctx, userId, filmId)
+	}
+	return nil, ratings.ErrNoComparisonAvailable
+}
+
+func TestService_GetDiary_Period(t *testing.T) {
+	tests := []struct {
+		name  string
+		month int
+		from  time.Time
+		to    time.Time
+	}{
+		{"whole year", 0, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"one month", 2, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"december", 12, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from, to time.Time
+			service := NewService(&mockDiaryStore{
+				getDiaryFunc: func(ctx context.Context, userId uuid.UUID, f time.Time, t time.Time) ([]domain.DiaryEntryDetail, error) {
+					from, to = f, t
+					return []domain.DiaryEntryDetail{}, nil
+				},
+			}, &mockRatingService{})
+
+			if _, err := service.GetDiary(context.Background(), uuid.New(), 2025, tt.month); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("expected %v to %v, got %v to %v", tt.from, tt.to, from, to)
+			}
+		})
+	}
+}
+
+func TestService_GetDiary_InvalidMonth(t *testing.T) {
+	service := NewService(&mockDiaryStore{}, &mockRatingService{})
+
+	if _, err := service.GetDiary(context.Background(), uuid.New(), 2025, 13); err != ErrInvalidPeriod {
+		t.Errorf("expected ErrInvalidPeriod, got %v", err)
+	}
+}
+
+func TestService_LogWatch(t *testing.T) {
+	service := NewService(&mockDiaryStore{}, &mockRatingService{})
+	userId, filmId := uuid.New(), uuid.New()
+	watched := time.Date(2025, 6, 14, 21, 30, 0, 0, time.UTC)
+
+	logged, err := service.LogWatch(context.Background(), userId, LogWatchRequest{FilmId: filmId, WatchedDate: watched, Format: domain.DiaryFormatCinema})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if logged.Entry.UserID != userId || logged.Entry.FilmID != filmId || logged.Entry.Rewatch {
+		t.Errorf("unexpected entry %+v", logged.Entry)
+	}
+	if !logged.Entry.WatchedDate.Equal(time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the watched date to be kept by day, got %v", logged.Entry.WatchedDate)
+	}
+	if logged.NextComparison != nil {
+		t.Error("expected no comparison for a first watch")
+	}
+}
+
+func TestService_LogWatch_Validation(t *testing.T) {
+	service := NewService(&mockDiaryStore{}, &mockRatingService{})
+
+	_, err := service.LogWatch(context.Background(), uuid.New(), LogWatchRequest{FilmId: uuid.New(), Format: "vhs"})
+	if err != ErrInvalidFormat {
+		t.Errorf("expected ErrInvalidFormat, got %v", err)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	_, err = service.LogWatch(context.Background(), uuid.New(), LogWatchRequest{FilmId: uuid.New(), WatchedDate: tomorrow, Format: domain.DiaryFormatStreaming})
+	if err != ErrFutureWatchDate {
+		t.Errorf("expected ErrFutureWatchDate, got %v", err)
+	}
+}
+
+func TestService_LogWatch_DetectsRewatch(t *testing.T) {
+	service := NewService(&mockDiaryStore{
+		hasWatchedBeforeFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, before time.Time) (bool, error) {
+			return true, nil
+		},
+	}, &mockRatingService{})
+
+	logged, err := service.LogWatch(context.Background(), uuid.New(), LogWatchRequest{FilmId: uuid.New(), Format: domain.DiaryFormatPhysical})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !logged.Entry.Rewatch {
+		t.Error("expected a film watched before to be logged as a rewatch")
+	}
+}
+
+func TestService_LogWatch_RecompareRewatch(t *testing.T) {
+	filmId := uuid.New()
+	next := &ratings.NextComparison{FilmA: domain.UserFilmRatingDetail{Rating: domain.UserFilmRating{FilmId: filmId}}}
+	var roundStarted, askedFor uuid.UUID
+	service := NewService(&mockDiaryStore{}, &mockRatingService{
+		startRecompareRoundFunc: func(ctx context.Context, userId uuid.UUID, id uuid.UUID) error {
+			roundStarted = id
+			return nil
+		},
+		getNextComparisonFunc: func(ctx context.Context, userId uuid.UUID, id uuid.UUID) (*ratings.NextComparison, error) {
+			if roundStarted != id {
+				t.Error("expected a fresh round to be started before asking for a comparison")
+			}
+			askedFor = id
+			return next, nil
+		},
+	})
+
+	logged, err := service.LogWatch(context.Background(), uuid.New(), LogWatchRequest{FilmId: filmId, Rewatch: true, Recompare: true, Format: domain.DiaryFormatStreaming})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if askedFor != filmId || logged.NextComparison != next {
+		t.Errorf("expected the next comparison for the rewatched film, got %+v", logged.NextComparison)
+	}
+}
+
+func TestService_LogWatch_RecompareFailureKeepsEntry(t *testing.T) {
+	service := NewService(&mockDiaryStore{}, &mockRatingService{
+		getNextComparisonFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*ratings.NextComparison, error) {
+			return nil, errors.New("database error")
+		},
+	})
+
+	logged, err := service.LogWatch(context.Background(), uuid.New(), LogWatchRequest{FilmId: uuid.New(), Rewatch: true, Recompare: true, Format: domain.DiaryFormatCinema})
+	if err != nil {
+		t.Fatalf("expected the entry to be logged anyway, got %v", err)
+	}
+	if logged.NextComparison != nil {
+		t.Error("expected no comparison")
+	}
+}
+
+func TestService_LogWatch_RecompareUnrankedFilm(t *testing.T) {
+	service := NewService(&mockDiaryStore{}, &mockRatingService{
+		startRecompareRoundFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+			return ratings.ErrRatingNotFound
+		},
+		getNextComparisonFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*ratings.NextComparison, error) {
+			t.Error("expected no comparison to be asked for")
+			return nil, nil
+		},
+	})
+
+	logged, err := service.LogWatch(context.Background(), uuid.New(), LogWatchRequest{FilmId: uuid.New(), Rewatch: true, Recompare: true, Format: domain.DiaryFormatCinema})
+	if err != nil || logged.NextComparison != nil {
+		t.Errorf("expected the entry to be logged with no comparison, got %+v and %v", logged, err)
+	}
+}
@@ -0,0 +1,164 @@
+package diary
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) DiaryStore {
+	return &store{
+		db: db,
+	}
+}
+
+// GetDiary returns the user's diary entries watched on or after from and before to, latest first
+func (s *store) GetDiary(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]domain.DiaryEntryDetail, error) {
+	query := /* sql */ `
+		SELECT d.diary_entry_id, d.user_id, d.film_id, d.watched_date, d.rewatch, d.format, d.review_id, d.created_at, f.title, f.release_year, f.poster_url
+		FROM diary_entries d
+		JOIN films f ON d.film_id = f.film_id
+		WHERE d.user_id = $1
+		AND d.watched_date >= $2
+		AND d.watched_date < $3
+		ORDER BY d.watched_date DESC, d.created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.DiaryEntryDetail{}
+	for rows.Next() {
+		var entry domain.DiaryEntryDetail
+		var reviewId uuid.NullUUID
+		err := rows.Scan(
+			&entry.Entry.ID,
+			&entry.Entry.UserID,
+			&entry.Entry.FilmID,
+			&entry.Entry.WatchedDate,
+			&entry.Entry.Rewatch,
+			&entry.Entry.Format,
+			&reviewId,
+			&entry.Entry.CreatedAt,
+			&entry.FilmTitle,
+			&entry.FilmReleaseYear,
+			&entry.FilmPosterURL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if reviewId.Valid {
+			entry.Entry.ReviewID = &reviewId.UUID
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// HasWatchedBefore reports whether the user logged or reviewed the film before the given date
+func (s *store) HasWatchedBefore(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, before time.Time) (bool, error) {
+	query := /* sql */ `
+		SELECT EXISTS (
+			SELECT 1 FROM diary_entries
+			WHERE user_id = $1 AND film_id = $2 AND watched_date < $3
+		) OR EXISTS (
+			SELECT 1 FROM reviews
+			WHERE user_id = $1 AND film_id = $2 AND date < $3
+		)
+	`
+
+	var watched bool
+	if err := s.db.QueryRowContext(ctx, query, userId, filmId, before).Scan(&watched); err != nil {
+		return false, err
+	}
+	return watched, nil
+}
+
+// CreateDiaryEntry logs a viewing. A review can only be linked when it is the user's review of the same film.
+func (s *store) CreateDiaryEntry(ctx context.Context, entry domain.DiaryEntry) (*domain.DiaryEntry, error) {
+	if entry.ReviewID != nil {
+		reviewQuery := /* sql */ `
+			SELECT EXISTS (
+				SELECT 1 FROM reviews
+				WHERE review_id = $1 AND user_id = $2 AND film_id = $3
+			)
+		`
+		var exists bool
+		if err := s.db.QueryRowContext(ctx, reviewQuery, *entry.ReviewID, entry.UserID, entry.FilmID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrReviewNotFound
+		}
+	}
+
+	// Selecting from films means nothing is inserted when the film doesn't exist
+	query := /* sql */ `
+		INSERT INTO diary_entries (diary_entry_id, user_id, film_id, watched_date, rewatch, format, review_id, created_at)
+		SELECT $1, $2, f.film_id, $4, $5, $6, $7, $8
+		FROM films f
+		WHERE f.film_id = $3
+	`
+
+	result, err := s.db.ExecContext(ctx, query,
+		entry.ID,
+		entry.UserID,
+		entry.FilmID,
+		entry.WatchedDate,
+		entry.Rewatch,
+		entry.Format,
+		entry.ReviewID,
+		entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrFilmNotFound
+	}
+
+	return &entry, nil
+}
+
+func (s *store) DeleteDiaryEntry(ctx context.Context, userId uuid.UUID, entryId uuid.UUID) error {
+	query := /* sql */ `
+		DELETE FROM diary_entries
+		WHERE user_id = $1
+		AND diary_entry_id = $2
+	`
+
+	result, err := s.db.ExecContext(ctx, query, userId, entryId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEntryNotFound
+	}
+
+	return nil
+}
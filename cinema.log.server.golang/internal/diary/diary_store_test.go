@@ -0,0 +1,180 @@
+package diary
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	testDB      *sql.DB
+	testStore   DiaryStore
+	testDbSetup *utils.TestDatabase
+)
+
+// Helper function to create test user
+func createTestUser(ctx context.Context, t *testing.T) uuid.UUID {
+	userID := uuid.New()
+	query := `INSERT INTO users (user_id, name, username, profile_pic_url)
+	          VALUES ($1, $2, $3, $4)`
+	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], "http://example.com/pic.jpg")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return userID
+}
+
+// Helper function to create test film
+func createTestFilm(ctx context.Context, t *testing.T) uuid.UUID {
+	filmID := uuid.New()
+	query := `INSERT INTO films (film_id, external_id, title, description, poster_url, release_year)
+	          VALUES ($1, $2, $3, $4, $5, $6)`
+	externalID := int(time.Now().UnixNano() % 2147483647) // Use nanoseconds for uniqueness
+	_, err := testDB.ExecContext(ctx, query, filmID, externalID, "Test Film "+filmID.String()[:8], "Description", "/poster.jpg", "2024")
+	if err != nil {
+		t.Fatalf("failed to create test film: %v", err)
+	}
+	return filmID
+}
+
+func newEntry(userId, filmId uuid.UUID, watched time.Time) domain.DiaryEntry {
+	return domain.DiaryEntry{
+		ID:          uuid.New(),
+		UserID:      userId,
+		FilmID:      filmId,
+		WatchedDate: watched,
+		Format:      domain.DiaryFormatStreaming,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	testDbSetup, err = utils.StartTestPostgres()
+	if err != nil {
+		log.Fatalf("could not start test database: %v", err)
+	}
+
+	testDB = testDbSetup.DB
+	testStore = NewStore(testDB)
+
+	code := m.Run()
+
+	testDbSetup.Close()
+	os.Exit(code)
+}
+
+func TestDiaryStore_CreateAndGetDiary(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	filmId := createTestFilm(ctx, t)
+	march := time.Date(2025, 3, 8, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC)
+
+	for _, watched := range []time.Time{march, april} {
+		if _, err := testStore.CreateDiaryEntry(ctx, newEntry(userId, filmId, watched)); err != nil {
+			t.Fatalf("failed to create diary entry: %v", err)
+		}
+	}
+
+	entries, err := testStore.GetDiary(ctx, userId, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("failed to get diary: %v", err)
+	}
+	if len(entries) != 1 || !entries[0].Entry.WatchedDate.Equal(march) || entries[0].FilmTitle == "" {
+		t.Errorf("expected only the march entry with film details, got %+v", entries)
+	}
+
+	entries, err = testStore.GetDiary(ctx, userId, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("failed to get diary: %v", err)
+	}
+	if len(entries) != 2 || !entries[0].Entry.WatchedDate.Equal(april) {
+		t.Errorf("expected both entries latest first, got %+v", entries)
+	}
+}
+
+func TestDiaryStore_CreateDiaryEntry_FilmNotFound(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+
+	_, err := testStore.CreateDiaryEntry(ctx, newEntry(userId, uuid.New(), time.Now()))
+	if err != ErrFilmNotFound {
+		t.Errorf("expected ErrFilmNotFound, got %v", err)
+	}
+}
+
+func TestDiaryStore_CreateDiaryEntry_LinksReview(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	filmId := createTestFilm(ctx, t)
+	otherFilmId := createTestFilm(ctx, t)
+
+	reviewId := uuid.New()
+	_, err := testDB.ExecContext(ctx, `INSERT INTO reviews (review_id, content, date, rating, film_id, user_id) VALUES ($1, $2, $3, $4, $5, $6)`,
+		reviewId, "Better the second time", time.Now(), 4.5, filmId, userId)
+	if err != nil {
+		t.Fatalf("failed to create review: %v", err)
+	}
+
+	entry := newEntry(userId, filmId, time.Now())
+	entry.ReviewID = &reviewId
+	if _, err := testStore.CreateDiaryEntry(ctx, entry); err != nil {
+		t.Fatalf("failed to create diary entry: %v", err)
+	}
+
+	other := newEntry(userId, otherFilmId, time.Now())
+	other.ReviewID = &reviewId
+	if _, err := testStore.CreateDiaryEntry(ctx, other); err != ErrReviewNotFound {
+		t.Errorf("expected ErrReviewNotFound for another film's review, got %v", err)
+	}
+}
+
+func TestDiaryStore_HasWatchedBefore(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	filmId := createTestFilm(ctx, t)
+	first := time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC)
+
+	watched, err := testStore.HasWatchedBefore(ctx, userId, filmId, first)
+	if err != nil || watched {
+		t.Fatalf("expected an unwatched film, got %v, %v", watched, err)
+	}
+
+	if _, err := testStore.CreateDiaryEntry(ctx, newEntry(userId, filmId, first)); err != nil {
+		t.Fatalf("failed to create diary entry: %v", err)
+	}
+
+	watched, err = testStore.HasWatchedBefore(ctx, userId, filmId, first.AddDate(1, 0, 0))
+	if err != nil || !watched {
+		t.Errorf("expected the earlier viewing to count, got %v, %v", watched, err)
+	}
+}
+
+func TestDiaryStore_DeleteDiaryEntry(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	filmId := createTestFilm(ctx, t)
+
+	entry, err := testStore.CreateDiaryEntry(ctx, newEntry(userId, filmId, time.Now()))
+	if err != nil {
+		t.Fatalf("failed to create diary entry: %v", err)
+	}
+
+	if err := testStore.DeleteDiaryEntry(ctx, uuid.New(), entry.ID); err != ErrEntryNotFound {
+		t.Errorf("expected another user's delete to fail with ErrEntryNotFound, got %v", err)
+	}
+	if err := testStore.DeleteDiaryEntry(ctx, userId, entry.ID); err != nil {
+		t.Fatalf("failed to delete diary entry: %v", err)
+	}
+	if err := testStore.DeleteDiaryEntry(ctx, userId, entry.ID); err != ErrEntryNotFound {
+		t.Errorf("expected ErrEntryNotFound, got %v", err)
+	}
+}
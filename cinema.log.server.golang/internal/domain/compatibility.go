@@ -0,0 +1,37 @@
+package domain
+
+import "github.com/google/uuid"
+
+// How much to trust a compatibility score, from how many films the two users have both rated
+const (
+	CompatibilityConfidenceNone   = "none"
+	CompatibilityConfidenceLow    = "low"
+	CompatibilityConfidenceMedium = "medium"
+	CompatibilityConfidenceHigh   = "high"
+)
+
+type Compatibility struct {
+	UserID      uuid.UUID `json:"userId"`
+	OtherUserID uuid.UUID `json:"otherUserId"`
+	SharedFilms int       `json:"sharedFilms"`
+	// Score, the correlations and the interval are left out when too few films are shared to say anything
+	Score         *int             `json:"score,omitempty"` // 0 is opposite tastes, 50 is unrelated, 100 is identical
+	Spearman      *float64         `json:"spearman,omitempty"`
+	KendallTau    *float64         `json:"kendallTau,omitempty"`
+	SpearmanLow   *float64         `json:"spearmanLow,omitempty"` // 95% confidence interval for spearman
+	SpearmanHigh  *float64         `json:"spearmanHigh,omitempty"`
+	Confidence    string           `json:"confidence"`
+	Agreements    []SharedFilmRank `json:"agreements"`
+	Disagreements []SharedFilmRank `json:"disagreements"`
+}
+
+// SharedFilmRank is where a film both users rated sits in each of their rankings of the shared films
+type SharedFilmRank struct {
+	FilmID         uuid.UUID `json:"filmId"`
+	FilmTitle      string    `json:"filmTitle"`
+	FilmPosterURL  string    `json:"filmPosterUrl"`
+	Rank           int       `json:"rank"`
+	OtherRank      int       `json:"otherRank"`
+	EloRating      float64   `json:"eloRating"`
+	OtherEloRating float64   `json:"otherEloRating"`
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How a film in the diary was watched
+const (
+	DiaryFormatCinema    = "cinema"
+	DiaryFormatStreaming = "streaming"
+	DiaryFormatPhysical  = "physical"
+)
+
+// DiaryEntry is a single viewing of a film, a film watched three times has three entries
+type DiaryEntry struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"userId"`
+	FilmID      uuid.UUID  `json:"filmId"`
+	WatchedDate time.Time  `json:"watchedDate"`
+	Rewatch     bool       `json:"rewatch"`
+	Format      string     `json:"format"`
+	ReviewID    *uuid.UUID `json:"reviewId,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type DiaryEntryDetail struct {
+	Entry           DiaryEntry `json:"entry"`
+	FilmTitle       string     `json:"filmTitle"`
+	FilmReleaseYear string     `json:"filmReleaseYear"`
+	FilmPosterURL   string     `json:"filmPosterUrl"`
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// What happened in a feed item
+const (
+	FeedItemReview     = "review"
+	FeedItemNewRating  = "new_rating"
+	FeedItemRankChange = "rank_change"
+)
+
+// FeedEvent is a review or comparison by a followed user, before it's turned into a feed item
+type FeedEvent struct {
+	ID             uuid.UUID
+	Kind           string // review or comparison
+	OccurredAt     time.Time
+	UserID         uuid.UUID
+	Username       string
+	ProfilePicURL  string
+	FilmID         uuid.UUID // the reviewed film, or film a of a comparison
+	FilmTitle      string
+	FilmPosterURL  string
+	FilmBID        uuid.UUID // only set for comparisons
+	FilmBTitle     string
+	FilmBPosterURL string
+	Content        string
+	Rating         float32
+}
+
+// Kinds of feed event
+const (
+	FeedEventReview     = "review"
+	FeedEventComparison = "comparison"
+)
+
+type FeedItem struct {
+	Type          string    `json:"type"`
+	OccurredAt    time.Time `json:"occurredAt"`
+	UserID        uuid.UUID `json:"userId"`
+	Username      string    `json:"username"`
+	ProfilePicURL string    `json:"profilePicUrl"`
+	FilmID        uuid.UUID `json:"filmId"`
+	FilmTitle     string    `json:"filmTitle"`
+	FilmPosterURL string    `json:"filmPosterUrl"`
+	Review        *Review   `json:"review,omitempty"`
+	Rank          int       `json:"rank,omitempty"`       // the film's place in the user's ranking afterwards
+	RankChange    int       `json:"rankChange,omitempty"` // positive means the film moved up
+}
+
+type FeedPage struct {
+	Items      []FeedItem `json:"items"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
@@ -1,24 +1,30 @@
 package domain
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
 type Film struct {
-	ID          uuid.UUID `json:"id"`
-	ExternalID  int       `json:"externalId"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	PosterUrl   string    `json:"posterUrl"`
-	ReleaseYear string    `json:"releaseYear"`
+	ID          uuid.UUID     `json:"id"`
+	ExternalID  int           `json:"externalId"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	PosterUrl   string        `json:"posterUrl"`
+	ReleaseYear string        `json:"releaseYear"`
+	Metadata    *FilmMetadata `json:"metadata,omitempty"` // only set where it's been loaded or fetched
 }
 
 // Note this is a pure backend construct to track recommendations, not exposed via API
 type FilmRecommendation struct {
-	ID                     uuid.UUID `json:"id"`
-	UserID                 uuid.UUID `json:"userId"`
-	ExternalFilmID         int       `json:"externalFilmId"`
-	HasSeen                bool      `json:"hasSeen"`
-	HasBeenRecommended     bool      `json:"hasBeenRecommended"`
-	RecommendationsGenerated bool     `json:"recommendationsGenerated"`
+	ID                       uuid.UUID  `json:"id"`
+	UserID                   uuid.UUID  `json:"userId"`
+	ExternalFilmID           int        `json:"externalFilmId"`
+	HasSeen                  bool       `json:"hasSeen"`
+	HasBeenRecommended       bool       `json:"hasBeenRecommended"`
+	RecommendationsGenerated bool       `json:"recommendationsGenerated"`
+	RecommendedAt            *time.Time `json:"recommendedAt,omitempty"` // last time the film was recommended
+	DismissedAt              *time.Time `json:"dismissedAt,omitempty"`   // dismissed films are never recommended again
+	SnoozedUntil             *time.Time `json:"snoozedUntil,omitempty"`  // kept out of the inbox until then
 }
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Who can see a list
+const (
+	ListVisibilityPrivate = "private"
+	ListVisibilityPublic  = "public"
+)
+
+// How the films in a list are ordered
+const (
+	ListOrderingManual = "manual" // the order the owner put them in
+	ListOrderingElo    = "elo"    // the owner's elo ranking, unrated films last
+)
+
+type FilmList struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"userId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Visibility  string    `json:"visibility"`
+	Ordering    string    `json:"ordering"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type FilmListEntry struct {
+	FilmID          uuid.UUID `json:"filmId"`
+	Position        int       `json:"position"` // 1 based, in the list's ordering
+	AddedAt         time.Time `json:"addedAt"`
+	FilmTitle       string    `json:"filmTitle"`
+	FilmReleaseYear string    `json:"filmReleaseYear"`
+	FilmPosterURL   string    `json:"filmPosterUrl"`
+	EloRating       *float64  `json:"eloRating,omitempty"` // the owner's rating, when they have rated the film
+}
+
+type FilmListDetail struct {
+	List  FilmList        `json:"list"`
+	Films []FilmListEntry `json:"films"`
+}
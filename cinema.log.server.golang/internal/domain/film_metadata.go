@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// Roles people have on a film
+const (
+	FilmRoleDirector = "director"
+	FilmRoleCast     = "cast"
+)
+
+// FilmMetadata is what's stored about a film beyond its title and poster
+type FilmMetadata struct {
+	Runtime          int                 `json:"runtime"` // minutes, 0 when unknown
+	OriginalLanguage string              `json:"originalLanguage"`
+	Genres           []Genre             `json:"genres"`
+	Countries        []ProductionCountry `json:"countries"`
+	Directors        []Person            `json:"directors"`
+	Cast             []CastMember        `json:"cast"` // top billed only
+	UpdatedAt        time.Time           `json:"updatedAt"`
+}
+
+type Person struct {
+	ID          int    `json:"id"` // the provider's id
+	Name        string `json:"name"`
+	ProfilePath string `json:"profilePath"`
+}
+
+// FilmDetails is everything a metadata provider knows about a film beyond what's stored on Film
+type FilmDetails struct {
+	Film
+	Runtime             int                 `json:"runtime"` // minutes, 0 when unknown
+	OriginalLanguage    string              `json:"originalLanguage"`
+	Genres              []Genre             `json:"genres"`
+	ProductionCountries []ProductionCountry `json:"productionCountries"`
+}
+
+type Genre struct {
+	ID   int    `json:"id"` // the provider's id
+	Name string `json:"name"`
+}
+
+type ProductionCountry struct {
+	Code string `json:"code"` // ISO 3166-1 alpha-2
+	Name string `json:"name"`
+}
+
+type FilmCredits struct {
+	Cast []CastMember `json:"cast"`
+	Crew []CrewMember `json:"crew"`
+}
+
+type CastMember struct {
+	PersonID    int    `json:"personId"` // the provider's id
+	Name        string `json:"name"`
+	Character   string `json:"character"`
+	Order       int    `json:"order"` // billing order, 0 is top billed
+	ProfilePath string `json:"profilePath"`
+}
+
+type CrewMember struct {
+	PersonID    int    `json:"personId"` // the provider's id
+	Name        string `json:"name"`
+	Job         string `json:"job"`
+	Department  string `json:"department"`
+	ProfilePath string `json:"profilePath"`
+}
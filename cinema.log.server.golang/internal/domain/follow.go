@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FollowUser is a user in someone's followers or following list
+type FollowUser struct {
+	ID            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	Username      string    `json:"username"`
+	ProfilePicURL string    `json:"profilePicUrl"`
+	FollowedAt    time.Time `json:"followedAt"`
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Where recommendations come from, blend mixes both
+const (
+	RecommendationSourceTMDB      = "tmdb"
+	RecommendationSourceCinemaLog = "cinemalog"
+	RecommendationSourceBlend     = "blend"
+)
+
+// RecommendedFilm is a film with why it was recommended. Film is embedded so the response keeps the
+// shape of a plain film for clients that only read those fields.
+type RecommendedFilm struct {
+	Film
+	Score         float64                `json:"score,omitempty"` // predicted preference, only from cinema.log's own recommender
+	Reasons       []RecommendationReason `json:"reasons"`
+	RecommendedAt *time.Time             `json:"recommendedAt,omitempty"` // only set on stored recommendations
+}
+
+// RecommendationPage is a page of the recommendation inbox, newest first
+type RecommendationPage struct {
+	Items      []RecommendedFilm `json:"items"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+type RecommendationReason struct {
+	Source      string            `json:"source"`
+	Detail      string            `json:"detail"`
+	SeedsAgreed int               `json:"seedsAgreed"` // how many of the user's films led to this pick
+	BasedOn     []RecommendedFrom `json:"basedOn"`
+}
+
+// StoredRecommendationReason is a reason as it's kept for one of the user's recommendations, the
+// export archive carries these so a restore keeps where recommendations came from
+type StoredRecommendationReason struct {
+	ID             uuid.UUID         `json:"id"`
+	ExternalFilmID int               `json:"externalFilmId"`
+	Source         string            `json:"source"`
+	Detail         string            `json:"detail"`
+	Score          float64           `json:"score"`
+	SeedsAgreed    int               `json:"seedsAgreed"`
+	RecommendedAt  time.Time         `json:"recommendedAt"`
+	BasedOn        []RecommendedFrom `json:"basedOn"`
+}
+
+// RecommendedFrom is a film the user has seen that led to a recommendation
+type RecommendedFrom struct {
+	FilmID    uuid.UUID `json:"filmId"`
+	Title     string    `json:"title"`
+	EloRating *float64  `json:"eloRating,omitempty"` // the user's rating of the film, if they've rated it
+	TmdbRank  int       `json:"tmdbRank,omitempty"`  // where TMDB placed the pick in its recommendations for this film, 1 is first
+}
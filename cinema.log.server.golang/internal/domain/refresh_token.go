@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is the server's record of a refresh token, the token itself is never stored
+type RefreshToken struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"userId"`
+	FamilyID     uuid.UUID  `json:"familyId"` // shared by every token rotated from the same login
+	TokenHash    string     `json:"-"`
+	IssuedAt     time.Time  `json:"issuedAt"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	RotatedAt    *time.Time `json:"rotatedAt,omitempty"`
+	ReplacedByID *uuid.UUID `json:"replacedById,omitempty"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserStats struct {
+	FilmsPerYear       []PeriodCount     `json:"filmsPerYear"`
+	FilmsPerMonth      []PeriodCount     `json:"filmsPerMonth"`
+	RatingDistribution []RatingCount     `json:"ratingDistribution"`
+	EloByGenre         []EloAverage      `json:"eloByGenre"`
+	EloByDecade        []EloAverage      `json:"eloByDecade"`
+	EloByDirector      []EloAverage      `json:"eloByDirector"`
+	MostCompared       []ComparedFilm    `json:"mostCompared"`
+	ComparisonStreaks  ComparisonStreaks `json:"comparisonStreaks"`
+	TopTenHistory      []TopTenSnapshot  `json:"topTenHistory"`
+	ComputedAt         time.Time         `json:"computedAt"`
+}
+
+// PeriodCount is how many films were logged in a year, or a month of it
+type PeriodCount struct {
+	Year  int `json:"year"`
+	Month int `json:"month,omitempty"` // 1 to 12, left out for a whole year
+	Count int `json:"count"`
+}
+
+// RatingCount is how many reviews gave a star rating
+type RatingCount struct {
+	Rating float32 `json:"rating"`
+	Count  int     `json:"count"`
+}
+
+// EloAverage is the average elo of the user's rated films in a genre, decade or by a director
+type EloAverage struct {
+	Name       string  `json:"name"`
+	AverageElo float64 `json:"averageElo"`
+	Films      int     `json:"films"`
+}
+
+type ComparedFilm struct {
+	FilmID        uuid.UUID `json:"filmId"`
+	FilmTitle     string    `json:"filmTitle"`
+	FilmPosterURL string    `json:"filmPosterUrl"`
+	Comparisons   int       `json:"comparisons"`
+}
+
+// ComparisonStreaks count consecutive days with at least one comparison
+type ComparisonStreaks struct {
+	Current        int        `json:"current"` // 0 unless the streak includes today or yesterday
+	Longest        int        `json:"longest"`
+	LongestEnd     *time.Time `json:"longestEnd,omitempty"`
+	LastComparison *time.Time `json:"lastComparison,omitempty"`
+}
+
+// TopTenSnapshot is the user's top ten as it stood after a month of comparisons
+type TopTenSnapshot struct {
+	Date  time.Time    `json:"date"`
+	Films []RankedFilm `json:"films"` // best first
+}
+
+type RankedFilm struct {
+	Rank          int       `json:"rank"`
+	FilmID        uuid.UUID `json:"filmId"`
+	FilmTitle     string    `json:"filmTitle"`
+	FilmPosterURL string    `json:"filmPosterUrl"`
+}
@@ -7,7 +7,8 @@ import (
 )
 
 type User struct {
-	ID            uuid.UUID `json:"id"`
+	ID uuid.UUID `json:"id"`
+	// The GitHub and Google logins, read from the user's identities
 	GithubId      *int64    `json:"githubId,omitempty"`
 	GoogleId      *string   `json:"googleId,omitempty"`
 	Name          string    `json:"name"`
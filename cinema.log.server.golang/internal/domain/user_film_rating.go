@@ -15,6 +15,8 @@ type UserFilmRating struct {
 	LastUpdated         time.Time `json:"lastUpdated"`
 	InitialRating       float32   `json:"initialRating"`
 	KConstantValue      float64   `json:"kConstantValue"`
+	RatingDeviation     float64   `json:"ratingDeviation"` // only used by engines that track uncertainty, high means the rating is still a guess
+	Volatility          float64   `json:"volatility"`
 }
 
 type UserFilmRatingDetail struct {
@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity is one way a user logs in, the subject is the provider's id for them
+type UserIdentity struct {
+	ID               uuid.UUID `json:"id"`
+	UserID           uuid.UUID `json:"userId"`
+	Provider         string    `json:"provider"`
+	Subject          string    `json:"subject"`
+	ProviderUsername string    `json:"providerUsername"`
+	LinkedAt         time.Time `json:"linkedAt"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Where a film on a watchlist came from
+const (
+	WatchlistSourceSearch         = "search"
+	WatchlistSourceRecommendation = "recommendation"
+	WatchlistSourceFriend         = "friend"
+)
+
+type WatchlistEntry struct {
+	ID       uuid.UUID `json:"id"`
+	UserID   uuid.UUID `json:"userId"`
+	FilmID   uuid.UUID `json:"filmId"`
+	Priority int       `json:"priority"` // 1 is most wanted, 5 is someday
+	Note     string    `json:"note"`
+	Source   string    `json:"source"`
+	AddedAt  time.Time `json:"addedAt"`
+}
+
+type WatchlistEntryDetail struct {
+	Entry           WatchlistEntry `json:"entry"`
+	FilmTitle       string         `json:"filmTitle"`
+	FilmReleaseYear string         `json:"filmReleaseYear"`
+	FilmPosterURL   string         `json:"filmPosterUrl"`
+}
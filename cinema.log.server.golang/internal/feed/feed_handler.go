@@ -0,0 +1,59 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	FeedService FeedService
+}
+
+type FeedService interface {
+	GetFeed(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.FeedPage, error)
+}
+
+func NewHandler(feedService FeedService) *Handler {
+	return &Handler{
+		FeedService: feedService,
+	}
+}
+
+// GetFeed returns a page of the user's feed, pass the nextCursor of one page as ?cursor= to get the next
+func (h *Handler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	page, err := h.FeedService.GetFeed(r.Context(), user.ID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		switch err {
+		case ErrInvalidCursor, ErrInvalidLimit:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to get feed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.SendJSON(w, page)
+}
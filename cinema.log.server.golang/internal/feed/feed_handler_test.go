@@ -0,0 +1,98 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"github.com/google/uuid"
+)
+
+type mockFeedService struct {
+	getFeedFunc func(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.FeedPage, error)
+}
+
+func (m *mockFeedService) GetFeed(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.FeedPage, error) {
+	if m.getFeedFunc != nil {
+		return m.getFeedFunc(ctx, userId, cursor, limit)
+	}
+	return &domain.FeedPage{Items: []domain.FeedItem{}}, nil
+}
+
+func withUser(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), middleware.KeyUser, &domain.User{ID: uuid.New()}))
+}
+
+func TestHandler_GetFeed(t *testing.T) {
+	var gotCursor string
+	var gotLimit int
+	handler := NewHandler(&mockFeedService{
+		getFeedFunc: func(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.FeedPage, error) {
+			gotCursor, gotLimit = cursor, limit
+			return &domain.FeedPage{Items: []domain.FeedItem{{Type: domain.FeedItemReview}}, NextCursor: "next"}, nil
+		},
+	})
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/feed?cursor=abc&limit=10", nil))
+	w := httptest.NewRecorder()
+	handler.GetFeed(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if gotCursor != "abc" || gotLimit != 10 {
+		t.Errorf("expected cursor abc and limit 10, got %q and %d", gotCursor, gotLimit)
+	}
+	var page domain.FeedPage
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(page.Items) != 1 || page.NextCursor != "next" {
+		t.Errorf("expected the page, got %+v", page)
+	}
+}
+
+func TestHandler_GetFeed_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		err      error
+		expected int
+	}{
+		{"invalid limit", "?limit=abc", nil, http.StatusBadRequest},
+		{"limit out of range", "?limit=500", ErrInvalidLimit, http.StatusBadRequest},
+		{"invalid cursor", "?cursor=abc", ErrInvalidCursor, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockFeedService{
+				getFeedFunc: func(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.FeedPage, error) {
+					return nil, tt.err
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.GetFeed(w, withUser(httptest.NewRequest(http.MethodGet, "/feed"+tt.query, nil)))
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_GetFeed_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockFeedService{})
+
+	w := httptest.NewRecorder()
+	handler.GetFeed(w, httptest.NewRequest(http.MethodGet, "/feed", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,279 @@
+package feed
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/ratings"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("limit must be between 1 and 50")
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 50
+
+	// BigRankChange is how many places a comparison has to move a film before it makes the feed
+	BigRankChange = 5
+
+	// Most comparisons don't make the feed, so a page can take several batches of events to fill.
+	// After this many the page is returned short with a cursor to carry on from.
+	maxBatches = 5
+
+	// How many users' rank movements are kept, the least recently asked for are dropped first
+	CacheSize = 1000
+)
+
+// The first page starts after every possible event
+var feedStart = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+type Service struct {
+	FeedStore     FeedStore
+	RatingService RatingService
+
+	// Rank movements are cached per user along with the version they were replayed from, uses counts
+	// every time they're read so the least recently used can be found
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedMovements
+	uses  uint64
+}
+
+type cachedMovements struct {
+	version   string
+	movements map[uuid.UUID][]ratings.RankMovement
+	lastUsed  uint64
+}
+
+type FeedStore interface {
+	GetFeedEvents(ctx context.Context, userId uuid.UUID, before time.Time, beforeId uuid.UUID, limit int) ([]domain.FeedEvent, error)
+	GetMovementsVersion(ctx context.Context, userId uuid.UUID) (string, error)
+}
+
+type RatingService interface {
+	GetRankMovements(ctx context.Context, userId uuid.UUID) (map[uuid.UUID][]ratings.RankMovement, error)
+}
+
+func NewService(feedStore FeedStore, ratingService RatingService) *Service {
+	return &Service{
+		FeedStore:     feedStore,
+		RatingService: ratingService,
+		cache:         map[uuid.UUID]cachedMovements{},
+	}
+}
+
+// GetFeed returns a page of what the users userId follows have been doing, newest first. Every review
+// is an item, a comparison is only an item when it gave a film its first place in the ranking or
+// moved one by at least BigRankChange places. An empty cursor starts from the most recent activity.
+func (s *Service) GetFeed(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.FeedPage, error) {
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	if limit < 1 || limit > MaxPageSize {
+		return nil, ErrInvalidLimit
+	}
+
+	before, beforeId, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each user's rank movements are only looked up once per page
+	movements := map[uuid.UUID]map[uuid.UUID][]ratings.RankMovement{}
+
+	page := &domain.FeedPage{Items: []domain.FeedItem{}}
+	exhausted := false
+	for batch := 0; batch < maxBatches && len(page.Items) < limit && !exhausted; batch++ {
+		events, err := s.FeedStore.GetFeedEvents(ctx, userId, before, beforeId, limit)
+		if err != nil {
+			return nil, err
+		}
+		exhausted = len(events) < limit
+
+		for i, event := range events {
+			item, err := s.feedItem(ctx, event, movements)
+			if err != nil {
+				return nil, err
+			}
+			if item != nil {
+				page.Items = append(page.Items, *item)
+			}
+			before, beforeId = event.OccurredAt, event.ID
+
+			if len(page.Items) == limit {
+				// Anything left in the batch is picked up by the next page
+				exhausted = exhausted && i == len(events)-1
+				break
+			}
+		}
+	}
+
+	if !exhausted {
+		page.NextCursor = encodeCursor(before, beforeId)
+	}
+
+	return page, nil
+}
+
+func (s *Service) feedItem(ctx context.Context, event domain.FeedEvent, movements map[uuid.UUID]map[uuid.UUID][]ratings.RankMovement) (*domain.FeedItem, error) {
+	item := domain.FeedItem{
+		OccurredAt:    event.OccurredAt,
+		UserID:        event.UserID,
+		Username:      event.Username,
+		ProfilePicURL: event.ProfilePicURL,
+		FilmID:        event.FilmID,
+		FilmTitle:     event.FilmTitle,
+		FilmPosterURL: event.FilmPosterURL,
+	}
+
+	if event.Kind == domain.FeedEventReview {
+		item.Type = domain.FeedItemReview
+		item.Review = &domain.Review{
+			ID:      event.ID,
+			Content: event.Content,
+			Date:    event.OccurredAt,
+			Rating:  event.Rating,
+			FilmId:  event.FilmID,
+			UserId:  event.UserID,
+		}
+		return &item, nil
+	}
+
+	userMovements, ok := movements[event.UserID]
+	if !ok {
+		var err error
+		userMovements, err = s.rankMovements(ctx, event.UserID)
+		if err != nil {
+			return nil, err
+		}
+		movements[event.UserID] = userMovements
+	}
+
+	movement, ok := notableMovement(userMovements[event.ID])
+	if !ok {
+		return nil, nil
+	}
+
+	if movement.FilmId == event.FilmBID {
+		item.FilmID, item.FilmTitle, item.FilmPosterURL = event.FilmBID, event.FilmBTitle, event.FilmBPosterURL
+	}
+	item.Rank = movement.NewRank
+	if movement.FirstComparison {
+		item.Type = domain.FeedItemNewRating
+	} else {
+		item.Type = domain.FeedItemRankChange
+		item.RankChange = movement.RankChange
+	}
+
+	return &item, nil
+}
+
+// rankMovements returns how each of the user's comparisons moved its films. They come from replaying
+// the user's whole history, so they're only replayed again once a comparison or rating has been added,
+// changed or removed since the last time, or when they've been dropped from the cache.
+func (s *Service) rankMovements(ctx context.Context, userId uuid.UUID) (map[uuid.UUID][]ratings.RankMovement, error) {
+	version, err := s.FeedStore.GetMovementsVersion(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	cached, ok := s.cache[userId]
+	if ok && cached.version == version {
+		s.uses++
+		cached.lastUsed = s.uses
+		s.cache[userId] = cached
+		s.mu.Unlock()
+		return cached.movements, nil
+	}
+	s.mu.Unlock()
+
+	movements, err := s.RatingService.GetRankMovements(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if _, ok := s.cache[userId]; !ok && len(s.cache) >= CacheSize {
+		s.evictLeastRecentlyUsed()
+	}
+	s.uses++
+	s.cache[userId] = cachedMovements{version: version, movements: movements, lastUsed: s.uses}
+	s.mu.Unlock()
+
+	return movements, nil
+}
+
+// evictLeastRecentlyUsed drops the movements that were asked for longest ago to make room for another
+// user's. Callers must hold s.mu.
+func (s *Service) evictLeastRecentlyUsed() {
+	var oldest uuid.UUID
+	oldestUsed := s.uses + 1
+	for userId, cached := range s.cache {
+		if cached.lastUsed < oldestUsed {
+			oldest, oldestUsed = userId, cached.lastUsed
+		}
+	}
+	delete(s.cache, oldest)
+}
+
+// notableMovement picks what a comparison is shown as, a film getting its first place in the ranking
+// beats a big move and a bigger move beats a smaller one
+func notableMovement(comparisonMovements []ratings.RankMovement) (ratings.RankMovement, bool) {
+	var notable ratings.RankMovement
+	found := false
+	for _, movement := range comparisonMovements {
+		if movement.FirstComparison {
+			return movement, true
+		}
+		if abs(movement.RankChange) >= BigRankChange && (!found || abs(movement.RankChange) > abs(notable.RankChange)) {
+			notable, found = movement, true
+		}
+	}
+	return notable, found
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// A cursor is the time and id of the last event a page looked at
+func encodeCursor(occurredAt time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(occurredAt.Format(time.RFC3339Nano) + "|" + id.String()))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	if cursor == "" {
+		return feedStart, uuid.Max, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	occurredAtStr, idStr, ok := strings.Cut(string(decoded), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	occurredAt, err := time.Parse(time.RFC3339Nano, occurredAtStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	return occurredAt, id, nil
+}
@@ -0,0 +1,236 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/ratings"
+	"github.com/google/uuid"
+)
+
+// mockFeedStore pages through events the way the real store does, events must be newest first
+type mockFeedStore struct {
+	events []domain.FeedEvent
+	calls  int
+	// Every user's rank movements version, changing it stands in for a new comparison
+	version string
+}
+
+func (m *mockFeedStore) GetFeedEvents(ctx context.Context, userId uuid.UUID, before time.Time, beforeId uuid.UUID, limit int) ([]domain.FeedEvent, error) {
+	m.calls++
+	events := []domain.FeedEvent{}
+	for _, event := range m.events {
+		if event.OccurredAt.Before(before) || (event.OccurredAt.Equal(before) && event.ID.String() < beforeId.String()) {
+			events = append(events, event)
+		}
+		if len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (m *mockFeedStore) GetMovementsVersion(ctx context.Context, userId uuid.UUID) (string, error) {
+	return m.version, nil
+}
+
+type mockRatingService struct {
+	movements map[uuid.UUID][]ratings.RankMovement
+	calls     int
+}
+
+func (m *mockRatingService) GetRankMovements(ctx context.Context, userId uuid.UUID) (map[uuid.UUID][]ratings.RankMovement, error) {
+	m.calls++
+	return m.movements, nil
+}
+
+var feedNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func review(userId uuid.UUID, minutesAgo int) domain.FeedEvent {
+	return domain.FeedEvent{ID: uuid.New(), Kind: domain.FeedEventReview, OccurredAt: feedNow.Add(-time.Duration(minutesAgo) * time.Minute), UserID: userId, FilmID: uuid.New(), Content: "Loved it", Rating: 4.5}
+}
+
+func comparison(userId uuid.UUID, minutesAgo int) domain.FeedEvent {
+	return domain.FeedEvent{ID: uuid.New(), Kind: domain.FeedEventComparison, OccurredAt: feedNow.Add(-time.Duration(minutesAgo) * time.Minute), UserID: userId, FilmID: uuid.New(), FilmBID: uuid.New(), FilmBTitle: "Film B"}
+}
+
+func TestService_GetFeed_ComparisonItems(t *testing.T) {
+	friend := uuid.New()
+	newRating, bigMove, smallMove := comparison(friend, 1), comparison(friend, 2), comparison(friend, 3)
+	rating := &mockRatingService{movements: map[uuid.UUID][]ratings.RankMovement{
+		newRating.ID: {
+			{FilmId: newRating.FilmID, NewRank: 3, RankChange: 1},
+			{FilmId: newRating.FilmBID, NewRank: 7, RankChange: 2, FirstComparison: true},
+		},
+		bigMove.ID: {
+			{FilmId: bigMove.FilmID, NewRank: 12, RankChange: -6},
+			{FilmId: bigMove.FilmBID, NewRank: 4, RankChange: 8},
+		},
+		smallMove.ID: {
+			{FilmId: smallMove.FilmID, NewRank: 2, RankChange: 1},
+			{FilmId: smallMove.FilmBID, NewRank: 3, RankChange: -1},
+		},
+	}}
+	service := NewService(&mockFeedStore{events: []domain.FeedEvent{newRating, bigMove, smallMove, review(friend, 4)}}, rating)
+
+	page, err := service.GetFeed(context.Background(), uuid.New(), "", 10)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(page.Items) != 3 || page.NextCursor != "" {
+		t.Fatalf("expected three items on a single page, got %+v", page)
+	}
+
+	if item := page.Items[0]; item.Type != domain.FeedItemNewRating || item.FilmID != newRating.FilmBID || item.FilmTitle != "Film B" || item.Rank != 7 {
+		t.Errorf("expected film b's new rating, got %+v", item)
+	}
+	if item := page.Items[1]; item.Type != domain.FeedItemRankChange || item.FilmID != bigMove.FilmBID || item.RankChange != 8 {
+		t.Errorf("expected the bigger of the two moves, got %+v", item)
+	}
+	if item := page.Items[2]; item.Type != domain.FeedItemReview || item.Review == nil || item.Review.Rating != 4.5 {
+		t.Errorf("expected the review, got %+v", item)
+	}
+	if rating.calls != 1 {
+		t.Errorf("expected one replay for the user, got %d", rating.calls)
+	}
+}
+
+func TestService_GetFeed_CachesRankMovements(t *testing.T) {
+	friend := uuid.New()
+	bigMove := comparison(friend, 1)
+	store := &mockFeedStore{events: []domain.FeedEvent{bigMove}, version: "v1"}
+	rating := &mockRatingService{movements: map[uuid.UUID][]ratings.RankMovement{
+		bigMove.ID: {{FilmId: bigMove.FilmID, NewRank: 1, RankChange: 6}},
+	}}
+	service := NewService(store, rating)
+	ctx := context.Background()
+
+	for range 3 {
+		page, err := service.GetFeed(ctx, uuid.New(), "", 10)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(page.Items) != 1 || page.Items[0].RankChange != 6 {
+			t.Fatalf("expected the big move, got %+v", page.Items)
+		}
+	}
+	if rating.calls != 1 {
+		t.Errorf("expected the movements to be replayed once across pages, got %d", rating.calls)
+	}
+
+	// A new comparison changes the version and the user is replayed again
+	store.version = "v2"
+	if _, err := service.GetFeed(ctx, uuid.New(), "", 10); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if rating.calls != 2 {
+		t.Errorf("expected a new version to be replayed, got %d replays", rating.calls)
+	}
+}
+
+func TestService_RankMovements_EvictsLeastRecentlyUsed(t *testing.T) {
+	rating := &mockRatingService{}
+	service := NewService(&mockFeedStore{version: "v1"}, rating)
+	ctx := context.Background()
+
+	first := uuid.New()
+	service.rankMovements(ctx, first)
+	for range CacheSize {
+		service.rankMovements(ctx, uuid.New())
+	}
+	if len(service.cache) != CacheSize {
+		t.Errorf("expected the cache to stay at %d users, got %d", CacheSize, len(service.cache))
+	}
+	if _, ok := service.cache[first]; ok {
+		t.Error("expected the least recently used user to be dropped")
+	}
+}
+
+func TestService_GetFeed_Paging(t *testing.T) {
+	friend := uuid.New()
+	store := &mockFeedStore{}
+	for i := range 5 {
+		store.events = append(store.events, review(friend, i))
+	}
+	service := NewService(store, &mockRatingService{})
+	ctx := context.Background()
+
+	var seen []uuid.UUID
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("expected paging to finish")
+		}
+		page, err := service.GetFeed(ctx, uuid.New(), cursor, 2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for _, item := range page.Items {
+			seen = append(seen, item.Review.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if len(seen) != 5 {
+		t.Fatalf("expected every review once, got %d", len(seen))
+	}
+	for i, id := range seen {
+		if id != store.events[i].ID {
+			t.Errorf("expected review %d to be %v, got %v", i, store.events[i].ID, id)
+		}
+	}
+}
+
+func TestService_GetFeed_SkipsQuietComparisons(t *testing.T) {
+	friend := uuid.New()
+	store := &mockFeedStore{}
+	// Four comparisons that didn't move anything much before the review
+	for i := range 4 {
+		store.events = append(store.events, comparison(friend, i))
+	}
+	store.events = append(store.events, review(friend, 10))
+	service := NewService(store, &mockRatingService{})
+
+	page, err := service.GetFeed(context.Background(), uuid.New(), "", 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Type != domain.FeedItemReview || page.NextCursor != "" {
+		t.Errorf("expected only the review, got %+v", page)
+	}
+	if store.calls != 3 {
+		t.Errorf("expected three batches to reach the review, got %d", store.calls)
+	}
+}
+
+func TestService_GetFeed_Validation(t *testing.T) {
+	service := NewService(&mockFeedStore{}, &mockRatingService{})
+	ctx := context.Background()
+
+	if _, err := service.GetFeed(ctx, uuid.New(), "", MaxPageSize+1); err != ErrInvalidLimit {
+		t.Errorf("expected ErrInvalidLimit, got %v", err)
+	}
+	for _, cursor := range []string{"not base64!", "bm8tc2VwYXJhdG9y", encodeCursor(feedNow, uuid.New())[:10]} {
+		if _, err := service.GetFeed(ctx, uuid.New(), cursor, 0); err != ErrInvalidCursor {
+			t.Errorf("expected ErrInvalidCursor for %q, got %v", cursor, err)
+		}
+	}
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	id := uuid.New()
+	occurredAt := time.Date(2025, 6, 1, 12, 30, 15, 123456000, time.UTC)
+
+	gotTime, gotId, err := decodeCursor(encodeCursor(occurredAt, id))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !gotTime.Equal(occurredAt) || gotId != id {
+		t.Errorf("expected %v and %v, got %v and %v", occurredAt, id, gotTime, gotId)
+	}
+}
@@ -0,0 +1,111 @@
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) FeedStore {
+	return &store{
+		db: db,
+	}
+}
+
+// GetFeedEvents returns up to limit reviews and comparisons by the users userId follows that happened
+// before the (before, beforeId) key, newest first. Ties on time are broken by id so paging never skips
+// or repeats an event.
+func (s *store) GetFeedEvents(ctx context.Context, userId uuid.UUID, before time.Time, beforeId uuid.UUID, limit int) ([]domain.FeedEvent, error) {
+	query := /* sql */ `
+		SELECT e.id, e.kind, e.occurred_at, e.user_id, u.username, COALESCE(u.profile_pic_url, ''),
+		       e.film_id, fa.title, COALESCE(fa.poster_url, ''),
+		       COALESCE(e.film_b_id, '00000000-0000-0000-0000-000000000000'), COALESCE(fb.title, ''), COALESCE(fb.poster_url, ''),
+		       e.content, e.rating
+		FROM (
+			SELECT r.review_id AS id, 'review' AS kind, r.date AS occurred_at, r.user_id, r.film_id,
+			       NULL::uuid AS film_b_id, COALESCE(r.content, '') AS content, r.rating
+			FROM reviews r
+			JOIN user_follows uf ON uf.followee_id = r.user_id
+			WHERE uf.follower_id = $1
+			AND (r.date, r.review_id) < ($2::timestamp, $3::uuid)
+			UNION ALL
+			SELECT c.comparison_history_id, 'comparison', c.comparison_date, c.user_id, c.film_a_film_id,
+			       c.film_b_film_id, '', 0
+			FROM comparison_histories c
+			JOIN user_follows uf ON uf.followee_id = c.user_id
+			WHERE uf.follower_id = $1
+			AND (c.comparison_date, c.comparison_history_id) < ($2::timestamp, $3::uuid)
+		) e
+		JOIN users u ON e.user_id = u.user_id
+		JOIN films fa ON e.film_id = fa.film_id
+		LEFT JOIN films fb ON e.film_b_id = fb.film_id
+		ORDER BY e.occurred_at DESC, e.id DESC
+		LIMIT $4
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userId, before, beforeId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []domain.FeedEvent{}
+	for rows.Next() {
+		var event domain.FeedEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.Kind,
+			&event.OccurredAt,
+			&event.UserID,
+			&event.Username,
+			&event.ProfilePicURL,
+			&event.FilmID,
+			&event.FilmTitle,
+			&event.FilmPosterURL,
+			&event.FilmBID,
+			&event.FilmBTitle,
+			&event.FilmBPosterURL,
+			&event.Content,
+			&event.Rating,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetMovementsVersion fingerprints everything a user's rank movements are replayed from: their rating
+// engine, every comparison and every rating. Editing or deleting a comparison keeps the others as they
+// were, so the rows themselves are hashed rather than counted.
+func (s *store) GetMovementsVersion(ctx context.Context, userId uuid.UUID) (string, error) {
+	query := /* sql */ `
+		SELECT md5(concat_ws('|',
+			(SELECT rating_engine FROM users WHERE user_id = $1),
+			(SELECT string_agg(concat_ws(':', comparison_history_id, film_a_film_id, film_b_film_id, winning_film_film_id, was_equal, comparison_date), ',' ORDER BY comparison_history_id)
+				FROM comparison_histories WHERE user_id = $1),
+			(SELECT string_agg(concat_ws(':', film_id, initial_rating, elo_rating, number_of_comparisons), ',' ORDER BY film_id)
+				FROM user_film_ratings WHERE user_id = $1)
+		))
+	`
+
+	var version string
+	if err := s.db.QueryRowContext(ctx, query, userId).Scan(&version); err != nil {
+		return "", err
+	}
+
+	return version, nil
+}
@@ -0,0 +1,154 @@
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	testDB      *sql.DB
+	testStore   FeedStore
+	testDbSetup *utils.TestDatabase
+)
+
+// Helper function to create test user
+func createTestUser(ctx context.Context, t *testing.T) uuid.UUID {
+	userID := uuid.New()
+	query := `INSERT INTO users (user_id, name, username, profile_pic_url)
+	          VALUES ($1, $2, $3, $4)`
+	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], "http://example.com/pic.jpg")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return userID
+}
+
+// Helper function to create test film
+func createTestFilm(ctx context.Context, t *testing.T) uuid.UUID {
+	filmID := uuid.New()
+	query := `INSERT INTO films (film_id, external_id, title, description, poster_url, release_year)
+	          VALUES ($1, $2, $3, $4, $5, $6)`
+	externalID := int(time.Now().UnixNano() % 2147483647) // Use nanoseconds for uniqueness
+	_, err := testDB.ExecContext(ctx, query, filmID, externalID, "Test Film "+filmID.String()[:8], "Description", "/poster.jpg", "2024")
+	if err != nil {
+		t.Fatalf("failed to create test film: %v", err)
+	}
+	return filmID
+}
+
+func createTestReview(ctx context.Context, t *testing.T, userId uuid.UUID, filmId uuid.UUID, date time.Time) uuid.UUID {
+	reviewID := uuid.New()
+	_, err := testDB.ExecContext(ctx, `INSERT INTO reviews (review_id, content, date, rating, film_id, user_id) VALUES ($1, 'Great', $2, 4, $3, $4)`,
+		reviewID, date, filmId, userId)
+	if err != nil {
+		t.Fatalf("failed to create review: %v", err)
+	}
+	return reviewID
+}
+
+func createTestComparison(ctx context.Context, t *testing.T, userId uuid.UUID, filmA uuid.UUID, filmB uuid.UUID, date time.Time) uuid.UUID {
+	comparisonID := uuid.New()
+	_, err := testDB.ExecContext(ctx, `INSERT INTO comparison_histories (comparison_history_id, user_id, film_a_film_id, film_b_film_id, winning_film_film_id, comparison_date, was_equal)
+		VALUES ($1, $2, $3, $4, $3, $5, false)`, comparisonID, userId, filmA, filmB, date)
+	if err != nil {
+		t.Fatalf("failed to create comparison: %v", err)
+	}
+	return comparisonID
+}
+
+func follow(ctx context.Context, t *testing.T, followerId uuid.UUID, followeeId uuid.UUID) {
+	if _, err := testDB.ExecContext(ctx, `INSERT INTO user_follows (follower_id, followee_id) VALUES ($1, $2)`, followerId, followeeId); err != nil {
+		t.Fatalf("failed to follow user: %v", err)
+	}
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	testDbSetup, err = utils.StartTestPostgres()
+	if err != nil {
+		log.Fatalf("could not start test database: %v", err)
+	}
+
+	testDB = testDbSetup.DB
+	testStore = NewStore(testDB)
+
+	code := m.Run()
+
+	testDbSetup.Close()
+	os.Exit(code)
+}
+
+func TestFeedStore_GetFeedEvents(t *testing.T) {
+	ctx := context.Background()
+	me, friend, stranger := createTestUser(ctx, t), createTestUser(ctx, t), createTestUser(ctx, t)
+	follow(ctx, t, me, friend)
+	filmA, filmB := createTestFilm(ctx, t), createTestFilm(ctx, t)
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	oldReview := createTestReview(ctx, t, friend, filmA, start)
+	comparison := createTestComparison(ctx, t, friend, filmA, filmB, start.Add(time.Hour))
+	newReview := createTestReview(ctx, t, friend, filmB, start.Add(2*time.Hour))
+	createTestReview(ctx, t, stranger, filmA, start.Add(3*time.Hour))
+
+	first, err := testStore.GetFeedEvents(ctx, me, feedStart, uuid.Max, 2)
+	if err != nil {
+		t.Fatalf("failed to get feed events: %v", err)
+	}
+	if len(first) != 2 || first[0].ID != newReview || first[1].ID != comparison {
+		t.Fatalf("expected the newest review then the comparison, got %+v", first)
+	}
+	if first[0].Kind != domain.FeedEventReview || first[0].Content != "Great" || first[0].FilmID != filmB {
+		t.Errorf("unexpected review event %+v", first[0])
+	}
+	if first[1].Kind != domain.FeedEventComparison || first[1].FilmID != filmA || first[1].FilmBID != filmB || first[1].FilmBTitle == "" {
+		t.Errorf("unexpected comparison event %+v", first[1])
+	}
+
+	second, err := testStore.GetFeedEvents(ctx, me, first[1].OccurredAt, first[1].ID, 2)
+	if err != nil {
+		t.Fatalf("failed to get feed events: %v", err)
+	}
+	if len(second) != 1 || second[0].ID != oldReview {
+		t.Errorf("expected only the oldest review, got %+v", second)
+	}
+
+	if events, _ := testStore.GetFeedEvents(ctx, friend, feedStart, uuid.Max, 10); len(events) != 0 {
+		t.Errorf("expected nothing for a user who follows nobody, got %+v", events)
+	}
+}
+
+func TestFeedStore_GetMovementsVersion(t *testing.T) {
+	ctx := context.Background()
+	user := createTestUser(ctx, t)
+	filmA, filmB := createTestFilm(ctx, t), createTestFilm(ctx, t)
+
+	before, err := testStore.GetMovementsVersion(ctx, user)
+	if err != nil {
+		t.Fatalf("failed to get movements version: %v", err)
+	}
+	if again, _ := testStore.GetMovementsVersion(ctx, user); again != before {
+		t.Errorf("expected the version to be stable, got %q then %q", before, again)
+	}
+
+	createTestComparison(ctx, t, user, filmA, filmB, time.Now())
+	compared, _ := testStore.GetMovementsVersion(ctx, user)
+	if compared == before {
+		t.Error("expected a new comparison to change the version")
+	}
+
+	// Editing a comparison keeps how many there are and when they were made, but not the winner
+	if _, err := testDB.ExecContext(ctx, `UPDATE comparison_histories SET winning_film_film_id = $2 WHERE user_id = $1`, user, filmB); err != nil {
+		t.Fatalf("failed to update comparison: %v", err)
+	}
+	if edited, _ := testStore.GetMovementsVersion(ctx, user); edited == compared {
+		t.Error("expected an edited comparison to change the version")
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
 	"cinema.log.server.golang/internal/utils"
 	"github.com/google/uuid"
 )
@@ -19,8 +22,9 @@ var (
 )
 
 type Handler struct {
-	FilmService   FilmService
-	RatingService RatingService
+	FilmService      FilmService
+	RatingService    RatingService
+	WatchlistService WatchlistService
 }
 
 type FilmService interface {
@@ -28,8 +32,13 @@ type FilmService interface {
 	GetFilmById(ctx context.Context, id uuid.UUID) (*domain.Film, error)
 	GetFilmsFromExternal(ctx context.Context, query string) ([]domain.Film, error) // ? pagination?
 	GetFilmsForRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) ([]domain.Film, error)
-	GenerateFilmRecommendations(ctx context.Context, userId uuid.UUID, films []domain.Film) ([]domain.Film, error)
+	GenerateFilmRecommendations(ctx context.Context, userId uuid.UUID, films []domain.Film, source string) ([]domain.RecommendedFilm, error)
 	GetSeenUnratedFilms(ctx context.Context, userId uuid.UUID) ([]domain.Film, error)
+	GetRecommendations(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.RecommendationPage, error)
+	GetRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.FilmRecommendation, error)
+	DismissRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
+	SnoozeRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, until time.Time) error
+	MarkRecommendationSeen(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
 }
 
 type RatingService interface {
@@ -37,10 +46,19 @@ type RatingService interface {
 	HasBeenCompared(ctx context.Context, userId, filmAId, filmBId uuid.UUID) (bool, error)
 }
 
-func NewHandler(filmService FilmService, ratingService RatingService) *Handler {
+type WatchlistService interface {
+	AddToWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, priority int, note string, source string) (*domain.WatchlistEntry, error)
+}
+
+type SnoozeRecommendationRequest struct {
+	Until time.Time `json:"until"`
+}
+
+func NewHandler(filmService FilmService, ratingService RatingService, watchlistService WatchlistService) *Handler {
 	return &Handler{
-		FilmService:   filmService,
-		RatingService: ratingService,
+		FilmService:      filmService,
+		RatingService:    ratingService,
+		WatchlistService: watchlistService,
 	}
 }
 
@@ -184,9 +202,9 @@ func (h *Handler) GenerateFilmRecommendations(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	recommendations, err := h.FilmService.GenerateFilmRecommendations(r.Context(), userID, films)
+	recommendations, err := h.FilmService.GenerateFilmRecommendations(r.Context(), userID, films, r.URL.Query().Get("source"))
 	if err != nil {
-		if errors.Is(err, ErrTooManyFilms) {
+		if errors.Is(err, ErrTooManyFilms) || errors.Is(err, ErrInvalidSource) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -218,3 +236,149 @@ func (h *Handler) GetSeenUnratedFilms(w http.ResponseWriter, r *http.Request) {
 
 	utils.SendJSON(w, films)
 }
+
+// GetRecommendations returns a page of the authenticated user's recommendation inbox with why each film was recommended
+func (h *Handler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	page, err := h.FilmService.GetRecommendations(r.Context(), user.ID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		switch err {
+		case ErrInvalidCursor, ErrInvalidLimit:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, ErrServer.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.SendJSON(w, page)
+}
+
+func (h *Handler) DismissRecommendation(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filmId, err := utils.ParseUUID(r.PathValue("filmId"))
+	if err != nil {
+		http.Error(w, "Invalid film ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.FilmService.DismissRecommendation(r.Context(), user.ID, filmId); err != nil {
+		recommendationActionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) SnoozeRecommendation(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filmId, err := utils.ParseUUID(r.PathValue("filmId"))
+	if err != nil {
+		http.Error(w, "Invalid film ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SnoozeRecommendationRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.FilmService.SnoozeRecommendation(r.Context(), user.ID, filmId, req.Until); err != nil {
+		recommendationActionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) MarkRecommendationSeen(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filmId, err := utils.ParseUUID(r.PathValue("filmId"))
+	if err != nil {
+		http.Error(w, "Invalid film ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.FilmService.MarkRecommendationSeen(r.Context(), user.ID, filmId); err != nil {
+		recommendationActionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddRecommendationToWatchlist puts a recommended film on the user's watchlist, which takes it out of their inbox
+func (h *Handler) AddRecommendationToWatchlist(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filmId, err := utils.ParseUUID(r.PathValue("filmId"))
+	if err != nil {
+		http.Error(w, "Invalid film ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.FilmService.GetRecommendation(r.Context(), user.ID, filmId); err != nil {
+		recommendationActionError(w, err)
+		return
+	}
+
+	entry, err := h.WatchlistService.AddToWatchlist(r.Context(), user.ID, filmId, 0, "", domain.WatchlistSourceRecommendation)
+	if err != nil {
+		http.Error(w, "Failed to add film to watchlist", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	utils.SendJSON(w, entry)
+}
+
+func recommendationActionError(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrFilmRecommendationNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case ErrInvalidSnooze:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, ErrServer.Error(), http.StatusInternalServerError)
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
 	"github.com/google/uuid"
 )
 
@@ -15,8 +18,13 @@ type mockFilmService struct {
 	createFilmFunc                  func(ctx context.Context, film *domain.Film) (*domain.Film, error)
 	getFilmByIdFunc                 func(ctx context.Context, id uuid.UUID) (*domain.Film, error)
 	getFilmsFromExternalFunc        func(ctx context.Context, query string) ([]domain.Film, error)
-	generateFilmRecommendationsFunc func(ctx context.Context, userId uuid.UUID, films []domain.Film) ([]domain.Film, error)
+	generateFilmRecommendationsFunc func(ctx context.Context, userId uuid.UUID, films []domain.Film, source string) ([]domain.RecommendedFilm, error)
 	getSeenUnratedFilmsFunc         func(ctx context.Context, userId uuid.UUID) ([]domain.Film, error)
+	getRecommendationsFunc          func(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.RecommendationPage, error)
+	getRecommendationFunc           func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.FilmRecommendation, error)
+	dismissRecommendationFunc       func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
+	snoozeRecommendationFunc        func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, until time.Time) error
+	markRecommendationSeenFunc      func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
 }
 
 func (m *mockFilmService) GetFilmsForRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) ([]domain.Film, error) {
@@ -47,11 +55,15 @@ func (m *mockFilmService) GetFilmsFromExternal(ctx context.Context, query string
 	return []domain.Film{{ID: uuid.New(), Title: "External Film"}}, nil
 }
 
-func (m *mockFilmService) GenerateFilmRecommendations(ctx context.Context, userId uuid.UUID, films []domain.Film) ([]domain.Film, error) {
+func (m *mockFilmService) GenerateFilmRecommendations(ctx context.Context, userId uuid.UUID, films []domain.Film, source string) ([]domain.RecommendedFilm, error) {
 	if m.generateFilmRecommendationsFunc != nil {
-		return m.generateFilmRecommendationsFunc(ctx, userId, films)
+		return m.generateFilmRecommendationsFunc(ctx, userId, films, source)
 	}
-	return films, nil
+	recommendations := []domain.RecommendedFilm{}
+	for _, film := range films {
+		recommendations = append(recommendations, domain.RecommendedFilm{Film: film})
+	}
+	return recommendations, nil
 }
 
 func (m *mockFilmService) GetSeenUnratedFilms(ctx context.Context, userId uuid.UUID) ([]domain.Film, error) {
@@ -61,6 +73,52 @@ func (m *mockFilmService) GetSeenUnratedFilms(ctx context.Context, userId uuid.U
 	return []domain.Film{{ID: uuid.New(), Title: "Seen Unrated Film"}}, nil
 }
 
+func (m *mockFilmService) GetRecommendations(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.RecommendationPage, error) {
+	if m.getRecommendationsFunc != nil {
+		return m.getRecommendationsFunc(ctx, userId, cursor, limit)
+	}
+	return &domain.RecommendationPage{Items: []domain.RecommendedFilm{}}, nil
+}
+
+func (m *mockFilmService) GetRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.FilmRecommendation, error) {
+	if m.getRecommendationFunc != nil {
+		return m.getRecommendationFunc(ctx, userId, filmId)
+	}
+	return &domain.FilmRecommendation{ID: uuid.New(), UserID: userId, HasBeenRecommended: true}, nil
+}
+
+func (m *mockFilmService) DismissRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	if m.dismissRecommendationFunc != nil {
+		return m.dismissRecommendationFunc(ctx, userId, filmId)
+	}
+	return nil
+}
+
+func (m *mockFilmService) SnoozeRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, until time.Time) error {
+	if m.snoozeRecommendationFunc != nil {
+		return m.snoozeRecommendationFunc(ctx, userId, filmId, until)
+	}
+	return nil
+}
+
+func (m *mockFilmService) MarkRecommendationSeen(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	if m.markRecommendationSeenFunc != nil {
+		return m.markRecommendationSeenFunc(ctx, userId, filmId)
+	}
+	return nil
+}
+
+type mockWatchlistService struct {
+	addToWatchlistFunc func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, priority int, note string, source string) (*domain.WatchlistEntry, error)
+}
+
+func (m *mockWatchlistService) AddToWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, priority int, note string, source string) (*domain.WatchlistEntry, error) {
+	if m.addToWatchlistFunc != nil {
+		return m.addToWatchlistFunc(ctx, userId, filmId, priority, note, source)
+	}
+	return &domain.WatchlistEntry{ID: uuid.New(), UserID: userId, FilmID: filmId, Source: source}, nil
+}
+
 type mockRatingService struct {
 	getAllRatingsFunc              func(ctx context.Context) ([]domain.UserFilmRating, error)
 	filterRatingsForComparisonFunc func([]domain.UserFilmRating) []domain.UserFilmRating
@@ -92,7 +150,7 @@ func (m *mockRatingService) HasBeenCompared(ctx context.Context, userId, filmAId
 func TestNewHandler_Films(t *testing.T) {
 	mockFilmSvc := &mockFilmService{}
 	mockRatingSvc := &mockRatingService{}
-	handler := NewHandler(mockFilmSvc, mockRatingSvc)
+	handler := NewHandler(mockFilmSvc, mockRatingSvc, &mockWatchlistService{})
 
 	if handler == nil {
 		t.Fatal("expected non-nil handler")
@@ -105,7 +163,7 @@ func TestNewHandler_Films(t *testing.T) {
 func TestHandler_GetFilmById_Success(t *testing.T) {
 	mockFilmSvc := &mockFilmService{}
 	mockRatingSvc := &mockRatingService{}
-	handler := NewHandler(mockFilmSvc, mockRatingSvc)
+	handler := NewHandler(mockFilmSvc, mockRatingSvc, &mockWatchlistService{})
 
 	filmId := uuid.New()
 	req := httptest.NewRequest(http.MethodGet, "/films/"+filmId.String(), nil)
@@ -122,7 +180,7 @@ func TestHandler_GetFilmById_Success(t *testing.T) {
 func TestHandler_GetFilmById_InvalidUUID(t *testing.T) {
 	mockFilmSvc := &mockFilmService{}
 	mockRatingSvc := &mockRatingService{}
-	handler := NewHandler(mockFilmSvc, mockRatingSvc)
+	handler := NewHandler(mockFilmSvc, mockRatingSvc, &mockWatchlistService{})
 
 	req := httptest.NewRequest(http.MethodGet, "/films/invalid", nil)
 	req.SetPathValue("id", "invalid")
@@ -142,7 +200,7 @@ func TestHandler_GetFilmById_NotFound(t *testing.T) {
 		},
 	}
 	mockRatingSvc := &mockRatingService{}
-	handler := NewHandler(mockFilmSvc, mockRatingSvc)
+	handler := NewHandler(mockFilmSvc, mockRatingSvc, &mockWatchlistService{})
 
 	filmId := uuid.New()
 	req := httptest.NewRequest(http.MethodGet, "/films/"+filmId.String(), nil)
@@ -163,7 +221,7 @@ func TestHandler_GetFilmById_ServiceError(t *testing.T) {
 		},
 	}
 	mockRatingSvc := &mockRatingService{}
-	handler := NewHandler(mockFilmSvc, mockRatingSvc)
+	handler := NewHandler(mockFilmSvc, mockRatingSvc, &mockWatchlistService{})
 
 	filmId := uuid.New()
 	req := httptest.NewRequest(http.MethodGet, "/films/"+filmId.String(), nil)
@@ -180,7 +238,7 @@ func TestHandler_GetFilmById_ServiceError(t *testing.T) {
 func TestHandler_GetFilmsFromExternal_Success(t *testing.T) {
 	mockFilmSvc := &mockFilmService{}
 	mockRatingSvc := &mockRatingService{}
-	handler := NewHandler(mockFilmSvc, mockRatingSvc)
+	handler := NewHandler(mockFilmSvc, mockRatingSvc, &mockWatchlistService{})
 
 	req := httptest.NewRequest(http.MethodGet, "/films/search?f=inception", nil)
 	w := httptest.NewRecorder()
@@ -195,7 +253,7 @@ func TestHandler_GetFilmsFromExternal_Success(t *testing.T) {
 func TestHandler_GetFilmsFromExternal_MissingQuery(t *testing.T) {
 	mockFilmSvc := &mockFilmService{}
 	mockRatingSvc := &mockRatingService{}
-	handler := NewHandler(mockFilmSvc, mockRatingSvc)
+	handler := NewHandler(mockFilmSvc, mockRatingSvc, &mockWatchlistService{})
 
 	req := httptest.NewRequest(http.MethodGet, "/films/search", nil)
 	w := httptest.NewRecorder()
@@ -214,7 +272,7 @@ func TestHandler_GetFilmsFromExternal_ServiceError(t *testing.T) {
 		},
 	}
 	mockRatingSvc := &mockRatingService{}
-	handler := NewHandler(mockFilmSvc, mockRatingSvc)
+	handler := NewHandler(mockFilmSvc, mockRatingSvc, &mockWatchlistService{})
 
 	req := httptest.NewRequest(http.MethodGet, "/films/search?f=test", nil)
 	w := httptest.NewRecorder()
@@ -225,3 +283,196 @@ func TestHandler_GetFilmsFromExternal_ServiceError(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
 	}
 }
+
+func TestHandler_GenerateFilmRecommendations_Source(t *testing.T) {
+	var gotSource string
+	mockFilmSvc := &mockFilmService{
+		generateFilmRecommendationsFunc: func(ctx context.Context, userId uuid.UUID, films []domain.Film, source string) ([]domain.RecommendedFilm, error) {
+			gotSource = source
+			if source == "letterboxd" {
+				return nil, ErrInvalidSource
+			}
+			return []domain.RecommendedFilm{}, nil
+		},
+	}
+	handler := NewHandler(mockFilmSvc, &mockRatingService{}, &mockWatchlistService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/films/generate-recommendations?userId="+uuid.NewString()+"&source=blend", strings.NewReader("[]"))
+	w := httptest.NewRecorder()
+	handler.GenerateFilmRecommendations(w, req)
+
+	if w.Code != http.StatusOK || gotSource != "blend" {
+		t.Errorf("expected status %d with source blend, got %d with %q", http.StatusOK, w.Code, gotSource)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/films/generate-recommendations?userId="+uuid.NewString()+"&source=letterboxd", strings.NewReader("[]"))
+	w = httptest.NewRecorder()
+	handler.GenerateFilmRecommendations(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an unknown source, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHandler_GetRecommendations(t *testing.T) {
+	user := &domain.User{ID: uuid.New()}
+	mockFilmSvc := &mockFilmService{
+		getRecommendationsFunc: func(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.RecommendationPage, error) {
+			if userId != user.ID || cursor != "abc" || limit != 10 {
+				t.Errorf("expected the authenticated user's page from the query, got %v %q %d", userId, cursor, limit)
+			}
+			return &domain.RecommendationPage{Items: []domain.RecommendedFilm{{
+				Film:    domain.Film{ID: uuid.New(), Title: "Interstellar"},
+				Reasons: []domain.RecommendationReason{{Source: domain.RecommendationSourceTMDB, SeedsAgreed: 2}},
+			}}}, nil
+		},
+	}
+	handler := NewHandler(mockFilmSvc, &mockRatingService{}, &mockWatchlistService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/recommendations?cursor=abc&limit=10", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+	w := httptest.NewRecorder()
+	handler.GetRecommendations(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"seedsAgreed":2`) {
+		t.Errorf("expected the reasons in the response, got %s", w.Body.String())
+	}
+}
+
+func TestHandler_GetRecommendations_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockFilmService{}, &mockRatingService{}, &mockWatchlistService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/recommendations", nil)
+	w := httptest.NewRecorder()
+	handler.GetRecommendations(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_GetRecommendations_InvalidPaging(t *testing.T) {
+	user := &domain.User{ID: uuid.New()}
+	mockFilmSvc := &mockFilmService{
+		getRecommendationsFunc: func(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.RecommendationPage, error) {
+			return nil, ErrInvalidCursor
+		},
+	}
+	handler := NewHandler(mockFilmSvc, &mockRatingService{}, &mockWatchlistService{})
+
+	for _, query := range []string{"?limit=ten", "?cursor=bad"} {
+		req := httptest.NewRequest(http.MethodGet, "/recommendations"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+		w := httptest.NewRecorder()
+		handler.GetRecommendations(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestHandler_RecommendationActions(t *testing.T) {
+	user := &domain.User{ID: uuid.New()}
+	known := uuid.New()
+	notFound := func(filmId uuid.UUID) error {
+		if filmId != known {
+			return ErrFilmRecommendationNotFound
+		}
+		return nil
+	}
+	mockFilmSvc := &mockFilmService{
+		dismissRecommendationFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+			return notFound(filmId)
+		},
+		snoozeRecommendationFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, until time.Time) error {
+			if until.Before(time.Now()) {
+				return ErrInvalidSnooze
+			}
+			return notFound(filmId)
+		},
+		markRecommendationSeenFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+			return notFound(filmId)
+		},
+	}
+	handler := NewHandler(mockFilmSvc, &mockRatingService{}, &mockWatchlistService{})
+	future := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name     string
+		action   http.HandlerFunc
+		filmId   string
+		body     string
+		expected int
+	}{
+		{"dismiss", handler.DismissRecommendation, known.String(), "", http.StatusNoContent},
+		{"dismiss unknown", handler.DismissRecommendation, uuid.NewString(), "", http.StatusNotFound},
+		{"dismiss invalid id", handler.DismissRecommendation, "invalid", "", http.StatusBadRequest},
+		{"snooze", handler.SnoozeRecommendation, known.String(), `{"until": "` + future + `"}`, http.StatusNoContent},
+		{"snooze into the past", handler.SnoozeRecommendation, known.String(), `{"until": "2020-01-01T00:00:00Z"}`, http.StatusBadRequest},
+		{"snooze without a body", handler.SnoozeRecommendation, known.String(), "", http.StatusBadRequest},
+		{"seen", handler.MarkRecommendationSeen, known.String(), "", http.StatusNoContent},
+		{"seen unknown", handler.MarkRecommendationSeen, uuid.NewString(), "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/recommendations/"+tt.filmId, strings.NewReader(tt.body))
+			req.SetPathValue("filmId", tt.filmId)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+			w := httptest.NewRecorder()
+			tt.action(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_AddRecommendationToWatchlist(t *testing.T) {
+	user := &domain.User{ID: uuid.New()}
+	filmId := uuid.New()
+	var gotSource string
+	watchlistSvc := &mockWatchlistService{
+		addToWatchlistFunc: func(ctx context.Context, userId uuid.UUID, id uuid.UUID, priority int, note string, source string) (*domain.WatchlistEntry, error) {
+			gotSource = source
+			return &domain.WatchlistEntry{ID: uuid.New(), UserID: userId, FilmID: id, Source: source}, nil
+		},
+	}
+	mockFilmSvc := &mockFilmService{
+		getRecommendationFunc: func(ctx context.Context, userId uuid.UUID, id uuid.UUID) (*domain.FilmRecommendation, error) {
+			if id != filmId {
+				return nil, ErrFilmRecommendationNotFound
+			}
+			return &domain.FilmRecommendation{HasBeenRecommended: true}, nil
+		},
+	}
+	handler := NewHandler(mockFilmSvc, &mockRatingService{}, watchlistSvc)
+
+	req := httptest.NewRequest(http.MethodPost, "/recommendations/"+filmId.String()+"/watchlist", nil)
+	req.SetPathValue("filmId", filmId.String())
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+	w := httptest.NewRecorder()
+	handler.AddRecommendationToWatchlist(w, req)
+
+	if w.Code != http.StatusCreated || gotSource != domain.WatchlistSourceRecommendation {
+		t.Errorf("expected status %d with the recommendation source, got %d with %q", http.StatusCreated, w.Code, gotSource)
+	}
+
+	// Films that weren't recommended can't be added from the inbox
+	gotSource = ""
+	other := uuid.NewString()
+	req = httptest.NewRequest(http.MethodPost, "/recommendations/"+other+"/watchlist", nil)
+	req.SetPathValue("filmId", other)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+	w = httptest.NewRecorder()
+	handler.AddRecommendationToWatchlist(w, req)
+
+	if w.Code != http.StatusNotFound || gotSource != "" {
+		t.Errorf("expected status %d without touching the watchlist, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -2,33 +2,109 @@ package films
 
 import (
 	"context"
-	"encoding/json"
+	"encoding/base64"
 	"errors"
-	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
 	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/metadata"
 	"github.com/google/uuid"
 )
 
 var (
-	tmdbBaseUrl = "https://api.themoviedb.org/3/"
+	ErrEmptyQueryString   = errors.New("cannot obtain films with empty query string")
+	ErrEmptyFilmList      = errors.New("cannot generate recommendations with empty film list")
+	ErrTooManyFilms       = errors.New("cannot generate recommendations with more than 10 films")
+	ErrInvalidSource      = errors.New("recommendation source must be one of tmdb, cinemalog or blend")
+	ErrNoRecommender      = errors.New("cinema.log recommendations are not available")
+	ErrInvalidCursor      = errors.New("invalid cursor")
+	ErrInvalidLimit       = errors.New("limit must be between 1 and 50")
+	ErrInvalidSnooze      = errors.New("snooze must end in the future")
+	ErrNoMetadataProvider = errors.New("film metadata is not available")
+)
 
-	ErrEmptyQueryString    = errors.New("cannot obtain films with empty query string")
-	ErrProcessTMDBResponse = errors.New("could not process response from tmdb")
-	ErrParseTMDBResponse   = errors.New("could not parse tmdb response")
-	ErrEmptyFilmList       = errors.New("cannot generate recommendations with empty film list")
-	ErrTooManyFilms        = errors.New("cannot generate recommendations with more than 10 films")
+const (
+	// How many films cinema.log's own recommender is asked for, TMDB gives up to 10 per seed film
+	internalRecommendationLimit = 20
+
+	DefaultInboxPageSize = 20
+	MaxInboxPageSize     = 50
+
+	// Stored metadata older than this is fetched again the next time the film is upserted. Films are
+	// upserted on every search pick, import and recommendation, so refreshing each time would cost two
+	// provider calls per upsert for data that rarely changes
+	MetadataRefreshInterval = 30 * 24 * time.Hour
+	// Metadata fetches are spaced at least this far apart, each is two provider calls, so a Letterboxd
+	// import or a batch of recommendations stays well inside TMDB's rate limit
+	MetadataFetchInterval = 50 * time.Millisecond
+	// How many recommended films can wait for their metadata, films past this get it on their next upsert
+	MetadataQueueSize = 500
+	// How many of a film's top billed cast are stored
+	TopCastSize = 10
 )
 
+// An empty cursor starts the inbox from the most recent recommendation
+var inboxStart = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
 type Service struct {
-	FilmStore              FilmStore
-	GraphService           GraphService
-	tmdbRecommendationFunc func(film domain.Film) []domain.Film
+	FilmStore    FilmStore
+	GraphService GraphService
+	Recommender  Recommender
+	Metadata     MetadataProvider
+
+	metadataThrottle *throttle
+	metadataQueue    chan domain.Film
+}
+
+// throttle spaces out calls shared across requests, each caller reserves the next free slot and waits for it
+type throttle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newThrottle(interval time.Duration) *throttle {
+	return &throttle{interval: interval}
+}
+
+// wait blocks until the caller's slot comes up, or returns the context's error if it's cancelled first
+func (t *throttle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	delay := t.next.Sub(now)
+	t.next = t.next.Add(t.interval)
+	t.mu.Unlock()
+
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// MetadataProvider is where films come from, TMDB unless the server is running offline from fixtures
+type MetadataProvider interface {
+	Search(ctx context.Context, query string) ([]domain.Film, error)
+	Details(ctx context.Context, externalId int) (*domain.FilmDetails, error)
+	Recommendations(ctx context.Context, externalId int) ([]domain.Film, error)
+	Credits(ctx context.Context, externalId int) (*domain.FilmCredits, error)
+}
+
+type Recommender interface {
+	Recommend(ctx context.Context, userId uuid.UUID, limit int) ([]domain.RecommendedFilm, error)
 }
 
 type GraphService interface {
@@ -44,100 +120,235 @@ type FilmStore interface {
 	CreateFilmRecommendation(ctx context.Context, recommendation *domain.FilmRecommendation) (*domain.FilmRecommendation, error)
 	UpdateFilmRecommendation(ctx context.Context, recommendation *domain.FilmRecommendation) (*domain.FilmRecommendation, error)
 	GetSeenUnratedFilms(ctx context.Context, userId uuid.UUID) ([]domain.Film, error)
+	GetFilmsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.Film, error)
+	GetFilmRecommendationsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.FilmRecommendation, error)
+	GetRecommendationReasonsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.StoredRecommendationReason, error)
+	GetMetadataUpdatedAt(ctx context.Context, externalId int) (*time.Time, error)
+	GetEloRatings(ctx context.Context, userId uuid.UUID, filmIds []uuid.UUID) (map[uuid.UUID]float64, error)
+	SaveRecommendationReasons(ctx context.Context, userId uuid.UUID, externalFilmId int, score float64, reasons []domain.RecommendationReason) error
+	GetRecommendations(ctx context.Context, userId uuid.UUID, before time.Time, beforeId uuid.UUID, limit int) ([]domain.RecommendedFilm, error)
+	GetRecommendationByFilmId(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.FilmRecommendation, error)
+	DismissRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
+	SnoozeRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, until time.Time) error
+	MarkRecommendationSeen(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
+}
+
+func NewService(f FilmStore, g GraphService, r Recommender, m MetadataProvider) *Service {
+	return &Service{
+		FilmStore:    f,
+		GraphService: g,
+		Recommender:  r,
+		Metadata:     m,
+
+		metadataThrottle: newThrottle(MetadataFetchInterval),
+		metadataQueue:    make(chan domain.Film, MetadataQueueSize),
+	}
 }
 
-type TMDBSearchResponse struct {
-	Results []FilmSearchResult `json:"results"`
+// StartMetadataWorker fetches metadata for the films queued by recommendations in the background until ctx
+// is cancelled, one film at a time so the provider only ever sees the throttled rate
+func (s Service) StartMetadataWorker(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case film := <-s.metadataQueue:
+				s.refreshMetadata(ctx, film)
+			}
+		}
+	}()
 }
 
-type FilmSearchResult struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
-	Overview    string `json:"overview"`
-	ReleaseDate string `json:"release_date"`
-	PosterPath  string `json:"poster_path"`
+// createFilmLater upserts a film without waiting on the provider and queues its metadata for the worker.
+// Recommendations store up to a hundred new films, fetching their metadata in the request would outlast it.
+func (s Service) createFilmLater(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+	film.Metadata = nil
+	stored, err := s.FilmStore.CreateFilm(ctx, film)
+	if err != nil {
+		return nil, err
+	}
+	s.queueMetadata(*stored)
+	return stored, nil
 }
 
-func NewService(f FilmStore, g GraphService) *Service {
-	return &Service{
-		FilmStore:              f,
-		GraphService:           g,
-		tmdbRecommendationFunc: getFilmRecommendationsFromTmdb,
+// queueMetadata hands a stored film to the metadata worker. When the queue is full the film is skipped,
+// its metadata is fetched the next time it's upserted instead.
+func (s Service) queueMetadata(film domain.Film) {
+	select {
+	case s.metadataQueue <- film:
+	default:
+		log.Printf("metadata queue is full, skipping film %d", film.ExternalID)
 	}
 }
 
+// refreshMetadata fetches a stored film's metadata and saves it, films whose metadata is fresh are left alone
+func (s Service) refreshMetadata(ctx context.Context, film domain.Film) {
+	s.addMetadata(ctx, &film)
+	if film.Metadata == nil {
+		return
+	}
+	if _, err := s.FilmStore.CreateFilm(ctx, &film); err != nil {
+		log.Printf("could not save metadata for film %d: %v", film.ExternalID, err)
+	}
+}
+
+// CreateFilm upserts a film along with its metadata. Metadata is only fetched when it's missing or older
+// than MetadataRefreshInterval, rather than on every upsert
 func (s Service) CreateFilm(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+	s.addMetadata(ctx, film)
+
 	// Store layer handles UPSERT - if film with same external_id exists,
 	// it will update and return existing film; otherwise creates new
 	return s.FilmStore.CreateFilm(ctx, film)
 }
 
-func (s Service) GetFilmById(ctx context.Context, id uuid.UUID) (*domain.Film, error) {
-	return s.FilmStore.GetFilmById(ctx, id)
-}
+// addMetadata fetches a film's details and credits from the provider when what's stored is missing or
+// stale. Metadata is nice to have, so a provider failure is logged and the film is stored without it.
+func (s Service) addMetadata(ctx context.Context, film *domain.Film) {
+	// Metadata only ever comes from the provider, never from the client
+	film.Metadata = nil
+	if s.Metadata == nil || film.ExternalID == 0 {
+		return
+	}
 
-func (s Service) GetFilmsFromExternal(ctx context.Context, query string) ([]domain.Film, error) {
-	if query == "" {
-		return nil, ErrEmptyQueryString
+	updatedAt, err := s.FilmStore.GetMetadataUpdatedAt(ctx, film.ExternalID)
+	if err != nil {
+		log.Printf("could not check metadata for film %d: %v", film.ExternalID, err)
+		return
+	}
+	if updatedAt != nil && time.Since(*updatedAt) < MetadataRefreshInterval {
+		return
 	}
 
-	key := os.Getenv("TMDB_API_KEY")
-	reqUrl := fmt.Sprintf("%ssearch/movie?query=%s&include_adult=false&language=en-US&page=1&api_key=%s", tmdbBaseUrl, query, key)
+	if s.metadataThrottle != nil {
+		if err := s.metadataThrottle.wait(ctx); err != nil {
+			log.Printf("gave up waiting to get metadata for film %d: %v", film.ExternalID, err)
+			return
+		}
+	}
 
-	resp, err := http.Get(reqUrl)
+	details, err := s.Metadata.Details(ctx, film.ExternalID)
 	if err != nil {
-		return nil, ErrServer
+		log.Printf("could not get details for film %d: %v", film.ExternalID, err)
+		return
+	}
+	credits, err := s.Metadata.Credits(ctx, film.ExternalID)
+	if err != nil {
+		log.Printf("could not get credits for film %d: %v", film.ExternalID, err)
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("tmdb api returned status %d", resp.StatusCode)
+	film.Metadata = newFilmMetadata(details, credits)
+}
+
+// CreateFilmFromProvider stores the film the metadata provider has under externalId, along with its metadata,
+// for when a TMDB id is all that can be trusted. Returns ErrFilmNotFound when the provider doesn't know the film.
+func (s Service) CreateFilmFromProvider(ctx context.Context, externalId int) (*domain.Film, error) {
+	if s.Metadata == nil {
+		return nil, ErrNoMetadataProvider
+	}
+	if s.metadataThrottle != nil {
+		if err := s.metadataThrottle.wait(ctx); err != nil {
+			return nil, err
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	details, err := s.Metadata.Details(ctx, externalId)
+	if err == metadata.ErrFilmNotFound {
+		return nil, ErrFilmNotFound
+	}
 	if err != nil {
-		return nil, ErrProcessTMDBResponse
+		return nil, err
 	}
 
-	var tmdbResponse TMDBSearchResponse
-	if err := json.Unmarshal(body, &tmdbResponse); err != nil {
-		return nil, ErrParseTMDBResponse
+	film := details.Film
+	credits, err := s.Metadata.Credits(ctx, externalId)
+	if err != nil {
+		log.Printf("could not get credits for film %d: %v", externalId, err)
+	} else {
+		film.Metadata = newFilmMetadata(details, credits)
 	}
 
-	films := make([]domain.Film, 0, len(tmdbResponse.Results))
+	return s.FilmStore.CreateFilm(ctx, &film)
+}
 
-	for _, filmResult := range tmdbResponse.Results {
-		films = append(films, domain.Film{
-			ID:          uuid.New(),
-			ExternalID:  filmResult.ID,
-			Title:       filmResult.Title,
-			Description: filmResult.Overview,
-			PosterUrl:   filmResult.PosterPath,
-			ReleaseYear: filmResult.ReleaseDate,
-		})
+func newFilmMetadata(details *domain.FilmDetails, credits *domain.FilmCredits) *domain.FilmMetadata {
+	metadata := &domain.FilmMetadata{
+		Runtime:          details.Runtime,
+		OriginalLanguage: details.OriginalLanguage,
+		Genres:           details.Genres,
+		Countries:        details.ProductionCountries,
+		Directors:        []domain.Person{},
+		Cast:             []domain.CastMember{},
+		UpdatedAt:        time.Now().UTC(),
 	}
-	return films, nil
+	if metadata.Genres == nil {
+		metadata.Genres = []domain.Genre{}
+	}
+	if metadata.Countries == nil {
+		metadata.Countries = []domain.ProductionCountry{}
+	}
+
+	for _, crew := range credits.Crew {
+		if crew.Job == "Director" {
+			metadata.Directors = append(metadata.Directors, domain.Person{ID: crew.PersonID, Name: crew.Name, ProfilePath: crew.ProfilePath})
+		}
+	}
+	for _, cast := range credits.Cast {
+		if cast.Order < TopCastSize {
+			metadata.Cast = append(metadata.Cast, cast)
+		}
+	}
+
+	return metadata
+}
+
+func (s Service) GetFilmById(ctx context.Context, id uuid.UUID) (*domain.Film, error) {
+	return s.FilmStore.GetFilmById(ctx, id)
+}
+
+func (s Service) GetFilmsFromExternal(ctx context.Context, query string) ([]domain.Film, error) {
+	if query == "" {
+		return nil, ErrEmptyQueryString
+	}
+
+	return s.Metadata.Search(ctx, query)
 }
 
 func (s Service) GetFilmsForRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) ([]domain.Film, error) {
 	return s.FilmStore.GetFilmsForRating(ctx, userId, filmId)
 }
 
-// Generates film recommendations using TMDB, assumption when using this is that films in the argument have been seen by the user
-func (s Service) GenerateFilmRecommendations(ctx context.Context, userId uuid.UUID, films []domain.Film) ([]domain.Film, error) {
-	if len(films) == 0 {
-		return []domain.Film{}, ErrEmptyFilmList
+// Generates film recommendations from TMDB, cinema.log's own recommender or a blend of both, an empty source is TMDB.
+// Assumption when using this is that films in the argument have been seen by the user, they're only optional for cinemalog
+// which recommends from everything the user has rated.
+func (s Service) GenerateFilmRecommendations(ctx context.Context, userId uuid.UUID, films []domain.Film, source string) ([]domain.RecommendedFilm, error) {
+	switch source {
+	case "":
+		source = domain.RecommendationSourceTMDB
+	case domain.RecommendationSourceTMDB, domain.RecommendationSourceCinemaLog, domain.RecommendationSourceBlend:
+	default:
+		return nil, ErrInvalidSource
+	}
+	useTmdb := source != domain.RecommendationSourceCinemaLog
+
+	if len(films) == 0 && useTmdb {
+		return []domain.RecommendedFilm{}, ErrEmptyFilmList
 	}
 
 	if len(films) > 10 {
 		return nil, ErrTooManyFilms
 	}
 
-	allRecommendations := make([]domain.Film, 0)
+	tmdbRecommendations := make([]domain.RecommendedFilm, 0)
+	// Multiple seed films can recommend the same film, it's kept once with every seed as the reason
+	tmdbIndex := make(map[int]int)
+	seedIds := make([]uuid.UUID, 0, len(films))
 
 	for _, film := range films {
-		// ensure film exists in films table
-		_, err := s.FilmStore.CreateFilm(ctx, &film)
+		// ensure film exists in films table, its metadata follows from the worker
+		storedFilm, err := s.createFilmLater(ctx, &film)
 		if err != nil {
 			return nil, err
 		}
@@ -171,7 +382,16 @@ func (s Service) GenerateFilmRecommendations(ctx context.Context, userId uuid.UU
 			}
 		}
 
-		recommendations := s.tmdbRecommendationFunc(film)
+		if !useTmdb {
+			continue
+		}
+
+		recommendations, err := s.Metadata.Recommendations(ctx, film.ExternalID)
+		if err != nil {
+			log.Printf("Failed to get recommendations for film %d: %v", film.ExternalID, err)
+			// A seed without recommendations shouldn't fail the others
+			recommendations = []domain.Film{}
+		}
 
 		// Add film to user's graph with its recommendations
 		if err := s.GraphService.AddFilmToGraph(ctx, userId, film, recommendations); err != nil {
@@ -179,38 +399,72 @@ func (s Service) GenerateFilmRecommendations(ctx context.Context, userId uuid.UU
 			// Don't fail the entire operation, just log and continue
 		}
 
-		allRecommendations = slices.Concat(allRecommendations, recommendations)
+		seedIds = append(seedIds, storedFilm.ID)
+		for rank, recFilm := range recommendations {
+			seed := domain.RecommendedFrom{FilmID: storedFilm.ID, Title: film.Title, TmdbRank: rank + 1}
+			if i, ok := tmdbIndex[recFilm.ExternalID]; ok {
+				tmdbRecommendations[i].Reasons[0].BasedOn = append(tmdbRecommendations[i].Reasons[0].BasedOn, seed)
+				continue
+			}
+			tmdbIndex[recFilm.ExternalID] = len(tmdbRecommendations)
+			tmdbRecommendations = append(tmdbRecommendations, domain.RecommendedFilm{
+				Film:    recFilm,
+				Reasons: []domain.RecommendationReason{{Source: domain.RecommendationSourceTMDB, BasedOn: []domain.RecommendedFrom{seed}}},
+			})
+		}
 	}
-
-	// Deduplicate recommendations by ExternalID
-	// Multiple seed films can recommend the same film
-	uniqueRecommendations := make(map[int]domain.Film)
-	for _, recFilm := range allRecommendations {
-		uniqueRecommendations[recFilm.ExternalID] = recFilm
+	if len(tmdbRecommendations) > 0 {
+		eloRatings, err := s.FilmStore.GetEloRatings(ctx, userId, seedIds)
+		if err != nil {
+			return nil, err
+		}
+		for i := range tmdbRecommendations {
+			reason := &tmdbRecommendations[i].Reasons[0]
+			for j := range reason.BasedOn {
+				if eloRating, ok := eloRatings[reason.BasedOn[j].FilmID]; ok {
+					reason.BasedOn[j].EloRating = &eloRating
+				}
+			}
+			sortSeeds(reason.BasedOn)
+			reason.SeedsAgreed = len(reason.BasedOn)
+			reason.Detail = "TMDB recommends this for people who liked " + seedTitles(reason.BasedOn)
+		}
 	}
 
-	allRecommendations = make([]domain.Film, 0, len(uniqueRecommendations))
-	for _, film := range uniqueRecommendations {
-		allRecommendations = append(allRecommendations, film)
+	internalRecommendations := make([]domain.RecommendedFilm, 0)
+	if source != domain.RecommendationSourceTMDB {
+		if s.Recommender == nil {
+			return nil, ErrNoRecommender
+		}
+		var err error
+		internalRecommendations, err = s.Recommender.Recommend(ctx, userId, internalRecommendationLimit)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	allRecommendations := blendRecommendations(internalRecommendations, tmdbRecommendations)
+
 	// to prevent circular recommendations, we filter the all recommendations list by checking the film_recommendation_table
 	// is there an entry? omit films where - has_seen = true (this means that recommended films could be re-recommended if they havent been seen, i'll have to see how circular this could get)
 	// take allRecommendations and add/update the film_recommendation table: has_been_recommended = true
 
-	filteredRecommendations := make([]domain.Film, 0)
-	for _, recFilm := range allRecommendations {
-		// ensure film exists in films table
-		_, err := s.FilmStore.CreateFilm(ctx, &recFilm)
+	filteredRecommendations := make([]domain.RecommendedFilm, 0)
+	recommendedAt := time.Now()
+	for _, recommendation := range allRecommendations {
+		recFilm := recommendation.Film
+		// ensure film exists in films table, TMDB films come back with the id they're stored under
+		storedFilm, err := s.createFilmLater(ctx, &recFilm)
 		if err != nil {
 			return nil, err
 		}
+		recommendation.Film = *storedFilm
 		// check film_recommendation table
 		existingRec, err := s.FilmStore.GetFilmRecommendation(ctx, userId, recFilm.ExternalID)
 		if err != nil {
 			if err == ErrFilmRecommendationNotFound {
 				// no existing recommendation, safe to add
-				filteredRecommendations = append(filteredRecommendations, recFilm)
+				filteredRecommendations = append(filteredRecommendations, recommendation)
 				_, err := s.FilmStore.CreateFilmRecommendation(ctx, &domain.FilmRecommendation{
 					ID:                       uuid.New(),
 					UserID:                   userId,
@@ -218,6 +472,7 @@ func (s Service) GenerateFilmRecommendations(ctx context.Context, userId uuid.UU
 					HasSeen:                  false,
 					HasBeenRecommended:       true,
 					RecommendationsGenerated: false,
+					RecommendedAt:            &recommendedAt,
 				})
 				if err != nil {
 					return nil, err
@@ -226,10 +481,11 @@ func (s Service) GenerateFilmRecommendations(ctx context.Context, userId uuid.UU
 				return nil, err
 			}
 		} else {
-			// existing recommendation found, only add if has_seen is false
-			if !existingRec.HasSeen {
-				filteredRecommendations = append(filteredRecommendations, recFilm)
+			// existing recommendation found, only add if has_seen is false and the user hasn't dismissed it
+			if !existingRec.HasSeen && existingRec.DismissedAt == nil {
+				filteredRecommendations = append(filteredRecommendations, recommendation)
 				existingRec.HasBeenRecommended = true
+				existingRec.RecommendedAt = &recommendedAt
 				_, err := s.FilmStore.UpdateFilmRecommendation(ctx, existingRec)
 				if err != nil {
 					return nil, err
@@ -238,62 +494,181 @@ func (s Service) GenerateFilmRecommendations(ctx context.Context, userId uuid.UU
 		}
 	}
 
-	allRecommendations = filteredRecommendations
+	// keep why each film was recommended so it can be shown with the stored recommendations later
+	for _, recommendation := range filteredRecommendations {
+		err := s.FilmStore.SaveRecommendationReasons(ctx, userId, recommendation.ExternalID, recommendation.Score, recommendation.Reasons)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return allRecommendations, nil
+	return filteredRecommendations, nil
 }
 
-// Gets seen but unrated films (should prompt user to rate these films)
-func (s Service) GetSeenUnratedFilms(ctx context.Context, userId uuid.UUID) ([]domain.Film, error) {
-	return s.FilmStore.GetSeenUnratedFilms(ctx, userId)
-}
+// GetRecommendations gets a page of the user's recommendation inbox, newest first, with why each film was
+// recommended. An empty cursor starts from the most recent recommendation.
+func (s Service) GetRecommendations(ctx context.Context, userId uuid.UUID, cursor string, limit int) (*domain.RecommendationPage, error) {
+	if limit == 0 {
+		limit = DefaultInboxPageSize
+	}
+	if limit < 1 || limit > MaxInboxPageSize {
+		return nil, ErrInvalidLimit
+	}
+
+	before, beforeId, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
 
-func getFilmRecommendationsFromTmdb(film domain.Film) []domain.Film {
-	key := os.Getenv("TMDB_API_KEY")
-	reqUrl := fmt.Sprintf("%smovie/%d/recommendations?api_key=%s", tmdbBaseUrl, film.ExternalID, key)
+	// One extra tells whether there's another page
+	recommendations, err := s.FilmStore.GetRecommendations(ctx, userId, before, beforeId, limit+1)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := http.Get(reqUrl)
+	page := &domain.RecommendationPage{Items: recommendations}
+	if len(recommendations) > limit {
+		page.Items = recommendations[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(*last.RecommendedAt, last.ID)
+	}
+
+	return page, nil
+}
+
+// GetRecommendation gets the user's recommendation for a film, ErrFilmRecommendationNotFound if it was never recommended to them
+func (s Service) GetRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.FilmRecommendation, error) {
+	recommendation, err := s.FilmStore.GetRecommendationByFilmId(ctx, userId, filmId)
 	if err != nil {
-		log.Println("Error fetching recommendations from TMDB:", err)
-		return []domain.Film{}
+		return nil, err
+	}
+	if !recommendation.HasBeenRecommended {
+		return nil, ErrFilmRecommendationNotFound
 	}
-	defer resp.Body.Close()
+	return recommendation, nil
+}
+
+// DismissRecommendation takes a film out of the user's inbox, it won't be recommended to them again
+func (s Service) DismissRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	return s.FilmStore.DismissRecommendation(ctx, userId, filmId)
+}
+
+// SnoozeRecommendation hides a film from the user's inbox until the given time
+func (s Service) SnoozeRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, until time.Time) error {
+	if !until.After(time.Now()) {
+		return ErrInvalidSnooze
+	}
+	return s.FilmStore.SnoozeRecommendation(ctx, userId, filmId, until)
+}
+
+// MarkRecommendationSeen records that the user has seen a recommended film, it then shows up in their seen but unrated films
+func (s Service) MarkRecommendationSeen(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	return s.FilmStore.MarkRecommendationSeen(ctx, userId, filmId)
+}
+
+// A cursor is when the last film on a page was recommended and its id
+func encodeCursor(recommendedAt time.Time, filmId uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(recommendedAt.Format(time.RFC3339Nano) + "|" + filmId.String()))
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("TMDB API returned status %d for film ID %d\n", resp.StatusCode, film.ExternalID)
-		return []domain.Film{}
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	if cursor == "" {
+		return inboxStart, uuid.Max, nil
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		log.Println("Error reading TMDB response body:", err)
-		return []domain.Film{}
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	recommendedAtStr, filmIdStr, ok := strings.Cut(string(decoded), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	recommendedAt, err := time.Parse(time.RFC3339Nano, recommendedAtStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	filmId, err := uuid.Parse(filmIdStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
 	}
 
-	var tmdbResponse TMDBSearchResponse
-	if err := json.Unmarshal(body, &tmdbResponse); err != nil {
-		log.Println("Error parsing TMDB response:", err)
-		return []domain.Film{}
+	return recommendedAt, filmId, nil
+}
+
+// blendRecommendations puts films both sources picked first, with both reasons, then alternates between
+// the two sources so neither crowds the other out. Either list can be empty.
+func blendRecommendations(internal []domain.RecommendedFilm, tmdb []domain.RecommendedFilm) []domain.RecommendedFilm {
+	tmdbByExternalId := make(map[int]int, len(tmdb))
+	for i, recommendation := range tmdb {
+		tmdbByExternalId[recommendation.ExternalID] = i
 	}
 
-	// Limit to top 10 recommendations to avoid weak suggestions
-	topRecommendations := tmdbResponse.Results
-	if len(topRecommendations) > 10 {
-		topRecommendations = topRecommendations[:10]
+	blended := make([]domain.RecommendedFilm, 0, len(internal)+len(tmdb))
+	pickedByBoth := make(map[int]bool)
+	for _, recommendation := range internal {
+		if i, ok := tmdbByExternalId[recommendation.ExternalID]; ok {
+			recommendation.Reasons = slices.Concat(recommendation.Reasons, tmdb[i].Reasons)
+			blended = append(blended, recommendation)
+			pickedByBoth[recommendation.ExternalID] = true
+		}
 	}
 
-	recommendedFilms := make([]domain.Film, 0, len(topRecommendations))
+	internalOnly := slices.DeleteFunc(slices.Clone(internal), func(r domain.RecommendedFilm) bool { return pickedByBoth[r.ExternalID] })
+	tmdbOnly := slices.DeleteFunc(slices.Clone(tmdb), func(r domain.RecommendedFilm) bool { return pickedByBoth[r.ExternalID] })
+	for i := 0; i < max(len(internalOnly), len(tmdbOnly)); i++ {
+		if i < len(internalOnly) {
+			blended = append(blended, internalOnly[i])
+		}
+		if i < len(tmdbOnly) {
+			blended = append(blended, tmdbOnly[i])
+		}
+	}
 
-	for _, filmResult := range topRecommendations {
-		recommendedFilms = append(recommendedFilms, domain.Film{
-			ID:          uuid.New(),
-			ExternalID:  filmResult.ID,
-			Title:       filmResult.Title,
-			Description: filmResult.Overview,
-			PosterUrl:   filmResult.PosterPath,
-			ReleaseYear: filmResult.ReleaseDate,
-		})
+	return blended
+}
+
+// sortSeeds puts the films the user rates highest first, then the ones TMDB ranked the pick highest for
+func sortSeeds(seeds []domain.RecommendedFrom) {
+	slices.SortStableFunc(seeds, func(a, b domain.RecommendedFrom) int {
+		switch {
+		case a.EloRating != nil && b.EloRating == nil:
+			return -1
+		case a.EloRating == nil && b.EloRating != nil:
+			return 1
+		case a.EloRating != nil && *a.EloRating != *b.EloRating:
+			if *a.EloRating > *b.EloRating {
+				return -1
+			}
+			return 1
+		}
+		return a.TmdbRank - b.TmdbRank
+	})
+}
+
+func seedTitles(seeds []domain.RecommendedFrom) string {
+	titles := make([]string, 0, len(seeds))
+	for _, seed := range seeds {
+		titles = append(titles, seed.Title)
 	}
+	return strings.Join(titles, ", ")
+}
+
+// Gets seen but unrated films (should prompt user to rate these films)
+func (s Service) GetSeenUnratedFilms(ctx context.Context, userId uuid.UUID) ([]domain.Film, error) {
+	return s.FilmStore.GetSeenUnratedFilms(ctx, userId)
+}
+
+// Gets every film the user has reviewed, rated, compared, been recommended or has in their graph,
+// along with the films their recommendations were based on
+func (s Service) GetFilmsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.Film, error) {
+	return s.FilmStore.GetFilmsByUserId(ctx, userId)
+}
+
+func (s Service) GetFilmRecommendationsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.FilmRecommendation, error) {
+	return s.FilmStore.GetFilmRecommendationsByUserId(ctx, userId)
+}
 
-	return recommendedFilms
+func (s Service) GetRecommendationReasonsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.StoredRecommendationReason, error) {
+	return s.FilmStore.GetRecommendationReasonsByUserId(ctx, userId)
 }
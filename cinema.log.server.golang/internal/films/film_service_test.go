@@ -3,9 +3,13 @@ package films
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/metadata"
 	"github.com/google/uuid"
 )
 
@@ -21,6 +25,63 @@ func (m *mockGraphService) AddFilmToGraph(ctx context.Context, userID uuid.UUID,
 	return nil // default: no error
 }
 
+// Mock Recommender for testing
+type mockRecommender struct {
+	recommendFunc func(ctx context.Context, userId uuid.UUID, limit int) ([]domain.RecommendedFilm, error)
+}
+
+func (m *mockRecommender) Recommend(ctx context.Context, userId uuid.UUID, limit int) ([]domain.RecommendedFilm, error) {
+	if m.recommendFunc != nil {
+		return m.recommendFunc(ctx, userId, limit)
+	}
+	return []domain.RecommendedFilm{}, nil
+}
+
+// Mock MetadataProvider for testing
+type mockMetadataProvider struct {
+	searchFunc          func(ctx context.Context, query string) ([]domain.Film, error)
+	recommendationsFunc func(ctx context.Context, externalId int) ([]domain.Film, error)
+	detailsFunc         func(ctx context.Context, externalId int) (*domain.FilmDetails, error)
+	creditsFunc         func(ctx context.Context, externalId int) (*domain.FilmCredits, error)
+}
+
+func (m *mockMetadataProvider) Search(ctx context.Context, query string) ([]domain.Film, error) {
+	if m.searchFunc != nil {
+		return m.searchFunc(ctx, query)
+	}
+	return []domain.Film{}, nil
+}
+
+func (m *mockMetadataProvider) Details(ctx context.Context, externalId int) (*domain.FilmDetails, error) {
+	if m.detailsFunc != nil {
+		return m.detailsFunc(ctx, externalId)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockMetadataProvider) Recommendations(ctx context.Context, externalId int) ([]domain.Film, error) {
+	if m.recommendationsFunc != nil {
+		return m.recommendationsFunc(ctx, externalId)
+	}
+	return []domain.Film{}, nil
+}
+
+func (m *mockMetadataProvider) Credits(ctx context.Context, externalId int) (*domain.FilmCredits, error) {
+	if m.creditsFunc != nil {
+		return m.creditsFunc(ctx, externalId)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// recommendationsFrom is a metadata provider that only gives recommendations
+func recommendationsFrom(recommend func(externalId int) []domain.Film) *mockMetadataProvider {
+	return &mockMetadataProvider{
+		recommendationsFunc: func(ctx context.Context, externalId int) ([]domain.Film, error) {
+			return recommend(externalId), nil
+		},
+	}
+}
+
 // Mock FilmStore for testing
 type mockFilmStore struct {
 	getFilmByIdFunc                 func(ctx context.Context, id uuid.UUID) (*domain.Film, error)
@@ -32,6 +93,14 @@ type mockFilmStore struct {
 	createFilmRecommendationFunc    func(ctx context.Context, recommendation *domain.FilmRecommendation) (*domain.FilmRecommendation, error)
 	getSeenUnratedFilmsFunc         func(ctx context.Context, userId uuid.UUID) ([]domain.Film, error)
 	generateFilmRecommendationsFunc func(ctx context.Context, userId uuid.UUID, films []domain.Film) ([]domain.Film, error)
+	getFilmsByUserIdFunc            func(ctx context.Context, userId uuid.UUID) ([]domain.Film, error)
+	getFilmRecommendationsByUserId  func(ctx context.Context, userId uuid.UUID) ([]domain.FilmRecommendation, error)
+	getMetadataUpdatedAtFunc        func(ctx context.Context, externalId int) (*time.Time, error)
+	getEloRatingsFunc               func(ctx context.Context, userId uuid.UUID, filmIds []uuid.UUID) (map[uuid.UUID]float64, error)
+	saveRecommendationReasonsFunc   func(ctx context.Context, userId uuid.UUID, externalFilmId int, score float64, reasons []domain.RecommendationReason) error
+	getRecommendationsFunc          func(ctx context.Context, userId uuid.UUID, before time.Time, beforeId uuid.UUID, limit int) ([]domain.RecommendedFilm, error)
+	getRecommendationByFilmIdFunc   func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.FilmRecommendation, error)
+	snoozeRecommendationFunc        func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, until time.Time) error
 }
 
 func (m *mockFilmStore) GetFilmById(ctx context.Context, id uuid.UUID) (*domain.Film, error) {
@@ -97,10 +166,78 @@ func (m *mockFilmStore) UpdateFilmRecommendation(ctx context.Context, recommenda
 	return nil, errors.New("not implemented")
 }
 
+func (m *mockFilmStore) GetFilmsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.Film, error) {
+	if m.getFilmsByUserIdFunc != nil {
+		return m.getFilmsByUserIdFunc(ctx, userId)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFilmStore) GetFilmRecommendationsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.FilmRecommendation, error) {
+	if m.getFilmRecommendationsByUserId != nil {
+		return m.getFilmRecommendationsByUserId(ctx, userId)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFilmStore) GetRecommendationReasonsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.StoredRecommendationReason, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFilmStore) GetMetadataUpdatedAt(ctx context.Context, externalId int) (*time.Time, error) {
+	if m.getMetadataUpdatedAtFunc != nil {
+		return m.getMetadataUpdatedAtFunc(ctx, externalId)
+	}
+	return nil, nil // default: never fetched
+}
+
+func (m *mockFilmStore) GetEloRatings(ctx context.Context, userId uuid.UUID, filmIds []uuid.UUID) (map[uuid.UUID]float64, error) {
+	if m.getEloRatingsFunc != nil {
+		return m.getEloRatingsFunc(ctx, userId, filmIds)
+	}
+	return map[uuid.UUID]float64{}, nil // default: nothing rated
+}
+
+func (m *mockFilmStore) SaveRecommendationReasons(ctx context.Context, userId uuid.UUID, externalFilmId int, score float64, reasons []domain.RecommendationReason) error {
+	if m.saveRecommendationReasonsFunc != nil {
+		return m.saveRecommendationReasonsFunc(ctx, userId, externalFilmId, score, reasons)
+	}
+	return nil // default: no error
+}
+
+func (m *mockFilmStore) GetRecommendations(ctx context.Context, userId uuid.UUID, before time.Time, beforeId uuid.UUID, limit int) ([]domain.RecommendedFilm, error) {
+	if m.getRecommendationsFunc != nil {
+		return m.getRecommendationsFunc(ctx, userId, before, beforeId, limit)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFilmStore) GetRecommendationByFilmId(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.FilmRecommendation, error) {
+	if m.getRecommendationByFilmIdFunc != nil {
+		return m.getRecommendationByFilmIdFunc(ctx, userId, filmId)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFilmStore) DismissRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockFilmStore) SnoozeRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, until time.Time) error {
+	if m.snoozeRecommendationFunc != nil {
+		return m.snoozeRecommendationFunc(ctx, userId, filmId, until)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockFilmStore) MarkRecommendationSeen(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	return errors.New("not implemented")
+}
+
 func TestNewService(t *testing.T) {
 	mockStore := &mockFilmStore{}
 	mockGraph := &mockGraphService{}
-	service := NewService(mockStore, mockGraph)
+	service := NewService(mockStore, mockGraph, nil, nil)
 
 	if service == nil {
 		t.Fatal("expected non-nil service")
@@ -129,7 +266,7 @@ func TestService_CreateFilm(t *testing.T) {
 	}
 
 	mockGraph := &mockGraphService{}
-	service := NewService(mockStore, mockGraph)
+	service := NewService(mockStore, mockGraph, nil, nil)
 	createdFilm, err := service.CreateFilm(ctx, &testFilm)
 
 	if err != nil {
@@ -155,7 +292,7 @@ func TestService_CreateFilm_Error(t *testing.T) {
 	}
 
 	mockGraph := &mockGraphService{}
-	service := NewService(mockStore, mockGraph)
+	service := NewService(mockStore, mockGraph, nil, nil)
 	_, err := service.CreateFilm(ctx, &testFilm)
 
 	if err == nil {
@@ -166,6 +303,203 @@ func TestService_CreateFilm_Error(t *testing.T) {
 	}
 }
 
+// matrixMetadata is a provider that knows The Matrix's details and credits
+func matrixMetadata() *mockMetadataProvider {
+	return &mockMetadataProvider{
+		detailsFunc: func(ctx context.Context, externalId int) (*domain.FilmDetails, error) {
+			return &domain.FilmDetails{
+				Runtime:             136,
+				OriginalLanguage:    "en",
+				Genres:              []domain.Genre{{ID: 28, Name: "Action"}, {ID: 878, Name: "Science Fiction"}},
+				ProductionCountries: []domain.ProductionCountry{{Code: "US", Name: "United States of America"}},
+			}, nil
+		},
+		creditsFunc: func(ctx context.Context, externalId int) (*domain.FilmCredits, error) {
+			credits := &domain.FilmCredits{
+				Crew: []domain.CrewMember{
+					{PersonID: 9339, Name: "Lilly Wachowski", Job: "Director"},
+					{PersonID: 9340, Name: "Lana Wachowski", Job: "Director"},
+					{PersonID: 1091, Name: "Joel Silver", Job: "Producer"},
+				},
+			}
+			for order := 0; order < TopCastSize+2; order++ {
+				credits.Cast = append(credits.Cast, domain.CastMember{PersonID: 6384 + order, Name: fmt.Sprintf("Actor %d", order), Order: order})
+			}
+			return credits, nil
+		},
+	}
+}
+
+func TestService_CreateFilm_Metadata(t *testing.T) {
+	var stored *domain.Film
+	mockStore := &mockFilmStore{
+		createFilmFunc: func(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+			stored = film
+			return film, nil
+		},
+	}
+	service := NewService(mockStore, &mockGraphService{}, nil, matrixMetadata())
+
+	// Metadata sent by the client is replaced with the provider's
+	film := &domain.Film{ExternalID: 603, Title: "The Matrix", Metadata: &domain.FilmMetadata{Runtime: 1}}
+	if _, err := service.CreateFilm(context.Background(), film); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got := stored.Metadata
+	if got == nil || got.Runtime != 136 || got.OriginalLanguage != "en" || got.UpdatedAt.IsZero() {
+		t.Fatalf("expected the provider's metadata, got %+v", got)
+	}
+	if len(got.Genres) != 2 || len(got.Countries) != 1 {
+		t.Errorf("expected 2 genres and 1 country, got %+v and %+v", got.Genres, got.Countries)
+	}
+	if len(got.Directors) != 2 || got.Directors[0].Name != "Lilly Wachowski" {
+		t.Errorf("expected both directors and no producer, got %+v", got.Directors)
+	}
+	if len(got.Cast) != TopCastSize {
+		t.Errorf("expected the top %d cast, got %d", TopCastSize, len(got.Cast))
+	}
+}
+
+func TestService_CreateFilm_FreshMetadata(t *testing.T) {
+	updatedAt := time.Now().Add(-time.Hour)
+	var stored *domain.Film
+	mockStore := &mockFilmStore{
+		getMetadataUpdatedAtFunc: func(ctx context.Context, externalId int) (*time.Time, error) {
+			return &updatedAt, nil
+		},
+		createFilmFunc: func(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+			stored = film
+			return film, nil
+		},
+	}
+	provider := matrixMetadata()
+	provider.detailsFunc = func(ctx context.Context, externalId int) (*domain.FilmDetails, error) {
+		t.Error("expected fresh metadata not to be fetched again")
+		return nil, errors.New("unexpected call")
+	}
+	service := NewService(mockStore, &mockGraphService{}, nil, provider)
+
+	if _, err := service.CreateFilm(context.Background(), &domain.Film{ExternalID: 603}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stored.Metadata != nil {
+		t.Errorf("expected the stored metadata to be left alone, got %+v", stored.Metadata)
+	}
+
+	// Once it's stale it's fetched again
+	updatedAt = time.Now().Add(-MetadataRefreshInterval - time.Hour)
+	service.Metadata = matrixMetadata()
+	if _, err := service.CreateFilm(context.Background(), &domain.Film{ExternalID: 603}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stored.Metadata == nil || stored.Metadata.Runtime != 136 {
+		t.Errorf("expected stale metadata to be refreshed, got %+v", stored.Metadata)
+	}
+}
+
+func TestService_CreateFilm_MetadataProviderError(t *testing.T) {
+	var stored *domain.Film
+	mockStore := &mockFilmStore{
+		createFilmFunc: func(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+			stored = film
+			return film, nil
+		},
+	}
+	provider := matrixMetadata()
+	provider.creditsFunc = func(ctx context.Context, externalId int) (*domain.FilmCredits, error) {
+		return nil, errors.New("tmdb is down")
+	}
+	service := NewService(mockStore, &mockGraphService{}, nil, provider)
+
+	if _, err := service.CreateFilm(context.Background(), &domain.Film{ExternalID: 603}); err != nil {
+		t.Fatalf("expected the film to be created without metadata, got %v", err)
+	}
+	if stored == nil || stored.Metadata != nil {
+		t.Errorf("expected the film to be stored without metadata, got %+v", stored)
+	}
+}
+
+func TestService_CreateFilm_MetadataThrottled(t *testing.T) {
+	var stored *domain.Film
+	mockStore := &mockFilmStore{
+		createFilmFunc: func(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+			stored = film
+			return film, nil
+		},
+	}
+	service := NewService(mockStore, &mockGraphService{}, nil, matrixMetadata())
+	service.metadataThrottle = newThrottle(time.Hour)
+
+	if _, err := service.CreateFilm(context.Background(), &domain.Film{ExternalID: 603}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stored.Metadata == nil {
+		t.Fatal("expected the first fetch not to wait")
+	}
+
+	// The next fetch has to wait its turn, the film is still stored if the caller gives up first
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := service.CreateFilm(ctx, &domain.Film{ExternalID: 604}); err != nil {
+		t.Fatalf("expected the film to be created without metadata, got %v", err)
+	}
+	if stored.ExternalID != 604 || stored.Metadata != nil {
+		t.Errorf("expected the film to be stored without metadata, got %+v", stored)
+	}
+}
+
+func TestService_CreateFilmFromProvider(t *testing.T) {
+	var stored *domain.Film
+	mockStore := &mockFilmStore{
+		createFilmFunc: func(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+			stored = film
+			return film, nil
+		},
+	}
+	provider := matrixMetadata()
+	matrixDetails := provider.detailsFunc
+	provider.detailsFunc = func(ctx context.Context, externalId int) (*domain.FilmDetails, error) {
+		if externalId != 603 {
+			return nil, metadata.ErrFilmNotFound
+		}
+		details, _ := matrixDetails(ctx, externalId)
+		details.Film = domain.Film{ID: uuid.New(), ExternalID: 603, Title: "The Matrix", ReleaseYear: "1999-03-31"}
+		return details, nil
+	}
+	service := NewService(mockStore, &mockGraphService{}, nil, provider)
+
+	film, err := service.CreateFilmFromProvider(context.Background(), 603)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if film.Title != "The Matrix" || stored.ExternalID != 603 || stored.Metadata == nil || stored.Metadata.Runtime != 136 {
+		t.Errorf("expected the provider's film and metadata to be stored, got %+v", stored)
+	}
+
+	if _, err := service.CreateFilmFromProvider(context.Background(), 1); err != ErrFilmNotFound {
+		t.Errorf("expected ErrFilmNotFound for a film the provider doesn't know, got %v", err)
+	}
+
+	service.Metadata = nil
+	if _, err := service.CreateFilmFromProvider(context.Background(), 603); err != ErrNoMetadataProvider {
+		t.Errorf("expected ErrNoMetadataProvider, got %v", err)
+	}
+}
+
+func TestThrottle_SpacesCalls(t *testing.T) {
+	throttle := newThrottle(20 * time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := throttle.wait(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected 3 calls to take at least 40ms, took %v", elapsed)
+	}
+}
+
 func TestService_GetFilmById(t *testing.T) {
 	ctx := context.Background()
 	testID := uuid.New()
@@ -186,7 +520,7 @@ func TestService_GetFilmById(t *testing.T) {
 	}
 
 	mockGraph := &mockGraphService{}
-	service := NewService(mockStore, mockGraph)
+	service := NewService(mockStore, mockGraph, nil, nil)
 	film, err := service.GetFilmById(ctx, testID)
 
 	if err != nil {
@@ -211,7 +545,7 @@ func TestService_GetFilmById_NotFound(t *testing.T) {
 	}
 
 	mockGraph := &mockGraphService{}
-	service := NewService(mockStore, mockGraph)
+	service := NewService(mockStore, mockGraph, nil, nil)
 	_, err := service.GetFilmById(ctx, testID)
 
 	if err == nil {
@@ -226,7 +560,7 @@ func TestService_GetFilmsFromExternal_EmptyQuery(t *testing.T) {
 	ctx := context.Background()
 	mockStore := &mockFilmStore{}
 	mockGraph := &mockGraphService{}
-	service := NewService(mockStore, mockGraph)
+	service := NewService(mockStore, mockGraph, nil, nil)
 
 	_, err := service.GetFilmsFromExternal(ctx, "")
 
@@ -239,6 +573,39 @@ func TestService_GetFilmsFromExternal_EmptyQuery(t *testing.T) {
 	}
 }
 
+func TestService_GetFilmsFromExternal_Fixtures(t *testing.T) {
+	service := NewService(&mockFilmStore{}, &mockGraphService{}, nil, metadata.NewFixture("../metadata/fixtures"))
+
+	films, err := service.GetFilmsFromExternal(context.Background(), "inception")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(films) != 1 || films[0].ExternalID != 27205 || films[0].ID == uuid.Nil {
+		t.Errorf("expected Inception with an id, got %+v", films)
+	}
+}
+
+func TestService_GenerateFilmRecommendations_ProviderError(t *testing.T) {
+	metadataProvider := &mockMetadataProvider{
+		recommendationsFunc: func(ctx context.Context, externalId int) ([]domain.Film, error) {
+			if externalId == 100 {
+				return nil, errors.New("tmdb api returned status 500")
+			}
+			return []domain.Film{{ExternalID: 300, Title: "Interstellar"}}, nil
+		},
+	}
+	service := NewService(freshRecommendationStore(), &mockGraphService{}, nil, metadataProvider)
+
+	seeds := []domain.Film{{ID: uuid.New(), ExternalID: 100, Title: "The Matrix"}, {ID: uuid.New(), ExternalID: 200, Title: "Inception"}}
+	results, err := service.GenerateFilmRecommendations(context.Background(), uuid.New(), seeds, "")
+	if err != nil {
+		t.Fatalf("expected a failing seed to be skipped, got %v", err)
+	}
+	if len(results) != 1 || results[0].Reasons[0].BasedOn[0].Title != "Inception" {
+		t.Errorf("expected the other seed's recommendations, got %+v", results)
+	}
+}
+
 func TestService_GenerateFilmRecommendations_DeduplicatesResults(t *testing.T) {
 	ctx := context.Background()
 	userID := uuid.New()
@@ -319,11 +686,11 @@ func TestService_GenerateFilmRecommendations_DeduplicatesResults(t *testing.T) {
 		},
 	}
 
-	service := NewService(mockStore, mockGraph)
+	service := NewService(mockStore, mockGraph, nil, nil)
 
 	// Mock the TMDB recommendation function to return predictable duplicates
-	service.tmdbRecommendationFunc = func(film domain.Film) []domain.Film {
-		switch film.ExternalID {
+	service.Metadata = recommendationsFrom(func(externalId int) []domain.Film {
+		switch externalId {
 		case 100: // The Matrix recommends duplicates + unique1
 			return []domain.Film{duplicateFilm1, duplicateFilm2, uniqueFilm1}
 		case 200: // Inception recommends same duplicates + unique2
@@ -331,10 +698,10 @@ func TestService_GenerateFilmRecommendations_DeduplicatesResults(t *testing.T) {
 		default:
 			return []domain.Film{}
 		}
-	}
+	})
 
 	// Generate recommendations from both seed films
-	results, err := service.GenerateFilmRecommendations(ctx, userID, []domain.Film{seedFilm1, seedFilm2})
+	results, err := service.GenerateFilmRecommendations(ctx, userID, []domain.Film{seedFilm1, seedFilm2}, "")
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -376,9 +743,9 @@ func TestService_GenerateFilmRecommendations_EmptyFilmList(t *testing.T) {
 
 	mockStore := &mockFilmStore{}
 	mockGraph := &mockGraphService{}
-	service := NewService(mockStore, mockGraph)
+	service := NewService(mockStore, mockGraph, nil, nil)
 
-	_, err := service.GenerateFilmRecommendations(ctx, userID, []domain.Film{})
+	_, err := service.GenerateFilmRecommendations(ctx, userID, []domain.Film{}, "")
 
 	if err == nil {
 		t.Fatal("expected error for empty film list, got nil")
@@ -388,3 +755,352 @@ func TestService_GenerateFilmRecommendations_EmptyFilmList(t *testing.T) {
 		t.Errorf("expected error message %q, got %q", expectedErrMsg, err.Error())
 	}
 }
+
+// A film recommendation store where nothing has been seen or recommended yet
+func freshRecommendationStore() *mockFilmStore {
+	return &mockFilmStore{
+		createFilmFunc: func(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+			return film, nil
+		},
+		getFilmRecommendation: func(ctx context.Context, userId uuid.UUID, externalFilmId int) (*domain.FilmRecommendation, error) {
+			return nil, ErrFilmRecommendationNotFound
+		},
+		createFilmRecommendationFunc: func(ctx context.Context, recommendation *domain.FilmRecommendation) (*domain.FilmRecommendation, error) {
+			return recommendation, nil
+		},
+	}
+}
+
+func TestService_GenerateFilmRecommendations_QueuesMetadata(t *testing.T) {
+	mockStore := freshRecommendationStore()
+	stored := make(chan *domain.Film, 10)
+	mockStore.createFilmFunc = func(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+		stored <- film
+		return film, nil
+	}
+	provider := matrixMetadata()
+	details := provider.detailsFunc
+	fetching := false
+	provider.detailsFunc = func(ctx context.Context, externalId int) (*domain.FilmDetails, error) {
+		if !fetching {
+			t.Error("expected no metadata to be fetched while recommending")
+		}
+		return details(ctx, externalId)
+	}
+	provider.recommendationsFunc = func(ctx context.Context, externalId int) ([]domain.Film, error) {
+		return []domain.Film{{ID: uuid.New(), ExternalID: 603, Title: "The Matrix"}}, nil
+	}
+	service := NewService(mockStore, &mockGraphService{}, nil, provider)
+
+	seeds := []domain.Film{{ID: uuid.New(), ExternalID: 100, Title: "Dark City"}}
+	if _, err := service.GenerateFilmRecommendations(context.Background(), uuid.New(), seeds, domain.RecommendationSourceTMDB); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for len(stored) > 0 {
+		if film := <-stored; film.Metadata != nil {
+			t.Errorf("expected films to be stored without metadata while recommending, got %+v", film)
+		}
+	}
+
+	// The worker fills the metadata in afterwards
+	fetching = true
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.StartMetadataWorker(ctx)
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case film := <-stored:
+			if film.ExternalID != 603 {
+				continue // the seed is queued too
+			}
+			if film.Metadata == nil || film.Metadata.Runtime != 136 {
+				t.Errorf("expected the recommended film to be saved with its metadata, got %+v", film)
+			}
+			return
+		case <-timeout:
+			t.Fatal("expected the worker to save the recommended film's metadata")
+		}
+	}
+}
+
+func internalPick(externalId int, title string) domain.RecommendedFilm {
+	return domain.RecommendedFilm{
+		Film:    domain.Film{ID: uuid.New(), ExternalID: externalId, Title: title},
+		Score:   0.8,
+		Reasons: []domain.RecommendationReason{{Source: domain.RecommendationSourceCinemaLog}},
+	}
+}
+
+func TestService_GenerateFilmRecommendations_CinemaLogWithoutSeeds(t *testing.T) {
+	recommender := &mockRecommender{
+		recommendFunc: func(ctx context.Context, userId uuid.UUID, limit int) ([]domain.RecommendedFilm, error) {
+			return []domain.RecommendedFilm{internalPick(949, "Heat")}, nil
+		},
+	}
+	service := NewService(freshRecommendationStore(), &mockGraphService{}, recommender, nil)
+	service.Metadata = recommendationsFrom(func(externalId int) []domain.Film {
+		t.Error("expected TMDB not to be called")
+		return nil
+	})
+
+	results, err := service.GenerateFilmRecommendations(context.Background(), uuid.New(), []domain.Film{}, domain.RecommendationSourceCinemaLog)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(results) != 1 || results[0].Title != "Heat" || results[0].Reasons[0].Source != domain.RecommendationSourceCinemaLog {
+		t.Errorf("expected the internal pick, got %+v", results)
+	}
+}
+
+func TestService_GenerateFilmRecommendations_Blend(t *testing.T) {
+	seed := domain.Film{ID: uuid.New(), ExternalID: 100, Title: "The Matrix"}
+	recommender := &mockRecommender{
+		recommendFunc: func(ctx context.Context, userId uuid.UUID, limit int) ([]domain.RecommendedFilm, error) {
+			return []domain.RecommendedFilm{internalPick(1, "Internal One"), internalPick(300, "Interstellar"), internalPick(2, "Internal Two")}, nil
+		},
+	}
+	service := NewService(freshRecommendationStore(), &mockGraphService{}, recommender, nil)
+	service.Metadata = recommendationsFrom(func(externalId int) []domain.Film {
+		return []domain.Film{{ExternalID: 300, Title: "Interstellar"}, {ExternalID: 3, Title: "TMDB One"}}
+	})
+
+	results, err := service.GenerateFilmRecommendations(context.Background(), uuid.New(), []domain.Film{seed}, domain.RecommendationSourceBlend)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var titles []string
+	for _, result := range results {
+		titles = append(titles, result.Title)
+	}
+	expected := []string{"Interstellar", "Internal One", "TMDB One", "Internal Two"}
+	if strings.Join(titles, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v, got %v", expected, titles)
+	}
+
+	both := results[0].Reasons
+	if len(both) != 2 || both[0].Source != domain.RecommendationSourceCinemaLog || both[1].Source != domain.RecommendationSourceTMDB {
+		t.Errorf("expected reasons from both sources, got %+v", both)
+	}
+	if both[1].BasedOn[0].Title != "The Matrix" || !strings.Contains(both[1].Detail, "The Matrix") {
+		t.Errorf("expected the seed film as the TMDB reason, got %+v", both[1])
+	}
+}
+
+func TestService_GenerateFilmRecommendations_Sources(t *testing.T) {
+	seeds := []domain.Film{{ID: uuid.New(), ExternalID: 100, Title: "The Matrix"}}
+	ctx := context.Background()
+
+	service := NewService(freshRecommendationStore(), &mockGraphService{}, nil, &mockMetadataProvider{})
+	if _, err := service.GenerateFilmRecommendations(ctx, uuid.New(), seeds, "letterboxd"); err != ErrInvalidSource {
+		t.Errorf("expected ErrInvalidSource, got %v", err)
+	}
+	if _, err := service.GenerateFilmRecommendations(ctx, uuid.New(), seeds, domain.RecommendationSourceBlend); err != ErrNoRecommender {
+		t.Errorf("expected ErrNoRecommender without a recommender, got %v", err)
+	}
+	if _, err := service.GenerateFilmRecommendations(ctx, uuid.New(), []domain.Film{}, domain.RecommendationSourceBlend); err != ErrEmptyFilmList {
+		t.Errorf("expected blend to still need seed films, got %v", err)
+	}
+}
+
+func TestService_GenerateFilmRecommendations_Provenance(t *testing.T) {
+	matrix := domain.Film{ID: uuid.New(), ExternalID: 100, Title: "The Matrix"}
+	inception := domain.Film{ID: uuid.New(), ExternalID: 200, Title: "Inception"}
+	storedInterstellar := uuid.New()
+
+	saved := map[int][]domain.RecommendationReason{}
+	store := freshRecommendationStore()
+	store.createFilmFunc = func(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+		stored := *film
+		if film.ExternalID == 300 {
+			stored.ID = storedInterstellar
+		}
+		return &stored, nil
+	}
+	store.getEloRatingsFunc = func(ctx context.Context, userId uuid.UUID, filmIds []uuid.UUID) (map[uuid.UUID]float64, error) {
+		if len(filmIds) != 2 {
+			t.Errorf("expected elo ratings for both seed films, got %v", filmIds)
+		}
+		return map[uuid.UUID]float64{inception.ID: 1200, matrix.ID: 1100}, nil
+	}
+	store.saveRecommendationReasonsFunc = func(ctx context.Context, userId uuid.UUID, externalFilmId int, score float64, reasons []domain.RecommendationReason) error {
+		saved[externalFilmId] = reasons
+		return nil
+	}
+
+	service := NewService(store, &mockGraphService{}, nil, nil)
+	service.Metadata = recommendationsFrom(func(externalId int) []domain.Film {
+		if externalId == 100 {
+			return []domain.Film{{ID: uuid.New(), ExternalID: 300, Title: "Interstellar"}}
+		}
+		return []domain.Film{{ID: uuid.New(), ExternalID: 400, Title: "Tenet"}, {ID: uuid.New(), ExternalID: 300, Title: "Interstellar"}}
+	})
+
+	results, err := service.GenerateFilmRecommendations(context.Background(), uuid.New(), []domain.Film{matrix, inception}, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(results) != 2 || results[0].ID != storedInterstellar {
+		t.Fatalf("expected the stored film to be returned, got %+v", results)
+	}
+
+	reason := results[0].Reasons[0]
+	if reason.SeedsAgreed != 2 || len(reason.BasedOn) != 2 {
+		t.Fatalf("expected both seeds to agree, got %+v", reason)
+	}
+	// Inception is rated higher so comes first even though TMDB ranked the pick lower for it
+	first, second := reason.BasedOn[0], reason.BasedOn[1]
+	if first.FilmID != inception.ID || *first.EloRating != 1200 || first.TmdbRank != 2 {
+		t.Errorf("expected Inception first with its elo and TMDB rank, got %+v", first)
+	}
+	if second.FilmID != matrix.ID || *second.EloRating != 1100 || second.TmdbRank != 1 {
+		t.Errorf("expected The Matrix second with its elo and TMDB rank, got %+v", second)
+	}
+	if reason.Detail != "TMDB recommends this for people who liked Inception, The Matrix" {
+		t.Errorf("unexpected detail %q", reason.Detail)
+	}
+
+	if len(saved) != 2 || saved[300][0].SeedsAgreed != 2 || saved[400][0].BasedOn[0].TmdbRank != 1 {
+		t.Errorf("expected the reasons for every recommendation to be saved, got %+v", saved)
+	}
+}
+
+func TestService_GenerateFilmRecommendations_SaveReasonsError(t *testing.T) {
+	store := freshRecommendationStore()
+	store.saveRecommendationReasonsFunc = func(ctx context.Context, userId uuid.UUID, externalFilmId int, score float64, reasons []domain.RecommendationReason) error {
+		return errors.New("database error")
+	}
+	service := NewService(store, &mockGraphService{}, nil, nil)
+	service.Metadata = recommendationsFrom(func(externalId int) []domain.Film {
+		return []domain.Film{{ExternalID: 300, Title: "Interstellar"}}
+	})
+
+	seeds := []domain.Film{{ID: uuid.New(), ExternalID: 100, Title: "The Matrix"}}
+	if _, err := service.GenerateFilmRecommendations(context.Background(), uuid.New(), seeds, ""); err == nil {
+		t.Error("expected the error saving reasons to be returned")
+	}
+}
+
+func TestService_GenerateFilmRecommendations_SkipsDismissed(t *testing.T) {
+	dismissedAt := time.Now().Add(-time.Hour)
+	var updated []*domain.FilmRecommendation
+	store := freshRecommendationStore()
+	store.getFilmRecommendation = func(ctx context.Context, userId uuid.UUID, externalFilmId int) (*domain.FilmRecommendation, error) {
+		switch externalFilmId {
+		case 300:
+			return &domain.FilmRecommendation{ID: uuid.New(), ExternalFilmID: 300, HasBeenRecommended: true, DismissedAt: &dismissedAt}, nil
+		case 400:
+			return &domain.FilmRecommendation{ID: uuid.New(), ExternalFilmID: 400, HasBeenRecommended: true}, nil
+		}
+		return nil, ErrFilmRecommendationNotFound
+	}
+	store.updateFilmRecommendationFunc = func(ctx context.Context, recommendation *domain.FilmRecommendation) (*domain.FilmRecommendation, error) {
+		updated = append(updated, recommendation)
+		return recommendation, nil
+	}
+	service := NewService(store, &mockGraphService{}, nil, nil)
+	service.Metadata = recommendationsFrom(func(externalId int) []domain.Film {
+		return []domain.Film{{ExternalID: 300, Title: "Interstellar"}, {ExternalID: 400, Title: "Tenet"}}
+	})
+
+	seeds := []domain.Film{{ID: uuid.New(), ExternalID: 100, Title: "The Matrix"}}
+	results, err := service.GenerateFilmRecommendations(context.Background(), uuid.New(), seeds, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(results) != 1 || results[0].ExternalID != 400 {
+		t.Fatalf("expected only the film that wasn't dismissed, got %+v", results)
+	}
+
+	// The seed and the re-recommended film are updated, the re-recommendation moves back to the top of the inbox
+	last := updated[len(updated)-1]
+	if last.ExternalFilmID != 400 || last.RecommendedAt == nil || time.Since(*last.RecommendedAt) > time.Minute {
+		t.Errorf("expected the re-recommended film to be dated now, got %+v", last)
+	}
+}
+
+func TestService_GetRecommendations_Paging(t *testing.T) {
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	inbox := make([]domain.RecommendedFilm, 5)
+	for i := range inbox {
+		recommendedAt := base.Add(-time.Duration(i) * time.Hour)
+		inbox[i] = domain.RecommendedFilm{Film: domain.Film{ID: uuid.New()}, RecommendedAt: &recommendedAt}
+	}
+	store := &mockFilmStore{
+		getRecommendationsFunc: func(ctx context.Context, userId uuid.UUID, before time.Time, beforeId uuid.UUID, limit int) ([]domain.RecommendedFilm, error) {
+			page := []domain.RecommendedFilm{}
+			for _, recommendation := range inbox {
+				if recommendation.RecommendedAt.Before(before) && len(page) < limit {
+					page = append(page, recommendation)
+				}
+			}
+			return page, nil
+		},
+	}
+	service := NewService(store, &mockGraphService{}, nil, nil)
+	ctx := context.Background()
+
+	first, err := service.GetRecommendations(ctx, uuid.New(), "", 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(first.Items) != 2 || first.Items[0].ID != inbox[0].ID || first.NextCursor == "" {
+		t.Fatalf("expected the two newest with a cursor, got %+v", first)
+	}
+
+	second, err := service.GetRecommendations(ctx, uuid.New(), first.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(second.Items) != 2 || second.Items[0].ID != inbox[2].ID {
+		t.Fatalf("expected the page to carry on from the cursor, got %+v", second)
+	}
+
+	last, err := service.GetRecommendations(ctx, uuid.New(), second.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(last.Items) != 1 || last.NextCursor != "" {
+		t.Errorf("expected the last film without a cursor, got %+v", last)
+	}
+
+	if _, err := service.GetRecommendations(ctx, uuid.New(), "not a cursor", 2); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+	if _, err := service.GetRecommendations(ctx, uuid.New(), "", MaxInboxPageSize+1); err != ErrInvalidLimit {
+		t.Errorf("expected ErrInvalidLimit, got %v", err)
+	}
+}
+
+func TestService_SnoozeRecommendation(t *testing.T) {
+	snoozed := false
+	store := &mockFilmStore{
+		snoozeRecommendationFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, until time.Time) error {
+			snoozed = true
+			return nil
+		},
+	}
+	service := NewService(store, &mockGraphService{}, nil, nil)
+
+	if err := service.SnoozeRecommendation(context.Background(), uuid.New(), uuid.New(), time.Now().Add(-time.Minute)); err != ErrInvalidSnooze || snoozed {
+		t.Errorf("expected a snooze into the past to be rejected, got %v", err)
+	}
+	if err := service.SnoozeRecommendation(context.Background(), uuid.New(), uuid.New(), time.Now().Add(48*time.Hour)); err != nil || !snoozed {
+		t.Errorf("expected the snooze to be stored, got %v", err)
+	}
+}
+
+func TestService_GetRecommendation_NotRecommended(t *testing.T) {
+	store := &mockFilmStore{
+		getRecommendationByFilmIdFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.FilmRecommendation, error) {
+			// A seed film the user told us they'd seen, never recommended to them
+			return &domain.FilmRecommendation{HasSeen: true, RecommendationsGenerated: true}, nil
+		},
+	}
+	service := NewService(store, &mockGraphService{}, nil, nil)
+
+	if _, err := service.GetRecommendation(context.Background(), uuid.New(), uuid.New()); err != ErrFilmRecommendationNotFound {
+		t.Errorf("expected ErrFilmRecommendationNotFound, got %v", err)
+	}
+}
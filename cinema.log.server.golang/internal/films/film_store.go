@@ -3,6 +3,7 @@ package films
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"cinema.log.server.golang/internal/domain"
 	"github.com/google/uuid"
@@ -24,6 +25,12 @@ func (s *store) CreateFilm(ctx context.Context, film *domain.Film) (*domain.Film
 		film.ID = uuid.New()
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	// Use UPSERT to prevent race conditions
 	// If film with same external_id exists, return it; otherwise create new
 	query := /* sql */ `
@@ -37,7 +44,7 @@ func (s *store) CreateFilm(ctx context.Context, film *domain.Film) (*domain.Film
 		RETURNING film_id, external_id, title, description, poster_url, release_year`
 
 	var createdFilm domain.Film
-	err := s.db.QueryRowContext(ctx, query, film.ID, film.ExternalID, film.Title, film.Description, film.PosterUrl, film.ReleaseYear).Scan(
+	err = tx.QueryRowContext(ctx, query, film.ID, film.ExternalID, film.Title, film.Description, film.PosterUrl, film.ReleaseYear).Scan(
 		&createdFilm.ID,
 		&createdFilm.ExternalID,
 		&createdFilm.Title,
@@ -50,9 +57,224 @@ func (s *store) CreateFilm(ctx context.Context, film *domain.Film) (*domain.Film
 		return nil, err
 	}
 
+	// Films upserted without metadata keep whatever was stored before
+	if film.Metadata != nil {
+		if err := replaceFilmMetadata(ctx, tx, createdFilm.ID, film.Metadata); err != nil {
+			return nil, err
+		}
+		createdFilm.Metadata = film.Metadata
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return &createdFilm, nil
 }
 
+// replaceFilmMetadata swaps a film's genres, countries and people for the given ones
+func replaceFilmMetadata(ctx context.Context, tx *sql.Tx, filmId uuid.UUID, metadata *domain.FilmMetadata) error {
+	filmQuery := /* sql */ `
+		UPDATE films
+		SET runtime = $2, original_language = $3, metadata_updated_at = $4
+		WHERE film_id = $1
+	`
+	if _, err := tx.ExecContext(ctx, filmQuery, filmId, metadata.Runtime, metadata.OriginalLanguage, metadata.UpdatedAt); err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		/* sql */ `DELETE FROM film_genres WHERE film_id = $1`,
+		/* sql */ `DELETE FROM film_countries WHERE film_id = $1`,
+		/* sql */ `DELETE FROM film_people WHERE film_id = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, filmId); err != nil {
+			return err
+		}
+	}
+
+	genreQuery := /* sql */ `
+		INSERT INTO genres (genre_id, name) VALUES ($1, $2)
+		ON CONFLICT (genre_id) DO UPDATE SET name = EXCLUDED.name
+	`
+	filmGenreQuery := /* sql */ `
+		INSERT INTO film_genres (film_id, genre_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	for _, genre := range metadata.Genres {
+		if _, err := tx.ExecContext(ctx, genreQuery, genre.ID, genre.Name); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, filmGenreQuery, filmId, genre.ID); err != nil {
+			return err
+		}
+	}
+
+	countryQuery := /* sql */ `
+		INSERT INTO countries (country_code, name) VALUES ($1, $2)
+		ON CONFLICT (country_code) DO UPDATE SET name = EXCLUDED.name
+	`
+	filmCountryQuery := /* sql */ `
+		INSERT INTO film_countries (film_id, country_code) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	for _, country := range metadata.Countries {
+		if _, err := tx.ExecContext(ctx, countryQuery, country.Code, country.Name); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, filmCountryQuery, filmId, country.Code); err != nil {
+			return err
+		}
+	}
+
+	personQuery := /* sql */ `
+		INSERT INTO people (person_id, name, profile_path) VALUES ($1, $2, $3)
+		ON CONFLICT (person_id) DO UPDATE SET name = EXCLUDED.name, profile_path = EXCLUDED.profile_path
+	`
+	filmPersonQuery := /* sql */ `
+		INSERT INTO film_people (film_id, person_id, role, character_name, billing_order) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+	`
+	for i, director := range metadata.Directors {
+		if _, err := tx.ExecContext(ctx, personQuery, director.ID, director.Name, director.ProfilePath); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, filmPersonQuery, filmId, director.ID, domain.FilmRoleDirector, "", i); err != nil {
+			return err
+		}
+	}
+	for _, cast := range metadata.Cast {
+		if _, err := tx.ExecContext(ctx, personQuery, cast.PersonID, cast.Name, cast.ProfilePath); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, filmPersonQuery, filmId, cast.PersonID, domain.FilmRoleCast, cast.Character, cast.Order); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetMetadataUpdatedAt returns when a film's metadata was last stored, nil if it never has been or the film isn't stored
+func (s *store) GetMetadataUpdatedAt(ctx context.Context, externalId int) (*time.Time, error) {
+	query := /* sql */ `SELECT metadata_updated_at FROM films WHERE external_id = $1`
+
+	var updatedAt *time.Time
+	err := s.db.QueryRowContext(ctx, query, externalId).Scan(&updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return updatedAt, nil
+}
+
+// getFilmMetadata loads a film's stored metadata, nil if it's never been fetched
+func (s *store) getFilmMetadata(ctx context.Context, filmId uuid.UUID) (*domain.FilmMetadata, error) {
+	filmQuery := /* sql */ `
+		SELECT COALESCE(runtime, 0), COALESCE(original_language, ''), metadata_updated_at
+		FROM films
+		WHERE film_id = $1
+	`
+	metadata := &domain.FilmMetadata{
+		Genres:    []domain.Genre{},
+		Countries: []domain.ProductionCountry{},
+		Directors: []domain.Person{},
+		Cast:      []domain.CastMember{},
+	}
+	var updatedAt *time.Time
+	if err := s.db.QueryRowContext(ctx, filmQuery, filmId).Scan(&metadata.Runtime, &metadata.OriginalLanguage, &updatedAt); err != nil {
+		return nil, err
+	}
+	if updatedAt == nil {
+		return nil, nil
+	}
+	metadata.UpdatedAt = *updatedAt
+
+	genreQuery := /* sql */ `
+		SELECT g.genre_id, g.name
+		FROM film_genres fg
+		INNER JOIN genres g ON g.genre_id = fg.genre_id
+		WHERE fg.film_id = $1
+		ORDER BY g.name
+	`
+	err := queryRows(ctx, s.db, genreQuery, filmId, func(rows *sql.Rows) error {
+		var genre domain.Genre
+		if err := rows.Scan(&genre.ID, &genre.Name); err != nil {
+			return err
+		}
+		metadata.Genres = append(metadata.Genres, genre)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	countryQuery := /* sql */ `
+		SELECT c.country_code, c.name
+		FROM film_countries fc
+		INNER JOIN countries c ON c.country_code = fc.country_code
+		WHERE fc.film_id = $1
+		ORDER BY c.name
+	`
+	err = queryRows(ctx, s.db, countryQuery, filmId, func(rows *sql.Rows) error {
+		var country domain.ProductionCountry
+		if err := rows.Scan(&country.Code, &country.Name); err != nil {
+			return err
+		}
+		metadata.Countries = append(metadata.Countries, country)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	peopleQuery := /* sql */ `
+		SELECT p.person_id, p.name, p.profile_path, fp.role, fp.character_name, fp.billing_order
+		FROM film_people fp
+		INNER JOIN people p ON p.person_id = fp.person_id
+		WHERE fp.film_id = $1
+		ORDER BY fp.role, fp.billing_order
+	`
+	err = queryRows(ctx, s.db, peopleQuery, filmId, func(rows *sql.Rows) error {
+		var person domain.CastMember
+		var role string
+		if err := rows.Scan(&person.PersonID, &person.Name, &person.ProfilePath, &role, &person.Character, &person.Order); err != nil {
+			return err
+		}
+		if role == domain.FilmRoleDirector {
+			metadata.Directors = append(metadata.Directors, domain.Person{ID: person.PersonID, Name: person.Name, ProfilePath: person.ProfilePath})
+		} else {
+			metadata.Cast = append(metadata.Cast, person)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// queryRows runs a query and hands each row to scan
+func queryRows(ctx context.Context, db *sql.DB, query string, arg any, scan func(rows *sql.Rows) error) error {
+	rows, err := db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 func (s *store) GetFilmByExternalId(ctx context.Context, id int) (*domain.Film, error) {
 	query := /* sql */ `SELECT film_id, external_id, title, description, poster_url, release_year 
 	          FROM films WHERE external_id = $1`
@@ -71,6 +293,7 @@ func (s *store) GetFilmByExternalId(ctx context.Context, id int) (*domain.Film,
 	return film, nil
 }
 
+// GetFilmById gets a film with its metadata, if it's been fetched
 func (s *store) GetFilmById(ctx context.Context, id uuid.UUID) (*domain.Film, error) {
 	query := /* sql */ `SELECT film_id, external_id, title, description, poster_url, release_year 
 	          FROM films WHERE film_id = $1`
@@ -86,6 +309,11 @@ func (s *store) GetFilmById(ctx context.Context, id uuid.UUID) (*domain.Film, er
 		return nil, err
 	}
 
+	film.Metadata, err = s.getFilmMetadata(ctx, film.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return film, nil
 }
 
@@ -141,9 +369,11 @@ func (s *store) GetFilmsForRating(ctx context.Context, userId uuid.UUID, filmId
 }
 
 func (s *store) CreateFilmRecommendation(ctx context.Context, recommendation *domain.FilmRecommendation) (*domain.FilmRecommendation, error) {
+	stampRecommendedAt(recommendation)
+
 	query := /* sql */ `
-		INSERT INTO film_recommendation (film_recommendation_id, user_id, external_film_id, has_seen, has_been_recommended, recommendations_generated) 
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO film_recommendation (film_recommendation_id, user_id, external_film_id, has_seen, has_been_recommended, recommendations_generated, recommended_at, dismissed_at, snoozed_until) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
 	_, err := s.db.ExecContext(ctx, query,
@@ -153,6 +383,9 @@ func (s *store) CreateFilmRecommendation(ctx context.Context, recommendation *do
 		recommendation.HasSeen,
 		recommendation.HasBeenRecommended,
 		recommendation.RecommendationsGenerated,
+		recommendation.RecommendedAt,
+		recommendation.DismissedAt,
+		recommendation.SnoozedUntil,
 	)
 
 	if err != nil {
@@ -164,15 +397,31 @@ func (s *store) CreateFilmRecommendation(ctx context.Context, recommendation *do
 
 func (s *store) GetFilmRecommendation(ctx context.Context, userId uuid.UUID, externalFilmId int) (*domain.FilmRecommendation, error) {
 	query := /* sql */ `
-		SELECT film_recommendation_id, user_id, external_film_id, has_seen, has_been_recommended, recommendations_generated
+		SELECT film_recommendation_id, user_id, external_film_id, has_seen, has_been_recommended, recommendations_generated, recommended_at, dismissed_at, snoozed_until
 		FROM film_recommendation
 		WHERE user_id = $1 AND external_film_id = $2
 	`
 
-	recommendation := &domain.FilmRecommendation{}
-	row := s.db.QueryRowContext(ctx, query, userId, externalFilmId)
+	return scanFilmRecommendation(s.db.QueryRowContext(ctx, query, userId, externalFilmId))
+}
+
+// GetRecommendationByFilmId gets the user's recommendation for a film by the film's id rather than its external id
+func (s *store) GetRecommendationByFilmId(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.FilmRecommendation, error) {
+	query := /* sql */ `
+		SELECT fr.film_recommendation_id, fr.user_id, fr.external_film_id, fr.has_seen, fr.has_been_recommended, fr.recommendations_generated, fr.recommended_at, fr.dismissed_at, fr.snoozed_until
+		FROM film_recommendation fr
+		INNER JOIN films f
+			ON f.external_id = fr.external_film_id
+		WHERE fr.user_id = $1 AND f.film_id = $2
+	`
+
+	return scanFilmRecommendation(s.db.QueryRowContext(ctx, query, userId, filmId))
+}
 
-	err := row.Scan(&recommendation.ID, &recommendation.UserID, &recommendation.ExternalFilmID, &recommendation.HasSeen, &recommendation.HasBeenRecommended, &recommendation.RecommendationsGenerated)
+func scanFilmRecommendation(row *sql.Row) (*domain.FilmRecommendation, error) {
+	recommendation := &domain.FilmRecommendation{}
+	err := row.Scan(&recommendation.ID, &recommendation.UserID, &recommendation.ExternalFilmID, &recommendation.HasSeen, &recommendation.HasBeenRecommended, &recommendation.RecommendationsGenerated,
+		&recommendation.RecommendedAt, &recommendation.DismissedAt, &recommendation.SnoozedUntil)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrFilmRecommendationNotFound
@@ -184,16 +433,21 @@ func (s *store) GetFilmRecommendation(ctx context.Context, userId uuid.UUID, ext
 }
 
 func (s *store) UpdateFilmRecommendation(ctx context.Context, recommendation *domain.FilmRecommendation) (*domain.FilmRecommendation, error) {
+	stampRecommendedAt(recommendation)
+
 	query := /* sql */ `
 		UPDATE film_recommendation
-		SET has_seen = $1, has_been_recommended = $2, recommendations_generated = $3
-		WHERE film_recommendation_id = $4
+		SET has_seen = $1, has_been_recommended = $2, recommendations_generated = $3, recommended_at = $4, dismissed_at = $5, snoozed_until = $6
+		WHERE film_recommendation_id = $7
 	`
 
 	_, err := s.db.ExecContext(ctx, query,
 		recommendation.HasSeen,
 		recommendation.HasBeenRecommended,
 		recommendation.RecommendationsGenerated,
+		recommendation.RecommendedAt,
+		recommendation.DismissedAt,
+		recommendation.SnoozedUntil,
 		recommendation.ID,
 	)
 
@@ -204,6 +458,14 @@ func (s *store) UpdateFilmRecommendation(ctx context.Context, recommendation *do
 	return recommendation, nil
 }
 
+// stampRecommendedAt dates recommendations that don't say when they were made, so they have a place in the inbox
+func stampRecommendedAt(recommendation *domain.FilmRecommendation) {
+	if recommendation.HasBeenRecommended && recommendation.RecommendedAt == nil {
+		now := time.Now()
+		recommendation.RecommendedAt = &now
+	}
+}
+
 // return list of films that have been seen (film_recommendation table) AND have not been rated (user_id and film_id on user_film_ratings)
 // might need to link up via external_id -> film table -> film_id -> user_film_ratings
 func (s *store) GetSeenUnratedFilms(ctx context.Context, userId uuid.UUID) ([]domain.Film, error) {
@@ -248,3 +510,455 @@ func (s *store) GetSeenUnratedFilms(ctx context.Context, userId uuid.UUID) ([]do
 
 	return films, nil
 }
+
+// return every film linked to the user through reviews, ratings, comparisons, recommendations or their film graph
+func (s *store) GetFilmsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.Film, error) {
+	query := /* sql */ `
+		SELECT
+			f.film_id,
+			f.external_id,
+			f.title,
+			f.description,
+			f.poster_url,
+			f.release_year
+		FROM films f
+		WHERE f.film_id IN (
+			SELECT film_id FROM reviews WHERE user_id = $1
+			UNION SELECT film_id FROM user_film_ratings WHERE user_id = $1
+			UNION SELECT film_a_film_id FROM comparison_histories WHERE user_id = $1
+			UNION SELECT film_b_film_id FROM comparison_histories WHERE user_id = $1
+		)
+		OR f.external_id IN (
+			SELECT external_film_id FROM film_recommendation WHERE user_id = $1
+			UNION SELECT external_film_id FROM film_graph_nodes WHERE user_id = $1
+		)
+		OR f.film_id IN (
+			SELECT s.seed_film_id
+			FROM recommendation_reason_seeds s
+			INNER JOIN recommendation_reasons r
+				ON r.recommendation_reason_id = s.recommendation_reason_id
+			WHERE r.user_id = $1
+		)
+		ORDER BY f.title
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	films := []domain.Film{}
+	for rows.Next() {
+		var film domain.Film
+		err := rows.Scan(&film.ID, &film.ExternalID, &film.Title, &film.Description, &film.PosterUrl, &film.ReleaseYear)
+		if err != nil {
+			return nil, err
+		}
+		films = append(films, film)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return films, nil
+}
+
+func (s *store) GetFilmRecommendationsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.FilmRecommendation, error) {
+	query := /* sql */ `
+		SELECT film_recommendation_id, user_id, external_film_id, has_seen, has_been_recommended, recommendations_generated,
+			recommended_at, dismissed_at, snoozed_until
+		FROM film_recommendation
+		WHERE user_id = $1
+		ORDER BY external_film_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recommendations := []domain.FilmRecommendation{}
+	for rows.Next() {
+		var recommendation domain.FilmRecommendation
+		err := rows.Scan(&recommendation.ID, &recommendation.UserID, &recommendation.ExternalFilmID, &recommendation.HasSeen, &recommendation.HasBeenRecommended, &recommendation.RecommendationsGenerated,
+			&recommendation.RecommendedAt, &recommendation.DismissedAt, &recommendation.SnoozedUntil)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recommendations, nil
+}
+
+// GetEloRatings returns the user's elo rating for each of the films they've rated, unrated films are left out
+func (s *store) GetEloRatings(ctx context.Context, userId uuid.UUID, filmIds []uuid.UUID) (map[uuid.UUID]float64, error) {
+	query := /* sql */ `
+		SELECT film_id, elo_rating
+		FROM user_film_ratings
+		WHERE user_id = $1
+		AND film_id = ANY($2)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userId, filmIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := make(map[uuid.UUID]float64)
+	for rows.Next() {
+		var filmId uuid.UUID
+		var eloRating float64
+		if err := rows.Scan(&filmId, &eloRating); err != nil {
+			return nil, err
+		}
+		ratings[filmId] = eloRating
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ratings, nil
+}
+
+// SaveRecommendationReasons stores why a film was recommended, replacing any earlier reason from the same source
+func (s *store) SaveRecommendationReasons(ctx context.Context, userId uuid.UUID, externalFilmId int, score float64, reasons []domain.RecommendationReason) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	reasonQuery := /* sql */ `
+		INSERT INTO recommendation_reasons (recommendation_reason_id, user_id, external_film_id, source, detail, score, seeds_agreed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, external_film_id, source) DO UPDATE SET
+			detail = EXCLUDED.detail,
+			score = EXCLUDED.score,
+			seeds_agreed = EXCLUDED.seeds_agreed,
+			recommended_at = NOW()
+		RETURNING recommendation_reason_id
+	`
+	deleteSeedsQuery := /* sql */ `DELETE FROM recommendation_reason_seeds WHERE recommendation_reason_id = $1`
+	seedQuery := /* sql */ `
+		INSERT INTO recommendation_reason_seeds (recommendation_reason_id, seed_film_id, elo_rating, tmdb_rank)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (recommendation_reason_id, seed_film_id) DO NOTHING
+	`
+
+	for _, reason := range reasons {
+		// Only cinema.log's own recommender scores its picks
+		reasonScore := 0.0
+		if reason.Source == domain.RecommendationSourceCinemaLog {
+			reasonScore = score
+		}
+
+		var reasonId uuid.UUID
+		err := tx.QueryRowContext(ctx, reasonQuery, uuid.New(), userId, externalFilmId, reason.Source, reason.Detail, reasonScore, reason.SeedsAgreed).Scan(&reasonId)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteSeedsQuery, reasonId); err != nil {
+			return err
+		}
+
+		for _, seed := range reason.BasedOn {
+			var tmdbRank sql.NullInt64
+			if seed.TmdbRank > 0 {
+				tmdbRank = sql.NullInt64{Int64: int64(seed.TmdbRank), Valid: true}
+			}
+			if _, err := tx.ExecContext(ctx, seedQuery, reasonId, seed.FilmID, seed.EloRating, tmdbRank); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetRecommendations returns a page of the user's recommendation inbox, newest first, starting after the
+// given time and film. The inbox is the films recommended to the user that they haven't seen, dismissed,
+// snoozed or put on their watchlist. Films recommended before reasons were kept have none.
+func (s *store) GetRecommendations(ctx context.Context, userId uuid.UUID, before time.Time, beforeId uuid.UUID, limit int) ([]domain.RecommendedFilm, error) {
+	query := /* sql */ `
+		SELECT
+			f.film_id,
+			f.external_id,
+			f.title,
+			f.description,
+			f.poster_url,
+			f.release_year,
+			fr.recommended_at
+		FROM film_recommendation fr
+		INNER JOIN films f
+			ON f.external_id = fr.external_film_id
+		WHERE
+			fr.user_id = $1
+			AND fr.has_been_recommended = TRUE
+			AND fr.has_seen = FALSE
+			AND fr.dismissed_at IS NULL
+			AND (fr.snoozed_until IS NULL OR fr.snoozed_until <= NOW())
+			AND NOT EXISTS (SELECT 1 FROM watchlist w WHERE w.user_id = fr.user_id AND w.film_id = f.film_id)
+			AND (fr.recommended_at, f.film_id) < ($2::timestamptz, $3::uuid)
+		ORDER BY fr.recommended_at DESC, f.film_id DESC
+		LIMIT $4
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userId, before, beforeId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recommendations := []domain.RecommendedFilm{}
+	filmIndex := make(map[int]int)
+	externalIds := []int{}
+	for rows.Next() {
+		var recommendation domain.RecommendedFilm
+		var recommendedAt time.Time
+		film := &recommendation.Film
+		if err := rows.Scan(&film.ID, &film.ExternalID, &film.Title, &film.Description, &film.PosterUrl, &film.ReleaseYear, &recommendedAt); err != nil {
+			return nil, err
+		}
+		recommendation.RecommendedAt = &recommendedAt
+		recommendation.Reasons = []domain.RecommendationReason{}
+
+		filmIndex[film.ExternalID] = len(recommendations)
+		externalIds = append(externalIds, film.ExternalID)
+		recommendations = append(recommendations, recommendation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(recommendations) == 0 {
+		return recommendations, nil
+	}
+
+	if err := s.addRecommendationReasons(ctx, userId, externalIds, recommendations, filmIndex); err != nil {
+		return nil, err
+	}
+
+	return recommendations, nil
+}
+
+// addRecommendationReasons fills in why each of the films was recommended, filmIndex is where each external id sits
+func (s *store) addRecommendationReasons(ctx context.Context, userId uuid.UUID, externalIds []int, recommendations []domain.RecommendedFilm, filmIndex map[int]int) error {
+	reasonQuery := /* sql */ `
+		SELECT recommendation_reason_id, external_film_id, source, detail, score, seeds_agreed
+		FROM recommendation_reasons
+		WHERE user_id = $1
+		AND external_film_id = ANY($2)
+		ORDER BY source
+	`
+
+	rows, err := s.db.QueryContext(ctx, reasonQuery, userId, externalIds)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Where each reason sits so its seeds can be filled in after
+	type reasonPosition struct{ film, reason int }
+	reasonPositions := make(map[uuid.UUID]reasonPosition)
+	reasonIds := []uuid.UUID{}
+
+	for rows.Next() {
+		var reasonId uuid.UUID
+		var externalFilmId int
+		var score float64
+		reason := domain.RecommendationReason{BasedOn: []domain.RecommendedFrom{}}
+		if err := rows.Scan(&reasonId, &externalFilmId, &reason.Source, &reason.Detail, &score, &reason.SeedsAgreed); err != nil {
+			return err
+		}
+
+		i := filmIndex[externalFilmId]
+		recommendation := &recommendations[i]
+		if score > recommendation.Score {
+			recommendation.Score = score
+		}
+		reasonPositions[reasonId] = reasonPosition{film: i, reason: len(recommendation.Reasons)}
+		reasonIds = append(reasonIds, reasonId)
+		recommendation.Reasons = append(recommendation.Reasons, reason)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if len(reasonIds) == 0 {
+		return nil
+	}
+
+	seedQuery := /* sql */ `
+		SELECT s.recommendation_reason_id, s.seed_film_id, f.title, s.elo_rating, s.tmdb_rank
+		FROM recommendation_reason_seeds s
+		INNER JOIN films f
+			ON f.film_id = s.seed_film_id
+		WHERE s.recommendation_reason_id = ANY($1)
+		ORDER BY s.elo_rating DESC NULLS LAST, s.tmdb_rank ASC NULLS LAST, f.title
+	`
+
+	seedRows, err := s.db.QueryContext(ctx, seedQuery, reasonIds)
+	if err != nil {
+		return err
+	}
+	defer seedRows.Close()
+
+	for seedRows.Next() {
+		var reasonId uuid.UUID
+		var seed domain.RecommendedFrom
+		var tmdbRank sql.NullInt64
+		if err := seedRows.Scan(&reasonId, &seed.FilmID, &seed.Title, &seed.EloRating, &tmdbRank); err != nil {
+			return err
+		}
+		seed.TmdbRank = int(tmdbRank.Int64)
+
+		position := reasonPositions[reasonId]
+		reason := &recommendations[position.film].Reasons[position.reason]
+		reason.BasedOn = append(reason.BasedOn, seed)
+	}
+
+	return seedRows.Err()
+}
+
+// GetRecommendationReasonsByUserId returns every stored reason for the user's recommendations with the
+// films behind them, ordered by film and source
+func (s *store) GetRecommendationReasonsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.StoredRecommendationReason, error) {
+	reasonQuery := /* sql */ `
+		SELECT recommendation_reason_id, external_film_id, source, detail, score, seeds_agreed, recommended_at
+		FROM recommendation_reasons
+		WHERE user_id = $1
+		ORDER BY external_film_id, source
+	`
+
+	rows, err := s.db.QueryContext(ctx, reasonQuery, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reasons := []domain.StoredRecommendationReason{}
+	reasonIndex := make(map[uuid.UUID]int)
+	for rows.Next() {
+		reason := domain.StoredRecommendationReason{BasedOn: []domain.RecommendedFrom{}}
+		if err := rows.Scan(&reason.ID, &reason.ExternalFilmID, &reason.Source, &reason.Detail, &reason.Score, &reason.SeedsAgreed, &reason.RecommendedAt); err != nil {
+			return nil, err
+		}
+		reasonIndex[reason.ID] = len(reasons)
+		reasons = append(reasons, reason)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	seedQuery := /* sql */ `
+		SELECT s.recommendation_reason_id, s.seed_film_id, f.title, s.elo_rating, s.tmdb_rank
+		FROM recommendation_reason_seeds s
+		INNER JOIN recommendation_reasons r
+			ON r.recommendation_reason_id = s.recommendation_reason_id
+		INNER JOIN films f
+			ON f.film_id = s.seed_film_id
+		WHERE r.user_id = $1
+		ORDER BY s.elo_rating DESC NULLS LAST, s.tmdb_rank ASC NULLS LAST, f.title
+	`
+
+	seedRows, err := s.db.QueryContext(ctx, seedQuery, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer seedRows.Close()
+
+	for seedRows.Next() {
+		var reasonId uuid.UUID
+		var seed domain.RecommendedFrom
+		var tmdbRank sql.NullInt64
+		if err := seedRows.Scan(&reasonId, &seed.FilmID, &seed.Title, &seed.EloRating, &tmdbRank); err != nil {
+			return nil, err
+		}
+		seed.TmdbRank = int(tmdbRank.Int64)
+
+		reason := &reasons[reasonIndex[reasonId]]
+		reason.BasedOn = append(reason.BasedOn, seed)
+	}
+
+	if err = seedRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reasons, nil
+}
+
+// DismissRecommendation takes a film out of the user's inbox for good
+func (s *store) DismissRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	query := /* sql */ `
+		UPDATE film_recommendation fr
+		SET dismissed_at = NOW()
+		FROM films f
+		WHERE f.external_id = fr.external_film_id
+		AND fr.user_id = $1
+		AND f.film_id = $2
+		AND fr.has_been_recommended = TRUE
+	`
+
+	return s.updateRecommendation(ctx, query, userId, filmId)
+}
+
+// SnoozeRecommendation keeps a film out of the user's inbox until the given time
+func (s *store) SnoozeRecommendation(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, until time.Time) error {
+	query := /* sql */ `
+		UPDATE film_recommendation fr
+		SET snoozed_until = $3
+		FROM films f
+		WHERE f.external_id = fr.external_film_id
+		AND fr.user_id = $1
+		AND f.film_id = $2
+		AND fr.has_been_recommended = TRUE
+	`
+
+	return s.updateRecommendation(ctx, query, userId, filmId, until)
+}
+
+// MarkRecommendationSeen records that the user has seen a recommended film, so it's waiting to be rated
+func (s *store) MarkRecommendationSeen(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	query := /* sql */ `
+		UPDATE film_recommendation fr
+		SET has_seen = TRUE
+		FROM films f
+		WHERE f.external_id = fr.external_film_id
+		AND fr.user_id = $1
+		AND f.film_id = $2
+		AND fr.has_been_recommended = TRUE
+	`
+
+	return s.updateRecommendation(ctx, query, userId, filmId)
+}
+
+func (s *store) updateRecommendation(ctx context.Context, query string, args ...any) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrFilmRecommendationNotFound
+	}
+
+	return nil
+}
@@ -3,9 +3,11 @@ package films
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	"cinema.log.server.golang/internal/domain"
 	"cinema.log.server.golang/internal/utils"
@@ -240,3 +242,321 @@ func TestCreateFilm_UpsertBehavior(t *testing.T) {
 		t.Errorf("expected film title to be updated to %s, got %s", film2.Title, retrievedFilm.Title)
 	}
 }
+
+func TestGetFilmsAndRecommendationsByUserId(t *testing.T) {
+	ctx := context.Background()
+
+	userID := uuid.New()
+	_, err := testDB.ExecContext(ctx, `INSERT INTO users (user_id, name, username) VALUES ($1, $2, $3)`,
+		userID, "Export User", "exportuser")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	reviewedFilm := domain.Film{ExternalID: 910001, Title: "Reviewed Film"}
+	recommendedFilm := domain.Film{ExternalID: 910002, Title: "Recommended Film"}
+	unrelatedFilm := domain.Film{ExternalID: 910003, Title: "Unrelated Film"}
+	for _, film := range []*domain.Film{&reviewedFilm, &recommendedFilm, &unrelatedFilm} {
+		if _, err := testStore.CreateFilm(ctx, film); err != nil {
+			t.Fatalf("failed to create film: %v", err)
+		}
+	}
+
+	_, err = testDB.ExecContext(ctx, `INSERT INTO reviews (review_id, content, date, rating, film_id, user_id) VALUES ($1, '', NOW(), 4, $2, $3)`,
+		uuid.New(), reviewedFilm.ID, userID)
+	if err != nil {
+		t.Fatalf("failed to create review: %v", err)
+	}
+
+	_, err = testStore.CreateFilmRecommendation(ctx, &domain.FilmRecommendation{
+		ID:                 uuid.New(),
+		UserID:             userID,
+		ExternalFilmID:     recommendedFilm.ExternalID,
+		HasBeenRecommended: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create recommendation: %v", err)
+	}
+
+	films, err := testStore.GetFilmsByUserId(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get films: %v", err)
+	}
+	if len(films) != 2 {
+		t.Fatalf("expected 2 films, got %d", len(films))
+	}
+	for _, film := range films {
+		if film.ID == unrelatedFilm.ID {
+			t.Error("expected unrelated film to be excluded")
+		}
+	}
+
+	recommendations, err := testStore.GetFilmRecommendationsByUserId(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get recommendations: %v", err)
+	}
+	if len(recommendations) != 1 || recommendations[0].ExternalFilmID != recommendedFilm.ExternalID {
+		t.Errorf("expected the recommended film, got %+v", recommendations)
+	}
+}
+
+func TestSaveAndGetRecommendationReasons(t *testing.T) {
+	ctx := context.Background()
+
+	userID := uuid.New()
+	_, err := testDB.ExecContext(ctx, `INSERT INTO users (user_id, name, username) VALUES ($1, $2, $3)`,
+		userID, "Provenance User", "provenanceuser")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	matrix := domain.Film{ExternalID: 920001, Title: "The Matrix"}
+	inception := domain.Film{ExternalID: 920002, Title: "Inception"}
+	interstellar := domain.Film{ExternalID: 920003, Title: "Interstellar"}
+	for _, film := range []*domain.Film{&matrix, &inception, &interstellar} {
+		if _, err := testStore.CreateFilm(ctx, film); err != nil {
+			t.Fatalf("failed to create film: %v", err)
+		}
+	}
+
+	_, err = testDB.ExecContext(ctx, `INSERT INTO user_film_ratings (user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value)
+		VALUES ($1, $2, $3, 1200, 0, NOW(), 4, 40)`, uuid.New(), userID, inception.ID)
+	if err != nil {
+		t.Fatalf("failed to create rating: %v", err)
+	}
+
+	eloRatings, err := testStore.GetEloRatings(ctx, userID, []uuid.UUID{matrix.ID, inception.ID})
+	if err != nil {
+		t.Fatalf("failed to get elo ratings: %v", err)
+	}
+	if len(eloRatings) != 1 || eloRatings[inception.ID] != 1200 {
+		t.Fatalf("expected only the rated film, got %v", eloRatings)
+	}
+
+	_, err = testStore.CreateFilmRecommendation(ctx, &domain.FilmRecommendation{
+		ID:                 uuid.New(),
+		UserID:             userID,
+		ExternalFilmID:     interstellar.ExternalID,
+		HasBeenRecommended: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create recommendation: %v", err)
+	}
+
+	eloRating := eloRatings[inception.ID]
+	reasons := []domain.RecommendationReason{{
+		Source:      domain.RecommendationSourceTMDB,
+		Detail:      "TMDB recommends this for people who liked Inception, The Matrix",
+		SeedsAgreed: 2,
+		BasedOn: []domain.RecommendedFrom{
+			{FilmID: inception.ID, Title: inception.Title, EloRating: &eloRating, TmdbRank: 3},
+			{FilmID: matrix.ID, Title: matrix.Title, TmdbRank: 1},
+		},
+	}}
+	if err := testStore.SaveRecommendationReasons(ctx, userID, interstellar.ExternalID, 0, reasons); err != nil {
+		t.Fatalf("failed to save reasons: %v", err)
+	}
+	// Saving again replaces the earlier reason rather than adding another
+	if err := testStore.SaveRecommendationReasons(ctx, userID, interstellar.ExternalID, 0, reasons); err != nil {
+		t.Fatalf("failed to save reasons again: %v", err)
+	}
+
+	recommendations, err := testStore.GetRecommendations(ctx, userID, inboxStart, uuid.Max, DefaultInboxPageSize)
+	if err != nil {
+		t.Fatalf("failed to get recommendations: %v", err)
+	}
+	if len(recommendations) != 1 || recommendations[0].ID != interstellar.ID || recommendations[0].RecommendedAt == nil {
+		t.Fatalf("expected the recommended film, got %+v", recommendations)
+	}
+	got := recommendations[0].Reasons
+	if len(got) != 1 || got[0].SeedsAgreed != 2 || got[0].Detail != reasons[0].Detail {
+		t.Fatalf("expected the saved reason, got %+v", got)
+	}
+	seeds := got[0].BasedOn
+	if len(seeds) != 2 || seeds[0].FilmID != inception.ID || *seeds[0].EloRating != 1200 || seeds[0].TmdbRank != 3 {
+		t.Fatalf("expected the rated seed first with its elo and TMDB rank, got %+v", seeds)
+	}
+	if seeds[1].FilmID != matrix.ID || seeds[1].EloRating != nil || seeds[1].TmdbRank != 1 {
+		t.Errorf("expected the unrated seed without an elo, got %+v", seeds[1])
+	}
+
+	// The export reads the same reasons back with their score and date
+	stored, err := testStore.GetRecommendationReasonsByUserId(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get stored reasons: %v", err)
+	}
+	if len(stored) != 1 || stored[0].ExternalFilmID != interstellar.ExternalID || stored[0].RecommendedAt.IsZero() {
+		t.Fatalf("expected the saved reason, got %+v", stored)
+	}
+	if len(stored[0].BasedOn) != 2 || stored[0].BasedOn[0].FilmID != inception.ID {
+		t.Errorf("expected both seeds with the rated one first, got %+v", stored[0].BasedOn)
+	}
+}
+
+func TestRecommendationInbox(t *testing.T) {
+	ctx := context.Background()
+
+	userID := uuid.New()
+	_, err := testDB.ExecContext(ctx, `INSERT INTO users (user_id, name, username) VALUES ($1, $2, $3)`,
+		userID, "Inbox User", "inboxuser")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	films := make([]domain.Film, 5)
+	base := time.Now().Add(-time.Hour)
+	for i := range films {
+		films[i] = domain.Film{ExternalID: 930001 + i, Title: fmt.Sprintf("Inbox Film %d", i)}
+		if _, err := testStore.CreateFilm(ctx, &films[i]); err != nil {
+			t.Fatalf("failed to create film: %v", err)
+		}
+		// Each film is recommended a minute after the one before
+		recommendedAt := base.Add(time.Duration(i) * time.Minute)
+		_, err = testStore.CreateFilmRecommendation(ctx, &domain.FilmRecommendation{
+			ID:                 uuid.New(),
+			UserID:             userID,
+			ExternalFilmID:     films[i].ExternalID,
+			HasBeenRecommended: true,
+			RecommendedAt:      &recommendedAt,
+		})
+		if err != nil {
+			t.Fatalf("failed to create recommendation: %v", err)
+		}
+	}
+
+	inboxIds := func() []uuid.UUID {
+		t.Helper()
+		recommendations, err := testStore.GetRecommendations(ctx, userID, inboxStart, uuid.Max, DefaultInboxPageSize)
+		if err != nil {
+			t.Fatalf("failed to get recommendations: %v", err)
+		}
+		ids := []uuid.UUID{}
+		for _, recommendation := range recommendations {
+			ids = append(ids, recommendation.ID)
+		}
+		return ids
+	}
+
+	if ids := inboxIds(); len(ids) != 5 || ids[0] != films[4].ID {
+		t.Fatalf("expected every film newest first, got %v", ids)
+	}
+
+	// Paging carries on after the last film of the page
+	page, err := testStore.GetRecommendations(ctx, userID, inboxStart, uuid.Max, 2)
+	if err != nil {
+		t.Fatalf("failed to get page: %v", err)
+	}
+	next, err := testStore.GetRecommendations(ctx, userID, *page[1].RecommendedAt, page[1].ID, 2)
+	if err != nil {
+		t.Fatalf("failed to get next page: %v", err)
+	}
+	if len(next) != 2 || next[0].ID != films[2].ID {
+		t.Fatalf("expected the next page to start at the third newest, got %+v", next)
+	}
+
+	if err := testStore.DismissRecommendation(ctx, userID, films[4].ID); err != nil {
+		t.Fatalf("failed to dismiss: %v", err)
+	}
+	if err := testStore.SnoozeRecommendation(ctx, userID, films[3].ID, time.Now().Add(24*time.Hour)); err != nil {
+		t.Fatalf("failed to snooze: %v", err)
+	}
+	if err := testStore.MarkRecommendationSeen(ctx, userID, films[2].ID); err != nil {
+		t.Fatalf("failed to mark seen: %v", err)
+	}
+	_, err = testDB.ExecContext(ctx, `INSERT INTO watchlist (watchlist_entry_id, user_id, film_id) VALUES ($1, $2, $3)`,
+		uuid.New(), userID, films[1].ID)
+	if err != nil {
+		t.Fatalf("failed to add to watchlist: %v", err)
+	}
+
+	if ids := inboxIds(); len(ids) != 1 || ids[0] != films[0].ID {
+		t.Errorf("expected only the film nothing was done with, got %v", ids)
+	}
+
+	dismissed, err := testStore.GetFilmRecommendation(ctx, userID, films[4].ExternalID)
+	if err != nil || dismissed.DismissedAt == nil {
+		t.Errorf("expected the dismissal to be stored, got %+v, %v", dismissed, err)
+	}
+
+	seenUnrated, err := testStore.GetSeenUnratedFilms(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get seen unrated films: %v", err)
+	}
+	if len(seenUnrated) != 1 || seenUnrated[0].ID != films[2].ID {
+		t.Errorf("expected the film marked seen to be waiting for a rating, got %+v", seenUnrated)
+	}
+
+	if err := testStore.DismissRecommendation(ctx, userID, uuid.New()); err != ErrFilmRecommendationNotFound {
+		t.Errorf("expected ErrFilmRecommendationNotFound, got %v", err)
+	}
+}
+
+func TestCreateFilm_Metadata(t *testing.T) {
+	ctx := context.Background()
+
+	updatedAt, err := testStore.GetMetadataUpdatedAt(ctx, 930001)
+	if err != nil || updatedAt != nil {
+		t.Fatalf("expected no metadata for a film that isn't stored, got %v, %v", updatedAt, err)
+	}
+
+	film := domain.Film{
+		ExternalID: 930001,
+		Title:      "The Matrix",
+		Metadata: &domain.FilmMetadata{
+			Runtime:          136,
+			OriginalLanguage: "en",
+			Genres:           []domain.Genre{{ID: 28, Name: "Action"}, {ID: 878, Name: "Science Fiction"}},
+			Countries:        []domain.ProductionCountry{{Code: "US", Name: "United States of America"}},
+			Directors:        []domain.Person{{ID: 9339, Name: "Lilly Wachowski"}, {ID: 9340, Name: "Lana Wachowski"}},
+			Cast: []domain.CastMember{
+				{PersonID: 6384, Name: "Keanu Reeves", Character: "Neo", Order: 0},
+				{PersonID: 2975, Name: "Laurence Fishburne", Character: "Morpheus", Order: 1},
+			},
+			UpdatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		},
+	}
+	createdFilm, err := testStore.CreateFilm(ctx, &film)
+	if err != nil {
+		t.Fatalf("failed to create film: %v", err)
+	}
+
+	retrievedFilm, err := testStore.GetFilmById(ctx, createdFilm.ID)
+	if err != nil {
+		t.Fatalf("failed to get film: %v", err)
+	}
+	metadata := retrievedFilm.Metadata
+	if metadata == nil || metadata.Runtime != 136 || metadata.OriginalLanguage != "en" {
+		t.Fatalf("expected the stored metadata, got %+v", metadata)
+	}
+	if len(metadata.Genres) != 2 || len(metadata.Countries) != 1 || len(metadata.Directors) != 2 {
+		t.Errorf("expected 2 genres, 1 country and 2 directors, got %+v", metadata)
+	}
+	if len(metadata.Cast) != 2 || metadata.Cast[0].Character != "Neo" || metadata.Cast[1].Name != "Laurence Fishburne" {
+		t.Errorf("expected the cast in billing order, got %+v", metadata.Cast)
+	}
+
+	updatedAt, err = testStore.GetMetadataUpdatedAt(ctx, film.ExternalID)
+	if err != nil || updatedAt == nil || !updatedAt.Equal(film.Metadata.UpdatedAt) {
+		t.Errorf("expected metadata updated at %v, got %v, %v", film.Metadata.UpdatedAt, updatedAt, err)
+	}
+
+	// An upsert without metadata keeps what's stored
+	if _, err := testStore.CreateFilm(ctx, &domain.Film{ExternalID: film.ExternalID, Title: "The Matrix"}); err != nil {
+		t.Fatalf("failed to upsert film: %v", err)
+	}
+	retrievedFilm, err = testStore.GetFilmById(ctx, createdFilm.ID)
+	if err != nil || retrievedFilm.Metadata == nil || len(retrievedFilm.Metadata.Genres) != 2 {
+		t.Errorf("expected the metadata to be kept, got %+v, %v", retrievedFilm.Metadata, err)
+	}
+
+	// A refresh replaces it
+	film.Metadata.Genres = []domain.Genre{{ID: 878, Name: "Science Fiction"}}
+	film.Metadata.Cast = film.Metadata.Cast[:1]
+	if _, err := testStore.CreateFilm(ctx, &film); err != nil {
+		t.Fatalf("failed to refresh film: %v", err)
+	}
+	retrievedFilm, err = testStore.GetFilmById(ctx, createdFilm.ID)
+	if err != nil || len(retrievedFilm.Metadata.Genres) != 1 || len(retrievedFilm.Metadata.Cast) != 1 {
+		t.Errorf("expected the metadata to be replaced, got %+v, %v", retrievedFilm.Metadata, err)
+	}
+}
@@ -0,0 +1,118 @@
+package follows
+
+import (
+	"context"
+	"net/http"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	FollowService FollowService
+}
+
+type FollowService interface {
+	Follow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+	Unfollow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+	GetFollowers(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error)
+	GetFollowing(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error)
+}
+
+func NewHandler(followService FollowService) *Handler {
+	return &Handler{
+		FollowService: followService,
+	}
+}
+
+func (h *Handler) Follow(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	followeeId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.FollowService.Follow(r.Context(), user.ID, followeeId); err != nil {
+		switch err {
+		case ErrCannotFollowSelf:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case ErrUserNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to follow user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	followeeId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.FollowService.Unfollow(r.Context(), user.ID, followeeId); err != nil {
+		if err == ErrNotFollowing {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to unfollow user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	h.listUsers(w, r, h.FollowService.GetFollowers, "Failed to get followers")
+}
+
+func (h *Handler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	h.listUsers(w, r, h.FollowService.GetFollowing, "Failed to get followed users")
+}
+
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request, list func(context.Context, uuid.UUID) ([]domain.FollowUser, error), failure string) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	users, err := list(r.Context(), userId)
+	if err != nil {
+		if err == ErrUserNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, failure, http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, users)
+}
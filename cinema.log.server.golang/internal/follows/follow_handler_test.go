@@ -0,0 +1,163 @@
+package follows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"github.com/google/uuid"
+)
+
+type mockFollowService struct {
+	followFunc       func(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+	unfollowFunc     func(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+	getFollowersFunc func(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error)
+}
+
+func (m *mockFollowService) Follow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	if m.followFunc != nil {
+		return m.followFunc(ctx, followerId, followeeId)
+	}
+	return nil
+}
+
+func (m *mockFollowService) Unfollow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	if m.unfollowFunc != nil {
+		return m.unfollowFunc(ctx, followerId, followeeId)
+	}
+	return nil
+}
+
+func (m *mockFollowService) GetFollowers(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error) {
+	if m.getFollowersFunc != nil {
+		return m.getFollowersFunc(ctx, userId)
+	}
+	return []domain.FollowUser{}, nil
+}
+
+func (m *mockFollowService) GetFollowing(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error) {
+	return []domain.FollowUser{}, nil
+}
+
+func withUser(r *http.Request, userId uuid.UUID) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), middleware.KeyUser, &domain.User{ID: userId}))
+}
+
+func TestHandler_Follow(t *testing.T) {
+	followerId, followeeId := uuid.New(), uuid.New()
+	var gotFollower, gotFollowee uuid.UUID
+	handler := NewHandler(&mockFollowService{
+		followFunc: func(ctx context.Context, follower uuid.UUID, followee uuid.UUID) error {
+			gotFollower, gotFollowee = follower, followee
+			return nil
+		},
+	})
+
+	req := withUser(httptest.NewRequest(http.MethodPost, "/users/"+followeeId.String()+"/follow", nil), followerId)
+	req.SetPathValue("id", followeeId.String())
+	w := httptest.NewRecorder()
+	handler.Follow(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if gotFollower != followerId || gotFollowee != followeeId {
+		t.Errorf("expected %v to follow %v, got %v and %v", followerId, followeeId, gotFollower, gotFollowee)
+	}
+}
+
+func TestHandler_Follow_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		err      error
+		expected int
+	}{
+		{"invalid id", "abc", nil, http.StatusBadRequest},
+		{"self", uuid.NewString(), ErrCannotFollowSelf, http.StatusBadRequest},
+		{"unknown user", uuid.NewString(), ErrUserNotFound, http.StatusNotFound},
+		{"store failure", uuid.NewString(), errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockFollowService{
+				followFunc: func(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+					return tt.err
+				},
+			})
+
+			req := withUser(httptest.NewRequest(http.MethodPost, "/users/"+tt.id+"/follow", nil), uuid.New())
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+			handler.Follow(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_Unfollow_NotFollowing(t *testing.T) {
+	handler := NewHandler(&mockFollowService{
+		unfollowFunc: func(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+			return ErrNotFollowing
+		},
+	})
+
+	followeeId := uuid.NewString()
+	req := withUser(httptest.NewRequest(http.MethodDelete, "/users/"+followeeId+"/follow", nil), uuid.New())
+	req.SetPathValue("id", followeeId)
+	w := httptest.NewRecorder()
+	handler.Unfollow(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandler_GetFollowers(t *testing.T) {
+	userId := uuid.New()
+	follower := domain.FollowUser{ID: uuid.New(), Username: "cinephile"}
+	handler := NewHandler(&mockFollowService{
+		getFollowersFunc: func(ctx context.Context, id uuid.UUID) ([]domain.FollowUser, error) {
+			if id != userId {
+				return nil, ErrUserNotFound
+			}
+			return []domain.FollowUser{follower}, nil
+		},
+	})
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/users/"+userId.String()+"/followers", nil), uuid.New())
+	req.SetPathValue("id", userId.String())
+	w := httptest.NewRecorder()
+	handler.GetFollowers(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var followers []domain.FollowUser
+	if err := json.NewDecoder(w.Body).Decode(&followers); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(followers) != 1 || followers[0].Username != "cinephile" {
+		t.Errorf("expected the follower, got %+v", followers)
+	}
+}
+
+func TestHandler_GetFollowing_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockFollowService{})
+
+	w := httptest.NewRecorder()
+	handler.GetFollowing(w, httptest.NewRequest(http.MethodGet, "/users/"+uuid.NewString()+"/following", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
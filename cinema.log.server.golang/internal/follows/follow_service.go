@@ -0,0 +1,71 @@
+package follows
+
+import (
+	"context"
+	"errors"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrNotFollowing     = errors.New("not following this user")
+	ErrCannotFollowSelf = errors.New("users can't follow themselves")
+)
+
+type Service struct {
+	FollowStore FollowStore
+}
+
+type FollowStore interface {
+	Follow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+	Unfollow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+	GetFollowers(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error)
+	GetFollowing(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error)
+	UserExists(ctx context.Context, userId uuid.UUID) (bool, error)
+}
+
+func NewService(followStore FollowStore) *Service {
+	return &Service{
+		FollowStore: followStore,
+	}
+}
+
+func (s *Service) Follow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	if followerId == followeeId {
+		return ErrCannotFollowSelf
+	}
+	return s.FollowStore.Follow(ctx, followerId, followeeId)
+}
+
+func (s *Service) Unfollow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	return s.FollowStore.Unfollow(ctx, followerId, followeeId)
+}
+
+// GetFollowers returns who follows the user, an unknown user is ErrUserNotFound rather than an empty list
+func (s *Service) GetFollowers(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error) {
+	if err := s.ensureUserExists(ctx, userId); err != nil {
+		return nil, err
+	}
+	return s.FollowStore.GetFollowers(ctx, userId)
+}
+
+// GetFollowing returns who the user follows, an unknown user is ErrUserNotFound rather than an empty list
+func (s *Service) GetFollowing(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error) {
+	if err := s.ensureUserExists(ctx, userId); err != nil {
+		return nil, err
+	}
+	return s.FollowStore.GetFollowing(ctx, userId)
+}
+
+func (s *Service) ensureUserExists(ctx context.Context, userId uuid.UUID) error {
+	exists, err := s.FollowStore.UserExists(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	return nil
+}
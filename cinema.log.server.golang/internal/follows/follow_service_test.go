@@ -0,0 +1,71 @@
+package follows
+
+import (
+	"context"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type mockFollowStore struct {
+	users      map[uuid.UUID]bool
+	followFunc func(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error
+}
+
+func (m *mockFollowStore) Follow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	if m.followFunc != nil {
+		return m.followFunc(ctx, followerId, followeeId)
+	}
+	return nil
+}
+
+func (m *mockFollowStore) Unfollow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	return nil
+}
+
+func (m *mockFollowStore) GetFollowers(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error) {
+	return []domain.FollowUser{{ID: uuid.New()}}, nil
+}
+
+func (m *mockFollowStore) GetFollowing(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error) {
+	return []domain.FollowUser{{ID: uuid.New()}}, nil
+}
+
+func (m *mockFollowStore) UserExists(ctx context.Context, userId uuid.UUID) (bool, error) {
+	return m.users[userId], nil
+}
+
+func TestService_Follow_Self(t *testing.T) {
+	called := false
+	service := NewService(&mockFollowStore{
+		followFunc: func(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+			called = true
+			return nil
+		},
+	})
+	userId := uuid.New()
+
+	if err := service.Follow(context.Background(), userId, userId); err != ErrCannotFollowSelf {
+		t.Errorf("expected ErrCannotFollowSelf, got %v", err)
+	}
+	if called {
+		t.Error("expected the store not to be called")
+	}
+}
+
+func TestService_GetFollowers_UnknownUser(t *testing.T) {
+	known := uuid.New()
+	service := NewService(&mockFollowStore{users: map[uuid.UUID]bool{known: true}})
+	ctx := context.Background()
+
+	if _, err := service.GetFollowers(ctx, uuid.New()); err != ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if _, err := service.GetFollowing(ctx, uuid.New()); err != ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if followers, err := service.GetFollowers(ctx, known); err != nil || len(followers) != 1 {
+		t.Errorf("expected the user's followers, got %v, %v", followers, err)
+	}
+}
@@ -0,0 +1,142 @@
+package follows
+
+import (
+	"context"
+	"database/sql"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) FollowStore {
+	return &store{
+		db: db,
+	}
+}
+
+// Follow records that the follower follows the followee, following someone twice is a no-op
+func (s *store) Follow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	// Selecting from users means nothing is inserted when the followee doesn't exist
+	query := /* sql */ `
+		INSERT INTO user_follows (follower_id, followee_id, created_at)
+		SELECT $1, u.user_id, NOW()
+		FROM users u
+		WHERE u.user_id = $2
+		ON CONFLICT (follower_id, followee_id) DO NOTHING
+	`
+
+	result, err := s.db.ExecContext(ctx, query, followerId, followeeId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		// Either the followee doesn't exist or they're already followed
+		exists, err := s.UserExists(ctx, followeeId)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+	}
+
+	return nil
+}
+
+func (s *store) Unfollow(ctx context.Context, followerId uuid.UUID, followeeId uuid.UUID) error {
+	query := /* sql */ `
+		DELETE FROM user_follows
+		WHERE follower_id = $1
+		AND followee_id = $2
+	`
+
+	result, err := s.db.ExecContext(ctx, query, followerId, followeeId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFollowing
+	}
+
+	return nil
+}
+
+// GetFollowers returns the users following userId, most recent first
+func (s *store) GetFollowers(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error) {
+	query := /* sql */ `
+		SELECT u.user_id, u.name, u.username, COALESCE(u.profile_pic_url, ''), uf.created_at
+		FROM user_follows uf
+		JOIN users u ON uf.follower_id = u.user_id
+		WHERE uf.followee_id = $1
+		ORDER BY uf.created_at DESC
+	`
+
+	return s.queryFollowUsers(ctx, query, userId)
+}
+
+// GetFollowing returns the users userId follows, most recent first
+func (s *store) GetFollowing(ctx context.Context, userId uuid.UUID) ([]domain.FollowUser, error) {
+	query := /* sql */ `
+		SELECT u.user_id, u.name, u.username, COALESCE(u.profile_pic_url, ''), uf.created_at
+		FROM user_follows uf
+		JOIN users u ON uf.followee_id = u.user_id
+		WHERE uf.follower_id = $1
+		ORDER BY uf.created_at DESC
+	`
+
+	return s.queryFollowUsers(ctx, query, userId)
+}
+
+func (s *store) UserExists(ctx context.Context, userId uuid.UUID) (bool, error) {
+	query := /* sql */ `SELECT EXISTS (SELECT 1 FROM users WHERE user_id = $1)`
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, query, userId).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (s *store) queryFollowUsers(ctx context.Context, query string, userId uuid.UUID) ([]domain.FollowUser, error) {
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []domain.FollowUser{}
+	for rows.Next() {
+		var user domain.FollowUser
+		err := rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Username,
+			&user.ProfilePicURL,
+			&user.FollowedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
@@ -0,0 +1,90 @@
+package follows
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	testDB      *sql.DB
+	testStore   FollowStore
+	testDbSetup *utils.TestDatabase
+)
+
+// Helper function to create test user
+func createTestUser(ctx context.Context, t *testing.T) uuid.UUID {
+	userID := uuid.New()
+	query := `INSERT INTO users (user_id, name, username, profile_pic_url)
+	          VALUES ($1, $2, $3, $4)`
+	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], "http://example.com/pic.jpg")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return userID
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	testDbSetup, err = utils.StartTestPostgres()
+	if err != nil {
+		log.Fatalf("could not start test database: %v", err)
+	}
+
+	testDB = testDbSetup.DB
+	testStore = NewStore(testDB)
+
+	code := m.Run()
+
+	testDbSetup.Close()
+	os.Exit(code)
+}
+
+func TestFollowStore_FollowAndUnfollow(t *testing.T) {
+	ctx := context.Background()
+	follower, followee := createTestUser(ctx, t), createTestUser(ctx, t)
+
+	if err := testStore.Follow(ctx, follower, followee); err != nil {
+		t.Fatalf("failed to follow: %v", err)
+	}
+	// Following twice is fine
+	if err := testStore.Follow(ctx, follower, followee); err != nil {
+		t.Fatalf("expected following twice to succeed, got %v", err)
+	}
+
+	followers, err := testStore.GetFollowers(ctx, followee)
+	if err != nil || len(followers) != 1 || followers[0].ID != follower {
+		t.Errorf("expected the follower, got %+v, %v", followers, err)
+	}
+	following, err := testStore.GetFollowing(ctx, follower)
+	if err != nil || len(following) != 1 || following[0].ID != followee {
+		t.Errorf("expected the followee, got %+v, %v", following, err)
+	}
+	if following, _ := testStore.GetFollowing(ctx, followee); len(following) != 0 {
+		t.Errorf("expected following to be one way, got %+v", following)
+	}
+
+	if err := testStore.Unfollow(ctx, follower, followee); err != nil {
+		t.Fatalf("failed to unfollow: %v", err)
+	}
+	if err := testStore.Unfollow(ctx, follower, followee); err != ErrNotFollowing {
+		t.Errorf("expected ErrNotFollowing, got %v", err)
+	}
+}
+
+func TestFollowStore_Follow_UnknownUser(t *testing.T) {
+	ctx := context.Background()
+	follower := createTestUser(ctx, t)
+
+	if err := testStore.Follow(ctx, follower, uuid.New()); err != ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if exists, err := testStore.UserExists(ctx, follower); err != nil || !exists {
+		t.Errorf("expected the follower to exist, got %v, %v", exists, err)
+	}
+}
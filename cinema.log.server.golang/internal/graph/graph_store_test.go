@@ -38,10 +38,9 @@ func TestMain(m *testing.M) {
 
 // Helper function to create test user
 func createTestUser(ctx context.Context, t *testing.T, userID uuid.UUID) {
-	query := `INSERT INTO users (user_id, name, username, github_id, profile_pic_url) 
-	          VALUES ($1, $2, $3, $4, $5)`
-	githubID := int(uuid.New().ID() % 2147483647) // Use UUID for uniqueness
-	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], githubID, "http://example.com/pic.jpg")
+	query := `INSERT INTO users (user_id, name, username, profile_pic_url)
+	          VALUES ($1, $2, $3, $4)`
+	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], "http://example.com/pic.jpg")
 	if err != nil {
 		t.Fatalf("failed to create test user: %v", err)
 	}
@@ -0,0 +1,82 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Letterboxd exports are small, 10MB leaves plenty of room for years of diary entries
+const maxImportSize = 10 << 20
+
+var (
+	ErrServer = errors.New("internal server error")
+)
+
+type Handler struct {
+	ImportService ImportService
+}
+
+type ImportService interface {
+	ImportLetterboxd(ctx context.Context, userId uuid.UUID, files LetterboxdFiles) (*ImportReport, error)
+}
+
+func NewHandler(importService ImportService) *Handler {
+	return &Handler{
+		ImportService: importService,
+	}
+}
+
+// ImportLetterboxd accepts a multipart form with any of the "ratings", "reviews" and "diary"
+// files from a Letterboxd export and imports them for the authenticated user
+func (h *Handler) ImportLetterboxd(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+
+	var files LetterboxdFiles
+	fields := map[string]*io.Reader{
+		"ratings": &files.Ratings,
+		"reviews": &files.Reviews,
+		"diary":   &files.Diary,
+	}
+	for field, dst := range fields {
+		file, _, err := r.FormFile(field)
+		if errors.Is(err, http.ErrMissingFile) {
+			continue
+		}
+		if err != nil {
+			http.Error(w, "Invalid file for "+field, http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		*dst = file
+	}
+
+	report, err := h.ImportService.ImportLetterboxd(r.Context(), user.ID, files)
+	if err != nil {
+		if errors.Is(err, ErrNoLetterboxdFiles) || errors.Is(err, ErrInvalidLetterboxdCSV) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, ErrServer.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, report)
+}
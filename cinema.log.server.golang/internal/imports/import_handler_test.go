@@ -0,0 +1,146 @@
+package imports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"github.com/google/uuid"
+)
+
+type mockImportService struct {
+	importLetterboxdFunc func(ctx context.Context, userId uuid.UUID, files LetterboxdFiles) (*ImportReport, error)
+}
+
+func (m *mockImportService) ImportLetterboxd(ctx context.Context, userId uuid.UUID, files LetterboxdFiles) (*ImportReport, error) {
+	if m.importLetterboxdFunc != nil {
+		return m.importLetterboxdFunc(ctx, userId, files)
+	}
+	return &ImportReport{}, nil
+}
+
+func newMultipartRequest(t *testing.T, files map[string]string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for field, content := range files {
+		part, err := writer.CreateFormFile(field, field+".csv")
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		part.Write([]byte(content))
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/imports/letterboxd", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func withUser(req *http.Request, user *domain.User) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+}
+
+func TestHandler_ImportLetterboxd_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockImportService{})
+	req := newMultipartRequest(t, map[string]string{"ratings": "Name\n"})
+	w := httptest.NewRecorder()
+
+	handler.ImportLetterboxd(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_ImportLetterboxd_NotMultipart(t *testing.T) {
+	handler := NewHandler(&mockImportService{})
+	req := httptest.NewRequest(http.MethodPost, "/imports/letterboxd", bytes.NewBufferString("{}"))
+	req = withUser(req, &domain.User{ID: uuid.New()})
+	w := httptest.NewRecorder()
+
+	handler.ImportLetterboxd(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHandler_ImportLetterboxd_Success(t *testing.T) {
+	user := &domain.User{ID: uuid.New()}
+	var gotRatings, gotDiary string
+	var gotReviews io.Reader
+
+	mockSvc := &mockImportService{
+		importLetterboxdFunc: func(ctx context.Context, userId uuid.UUID, files LetterboxdFiles) (*ImportReport, error) {
+			if userId != user.ID {
+				t.Errorf("expected user %v, got %v", user.ID, userId)
+			}
+			ratings, _ := io.ReadAll(files.Ratings)
+			diary, _ := io.ReadAll(files.Diary)
+			gotRatings, gotDiary, gotReviews = string(ratings), string(diary), files.Reviews
+			return &ImportReport{Imported: 2, Results: []RowResult{}}, nil
+		},
+	}
+	handler := NewHandler(mockSvc)
+
+	req := newMultipartRequest(t, map[string]string{"ratings": "ratings-data", "diary": "diary-data"})
+	req = withUser(req, user)
+	w := httptest.NewRecorder()
+
+	handler.ImportLetterboxd(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if gotRatings != "ratings-data" || gotDiary != "diary-data" {
+		t.Errorf("expected uploaded files to be passed through, got %q and %q", gotRatings, gotDiary)
+	}
+	if gotReviews != nil {
+		t.Error("expected reviews to be nil when not uploaded")
+	}
+
+	var report ImportReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if report.Imported != 2 {
+		t.Errorf("expected 2 imported, got %d", report.Imported)
+	}
+}
+
+func TestHandler_ImportLetterboxd_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{ErrNoLetterboxdFiles, http.StatusBadRequest},
+		{fmt.Errorf("%w: ratings.csv: missing Name column", ErrInvalidLetterboxdCSV), http.StatusBadRequest},
+		{fmt.Errorf("database down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			handler := NewHandler(&mockImportService{
+				importLetterboxdFunc: func(ctx context.Context, userId uuid.UUID, files LetterboxdFiles) (*ImportReport, error) {
+					return nil, tt.err
+				},
+			})
+			req := withUser(newMultipartRequest(t, map[string]string{}), &domain.User{ID: uuid.New()})
+			w := httptest.NewRecorder()
+
+			handler.ImportLetterboxd(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
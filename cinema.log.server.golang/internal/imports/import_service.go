@@ -0,0 +1,313 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoLetterboxdFiles = errors.New("at least one of ratings.csv, reviews.csv or diary.csv is required")
+)
+
+type RowStatus string
+
+const (
+	RowImported  RowStatus = "imported"
+	RowSkipped   RowStatus = "skipped"
+	RowAmbiguous RowStatus = "ambiguous"
+	RowFailed    RowStatus = "failed"
+)
+
+// RowResult is the outcome for one film in the import, along with the csv rows that referred to it
+type RowResult struct {
+	Rows       []string      `json:"rows"`
+	Name       string        `json:"name"`
+	Year       string        `json:"year"`
+	Status     RowStatus     `json:"status"`
+	Message    string        `json:"message,omitempty"`
+	Film       *domain.Film  `json:"film,omitempty"`
+	Candidates []domain.Film `json:"candidates,omitempty"`
+}
+
+type ImportReport struct {
+	Imported  int         `json:"imported"`
+	Skipped   int         `json:"skipped"`
+	Ambiguous int         `json:"ambiguous"`
+	Failed    int         `json:"failed"`
+	Results   []RowResult `json:"results"`
+}
+
+func (r *ImportReport) add(result RowResult) {
+	switch result.Status {
+	case RowImported:
+		r.Imported++
+	case RowSkipped:
+		r.Skipped++
+	case RowAmbiguous:
+		r.Ambiguous++
+	case RowFailed:
+		r.Failed++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// LetterboxdFiles holds the files from a Letterboxd export. Any of them may be nil.
+type LetterboxdFiles struct {
+	Ratings io.Reader
+	Reviews io.Reader
+	Diary   io.Reader
+}
+
+type Service struct {
+	FilmService   FilmService
+	ReviewService ReviewService
+	RatingService RatingService
+	GraphService  GraphService
+}
+
+type FilmService interface {
+	CreateFilm(ctx context.Context, film *domain.Film) (*domain.Film, error)
+	GetFilmsFromExternal(ctx context.Context, query string) ([]domain.Film, error)
+}
+
+type ReviewService interface {
+	GetAllReviewsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.Review, error)
+	CreateReview(ctx context.Context, review domain.Review) (*domain.Review, error)
+}
+
+type RatingService interface {
+	GetRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.UserFilmRating, error)
+	CreateRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, initialRating float32) (*domain.UserFilmRating, error)
+}
+
+type GraphService interface {
+	AddFilmToGraph(ctx context.Context, userID uuid.UUID, film domain.Film, recommendations []domain.Film) error
+}
+
+func NewService(filmService FilmService, reviewService ReviewService, ratingService RatingService, graphService GraphService) *Service {
+	return &Service{
+		FilmService:   filmService,
+		ReviewService: reviewService,
+		RatingService: ratingService,
+		GraphService:  graphService,
+	}
+}
+
+// ImportLetterboxd imports a Letterboxd export for a user. Every film is resolved against TMDB,
+// upserted into films, logged as a review and seeded into the user's Elo ranking using the star
+// rating as the initial rating. Problems with a single film are reported rather than aborting the import.
+func (s *Service) ImportLetterboxd(ctx context.Context, userId uuid.UUID, files LetterboxdFiles) (*ImportReport, error) {
+	sources := []struct {
+		name   string
+		reader io.Reader
+	}{
+		// ratings.csv first so the current star rating wins over older diary entries
+		{"ratings.csv", files.Ratings},
+		{"diary.csv", files.Diary},
+		{"reviews.csv", files.Reviews},
+	}
+
+	report := &ImportReport{Results: []RowResult{}}
+	var entries []LetterboxdEntry
+	parsed := 0
+	for _, source := range sources {
+		if source.reader == nil {
+			continue
+		}
+		fileEntries, rowErrors, err := ParseLetterboxdCSV(source.name, source.reader)
+		if err != nil {
+			return nil, err
+		}
+		for _, rowError := range rowErrors {
+			report.add(rowError)
+		}
+		entries = append(entries, fileEntries...)
+		parsed++
+	}
+
+	if parsed == 0 {
+		return nil, ErrNoLetterboxdFiles
+	}
+
+	existingReviews, err := s.ReviewService.GetAllReviewsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	reviewedFilms := make(map[uuid.UUID]bool, len(existingReviews))
+	for _, review := range existingReviews {
+		reviewedFilms[review.FilmId] = true
+	}
+
+	for _, film := range groupLetterboxdEntries(entries) {
+		result := s.importFilm(ctx, userId, film, reviewedFilms)
+		report.add(result)
+	}
+
+	return report, nil
+}
+
+func (s *Service) importFilm(ctx context.Context, userId uuid.UUID, lbFilm *letterboxdFilm, reviewedFilms map[uuid.UUID]bool) RowResult {
+	result := RowResult{
+		Rows: lbFilm.rows(),
+		Name: lbFilm.Name,
+		Year: lbFilm.Year,
+	}
+
+	candidates, err := s.FilmService.GetFilmsFromExternal(ctx, lbFilm.Name)
+	if err != nil {
+		result.Status = RowFailed
+		result.Message = "tmdb search failed: " + err.Error()
+		return result
+	}
+
+	match, ambiguous := resolveFilm(lbFilm.Name, lbFilm.Year, candidates)
+	if match == nil {
+		if len(ambiguous) > 0 {
+			result.Status = RowAmbiguous
+			result.Message = "more than one tmdb film matches this title and year"
+			result.Candidates = ambiguous
+			return result
+		}
+		result.Status = RowFailed
+		result.Message = "no tmdb film matches this title and year"
+		return result
+	}
+
+	// Store layer handles UPSERT, so the returned film carries the existing film_id if we already have it
+	film, err := s.FilmService.CreateFilm(ctx, match)
+	if err != nil {
+		result.Status = RowFailed
+		result.Message = "failed to save film: " + err.Error()
+		return result
+	}
+	result.Film = film
+
+	if reviewedFilms[film.ID] {
+		result.Status = RowSkipped
+		result.Message = "film has already been reviewed"
+		return result
+	}
+
+	for _, review := range buildReviews(userId, film.ID, lbFilm) {
+		if _, err := s.ReviewService.CreateReview(ctx, review); err != nil {
+			result.Status = RowFailed
+			result.Message = "failed to create review: " + err.Error()
+			return result
+		}
+	}
+	reviewedFilms[film.ID] = true
+
+	result.Status = RowImported
+	if lbFilm.Rating == 0 {
+		result.Message = "no star rating, film was not added to the ranking"
+	} else if _, err := s.RatingService.GetRating(ctx, userId, film.ID); err != nil {
+		if _, err := s.RatingService.CreateRating(ctx, userId, film.ID, lbFilm.Rating); err != nil {
+			result.Status = RowFailed
+			result.Message = "review imported but failed to create rating: " + err.Error()
+			return result
+		}
+	}
+
+	// Same as creating a review through the api - the graph is best effort
+	if err := s.GraphService.AddFilmToGraph(ctx, userId, *film, candidates); err != nil {
+		log.Printf("Failed to add imported film to graph: %v", err)
+	}
+
+	return result
+}
+
+// resolveFilm picks the TMDB search result for a Letterboxd title and year.
+// It returns the match, or when there isn't a single clear match, the candidates that were too close to call.
+func resolveFilm(name string, year string, candidates []domain.Film) (*domain.Film, []domain.Film) {
+	sameYear := make([]domain.Film, 0)
+	for _, candidate := range candidates {
+		// TMDB release dates are full yyyy-mm-dd dates
+		if year == "" || strings.HasPrefix(candidate.ReleaseYear, year) {
+			sameYear = append(sameYear, candidate)
+		}
+	}
+
+	if len(sameYear) == 1 {
+		return &sameYear[0], nil
+	}
+	if len(sameYear) == 0 {
+		return nil, nil
+	}
+
+	sameTitle := make([]domain.Film, 0)
+	for _, candidate := range sameYear {
+		if strings.EqualFold(candidate.Title, name) {
+			sameTitle = append(sameTitle, candidate)
+		}
+	}
+
+	if len(sameTitle) == 1 {
+		return &sameTitle[0], nil
+	}
+	if len(sameTitle) > 1 {
+		return nil, sameTitle
+	}
+	return nil, sameYear
+}
+
+// buildReviews turns the rows for a film into reviews. Every written review becomes its own review,
+// and a film that was only rated or logged gets a single review with no content.
+func buildReviews(userId uuid.UUID, filmId uuid.UUID, lbFilm *letterboxdFilm) []domain.Review {
+	var reviews []domain.Review
+	for _, entry := range lbFilm.Entries {
+		if entry.Review == "" {
+			continue
+		}
+		rating := entry.Rating
+		if rating == 0 {
+			rating = lbFilm.Rating
+		}
+		reviews = append(reviews, domain.Review{
+			ID:      uuid.New(),
+			Content: entry.Review,
+			Date:    reviewDate(entry.WatchedDate, entry.Date),
+			Rating:  rating,
+			FilmId:  filmId,
+			UserId:  userId,
+		})
+	}
+
+	if len(reviews) == 0 {
+		reviews = append(reviews, domain.Review{
+			ID:     uuid.New(),
+			Date:   reviewDate(lbFilm.lastWatched()),
+			Rating: lbFilm.Rating,
+			FilmId: filmId,
+			UserId: userId,
+		})
+	}
+
+	return reviews
+}
+
+// reviewDate returns the first non-zero date, falling back to now
+func reviewDate(dates ...time.Time) time.Time {
+	for _, date := range dates {
+		if !date.IsZero() {
+			return date
+		}
+	}
+	return time.Now()
+}
+
+func failedRow(entry LetterboxdEntry, message string) RowResult {
+	return RowResult{
+		Rows:    []string{entry.Row()},
+		Name:    entry.Name,
+		Year:    entry.Year,
+		Status:  RowFailed,
+		Message: message,
+	}
+}
@@ -0,0 +1,284 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type mockFilmService struct {
+	createFilmFunc           func(ctx context.Context, film *domain.Film) (*domain.Film, error)
+	getFilmsFromExternalFunc func(ctx context.Context, query string) ([]domain.Film, error)
+}
+
+func (m *mockFilmService) CreateFilm(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+	if m.createFilmFunc != nil {
+		return m.createFilmFunc(ctx, film)
+	}
+	return film, nil
+}
+
+func (m *mockFilmService) GetFilmsFromExternal(ctx context.Context, query string) ([]domain.Film, error) {
+	if m.getFilmsFromExternalFunc != nil {
+		return m.getFilmsFromExternalFunc(ctx, query)
+	}
+	return []domain.Film{}, nil
+}
+
+type mockReviewService struct {
+	getAllReviewsByUserIdFunc func(ctx context.Context, userId uuid.UUID) ([]domain.Review, error)
+	createReviewFunc          func(ctx context.Context, review domain.Review) (*domain.Review, error)
+}
+
+func (m *mockReviewService) GetAllReviewsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.Review, error) {
+	if m.getAllReviewsByUserIdFunc != nil {
+		return m.getAllReviewsByUserIdFunc(ctx, userId)
+	}
+	return []domain.Review{}, nil
+}
+
+func (m *mockReviewService) CreateReview(ctx context.Context, review domain.Review) (*domain.Review, error) {
+	if m.createReviewFunc != nil {
+		return m.createReviewFunc(ctx, review)
+	}
+	return &review, nil
+}
+
+type mockRatingService struct {
+	getRatingFunc    func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.UserFilmRating, error)
+	createRatingFunc func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, initialRating float32) (*domain.UserFilmRating, error)
+}
+
+func (m *mockRatingService) GetRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.UserFilmRating, error) {
+	if m.getRatingFunc != nil {
+		return m.getRatingFunc(ctx, userId, filmId)
+	}
+	return nil, errors.New("rating not found")
+}
+
+func (m *mockRatingService) CreateRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, initialRating float32) (*domain.UserFilmRating, error) {
+	if m.createRatingFunc != nil {
+		return m.createRatingFunc(ctx, userId, filmId, initialRating)
+	}
+	return &domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: filmId, InitialRating: initialRating}, nil
+}
+
+type mockGraphService struct {
+	addFilmToGraphFunc func(ctx context.Context, userID uuid.UUID, film domain.Film, recommendations []domain.Film) error
+}
+
+func (m *mockGraphService) AddFilmToGraph(ctx context.Context, userID uuid.UUID, film domain.Film, recommendations []domain.Film) error {
+	if m.addFilmToGraphFunc != nil {
+		return m.addFilmToGraphFunc(ctx, userID, film, recommendations)
+	}
+	return nil
+}
+
+func newTestService(f *mockFilmService, rv *mockReviewService, ra *mockRatingService, g *mockGraphService) *Service {
+	return NewService(f, rv, ra, g)
+}
+
+func TestResolveFilm(t *testing.T) {
+	heat95 := domain.Film{ExternalID: 949, Title: "Heat", ReleaseYear: "1995-12-15"}
+	heat86 := domain.Film{ExternalID: 1, Title: "Heat", ReleaseYear: "1986-03-14"}
+	heatDoc := domain.Film{ExternalID: 2, Title: "Heat: Behind the Scenes", ReleaseYear: "1995-01-01"}
+	heatTwin := domain.Film{ExternalID: 3, Title: "Heat", ReleaseYear: "1995-06-01"}
+
+	t.Run("single film in year", func(t *testing.T) {
+		match, ambiguous := resolveFilm("Heat", "1986", []domain.Film{heat95, heat86})
+		if match == nil || match.ExternalID != 1 {
+			t.Errorf("expected 1986 Heat, got %+v (ambiguous %v)", match, ambiguous)
+		}
+	})
+
+	t.Run("exact title breaks a tie", func(t *testing.T) {
+		match, _ := resolveFilm("heat", "1995", []domain.Film{heat95, heatDoc, heat86})
+		if match == nil || match.ExternalID != 949 {
+			t.Errorf("expected 1995 Heat, got %+v", match)
+		}
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		match, ambiguous := resolveFilm("Heat", "1995", []domain.Film{heat95, heatTwin})
+		if match != nil {
+			t.Errorf("expected no match, got %+v", match)
+		}
+		if len(ambiguous) != 2 {
+			t.Errorf("expected 2 candidates, got %d", len(ambiguous))
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		match, ambiguous := resolveFilm("Heat", "2020", []domain.Film{heat95, heat86})
+		if match != nil || len(ambiguous) != 0 {
+			t.Errorf("expected nothing, got %+v %v", match, ambiguous)
+		}
+	})
+}
+
+func TestService_ImportLetterboxd_NoFiles(t *testing.T) {
+	service := newTestService(&mockFilmService{}, &mockReviewService{}, &mockRatingService{}, &mockGraphService{})
+
+	_, err := service.ImportLetterboxd(context.Background(), uuid.New(), LetterboxdFiles{})
+	if !errors.Is(err, ErrNoLetterboxdFiles) {
+		t.Errorf("expected ErrNoLetterboxdFiles, got %v", err)
+	}
+}
+
+func TestService_ImportLetterboxd_ReportsEachOutcome(t *testing.T) {
+	ctx := context.Background()
+	userId := uuid.New()
+	alreadyReviewedId := uuid.New()
+
+	searchResults := map[string][]domain.Film{
+		"Heat": {
+			{ID: uuid.New(), ExternalID: 949, Title: "Heat", ReleaseYear: "1995-12-15"},
+		},
+		"Ran": {
+			{ID: uuid.New(), ExternalID: 11645, Title: "Ran", ReleaseYear: "1985-06-01"},
+		},
+		"Solaris": {
+			{ID: uuid.New(), ExternalID: 593, Title: "Solaris", ReleaseYear: "1972-03-20"},
+			{ID: uuid.New(), ExternalID: 2103, Title: "Solaris", ReleaseYear: "1972-01-01"},
+		},
+	}
+
+	filmService := &mockFilmService{
+		getFilmsFromExternalFunc: func(ctx context.Context, query string) ([]domain.Film, error) {
+			return searchResults[query], nil
+		},
+		createFilmFunc: func(ctx context.Context, film *domain.Film) (*domain.Film, error) {
+			if film.ExternalID == 11645 {
+				stored := *film
+				stored.ID = alreadyReviewedId
+				return &stored, nil
+			}
+			return film, nil
+		},
+	}
+
+	var createdReviews []domain.Review
+	reviewService := &mockReviewService{
+		getAllReviewsByUserIdFunc: func(ctx context.Context, id uuid.UUID) ([]domain.Review, error) {
+			return []domain.Review{{ID: uuid.New(), FilmId: alreadyReviewedId, UserId: userId}}, nil
+		},
+		createReviewFunc: func(ctx context.Context, review domain.Review) (*domain.Review, error) {
+			createdReviews = append(createdReviews, review)
+			return &review, nil
+		},
+	}
+
+	var seededRatings []float32
+	ratingService := &mockRatingService{
+		createRatingFunc: func(ctx context.Context, uid uuid.UUID, filmId uuid.UUID, initialRating float32) (*domain.UserFilmRating, error) {
+			seededRatings = append(seededRatings, initialRating)
+			return &domain.UserFilmRating{ID: uuid.New(), UserId: uid, FilmId: filmId, InitialRating: initialRating}, nil
+		},
+	}
+
+	graphCalls := 0
+	graphService := &mockGraphService{
+		addFilmToGraphFunc: func(ctx context.Context, userID uuid.UUID, film domain.Film, recommendations []domain.Film) error {
+			graphCalls++
+			return nil
+		},
+	}
+
+	ratingsCsv := "Date,Name,Year,Letterboxd URI,Rating\n" +
+		"2024-01-02,Heat,1995,https://boxd.it/1,4.5\n" +
+		"2024-01-02,Ran,1985,https://boxd.it/2,5\n" +
+		"2024-01-02,Solaris,1972,https://boxd.it/3,4\n" +
+		"2024-01-02,Unknown Film,1999,https://boxd.it/4,2\n"
+	reviewsCsv := "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Review,Tags,Watched Date\n" +
+		"2024-01-05,Heat,1995,https://boxd.it/x,4.5,No,Great diner scene,,2024-01-04\n"
+
+	service := newTestService(filmService, reviewService, ratingService, graphService)
+	report, err := service.ImportLetterboxd(ctx, userId, LetterboxdFiles{
+		Ratings: strings.NewReader(ratingsCsv),
+		Reviews: strings.NewReader(reviewsCsv),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if report.Imported != 1 || report.Skipped != 1 || report.Ambiguous != 1 || report.Failed != 1 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+
+	if len(createdReviews) != 1 || createdReviews[0].Content != "Great diner scene" {
+		t.Fatalf("expected one review with the letterboxd text, got %+v", createdReviews)
+	}
+	if createdReviews[0].Date.Format(letterboxdDateLayout) != "2024-01-04" {
+		t.Errorf("expected review dated on the watched date, got %v", createdReviews[0].Date)
+	}
+	if len(seededRatings) != 1 || seededRatings[0] != 4.5 {
+		t.Errorf("expected rating seeded with 4.5 stars, got %v", seededRatings)
+	}
+	if graphCalls != 1 {
+		t.Errorf("expected film to be added to graph once, got %d", graphCalls)
+	}
+
+	var heat RowResult
+	for _, result := range report.Results {
+		if result.Name == "Heat" {
+			heat = result
+		}
+	}
+	if len(heat.Rows) != 2 {
+		t.Errorf("expected Heat to list rows from both files, got %v", heat.Rows)
+	}
+}
+
+func TestService_ImportLetterboxd_SearchFailureDoesNotAbort(t *testing.T) {
+	filmService := &mockFilmService{
+		getFilmsFromExternalFunc: func(ctx context.Context, query string) ([]domain.Film, error) {
+			if query == "Heat" {
+				return nil, errors.New("tmdb down")
+			}
+			return []domain.Film{{ID: uuid.New(), ExternalID: 11645, Title: "Ran", ReleaseYear: "1985-06-01"}}, nil
+		},
+	}
+	service := newTestService(filmService, &mockReviewService{}, &mockRatingService{}, &mockGraphService{})
+
+	ratingsCsv := "Date,Name,Year,Letterboxd URI,Rating\n" +
+		"2024-01-02,Heat,1995,https://boxd.it/1,4.5\n" +
+		"2024-01-02,Ran,1985,https://boxd.it/2,5\n"
+
+	report, err := service.ImportLetterboxd(context.Background(), uuid.New(), LetterboxdFiles{Ratings: strings.NewReader(ratingsCsv)})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.Failed != 1 || report.Imported != 1 {
+		t.Errorf("expected 1 failed and 1 imported, got %+v", report)
+	}
+}
+
+func TestService_ImportLetterboxd_UnratedFilmIsNotRanked(t *testing.T) {
+	filmService := &mockFilmService{
+		getFilmsFromExternalFunc: func(ctx context.Context, query string) ([]domain.Film, error) {
+			return []domain.Film{{ID: uuid.New(), ExternalID: 11645, Title: "Ran", ReleaseYear: "1985-06-01"}}, nil
+		},
+	}
+	ratingService := &mockRatingService{
+		createRatingFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, initialRating float32) (*domain.UserFilmRating, error) {
+			t.Error("expected no rating to be created for an unrated film")
+			return nil, nil
+		},
+	}
+	service := newTestService(filmService, &mockReviewService{}, ratingService, &mockGraphService{})
+
+	diaryCsv := "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Tags,Watched Date\n" +
+		"2024-01-02,Ran,1985,https://boxd.it/2,,No,,2024-01-01\n"
+
+	report, err := service.ImportLetterboxd(context.Background(), uuid.New(), LetterboxdFiles{Diary: strings.NewReader(diaryCsv)})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.Imported != 1 {
+		t.Errorf("expected film to be imported, got %+v", report)
+	}
+}
@@ -0,0 +1,177 @@
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidLetterboxdCSV = errors.New("invalid letterboxd csv")
+)
+
+// letterboxd exports dates as yyyy-mm-dd
+const letterboxdDateLayout = "2006-01-02"
+
+// LetterboxdEntry is a single row from one of the Letterboxd export files
+// (ratings.csv, reviews.csv or diary.csv). Columns that a file doesn't have are left empty.
+type LetterboxdEntry struct {
+	Source      string
+	Line        int
+	Date        time.Time
+	Name        string
+	Year        string
+	URI         string
+	Rating      float32 // 0 when the row has no star rating
+	Rewatch     bool
+	Review      string
+	WatchedDate time.Time
+}
+
+// Row returns a "file:line" reference used when reporting on this entry
+func (e LetterboxdEntry) Row() string {
+	return fmt.Sprintf("%s:%d", e.Source, e.Line)
+}
+
+// ParseLetterboxdCSV reads a Letterboxd export file. Columns are looked up by header name so the
+// same function handles ratings.csv, reviews.csv and diary.csv. Rows that can't be parsed are
+// returned as row errors rather than failing the whole file.
+func ParseLetterboxdCSV(source string, r io.Reader) ([]LetterboxdEntry, []RowResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // letterboxd omits trailing empty columns on some rows
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s: could not read header: %v", ErrInvalidLetterboxdCSV, source, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, nil, fmt.Errorf("%w: %s: missing Name column", ErrInvalidLetterboxdCSV, source)
+	}
+
+	get := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var entries []LetterboxdEntry
+	var rowErrors []RowResult
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		entry := LetterboxdEntry{Source: source, Line: line}
+		if err != nil {
+			rowErrors = append(rowErrors, failedRow(entry, fmt.Sprintf("could not read row: %v", err)))
+			continue
+		}
+
+		entry.Name = get(record, "name")
+		entry.Year = get(record, "year")
+		entry.URI = get(record, "letterboxd uri")
+		entry.Review = get(record, "review")
+		entry.Rewatch = strings.EqualFold(get(record, "rewatch"), "yes")
+
+		if entry.Name == "" {
+			rowErrors = append(rowErrors, failedRow(entry, "row has no film name"))
+			continue
+		}
+
+		if rating := get(record, "rating"); rating != "" {
+			value, err := strconv.ParseFloat(rating, 32)
+			if err != nil || value < 0 || value > 5 {
+				rowErrors = append(rowErrors, failedRow(entry, fmt.Sprintf("invalid rating %q", rating)))
+				continue
+			}
+			entry.Rating = float32(value)
+		}
+
+		if date := get(record, "date"); date != "" {
+			if entry.Date, err = time.Parse(letterboxdDateLayout, date); err != nil {
+				rowErrors = append(rowErrors, failedRow(entry, fmt.Sprintf("invalid date %q", date)))
+				continue
+			}
+		}
+
+		if watched := get(record, "watched date"); watched != "" {
+			if entry.WatchedDate, err = time.Parse(letterboxdDateLayout, watched); err != nil {
+				rowErrors = append(rowErrors, failedRow(entry, fmt.Sprintf("invalid watched date %q", watched)))
+				continue
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rowErrors, nil
+}
+
+// letterboxdFilm collects every row that refers to the same film across the export files.
+// Rows are matched on name and year because the Letterboxd URI in diary.csv and reviews.csv
+// points at the diary entry rather than the film.
+type letterboxdFilm struct {
+	Name    string
+	Year    string
+	Rating  float32
+	Entries []LetterboxdEntry
+}
+
+func (f *letterboxdFilm) rows() []string {
+	rows := make([]string, 0, len(f.Entries))
+	for _, entry := range f.Entries {
+		rows = append(rows, entry.Row())
+	}
+	return rows
+}
+
+// lastWatched returns the most recent date the film was watched or logged
+func (f *letterboxdFilm) lastWatched() time.Time {
+	var latest time.Time
+	for _, entry := range f.Entries {
+		date := entry.WatchedDate
+		if date.IsZero() {
+			date = entry.Date
+		}
+		if date.After(latest) {
+			latest = date
+		}
+	}
+	return latest
+}
+
+// groupLetterboxdEntries groups entries by film, keeping first-seen order. The first star rating
+// seen for a film wins, so ratings.csv (the current rating) should be passed in before the diary.
+func groupLetterboxdEntries(entries []LetterboxdEntry) []*letterboxdFilm {
+	byKey := make(map[string]*letterboxdFilm)
+	var films []*letterboxdFilm
+
+	for _, entry := range entries {
+		key := strings.ToLower(entry.Name) + "|" + entry.Year
+		film, ok := byKey[key]
+		if !ok {
+			film = &letterboxdFilm{Name: entry.Name, Year: entry.Year}
+			byKey[key] = film
+			films = append(films, film)
+		}
+		if film.Rating == 0 && entry.Rating > 0 {
+			film.Rating = entry.Rating
+		}
+		film.Entries = append(film.Entries, entry)
+	}
+
+	return films
+}
@@ -0,0 +1,111 @@
+package imports
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLetterboxdCSV_Ratings(t *testing.T) {
+	csv := "Date,Name,Year,Letterboxd URI,Rating\n" +
+		"2024-01-02,Heat,1995,https://boxd.it/1,4.5\n" +
+		"2024-01-03,\"Crouching Tiger, Hidden Dragon\",2000,https://boxd.it/2,3\n"
+
+	entries, rowErrors, err := ParseLetterboxdCSV("ratings.csv", strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(rowErrors) != 0 {
+		t.Fatalf("expected no row errors, got %v", rowErrors)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	if entries[0].Name != "Heat" || entries[0].Year != "1995" || entries[0].Rating != 4.5 {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[0].Row() != "ratings.csv:2" {
+		t.Errorf("expected row ratings.csv:2, got %s", entries[0].Row())
+	}
+	if !entries[0].Date.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date %v", entries[0].Date)
+	}
+	if entries[1].Name != "Crouching Tiger, Hidden Dragon" {
+		t.Errorf("expected quoted name to be parsed, got %q", entries[1].Name)
+	}
+}
+
+func TestParseLetterboxdCSV_Reviews(t *testing.T) {
+	csv := "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Review,Tags,Watched Date\n" +
+		"2024-02-01,Heat,1995,https://boxd.it/a,5,Yes,\"Still great\",,2024-01-30\n"
+
+	entries, _, err := ParseLetterboxdCSV("reviews.csv", strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Review != "Still great" {
+		t.Errorf("expected review text, got %q", entry.Review)
+	}
+	if !entry.Rewatch {
+		t.Error("expected rewatch to be true")
+	}
+	if !entry.WatchedDate.Equal(time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected watched date %v", entry.WatchedDate)
+	}
+}
+
+func TestParseLetterboxdCSV_BadRowsAreReported(t *testing.T) {
+	csv := "Date,Name,Year,Letterboxd URI,Rating\n" +
+		"2024-01-02,Heat,1995,https://boxd.it/1,eleven\n" +
+		"not-a-date,Ran,1985,https://boxd.it/2,4\n" +
+		"2024-01-02,,1985,https://boxd.it/3,4\n" +
+		"2024-01-02,Ran,1985,https://boxd.it/4,4\n"
+
+	entries, rowErrors, err := ParseLetterboxdCSV("ratings.csv", strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected 1 valid entry, got %d", len(entries))
+	}
+	if len(rowErrors) != 3 {
+		t.Fatalf("expected 3 row errors, got %d", len(rowErrors))
+	}
+	for _, rowError := range rowErrors {
+		if rowError.Status != RowFailed {
+			t.Errorf("expected failed status, got %s", rowError.Status)
+		}
+	}
+}
+
+func TestParseLetterboxdCSV_MissingNameColumn(t *testing.T) {
+	_, _, err := ParseLetterboxdCSV("ratings.csv", strings.NewReader("Date,Title\n2024-01-01,Heat\n"))
+	if !errors.Is(err, ErrInvalidLetterboxdCSV) {
+		t.Errorf("expected ErrInvalidLetterboxdCSV, got %v", err)
+	}
+}
+
+func TestGroupLetterboxdEntries(t *testing.T) {
+	entries := []LetterboxdEntry{
+		{Source: "ratings.csv", Line: 2, Name: "Heat", Year: "1995", Rating: 4.5},
+		{Source: "diary.csv", Line: 2, Name: "heat", Year: "1995", Rating: 3},
+		{Source: "diary.csv", Line: 3, Name: "Heat", Year: "1986"},
+	}
+
+	films := groupLetterboxdEntries(entries)
+	if len(films) != 2 {
+		t.Fatalf("expected 2 films, got %d", len(films))
+	}
+	if len(films[0].Entries) != 2 {
+		t.Errorf("expected both 1995 rows to be grouped, got %d", len(films[0].Entries))
+	}
+	if films[0].Rating != 4.5 {
+		t.Errorf("expected first rating seen to win, got %v", films[0].Rating)
+	}
+}
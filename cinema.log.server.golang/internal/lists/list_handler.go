@@ -0,0 +1,282 @@
+package lists
+
+import (
+	"context"
+	"net/http"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	ListService ListService
+}
+
+type ListService interface {
+	GetLists(ctx context.Context, userId uuid.UUID) ([]domain.FilmList, error)
+	GetList(ctx context.Context, userId uuid.UUID, listId uuid.UUID) (*domain.FilmListDetail, error)
+	GetPublicList(ctx context.Context, listId uuid.UUID) (*domain.FilmListDetail, error)
+	CreateList(ctx context.Context, userId uuid.UUID, req ListRequest) (*domain.FilmList, error)
+	UpdateList(ctx context.Context, userId uuid.UUID, listId uuid.UUID, req ListRequest) (*domain.FilmList, error)
+	DeleteList(ctx context.Context, userId uuid.UUID, listId uuid.UUID) error
+	AddFilm(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmId uuid.UUID, position int) (*domain.FilmListDetail, error)
+	RemoveFilm(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmId uuid.UUID) (*domain.FilmListDetail, error)
+	ReorderFilms(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmIds []uuid.UUID) (*domain.FilmListDetail, error)
+}
+
+func NewHandler(listService ListService) *Handler {
+	return &Handler{
+		ListService: listService,
+	}
+}
+
+type AddFilmRequest struct {
+	FilmId   uuid.UUID `json:"filmId"`
+	Position int       `json:"position"` // 1 based, leave out to add to the end
+}
+
+type ReorderFilmsRequest struct {
+	FilmIds []uuid.UUID `json:"filmIds"` // every film in the list, in the new order
+}
+
+// writeListError maps the list errors onto status codes, anything else is a 500 with message
+func writeListError(w http.ResponseWriter, err error, message string) {
+	switch err {
+	case ErrListNotFound, ErrFilmNotFound, ErrFilmNotInList:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case ErrFilmAlreadyInList:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case ErrEmptyListName, ErrInvalidVisibility, ErrInvalidOrdering, ErrEloOrderedList, ErrReorderMismatch:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetLists(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	lists, err := h.ListService.GetLists(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get lists", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, lists)
+}
+
+func (h *Handler) GetList(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	listId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.ListService.GetList(r.Context(), user.ID, listId)
+	if err != nil {
+		writeListError(w, err, "Failed to get list")
+		return
+	}
+
+	utils.SendJSON(w, list)
+}
+
+// GetPublicList serves public lists to anyone, it sits outside the auth middleware
+func (h *Handler) GetPublicList(w http.ResponseWriter, r *http.Request) {
+	listId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.ListService.GetPublicList(r.Context(), listId)
+	if err != nil {
+		writeListError(w, err, "Failed to get list")
+		return
+	}
+
+	utils.SendJSON(w, list)
+}
+
+func (h *Handler) CreateList(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ListRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.ListService.CreateList(r.Context(), user.ID, req)
+	if err != nil {
+		writeListError(w, err, "Failed to create list")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	utils.SendJSON(w, list)
+}
+
+func (h *Handler) UpdateList(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	listId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ListRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.ListService.UpdateList(r.Context(), user.ID, listId, req)
+	if err != nil {
+		writeListError(w, err, "Failed to update list")
+		return
+	}
+
+	utils.SendJSON(w, list)
+}
+
+func (h *Handler) DeleteList(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	listId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.ListService.DeleteList(r.Context(), user.ID, listId); err != nil {
+		writeListError(w, err, "Failed to delete list")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) AddFilm(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	listId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	var req AddFilmRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.FilmId == uuid.Nil {
+		http.Error(w, "filmId is required", http.StatusBadRequest)
+		return
+	}
+	if req.Position < 0 {
+		http.Error(w, "position cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.ListService.AddFilm(r.Context(), user.ID, listId, req.FilmId, req.Position)
+	if err != nil {
+		writeListError(w, err, "Failed to add film to list")
+		return
+	}
+
+	utils.SendJSON(w, list)
+}
+
+func (h *Handler) RemoveFilm(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	listId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+	filmId, err := utils.ParseUUID(r.PathValue("filmId"))
+	if err != nil {
+		http.Error(w, "Invalid film ID", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.ListService.RemoveFilm(r.Context(), user.ID, listId, filmId)
+	if err != nil {
+		writeListError(w, err, "Failed to remove film from list")
+		return
+	}
+
+	utils.SendJSON(w, list)
+}
+
+func (h *Handler) ReorderFilms(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	listId, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ReorderFilmsRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.ListService.ReorderFilms(r.Context(), user.ID, listId, req.FilmIds)
+	if err != nil {
+		writeListError(w, err, "Failed to reorder list")
+		return
+	}
+
+	utils.SendJSON(w, list)
+}
@@ -0,0 +1,273 @@
+package lists
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"github.com/google/uuid"
+)
+
+type mockListService struct {
+	getListFunc       func(ctx context.Context, userId uuid.UUID, listId uuid.UUID) (*domain.FilmListDetail, error)
+	getPublicListFunc func(ctx context.Context, listId uuid.UUID) (*domain.FilmListDetail, error)
+	createListFunc    func(ctx context.Context, userId uuid.UUID, req ListRequest) (*domain.FilmList, error)
+	addFilmFunc       func(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmId uuid.UUID, position int) (*domain.FilmListDetail, error)
+	reorderFilmsFunc  func(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmIds []uuid.UUID) (*domain.FilmListDetail, error)
+}
+
+func (m *mockListService) GetLists(ctx context.Context, userId uuid.UUID) ([]domain.FilmList, error) {
+	return []domain.FilmList{{ID: uuid.New(), UserID: userId}}, nil
+}
+
+func (m *mockListService) GetList(ctx context.Context, userId uuid.UUID, listId uuid.UUID) (*domain.FilmListDetail, error) {
+	if m.getListFunc != nil {
+		return m.getListFunc(ctx, userId, listId)
+	}
+	return &domain.FilmListDetail{List: domain.FilmList{ID: listId}}, nil
+}
+
+func (m *mockListService) GetPublicList(ctx context.Context, listId uuid.UUID) (*domain.FilmListDetail, error) {
+	if m.getPublicListFunc != nil {
+		return m.getPublicListFunc(ctx, listId)
+	}
+	return &domain.FilmListDetail{List: domain.FilmList{ID: listId, Visibility: domain.ListVisibilityPublic}}, nil
+}
+
+func (m *mockListService) CreateList(ctx context.Context, userId uuid.UUID, req ListRequest) (*domain.FilmList, error) {
+	if m.createListFunc != nil {
+		return m.createListFunc(ctx, userId, req)
+	}
+	return &domain.FilmList{ID: uuid.New(), UserID: userId, Name: req.Name}, nil
+}
+
+func (m *mockListService) UpdateList(ctx context.Context, userId uuid.UUID, listId uuid.UUID, req ListRequest) (*domain.FilmList, error) {
+	return &domain.FilmList{ID: listId, UserID: userId, Name: req.Name}, nil
+}
+
+func (m *mockListService) DeleteList(ctx context.Context, userId uuid.UUID, listId uuid.UUID) error {
+	return nil
+}
+
+func (m *mockListService) AddFilm(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmId uuid.UUID, position int) (*domain.FilmListDetail, error) {
+	if m.addFilmFunc != nil {
+		return m.addFilmFunc(ctx, userId, listId, filmId, position)
+	}
+	return &domain.FilmListDetail{List: domain.FilmList{ID: listId}}, nil
+}
+
+func (m *mockListService) RemoveFilm(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmId uuid.UUID) (*domain.FilmListDetail, error) {
+	return &domain.FilmListDetail{List: domain.FilmList{ID: listId}}, nil
+}
+
+func (m *mockListService) ReorderFilms(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmIds []uuid.UUID) (*domain.FilmListDetail, error) {
+	if m.reorderFilmsFunc != nil {
+		return m.reorderFilmsFunc(ctx, userId, listId, filmIds)
+	}
+	return &domain.FilmListDetail{List: domain.FilmList{ID: listId}}, nil
+}
+
+func withUser(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), middleware.KeyUser, &domain.User{ID: uuid.New()}))
+}
+
+func TestHandler_CreateList(t *testing.T) {
+	handler := NewHandler(&mockListService{})
+
+	body, _ := json.Marshal(ListRequest{Name: "Best of 2024", Visibility: domain.ListVisibilityPublic})
+	req := withUser(httptest.NewRequest(http.MethodPost, "/lists", bytes.NewReader(body)))
+	w := httptest.NewRecorder()
+	handler.CreateList(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d, body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var list domain.FilmList
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if list.Name != "Best of 2024" {
+		t.Errorf("expected the new list, got %+v", list)
+	}
+}
+
+func TestHandler_CreateList_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		expected int
+	}{
+		{"invalid json", "{", nil, http.StatusBadRequest},
+		{"empty name", `{"name": ""}`, ErrEmptyListName, http.StatusBadRequest},
+		{"invalid visibility", `{"name": "a", "visibility": "friends"}`, ErrInvalidVisibility, http.StatusBadRequest},
+		{"store failure", `{"name": "a"}`, errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockListService{
+				createListFunc: func(ctx context.Context, userId uuid.UUID, req ListRequest) (*domain.FilmList, error) {
+					return nil, tt.err
+				},
+			})
+
+			req := withUser(httptest.NewRequest(http.MethodPost, "/lists", bytes.NewBufferString(tt.body)))
+			w := httptest.NewRecorder()
+			handler.CreateList(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_GetLists_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockListService{})
+
+	w := httptest.NewRecorder()
+	handler.GetLists(w, httptest.NewRequest(http.MethodGet, "/lists", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_GetList_NotFound(t *testing.T) {
+	handler := NewHandler(&mockListService{
+		getListFunc: func(ctx context.Context, userId uuid.UUID, listId uuid.UUID) (*domain.FilmListDetail, error) {
+			return nil, ErrListNotFound
+		},
+	})
+
+	listId := uuid.NewString()
+	req := withUser(httptest.NewRequest(http.MethodGet, "/lists/"+listId, nil))
+	req.SetPathValue("id", listId)
+	w := httptest.NewRecorder()
+	handler.GetList(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandler_GetPublicList_WithoutUser(t *testing.T) {
+	listId := uuid.New()
+	handler := NewHandler(&mockListService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/public/lists/"+listId.String(), nil)
+	req.SetPathValue("id", listId.String())
+	w := httptest.NewRecorder()
+	handler.GetPublicList(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var detail domain.FilmListDetail
+	if err := json.NewDecoder(w.Body).Decode(&detail); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if detail.List.ID != listId {
+		t.Errorf("expected list %v, got %v", listId, detail.List.ID)
+	}
+}
+
+func TestHandler_AddFilm(t *testing.T) {
+	listId, filmId := uuid.New(), uuid.New()
+	var gotFilm uuid.UUID
+	var gotPosition int
+	handler := NewHandler(&mockListService{
+		addFilmFunc: func(ctx context.Context, userId uuid.UUID, l uuid.UUID, f uuid.UUID, position int) (*domain.FilmListDetail, error) {
+			gotFilm, gotPosition = f, position
+			return &domain.FilmListDetail{List: domain.FilmList{ID: l}}, nil
+		},
+	})
+
+	body, _ := json.Marshal(AddFilmRequest{FilmId: filmId, Position: 2})
+	req := withUser(httptest.NewRequest(http.MethodPost, "/lists/"+listId.String()+"/films", bytes.NewReader(body)))
+	req.SetPathValue("id", listId.String())
+	w := httptest.NewRecorder()
+	handler.AddFilm(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d, body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if gotFilm != filmId || gotPosition != 2 {
+		t.Errorf("expected film %v at 2, got %v at %d", filmId, gotFilm, gotPosition)
+	}
+}
+
+func TestHandler_AddFilm_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		expected int
+	}{
+		{"missing film", `{}`, nil, http.StatusBadRequest},
+		{"negative position", `{"filmId": "` + uuid.NewString() + `", "position": -1}`, nil, http.StatusBadRequest},
+		{"already in list", `{"filmId": "` + uuid.NewString() + `"}`, ErrFilmAlreadyInList, http.StatusConflict},
+		{"unknown film", `{"filmId": "` + uuid.NewString() + `"}`, ErrFilmNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockListService{
+				addFilmFunc: func(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmId uuid.UUID, position int) (*domain.FilmListDetail, error) {
+					return nil, tt.err
+				},
+			})
+
+			listId := uuid.NewString()
+			req := withUser(httptest.NewRequest(http.MethodPost, "/lists/"+listId+"/films", bytes.NewBufferString(tt.body)))
+			req.SetPathValue("id", listId)
+			w := httptest.NewRecorder()
+			handler.AddFilm(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_ReorderFilms_EloOrdered(t *testing.T) {
+	handler := NewHandler(&mockListService{
+		reorderFilmsFunc: func(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmIds []uuid.UUID) (*domain.FilmListDetail, error) {
+			return nil, ErrEloOrderedList
+		},
+	})
+
+	listId := uuid.NewString()
+	body, _ := json.Marshal(ReorderFilmsRequest{FilmIds: []uuid.UUID{uuid.New()}})
+	req := withUser(httptest.NewRequest(http.MethodPut, "/lists/"+listId+"/films", bytes.NewReader(body)))
+	req.SetPathValue("id", listId)
+	w := httptest.NewRecorder()
+	handler.ReorderFilms(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHandler_RemoveFilm_InvalidFilmId(t *testing.T) {
+	handler := NewHandler(&mockListService{})
+
+	listId := uuid.NewString()
+	req := withUser(httptest.NewRequest(http.MethodDelete, "/lists/"+listId+"/films/abc", nil))
+	req.SetPathValue("id", listId)
+	req.SetPathValue("filmId", "abc")
+	w := httptest.NewRecorder()
+	handler.RemoveFilm(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,223 @@
+package lists
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrListNotFound      = errors.New("list not found")
+	ErrFilmNotFound      = errors.New("film not found")
+	ErrFilmAlreadyInList = errors.New("film is already in the list")
+	ErrFilmNotInList     = errors.New("film is not in the list")
+	ErrEmptyListName     = errors.New("list name cannot be empty")
+	ErrInvalidVisibility = errors.New("visibility must be private or public")
+	ErrInvalidOrdering   = errors.New("ordering must be manual or elo")
+	ErrEloOrderedList    = errors.New("films in an elo ordered list follow the ranking and cannot be reordered")
+	ErrReorderMismatch   = errors.New("reorder must include every film in the list exactly once")
+)
+
+type Service struct {
+	ListStore ListStore
+}
+
+type ListStore interface {
+	GetListsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.FilmList, error)
+	GetList(ctx context.Context, listId uuid.UUID) (*domain.FilmList, error)
+	GetListFilms(ctx context.Context, list domain.FilmList) ([]domain.FilmListEntry, error)
+	GetListFilmIds(ctx context.Context, listId uuid.UUID) ([]uuid.UUID, error)
+	CreateList(ctx context.Context, list domain.FilmList) (*domain.FilmList, error)
+	UpdateList(ctx context.Context, list domain.FilmList) (*domain.FilmList, error)
+	DeleteList(ctx context.Context, userId uuid.UUID, listId uuid.UUID) error
+	AddFilm(ctx context.Context, listId uuid.UUID, filmId uuid.UUID, position int) error
+	RemoveFilm(ctx context.Context, listId uuid.UUID, filmId uuid.UUID) error
+	ReorderFilms(ctx context.Context, listId uuid.UUID, filmIds []uuid.UUID) error
+}
+
+func NewService(listStore ListStore) *Service {
+	return &Service{
+		ListStore: listStore,
+	}
+}
+
+// ListRequest is the part of a list the owner can edit
+type ListRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"` // private or public, defaults to private
+	Ordering    string `json:"ordering"`   // manual or elo, defaults to manual
+}
+
+// validate trims the name and fills in the default visibility and ordering
+func (req *ListRequest) validate() error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return ErrEmptyListName
+	}
+
+	switch req.Visibility {
+	case "":
+		req.Visibility = domain.ListVisibilityPrivate
+	case domain.ListVisibilityPrivate, domain.ListVisibilityPublic:
+	default:
+		return ErrInvalidVisibility
+	}
+
+	switch req.Ordering {
+	case "":
+		req.Ordering = domain.ListOrderingManual
+	case domain.ListOrderingManual, domain.ListOrderingElo:
+	default:
+		return ErrInvalidOrdering
+	}
+
+	return nil
+}
+
+func (s *Service) GetLists(ctx context.Context, userId uuid.UUID) ([]domain.FilmList, error) {
+	return s.ListStore.GetListsByUserId(ctx, userId)
+}
+
+// GetList returns one of the user's own lists, or anyone's public list
+func (s *Service) GetList(ctx context.Context, userId uuid.UUID, listId uuid.UUID) (*domain.FilmListDetail, error) {
+	list, err := s.ListStore.GetList(ctx, listId)
+	if err != nil {
+		return nil, err
+	}
+	if list.UserID != userId && list.Visibility != domain.ListVisibilityPublic {
+		return nil, ErrListNotFound
+	}
+	return s.withFilms(ctx, *list)
+}
+
+// GetPublicList returns a list for someone who isn't signed in, private lists don't exist as far as they can tell
+func (s *Service) GetPublicList(ctx context.Context, listId uuid.UUID) (*domain.FilmListDetail, error) {
+	list, err := s.ListStore.GetList(ctx, listId)
+	if err != nil {
+		return nil, err
+	}
+	if list.Visibility != domain.ListVisibilityPublic {
+		return nil, ErrListNotFound
+	}
+	return s.withFilms(ctx, *list)
+}
+
+func (s *Service) CreateList(ctx context.Context, userId uuid.UUID, req ListRequest) (*domain.FilmList, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return s.ListStore.CreateList(ctx, domain.FilmList{
+		ID:          uuid.New(),
+		UserID:      userId,
+		Name:        req.Name,
+		Description: req.Description,
+		Visibility:  req.Visibility,
+		Ordering:    req.Ordering,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+}
+
+func (s *Service) UpdateList(ctx context.Context, userId uuid.UUID, listId uuid.UUID, req ListRequest) (*domain.FilmList, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	return s.ListStore.UpdateList(ctx, domain.FilmList{
+		ID:          listId,
+		UserID:      userId,
+		Name:        req.Name,
+		Description: req.Description,
+		Visibility:  req.Visibility,
+		Ordering:    req.Ordering,
+		UpdatedAt:   time.Now(),
+	})
+}
+
+func (s *Service) DeleteList(ctx context.Context, userId uuid.UUID, listId uuid.UUID) error {
+	return s.ListStore.DeleteList(ctx, userId, listId)
+}
+
+// AddFilm adds a film to one of the user's lists, position is 1 based and 0 means the end of the list
+func (s *Service) AddFilm(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmId uuid.UUID, position int) (*domain.FilmListDetail, error) {
+	list, err := s.ownedList(ctx, userId, listId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ListStore.AddFilm(ctx, listId, filmId, position); err != nil {
+		return nil, err
+	}
+	return s.withFilms(ctx, *list)
+}
+
+func (s *Service) RemoveFilm(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmId uuid.UUID) (*domain.FilmListDetail, error) {
+	list, err := s.ownedList(ctx, userId, listId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ListStore.RemoveFilm(ctx, listId, filmId); err != nil {
+		return nil, err
+	}
+	return s.withFilms(ctx, *list)
+}
+
+// ReorderFilms puts the films of a manually ordered list in the order of filmIds, which must name every film in the list
+func (s *Service) ReorderFilms(ctx context.Context, userId uuid.UUID, listId uuid.UUID, filmIds []uuid.UUID) (*domain.FilmListDetail, error) {
+	list, err := s.ownedList(ctx, userId, listId)
+	if err != nil {
+		return nil, err
+	}
+	if list.Ordering == domain.ListOrderingElo {
+		return nil, ErrEloOrderedList
+	}
+
+	current, err := s.ListStore.GetListFilmIds(ctx, listId)
+	if err != nil {
+		return nil, err
+	}
+	if len(filmIds) != len(current) {
+		return nil, ErrReorderMismatch
+	}
+	inList := make(map[uuid.UUID]bool, len(current))
+	for _, filmId := range current {
+		inList[filmId] = true
+	}
+	for _, filmId := range filmIds {
+		if !inList[filmId] {
+			return nil, ErrReorderMismatch
+		}
+		delete(inList, filmId) // a film named twice fails the check above the second time
+	}
+
+	if err := s.ListStore.ReorderFilms(ctx, listId, filmIds); err != nil {
+		return nil, err
+	}
+	return s.withFilms(ctx, *list)
+}
+
+// ownedList returns the list if it belongs to the user, another user's list is reported as not found
+func (s *Service) ownedList(ctx context.Context, userId uuid.UUID, listId uuid.UUID) (*domain.FilmList, error) {
+	list, err := s.ListStore.GetList(ctx, listId)
+	if err != nil {
+		return nil, err
+	}
+	if list.UserID != userId {
+		return nil, ErrListNotFound
+	}
+	return list, nil
+}
+
+func (s *Service) withFilms(ctx context.Context, list domain.FilmList) (*domain.FilmListDetail, error) {
+	films, err := s.ListStore.GetListFilms(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.FilmListDetail{List: list, Films: films}, nil
+}
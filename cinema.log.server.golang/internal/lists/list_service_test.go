@@ -0,0 +1,206 @@
+package lists
+
+import (
+	"context"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type mockListStore struct {
+	lists       map[uuid.UUID]*domain.FilmList
+	filmIds     []uuid.UUID
+	reordered   []uuid.UUID
+	addFilmFunc func(ctx context.Context, listId uuid.UUID, filmId uuid.UUID, position int) error
+}
+
+func (m *mockListStore) GetListsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.FilmList, error) {
+	var lists []domain.FilmList
+	for _, list := range m.lists {
+		if list.UserID == userId {
+			lists = append(lists, *list)
+		}
+	}
+	return lists, nil
+}
+
+func (m *mockListStore) GetList(ctx context.Context, listId uuid.UUID) (*domain.FilmList, error) {
+	if list, ok := m.lists[listId]; ok {
+		return list, nil
+	}
+	return nil, ErrListNotFound
+}
+
+func (m *mockListStore) GetListFilms(ctx context.Context, list domain.FilmList) ([]domain.FilmListEntry, error) {
+	films := []domain.FilmListEntry{}
+	for i, filmId := range m.filmIds {
+		films = append(films, domain.FilmListEntry{FilmID: filmId, Position: i + 1})
+	}
+	return films, nil
+}
+
+func (m *mockListStore) GetListFilmIds(ctx context.Context, listId uuid.UUID) ([]uuid.UUID, error) {
+	return m.filmIds, nil
+}
+
+func (m *mockListStore) CreateList(ctx context.Context, list domain.FilmList) (*domain.FilmList, error) {
+	return &list, nil
+}
+
+func (m *mockListStore) UpdateList(ctx context.Context, list domain.FilmList) (*domain.FilmList, error) {
+	return &list, nil
+}
+
+func (m *mockListStore) DeleteList(ctx context.Context, userId uuid.UUID, listId uuid.UUID) error {
+	return nil
+}
+
+func (m *mockListStore) AddFilm(ctx context.Context, listId uuid.UUID, filmId uuid.UUID, position int) error {
+	if m.addFilmFunc != nil {
+		return m.addFilmFunc(ctx, listId, filmId, position)
+	}
+	return nil
+}
+
+func (m *mockListStore) RemoveFilm(ctx context.Context, listId uuid.UUID, filmId uuid.UUID) error {
+	return nil
+}
+
+func (m *mockListStore) ReorderFilms(ctx context.Context, listId uuid.UUID, filmIds []uuid.UUID) error {
+	m.reordered = filmIds
+	return nil
+}
+
+func newListStore(lists ...domain.FilmList) *mockListStore {
+	store := &mockListStore{lists: map[uuid.UUID]*domain.FilmList{}}
+	for _, list := range lists {
+		store.lists[list.ID] = &list
+	}
+	return store
+}
+
+func TestService_CreateList_Defaults(t *testing.T) {
+	service := NewService(newListStore())
+	userId := uuid.New()
+
+	list, err := service.CreateList(context.Background(), userId, ListRequest{Name: "  Comfort films  "})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if list.Name != "Comfort films" || list.UserID != userId {
+		t.Errorf("unexpected list %+v", list)
+	}
+	if list.Visibility != domain.ListVisibilityPrivate || list.Ordering != domain.ListOrderingManual {
+		t.Errorf("expected a private manual list by default, got %s and %s", list.Visibility, list.Ordering)
+	}
+}
+
+func TestService_CreateList_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  ListRequest
+		err  error
+	}{
+		{"empty name", ListRequest{Name: " "}, ErrEmptyListName},
+		{"invalid visibility", ListRequest{Name: "Best of 2024", Visibility: "friends"}, ErrInvalidVisibility},
+		{"invalid ordering", ListRequest{Name: "Best of 2024", Ordering: "alphabetical"}, ErrInvalidOrdering},
+		{"public elo list", ListRequest{Name: "Best of 2024", Visibility: "public", Ordering: "elo"}, nil},
+	}
+
+	service := NewService(newListStore())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.CreateList(context.Background(), uuid.New(), tt.req); err != tt.err {
+				t.Errorf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestService_GetList_Visibility(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+	private := domain.FilmList{ID: uuid.New(), UserID: owner, Visibility: domain.ListVisibilityPrivate}
+	public := domain.FilmList{ID: uuid.New(), UserID: owner, Visibility: domain.ListVisibilityPublic}
+	service := NewService(newListStore(private, public))
+	ctx := context.Background()
+
+	if _, err := service.GetList(ctx, owner, private.ID); err != nil {
+		t.Errorf("expected the owner to see their private list, got %v", err)
+	}
+	if _, err := service.GetList(ctx, other, private.ID); err != ErrListNotFound {
+		t.Errorf("expected another user's private list to be not found, got %v", err)
+	}
+	if _, err := service.GetList(ctx, other, public.ID); err != nil {
+		t.Errorf("expected a public list to be visible, got %v", err)
+	}
+	if _, err := service.GetPublicList(ctx, private.ID); err != ErrListNotFound {
+		t.Errorf("expected a private list to be hidden from the public url, got %v", err)
+	}
+	if detail, err := service.GetPublicList(ctx, public.ID); err != nil || detail.List.ID != public.ID {
+		t.Errorf("expected the public list, got %v, %v", detail, err)
+	}
+}
+
+func TestService_AddFilm_OnlyOwner(t *testing.T) {
+	owner := uuid.New()
+	list := domain.FilmList{ID: uuid.New(), UserID: owner, Visibility: domain.ListVisibilityPublic}
+	added := false
+	store := newListStore(list)
+	store.addFilmFunc = func(ctx context.Context, listId uuid.UUID, filmId uuid.UUID, position int) error {
+		added = true
+		return nil
+	}
+	service := NewService(store)
+
+	if _, err := service.AddFilm(context.Background(), uuid.New(), list.ID, uuid.New(), 0); err != ErrListNotFound {
+		t.Errorf("expected ErrListNotFound for someone else's list, got %v", err)
+	}
+	if added {
+		t.Error("expected nothing to be added to someone else's list")
+	}
+
+	if _, err := service.AddFilm(context.Background(), owner, list.ID, uuid.New(), 0); err != nil || !added {
+		t.Errorf("expected the owner to add a film, got %v", err)
+	}
+}
+
+func TestService_ReorderFilms(t *testing.T) {
+	owner := uuid.New()
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+	manual := domain.FilmList{ID: uuid.New(), UserID: owner, Ordering: domain.ListOrderingManual}
+	elo := domain.FilmList{ID: uuid.New(), UserID: owner, Ordering: domain.ListOrderingElo}
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		listId  uuid.UUID
+		filmIds []uuid.UUID
+		err     error
+	}{
+		{"reordered", manual.ID, []uuid.UUID{third, first, second}, nil},
+		{"missing film", manual.ID, []uuid.UUID{third, first}, ErrReorderMismatch},
+		{"film twice", manual.ID, []uuid.UUID{third, first, first}, ErrReorderMismatch},
+		{"film not in list", manual.ID, []uuid.UUID{third, first, uuid.New()}, ErrReorderMismatch},
+		{"elo ordered", elo.ID, []uuid.UUID{third, first, second}, ErrEloOrderedList},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newListStore(manual, elo)
+			store.filmIds = []uuid.UUID{first, second, third}
+			service := NewService(store)
+
+			_, err := service.ReorderFilms(ctx, owner, tt.listId, tt.filmIds)
+			if err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if err == nil && (len(store.reordered) != 3 || store.reordered[0] != third) {
+				t.Errorf("expected the new order to be stored, got %v", store.reordered)
+			}
+			if err != nil && store.reordered != nil {
+				t.Error("expected nothing to be stored")
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package lists
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) ListStore {
+	return &store{
+		db: db,
+	}
+}
+
+func (s *store) GetListsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.FilmList, error) {
+	query := /* sql */ `
+		SELECT film_list_id, user_id, name, description, visibility, ordering, created_at, updated_at
+		FROM film_lists
+		WHERE user_id = $1
+		ORDER BY updated_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []domain.FilmList{}
+	for rows.Next() {
+		var list domain.FilmList
+		err := rows.Scan(&list.ID, &list.UserID, &list.Name, &list.Description, &list.Visibility, &list.Ordering, &list.CreatedAt, &list.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+func (s *store) GetList(ctx context.Context, listId uuid.UUID) (*domain.FilmList, error) {
+	query := /* sql */ `
+		SELECT film_list_id, user_id, name, description, visibility, ordering, created_at, updated_at
+		FROM film_lists
+		WHERE film_list_id = $1
+	`
+
+	var list domain.FilmList
+	err := s.db.QueryRowContext(ctx, query, listId).Scan(&list.ID, &list.UserID, &list.Name, &list.Description, &list.Visibility, &list.Ordering, &list.CreatedAt, &list.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrListNotFound
+		}
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// GetListFilms returns the films in a list in the list's ordering, with the owner's elo rating when they have one
+func (s *store) GetListFilms(ctx context.Context, list domain.FilmList) ([]domain.FilmListEntry, error) {
+	query := /* sql */ `
+		SELECT e.film_id, e.position, e.added_at, f.title, f.release_year, f.poster_url, r.elo_rating
+		FROM film_list_entries e
+		JOIN films f ON e.film_id = f.film_id
+		LEFT JOIN user_film_ratings r ON r.film_id = e.film_id AND r.user_id = $2
+		WHERE e.film_list_id = $1
+		ORDER BY e.position ASC
+	`
+	if list.Ordering == domain.ListOrderingElo {
+		query = /* sql */ `
+			SELECT e.film_id, e.position, e.added_at, f.title, f.release_year, f.poster_url, r.elo_rating
+			FROM film_list_entries e
+			JOIN films f ON e.film_id = f.film_id
+			LEFT JOIN user_film_ratings r ON r.film_id = e.film_id AND r.user_id = $2
+			WHERE e.film_list_id = $1
+			ORDER BY r.elo_rating DESC NULLS LAST, e.position ASC
+		`
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, list.ID, list.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	films := []domain.FilmListEntry{}
+	for rows.Next() {
+		var film domain.FilmListEntry
+		var eloRating sql.NullFloat64
+		err := rows.Scan(&film.FilmID, &film.Position, &film.AddedAt, &film.FilmTitle, &film.FilmReleaseYear, &film.FilmPosterURL, &eloRating)
+		if err != nil {
+			return nil, err
+		}
+		if eloRating.Valid {
+			film.EloRating = &eloRating.Float64
+		}
+		// Positions follow the order the films come back in, which for elo lists isn't the stored order
+		film.Position = len(films) + 1
+		films = append(films, film)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return films, nil
+}
+
+func (s *store) CreateList(ctx context.Context, list domain.FilmList) (*domain.FilmList, error) {
+	query := /* sql */ `
+		INSERT INTO film_lists (film_list_id, user_id, name, description, visibility, ordering, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, list.ID, list.UserID, list.Name, list.Description, list.Visibility, list.Ordering, list.CreatedAt, list.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// UpdateList updates the name, description, visibility and ordering of one of the user's lists
+func (s *store) UpdateList(ctx context.Context, list domain.FilmList) (*domain.FilmList, error) {
+	query := /* sql */ `
+		UPDATE film_lists
+		SET name = $3,
+		    description = $4,
+		    visibility = $5,
+		    ordering = $6,
+		    updated_at = $7
+		WHERE film_list_id = $1
+		AND user_id = $2
+		RETURNING created_at
+	`
+
+	err := s.db.QueryRowContext(ctx, query, list.ID, list.UserID, list.Name, list.Description, list.Visibility, list.Ordering, list.UpdatedAt).Scan(&list.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrListNotFound
+		}
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+func (s *store) DeleteList(ctx context.Context, userId uuid.UUID, listId uuid.UUID) error {
+	query := /* sql */ `
+		DELETE FROM film_lists
+		WHERE film_list_id = $1
+		AND user_id = $2
+	`
+
+	result, err := s.db.ExecContext(ctx, query, listId, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrListNotFound
+	}
+
+	return nil
+}
+
+// AddFilm puts a film into a list at position, moving the films from there on down one.
+// A position of 0 or past the end of the list adds the film at the end.
+func (s *store) AddFilm(ctx context.Context, listId uuid.UUID, filmId uuid.UUID, position int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var filmExists, inList bool
+	var count int
+	checkQuery := /* sql */ `
+		SELECT
+			EXISTS (SELECT 1 FROM films WHERE film_id = $2),
+			EXISTS (SELECT 1 FROM film_list_entries WHERE film_list_id = $1 AND film_id = $2),
+			(SELECT COUNT(*) FROM film_list_entries WHERE film_list_id = $1)
+	`
+	if err := tx.QueryRowContext(ctx, checkQuery, listId, filmId).Scan(&filmExists, &inList, &count); err != nil {
+		return err
+	}
+	if !filmExists {
+		return ErrFilmNotFound
+	}
+	if inList {
+		return ErrFilmAlreadyInList
+	}
+
+	if position < 1 || position > count {
+		position = count + 1
+	} else {
+		shiftQuery := /* sql */ `
+			UPDATE film_list_entries
+			SET position = position + 1
+			WHERE film_list_id = $1
+			AND position >= $2
+		`
+		if _, err := tx.ExecContext(ctx, shiftQuery, listId, position); err != nil {
+			return err
+		}
+	}
+
+	insertQuery := /* sql */ `
+		INSERT INTO film_list_entries (film_list_id, film_id, position, added_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := tx.ExecContext(ctx, insertQuery, listId, filmId, position, time.Now()); err != nil {
+		return err
+	}
+
+	if err := touchList(ctx, tx, listId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveFilm takes a film out of a list and closes the gap it leaves
+func (s *store) RemoveFilm(ctx context.Context, listId uuid.UUID, filmId uuid.UUID) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleteQuery := /* sql */ `
+		DELETE FROM film_list_entries
+		WHERE film_list_id = $1
+		AND film_id = $2
+		RETURNING position
+	`
+	var position int
+	if err := tx.QueryRowContext(ctx, deleteQuery, listId, filmId).Scan(&position); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrFilmNotInList
+		}
+		return err
+	}
+
+	shiftQuery := /* sql */ `
+		UPDATE film_list_entries
+		SET position = position - 1
+		WHERE film_list_id = $1
+		AND position > $2
+	`
+	if _, err := tx.ExecContext(ctx, shiftQuery, listId, position); err != nil {
+		return err
+	}
+
+	if err := touchList(ctx, tx, listId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *store) GetListFilmIds(ctx context.Context, listId uuid.UUID) ([]uuid.UUID, error) {
+	query := /* sql */ `
+		SELECT film_id
+		FROM film_list_entries
+		WHERE film_list_id = $1
+		ORDER BY position ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, listId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filmIds []uuid.UUID
+	for rows.Next() {
+		var filmId uuid.UUID
+		if err := rows.Scan(&filmId); err != nil {
+			return nil, err
+		}
+		filmIds = append(filmIds, filmId)
+	}
+
+	return filmIds, rows.Err()
+}
+
+// ReorderFilms sets the position of every film in the list to its place in filmIds
+func (s *store) ReorderFilms(ctx context.Context, listId uuid.UUID, filmIds []uuid.UUID) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := /* sql */ `
+		UPDATE film_list_entries
+		SET position = $3
+		WHERE film_list_id = $1
+		AND film_id = $2
+	`
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, filmId := range filmIds {
+		if _, err := stmt.ExecContext(ctx, listId, filmId, i+1); err != nil {
+			return err
+		}
+	}
+
+	if err := touchList(ctx, tx, listId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// touchList bumps a list's updated_at when its films change
+func touchList(ctx context.Context, tx *sql.Tx, listId uuid.UUID) error {
+	query := /* sql */ `
+		UPDATE film_lists
+		SET updated_at = $2
+		WHERE film_list_id = $1
+	`
+	_, err := tx.ExecContext(ctx, query, listId, time.Now())
+	return err
+}
@@ -0,0 +1,215 @@
+package lists
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	testDB      *sql.DB
+	testStore   ListStore
+	testDbSetup *utils.TestDatabase
+)
+
+// Helper function to create test user
+func createTestUser(ctx context.Context, t *testing.T) uuid.UUID {
+	userID := uuid.New()
+	query := `INSERT INTO users (user_id, name, username, profile_pic_url)
+	          VALUES ($1, $2, $3, $4)`
+	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], "http://example.com/pic.jpg")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return userID
+}
+
+// Helper function to create test film
+func createTestFilm(ctx context.Context, t *testing.T) uuid.UUID {
+	filmID := uuid.New()
+	query := `INSERT INTO films (film_id, external_id, title, description, poster_url, release_year)
+	          VALUES ($1, $2, $3, $4, $5, $6)`
+	externalID := int(time.Now().UnixNano() % 2147483647) // Use nanoseconds for uniqueness
+	_, err := testDB.ExecContext(ctx, query, filmID, externalID, "Test Film "+filmID.String()[:8], "Description", "/poster.jpg", "2024")
+	if err != nil {
+		t.Fatalf("failed to create test film: %v", err)
+	}
+	return filmID
+}
+
+// Helper function to create a list for the user
+func createTestList(ctx context.Context, t *testing.T, userId uuid.UUID, ordering string) domain.FilmList {
+	now := time.Now()
+	list, err := testStore.CreateList(ctx, domain.FilmList{
+		ID:         uuid.New(),
+		UserID:     userId,
+		Name:       "Comfort films",
+		Visibility: domain.ListVisibilityPublic,
+		Ordering:   ordering,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	return *list
+}
+
+func filmOrder(t *testing.T, list domain.FilmList) []uuid.UUID {
+	films, err := testStore.GetListFilms(context.Background(), list)
+	if err != nil {
+		t.Fatalf("failed to get list films: %v", err)
+	}
+	var filmIds []uuid.UUID
+	for i, film := range films {
+		if film.Position != i+1 {
+			t.Errorf("expected position %d, got %d", i+1, film.Position)
+		}
+		filmIds = append(filmIds, film.FilmID)
+	}
+	return filmIds
+}
+
+func sameOrder(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	testDbSetup, err = utils.StartTestPostgres()
+	if err != nil {
+		log.Fatalf("could not start test database: %v", err)
+	}
+
+	testDB = testDbSetup.DB
+	testStore = NewStore(testDB)
+
+	code := m.Run()
+
+	testDbSetup.Close()
+	os.Exit(code)
+}
+
+func TestListStore_CreateGetUpdateDelete(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	list := createTestList(ctx, t, userId, domain.ListOrderingManual)
+
+	got, err := testStore.GetList(ctx, list.ID)
+	if err != nil || got.Name != list.Name {
+		t.Fatalf("expected the created list, got %+v, %v", got, err)
+	}
+
+	list.Name = "Best of 2024"
+	list.Visibility = domain.ListVisibilityPrivate
+	if _, err := testStore.UpdateList(ctx, list); err != nil {
+		t.Fatalf("failed to update list: %v", err)
+	}
+	lists, err := testStore.GetListsByUserId(ctx, userId)
+	if err != nil || len(lists) != 1 || lists[0].Name != "Best of 2024" {
+		t.Errorf("expected the updated list, got %+v, %v", lists, err)
+	}
+
+	other := list
+	other.UserID = uuid.New()
+	if _, err := testStore.UpdateList(ctx, other); err != ErrListNotFound {
+		t.Errorf("expected another user's update to fail with ErrListNotFound, got %v", err)
+	}
+
+	if err := testStore.DeleteList(ctx, userId, list.ID); err != nil {
+		t.Fatalf("failed to delete list: %v", err)
+	}
+	if _, err := testStore.GetList(ctx, list.ID); err != ErrListNotFound {
+		t.Errorf("expected ErrListNotFound, got %v", err)
+	}
+}
+
+func TestListStore_AddRemoveAndReorder(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	list := createTestList(ctx, t, userId, domain.ListOrderingManual)
+	a, b, c := createTestFilm(ctx, t), createTestFilm(ctx, t), createTestFilm(ctx, t)
+
+	for _, filmId := range []uuid.UUID{a, b} {
+		if err := testStore.AddFilm(ctx, list.ID, filmId, 0); err != nil {
+			t.Fatalf("failed to add film: %v", err)
+		}
+	}
+	// c goes to the top and pushes the others down
+	if err := testStore.AddFilm(ctx, list.ID, c, 1); err != nil {
+		t.Fatalf("failed to add film: %v", err)
+	}
+	if order := filmOrder(t, list); !sameOrder(order, []uuid.UUID{c, a, b}) {
+		t.Errorf("expected c, a, b, got %v", order)
+	}
+
+	if err := testStore.AddFilm(ctx, list.ID, a, 0); err != ErrFilmAlreadyInList {
+		t.Errorf("expected ErrFilmAlreadyInList, got %v", err)
+	}
+	if err := testStore.AddFilm(ctx, list.ID, uuid.New(), 0); err != ErrFilmNotFound {
+		t.Errorf("expected ErrFilmNotFound, got %v", err)
+	}
+
+	if err := testStore.RemoveFilm(ctx, list.ID, a); err != nil {
+		t.Fatalf("failed to remove film: %v", err)
+	}
+	if order := filmOrder(t, list); !sameOrder(order, []uuid.UUID{c, b}) {
+		t.Errorf("expected c, b, got %v", order)
+	}
+	if err := testStore.RemoveFilm(ctx, list.ID, a); err != ErrFilmNotInList {
+		t.Errorf("expected ErrFilmNotInList, got %v", err)
+	}
+
+	if err := testStore.ReorderFilms(ctx, list.ID, []uuid.UUID{b, c}); err != nil {
+		t.Fatalf("failed to reorder films: %v", err)
+	}
+	if order := filmOrder(t, list); !sameOrder(order, []uuid.UUID{b, c}) {
+		t.Errorf("expected b, c, got %v", order)
+	}
+}
+
+func TestListStore_EloOrdering(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	list := createTestList(ctx, t, userId, domain.ListOrderingElo)
+	unrated, low, high := createTestFilm(ctx, t), createTestFilm(ctx, t), createTestFilm(ctx, t)
+
+	for filmId, elo := range map[uuid.UUID]float64{low: 950, high: 1200} {
+		_, err := testDB.ExecContext(ctx, `INSERT INTO user_film_ratings (user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value)
+			VALUES ($1, $2, $3, $4, 0, NOW(), 3, 40)`, uuid.New(), userId, filmId, elo)
+		if err != nil {
+			t.Fatalf("failed to create rating: %v", err)
+		}
+	}
+	for _, filmId := range []uuid.UUID{unrated, low, high} {
+		if err := testStore.AddFilm(ctx, list.ID, filmId, 0); err != nil {
+			t.Fatalf("failed to add film: %v", err)
+		}
+	}
+
+	films, err := testStore.GetListFilms(ctx, list)
+	if err != nil {
+		t.Fatalf("failed to get list films: %v", err)
+	}
+	if len(films) != 3 || films[0].FilmID != high || films[1].FilmID != low || films[2].FilmID != unrated {
+		t.Fatalf("expected films by elo with unrated last, got %+v", films)
+	}
+	if films[0].EloRating == nil || *films[0].EloRating != 1200 || films[2].EloRating != nil {
+		t.Errorf("expected the owner's elo ratings, got %v and %v", films[0].EloRating, films[2].EloRating)
+	}
+}
@@ -0,0 +1,106 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cinema.log.server.golang/internal/domain"
+)
+
+// Fixture reads film metadata from json files laid out like TMDB's endpoints, for running without a network:
+//
+//	search/movie.json                  every film that can be searched for
+//	movie/{id}.json                    details
+//	movie/{id}/recommendations.json    recommendations
+//	movie/{id}/credits.json            credits
+//
+// A film without a details file doesn't exist, a missing recommendations or credits file means there are none.
+type Fixture struct {
+	dir string
+}
+
+func NewFixture(dir string) *Fixture {
+	return &Fixture{dir: dir}
+}
+
+// Search matches the query against film titles, ignoring case
+func (f *Fixture) Search(ctx context.Context, query string) ([]domain.Film, error) {
+	var all movieResults
+	found, err := f.read(&all, "search", "movie.json")
+	if err != nil || !found {
+		return []domain.Film{}, err
+	}
+
+	var matches movieResults
+	for _, result := range all.Results {
+		if strings.Contains(strings.ToLower(result.Title), strings.ToLower(query)) {
+			matches.Results = append(matches.Results, result)
+		}
+	}
+	return matches.toFilms(0), nil
+}
+
+func (f *Fixture) Details(ctx context.Context, externalId int) (*domain.FilmDetails, error) {
+	var details movieDetails
+	found, err := f.read(&details, "movie", fmt.Sprintf("%d.json", externalId))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrFilmNotFound
+	}
+	return details.toDetails(), nil
+}
+
+func (f *Fixture) Recommendations(ctx context.Context, externalId int) ([]domain.Film, error) {
+	if err := f.exists(externalId); err != nil {
+		return nil, err
+	}
+
+	var recommendations movieResults
+	if _, err := f.read(&recommendations, "movie", fmt.Sprint(externalId), "recommendations.json"); err != nil {
+		return nil, err
+	}
+	return recommendations.toFilms(MaxRecommendations), nil
+}
+
+func (f *Fixture) Credits(ctx context.Context, externalId int) (*domain.FilmCredits, error) {
+	if err := f.exists(externalId); err != nil {
+		return nil, err
+	}
+
+	var credits movieCredits
+	if _, err := f.read(&credits, "movie", fmt.Sprint(externalId), "credits.json"); err != nil {
+		return nil, err
+	}
+	return credits.toCredits(), nil
+}
+
+func (f *Fixture) exists(externalId int) error {
+	_, err := os.Stat(filepath.Join(f.dir, "movie", fmt.Sprintf("%d.json", externalId)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrFilmNotFound
+	}
+	return err
+}
+
+// read decodes a fixture file into out, reporting whether the file exists
+func (f *Fixture) read(out any, path ...string) (bool, error) {
+	data, err := os.ReadFile(filepath.Join(append([]string{f.dir}, path...)...))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrProcessResponse
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return false, ErrParseResponse
+	}
+	return true, nil
+}
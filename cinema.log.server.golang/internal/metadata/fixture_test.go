@@ -0,0 +1,98 @@
+package metadata
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFixture(t *testing.T) {
+	fixture := NewFixture("fixtures")
+	ctx := context.Background()
+
+	films, err := fixture.Search(ctx, "the ")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(films) != 3 {
+		t.Errorf("expected The Matrix, The Dark Knight and The Prestige, got %+v", films)
+	}
+	if films, _ := fixture.Search(ctx, "HEAT"); len(films) != 1 || films[0].ExternalID != 949 {
+		t.Errorf("expected search to ignore case, got %+v", films)
+	}
+
+	details, err := fixture.Details(ctx, 603)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if details.Title != "The Matrix" || details.Runtime != 136 || details.OriginalLanguage != "en" || details.Genres[1].Name != "Science Fiction" {
+		t.Errorf("unexpected details %+v", details)
+	}
+
+	recommendations, err := fixture.Recommendations(ctx, 603)
+	if err != nil || len(recommendations) != 4 || recommendations[0].Title != "Inception" {
+		t.Errorf("unexpected recommendations %+v, %v", recommendations, err)
+	}
+
+	credits, err := fixture.Credits(ctx, 603)
+	if err != nil || len(credits.Crew) != 2 || credits.Cast[0].Character != "Thomas A. Anderson / Neo" {
+		t.Errorf("unexpected credits %+v, %v", credits, err)
+	}
+
+	for name, call := range map[string]func() error{
+		"details":         func() error { _, err := fixture.Details(ctx, 1); return err },
+		"recommendations": func() error { _, err := fixture.Recommendations(ctx, 1); return err },
+		"credits":         func() error { _, err := fixture.Credits(ctx, 1); return err },
+	} {
+		if err := call(); err != ErrFilmNotFound {
+			t.Errorf("%s: expected ErrFilmNotFound for an unknown film, got %v", name, err)
+		}
+	}
+}
+
+func TestFixture_MissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "movie"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "movie", "42.json"), []byte(`{"id": 42, "title": "Answer"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fixture := NewFixture(dir)
+	ctx := context.Background()
+
+	if films, err := fixture.Search(ctx, "answer"); err != nil || len(films) != 0 {
+		t.Errorf("expected no films without a search file, got %+v, %v", films, err)
+	}
+	if recommendations, err := fixture.Recommendations(ctx, 42); err != nil || len(recommendations) != 0 {
+		t.Errorf("expected no recommendations, got %+v, %v", recommendations, err)
+	}
+	if credits, err := fixture.Credits(ctx, 42); err != nil || len(credits.Cast) != 0 {
+		t.Errorf("expected no credits, got %+v, %v", credits, err)
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("METADATA_PROVIDER", "fixture")
+	t.Setenv("METADATA_FIXTURE_DIR", "fixtures")
+	provider, err := NewFromEnv()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := provider.(*Fixture); !ok {
+		t.Errorf("expected the fixture provider, got %T", provider)
+	}
+
+	t.Setenv("METADATA_PROVIDER", "")
+	if provider, _ := NewFromEnv(); provider == nil {
+		t.Error("expected TMDB by default")
+	} else if _, ok := provider.(*TMDB); !ok {
+		t.Errorf("expected TMDB by default, got %T", provider)
+	}
+
+	t.Setenv("METADATA_PROVIDER", "imdb")
+	if _, err := NewFromEnv(); err != ErrUnknownProvider {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrFilmNotFound    = errors.New("film not found")
+	ErrProcessResponse = errors.New("could not process metadata response")
+	ErrParseResponse   = errors.New("could not parse metadata response")
+	ErrUnknownProvider = errors.New("metadata provider must be one of tmdb or fixture")
+)
+
+const (
+	// Recommendations are limited to the top few to avoid weak suggestions
+	MaxRecommendations = 10
+
+	// Where fixtures are read from when METADATA_FIXTURE_DIR isn't set, relative to the module root
+	DefaultFixtureDir = "internal/metadata/fixtures"
+
+	clientTimeout = 10 * time.Second
+)
+
+// Provider is a source of film metadata, films are returned with fresh ids for the store to replace on upsert
+type Provider interface {
+	Search(ctx context.Context, query string) ([]domain.Film, error)
+	Details(ctx context.Context, externalId int) (*domain.FilmDetails, error)
+	Recommendations(ctx context.Context, externalId int) ([]domain.Film, error)
+	Credits(ctx context.Context, externalId int) (*domain.FilmCredits, error)
+}
+
+// NewFromEnv picks the provider from METADATA_PROVIDER, TMDB unless it's "fixture", in which case films
+// are read from METADATA_FIXTURE_DIR so the server can run with no network
+func NewFromEnv() (Provider, error) {
+	switch os.Getenv("METADATA_PROVIDER") {
+	case "", "tmdb":
+		return NewTMDB(&http.Client{Timeout: clientTimeout}, DefaultTMDBBaseUrl, os.Getenv("TMDB_API_KEY")), nil
+	case "fixture":
+		dir := os.Getenv("METADATA_FIXTURE_DIR")
+		if dir == "" {
+			dir = DefaultFixtureDir
+		}
+		return NewFixture(dir), nil
+	default:
+		return nil, ErrUnknownProvider
+	}
+}
+
+// The response shapes below are TMDB's, fixtures are stored in the same shapes
+
+type movieResult struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Overview    string `json:"overview"`
+	ReleaseDate string `json:"release_date"`
+	PosterPath  string `json:"poster_path"`
+}
+
+type movieResults struct {
+	Results []movieResult `json:"results"`
+}
+
+type movieDetails struct {
+	movieResult
+	Runtime          int    `json:"runtime"`
+	OriginalLanguage string `json:"original_language"`
+	Genres           []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"genres"`
+	ProductionCountries []struct {
+		Code string `json:"iso_3166_1"`
+		Name string `json:"name"`
+	} `json:"production_countries"`
+}
+
+type movieCredits struct {
+	Cast []struct {
+		ID          int    `json:"id"`
+		Name        string `json:"name"`
+		Character   string `json:"character"`
+		Order       int    `json:"order"`
+		ProfilePath string `json:"profile_path"`
+	} `json:"cast"`
+	Crew []struct {
+		ID          int    `json:"id"`
+		Name        string `json:"name"`
+		Job         string `json:"job"`
+		Department  string `json:"department"`
+		ProfilePath string `json:"profile_path"`
+	} `json:"crew"`
+}
+
+func (m movieResult) toFilm() domain.Film {
+	return domain.Film{
+		ID:          uuid.New(),
+		ExternalID:  m.ID,
+		Title:       m.Title,
+		Description: m.Overview,
+		PosterUrl:   m.PosterPath,
+		ReleaseYear: m.ReleaseDate,
+	}
+}
+
+func (m movieResults) toFilms(limit int) []domain.Film {
+	results := m.Results
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	films := make([]domain.Film, 0, len(results))
+	for _, result := range results {
+		films = append(films, result.toFilm())
+	}
+	return films
+}
+
+func (m movieDetails) toDetails() *domain.FilmDetails {
+	details := &domain.FilmDetails{
+		Film:                m.toFilm(),
+		Runtime:             m.Runtime,
+		OriginalLanguage:    m.OriginalLanguage,
+		Genres:              make([]domain.Genre, 0, len(m.Genres)),
+		ProductionCountries: make([]domain.ProductionCountry, 0, len(m.ProductionCountries)),
+	}
+	for _, genre := range m.Genres {
+		details.Genres = append(details.Genres, domain.Genre{ID: genre.ID, Name: genre.Name})
+	}
+	for _, country := range m.ProductionCountries {
+		details.ProductionCountries = append(details.ProductionCountries, domain.ProductionCountry{Code: country.Code, Name: country.Name})
+	}
+	return details
+}
+
+func (m movieCredits) toCredits() *domain.FilmCredits {
+	credits := &domain.FilmCredits{
+		Cast: make([]domain.CastMember, 0, len(m.Cast)),
+		Crew: make([]domain.CrewMember, 0, len(m.Crew)),
+	}
+	for _, cast := range m.Cast {
+		credits.Cast = append(credits.Cast, domain.CastMember{PersonID: cast.ID, Name: cast.Name, Character: cast.Character, Order: cast.Order, ProfilePath: cast.ProfilePath})
+	}
+	for _, crew := range m.Crew {
+		credits.Crew = append(credits.Crew, domain.CrewMember{PersonID: crew.ID, Name: crew.Name, Job: crew.Job, Department: crew.Department, ProfilePath: crew.ProfilePath})
+	}
+	return credits
+}
@@ -0,0 +1,101 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"cinema.log.server.golang/internal/domain"
+)
+
+const DefaultTMDBBaseUrl = "https://api.themoviedb.org/3/"
+
+// TMDB reads film metadata from The Movie Database's API
+type TMDB struct {
+	client  *http.Client
+	baseUrl string
+	apiKey  string
+}
+
+func NewTMDB(client *http.Client, baseUrl string, apiKey string) *TMDB {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &TMDB{
+		client:  client,
+		baseUrl: baseUrl,
+		apiKey:  apiKey,
+	}
+}
+
+func (t *TMDB) Search(ctx context.Context, query string) ([]domain.Film, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("include_adult", "false")
+	params.Set("language", "en-US")
+	params.Set("page", "1")
+
+	var response movieResults
+	if err := t.get(ctx, "search/movie", params, &response); err != nil {
+		return nil, err
+	}
+	return response.toFilms(0), nil
+}
+
+func (t *TMDB) Details(ctx context.Context, externalId int) (*domain.FilmDetails, error) {
+	var response movieDetails
+	if err := t.get(ctx, fmt.Sprintf("movie/%d", externalId), url.Values{}, &response); err != nil {
+		return nil, err
+	}
+	return response.toDetails(), nil
+}
+
+func (t *TMDB) Recommendations(ctx context.Context, externalId int) ([]domain.Film, error) {
+	var response movieResults
+	if err := t.get(ctx, fmt.Sprintf("movie/%d/recommendations", externalId), url.Values{}, &response); err != nil {
+		return nil, err
+	}
+	return response.toFilms(MaxRecommendations), nil
+}
+
+func (t *TMDB) Credits(ctx context.Context, externalId int) (*domain.FilmCredits, error) {
+	var response movieCredits
+	if err := t.get(ctx, fmt.Sprintf("movie/%d/credits", externalId), url.Values{}, &response); err != nil {
+		return nil, err
+	}
+	return response.toCredits(), nil
+}
+
+// get calls a TMDB endpoint and decodes its json into out
+func (t *TMDB) get(ctx context.Context, path string, params url.Values, out any) error {
+	params.Set("api_key", t.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.baseUrl+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrFilmNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("tmdb api returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ErrProcessResponse
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return ErrParseResponse
+	}
+	return nil
+}
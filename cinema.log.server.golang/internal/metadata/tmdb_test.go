@@ -0,0 +1,109 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureServer serves the shipped fixtures the way TMDB's API would
+func fixtureServer(t *testing.T, apiKey string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("api_key") != apiKey {
+			http.Error(w, "invalid api key", http.StatusUnauthorized)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("fixtures", strings.TrimPrefix(r.URL.Path, "/")+".json"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTMDB(t *testing.T) {
+	server := fixtureServer(t, "secret")
+	tmdb := NewTMDB(server.Client(), server.URL+"/", "secret")
+	ctx := context.Background()
+
+	films, err := tmdb.Search(ctx, "matrix")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// The server doesn't filter, so every searchable film comes back
+	if len(films) != 8 || films[0].ExternalID != 603 || films[0].ReleaseYear != "1999-03-31" {
+		t.Errorf("unexpected search results %+v", films)
+	}
+
+	details, err := tmdb.Details(ctx, 27205)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if details.Title != "Inception" || details.Runtime != 148 || len(details.Genres) != 3 || details.ProductionCountries[1].Code != "GB" {
+		t.Errorf("unexpected details %+v", details)
+	}
+
+	recommendations, err := tmdb.Recommendations(ctx, 27205)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(recommendations) != 5 || recommendations[0].Title != "Interstellar" {
+		t.Errorf("unexpected recommendations %+v", recommendations)
+	}
+
+	credits, err := tmdb.Credits(ctx, 949)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if credits.Crew[0].Name != "Michael Mann" || credits.Crew[0].Job != "Director" || credits.Cast[0].Name != "Al Pacino" {
+		t.Errorf("unexpected credits %+v", credits)
+	}
+
+	if _, err := tmdb.Details(ctx, 1); err != ErrFilmNotFound {
+		t.Errorf("expected ErrFilmNotFound, got %v", err)
+	}
+}
+
+func TestTMDB_Errors(t *testing.T) {
+	server := fixtureServer(t, "secret")
+	ctx := context.Background()
+
+	if _, err := NewTMDB(server.Client(), server.URL+"/", "wrong").Search(ctx, "matrix"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected the status to be reported, got %v", err)
+	}
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer broken.Close()
+	if _, err := NewTMDB(broken.Client(), broken.URL+"/", "secret").Search(ctx, "matrix"); err != ErrParseResponse {
+		t.Errorf("expected ErrParseResponse, got %v", err)
+	}
+}
+
+func TestTMDB_LimitsRecommendations(t *testing.T) {
+	results := make([]string, 0, 15)
+	for i := 1; i <= 15; i++ {
+		results = append(results, `{"id": `+strings.Repeat("1", i)+`, "title": "Film"}`)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results": [` + strings.Join(results, ",") + `]}`))
+	}))
+	defer server.Close()
+
+	recommendations, err := NewTMDB(server.Client(), server.URL+"/", "secret").Recommendations(context.Background(), 603)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(recommendations) != MaxRecommendations {
+		t.Errorf("expected %d recommendations, got %d", MaxRecommendations, len(recommendations))
+	}
+}
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Extra state for rating engines other than elo
+ALTER TABLE user_film_ratings ADD COLUMN rating_deviation DOUBLE PRECISION NOT NULL DEFAULT 0.0;
+ALTER TABLE user_film_ratings ADD COLUMN volatility DOUBLE PRECISION NOT NULL DEFAULT 0.0;
+
+-- Each user picks the engine their ratings are calculated with
+ALTER TABLE users ADD COLUMN rating_engine VARCHAR(32) NOT NULL DEFAULT 'elo';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS rating_engine;
+ALTER TABLE user_film_ratings DROP COLUMN IF EXISTS volatility;
+ALTER TABLE user_film_ratings DROP COLUMN IF EXISTS rating_deviation;
+-- +goose StatementEnd
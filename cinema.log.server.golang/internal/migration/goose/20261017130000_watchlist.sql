@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE watchlist (
+    watchlist_entry_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    film_id UUID NOT NULL,
+    priority INT NOT NULL DEFAULT 3,
+    note TEXT NOT NULL DEFAULT '',
+    source VARCHAR(32) NOT NULL DEFAULT 'search',
+    added_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+ALTER TABLE watchlist
+ADD CONSTRAINT pk_watchlist PRIMARY KEY (watchlist_entry_id);
+
+ALTER TABLE watchlist
+ADD CONSTRAINT fk_watchlist_users_user_id
+FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+ALTER TABLE watchlist
+ADD CONSTRAINT fk_watchlist_films_film_id
+FOREIGN KEY (film_id) REFERENCES films (film_id) ON DELETE CASCADE;
+
+-- A film can only be on a user's watchlist once
+ALTER TABLE watchlist
+ADD CONSTRAINT uq_watchlist_user_id_film_id UNIQUE (user_id, film_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS watchlist CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE diary_entries (
+    diary_entry_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    film_id UUID NOT NULL,
+    watched_date DATE NOT NULL,
+    rewatch BOOLEAN NOT NULL DEFAULT FALSE,
+    format VARCHAR(32) NOT NULL,
+    review_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+ALTER TABLE diary_entries
+ADD CONSTRAINT pk_diary_entries PRIMARY KEY (diary_entry_id);
+
+ALTER TABLE diary_entries
+ADD CONSTRAINT fk_diary_entries_users_user_id
+FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+ALTER TABLE diary_entries
+ADD CONSTRAINT fk_diary_entries_films_film_id
+FOREIGN KEY (film_id) REFERENCES films (film_id) ON DELETE CASCADE;
+
+-- Deleting a review keeps the viewing, it just loses the link
+ALTER TABLE diary_entries
+ADD CONSTRAINT fk_diary_entries_reviews_review_id
+FOREIGN KEY (review_id) REFERENCES reviews (review_id) ON DELETE SET NULL;
+
+CREATE INDEX ix_diary_entries_user_id_watched_date ON diary_entries (user_id, watched_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS diary_entries CASCADE;
+-- +goose StatementEnd
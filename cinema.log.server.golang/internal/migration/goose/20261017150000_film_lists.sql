@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE film_lists (
+    film_list_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    visibility VARCHAR(32) NOT NULL DEFAULT 'private',
+    ordering VARCHAR(32) NOT NULL DEFAULT 'manual',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+ALTER TABLE film_lists
+ADD CONSTRAINT pk_film_lists PRIMARY KEY (film_list_id);
+
+ALTER TABLE film_lists
+ADD CONSTRAINT fk_film_lists_users_user_id
+FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+CREATE INDEX ix_film_lists_user_id ON film_lists (user_id);
+
+CREATE TABLE film_list_entries (
+    film_list_id UUID NOT NULL,
+    film_id UUID NOT NULL,
+    position INT NOT NULL,
+    added_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+ALTER TABLE film_list_entries
+ADD CONSTRAINT pk_film_list_entries PRIMARY KEY (film_list_id, film_id);
+
+ALTER TABLE film_list_entries
+ADD CONSTRAINT fk_film_list_entries_film_lists_film_list_id
+FOREIGN KEY (film_list_id) REFERENCES film_lists (film_list_id) ON DELETE CASCADE;
+
+ALTER TABLE film_list_entries
+ADD CONSTRAINT fk_film_list_entries_films_film_id
+FOREIGN KEY (film_id) REFERENCES films (film_id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS film_list_entries CASCADE;
+DROP TABLE IF EXISTS film_lists CASCADE;
+-- +goose StatementEnd
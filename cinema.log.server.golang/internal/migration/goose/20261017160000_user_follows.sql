@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE user_follows (
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+ALTER TABLE user_follows
+ADD CONSTRAINT pk_user_follows PRIMARY KEY (follower_id, followee_id);
+
+ALTER TABLE user_follows
+ADD CONSTRAINT fk_user_follows_users_follower_id
+FOREIGN KEY (follower_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+ALTER TABLE user_follows
+ADD CONSTRAINT fk_user_follows_users_followee_id
+FOREIGN KEY (followee_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+-- Users can't follow themselves
+ALTER TABLE user_follows
+ADD CONSTRAINT ck_user_follows_not_self CHECK (follower_id <> followee_id);
+
+-- The primary key covers who a user follows, this covers who follows a user
+CREATE INDEX ix_user_follows_followee_id ON user_follows (followee_id);
+
+-- The feed pages through followed users' reviews and comparisons newest first
+CREATE INDEX ix_reviews_user_id_date ON reviews (user_id, date DESC);
+CREATE INDEX ix_comparison_histories_user_id_comparison_date ON comparison_histories (user_id, comparison_date DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS ix_comparison_histories_user_id_comparison_date;
+DROP INDEX IF EXISTS ix_reviews_user_id_date;
+DROP TABLE IF EXISTS user_follows CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Why a film was recommended to a user, one row per source that picked it
+CREATE TABLE recommendation_reasons (
+    recommendation_reason_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    external_film_id INT NOT NULL,
+    source VARCHAR(32) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    seeds_agreed INT NOT NULL DEFAULT 0,
+    recommended_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+ALTER TABLE recommendation_reasons
+ADD CONSTRAINT pk_recommendation_reasons PRIMARY KEY (recommendation_reason_id);
+
+ALTER TABLE recommendation_reasons
+ADD CONSTRAINT fk_recommendation_reasons_users_user_id
+FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+ALTER TABLE recommendation_reasons
+ADD CONSTRAINT fk_recommendation_reasons_films_external_film_id
+FOREIGN KEY (external_film_id) REFERENCES films (external_id) ON DELETE CASCADE;
+
+-- Recommending a film again replaces the reason from the same source
+ALTER TABLE recommendation_reasons
+ADD CONSTRAINT uq_recommendation_reasons_user_id_external_film_id_source UNIQUE (user_id, external_film_id, source);
+
+-- The films the user has seen that led to a recommendation
+CREATE TABLE recommendation_reason_seeds (
+    recommendation_reason_id UUID NOT NULL,
+    seed_film_id UUID NOT NULL,
+    elo_rating DOUBLE PRECISION,
+    tmdb_rank INT
+);
+ALTER TABLE recommendation_reason_seeds
+ADD CONSTRAINT pk_recommendation_reason_seeds PRIMARY KEY (recommendation_reason_id, seed_film_id);
+
+ALTER TABLE recommendation_reason_seeds
+ADD CONSTRAINT fk_recommendation_reason_seeds_recommendation_reasons_recommendation_reason_id
+FOREIGN KEY (recommendation_reason_id) REFERENCES recommendation_reasons (recommendation_reason_id) ON DELETE CASCADE;
+
+ALTER TABLE recommendation_reason_seeds
+ADD CONSTRAINT fk_recommendation_reason_seeds_films_seed_film_id
+FOREIGN KEY (seed_film_id) REFERENCES films (film_id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS recommendation_reason_seeds CASCADE;
+DROP TABLE IF EXISTS recommendation_reasons CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- When a film was last recommended and what the user did with it, so recommendations can be kept as an inbox
+ALTER TABLE film_recommendation ADD COLUMN recommended_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE film_recommendation ADD COLUMN dismissed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE film_recommendation ADD COLUMN snoozed_until TIMESTAMP WITH TIME ZONE;
+
+-- Recommendations made before this are dated by their stored reasons where there are any
+UPDATE film_recommendation fr
+SET recommended_at = COALESCE(
+    (SELECT MAX(rr.recommended_at) FROM recommendation_reasons rr WHERE rr.user_id = fr.user_id AND rr.external_film_id = fr.external_film_id),
+    NOW()
+)
+WHERE fr.has_been_recommended = TRUE;
+
+CREATE INDEX ix_film_recommendation_user_id_recommended_at ON film_recommendation (user_id, recommended_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS ix_film_recommendation_user_id_recommended_at;
+ALTER TABLE film_recommendation DROP COLUMN IF EXISTS snoozed_until;
+ALTER TABLE film_recommendation DROP COLUMN IF EXISTS dismissed_at;
+ALTER TABLE film_recommendation DROP COLUMN IF EXISTS recommended_at;
+-- +goose StatementEnd
@@ -0,0 +1,99 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Details from the metadata provider, metadata_updated_at is NULL until they've been fetched
+ALTER TABLE films ADD COLUMN runtime INT;
+ALTER TABLE films ADD COLUMN original_language VARCHAR(16);
+ALTER TABLE films ADD COLUMN metadata_updated_at TIMESTAMP WITH TIME ZONE;
+
+-- Genres, people and countries are keyed by the provider's ids so they're shared between films
+CREATE TABLE genres (
+    genre_id INT NOT NULL,
+    name VARCHAR(64) NOT NULL
+);
+ALTER TABLE genres
+ADD CONSTRAINT pk_genres PRIMARY KEY (genre_id);
+
+CREATE TABLE film_genres (
+    film_id UUID NOT NULL,
+    genre_id INT NOT NULL
+);
+ALTER TABLE film_genres
+ADD CONSTRAINT pk_film_genres PRIMARY KEY (film_id, genre_id);
+
+ALTER TABLE film_genres
+ADD CONSTRAINT fk_film_genres_films_film_id
+FOREIGN KEY (film_id) REFERENCES films (film_id) ON DELETE CASCADE;
+
+ALTER TABLE film_genres
+ADD CONSTRAINT fk_film_genres_genres_genre_id
+FOREIGN KEY (genre_id) REFERENCES genres (genre_id) ON DELETE CASCADE;
+
+CREATE INDEX ix_film_genres_genre_id ON film_genres (genre_id);
+
+CREATE TABLE countries (
+    country_code CHAR(2) NOT NULL,
+    name VARCHAR(128) NOT NULL
+);
+ALTER TABLE countries
+ADD CONSTRAINT pk_countries PRIMARY KEY (country_code);
+
+CREATE TABLE film_countries (
+    film_id UUID NOT NULL,
+    country_code CHAR(2) NOT NULL
+);
+ALTER TABLE film_countries
+ADD CONSTRAINT pk_film_countries PRIMARY KEY (film_id, country_code);
+
+ALTER TABLE film_countries
+ADD CONSTRAINT fk_film_countries_films_film_id
+FOREIGN KEY (film_id) REFERENCES films (film_id) ON DELETE CASCADE;
+
+ALTER TABLE film_countries
+ADD CONSTRAINT fk_film_countries_countries_country_code
+FOREIGN KEY (country_code) REFERENCES countries (country_code) ON DELETE CASCADE;
+
+CREATE TABLE people (
+    person_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    profile_path VARCHAR(255) NOT NULL DEFAULT ''
+);
+ALTER TABLE people
+ADD CONSTRAINT pk_people PRIMARY KEY (person_id);
+
+-- Directors and top billed cast, someone who directed and starred in a film has a row for each
+CREATE TABLE film_people (
+    film_id UUID NOT NULL,
+    person_id INT NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    character_name VARCHAR(255) NOT NULL DEFAULT '',
+    billing_order INT NOT NULL DEFAULT 0
+);
+ALTER TABLE film_people
+ADD CONSTRAINT pk_film_people PRIMARY KEY (film_id, person_id, role);
+
+ALTER TABLE film_people
+ADD CONSTRAINT fk_film_people_films_film_id
+FOREIGN KEY (film_id) REFERENCES films (film_id) ON DELETE CASCADE;
+
+ALTER TABLE film_people
+ADD CONSTRAINT fk_film_people_people_person_id
+FOREIGN KEY (person_id) REFERENCES people (person_id) ON DELETE CASCADE;
+
+ALTER TABLE film_people
+ADD CONSTRAINT ck_film_people_role CHECK (role IN ('director', 'cast'));
+
+CREATE INDEX ix_film_people_person_id ON film_people (person_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS film_people CASCADE;
+DROP TABLE IF EXISTS people CASCADE;
+DROP TABLE IF EXISTS film_countries CASCADE;
+DROP TABLE IF EXISTS countries CASCADE;
+DROP TABLE IF EXISTS film_genres CASCADE;
+DROP TABLE IF EXISTS genres CASCADE;
+ALTER TABLE films DROP COLUMN IF EXISTS metadata_updated_at;
+ALTER TABLE films DROP COLUMN IF EXISTS original_language;
+ALTER TABLE films DROP COLUMN IF EXISTS runtime;
+-- +goose StatementEnd
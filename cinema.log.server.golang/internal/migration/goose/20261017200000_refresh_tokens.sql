@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Only a hash of each refresh token is kept. Every refresh rotates the token within its family, the
+-- chain of tokens descended from one login, so a rotated token coming back means it was stolen.
+CREATE TABLE refresh_tokens (
+    refresh_token_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    family_id UUID NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    rotated_at TIMESTAMP WITH TIME ZONE NULL,
+    replaced_by_id UUID NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE NULL
+);
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT pk_refresh_tokens PRIMARY KEY (refresh_token_id);
+
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT fk_refresh_tokens_users_user_id
+FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT uq_refresh_tokens_token_hash UNIQUE (token_hash);
+
+CREATE INDEX ix_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX ix_refresh_tokens_user_id ON refresh_tokens (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS refresh_tokens CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Each way a user can log in, so one account can have both a GitHub and a Google login
+CREATE TABLE user_identities (
+    user_identity_id UUID NOT NULL DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    provider_username VARCHAR(255),
+    linked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE user_identities
+ADD CONSTRAINT pk_user_identities PRIMARY KEY (user_identity_id);
+
+ALTER TABLE user_identities
+ADD CONSTRAINT fk_user_identities_users_user_id
+FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+-- A login belongs to one user, and a user has at most one login per provider
+ALTER TABLE user_identities
+ADD CONSTRAINT uq_user_identities_provider_subject UNIQUE (provider, subject);
+
+ALTER TABLE user_identities
+ADD CONSTRAINT uq_user_identities_user_id_provider UNIQUE (user_id, provider);
+
+INSERT INTO user_identities (user_id, provider, subject, provider_username, linked_at)
+SELECT user_id, 'github', CAST(github_id AS VARCHAR), username, created_at
+FROM users
+WHERE github_id IS NOT NULL;
+
+INSERT INTO user_identities (user_id, provider, subject, provider_username, linked_at)
+SELECT user_id, 'google', google_id, username, created_at
+FROM users
+WHERE google_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_users_github_id;
+DROP INDEX IF EXISTS idx_users_google_id;
+ALTER TABLE users DROP COLUMN github_id;
+ALTER TABLE users DROP COLUMN google_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN github_id BIGINT UNIQUE;
+ALTER TABLE users ADD COLUMN google_id VARCHAR(255);
+ALTER TABLE users ADD CONSTRAINT users_google_id_unique UNIQUE (google_id);
+CREATE INDEX idx_users_github_id ON users(github_id);
+CREATE INDEX idx_users_google_id ON users(google_id);
+
+UPDATE users u
+SET github_id = CAST(ui.subject AS BIGINT)
+FROM user_identities ui
+WHERE ui.user_id = u.user_id AND ui.provider = 'github';
+
+UPDATE users u
+SET google_id = ui.subject
+FROM user_identities ui
+WHERE ui.user_id = u.user_id AND ui.provider = 'google';
+
+DROP TABLE IF EXISTS user_identities CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- When a rewatch starts a fresh round of comparisons for a film, comparisons made before it no longer
+-- stop the film being compared with the same films again
+ALTER TABLE user_film_ratings ADD COLUMN recompare_from TIMESTAMP(6);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_film_ratings DROP COLUMN IF EXISTS recompare_from;
+-- +goose StatementEnd
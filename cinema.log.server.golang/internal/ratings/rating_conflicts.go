@@ -0,0 +1,194 @@
+package ratings
+
+import (
+	"slices"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+/*
+Conflicts
+---
+Every decided comparison is an edge from the winner to the loser. A strongly connected
+component with more than one film means the user has said things like A > B, B > C and C > A,
+so no ordering of those films can agree with every answer. Somewhere in every such cycle the
+elo ordering has to go against at least one answer, and the bigger the gap the worse the
+ratings are distorted. The answer the ratings disagree with most is the weakest link and the
+one worth asking the user to re-judge, through PUT /ratings/comparisons/{id}.
+*/
+
+// RatingConflict is a group of films whose comparisons contradict each other
+type RatingConflict struct {
+	Films       []domain.UserFilmRatingDetail `json:"films"`       // ranked by rating
+	Cycle       []uuid.UUID                   `json:"cycle"`       // a shortest cycle through the weakest link, each film beat the next and the last beat the first
+	Comparisons []domain.ComparisonHistory    `json:"comparisons"` // every decided comparison between films in the group
+	WeakestLink domain.ComparisonHistory      `json:"weakestLink"`
+	Distortion  float64                       `json:"distortion"` // total rating gap across the answers the ratings disagree with
+}
+
+type ConflictReport struct {
+	UserId          uuid.UUID        `json:"userId"`
+	FilmsInConflict int              `json:"filmsInConflict"`
+	Conflicts       []RatingConflict `json:"conflicts"` // most distorting first
+}
+
+// findConflicts finds the strongly connected components of the user's win graph, ratings must be ranked by elo
+func findConflicts(ratings []domain.UserFilmRatingDetail, history []domain.ComparisonHistory) []RatingConflict {
+	index := make(map[uuid.UUID]int, len(ratings))
+	for i, detail := range ratings {
+		index[detail.Rating.FilmId] = i
+	}
+
+	type edge struct {
+		loser      int
+		comparison domain.ComparisonHistory
+	}
+	edges := make([][]edge, len(ratings))
+	for _, comparison := range history {
+		a, okA := index[comparison.FilmAId]
+		b, okB := index[comparison.FilmBId]
+		if !okA || !okB || a == b || comparison.WasEqual {
+			continue
+		}
+		if comparison.WinningFilmId == comparison.FilmBId {
+			a, b = b, a
+		}
+		edges[a] = append(edges[a], edge{loser: b, comparison: comparison})
+	}
+
+	successors := make([][]int, len(edges))
+	for film, out := range edges {
+		for _, e := range out {
+			successors[film] = append(successors[film], e.loser)
+		}
+	}
+
+	var conflicts []RatingConflict
+	for _, component := range stronglyConnectedComponents(successors) {
+		if len(component) < 2 {
+			continue
+		}
+		slices.Sort(component) // ratings are ranked, so this ranks the films too
+		inComponent := make(map[int]bool, len(component))
+		for _, film := range component {
+			inComponent[film] = true
+		}
+
+		conflict := RatingConflict{}
+		weakestGap, weakestWinner, weakestLoser := -1.0, -1, -1
+		for _, winner := range component {
+			conflict.Films = append(conflict.Films, ratings[winner])
+			for _, e := range edges[winner] {
+				if !inComponent[e.loser] {
+					continue
+				}
+				conflict.Comparisons = append(conflict.Comparisons, e.comparison)
+
+				gap := ratings[e.loser].Rating.EloRating - ratings[winner].Rating.EloRating
+				if gap > 0 {
+					conflict.Distortion += gap
+				}
+				if gap > weakestGap {
+					weakestGap, weakestWinner, weakestLoser = gap, winner, e.loser
+					conflict.WeakestLink = e.comparison
+				}
+			}
+		}
+
+		// Close the cycle by finding the shortest way back from the weakest link's loser to its winner
+		path := shortestPath(successors, weakestLoser, weakestWinner, inComponent)
+		conflict.Cycle = []uuid.UUID{ratings[weakestWinner].Rating.FilmId, ratings[weakestLoser].Rating.FilmId}
+		for _, film := range path[:len(path)-1] {
+			conflict.Cycle = append(conflict.Cycle, ratings[film].Rating.FilmId)
+		}
+
+		conflicts = append(conflicts, conflict)
+	}
+
+	slices.SortStableFunc(conflicts, func(a, b RatingConflict) int {
+		return compareFloatsDesc(a.Distortion, b.Distortion)
+	})
+	return conflicts
+}
+
+// stronglyConnectedComponents is tarjan's algorithm over films numbered 0..n-1
+func stronglyConnectedComponents(successors [][]int) [][]int {
+	n := len(successors)
+	order := make([]int, n)
+	lowLink := make([]int, n)
+	onStack := make([]bool, n)
+	for i := range order {
+		order[i] = -1
+	}
+
+	var stack []int
+	var components [][]int
+	next := 0
+
+	var visit func(film int)
+	visit = func(film int) {
+		order[film], lowLink[film] = next, next
+		next++
+		stack = append(stack, film)
+		onStack[film] = true
+
+		for _, successor := range successors[film] {
+			if order[successor] == -1 {
+				visit(successor)
+				lowLink[film] = min(lowLink[film], lowLink[successor])
+			} else if onStack[successor] {
+				lowLink[film] = min(lowLink[film], order[successor])
+			}
+		}
+
+		if lowLink[film] == order[film] {
+			var component []int
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == film {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for film := range successors {
+		if order[film] == -1 {
+			visit(film)
+		}
+	}
+	return components
+}
+
+// shortestPath is a breadth first search from one film to another, staying inside allowed.
+// It returns the films after from, ending with to.
+func shortestPath(successors [][]int, from, to int, allowed map[int]bool) []int {
+	previous := map[int]int{from: -1}
+	queue := []int{from}
+	for len(queue) > 0 {
+		film := queue[0]
+		queue = queue[1:]
+		if film == to {
+			break
+		}
+		for _, successor := range successors[film] {
+			if _, seen := previous[successor]; seen || !allowed[successor] {
+				continue
+			}
+			previous[successor] = film
+			queue = append(queue, successor)
+		}
+	}
+
+	var path []int
+	for film := to; film != from; film = previous[film] {
+		path = append(path, film)
+	}
+	slices.Reverse(path)
+	return path
+}
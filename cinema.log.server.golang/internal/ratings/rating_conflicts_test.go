@@ -0,0 +1,100 @@
+package ratings
+
+import (
+	"context"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+func beats(winner, loser uuid.UUID) domain.ComparisonHistory {
+	return domain.ComparisonHistory{ID: uuid.New(), FilmAId: winner, FilmBId: loser, WinningFilmId: winner}
+}
+
+func TestFindConflicts_NoCycles(t *testing.T) {
+	ratings := newPairDetails(1100, 1000, 900)
+	a, b, c := ratings[0].Rating.FilmId, ratings[1].Rating.FilmId, ratings[2].Rating.FilmId
+
+	conflicts := findConflicts(ratings, []domain.ComparisonHistory{beats(a, b), beats(b, c), beats(a, c)})
+	if len(conflicts) != 0 {
+		t.Errorf("expected no conflicts, got %+v", conflicts)
+	}
+}
+
+func TestFindConflicts_Cycle(t *testing.T) {
+	ratings := newPairDetails(1100, 1000, 900, 800)
+	a, b, c, d := ratings[0].Rating.FilmId, ratings[1].Rating.FilmId, ratings[2].Rating.FilmId, ratings[3].Rating.FilmId
+	weakest := beats(c, a) // 900 beating 1100 is the answer the ratings disagree with most
+	history := []domain.ComparisonHistory{beats(a, b), beats(b, c), weakest, beats(c, d)}
+
+	conflicts := findConflicts(ratings, history)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %d", len(conflicts))
+	}
+	conflict := conflicts[0]
+	if len(conflict.Films) != 3 || conflict.Films[0].Rating.FilmId != a {
+		t.Errorf("expected a, b and c ranked by rating, got %+v", conflict.Films)
+	}
+	if len(conflict.Comparisons) != 3 {
+		t.Errorf("expected the comparison with d to be left out, got %d", len(conflict.Comparisons))
+	}
+	if conflict.WeakestLink.ID != weakest.ID || conflict.Distortion != 200 {
+		t.Errorf("expected c beating a to be the weakest link with a distortion of 200, got %+v", conflict)
+	}
+	expected := []uuid.UUID{c, a, b}
+	if len(conflict.Cycle) != len(expected) {
+		t.Fatalf("expected cycle %v, got %v", expected, conflict.Cycle)
+	}
+	for i := range expected {
+		if conflict.Cycle[i] != expected[i] {
+			t.Errorf("expected cycle %v, got %v", expected, conflict.Cycle)
+			break
+		}
+	}
+}
+
+func TestFindConflicts_RankedByDistortion(t *testing.T) {
+	ratings := newPairDetails(1500, 1400, 1020, 1000, 700)
+	a, b, c, d, e := ratings[0].Rating.FilmId, ratings[1].Rating.FilmId, ratings[2].Rating.FilmId, ratings[3].Rating.FilmId, ratings[4].Rating.FilmId
+	history := []domain.ComparisonHistory{
+		// A small flip flop between two close films
+		beats(c, d), beats(d, c),
+		// A big one spanning most of the ranking
+		beats(a, b), beats(b, e), beats(e, a),
+		// Draws never make a conflict
+		{ID: uuid.New(), FilmAId: a, FilmBId: c, WinningFilmId: a, WasEqual: true},
+	}
+
+	conflicts := findConflicts(ratings, history)
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %d", len(conflicts))
+	}
+	if conflicts[0].Distortion != 800 || conflicts[1].Distortion != 20 {
+		t.Errorf("expected the bigger conflict first, got %.0f then %.0f", conflicts[0].Distortion, conflicts[1].Distortion)
+	}
+	if len(conflicts[1].Cycle) != 2 || conflicts[1].Cycle[0] != d {
+		t.Errorf("expected d beating c to be the link to re-judge, got %v", conflicts[1].Cycle)
+	}
+}
+
+func TestService_GetConflicts(t *testing.T) {
+	ratings := newPairDetails(1000, 1000)
+	a, b := ratings[0].Rating.FilmId, ratings[1].Rating.FilmId
+	service := NewService(&mockRatingStore{
+		getRatingsByUserIdFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
+			return ratings, nil
+		},
+		getComparisonHistoryFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error) {
+			return []domain.ComparisonHistory{beats(a, b), beats(b, a)}, nil
+		},
+	})
+
+	report, err := service.GetConflicts(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(report.Conflicts) != 1 || report.FilmsInConflict != 2 {
+		t.Errorf("expected one conflict over 2 films, got %+v", report)
+	}
+}
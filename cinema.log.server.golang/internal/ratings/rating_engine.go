@@ -0,0 +1,201 @@
+package ratings
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+const DefaultRatingEngine = "elo"
+
+// RatingEngine turns a user's comparisons into ratings for their films
+type RatingEngine interface {
+	Name() string
+	// Seed gives a newly rated film its starting state from the star rating the user gave it
+	Seed(rating domain.UserFilmRating) domain.UserFilmRating
+	// Rate folds comparisons, oldest first, into the ratings. Every film the comparisons refer to must be in ratings.
+	// Batch engines refit from scratch, so they must be given every film the user has rated and their full history.
+	Rate(ratings map[uuid.UUID]*domain.UserFilmRating, comparisons []domain.ComparisonHistory)
+	// Batch is true for engines that need the full history to rate a single comparison
+	Batch() bool
+}
+
+var ratingEngines = map[string]RatingEngine{
+	"elo":           eloEngine{},
+	"glicko2":       glicko2Engine{},
+	"bradley-terry": bradleyTerryEngine{},
+}
+
+// GetRatingEngine looks an engine up by name, an empty name gives the default engine
+func GetRatingEngine(name string) (RatingEngine, error) {
+	if name == "" {
+		name = DefaultRatingEngine
+	}
+	engine, ok := ratingEngines[name]
+	if !ok {
+		return nil, ErrUnknownRatingEngine
+	}
+	return engine, nil
+}
+
+// RatingEngineNames lists every engine a user can choose from
+func RatingEngineNames() []string {
+	names := make([]string, 0, len(ratingEngines))
+	for name := range ratingEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defineFilmContestResult scores a comparison from each film's point of view: 1 for a win, 0.5 for a draw and 0 for a loss
+func defineFilmContestResult(filmA uuid.UUID, filmB uuid.UUID, comparison domain.ComparisonHistory) (float64, float64) {
+	var filmAResult, filmBResult float64
+	if comparison.WasEqual {
+		filmAResult = 0.5
+		filmBResult = 0.5
+		return filmAResult, filmBResult
+	}
+
+	if filmA == comparison.WinningFilmId {
+		filmAResult = 1
+		filmBResult = 0
+	} else if filmB == comparison.WinningFilmId {
+		filmAResult = 0
+		filmBResult = 1
+	}
+	return filmAResult, filmBResult
+}
+
+// rateInOrder applies comparisons one at a time for engines that update incrementally
+func rateInOrder(ratings map[uuid.UUID]*domain.UserFilmRating, comparisons []domain.ComparisonHistory,
+	compare func(filmA, filmB domain.UserFilmRating, filmAResult, filmBResult float64, comparedAt time.Time) (domain.UserFilmRating, domain.UserFilmRating)) {
+	for _, comparison := range comparisons {
+		filmA, okA := ratings[comparison.FilmAId]
+		filmB, okB := ratings[comparison.FilmBId]
+		if !okA || !okB {
+			continue
+		}
+		filmAResult, filmBResult := defineFilmContestResult(filmA.FilmId, filmB.FilmId, comparison)
+		*filmA, *filmB = compare(*filmA, *filmB, filmAResult, filmBResult, comparison.ComparisonDate)
+	}
+}
+
+// this is generated from the users initial 5 star rating 'InitialRating'
+func getInitialEloRating(rating float32) float32 {
+	switch {
+	case rating >= 0 && rating < 2:
+		return 950
+	case rating >= 2 && rating < 3:
+		return 1000
+	case rating >= 3 && rating < 4:
+		return 1050
+	case rating >= 4 && rating <= 5:
+		return 1100
+	default:
+		return 1000
+	}
+}
+
+// eloEngine is the original cinema.log rating maths, with a K constant that shrinks as a film is compared more
+type eloEngine struct{}
+
+func (e eloEngine) Name() string {
+	return "elo"
+}
+
+func (e eloEngine) Batch() bool {
+	return false
+}
+
+func (e eloEngine) Seed(rating domain.UserFilmRating) domain.UserFilmRating {
+	rating.EloRating = float64(getInitialEloRating(rating.InitialRating))
+	rating.NumberOfComparisons = 0
+	rating.KConstantValue = 40 // Start with highest K value for new ratings
+	rating.RatingDeviation = 0
+	rating.Volatility = 0
+	return rating
+}
+
+func (e eloEngine) Rate(ratings map[uuid.UUID]*domain.UserFilmRating, comparisons []domain.ComparisonHistory) {
+	rateInOrder(ratings, comparisons, e.compare)
+}
+
+// compare runs a single head to head through the elo maths and returns both films' new ratings
+func (e eloEngine) compare(filmA, filmB domain.UserFilmRating, filmAResult, filmBResult float64, comparedAt time.Time) (domain.UserFilmRating, domain.UserFilmRating) {
+	// Calculate expected results for film A and film B
+	filmAExpectedResult := e.calculateExpectedResult(filmA.EloRating, filmB.EloRating)
+	filmBExpectedResult := e.calculateExpectedResult(filmB.EloRating, filmA.EloRating)
+
+	// Update K Constants
+	filmA.KConstantValue = e.updateKConstantValue(filmA)
+	filmB.KConstantValue = e.updateKConstantValue(filmB)
+
+	// Recalculate film rating for film A and film B
+	filmA.EloRating = e.recalculateFilmRating(filmAExpectedResult, filmAResult, filmA.EloRating, filmA.KConstantValue)
+	filmB.EloRating = e.recalculateFilmRating(filmBExpectedResult, filmBResult, filmB.EloRating, filmB.KConstantValue)
+
+	filmA.LastUpdated = comparedAt
+	filmA.NumberOfComparisons += 1
+	filmB.LastUpdated = comparedAt
+	filmB.NumberOfComparisons += 1
+
+	return filmA, filmB
+}
+
+/*
+   Calculate expected result
+   ---
+   Ea = 1 / (1 + 10^(Rb - Ra)/400)
+   Where:
+   Ea is expected score of film a
+   Ra is current rating of film a
+   Rb is current rating of film b
+*/
+
+func (e eloEngine) calculateExpectedResult(filmUnderReviewEloRating float64, challengerFilmEloRating float64) float64 {
+	rawCalc := (1 / (1 + math.Pow(10, (challengerFilmEloRating-filmUnderReviewEloRating)/400)))
+
+	ratio := math.Pow(10, float64(2)) // round to 2 dp
+	return math.Round(rawCalc*ratio) / ratio
+}
+
+/*
+Recalculate elo rating
+---
+R'a = Ra + K(Sa - Ea)
+Where:
+R'a is new rating for film a
+Ra is current rating for film a
+K is K-factor (to be adjusted based on review date) (With the most recent having the highest K)
+Sa is actual result of match up (0 for loss, 0.5 for draw, 1 for win)
+Ea is expected result (Ea = 1 / (1 + 10^(Rb - Ra)/400))
+*/
+
+func (e eloEngine) recalculateFilmRating(expectedResult float64, actualResult float64,
+	currentRating float64, filmKConstantValue float64) float64 {
+
+	rawCalc := currentRating + filmKConstantValue*(actualResult-expectedResult)
+	if rawCalc <= 100 {
+		return 100 // 100 is the lowest rating value you can get, so no further decreases past this point
+	}
+
+	return math.Round(rawCalc)
+}
+
+func (e eloEngine) updateKConstantValue(film domain.UserFilmRating) float64 {
+	numberOfComparisons := film.NumberOfComparisons
+	switch {
+	case numberOfComparisons >= 0 && numberOfComparisons < 20:
+		return 40
+	case numberOfComparisons >= 20 && numberOfComparisons < 40:
+		return 20
+	case numberOfComparisons >= 40:
+		return 10
+	default:
+		return 40
+	}
+}
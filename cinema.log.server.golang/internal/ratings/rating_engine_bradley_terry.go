@@ -0,0 +1,124 @@
+package ratings
+
+import (
+	"math"
+	"slices"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+/*
+Bradley-Terry
+---
+P(a beats b) = pa / (pa + pb)
+Every film gets a strength p fitted to the user's whole comparison history at once,
+so the order comparisons were made in doesn't matter. Draws count as half a win each.
+Each film also plays one virtual draw against an anchor at the strength its InitialRating
+gives, this keeps films that have never lost (or never won) finite and keeps the scale
+in line with elo. Strengths are shown on the elo scale: rating = 1000 + 400 * log10(p).
+*/
+
+const (
+	bradleyTerryMaxIterations = 200
+	bradleyTerryTolerance     = 0.000001
+)
+
+type bradleyTerryEngine struct{}
+
+func (e bradleyTerryEngine) Name() string {
+	return "bradley-terry"
+}
+
+func (e bradleyTerryEngine) Batch() bool {
+	return true
+}
+
+func (e bradleyTerryEngine) Seed(rating domain.UserFilmRating) domain.UserFilmRating {
+	ratings := map[uuid.UUID]*domain.UserFilmRating{rating.FilmId: &rating}
+	e.Rate(ratings, nil)
+	return rating
+}
+
+// Rate refits every film in ratings from scratch using the comparisons given
+func (e bradleyTerryEngine) Rate(ratings map[uuid.UUID]*domain.UserFilmRating, comparisons []domain.ComparisonHistory) {
+	// Sort the films so the fit is the same every time
+	filmIds := make([]uuid.UUID, 0, len(ratings))
+	for filmId := range ratings {
+		filmIds = append(filmIds, filmId)
+	}
+	slices.SortFunc(filmIds, func(a, b uuid.UUID) int {
+		return slices.Compare(a[:], b[:])
+	})
+	index := make(map[uuid.UUID]int, len(filmIds))
+	for i, filmId := range filmIds {
+		index[filmId] = i
+	}
+
+	n := len(filmIds)
+	anchors := make([]float64, n)
+	strengths := make([]float64, n)
+	wins := make([]float64, n)
+	counts := make([]int, n)
+	games := make([]map[int]float64, n)
+	for i, filmId := range filmIds {
+		anchors[i] = math.Pow(10, (float64(getInitialEloRating(ratings[filmId].InitialRating))-1000)/400)
+		strengths[i] = anchors[i]
+		wins[i] = 0.5 // the virtual draw against the anchor
+		games[i] = map[int]float64{}
+	}
+
+	for _, comparison := range comparisons {
+		a, okA := index[comparison.FilmAId]
+		b, okB := index[comparison.FilmBId]
+		if !okA || !okB || a == b {
+			continue
+		}
+		filmAResult, filmBResult := defineFilmContestResult(comparison.FilmAId, comparison.FilmBId, comparison)
+		wins[a] += filmAResult
+		wins[b] += filmBResult
+		games[a][b]++
+		games[b][a]++
+		counts[a]++
+		counts[b]++
+
+		for _, filmId := range []uuid.UUID{comparison.FilmAId, comparison.FilmBId} {
+			if comparison.ComparisonDate.After(ratings[filmId].LastUpdated) {
+				ratings[filmId].LastUpdated = comparison.ComparisonDate
+			}
+		}
+	}
+
+	// Minorisation-maximisation, each step can only improve the fit
+	for iteration := 0; iteration < bradleyTerryMaxIterations; iteration++ {
+		next := make([]float64, n)
+		largestChange := 0.0
+		for i := range strengths {
+			denominator := 1 / (strengths[i] + anchors[i])
+			for j, played := range games[i] {
+				denominator += played / (strengths[i] + strengths[j])
+			}
+			next[i] = wins[i] / denominator
+			largestChange = math.Max(largestChange, math.Abs(math.Log(next[i]/strengths[i])))
+		}
+		strengths = next
+		if largestChange < bradleyTerryTolerance {
+			break
+		}
+	}
+
+	for i, filmId := range filmIds {
+		// Fisher information of log(p), the deviation is its standard error on the elo scale
+		information := strengths[i] * anchors[i] / math.Pow(strengths[i]+anchors[i], 2)
+		for j, played := range games[i] {
+			information += played * strengths[i] * strengths[j] / math.Pow(strengths[i]+strengths[j], 2)
+		}
+
+		rating := ratings[filmId]
+		rating.EloRating = math.Round((1000+400*math.Log10(strengths[i]))*100) / 100
+		rating.RatingDeviation = math.Round(400/math.Ln10/math.Sqrt(information)*100) / 100
+		rating.Volatility = 0
+		rating.KConstantValue = 0
+		rating.NumberOfComparisons = counts[i]
+	}
+}
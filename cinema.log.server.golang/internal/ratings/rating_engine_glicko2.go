@@ -0,0 +1,135 @@
+package ratings
+
+import (
+	"math"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+/*
+Glicko-2
+---
+Each film carries a rating (r), a rating deviation (RD) and a volatility (σ).
+RD is how unsure we are of the rating, it shrinks as a film is compared and
+films with a high RD are the ones whose position in the ranking is still uncertain.
+Every comparison is treated as its own rating period with a single game, see
+http://www.glicko.net/glicko/glicko2.pdf for the maths.
+*/
+
+const (
+	glicko2Scale             = 173.7178 // converts between the glicko and glicko-2 scales
+	glicko2Center            = 1500
+	glicko2Tau               = 0.5 // constrains how quickly volatility can change
+	glicko2Epsilon           = 0.000001
+	glicko2InitialDeviation  = 350
+	glicko2InitialVolatility = 0.06
+)
+
+type glicko2Engine struct{}
+
+func (e glicko2Engine) Name() string {
+	return "glicko2"
+}
+
+func (e glicko2Engine) Batch() bool {
+	return false
+}
+
+func (e glicko2Engine) Seed(rating domain.UserFilmRating) domain.UserFilmRating {
+	rating.EloRating = float64(getInitialEloRating(rating.InitialRating))
+	rating.NumberOfComparisons = 0
+	rating.KConstantValue = 0
+	rating.RatingDeviation = glicko2InitialDeviation
+	rating.Volatility = glicko2InitialVolatility
+	return rating
+}
+
+func (e glicko2Engine) Rate(ratings map[uuid.UUID]*domain.UserFilmRating, comparisons []domain.ComparisonHistory) {
+	rateInOrder(ratings, comparisons, e.compare)
+}
+
+// compare updates both films from their ratings before the comparison
+func (e glicko2Engine) compare(filmA, filmB domain.UserFilmRating, filmAResult, filmBResult float64, comparedAt time.Time) (domain.UserFilmRating, domain.UserFilmRating) {
+	newA := e.update(filmA, filmB, filmAResult)
+	newB := e.update(filmB, filmA, filmBResult)
+
+	newA.LastUpdated = comparedAt
+	newA.NumberOfComparisons += 1
+	newB.LastUpdated = comparedAt
+	newB.NumberOfComparisons += 1
+
+	return newA, newB
+}
+
+func (e glicko2Engine) update(film, opponent domain.UserFilmRating, score float64) domain.UserFilmRating {
+	// Ratings from before glicko-2 was chosen have no deviation yet
+	if film.RatingDeviation <= 0 {
+		film.RatingDeviation = glicko2InitialDeviation
+	}
+	if film.Volatility <= 0 {
+		film.Volatility = glicko2InitialVolatility
+	}
+	opponentDeviation := opponent.RatingDeviation
+	if opponentDeviation <= 0 {
+		opponentDeviation = glicko2InitialDeviation
+	}
+
+	mu := (film.EloRating - glicko2Center) / glicko2Scale
+	phi := film.RatingDeviation / glicko2Scale
+	opponentMu := (opponent.EloRating - glicko2Center) / glicko2Scale
+	opponentPhi := opponentDeviation / glicko2Scale
+
+	g := 1 / math.Sqrt(1+3*opponentPhi*opponentPhi/(math.Pi*math.Pi))
+	expected := 1 / (1 + math.Exp(-g*(mu-opponentMu)))
+	variance := 1 / (g * g * expected * (1 - expected))
+	delta := variance * g * (score - expected)
+
+	volatility := e.updateVolatility(phi, variance, delta, film.Volatility)
+
+	phiStar := math.Sqrt(phi*phi + volatility*volatility)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/variance)
+	newMu := mu + newPhi*newPhi*g*(score-expected)
+
+	film.EloRating = math.Round((newMu*glicko2Scale+glicko2Center)*100) / 100
+	film.RatingDeviation = math.Round(newPhi*glicko2Scale*100) / 100
+	film.Volatility = volatility
+	return film
+}
+
+// updateVolatility finds the new volatility with the Illinois algorithm (step 5 of the paper)
+func (e glicko2Engine) updateVolatility(phi, variance, delta, volatility float64) float64 {
+	a := math.Log(volatility * volatility)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		denominator := phi*phi + variance + ex
+		return ex*(delta*delta-phi*phi-variance-ex)/(2*denominator*denominator) - (x-a)/(glicko2Tau*glicko2Tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+variance {
+		B = math.Log(delta*delta - phi*phi - variance)
+	} else {
+		k := 1.0
+		for f(a-k*glicko2Tau) < 0 && k < 100 {
+			k++
+		}
+		B = a - k*glicko2Tau
+	}
+
+	fA, fB := f(A), f(B)
+	for i := 0; math.Abs(B-A) > glicko2Epsilon && i < 100; i++ {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA = fA / 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,208 @@
+package ratings
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+func newEngineRatings(engine RatingEngine, initialRatings ...float32) (map[uuid.UUID]*domain.UserFilmRating, []uuid.UUID) {
+	ratings := map[uuid.UUID]*domain.UserFilmRating{}
+	var filmIds []uuid.UUID
+	for _, initialRating := range initialRatings {
+		rating := engine.Seed(domain.UserFilmRating{ID: uuid.New(), FilmId: uuid.New(), InitialRating: initialRating})
+		ratings[rating.FilmId] = &rating
+		filmIds = append(filmIds, rating.FilmId)
+	}
+	return ratings, filmIds
+}
+
+func win(winner, loser uuid.UUID, at time.Time) domain.ComparisonHistory {
+	return domain.ComparisonHistory{ID: uuid.New(), FilmAId: winner, FilmBId: loser, WinningFilmId: winner, ComparisonDate: at}
+}
+
+func TestGetRatingEngine(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		err      error
+	}{
+		{"", "elo", nil},
+		{"elo", "elo", nil},
+		{"glicko2", "glicko2", nil},
+		{"bradley-terry", "bradley-terry", nil},
+		{"chess", "", ErrUnknownRatingEngine},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := GetRatingEngine(tt.name)
+			if err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if err == nil && engine.Name() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, engine.Name())
+			}
+		})
+	}
+}
+
+func TestGlicko2Engine_Rate(t *testing.T) {
+	engine := glicko2Engine{}
+	ratings, films := newEngineRatings(engine, 3, 3)
+	winner, loser := ratings[films[0]], ratings[films[1]]
+	start := winner.EloRating
+	now := time.Now()
+
+	if winner.RatingDeviation != glicko2InitialDeviation || winner.Volatility != glicko2InitialVolatility {
+		t.Fatalf("expected seeded deviation and volatility, got %+v", winner)
+	}
+
+	engine.Rate(ratings, []domain.ComparisonHistory{win(films[0], films[1], now)})
+
+	if winner.EloRating <= start || loser.EloRating >= start {
+		t.Errorf("expected winner above %.0f and loser below, got %.2f and %.2f", start, winner.EloRating, loser.EloRating)
+	}
+	// Equal films should move by the same amount in opposite directions
+	if diff := (winner.EloRating - start) - (start - loser.EloRating); diff > 0.01 || diff < -0.01 {
+		t.Errorf("expected a symmetric update, got %.2f and %.2f", winner.EloRating, loser.EloRating)
+	}
+	if winner.RatingDeviation >= glicko2InitialDeviation || loser.RatingDeviation >= glicko2InitialDeviation {
+		t.Errorf("expected deviation to shrink, got %.2f and %.2f", winner.RatingDeviation, loser.RatingDeviation)
+	}
+	if winner.NumberOfComparisons != 1 || !winner.LastUpdated.Equal(now) {
+		t.Errorf("expected comparison to be counted, got %+v", winner)
+	}
+
+	// A second comparison should leave the films more certain again
+	deviation := winner.RatingDeviation
+	engine.Rate(ratings, []domain.ComparisonHistory{win(films[0], films[1], now)})
+	if winner.RatingDeviation >= deviation {
+		t.Errorf("expected deviation to keep shrinking, got %.2f after %.2f", winner.RatingDeviation, deviation)
+	}
+}
+
+func TestGlicko2Engine_ConvertsEloRatings(t *testing.T) {
+	engine := glicko2Engine{}
+	filmA := domain.UserFilmRating{FilmId: uuid.New(), EloRating: 1000, KConstantValue: 40}
+	filmB := domain.UserFilmRating{FilmId: uuid.New(), EloRating: 1000, KConstantValue: 40}
+
+	engine.Rate(map[uuid.UUID]*domain.UserFilmRating{filmA.FilmId: &filmA, filmB.FilmId: &filmB},
+		[]domain.ComparisonHistory{win(filmA.FilmId, filmB.FilmId, time.Now())})
+
+	if filmA.RatingDeviation <= 0 || filmA.RatingDeviation >= glicko2InitialDeviation || filmA.Volatility <= 0 {
+		t.Errorf("expected a rating with no deviation to start from the glicko-2 defaults, got %+v", filmA)
+	}
+}
+
+func TestBradleyTerryEngine_Rate(t *testing.T) {
+	engine := bradleyTerryEngine{}
+	ratings, films := newEngineRatings(engine, 3, 3, 3)
+	seeded := *ratings[films[0]]
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if seeded.EloRating != 1050 || seeded.RatingDeviation <= 0 {
+		t.Fatalf("expected the seed to sit on the initial elo with a deviation, got %+v", seeded)
+	}
+
+	// 0 beats 1 twice, 1 beats 2 twice
+	history := []domain.ComparisonHistory{
+		win(films[0], films[1], start),
+		win(films[1], films[2], start.Add(time.Hour)),
+		win(films[0], films[1], start.Add(2*time.Hour)),
+		win(films[1], films[2], start.Add(3*time.Hour)),
+	}
+	engine.Rate(ratings, history)
+
+	first, second, third := ratings[films[0]], ratings[films[1]], ratings[films[2]]
+	if !(first.EloRating > second.EloRating && second.EloRating > third.EloRating) {
+		t.Errorf("expected ratings in order, got %.2f, %.2f, %.2f", first.EloRating, second.EloRating, third.EloRating)
+	}
+	if second.RatingDeviation >= seeded.RatingDeviation || second.RatingDeviation >= first.RatingDeviation {
+		t.Errorf("expected the most compared film to be the most certain, got %.2f (first %.2f)", second.RatingDeviation, first.RatingDeviation)
+	}
+	if second.NumberOfComparisons != 4 || first.NumberOfComparisons != 2 {
+		t.Errorf("expected comparison counts from the history, got %d and %d", second.NumberOfComparisons, first.NumberOfComparisons)
+	}
+	if !first.LastUpdated.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("expected last updated to be the film's latest comparison, got %v", first.LastUpdated)
+	}
+
+	// The fit uses the whole history at once, so order and reruns don't matter
+	reversed := map[uuid.UUID]*domain.UserFilmRating{}
+	for filmId, rating := range ratings {
+		copied := engine.Seed(*rating)
+		reversed[filmId] = &copied
+	}
+	engine.Rate(reversed, []domain.ComparisonHistory{history[3], history[2], history[1], history[0]})
+	for filmId, rating := range ratings {
+		if reversed[filmId].EloRating != rating.EloRating {
+			t.Errorf("expected the same fit in any order, got %.2f and %.2f", reversed[filmId].EloRating, rating.EloRating)
+		}
+	}
+}
+
+func TestBradleyTerryEngine_UnbeatenFilmStaysFinite(t *testing.T) {
+	engine := bradleyTerryEngine{}
+	ratings, films := newEngineRatings(engine, 5, 0.5)
+	var history []domain.ComparisonHistory
+	for i := 0; i < 10; i++ {
+		history = append(history, win(films[0], films[1], time.Now()))
+	}
+
+	engine.Rate(ratings, history)
+
+	if ratings[films[0]].EloRating > 3000 || ratings[films[1]].EloRating < -1000 {
+		t.Errorf("expected the prior to keep ratings finite, got %.2f and %.2f", ratings[films[0]].EloRating, ratings[films[1]].EloRating)
+	}
+}
+
+func TestService_UpdateRatings_UsesChosenEngine(t *testing.T) {
+	ctx := context.Background()
+	userId := uuid.New()
+	filmA := glicko2Engine{}.Seed(domain.UserFilmRating{UserId: userId, FilmId: uuid.New(), InitialRating: 3})
+	filmB := glicko2Engine{}.Seed(domain.UserFilmRating{UserId: userId, FilmId: uuid.New(), InitialRating: 3})
+	store := storeWithRatings(filmA, filmB)
+	store.getRatingEngineFunc = func(ctx context.Context, id uuid.UUID) (string, error) {
+		return "glicko2", nil
+	}
+	service := NewService(store)
+
+	updated, err := service.UpdateRatings(ctx, domain.ComparisonPair{FilmA: filmA, FilmB: filmB}, win(filmA.FilmId, filmB.FilmId, time.Now()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.FilmA.RatingDeviation >= glicko2InitialDeviation || updated.FilmA.EloRating <= filmA.EloRating {
+		t.Errorf("expected a glicko-2 update, got %+v", updated.FilmA)
+	}
+}
+
+func TestService_CreateRating_SeedsChosenEngine(t *testing.T) {
+	service := NewService(&mockRatingStore{
+		getRatingEngineFunc: func(ctx context.Context, id uuid.UUID) (string, error) {
+			return "glicko2", nil
+		},
+		createRatingFunc: func(ctx context.Context, rating domain.UserFilmRating) (*domain.UserFilmRating, error) {
+			return &rating, nil
+		},
+	})
+
+	rating, err := service.CreateRating(context.Background(), uuid.New(), uuid.New(), 4)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if rating.EloRating != 1100 || rating.RatingDeviation != glicko2InitialDeviation {
+		t.Errorf("expected a glicko-2 seed, got %+v", rating)
+	}
+}
+
+func TestService_SetRatingEngine_Unknown(t *testing.T) {
+	service := NewService(&mockRatingStore{})
+
+	if _, err := service.SetRatingEngine(context.Background(), uuid.New(), "chess"); err != ErrUnknownRatingEngine {
+		t.Errorf("expected ErrUnknownRatingEngine, got %v", err)
+	}
+}
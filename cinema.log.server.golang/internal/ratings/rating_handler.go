@@ -21,10 +21,18 @@ type RatingService interface {
 	GetRatingsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error)
 	GetAllRatings(ctx context.Context) ([]domain.UserFilmRating, error)
 	UpdateRatings(ctx context.Context, ratings domain.ComparisonPair, comparison domain.ComparisonHistory) (*domain.ComparisonPair, error)
-	CreateComparison(ctx context.Context, comparison domain.ComparisonHistory) (*domain.ComparisonHistory, error)
 	HasBeenCompared(ctx context.Context, userId, filmAId, filmBId uuid.UUID) (bool, error)
 	GetComparisonHistory(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error)
 	ProcessBatchComparisons(ctx context.Context, userId, targetFilmId uuid.UUID, comparisons []ComparisonItem) error
+	ReplayRatings(ctx context.Context, userId uuid.UUID, dryRun bool) (*ReplayReport, error)
+	GetRatingEngine(ctx context.Context, userId uuid.UUID) (string, error)
+	SetRatingEngine(ctx context.Context, userId uuid.UUID, name string) (*ReplayReport, error)
+	GetNextComparison(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*NextComparison, error)
+	DeleteComparison(ctx context.Context, userId uuid.UUID, comparisonId uuid.UUID) (*ComparisonChange, error)
+	UpdateComparisonResult(ctx context.Context, userId uuid.UUID, comparisonId uuid.UUID, winningFilmId uuid.UUID, wasEqual bool) (*ComparisonChange, error)
+	GetConflicts(ctx context.Context, userId uuid.UUID) (*ConflictReport, error)
+	GetFilmRatingHistory(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*FilmRatingHistory, error)
+	GetRankChanges(ctx context.Context, userId uuid.UUID, since time.Time) ([]FilmRankChange, error)
 }
 
 func NewHandler(ratingService RatingService) *Handler {
@@ -109,6 +117,20 @@ type CompareBatchRequest struct {
 	Comparisons  []ComparisonItem `json:"comparisons"`
 }
 
+type UpdateComparisonRequest struct {
+	WinningFilmId uuid.UUID `json:"winningFilmId"`
+	WasEqual      bool      `json:"wasEqual"`
+}
+
+type RatingEngineRequest struct {
+	Engine string `json:"engine"`
+}
+
+type RatingEngineResponse struct {
+	Engine    string   `json:"engine"`
+	Available []string `json:"available"`
+}
+
 func (h *Handler) CompareFilms(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user from context
 	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
@@ -171,18 +193,13 @@ func (h *Handler) CompareFilms(w http.ResponseWriter, r *http.Request) {
 		WasEqual:       req.WasEqual,
 	}
 
+	// Rates the pair and saves the comparison together
 	updatedPair, err := h.RatingService.UpdateRatings(r.Context(), pair, comparison)
 	if err != nil {
 		http.Error(w, "Failed to update ratings", http.StatusInternalServerError)
 		return
 	}
 
-	_, err = h.RatingService.CreateComparison(r.Context(), comparison)
-	if err != nil {
-		http.Error(w, "Failed to create comparison history", http.StatusInternalServerError)
-		return
-	}
-
 	utils.SendJSON(w, updatedPair)
 }
 
@@ -234,3 +251,257 @@ func (h *Handler) CompareBatch(w http.ResponseWriter, r *http.Request) {
 		"message": "Batch comparisons processed successfully",
 	})
 }
+
+// ReplayRatings recomputes the authenticated user's ratings from their comparison history.
+// With ?dryRun=true the report is returned without saving anything.
+func (h *Handler) ReplayRatings(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+
+	report, err := h.RatingService.ReplayRatings(r.Context(), user.ID, dryRun)
+	if err != nil {
+		http.Error(w, "Failed to replay ratings", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, report)
+}
+
+// GetRatingEngine returns the engine the authenticated user's ratings are calculated with and the engines they can switch to
+func (h *Handler) GetRatingEngine(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	engine, err := h.RatingService.GetRatingEngine(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get rating engine", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, RatingEngineResponse{Engine: engine, Available: RatingEngineNames()})
+}
+
+// SetRatingEngine switches the authenticated user to another rating engine.
+// All of their ratings are recomputed with it and the replay report is returned.
+func (h *Handler) SetRatingEngine(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RatingEngineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	report, err := h.RatingService.SetRatingEngine(r.Context(), user.ID, req.Engine)
+	if err == ErrUnknownRatingEngine {
+		http.Error(w, "Unknown rating engine", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to set rating engine", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, report)
+}
+
+// GetNextComparison returns the pair of films the authenticated user should compare next.
+// With ?filmId= the pair always includes that film.
+func (h *Handler) GetNextComparison(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var filmID uuid.UUID
+	if filmIDStr := r.URL.Query().Get("filmId"); filmIDStr != "" {
+		var err error
+		filmID, err = uuid.Parse(filmIDStr)
+		if err != nil {
+			http.Error(w, "invalid filmId", http.StatusBadRequest)
+			return
+		}
+	}
+
+	next, err := h.RatingService.GetNextComparison(r.Context(), user.ID, filmID)
+	if err == ErrRatingNotFound {
+		http.Error(w, "Rating not found", http.StatusNotFound)
+		return
+	}
+	if err == ErrNoComparisonAvailable {
+		http.Error(w, "No comparisons left to make", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to pick next comparison", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, next)
+}
+
+// DeleteComparison undoes one of the authenticated user's comparisons and returns how their ratings moved
+func (h *Handler) DeleteComparison(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	comparisonID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid comparison id", http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.RatingService.DeleteComparison(r.Context(), user.ID, comparisonID)
+	if err == ErrComparisonNotFound {
+		http.Error(w, "Comparison not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete comparison", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, change)
+}
+
+// UpdateComparison flips or equalises the result of one of the authenticated user's comparisons
+// and returns how their ratings moved
+func (h *Handler) UpdateComparison(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	comparisonID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid comparison id", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateComparisonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	change, err := h.RatingService.UpdateComparisonResult(r.Context(), user.ID, comparisonID, req.WinningFilmId, req.WasEqual)
+	if err == ErrComparisonNotFound {
+		http.Error(w, "Comparison not found", http.StatusNotFound)
+		return
+	}
+	if err == ErrInvalidWinningFilm {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update comparison", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, change)
+}
+
+// GetConflicts returns the groups of films the authenticated user's comparisons contradict each other on,
+// most distorting first, each with the comparison worth re-judging
+func (h *Handler) GetConflicts(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := h.RatingService.GetConflicts(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to find conflicts", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, report)
+}
+
+// GetFilmRatingHistory returns how a film's rating and rank moved with each of the authenticated user's comparisons
+func (h *Handler) GetFilmRatingHistory(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filmID, err := uuid.Parse(r.PathValue("filmId"))
+	if err != nil {
+		http.Error(w, "invalid filmId", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.RatingService.GetFilmRatingHistory(r.Context(), user.ID, filmID)
+	if err == ErrRatingNotFound {
+		http.Error(w, "Rating not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get rating history", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, history)
+}
+
+// GetRankChanges returns the films that moved in the authenticated user's ranking since ?since=, biggest movers first.
+// since is a date (2006-01-02) or an RFC 3339 time.
+func (h *Handler) GetRankChanges(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sinceStr := r.URL.Query().Get("since")
+	if sinceStr == "" {
+		http.Error(w, "since is required", http.StatusBadRequest)
+		return
+	}
+	since, err := time.Parse(time.DateOnly, sinceStr)
+	if err != nil {
+		since, err = time.Parse(time.RFC3339, sinceStr)
+	}
+	if err != nil {
+		http.Error(w, "invalid since", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.RatingService.GetRankChanges(r.Context(), user.ID, since)
+	if err != nil {
+		http.Error(w, "Failed to get rank changes", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, changes)
+}
@@ -2,18 +2,26 @@ package ratings
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"cinema.log.server.golang/internal/domain"
 	"cinema.log.server.golang/internal/middleware"
 	"github.com/google/uuid"
 )
 
-type mockRatingService struct{}
+type mockRatingService struct {
+	replayRatingsFunc     func(ctx context.Context, userId uuid.UUID, dryRun bool) (*ReplayReport, error)
+	setRatingEngineFunc   func(ctx context.Context, userId uuid.UUID, name string) (*ReplayReport, error)
+	getNextComparisonFunc func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*NextComparison, error)
+	changeComparisonFunc  func(ctx context.Context, userId uuid.UUID, comparisonId uuid.UUID, winningFilmId uuid.UUID, wasEqual bool, deleted bool) (*ComparisonChange, error)
+}
 
 func (m *mockRatingService) GetRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.UserFilmRating, error) {
 	return &domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: filmId}, nil
@@ -37,10 +45,6 @@ func (m *mockRatingService) UpdateRatings(ctx context.Context, ratings domain.Co
 	return &ratings, nil
 }
 
-func (m *mockRatingService) CreateComparison(ctx context.Context, comparison domain.ComparisonHistory) (*domain.ComparisonHistory, error) {
-	return &comparison, nil
-}
-
 func (m *mockRatingService) HasBeenCompared(ctx context.Context, userId, filmAId, filmBId uuid.UUID) (bool, error) {
 	return false, nil
 }
@@ -53,6 +57,63 @@ func (m *mockRatingService) ProcessBatchComparisons(ctx context.Context, userId,
 	return nil
 }
 
+func (m *mockRatingService) ReplayRatings(ctx context.Context, userId uuid.UUID, dryRun bool) (*ReplayReport, error) {
+	if m.replayRatingsFunc != nil {
+		return m.replayRatingsFunc(ctx, userId, dryRun)
+	}
+	return &ReplayReport{UserId: userId, DryRun: dryRun}, nil
+}
+
+func (m *mockRatingService) GetRatingEngine(ctx context.Context, userId uuid.UUID) (string, error) {
+	return DefaultRatingEngine, nil
+}
+
+func (m *mockRatingService) SetRatingEngine(ctx context.Context, userId uuid.UUID, name string) (*ReplayReport, error) {
+	if m.setRatingEngineFunc != nil {
+		return m.setRatingEngineFunc(ctx, userId, name)
+	}
+	return &ReplayReport{UserId: userId, Engine: name}, nil
+}
+
+func (m *mockRatingService) GetNextComparison(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*NextComparison, error) {
+	if m.getNextComparisonFunc != nil {
+		return m.getNextComparisonFunc(ctx, userId, filmId)
+	}
+	return &NextComparison{}, nil
+}
+
+func (m *mockRatingService) GetConflicts(ctx context.Context, userId uuid.UUID) (*ConflictReport, error) {
+	if userId == uuid.Nil {
+		return nil, errors.New("missing user")
+	}
+	return &ConflictReport{UserId: userId, Conflicts: []RatingConflict{}}, nil
+}
+
+func (m *mockRatingService) GetFilmRatingHistory(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*FilmRatingHistory, error) {
+	if filmId == uuid.Nil {
+		return nil, ErrRatingNotFound
+	}
+	return &FilmRatingHistory{FilmId: filmId, Points: []RatingPoint{{Rank: 3}}}, nil
+}
+
+func (m *mockRatingService) GetRankChanges(ctx context.Context, userId uuid.UUID, since time.Time) ([]FilmRankChange, error) {
+	return []FilmRankChange{{FilmTitle: since.Format(time.DateOnly), RankChange: 4}}, nil
+}
+
+func (m *mockRatingService) DeleteComparison(ctx context.Context, userId uuid.UUID, comparisonId uuid.UUID) (*ComparisonChange, error) {
+	if m.changeComparisonFunc != nil {
+		return m.changeComparisonFunc(ctx, userId, comparisonId, uuid.Nil, false, true)
+	}
+	return &ComparisonChange{Deleted: true}, nil
+}
+
+func (m *mockRatingService) UpdateComparisonResult(ctx context.Context, userId uuid.UUID, comparisonId uuid.UUID, winningFilmId uuid.UUID, wasEqual bool) (*ComparisonChange, error) {
+	if m.changeComparisonFunc != nil {
+		return m.changeComparisonFunc(ctx, userId, comparisonId, winningFilmId, wasEqual, false)
+	}
+	return &ComparisonChange{}, nil
+}
+
 func TestHandler_GetRating_MissingUserId(t *testing.T) {
 	handler := NewHandler(&mockRatingService{})
 	req := httptest.NewRequest(http.MethodGet, "/ratings?filmId="+uuid.New().String(), nil)
@@ -172,3 +233,353 @@ func TestHandler_CompareFilms_InvalidJSON(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
 }
+
+func TestHandler_ReplayRatings_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockRatingService{})
+	req := httptest.NewRequest(http.MethodPost, "/ratings/replay", nil)
+	w := httptest.NewRecorder()
+
+	handler.ReplayRatings(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_ReplayRatings_DryRun(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	var gotDryRun bool
+	handler := NewHandler(&mockRatingService{
+		replayRatingsFunc: func(ctx context.Context, userId uuid.UUID, dryRun bool) (*ReplayReport, error) {
+			if userId != user.ID {
+				t.Errorf("expected user %v, got %v", user.ID, userId)
+			}
+			gotDryRun = dryRun
+			return &ReplayReport{UserId: userId, DryRun: dryRun, ComparisonsReplayed: 3}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/ratings/replay?dryRun=true", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+	w := httptest.NewRecorder()
+
+	handler.ReplayRatings(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !gotDryRun {
+		t.Error("expected dry run to be passed to the service")
+	}
+	if !strings.Contains(w.Body.String(), `"comparisonsReplayed":3`) {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+}
+
+func TestHandler_ReplayRatings_ServiceError(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	handler := NewHandler(&mockRatingService{
+		replayRatingsFunc: func(ctx context.Context, userId uuid.UUID, dryRun bool) (*ReplayReport, error) {
+			return nil, errors.New("database down")
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/ratings/replay", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+	w := httptest.NewRecorder()
+
+	handler.ReplayRatings(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestHandler_GetRatingEngine(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	handler := NewHandler(&mockRatingService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/ratings/engine", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+	w := httptest.NewRecorder()
+
+	handler.GetRatingEngine(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var response RatingEngineResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Engine != "elo" || len(response.Available) != 3 {
+		t.Errorf("unexpected response %+v", response)
+	}
+}
+
+func TestHandler_SetRatingEngine(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		expected int
+	}{
+		{"switches engine", `{"engine":"glicko2"}`, nil, http.StatusOK},
+		{"unknown engine", `{"engine":"chess"}`, ErrUnknownRatingEngine, http.StatusBadRequest},
+		{"invalid body", `{`, nil, http.StatusBadRequest},
+		{"service error", `{"engine":"glicko2"}`, errors.New("database down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockRatingService{
+				setRatingEngineFunc: func(ctx context.Context, userId uuid.UUID, name string) (*ReplayReport, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &ReplayReport{UserId: userId, Engine: name}, nil
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPut, "/ratings/engine", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+			w := httptest.NewRecorder()
+
+			handler.SetRatingEngine(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_SetRatingEngine_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockRatingService{})
+	req := httptest.NewRequest(http.MethodPut, "/ratings/engine", strings.NewReader(`{"engine":"glicko2"}`))
+	w := httptest.NewRecorder()
+
+	handler.SetRatingEngine(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_GetNextComparison(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	filmId := uuid.New()
+	tests := []struct {
+		name     string
+		query    string
+		err      error
+		expected int
+	}{
+		{"next pair", "", nil, http.StatusOK},
+		{"next pair for a film", "?filmId=" + filmId.String(), nil, http.StatusOK},
+		{"invalid film id", "?filmId=nope", nil, http.StatusBadRequest},
+		{"film not rated", "?filmId=" + filmId.String(), ErrRatingNotFound, http.StatusNotFound},
+		{"nothing left to compare", "", ErrNoComparisonAvailable, http.StatusNotFound},
+		{"service error", "", errors.New("database down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockRatingService{
+				getNextComparisonFunc: func(ctx context.Context, userId uuid.UUID, id uuid.UUID) (*NextComparison, error) {
+					if tt.query != "" && id != filmId {
+						t.Errorf("expected film %v, got %v", filmId, id)
+					}
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &NextComparison{WinProbability: 0.5}, nil
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/ratings/next-comparison"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+			w := httptest.NewRecorder()
+
+			handler.GetNextComparison(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_DeleteComparison(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	comparisonId := uuid.New()
+	tests := []struct {
+		name     string
+		id       string
+		err      error
+		expected int
+	}{
+		{"deletes comparison", comparisonId.String(), nil, http.StatusOK},
+		{"invalid id", "nope", nil, http.StatusBadRequest},
+		{"not found", comparisonId.String(), ErrComparisonNotFound, http.StatusNotFound},
+		{"service error", comparisonId.String(), errors.New("database down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockRatingService{
+				changeComparisonFunc: func(ctx context.Context, userId uuid.UUID, id uuid.UUID, winningFilmId uuid.UUID, wasEqual bool, deleted bool) (*ComparisonChange, error) {
+					if !deleted || id != comparisonId || userId != user.ID {
+						t.Errorf("unexpected delete of %v for %v", id, userId)
+					}
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &ComparisonChange{Deleted: true, Films: []FilmReplayResult{{OldRating: 1020, NewRating: 1000}}}, nil
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodDelete, "/ratings/comparisons/"+tt.id, nil)
+			req.SetPathValue("id", tt.id)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+			w := httptest.NewRecorder()
+
+			handler.DeleteComparison(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_UpdateComparison(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	comparisonId := uuid.New()
+	winner := uuid.New()
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		expected int
+	}{
+		{"flips result", `{"winningFilmId":"` + winner.String() + `"}`, nil, http.StatusOK},
+		{"equalises result", `{"wasEqual":true}`, nil, http.StatusOK},
+		{"invalid body", `{`, nil, http.StatusBadRequest},
+		{"winner not compared", `{"winningFilmId":"` + winner.String() + `"}`, ErrInvalidWinningFilm, http.StatusBadRequest},
+		{"not found", `{"wasEqual":true}`, ErrComparisonNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockRatingService{
+				changeComparisonFunc: func(ctx context.Context, userId uuid.UUID, id uuid.UUID, winningFilmId uuid.UUID, wasEqual bool, deleted bool) (*ComparisonChange, error) {
+					if deleted || id != comparisonId {
+						t.Errorf("unexpected update of %v", id)
+					}
+					if !wasEqual && winningFilmId != winner {
+						t.Errorf("expected winner %v, got %v", winner, winningFilmId)
+					}
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &ComparisonChange{}, nil
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPut, "/ratings/comparisons/"+comparisonId.String(), strings.NewReader(tt.body))
+			req.SetPathValue("id", comparisonId.String())
+			req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+			w := httptest.NewRecorder()
+
+			handler.UpdateComparison(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_GetConflicts(t *testing.T) {
+	handler := NewHandler(&mockRatingService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/ratings/conflicts", nil)
+	w := httptest.NewRecorder()
+	handler.GetConflicts(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+	req = httptest.NewRequest(http.MethodGet, "/ratings/conflicts", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+	w = httptest.NewRecorder()
+	handler.GetConflicts(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"conflicts":[]`) {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+}
+
+func TestHandler_GetFilmRatingHistory(t *testing.T) {
+	handler := NewHandler(&mockRatingService{})
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+
+	tests := []struct {
+		name     string
+		filmId   string
+		expected int
+	}{
+		{"found", uuid.NewString(), http.StatusOK},
+		{"not rated", uuid.Nil.String(), http.StatusNotFound},
+		{"invalid id", "abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ratings/"+tt.filmId+"/history", nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+			req.SetPathValue("filmId", tt.filmId)
+			w := httptest.NewRecorder()
+			handler.GetFilmRatingHistory(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_GetRankChanges(t *testing.T) {
+	handler := NewHandler(&mockRatingService{})
+	user := &domain.User{ID: uuid.New(), Name: "Test User", Username: "testuser"}
+
+	tests := []struct {
+		name     string
+		since    string
+		expected int
+	}{
+		{"date", "2025-06-01", http.StatusOK},
+		{"time", "2025-06-01T12:00:00Z", http.StatusOK},
+		{"missing", "", http.StatusBadRequest},
+		{"invalid", "last week", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ratings/rank-changes?since="+url.QueryEscape(tt.since), nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+			w := httptest.NewRecorder()
+			handler.GetRankChanges(w, req)
+
+			if w.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d", tt.expected, w.Code)
+			}
+			if tt.expected == http.StatusOK && !strings.Contains(w.Body.String(), `"filmTitle":"2025-06-01"`) {
+				t.Errorf("expected the since date to reach the service, got %s", w.Body.String())
+			}
+		})
+	}
+}
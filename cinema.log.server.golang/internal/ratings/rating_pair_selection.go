@@ -0,0 +1,171 @@
+package ratings
+
+import (
+	"math"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+/*
+Next comparison
+---
+Picks the pair of films whose comparison should teach us the most about the user's ranking.
+For a pair (a, b) with win probability p = 1 / (1 + 10^((Rb - Ra)/400)) the expected
+information from asking is roughly
+
+	p(1 - p) * (σa² + σb²)
+
+p(1 - p) is highest when the result is a coin flip, and σ is how unsure we are of each rating,
+so close films with uncertain ratings come first. Pairs that have already been compared are
+never asked again, and neither are pairs whose result already follows from the history by
+transitivity (a beat b and b beat c, so a beats c). A rewatch can start a fresh round for a film,
+after which only comparisons made in the round count towards either of these.
+*/
+
+// eloUncertaintyScale gives elo ratings, which have no deviation of their own, an uncertainty of
+// 350 / sqrt(1 + comparisons), roughly matching where glicko-2 and bradley-terry ratings start
+const eloUncertaintyScale = 350
+
+// NextComparison is a pair of films ready to send to /ratings/compare-films
+type NextComparison struct {
+	FilmA           domain.UserFilmRatingDetail `json:"filmA"`
+	FilmB           domain.UserFilmRatingDetail `json:"filmB"`
+	WinProbability  float64                     `json:"winProbability"` // chance film A wins given the current ratings
+	InformationGain float64                     `json:"informationGain"`
+	PairsRemaining  int                         `json:"pairsRemaining"` // pairs that are neither compared nor implied
+	PairsImplied    int                         `json:"pairsImplied"`   // pairs skipped because the history already decides them
+}
+
+// ratingUncertainty is the standard deviation of a rating, on the elo scale
+func ratingUncertainty(rating domain.UserFilmRating) float64 {
+	if rating.RatingDeviation > 0 {
+		return rating.RatingDeviation
+	}
+	return eloUncertaintyScale / math.Sqrt(1+float64(rating.NumberOfComparisons))
+}
+
+// selectNextComparison finds the most informative pair out of the user's ratings. When filmId is set
+// one side of the pair is always that film. Returns nil when there is nothing left worth asking.
+func selectNextComparison(ratings []domain.UserFilmRatingDetail, history []domain.ComparisonHistory, filmId uuid.UUID) *NextComparison {
+	index := make(map[uuid.UUID]int, len(ratings))
+	for i, detail := range ratings {
+		index[detail.Rating.FilmId] = i
+	}
+
+	n := len(ratings)
+	compared := make(map[[2]int]bool, len(history))
+	beat := make([][]int, n)
+	for _, comparison := range history {
+		a, okA := index[comparison.FilmAId]
+		b, okB := index[comparison.FilmBId]
+		if !okA || !okB || a == b {
+			continue
+		}
+		compared[pairKey(a, b)] = true
+		if comparison.WasEqual {
+			continue // draws don't order anything
+		}
+		if comparison.WinningFilmId == comparison.FilmBId {
+			a, b = b, a
+		}
+		beat[a] = append(beat[a], b)
+	}
+	reach := transitiveClosure(beat)
+
+	var best *NextComparison
+	remaining, implied := 0, 0
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if filmId != uuid.Nil && ratings[i].Rating.FilmId != filmId && ratings[j].Rating.FilmId != filmId {
+				continue
+			}
+			if compared[pairKey(i, j)] {
+				continue
+			}
+			// Only count the result as implied when the history agrees with itself, cycles still need asking about
+			if reach[i].has(j) != reach[j].has(i) {
+				implied++
+				continue
+			}
+			remaining++
+
+			filmA, filmB := ratings[i].Rating, ratings[j].Rating
+			p := 1 / (1 + math.Pow(10, (filmB.EloRating-filmA.EloRating)/400))
+			sigmaA, sigmaB := ratingUncertainty(filmA), ratingUncertainty(filmB)
+			gain := p * (1 - p) * (sigmaA*sigmaA + sigmaB*sigmaB)
+
+			if best == nil || gain > best.InformationGain {
+				best = &NextComparison{FilmA: ratings[i], FilmB: ratings[j], WinProbability: p, InformationGain: gain}
+			}
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	best.WinProbability = math.Round(best.WinProbability*1000) / 1000
+	best.InformationGain = math.Round(best.InformationGain*100) / 100
+	best.PairsRemaining = remaining
+	best.PairsImplied = implied
+	return best
+}
+
+// currentRound drops the comparisons made before either film's current round of comparisons started
+func currentRound(history []domain.ComparisonHistory, rounds map[uuid.UUID]time.Time) []domain.ComparisonHistory {
+	if len(rounds) == 0 {
+		return history
+	}
+
+	current := make([]domain.ComparisonHistory, 0, len(history))
+	for _, comparison := range history {
+		fromA, okA := rounds[comparison.FilmAId]
+		fromB, okB := rounds[comparison.FilmBId]
+		if (okA && !comparison.ComparisonDate.After(fromA)) || (okB && !comparison.ComparisonDate.After(fromB)) {
+			continue
+		}
+		current = append(current, comparison)
+	}
+	return current
+}
+
+func pairKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+type bitset []uint64
+
+func newBitset(size int) bitset {
+	return make(bitset, (size+63)/64)
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (i % 64)
+}
+
+func (b bitset) has(i int) bool {
+	return b[i/64]&(1<<(i%64)) != 0
+}
+
+// transitiveClosure returns, for every film, the set of films it beat directly or through a chain of wins
+func transitiveClosure(beat [][]int) []bitset {
+	reach := make([]bitset, len(beat))
+	for start := range beat {
+		reach[start] = newBitset(len(beat))
+		stack := append([]int{}, beat[start]...)
+		for len(stack) > 0 {
+			film := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if reach[start].has(film) {
+				continue
+			}
+			reach[start].set(film)
+			stack = append(stack, beat[film]...)
+		}
+	}
+	return reach
+}
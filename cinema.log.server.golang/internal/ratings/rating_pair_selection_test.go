@@ -0,0 +1,126 @@
+package ratings
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+func newPairDetails(eloRatings ...float64) []domain.UserFilmRatingDetail {
+	var details []domain.UserFilmRatingDetail
+	for _, eloRating := range eloRatings {
+		details = append(details, domain.UserFilmRatingDetail{Rating: domain.UserFilmRating{FilmId: uuid.New(), EloRating: eloRating}})
+	}
+	return details
+}
+
+func filmIdsOf(next *NextComparison) map[uuid.UUID]bool {
+	return map[uuid.UUID]bool{next.FilmA.Rating.FilmId: true, next.FilmB.Rating.FilmId: true}
+}
+
+func TestSelectNextComparison_PrefersCloseUncertainFilms(t *testing.T) {
+	ratings := newPairDetails(1400, 1010, 1000, 600)
+	// The two close films have both been compared a lot, so a fresh pair can be worth more
+	ratings[1].Rating.NumberOfComparisons = 30
+	ratings[2].Rating.NumberOfComparisons = 30
+
+	next := selectNextComparison(ratings, nil, uuid.Nil)
+	if next == nil {
+		t.Fatal("expected a pair")
+	}
+	if got := filmIdsOf(next); got[ratings[1].Rating.FilmId] && got[ratings[2].Rating.FilmId] {
+		t.Errorf("expected the well known pair to be skipped for a more uncertain one, got %+v", next)
+	}
+
+	// With equal certainty the closest pair is the most informative
+	ratings = newPairDetails(1400, 1010, 1000, 600)
+	next = selectNextComparison(ratings, nil, uuid.Nil)
+	if got := filmIdsOf(next); !got[ratings[1].Rating.FilmId] || !got[ratings[2].Rating.FilmId] {
+		t.Errorf("expected the two closest films, got %+v", next)
+	}
+	if next.PairsRemaining != 6 || next.WinProbability < 0.5 || next.WinProbability > 0.52 {
+		t.Errorf("unexpected pair details %+v", next)
+	}
+}
+
+func TestSelectNextComparison_SkipsComparedAndImpliedPairs(t *testing.T) {
+	ratings := newPairDetails(1000, 1000, 1000)
+	a, b, c := ratings[0].Rating.FilmId, ratings[1].Rating.FilmId, ratings[2].Rating.FilmId
+	now := time.Now()
+
+	// a beat b and b beat c, so a beating c is already known
+	history := []domain.ComparisonHistory{
+		{FilmAId: a, FilmBId: b, WinningFilmId: a, ComparisonDate: now},
+		{FilmAId: c, FilmBId: b, WinningFilmId: b, ComparisonDate: now},
+	}
+	if next := selectNextComparison(ratings, history, uuid.Nil); next != nil {
+		t.Errorf("expected nothing left to ask, got %+v", next)
+	}
+
+	// A draw doesn't order anything, so a and c still need comparing
+	history[1].WasEqual = true
+	next := selectNextComparison(ratings, history, uuid.Nil)
+	if next == nil || !filmIdsOf(next)[a] || !filmIdsOf(next)[c] {
+		t.Fatalf("expected a and c, got %+v", next)
+	}
+	if next.PairsImplied != 0 || next.PairsRemaining != 1 {
+		t.Errorf("expected 1 pair remaining and none implied, got %+v", next)
+	}
+}
+
+func TestSelectNextComparison_CyclesStillNeedAsking(t *testing.T) {
+	ratings := newPairDetails(1000, 1000, 1000, 1000)
+	a, b, c, d := ratings[0].Rating.FilmId, ratings[1].Rating.FilmId, ratings[2].Rating.FilmId, ratings[3].Rating.FilmId
+	history := []domain.ComparisonHistory{
+		{FilmAId: a, FilmBId: b, WinningFilmId: a},
+		{FilmAId: b, FilmBId: c, WinningFilmId: b},
+		{FilmAId: c, FilmBId: d, WinningFilmId: c},
+		{FilmAId: d, FilmBId: a, WinningFilmId: d},
+	}
+
+	next := selectNextComparison(ratings, history, uuid.Nil)
+	if next == nil || next.PairsRemaining != 2 || next.PairsImplied != 0 {
+		t.Errorf("expected both diagonals of the cycle to be open, got %+v", next)
+	}
+}
+
+func TestSelectNextComparison_ForFilm(t *testing.T) {
+	ratings := newPairDetails(1400, 1010, 1000, 600)
+	target := ratings[0].Rating.FilmId
+
+	next := selectNextComparison(ratings, nil, target)
+	if next == nil || !filmIdsOf(next)[target] {
+		t.Fatalf("expected a pair including the target film, got %+v", next)
+	}
+	if !filmIdsOf(next)[ratings[1].Rating.FilmId] || next.PairsRemaining != 3 {
+		t.Errorf("expected the target's closest film, got %+v", next)
+	}
+}
+
+func TestService_GetNextComparison(t *testing.T) {
+	ctx := context.Background()
+	ratings := newPairDetails(1000, 1000)
+	service := NewService(&mockRatingStore{
+		getRatingsByUserIdFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
+			return ratings, nil
+		},
+		getComparisonHistoryFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error) {
+			return nil, nil
+		},
+	})
+
+	if _, err := service.GetNextComparison(ctx, uuid.New(), uuid.Nil); err != nil {
+		t.Errorf("expected a pair, got %v", err)
+	}
+	if _, err := service.GetNextComparison(ctx, uuid.New(), uuid.New()); err != ErrRatingNotFound {
+		t.Errorf("expected ErrRatingNotFound for an unrated film, got %v", err)
+	}
+
+	ratings = ratings[:1]
+	if _, err := service.GetNextComparison(ctx, uuid.New(), uuid.Nil); err != ErrNoComparisonAvailable {
+		t.Errorf("expected ErrNoComparisonAvailable, got %v", err)
+	}
+}
@@ -3,7 +3,7 @@ package ratings
 import (
 	"context"
 	"database/sql"
-	"math"
+	"slices"
 	"time"
 
 	"cinema.log.server.golang/internal/domain"
@@ -26,9 +26,16 @@ type RatingStore interface {
 	GetComparisonHistory(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error)
 	BulkGetRatings(ctx context.Context, userId uuid.UUID, filmIds []uuid.UUID) (map[uuid.UUID]*domain.UserFilmRating, error)
 	BulkHasBeenCompared(ctx context.Context, userId uuid.UUID, pairs []domain.ComparisonPair) (map[string]bool, error)
-	BulkInsertComparisons(ctx context.Context, comparisons []domain.ComparisonHistory) error
+	BulkInsertComparisons(ctx context.Context, tx *sql.Tx, comparisons []domain.ComparisonHistory) error
 	BulkUpdateRatings(ctx context.Context, tx *sql.Tx, ratings []domain.UserFilmRating) error
 	BeginTx(ctx context.Context) (*sql.Tx, error)
+	LockRatings(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error
+	GetRatingEngine(ctx context.Context, userId uuid.UUID) (string, error)
+	SetRatingEngine(ctx context.Context, tx *sql.Tx, userId uuid.UUID, engine string) error
+	DeleteComparison(ctx context.Context, tx *sql.Tx, userId uuid.UUID, comparisonId uuid.UUID) error
+	UpdateComparisonResult(ctx context.Context, tx *sql.Tx, comparison domain.ComparisonHistory) error
+	StartRecompareRound(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, from time.Time) error
+	GetRecompareRounds(ctx context.Context, userId uuid.UUID) (map[uuid.UUID]time.Time, error)
 }
 
 func NewService(r RatingStore) *Service {
@@ -61,152 +68,148 @@ func (s Service) GetComparisonHistory(ctx context.Context, userId uuid.UUID) ([]
 	return s.RatingStore.GetComparisonHistory(ctx, userId)
 }
 
+// engineFor returns the rating engine the user has chosen
+func (s Service) engineFor(ctx context.Context, userId uuid.UUID) (RatingEngine, error) {
+	name, err := s.RatingStore.GetRatingEngine(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return GetRatingEngine(name)
+}
+
+// GetRatingEngine returns the name of the engine the user's ratings are calculated with
+func (s Service) GetRatingEngine(ctx context.Context, userId uuid.UUID) (string, error) {
+	engine, err := s.engineFor(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	return engine.Name(), nil
+}
+
 func (s Service) CreateRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, initialRating float32) (*domain.UserFilmRating, error) {
-	// Create a new rating with initial values
-	rating := domain.UserFilmRating{
-		ID:                  uuid.New(),
-		UserId:              userId,
-		FilmId:              filmId,
-		EloRating:           float64(s.getInitialEloRating(initialRating)),
-		NumberOfComparisons: 0,
-		LastUpdated:         time.Now(),
-		InitialRating:       initialRating,
-		KConstantValue:      40, // Start with highest K value for new ratings
+	engine, err := s.engineFor(ctx, userId)
+	if err != nil {
+		return nil, err
 	}
 
+	// Create a new rating with the engine's starting values
+	rating := engine.Seed(domain.UserFilmRating{
+		ID:            uuid.New(),
+		UserId:        userId,
+		FilmId:        filmId,
+		LastUpdated:   time.Now(),
+		InitialRating: initialRating,
+	})
+
 	return s.RatingStore.CreateRating(ctx, rating)
 }
 
+// UpdateRatings rates the pair on the result of comparison and saves the comparison to the user's history,
+// both in one transaction under the lock on the user's ratings. The pair is read again once the lock is held,
+// so a comparison saved since the caller read it is rated on top of rather than overwritten.
 func (s Service) UpdateRatings(ctx context.Context, ratings domain.ComparisonPair, comparison domain.ComparisonHistory) (*domain.ComparisonPair, error) {
-	filmA := ratings.FilmA
-	filmB := ratings.FilmB
-	// Set the results from the film head to head
-	filmAResult, filmBResult := s.defineFilmContestResult(filmA.FilmId, filmB.FilmId, comparison)
-	// Calculate expected results for film A and film B
-	filmAExpectedResult := s.calculateExpectedResult(filmA.EloRating, filmB.EloRating)
-	filmBExpectedResult := s.calculateExpectedResult(filmB.EloRating, filmA.EloRating)
-
-	// Update K Constants
-	filmA.KConstantValue = s.updateKConstantValue(filmA)
-	filmB.KConstantValue = s.updateKConstantValue(filmB)
-
-	// Recalculate film rating for film A and film B
-	filmANewRating := s.recalculateFilmRating(filmAExpectedResult, filmAResult, filmA.EloRating, filmA.KConstantValue)
-	filmBNewRating := s.recalculateFilmRating(filmBExpectedResult, filmBResult, filmB.EloRating, filmB.KConstantValue)
-
-	// Update films
-	filmA.EloRating = filmANewRating
-	filmA.LastUpdated = time.Now()
-	filmA.NumberOfComparisons += 1
-
-	filmB.EloRating = filmBNewRating
-	filmB.LastUpdated = time.Now()
-	filmB.NumberOfComparisons += 1
-
-	// Update both films in the store
-	updatedFilmA, err := s.RatingStore.UpdateRating(ctx, filmA)
+	userId := ratings.FilmA.UserId
+	engine, err := s.engineFor(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
 
-	updatedFilmB, err := s.RatingStore.UpdateRating(ctx, filmB)
+	tx, err := s.RatingStore.BeginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	// Return the updated comparison pair
-	return &domain.ComparisonPair{
-		FilmA: *updatedFilmA,
-		FilmB: *updatedFilmB,
-	}, nil
-}
+	if err := s.RatingStore.LockRatings(ctx, tx, userId); err != nil {
+		return nil, err
+	}
 
-/*
-   Calculate expected result
-   ---
-   Ea = 1 / (1 + 10^(Rb - Ra)/400)
-   Where:
-   Ea is expected score of film a
-   Ra is current rating of film a
-   Rb is current rating of film b
-*/
-
-func (s Service) calculateExpectedResult(filmUnderReviewEloRating float64, challengerFilmEloRating float64) float64 {
-	rawCalc := (1 / (1 + math.Pow(10, (challengerFilmEloRating-filmUnderReviewEloRating)/400)))
-
-	ratio := math.Pow(10, float64(2)) // round to 2 dp
-	return math.Round(rawCalc*ratio) / ratio
-}
+	var rated map[uuid.UUID]*domain.UserFilmRating
+	if engine.Batch() {
+		// Batch engines refit every rating from the whole history
+		rated, err = s.refit(ctx, tx, userId, engine, []domain.ComparisonHistory{comparison})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Rate the film head to head
+		rated, err = s.RatingStore.BulkGetRatings(ctx, userId, []uuid.UUID{ratings.FilmA.FilmId, ratings.FilmB.FilmId})
+		if err != nil {
+			return nil, err
+		}
+		if rated[ratings.FilmA.FilmId] == nil || rated[ratings.FilmB.FilmId] == nil {
+			return nil, ErrRatingNotFound
+		}
+		engine.Rate(rated, []domain.ComparisonHistory{comparison})
 
-/*
-Recalculate elo rating
----
-R'a = Ra + K(Sa - Ea)
-Where:
-R'a is new rating for film a
-Ra is current rating for film a
-K is K-factor (to be adjusted based on review date) (With the most recent having the highest K)
-Sa is actual result of match up (0 for loss, 0.5 for draw, 1 for win)
-Ea is expected result (Ea = 1 / (1 + 10^(Rb - Ra)/400))
-*/
+		if err := s.RatingStore.BulkUpdateRatings(ctx, tx, []domain.UserFilmRating{*rated[ratings.FilmA.FilmId], *rated[ratings.FilmB.FilmId]}); err != nil {
+			return nil, err
+		}
+	}
+	filmA, okA := rated[ratings.FilmA.FilmId]
+	filmB, okB := rated[ratings.FilmB.FilmId]
+	if !okA || !okB {
+		return nil, ErrRatingNotFound
+	}
 
-func (s Service) recalculateFilmRating(expectedResult float64, actualResult float64,
-	currentRating float64, filmKConstantValue float64) float64 {
+	if err := s.RatingStore.BulkInsertComparisons(ctx, tx, []domain.ComparisonHistory{comparison}); err != nil {
+		return nil, err
+	}
 
-	rawCalc := currentRating + filmKConstantValue*(actualResult-expectedResult)
-	if rawCalc <= 100 {
-		return 100 // 100 is the lowest rating value you can get, so no further decreases past this point
+	if err := tx.Commit(); err != nil {
+		return nil, err
 	}
 
-	return math.Round(rawCalc)
+	return &domain.ComparisonPair{FilmA: *filmA, FilmB: *filmB}, nil
 }
 
-func (s Service) defineFilmContestResult(filmA uuid.UUID, filmB uuid.UUID, comparison domain.ComparisonHistory) (float64, float64) {
-	var filmAResult, filmBResult float64
-	if comparison.WasEqual {
-		filmAResult = 0.5
-		filmBResult = 0.5
-		return filmAResult, filmBResult
+// refit reruns a batch engine over all of the user's ratings and their full history plus newComparisons, and
+// writes every rating back in tx. The caller must already hold the lock on the user's ratings in tx, so a refit
+// running alongside waits for this one to commit and then reads what it wrote rather than overwriting it.
+// Saving newComparisons in tx is up to the caller too.
+func (s Service) refit(ctx context.Context, tx *sql.Tx, userId uuid.UUID, engine RatingEngine, newComparisons []domain.ComparisonHistory) (map[uuid.UUID]*domain.UserFilmRating, error) {
+	ratings, history, err := s.loadRatingsAndHistory(ctx, userId)
+	if err != nil {
+		return nil, err
 	}
 
-	if filmA == comparison.WinningFilmId {
-		filmAResult = 1
-		filmBResult = 0
-	} else if filmB == comparison.WinningFilmId {
-		filmAResult = 0
-		filmBResult = 1
+	refitted := make(map[uuid.UUID]*domain.UserFilmRating, len(ratings))
+	for _, detail := range ratings {
+		rating := detail.Rating
+		refitted[rating.FilmId] = &rating
 	}
-	return filmAResult, filmBResult
-}
+	engine.Rate(refitted, append(history, newComparisons...))
 
-func (s Service) updateKConstantValue(film domain.UserFilmRating) float64 {
-	numberOfComparisons := film.NumberOfComparisons
-	switch {
-	case numberOfComparisons >= 0 && numberOfComparisons < 20:
-		return 40
-	case numberOfComparisons >= 20 && numberOfComparisons < 40:
-		return 20
-	case numberOfComparisons >= 40:
-		return 10
-	default:
-		return 40
+	updatedRatings := make([]domain.UserFilmRating, 0, len(refitted))
+	for _, detail := range ratings {
+		updatedRatings = append(updatedRatings, *refitted[detail.Rating.FilmId])
+	}
+
+	if err := s.RatingStore.BulkUpdateRatings(ctx, tx, updatedRatings); err != nil {
+		return nil, err
 	}
+
+	return refitted, nil
 }
 
-// this is generated from the users initial 5 star rating 'InitialRating'
-func (s Service) getInitialEloRating(rating float32) float32 {
-	switch {
-	case rating >= 0 && rating < 2:
-		return 950
-	case rating >= 2 && rating < 3:
-		return 1000
-	case rating >= 3 && rating < 4:
-		return 1050
-	case rating >= 4 && rating <= 5:
-		return 1100
-	default:
-		return 1000
+// loadRatingsAndHistory returns the user's ratings ranked by elo and their comparisons oldest first
+func (s Service) loadRatingsAndHistory(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, []domain.ComparisonHistory, error) {
+	ratings, err := s.RatingStore.GetRatingsByUserId(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	history, err := s.RatingStore.GetComparisonHistory(ctx, userId)
+	if err != nil {
+		return nil, nil, err
 	}
+	// History is newest first, flip it and sort so comparisons made at the same instant keep their order
+	slices.Reverse(history)
+	slices.SortStableFunc(history, func(a, b domain.ComparisonHistory) int {
+		return a.ComparisonDate.Compare(b.ComparisonDate)
+	})
+
+	return ratings, history, nil
 }
 
 // ProcessBatchComparisons processes multiple film comparisons in a single transaction
@@ -220,6 +223,24 @@ func (s Service) ProcessBatchComparisons(ctx context.Context, userId, targetFilm
 		comparisons = comparisons[:50]
 	}
 
+	engine, err := s.engineFor(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	// Begin transaction
+	tx, err := s.RatingStore.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the user's ratings before reading them, so two batches for the same user can't both rate
+	// from the same starting point and overwrite each other
+	if err := s.RatingStore.LockRatings(ctx, tx, userId); err != nil {
+		return err
+	}
+
 	// Collect all film IDs (target + all challengers)
 	filmIds := []uuid.UUID{targetFilmId}
 	for _, comp := range comparisons {
@@ -263,67 +284,23 @@ func (s Service) ProcessBatchComparisons(ctx context.Context, userId, targetFilm
 		return nil // Nothing to process
 	}
 
-	// Begin transaction
-	tx, err := s.RatingStore.BeginTx(ctx)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// Process comparisons sequentially to maintain K-factor progression
-	var updatedRatings []domain.UserFilmRating
+	// Build the comparison history, in the order the user made them
 	var comparisonHistory []domain.ComparisonHistory
-	currentTargetRating := *targetRating
-
 	for _, comp := range validComparisons {
-		challengerRating := ratingsMap[comp.ChallengerFilmId]
-
 		// Determine winner
 		var winningFilmId uuid.UUID
 		var wasEqual bool
-		var targetResult, challengerResult float64
 
 		switch comp.Result {
 		case "better":
 			winningFilmId = targetFilmId
-			targetResult = 1.0
-			challengerResult = 0.0
 		case "worse":
 			winningFilmId = comp.ChallengerFilmId
-			targetResult = 0.0
-			challengerResult = 1.0
 		case "same":
 			winningFilmId = targetFilmId // Use target as placeholder
 			wasEqual = true
-			targetResult = 0.5
-			challengerResult = 0.5
 		}
 
-		// Calculate expected results
-		targetExpected := s.calculateExpectedResult(currentTargetRating.EloRating, challengerRating.EloRating)
-		challengerExpected := s.calculateExpectedResult(challengerRating.EloRating, currentTargetRating.EloRating)
-
-		// Update K constants
-		currentTargetRating.KConstantValue = s.updateKConstantValue(currentTargetRating)
-		challengerRating.KConstantValue = s.updateKConstantValue(*challengerRating)
-
-		// Recalculate ratings
-		targetNewRating := s.recalculateFilmRating(targetExpected, targetResult, currentTargetRating.EloRating, currentTargetRating.KConstantValue)
-		challengerNewRating := s.recalculateFilmRating(challengerExpected, challengerResult, challengerRating.EloRating, challengerRating.KConstantValue)
-
-		// Update ratings
-		currentTargetRating.EloRating = targetNewRating
-		currentTargetRating.LastUpdated = time.Now()
-		currentTargetRating.NumberOfComparisons += 1
-
-		challengerRating.EloRating = challengerNewRating
-		challengerRating.LastUpdated = time.Now()
-		challengerRating.NumberOfComparisons += 1
-
-		// Add to update list
-		updatedRatings = append(updatedRatings, currentTargetRating, *challengerRating)
-
-		// Create comparison history
 		comparisonHistory = append(comparisonHistory, domain.ComparisonHistory{
 			ID:             uuid.New(),
 			UserId:         userId,
@@ -333,18 +310,30 @@ func (s Service) ProcessBatchComparisons(ctx context.Context, userId, targetFilm
 			ComparisonDate: time.Now(),
 			WasEqual:       wasEqual,
 		})
-
-		// Update ratingsMap for next iteration
-		ratingsMap[comp.ChallengerFilmId] = challengerRating
 	}
 
-	// Bulk update all ratings
-	if err := s.RatingStore.BulkUpdateRatings(ctx, tx, updatedRatings); err != nil {
-		return err
+	if engine.Batch() {
+		// Batch engines refit every rating from the whole history
+		if _, err := s.refit(ctx, tx, userId, engine, comparisonHistory); err != nil {
+			return err
+		}
+	} else {
+		// Process comparisons sequentially to maintain K-factor progression
+		engine.Rate(ratingsMap, comparisonHistory)
+
+		updatedRatings := []domain.UserFilmRating{*targetRating}
+		for _, comp := range validComparisons {
+			updatedRatings = append(updatedRatings, *ratingsMap[comp.ChallengerFilmId])
+		}
+
+		// Bulk update all ratings
+		if err := s.RatingStore.BulkUpdateRatings(ctx, tx, updatedRatings); err != nil {
+			return err
+		}
 	}
 
 	// Bulk insert comparison history
-	if err := s.RatingStore.BulkInsertComparisons(ctx, comparisonHistory); err != nil {
+	if err := s.RatingStore.BulkInsertComparisons(ctx, tx, comparisonHistory); err != nil {
 		return err
 	}
 
@@ -355,3 +344,346 @@ func (s Service) ProcessBatchComparisons(ctx context.Context, userId, targetFilm
 
 	return nil
 }
+
+// GetNextComparison picks the comparison that should tell us the most about the user's ranking,
+// optionally one that involves filmId
+func (s Service) GetNextComparison(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*NextComparison, error) {
+	ratings, history, err := s.loadRatingsAndHistory(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if filmId != uuid.Nil && !slices.ContainsFunc(ratings, func(detail domain.UserFilmRatingDetail) bool {
+		return detail.Rating.FilmId == filmId
+	}) {
+		return nil, ErrRatingNotFound
+	}
+
+	rounds, err := s.RatingStore.GetRecompareRounds(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	next := selectNextComparison(ratings, currentRound(history, rounds), filmId)
+	if next == nil {
+		return nil, ErrNoComparisonAvailable
+	}
+	return next, nil
+}
+
+// StartRecompareRound starts a fresh round of comparisons for a film the user has rewatched. The film's
+// earlier comparisons stay in the history and keep counting towards its rating, but no longer stop it being
+// compared with the same films again.
+func (s Service) StartRecompareRound(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	return s.RatingStore.StartRecompareRound(ctx, userId, filmId, time.Now())
+}
+
+// GetConflicts finds the groups of films the user's comparisons contradict each other on
+func (s Service) GetConflicts(ctx context.Context, userId uuid.UUID) (*ConflictReport, error) {
+	ratings, history, err := s.loadRatingsAndHistory(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ConflictReport{UserId: userId, Conflicts: findConflicts(ratings, history)}
+	if report.Conflicts == nil {
+		report.Conflicts = []RatingConflict{}
+	}
+	for _, conflict := range report.Conflicts {
+		report.FilmsInConflict += len(conflict.Films)
+	}
+	return report, nil
+}
+
+// FilmReplayResult is how far a single film moved when its ratings were replayed
+type FilmReplayResult struct {
+	FilmId          uuid.UUID `json:"filmId"`
+	FilmTitle       string    `json:"filmTitle"`
+	OldRating       float64   `json:"oldRating"`
+	NewRating       float64   `json:"newRating"`
+	RatingChange    float64   `json:"ratingChange"`
+	RatingDeviation float64   `json:"ratingDeviation"` // how unsure the engine is of the new rating, always 0 for elo
+	OldRank         int       `json:"oldRank"`
+	NewRank         int       `json:"newRank"`
+	RankChange      int       `json:"rankChange"` // positive means the film moved up the ranking
+	Comparisons     int       `json:"comparisons"`
+}
+
+type ReplayReport struct {
+	UserId              uuid.UUID          `json:"userId"`
+	Engine              string             `json:"engine"`
+	DryRun              bool               `json:"dryRun"`
+	ComparisonsReplayed int                `json:"comparisonsReplayed"`
+	ComparisonsSkipped  int                `json:"comparisonsSkipped"`
+	Films               []FilmReplayResult `json:"films"`
+}
+
+// ReplayRatings recomputes a user's ratings from scratch by resetting every film to the starting rating
+// its InitialRating gives and replaying comparison_histories in the order they happened.
+// This lets changes to the rating maths apply to ratings made before the change.
+// The new ratings are written in a single transaction unless dryRun is set. The history is read under the lock
+// on the user's ratings, so a comparison saved while the replay runs can't be overwritten by it.
+func (s Service) ReplayRatings(ctx context.Context, userId uuid.UUID, dryRun bool) (*ReplayReport, error) {
+	engine, err := s.engineFor(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		report, _, err := s.replay(ctx, userId, engine)
+		if err != nil {
+			return nil, err
+		}
+		report.DryRun = true
+		return report, nil
+	}
+
+	tx, err := s.RatingStore.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.RatingStore.LockRatings(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+
+	report, updatedRatings, err := s.replay(ctx, userId, engine)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(updatedRatings) > 0 {
+		if err := s.RatingStore.BulkUpdateRatings(ctx, tx, updatedRatings); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// SetRatingEngine switches the user to another rating engine and recomputes all of their ratings with it
+func (s Service) SetRatingEngine(ctx context.Context, userId uuid.UUID, name string) (*ReplayReport, error) {
+	engine, err := GetRatingEngine(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.RatingStore.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.RatingStore.LockRatings(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+
+	report, updatedRatings, err := s.replay(ctx, userId, engine)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.RatingStore.SetRatingEngine(ctx, tx, userId, engine.Name()); err != nil {
+		return nil, err
+	}
+
+	if len(updatedRatings) > 0 {
+		if err := s.RatingStore.BulkUpdateRatings(ctx, tx, updatedRatings); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// replay reseeds every rating the user has and runs their whole history through the engine, it saves nothing
+func (s Service) replay(ctx context.Context, userId uuid.UUID, engine RatingEngine) (*ReplayReport, []domain.UserFilmRating, error) {
+	// Ratings come back ranked by elo, which gives us the ranking before the replay
+	current, history, err := s.loadRatingsAndHistory(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report, updatedRatings := replayHistory(userId, engine, current, history)
+	return report, updatedRatings, nil
+}
+
+// replayHistory reseeds the ratings in current, which must be ranked by elo, and runs history through the engine
+func replayHistory(userId uuid.UUID, engine RatingEngine, current []domain.UserFilmRatingDetail, history []domain.ComparisonHistory) (*ReplayReport, []domain.UserFilmRating) {
+	replayed := make(map[uuid.UUID]*domain.UserFilmRating, len(current))
+	for _, detail := range current {
+		rating := engine.Seed(detail.Rating)
+		replayed[rating.FilmId] = &rating
+	}
+
+	report := &ReplayReport{UserId: userId, Engine: engine.Name(), Films: []FilmReplayResult{}}
+	var comparisons []domain.ComparisonHistory
+	for _, comparison := range history {
+		_, okA := replayed[comparison.FilmAId]
+		_, okB := replayed[comparison.FilmBId]
+		if !okA || !okB {
+			// The rating for one of the films has since been deleted
+			report.ComparisonsSkipped++
+			continue
+		}
+		comparisons = append(comparisons, comparison)
+	}
+	engine.Rate(replayed, comparisons)
+	report.ComparisonsReplayed = len(comparisons)
+
+	// Rank the replayed ratings, ties keep their old order
+	newOrder := make([]domain.UserFilmRatingDetail, len(current))
+	copy(newOrder, current)
+	slices.SortStableFunc(newOrder, func(a, b domain.UserFilmRatingDetail) int {
+		return compareFloatsDesc(replayed[a.Rating.FilmId].EloRating, replayed[b.Rating.FilmId].EloRating)
+	})
+	oldRanks := make(map[uuid.UUID]int, len(current))
+	for i, detail := range current {
+		oldRanks[detail.Rating.FilmId] = i + 1
+	}
+
+	updatedRatings := make([]domain.UserFilmRating, 0, len(newOrder))
+	for i, detail := range newOrder {
+		rating := replayed[detail.Rating.FilmId]
+		oldRank := oldRanks[rating.FilmId]
+		report.Films = append(report.Films, FilmReplayResult{
+			FilmId:          rating.FilmId,
+			FilmTitle:       detail.FilmTitle,
+			OldRating:       detail.Rating.EloRating,
+			NewRating:       rating.EloRating,
+			RatingChange:    rating.EloRating - detail.Rating.EloRating,
+			RatingDeviation: rating.RatingDeviation,
+			OldRank:         oldRank,
+			NewRank:         i + 1,
+			RankChange:      oldRank - (i + 1),
+			Comparisons:     rating.NumberOfComparisons,
+		})
+		updatedRatings = append(updatedRatings, *rating)
+	}
+
+	return report, updatedRatings
+}
+
+// ComparisonChange is what undoing or editing a comparison did to the user's ratings
+type ComparisonChange struct {
+	Comparison *domain.ComparisonHistory `json:"comparison"` // nil when the comparison was deleted
+	Deleted    bool                      `json:"deleted"`
+	Films      []FilmReplayResult        `json:"films"` // both films in the comparison and any other film whose rating or rank moved
+}
+
+// DeleteComparison undoes one of the user's comparisons and recomputes their ratings from the history that's left
+func (s Service) DeleteComparison(ctx context.Context, userId uuid.UUID, comparisonId uuid.UUID) (*ComparisonChange, error) {
+	return s.changeComparison(ctx, userId, comparisonId, nil)
+}
+
+// UpdateComparisonResult flips or equalises the result of one of the user's comparisons and recomputes their ratings
+func (s Service) UpdateComparisonResult(ctx context.Context, userId uuid.UUID, comparisonId uuid.UUID, winningFilmId uuid.UUID, wasEqual bool) (*ComparisonChange, error) {
+	return s.changeComparison(ctx, userId, comparisonId, func(comparison *domain.ComparisonHistory) error {
+		if wasEqual {
+			comparison.WinningFilmId = comparison.FilmAId // Use film A as placeholder, like batch comparisons
+			comparison.WasEqual = true
+			return nil
+		}
+		if winningFilmId != comparison.FilmAId && winningFilmId != comparison.FilmBId {
+			return ErrInvalidWinningFilm
+		}
+		comparison.WinningFilmId = winningFilmId
+		comparison.WasEqual = false
+		return nil
+	})
+}
+
+// changeComparison edits a comparison in the user's history, or deletes it when edit is nil, and replays the
+// whole history with the user's engine so every later comparison involving the films is recalculated too.
+// The history is loaded under the lock on the user's ratings, so a comparison saved meanwhile is replayed too.
+func (s Service) changeComparison(ctx context.Context, userId uuid.UUID, comparisonId uuid.UUID, edit func(comparison *domain.ComparisonHistory) error) (*ComparisonChange, error) {
+	engine, err := s.engineFor(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.RatingStore.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.RatingStore.LockRatings(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+
+	current, history, err := s.loadRatingsAndHistory(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	i := slices.IndexFunc(history, func(comparison domain.ComparisonHistory) bool {
+		return comparison.ID == comparisonId
+	})
+	if i < 0 {
+		return nil, ErrComparisonNotFound
+	}
+	changed := history[i]
+
+	change := &ComparisonChange{}
+	if edit == nil {
+		history = slices.Delete(history, i, i+1)
+		change.Deleted = true
+	} else {
+		if err := edit(&changed); err != nil {
+			return nil, err
+		}
+		history[i] = changed
+		change.Comparison = &changed
+	}
+
+	report, updatedRatings := replayHistory(userId, engine, current, history)
+
+	if edit == nil {
+		err = s.RatingStore.DeleteComparison(ctx, tx, userId, comparisonId)
+	} else {
+		err = s.RatingStore.UpdateComparisonResult(ctx, tx, changed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(updatedRatings) > 0 {
+		if err := s.RatingStore.BulkUpdateRatings(ctx, tx, updatedRatings); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	change.Films = []FilmReplayResult{}
+	for _, film := range report.Films {
+		if film.FilmId == changed.FilmAId || film.FilmId == changed.FilmBId || film.RatingChange != 0 || film.RankChange != 0 {
+			change.Films = append(change.Films, film)
+		}
+	}
+
+	return change, nil
+}
+
+func compareFloatsDesc(a, b float64) int {
+	switch {
+	case a > b:
+		return -1
+	case a < b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -3,6 +3,9 @@ package ratings
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -10,6 +13,26 @@ import (
 	"github.com/google/uuid"
 )
 
+// txOnlyDB hands out transactions that commit and roll back without a database behind them, the mock
+// store does the reads and writes
+var txOnlyDB = func() *sql.DB {
+	sql.Register("txonly", txOnlyDriver{})
+	db, _ := sql.Open("txonly", "")
+	return db
+}()
+
+type txOnlyDriver struct{}
+
+func (txOnlyDriver) Open(name string) (driver.Conn, error) { return txOnlyConn{}, nil }
+
+type txOnlyConn struct{}
+
+func (txOnlyConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("no database") }
+func (txOnlyConn) Close() error                              { return nil }
+func (txOnlyConn) Begin() (driver.Tx, error)                 { return txOnlyConn{}, nil }
+func (txOnlyConn) Commit() error                             { return nil }
+func (txOnlyConn) Rollback() error                           { return nil }
+
 // Add mock store for service testing
 type mockRatingStore struct {
 	getRatingFunc             func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.UserFilmRating, error)
@@ -23,9 +46,16 @@ type mockRatingStore struct {
 	getComparisonHistoryFunc  func(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error)
 	bulkGetRatingsFunc        func(ctx context.Context, userId uuid.UUID, filmIds []uuid.UUID) (map[uuid.UUID]*domain.UserFilmRating, error)
 	bulkHasBeenComparedFunc   func(ctx context.Context, userId uuid.UUID, pairs []domain.ComparisonPair) (map[string]bool, error)
-	bulkInsertComparisonsFunc func(ctx context.Context, comparisons []domain.ComparisonHistory) error
+	bulkInsertComparisonsFunc func(ctx context.Context, tx *sql.Tx, comparisons []domain.ComparisonHistory) error
 	bulkUpdateRatingsFunc     func(ctx context.Context, tx *sql.Tx, ratings []domain.UserFilmRating) error
 	beginTxFunc               func(ctx context.Context) (*sql.Tx, error)
+	lockRatingsFunc           func(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error
+	startRecompareRoundFunc   func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, from time.Time) error
+	getRecompareRoundsFunc    func(ctx context.Context, userId uuid.UUID) (map[uuid.UUID]time.Time, error)
+	getRatingEngineFunc       func(ctx context.Context, userId uuid.UUID) (string, error)
+	setRatingEngineFunc       func(ctx context.Context, tx *sql.Tx, userId uuid.UUID, engine string) error
+	deleteComparisonFunc      func(ctx context.Context, tx *sql.Tx, userId uuid.UUID, comparisonId uuid.UUID) error
+	updateComparisonFunc      func(ctx context.Context, tx *sql.Tx, comparison domain.ComparisonHistory) error
 }
 
 func (m *mockRatingStore) GetRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.UserFilmRating, error) {
@@ -105,9 +135,9 @@ func (m *mockRatingStore) BulkHasBeenCompared(ctx context.Context, userId uuid.U
 	return nil, nil
 }
 
-func (m *mockRatingStore) BulkInsertComparisons(ctx context.Context, comparisons []domain.ComparisonHistory) error {
+func (m *mockRatingStore) BulkInsertComparisons(ctx context.Context, tx *sql.Tx, comparisons []domain.ComparisonHistory) error {
 	if m.bulkInsertComparisonsFunc != nil {
-		return m.bulkInsertComparisonsFunc(ctx, comparisons)
+		return m.bulkInsertComparisonsFunc(ctx, tx, comparisons)
 	}
 	return nil
 }
@@ -123,7 +153,42 @@ func (m *mockRatingStore) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	if m.beginTxFunc != nil {
 		return m.beginTxFunc(ctx)
 	}
-	return nil, nil
+	return txOnlyDB.BeginTx(ctx, nil)
+}
+
+func (m *mockRatingStore) LockRatings(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	if m.lockRatingsFunc != nil {
+		return m.lockRatingsFunc(ctx, tx, userId)
+	}
+	return nil
+}
+
+func (m *mockRatingStore) GetRatingEngine(ctx context.Context, userId uuid.UUID) (string, error) {
+	if m.getRatingEngineFunc != nil {
+		return m.getRatingEngineFunc(ctx, userId)
+	}
+	return "", nil
+}
+
+func (m *mockRatingStore) SetRatingEngine(ctx context.Context, tx *sql.Tx, userId uuid.UUID, engine string) error {
+	if m.setRatingEngineFunc != nil {
+		return m.setRatingEngineFunc(ctx, tx, userId, engine)
+	}
+	return nil
+}
+
+func (m *mockRatingStore) DeleteComparison(ctx context.Context, tx *sql.Tx, userId uuid.UUID, comparisonId uuid.UUID) error {
+	if m.deleteComparisonFunc != nil {
+		return m.deleteComparisonFunc(ctx, tx, userId, comparisonId)
+	}
+	return nil
+}
+
+func (m *mockRatingStore) UpdateComparisonResult(ctx context.Context, tx *sql.Tx, comparison domain.ComparisonHistory) error {
+	if m.updateComparisonFunc != nil {
+		return m.updateComparisonFunc(ctx, tx, comparison)
+	}
+	return nil
 }
 
 func TestService_GetRating(t *testing.T) {
@@ -220,6 +285,35 @@ func TestService_CreateRating(t *testing.T) {
 	}
 }
 
+func (m *mockRatingStore) StartRecompareRound(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, from time.Time) error {
+	if m.startRecompareRoundFunc != nil {
+		return m.startRecompareRoundFunc(ctx, userId, filmId, from)
+	}
+	return nil
+}
+
+func (m *mockRatingStore) GetRecompareRounds(ctx context.Context, userId uuid.UUID) (map[uuid.UUID]time.Time, error) {
+	if m.getRecompareRoundsFunc != nil {
+		return m.getRecompareRoundsFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+// storeWithRatings is a store holding the given ratings, as they stood before anything was rated
+func storeWithRatings(ratings ...domain.UserFilmRating) *mockRatingStore {
+	return &mockRatingStore{
+		bulkGetRatingsFunc: func(ctx context.Context, userId uuid.UUID, filmIds []uuid.UUID) (map[uuid.UUID]*domain.UserFilmRating, error) {
+			found := make(map[uuid.UUID]*domain.UserFilmRating)
+			for _, rating := range ratings {
+				if slices.Contains(filmIds, rating.FilmId) {
+					found[rating.FilmId] = &rating
+				}
+			}
+			return found, nil
+		},
+	}
+}
+
 func TestService_UpdateRatings(t *testing.T) {
 	ctx := context.Background()
 
@@ -248,13 +342,7 @@ func TestService_UpdateRatings(t *testing.T) {
 		KConstantValue:      32.0,
 	}
 
-	mock := &mockRatingStore{
-		updateRatingFunc: func(ctx context.Context, rating domain.UserFilmRating) (*domain.UserFilmRating, error) {
-			return &rating, nil
-		},
-	}
-
-	service := NewService(mock)
+	service := NewService(storeWithRatings(filmA, filmB))
 	pair := domain.ComparisonPair{
 		FilmA: filmA,
 		FilmB: filmB,
@@ -319,13 +407,7 @@ func TestService_UpdateRatings_Draw(t *testing.T) {
 		KConstantValue:      32.0,
 	}
 
-	mock := &mockRatingStore{
-		updateRatingFunc: func(ctx context.Context, rating domain.UserFilmRating) (*domain.UserFilmRating, error) {
-			return &rating, nil
-		},
-	}
-
-	service := NewService(mock)
+	service := NewService(storeWithRatings(filmA, filmB))
 	pair := domain.ComparisonPair{
 		FilmA: filmA,
 		FilmB: filmB,
@@ -353,8 +435,77 @@ func TestService_UpdateRatings_Draw(t *testing.T) {
 	}
 }
 
+func TestService_UpdateRatings_RatesUnderLock(t *testing.T) {
+	ctx := context.Background()
+	userId := uuid.New()
+	filmA := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), EloRating: 1000, NumberOfComparisons: 5, KConstantValue: 32}
+	filmB := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), EloRating: 1000, NumberOfComparisons: 5, KConstantValue: 32}
+
+	// Another comparison moved film A after the caller read it
+	movedA := filmA
+	movedA.EloRating = 1100
+	movedA.NumberOfComparisons = 6
+
+	var calls []string
+	var saved []domain.UserFilmRating
+	errInsert := errors.New("insert failed")
+	store := storeWithRatings(movedA, filmB)
+	bulkGetRatings := store.bulkGetRatingsFunc
+	store.lockRatingsFunc = func(ctx context.Context, tx *sql.Tx, lockedUserId uuid.UUID) error {
+		if lockedUserId != userId {
+			t.Errorf("expected user %v to be locked, got %v", userId, lockedUserId)
+		}
+		calls = append(calls, "lock")
+		return nil
+	}
+	store.bulkGetRatingsFunc = func(ctx context.Context, userId uuid.UUID, filmIds []uuid.UUID) (map[uuid.UUID]*domain.UserFilmRating, error) {
+		calls = append(calls, "read")
+		return bulkGetRatings(ctx, userId, filmIds)
+	}
+	store.bulkUpdateRatingsFunc = func(ctx context.Context, tx *sql.Tx, ratings []domain.UserFilmRating) error {
+		if tx == nil {
+			t.Error("expected the ratings to be saved in the transaction")
+		}
+		saved = ratings
+		return nil
+	}
+	service := NewService(store)
+
+	comparison := domain.ComparisonHistory{ID: uuid.New(), UserId: userId, FilmAId: filmA.FilmId, FilmBId: filmB.FilmId, WinningFilmId: filmA.FilmId}
+	pair, err := service.UpdateRatings(ctx, domain.ComparisonPair{FilmA: filmA, FilmB: filmB}, comparison)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !slices.Equal(calls, []string{"lock", "read"}) {
+		t.Errorf("expected the pair to be read after locking, got %v", calls)
+	}
+	if pair.FilmA.NumberOfComparisons != 7 || pair.FilmA.EloRating <= movedA.EloRating {
+		t.Errorf("expected film A to be rated from where the other comparison left it, got %+v", pair.FilmA)
+	}
+	if len(saved) != 2 || saved[0] != pair.FilmA || saved[1] != pair.FilmB {
+		t.Errorf("expected both ratings to be saved, got %+v", saved)
+	}
+
+	// Ratings don't move without the comparison behind them
+	var rolledBack *sql.Tx
+	store.beginTxFunc = func(ctx context.Context) (*sql.Tx, error) {
+		tx, err := txOnlyDB.BeginTx(ctx, nil)
+		rolledBack = tx
+		return tx, err
+	}
+	store.bulkInsertComparisonsFunc = func(ctx context.Context, tx *sql.Tx, comparisons []domain.ComparisonHistory) error {
+		return errInsert
+	}
+	if _, err := service.UpdateRatings(ctx, domain.ComparisonPair{FilmA: filmA, FilmB: filmB}, comparison); err != errInsert {
+		t.Fatalf("expected %v, got %v", errInsert, err)
+	}
+	if err := rolledBack.Commit(); err != sql.ErrTxDone {
+		t.Errorf("expected the transaction to be rolled back, got %v", err)
+	}
+}
+
 func TestService_CalculateExpectedResult(t *testing.T) {
-	service := NewService(nil)
+	engine := eloEngine{}
 
 	tests := []struct {
 		name           string
@@ -384,7 +535,7 @@ func TestService_CalculateExpectedResult(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := service.calculateExpectedResult(tt.filmRating, tt.opponentRating)
+			result := engine.calculateExpectedResult(tt.filmRating, tt.opponentRating)
 			if result < tt.wantRange[0] || result > tt.wantRange[1] {
 				t.Errorf("expected result between %v and %v, got %v", tt.wantRange[0], tt.wantRange[1], result)
 			}
@@ -393,7 +544,7 @@ func TestService_CalculateExpectedResult(t *testing.T) {
 }
 
 func TestService_RecalculateFilmRating(t *testing.T) {
-	service := NewService(nil)
+	engine := eloEngine{}
 
 	tests := []struct {
 		name           string
@@ -431,7 +582,7 @@ func TestService_RecalculateFilmRating(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := service.recalculateFilmRating(tt.expectedResult, tt.actualResult, tt.currentRating, tt.kValue)
+			result := engine.recalculateFilmRating(tt.expectedResult, tt.actualResult, tt.currentRating, tt.kValue)
 			if result < tt.wantMin {
 				t.Errorf("expected rating >= %v, got %v", tt.wantMin, result)
 			}
@@ -440,8 +591,6 @@ func TestService_RecalculateFilmRating(t *testing.T) {
 }
 
 func TestService_DefineFilmContestResult(t *testing.T) {
-	service := NewService(nil)
-
 	filmA := uuid.New()
 	filmB := uuid.New()
 
@@ -482,7 +631,7 @@ func TestService_DefineFilmContestResult(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resultA, resultB := service.defineFilmContestResult(filmA, filmB, tt.comparison)
+			resultA, resultB := defineFilmContestResult(filmA, filmB, tt.comparison)
 			if resultA != tt.expectedA {
 				t.Errorf("expected film A result %v, got %v", tt.expectedA, resultA)
 			}
@@ -494,7 +643,7 @@ func TestService_DefineFilmContestResult(t *testing.T) {
 }
 
 func TestService_UpdateKConstantValue(t *testing.T) {
-	service := NewService(nil)
+	engine := eloEngine{}
 
 	tests := []struct {
 		name                string
@@ -523,7 +672,7 @@ func TestService_UpdateKConstantValue(t *testing.T) {
 			film := domain.UserFilmRating{
 				NumberOfComparisons: tt.numberOfComparisons,
 			}
-			result := service.updateKConstantValue(film)
+			result := engine.updateKConstantValue(film)
 			if result != tt.expectedK {
 				t.Errorf("expected K value %v, got %v", tt.expectedK, result)
 			}
@@ -532,8 +681,6 @@ func TestService_UpdateKConstantValue(t *testing.T) {
 }
 
 func TestService_GetInitialEloRating(t *testing.T) {
-	service := NewService(nil)
-
 	tests := []struct {
 		name          string
 		initialRating float32
@@ -563,10 +710,244 @@ func TestService_GetInitialEloRating(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := service.getInitialEloRating(tt.initialRating)
+			result := getInitialEloRating(tt.initialRating)
 			if result != tt.expectedElo {
 				t.Errorf("expected Elo rating %v, got %v", tt.expectedElo, result)
 			}
 		})
 	}
 }
+
+func TestService_ReplayRatings_DryRun(t *testing.T) {
+	ctx := context.Background()
+	userId := uuid.New()
+	filmA := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), EloRating: 1200, NumberOfComparisons: 2, InitialRating: 3}
+	filmB := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), EloRating: 900, NumberOfComparisons: 2, InitialRating: 4.5}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock := &mockRatingStore{
+		getRatingsByUserIdFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
+			return []domain.UserFilmRatingDetail{
+				{Rating: filmA, FilmTitle: "Film A"},
+				{Rating: filmB, FilmTitle: "Film B"},
+			}, nil
+		},
+		// Newest first, like the store returns it
+		getComparisonHistoryFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error) {
+			return []domain.ComparisonHistory{
+				{ID: uuid.New(), FilmAId: filmA.FilmId, FilmBId: uuid.New(), WinningFilmId: filmA.FilmId, ComparisonDate: start.Add(2 * time.Hour)},
+				{ID: uuid.New(), FilmAId: filmA.FilmId, FilmBId: filmB.FilmId, WinningFilmId: filmB.FilmId, ComparisonDate: start.Add(time.Hour)},
+				{ID: uuid.New(), FilmAId: filmA.FilmId, FilmBId: filmB.FilmId, WasEqual: true, WinningFilmId: filmA.FilmId, ComparisonDate: start},
+			}, nil
+		},
+		beginTxFunc: func(ctx context.Context) (*sql.Tx, error) {
+			t.Fatal("expected dry run not to open a transaction")
+			return nil, nil
+		},
+	}
+	service := NewService(mock)
+
+	report, err := service.ReplayRatings(ctx, userId, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.ComparisonsReplayed != 2 || report.ComparisonsSkipped != 1 {
+		t.Errorf("expected 2 replayed and 1 skipped, got %d and %d", report.ComparisonsReplayed, report.ComparisonsSkipped)
+	}
+
+	// Replaying by hand: B starts on 1100 and A on 1050, they draw and then B wins
+	expectedA, expectedB := getInitialEloRating(filmA.InitialRating), getInitialEloRating(filmB.InitialRating)
+	a := domain.UserFilmRating{FilmId: filmA.FilmId, EloRating: float64(expectedA)}
+	b := domain.UserFilmRating{FilmId: filmB.FilmId, EloRating: float64(expectedB)}
+	a, b = eloEngine{}.compare(a, b, 0.5, 0.5, start)
+	a, b = eloEngine{}.compare(a, b, 0, 1, start.Add(time.Hour))
+
+	if len(report.Films) != 2 {
+		t.Fatalf("expected 2 films, got %d", len(report.Films))
+	}
+	top, bottom := report.Films[0], report.Films[1]
+	if top.FilmId != filmB.FilmId || top.NewRating != b.EloRating || top.OldRank != 2 || top.RankChange != 1 {
+		t.Errorf("expected film B to move to the top on %.0f, got %+v", b.EloRating, top)
+	}
+	if bottom.FilmId != filmA.FilmId || bottom.NewRating != a.EloRating || bottom.RatingChange != a.EloRating-filmA.EloRating {
+		t.Errorf("expected film A to drop to %.0f, got %+v", a.EloRating, bottom)
+	}
+	if top.Comparisons != 2 || bottom.Comparisons != 2 {
+		t.Errorf("expected both films to have 2 comparisons, got %d and %d", top.Comparisons, bottom.Comparisons)
+	}
+}
+
+func TestService_ReplayRatings_MatchesLiveComparisons(t *testing.T) {
+	ctx := context.Background()
+	userId := uuid.New()
+	filmA := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), InitialRating: 2}
+	filmB := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), InitialRating: 5}
+
+	// Rate a fresh pair live
+	filmA.EloRating = float64(getInitialEloRating(filmA.InitialRating))
+	filmB.EloRating = float64(getInitialEloRating(filmB.InitialRating))
+	service := NewService(storeWithRatings(filmA, filmB))
+	comparison := domain.ComparisonHistory{FilmAId: filmA.FilmId, FilmBId: filmB.FilmId, WinningFilmId: filmA.FilmId, ComparisonDate: time.Now()}
+	live, err := service.UpdateRatings(ctx, domain.ComparisonPair{FilmA: filmA, FilmB: filmB}, comparison)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Replaying the same history should land on the same numbers
+	service.RatingStore = &mockRatingStore{
+		getRatingsByUserIdFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
+			return []domain.UserFilmRatingDetail{{Rating: live.FilmA}, {Rating: live.FilmB}}, nil
+		},
+		getComparisonHistoryFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error) {
+			return []domain.ComparisonHistory{comparison}, nil
+		},
+	}
+	report, err := service.ReplayRatings(ctx, userId, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, film := range report.Films {
+		if film.RatingChange != 0 {
+			t.Errorf("expected replay to match live ratings, film %v moved by %.0f", film.FilmId, film.RatingChange)
+		}
+	}
+}
+
+func TestService_ChangeComparison_Validation(t *testing.T) {
+	ctx := context.Background()
+	userId := uuid.New()
+	filmA := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), EloRating: 1000}
+	filmB := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), EloRating: 1000}
+	comparison := domain.ComparisonHistory{ID: uuid.New(), UserId: userId, FilmAId: filmA.FilmId, FilmBId: filmB.FilmId, WinningFilmId: filmA.FilmId}
+	errTx := errors.New("could not save")
+	service := NewService(&mockRatingStore{
+		getRatingsByUserIdFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
+			return []domain.UserFilmRatingDetail{{Rating: filmA}, {Rating: filmB}}, nil
+		},
+		getComparisonHistoryFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error) {
+			return []domain.ComparisonHistory{comparison}, nil
+		},
+		// Everything that gets as far as saving has passed validation
+		deleteComparisonFunc: func(ctx context.Context, tx *sql.Tx, userId uuid.UUID, comparisonId uuid.UUID) error {
+			return errTx
+		},
+		updateComparisonFunc: func(ctx context.Context, tx *sql.Tx, comparison domain.ComparisonHistory) error {
+			return errTx
+		},
+	})
+
+	tests := []struct {
+		name     string
+		change   func() error
+		expected error
+	}{
+		{"delete unknown comparison", func() error {
+			_, err := service.DeleteComparison(ctx, userId, uuid.New())
+			return err
+		}, ErrComparisonNotFound},
+		{"update unknown comparison", func() error {
+			_, err := service.UpdateComparisonResult(ctx, userId, uuid.New(), filmB.FilmId, false)
+			return err
+		}, ErrComparisonNotFound},
+		{"winner not in comparison", func() error {
+			_, err := service.UpdateComparisonResult(ctx, userId, comparison.ID, uuid.New(), false)
+			return err
+		}, ErrInvalidWinningFilm},
+		{"flip result", func() error {
+			_, err := service.UpdateComparisonResult(ctx, userId, comparison.ID, filmB.FilmId, false)
+			return err
+		}, errTx},
+		{"equalise result ignores winner", func() error {
+			_, err := service.UpdateComparisonResult(ctx, userId, comparison.ID, uuid.Nil, true)
+			return err
+		}, errTx},
+		{"delete", func() error {
+			_, err := service.DeleteComparison(ctx, userId, comparison.ID)
+			return err
+		}, errTx},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestService_ChangeComparison_LoadsHistoryUnderLock(t *testing.T) {
+	ctx := context.Background()
+	userId := uuid.New()
+	filmA := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), EloRating: 1000}
+	filmB := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), EloRating: 1000}
+	comparison := domain.ComparisonHistory{ID: uuid.New(), UserId: userId, FilmAId: filmA.FilmId, FilmBId: filmB.FilmId, WinningFilmId: filmA.FilmId}
+
+	var calls []string
+	service := NewService(&mockRatingStore{
+		lockRatingsFunc: func(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+			calls = append(calls, "lock")
+			return nil
+		},
+		getRatingsByUserIdFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
+			calls = append(calls, "ratings")
+			return []domain.UserFilmRatingDetail{{Rating: filmA}, {Rating: filmB}}, nil
+		},
+		getComparisonHistoryFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error) {
+			calls = append(calls, "history")
+			return []domain.ComparisonHistory{comparison}, nil
+		},
+		bulkUpdateRatingsFunc: func(ctx context.Context, tx *sql.Tx, ratings []domain.UserFilmRating) error {
+			calls = append(calls, "save")
+			return nil
+		},
+	})
+
+	if _, err := service.UpdateComparisonResult(ctx, userId, comparison.ID, filmB.FilmId, false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !slices.Equal(calls, []string{"lock", "ratings", "history", "save"}) {
+		t.Errorf("expected the history to be loaded after locking, got %v", calls)
+	}
+}
+
+func TestService_GetNextComparison_RecompareRound(t *testing.T) {
+	ctx := context.Background()
+	userId := uuid.New()
+	filmA := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), EloRating: 1000}
+	filmB := domain.UserFilmRating{ID: uuid.New(), UserId: userId, FilmId: uuid.New(), EloRating: 1000}
+	comparedAt := time.Now().Add(-time.Hour)
+
+	var rounds map[uuid.UUID]time.Time
+	service := NewService(&mockRatingStore{
+		getRatingsByUserIdFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
+			return []domain.UserFilmRatingDetail{{Rating: filmA}, {Rating: filmB}}, nil
+		},
+		getComparisonHistoryFunc: func(ctx context.Context, userId uuid.UUID) ([]domain.ComparisonHistory, error) {
+			return []domain.ComparisonHistory{{ID: uuid.New(), FilmAId: filmA.FilmId, FilmBId: filmB.FilmId, WinningFilmId: filmA.FilmId, ComparisonDate: comparedAt}}, nil
+		},
+		startRecompareRoundFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, from time.Time) error {
+			rounds = map[uuid.UUID]time.Time{filmId: from}
+			return nil
+		},
+		getRecompareRoundsFunc: func(ctx context.Context, userId uuid.UUID) (map[uuid.UUID]time.Time, error) {
+			return rounds, nil
+		},
+	})
+
+	if _, err := service.GetNextComparison(ctx, userId, filmA.FilmId); err != ErrNoComparisonAvailable {
+		t.Fatalf("expected the pair not to be asked again, got %v", err)
+	}
+
+	// After a rewatch the film can be compared against its previous opponent again
+	if err := service.StartRecompareRound(ctx, userId, filmA.FilmId); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	next, err := service.GetNextComparison(ctx, userId, filmA.FilmId)
+	if err != nil {
+		t.Fatalf("expected a comparison in the new round, got %v", err)
+	}
+	if got := filmIdsOf(next); !got[filmA.FilmId] || !got[filmB.FilmId] {
+		t.Errorf("expected the rewatched film against its previous opponent, got %+v", next)
+	}
+}
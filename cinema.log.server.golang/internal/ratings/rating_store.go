@@ -4,14 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"cinema.log.server.golang/internal/domain"
 	"github.com/google/uuid"
 )
 
 var (
-	ErrRatingNotFound     = errors.New("rating not found")
-	ErrComparisonNotFound = errors.New("comparison not found")
+	ErrRatingNotFound        = errors.New("rating not found")
+	ErrComparisonNotFound    = errors.New("comparison not found")
+	ErrUnknownRatingEngine   = errors.New("unknown rating engine")
+	ErrNoComparisonAvailable = errors.New("no comparison left to make")
+	ErrInvalidWinningFilm    = errors.New("winning film must be one of the films compared")
 )
 
 type store struct {
@@ -26,7 +30,7 @@ func NewStore(db *sql.DB) RatingStore {
 
 func (s *store) GetRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*domain.UserFilmRating, error) {
 	query := /* sql */ `
-		SELECT user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value
+		SELECT user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value, rating_deviation, volatility
 		FROM user_film_ratings
 		WHERE user_id = $1 AND film_id = $2
 	`
@@ -43,6 +47,8 @@ func (s *store) GetRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUI
 		&rating.LastUpdated,
 		&rating.InitialRating,
 		&rating.KConstantValue,
+		&rating.RatingDeviation,
+		&rating.Volatility,
 	)
 
 	if err != nil {
@@ -57,7 +63,7 @@ func (s *store) GetRating(ctx context.Context, userId uuid.UUID, filmId uuid.UUI
 
 func (s *store) GetAllRatings(ctx context.Context) ([]domain.UserFilmRating, error) {
 	query := /* sql */ `
-		SELECT user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value
+		SELECT user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value, rating_deviation, volatility
 		FROM user_film_ratings
 		ORDER BY last_updated DESC
 	`
@@ -80,6 +86,8 @@ func (s *store) GetAllRatings(ctx context.Context) ([]domain.UserFilmRating, err
 			&rating.LastUpdated,
 			&rating.InitialRating,
 			&rating.KConstantValue,
+			&rating.RatingDeviation,
+			&rating.Volatility,
 		)
 		if err != nil {
 			return nil, err
@@ -97,7 +105,7 @@ func (s *store) GetAllRatings(ctx context.Context) ([]domain.UserFilmRating, err
 func (s *store) GetRatingsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
 	// Fetch ratings along with film details for the given userId
 	query := /* sql */ `
-		SELECT r.user_film_rating_id, r.user_id, r.film_id, r.elo_rating, r.number_of_comparisons, r.last_updated, r.initial_rating, r.k_constant_value, f.title, f.release_year, f.poster_url
+		SELECT r.user_film_rating_id, r.user_id, r.film_id, r.elo_rating, r.number_of_comparisons, r.last_updated, r.initial_rating, r.k_constant_value, r.rating_deviation, r.volatility, f.title, f.release_year, f.poster_url
 		FROM user_film_ratings r
 		JOIN films f ON r.film_id = f.film_id
 		WHERE r.user_id = $1
@@ -121,6 +129,8 @@ func (s *store) GetRatingsByUserId(ctx context.Context, userId uuid.UUID) ([]dom
 			&rating.Rating.LastUpdated,
 			&rating.Rating.InitialRating,
 			&rating.Rating.KConstantValue,
+			&rating.Rating.RatingDeviation,
+			&rating.Rating.Volatility,
 			&rating.FilmTitle,
 			&rating.FilmReleaseYear,
 			&rating.FilmPosterURL,
@@ -141,9 +151,9 @@ func (s *store) GetRatingsByUserId(ctx context.Context, userId uuid.UUID) ([]dom
 
 func (s *store) CreateRating(ctx context.Context, rating domain.UserFilmRating) (*domain.UserFilmRating, error) {
 	query := /* sql */ `
-		INSERT INTO user_film_ratings (user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value
+		INSERT INTO user_film_ratings (user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value, rating_deviation, volatility)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value, rating_deviation, volatility
 	`
 
 	createdRating := &domain.UserFilmRating{}
@@ -156,6 +166,8 @@ func (s *store) CreateRating(ctx context.Context, rating domain.UserFilmRating)
 		rating.LastUpdated,
 		rating.InitialRating,
 		rating.KConstantValue,
+		rating.RatingDeviation,
+		rating.Volatility,
 	)
 
 	err := row.Scan(
@@ -167,6 +179,8 @@ func (s *store) CreateRating(ctx context.Context, rating domain.UserFilmRating)
 		&createdRating.LastUpdated,
 		&createdRating.InitialRating,
 		&createdRating.KConstantValue,
+		&createdRating.RatingDeviation,
+		&createdRating.Volatility,
 	)
 
 	if err != nil {
@@ -182,9 +196,11 @@ func (s *store) UpdateRating(ctx context.Context, rating domain.UserFilmRating)
 		SET elo_rating = $1,
 		    number_of_comparisons = $2,
 		    last_updated = $3,
-		    k_constant_value = $4
-		WHERE user_film_rating_id = $5
-		RETURNING user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value
+		    k_constant_value = $4,
+		    rating_deviation = $5,
+		    volatility = $6
+		WHERE user_film_rating_id = $7
+		RETURNING user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value, rating_deviation, volatility
 	`
 
 	updatedRating := &domain.UserFilmRating{}
@@ -193,6 +209,8 @@ func (s *store) UpdateRating(ctx context.Context, rating domain.UserFilmRating)
 		rating.NumberOfComparisons,
 		rating.LastUpdated,
 		rating.KConstantValue,
+		rating.RatingDeviation,
+		rating.Volatility,
 		rating.ID,
 	)
 
@@ -205,6 +223,8 @@ func (s *store) UpdateRating(ctx context.Context, rating domain.UserFilmRating)
 		&updatedRating.LastUpdated,
 		&updatedRating.InitialRating,
 		&updatedRating.KConstantValue,
+		&updatedRating.RatingDeviation,
+		&updatedRating.Volatility,
 	)
 
 	if err != nil {
@@ -230,9 +250,11 @@ func (s *store) UpdateRatings(ctx context.Context, ratings domain.ComparisonPair
 		SET elo_rating = $1,
 		    number_of_comparisons = $2,
 		    last_updated = $3,
-		    k_constant_value = $4
-		WHERE user_film_rating_id = $5
-		RETURNING user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value
+		    k_constant_value = $4,
+		    rating_deviation = $5,
+		    volatility = $6
+		WHERE user_film_rating_id = $7
+		RETURNING user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value, rating_deviation, volatility
 	`
 
 	// Update Film A
@@ -242,6 +264,8 @@ func (s *store) UpdateRatings(ctx context.Context, ratings domain.ComparisonPair
 		ratings.FilmA.NumberOfComparisons,
 		ratings.FilmA.LastUpdated,
 		ratings.FilmA.KConstantValue,
+		ratings.FilmA.RatingDeviation,
+		ratings.FilmA.Volatility,
 		ratings.FilmA.ID,
 	)
 
@@ -254,6 +278,8 @@ func (s *store) UpdateRatings(ctx context.Context, ratings domain.ComparisonPair
 		&updatedFilmA.LastUpdated,
 		&updatedFilmA.InitialRating,
 		&updatedFilmA.KConstantValue,
+		&updatedFilmA.RatingDeviation,
+		&updatedFilmA.Volatility,
 	)
 
 	if err != nil {
@@ -267,6 +293,8 @@ func (s *store) UpdateRatings(ctx context.Context, ratings domain.ComparisonPair
 		ratings.FilmB.NumberOfComparisons,
 		ratings.FilmB.LastUpdated,
 		ratings.FilmB.KConstantValue,
+		ratings.FilmB.RatingDeviation,
+		ratings.FilmB.Volatility,
 		ratings.FilmB.ID,
 	)
 
@@ -279,6 +307,8 @@ func (s *store) UpdateRatings(ctx context.Context, ratings domain.ComparisonPair
 		&updatedFilmB.LastUpdated,
 		&updatedFilmB.InitialRating,
 		&updatedFilmB.KConstantValue,
+		&updatedFilmB.RatingDeviation,
+		&updatedFilmB.Volatility,
 	)
 
 	if err != nil {
@@ -336,15 +366,19 @@ func (s *store) CreateComparison(ctx context.Context, comparison domain.Comparis
 }
 
 func (s *store) HasBeenCompared(ctx context.Context, userId, filmAId, filmBId uuid.UUID) (bool, error) {
+	// Comparisons from before either film's current round of comparisons don't count
 	query := /* sql */ `
-		SELECT COUNT(*) 
-		FROM comparison_histories 
-		WHERE user_id = $1 
+		SELECT COUNT(*)
+		FROM comparison_histories ch
+		LEFT JOIN user_film_ratings ra ON ra.user_id = ch.user_id AND ra.film_id = ch.film_a_film_id
+		LEFT JOIN user_film_ratings rb ON rb.user_id = ch.user_id AND rb.film_id = ch.film_b_film_id
+		WHERE ch.user_id = $1
 		AND (
-		(film_a_film_id = $2 AND film_b_film_id = $3) 
-		OR 
-		(film_a_film_id = $3 AND film_b_film_id = $2)
+		(ch.film_a_film_id = $2 AND ch.film_b_film_id = $3)
+		OR
+		(ch.film_a_film_id = $3 AND ch.film_b_film_id = $2)
 		)
+		AND ch.comparison_date > COALESCE(GREATEST(ra.recompare_from, rb.recompare_from), '-infinity')
 	`
 
 	var count int
@@ -403,7 +437,7 @@ func (s *store) BulkGetRatings(ctx context.Context, userId uuid.UUID, filmIds []
 
 	// Build query with placeholders
 	query := /* sql */ `
-		SELECT user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value
+		SELECT user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value, rating_deviation, volatility
 		FROM user_film_ratings
 		WHERE user_id = $1 AND film_id = ANY($2)
 	`
@@ -426,6 +460,8 @@ func (s *store) BulkGetRatings(ctx context.Context, userId uuid.UUID, filmIds []
 			&rating.LastUpdated,
 			&rating.InitialRating,
 			&rating.KConstantValue,
+			&rating.RatingDeviation,
+			&rating.Volatility,
 		)
 		if err != nil {
 			return nil, err
@@ -440,7 +476,7 @@ func (s *store) BulkGetRatings(ctx context.Context, userId uuid.UUID, filmIds []
 	return ratings, nil
 }
 
-// BulkHasBeenCompared checks if multiple film pairs have been compared
+// BulkHasBeenCompared checks if multiple film pairs have been compared in the films' current rounds
 func (s *store) BulkHasBeenCompared(ctx context.Context, userId uuid.UUID, pairs []domain.ComparisonPair) (map[string]bool, error) {
 	if len(pairs) == 0 {
 		return make(map[string]bool), nil
@@ -448,9 +484,12 @@ func (s *store) BulkHasBeenCompared(ctx context.Context, userId uuid.UUID, pairs
 
 	// Build query to check all pairs at once
 	query := /* sql */ `
-		SELECT film_a_film_id, film_b_film_id 
-		FROM comparison_histories 
-		WHERE user_id = $1
+		SELECT ch.film_a_film_id, ch.film_b_film_id
+		FROM comparison_histories ch
+		LEFT JOIN user_film_ratings ra ON ra.user_id = ch.user_id AND ra.film_id = ch.film_a_film_id
+		LEFT JOIN user_film_ratings rb ON rb.user_id = ch.user_id AND rb.film_id = ch.film_b_film_id
+		WHERE ch.user_id = $1
+		AND ch.comparison_date > COALESCE(GREATEST(ra.recompare_from, rb.recompare_from), '-infinity')
 	`
 
 	rows, err := s.db.QueryContext(ctx, query, userId)
@@ -488,7 +527,7 @@ func (s *store) BulkHasBeenCompared(ctx context.Context, userId uuid.UUID, pairs
 }
 
 // BulkInsertComparisons inserts multiple comparison records in one query
-func (s *store) BulkInsertComparisons(ctx context.Context, comparisons []domain.ComparisonHistory) error {
+func (s *store) BulkInsertComparisons(ctx context.Context, tx *sql.Tx, comparisons []domain.ComparisonHistory) error {
 	if len(comparisons) == 0 {
 		return nil
 	}
@@ -498,8 +537,8 @@ func (s *store) BulkInsertComparisons(ctx context.Context, comparisons []domain.
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
-	// Use a prepared statement for batch inserts
-	stmt, err := s.db.PrepareContext(ctx, query)
+	// Use a prepared statement for batch inserts within the transaction
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		return err
 	}
@@ -538,8 +577,10 @@ func (s *store) BulkUpdateRatings(ctx context.Context, tx *sql.Tx, ratings []dom
 		SET elo_rating = $1,
 		    number_of_comparisons = $2,
 		    last_updated = $3,
-		    k_constant_value = $4
-		WHERE user_film_rating_id = $5
+		    k_constant_value = $4,
+		    rating_deviation = $5,
+		    volatility = $6
+		WHERE user_film_rating_id = $7
 	`
 
 	stmt, err := tx.PrepareContext(ctx, query)
@@ -554,6 +595,8 @@ func (s *store) BulkUpdateRatings(ctx context.Context, tx *sql.Tx, ratings []dom
 			rating.NumberOfComparisons,
 			rating.LastUpdated,
 			rating.KConstantValue,
+			rating.RatingDeviation,
+			rating.Volatility,
 			rating.ID,
 		)
 		if err != nil {
@@ -565,6 +608,156 @@ func (s *store) BulkUpdateRatings(ctx context.Context, tx *sql.Tx, ratings []dom
 }
 
 // BeginTx starts a new transaction
+// LockRatings locks every one of the user's ratings until tx ends, anyone else locking or updating them waits
+func (s *store) LockRatings(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	query := /* sql */ `
+		SELECT user_film_rating_id
+		FROM user_film_ratings
+		WHERE user_id = $1
+		FOR UPDATE
+	`
+
+	_, err := tx.ExecContext(ctx, query, userId)
+	return err
+}
+
+// StartRecompareRound starts a fresh round of comparisons for one of the user's films from from
+func (s *store) StartRecompareRound(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, from time.Time) error {
+	query := /* sql */ `
+		UPDATE user_film_ratings
+		SET recompare_from = $1
+		WHERE user_id = $2 AND film_id = $3
+	`
+
+	result, err := s.db.ExecContext(ctx, query, from, userId, filmId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRatingNotFound
+	}
+
+	return nil
+}
+
+// GetRecompareRounds returns when the current round of comparisons started for each of the user's films
+// that has had a fresh round
+func (s *store) GetRecompareRounds(ctx context.Context, userId uuid.UUID) (map[uuid.UUID]time.Time, error) {
+	query := /* sql */ `
+		SELECT film_id, recompare_from
+		FROM user_film_ratings
+		WHERE user_id = $1 AND recompare_from IS NOT NULL
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rounds := make(map[uuid.UUID]time.Time)
+	for rows.Next() {
+		var filmId uuid.UUID
+		var from time.Time
+		if err := rows.Scan(&filmId, &from); err != nil {
+			return nil, err
+		}
+		rounds[filmId] = from
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rounds, nil
+}
+
 func (s *store) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return s.db.BeginTx(ctx, nil)
 }
+
+// DeleteComparison removes one of the user's comparisons as part of tx
+func (s *store) DeleteComparison(ctx context.Context, tx *sql.Tx, userId uuid.UUID, comparisonId uuid.UUID) error {
+	query := /* sql */ `
+		DELETE FROM comparison_histories
+		WHERE comparison_history_id = $1
+		AND user_id = $2
+	`
+
+	result, err := tx.ExecContext(ctx, query, comparisonId, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrComparisonNotFound
+	}
+
+	return nil
+}
+
+// UpdateComparisonResult changes who won one of the user's comparisons as part of tx
+func (s *store) UpdateComparisonResult(ctx context.Context, tx *sql.Tx, comparison domain.ComparisonHistory) error {
+	query := /* sql */ `
+		UPDATE comparison_histories
+		SET winning_film_film_id = $1,
+		    was_equal = $2
+		WHERE comparison_history_id = $3
+		AND user_id = $4
+	`
+
+	result, err := tx.ExecContext(ctx, query, comparison.WinningFilmId, comparison.WasEqual, comparison.ID, comparison.UserId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrComparisonNotFound
+	}
+
+	return nil
+}
+
+// GetRatingEngine returns the name of the user's chosen rating engine
+func (s *store) GetRatingEngine(ctx context.Context, userId uuid.UUID) (string, error) {
+	query := /* sql */ `
+		SELECT rating_engine
+		FROM users
+		WHERE user_id = $1`
+
+	var engine string
+	err := s.db.QueryRowContext(ctx, query, userId).Scan(&engine)
+	if err == sql.ErrNoRows {
+		return "", nil // unknown users get the default engine
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return engine, nil
+}
+
+// SetRatingEngine saves the user's chosen rating engine as part of tx
+func (s *store) SetRatingEngine(ctx context.Context, tx *sql.Tx, userId uuid.UUID, engine string) error {
+	query := /* sql */ `
+		UPDATE users
+		SET rating_engine = $1,
+		    updated_at = NOW()
+		WHERE user_id = $2`
+
+	_, err := tx.ExecContext(ctx, query, engine, userId)
+	return err
+}
@@ -3,6 +3,7 @@ package ratings
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"os"
 	"testing"
@@ -22,10 +23,9 @@ var (
 // Helper function to create test user
 func createTestUser(ctx context.Context, t *testing.T) uuid.UUID {
 	userID := uuid.New()
-	query := `INSERT INTO users (user_id, name, username, github_id, profile_pic_url) 
-	          VALUES ($1, $2, $3, $4, $5)`
-	githubID := int(time.Now().UnixNano() % 2147483647) // Use nanoseconds for uniqueness
-	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], githubID, "http://example.com/pic.jpg")
+	query := `INSERT INTO users (user_id, name, username, profile_pic_url)
+	          VALUES ($1, $2, $3, $4)`
+	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], "http://example.com/pic.jpg")
 	if err != nil {
 		t.Fatalf("failed to create test user: %v", err)
 	}
@@ -395,3 +395,157 @@ func TestRatingStore_UpdateRatings(t *testing.T) {
 		t.Errorf("expected FilmB elo rating 1650.0, got %.2f", updatedPair.FilmB.EloRating)
 	}
 }
+
+func TestRatingStore_DeleteAndUpdateComparison(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(testStore)
+
+	userId := createTestUser(ctx, t)
+	filmA, err := service.CreateRating(ctx, userId, createTestFilm(ctx, t), 3)
+	if err != nil {
+		t.Fatalf("failed to create rating: %v", err)
+	}
+	filmB, err := service.CreateRating(ctx, userId, createTestFilm(ctx, t), 3)
+	if err != nil {
+		t.Fatalf("failed to create rating: %v", err)
+	}
+
+	comparison := domain.ComparisonHistory{ID: uuid.New(), UserId: userId, FilmAId: filmA.FilmId, FilmBId: filmB.FilmId, WinningFilmId: filmA.FilmId, ComparisonDate: time.Now()}
+	if _, err := service.UpdateRatings(ctx, domain.ComparisonPair{FilmA: *filmA, FilmB: *filmB}, comparison); err != nil {
+		t.Fatalf("failed to update ratings: %v", err)
+	}
+
+	// Flip the result, film B should now be on top
+	change, err := service.UpdateComparisonResult(ctx, userId, comparison.ID, filmB.FilmId, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if change.Comparison.WinningFilmId != filmB.FilmId || len(change.Films) != 2 || change.Films[0].FilmId != filmB.FilmId {
+		t.Errorf("expected film B to win after the flip, got %+v", change)
+	}
+	updatedB, _ := testStore.GetRating(ctx, userId, filmB.FilmId)
+	if updatedB.EloRating <= filmB.EloRating {
+		t.Errorf("expected film B's stored rating to go up from %.0f, got %.0f", filmB.EloRating, updatedB.EloRating)
+	}
+
+	// Deleting it puts both films back where they started
+	change, err = service.DeleteComparison(ctx, userId, comparison.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !change.Deleted || change.Comparison != nil {
+		t.Errorf("expected a deletion, got %+v", change)
+	}
+	history, _ := testStore.GetComparisonHistory(ctx, userId)
+	if len(history) != 0 {
+		t.Errorf("expected the comparison to be gone, got %d", len(history))
+	}
+	restoredA, _ := testStore.GetRating(ctx, userId, filmA.FilmId)
+	if restoredA.EloRating != filmA.EloRating || restoredA.NumberOfComparisons != 0 {
+		t.Errorf("expected film A back on %.0f, got %+v", filmA.EloRating, restoredA)
+	}
+
+	if _, err := service.DeleteComparison(ctx, userId, comparison.ID); err != ErrComparisonNotFound {
+		t.Errorf("expected ErrComparisonNotFound, got %v", err)
+	}
+}
+
+func TestRatingStore_RecompareRound(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(testStore)
+
+	userId := createTestUser(ctx, t)
+	filmA, err := service.CreateRating(ctx, userId, createTestFilm(ctx, t), 3)
+	if err != nil {
+		t.Fatalf("failed to create rating: %v", err)
+	}
+	filmB, err := service.CreateRating(ctx, userId, createTestFilm(ctx, t), 3)
+	if err != nil {
+		t.Fatalf("failed to create rating: %v", err)
+	}
+
+	comparison := domain.ComparisonHistory{ID: uuid.New(), UserId: userId, FilmAId: filmA.FilmId, FilmBId: filmB.FilmId, WinningFilmId: filmA.FilmId, ComparisonDate: time.Now()}
+	if _, err := service.UpdateRatings(ctx, domain.ComparisonPair{FilmA: *filmA, FilmB: *filmB}, comparison); err != nil {
+		t.Fatalf("failed to update ratings: %v", err)
+	}
+	if compared, _ := testStore.HasBeenCompared(ctx, userId, filmB.FilmId, filmA.FilmId); !compared {
+		t.Fatal("expected the pair to have been compared")
+	}
+
+	// A rewatch of film A lets it be compared with film B again
+	if err := service.StartRecompareRound(ctx, userId, filmA.FilmId); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if compared, _ := testStore.HasBeenCompared(ctx, userId, filmA.FilmId, filmB.FilmId); compared {
+		t.Error("expected the earlier comparison not to count in the new round")
+	}
+	rounds, err := testStore.GetRecompareRounds(ctx, userId)
+	if err != nil || len(rounds) != 1 || rounds[filmA.FilmId].IsZero() {
+		t.Errorf("expected a round for film A, got %v with %v", rounds, err)
+	}
+
+	again := domain.ComparisonHistory{ID: uuid.New(), UserId: userId, FilmAId: filmA.FilmId, FilmBId: filmB.FilmId, WinningFilmId: filmB.FilmId, ComparisonDate: time.Now()}
+	if _, err := service.UpdateRatings(ctx, domain.ComparisonPair{FilmA: *filmA, FilmB: *filmB}, again); err != nil {
+		t.Fatalf("failed to update ratings: %v", err)
+	}
+	if compared, _ := testStore.HasBeenCompared(ctx, userId, filmA.FilmId, filmB.FilmId); !compared {
+		t.Error("expected the comparison in the new round to count")
+	}
+
+	if err := service.StartRecompareRound(ctx, userId, uuid.New()); err != ErrRatingNotFound {
+		t.Errorf("expected ErrRatingNotFound for an unrated film, got %v", err)
+	}
+}
+
+func TestRatingStore_BatchEngineSavesComparisonsWithRatings(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(testStore)
+
+	userId := createTestUser(ctx, t)
+	if _, err := service.SetRatingEngine(ctx, userId, "bradley-terry"); err != nil {
+		t.Fatalf("failed to set engine: %v", err)
+	}
+	target, err := service.CreateRating(ctx, userId, createTestFilm(ctx, t), 3)
+	if err != nil {
+		t.Fatalf("failed to create rating: %v", err)
+	}
+	challenger, err := service.CreateRating(ctx, userId, createTestFilm(ctx, t), 3)
+	if err != nil {
+		t.Fatalf("failed to create rating: %v", err)
+	}
+
+	comparison := domain.ComparisonHistory{ID: uuid.New(), UserId: userId, FilmAId: target.FilmId, FilmBId: challenger.FilmId, WinningFilmId: target.FilmId, ComparisonDate: time.Now()}
+	if _, err := service.UpdateRatings(ctx, domain.ComparisonPair{FilmA: *target, FilmB: *challenger}, comparison); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// A batch that fails to save its comparisons leaves the ratings as they were
+	other, err := service.CreateRating(ctx, userId, createTestFilm(ctx, t), 3)
+	if err != nil {
+		t.Fatalf("failed to create rating: %v", err)
+	}
+	before, _ := testStore.GetRating(ctx, userId, target.FilmId)
+	failing := NewService(&failingInsertStore{RatingStore: testStore})
+	err = failing.ProcessBatchComparisons(ctx, userId, target.FilmId, []ComparisonItem{{ChallengerFilmId: other.FilmId, Result: "better"}})
+	if err == nil {
+		t.Fatal("expected the failed insert to be returned")
+	}
+	after, _ := testStore.GetRating(ctx, userId, target.FilmId)
+	if after.EloRating != before.EloRating || after.NumberOfComparisons != before.NumberOfComparisons {
+		t.Errorf("expected the refit to be rolled back, went from %+v to %+v", before, after)
+	}
+
+	history, err := service.GetComparisonHistory(ctx, userId)
+	if err != nil || len(history) != 1 || history[0].ID != comparison.ID {
+		t.Errorf("expected only the first comparison to be saved, got %+v with %v", history, err)
+	}
+}
+
+// failingInsertStore fails to save comparisons after everything else has been written in the transaction
+type failingInsertStore struct {
+	RatingStore
+}
+
+func (s *failingInsertStore) BulkInsertComparisons(ctx context.Context, tx *sql.Tx, comparisons []domain.ComparisonHistory) error {
+	return errors.New("insert failed")
+}
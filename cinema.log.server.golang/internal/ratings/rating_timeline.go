@@ -0,0 +1,409 @@
+package ratings
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+/*
+Rank timeline
+---
+comparison_histories only says who beat who, so to know how far a comparison moved a film we
+replay the user's history one comparison at a time and rank every film after each step. Every film
+the user has rated is in the ranking from the start at its seeded rating, and a film's first
+comparison is when it really joins the ranking.
+
+Batch engines fit the whole history at once and have no rating "after" a single comparison short of
+refitting at every step, so their timeline is played with elo steps instead. The moves are close
+enough to show what a comparison did without the cost of hundreds of refits.
+
+Rating history and rank changes are derived the same way rather than stored as snapshots, so they
+stay right when a comparison is undone or edited, or the user switches engine.
+*/
+
+// RankMovement is what one comparison did to one of its films
+type RankMovement struct {
+	FilmId          uuid.UUID `json:"filmId"`
+	OldRating       float64   `json:"oldRating"`
+	NewRating       float64   `json:"newRating"`
+	OldRank         int       `json:"oldRank"`
+	NewRank         int       `json:"newRank"`
+	RankChange      int       `json:"rankChange"`      // positive means the film moved up the ranking
+	FirstComparison bool      `json:"firstComparison"` // the film had never been compared before
+}
+
+// rankTimeline replays history through the engine and returns the movement of both films for every
+// comparison, keyed by comparison id. History must be oldest first.
+func rankTimeline(engine RatingEngine, current []domain.UserFilmRatingDetail, history []domain.ComparisonHistory) map[uuid.UUID][]RankMovement {
+	engine = timelineEngine(engine)
+	replayed := seedReplay(engine, current)
+
+	rankOf := func(filmId uuid.UUID) int {
+		rating := replayed[filmId].EloRating
+		rank := 1
+		for _, other := range replayed {
+			if other.EloRating > rating {
+				rank++
+			}
+		}
+		return rank
+	}
+
+	timeline := make(map[uuid.UUID][]RankMovement, len(history))
+	for _, comparison := range history {
+		filmA, okA := replayed[comparison.FilmAId]
+		filmB, okB := replayed[comparison.FilmBId]
+		if !okA || !okB {
+			continue // the rating for one of the films has since been deleted
+		}
+
+		before := []RankMovement{
+			{FilmId: filmA.FilmId, OldRating: filmA.EloRating, OldRank: rankOf(filmA.FilmId), FirstComparison: filmA.NumberOfComparisons == 0},
+			{FilmId: filmB.FilmId, OldRating: filmB.EloRating, OldRank: rankOf(filmB.FilmId), FirstComparison: filmB.NumberOfComparisons == 0},
+		}
+		engine.Rate(replayed, []domain.ComparisonHistory{comparison})
+
+		for i := range before {
+			movement := &before[i]
+			movement.NewRating = replayed[movement.FilmId].EloRating
+			movement.NewRank = rankOf(movement.FilmId)
+			movement.RankChange = movement.OldRank - movement.NewRank
+		}
+		timeline[comparison.ID] = before
+	}
+
+	return timeline
+}
+
+// TopRanking is the user's best films as they stood after a month of comparisons
+type TopRanking struct {
+	Date    time.Time   `json:"date"`    // the month's last comparison
+	FilmIds []uuid.UUID `json:"filmIds"` // best first
+}
+
+// topRankingTimeline replays history through the engine and returns the top n films at the end of
+// every month they changed in. History must be oldest first.
+func topRankingTimeline(engine RatingEngine, current []domain.UserFilmRatingDetail, history []domain.ComparisonHistory, n int) []TopRanking {
+	engine = timelineEngine(engine)
+	replayed := seedReplay(engine, current)
+
+	timeline := []TopRanking{}
+	for i, comparison := range history {
+		_, okA := replayed[comparison.FilmAId]
+		_, okB := replayed[comparison.FilmBId]
+		if okA && okB {
+			engine.Rate(replayed, []domain.ComparisonHistory{comparison})
+		}
+
+		// Only the month's last comparison is looked at
+		if i < len(history)-1 && sameMonth(comparison.ComparisonDate, history[i+1].ComparisonDate) {
+			continue
+		}
+
+		top := topFilms(replayed, n)
+		if len(timeline) > 0 && slices.Equal(timeline[len(timeline)-1].FilmIds, top) {
+			continue
+		}
+		timeline = append(timeline, TopRanking{Date: comparison.ComparisonDate, FilmIds: top})
+	}
+
+	return timeline
+}
+
+// timelineEngine is the engine a timeline is played with, batch engines are swapped for elo steps
+func timelineEngine(engine RatingEngine) RatingEngine {
+	if engine.Batch() {
+		return eloEngine{}
+	}
+	return engine
+}
+
+// seedReplay starts a replay with every film at its seeded rating
+func seedReplay(engine RatingEngine, current []domain.UserFilmRatingDetail) map[uuid.UUID]*domain.UserFilmRating {
+	replayed := make(map[uuid.UUID]*domain.UserFilmRating, len(current))
+	for _, detail := range current {
+		rating := engine.Seed(detail.Rating)
+		replayed[rating.FilmId] = &rating
+	}
+	return replayed
+}
+
+// topFilms returns the ids of the n highest rated films, ties broken by id so a replay always gives the same order
+func topFilms(replayed map[uuid.UUID]*domain.UserFilmRating, n int) []uuid.UUID {
+	ratings := make([]*domain.UserFilmRating, 0, len(replayed))
+	for _, rating := range replayed {
+		ratings = append(ratings, rating)
+	}
+	slices.SortFunc(ratings, func(a, b *domain.UserFilmRating) int {
+		if a.EloRating != b.EloRating {
+			if a.EloRating > b.EloRating {
+				return -1
+			}
+			return 1
+		}
+		return slices.Compare(a.FilmId[:], b.FilmId[:])
+	})
+
+	top := make([]uuid.UUID, 0, n)
+	for _, rating := range ratings[:min(n, len(ratings))] {
+		top = append(top, rating.FilmId)
+	}
+	return top
+}
+
+func sameMonth(a, b time.Time) bool {
+	a, b = a.UTC(), b.UTC()
+	return a.Year() == b.Year() && a.Month() == b.Month()
+}
+
+// RatingPoint is where a film stood after one of its comparisons
+type RatingPoint struct {
+	ComparisonId uuid.UUID `json:"comparisonId"`
+	Date         time.Time `json:"date"`
+	OpponentId   uuid.UUID `json:"opponentId"`
+	Result       string    `json:"result"` // won, lost or drew
+	Rating       float64   `json:"rating"`
+	Rank         int       `json:"rank"`
+	RankChange   int       `json:"rankChange"` // positive means the film moved up the ranking
+}
+
+// Results of a comparison from one film's point of view
+const (
+	ResultWon  = "won"
+	ResultLost = "lost"
+	ResultDrew = "drew"
+)
+
+// FilmRatingHistory is a film's trajectory through the user's ranking, one point per comparison
+type FilmRatingHistory struct {
+	FilmId        uuid.UUID     `json:"filmId"`
+	FilmTitle     string        `json:"filmTitle"`
+	StartRating   float64       `json:"startRating"` // the rating the film was seeded with
+	CurrentRating float64       `json:"currentRating"`
+	Points        []RatingPoint `json:"points"` // oldest first
+}
+
+// filmRatingHistory picks filmId's movements out of the timeline. History must be oldest first.
+func filmRatingHistory(engine RatingEngine, current []domain.UserFilmRatingDetail, history []domain.ComparisonHistory, detail domain.UserFilmRatingDetail) *FilmRatingHistory {
+	filmId := detail.Rating.FilmId
+	filmHistory := &FilmRatingHistory{
+		FilmId:        filmId,
+		FilmTitle:     detail.FilmTitle,
+		StartRating:   timelineEngine(engine).Seed(detail.Rating).EloRating,
+		CurrentRating: detail.Rating.EloRating,
+		Points:        []RatingPoint{},
+	}
+
+	timeline := rankTimeline(engine, current, history)
+	for _, comparison := range history {
+		if comparison.FilmAId != filmId && comparison.FilmBId != filmId {
+			continue
+		}
+
+		for _, movement := range timeline[comparison.ID] {
+			if movement.FilmId != filmId {
+				continue
+			}
+			point := RatingPoint{
+				ComparisonId: comparison.ID,
+				Date:         comparison.ComparisonDate,
+				OpponentId:   comparison.FilmAId,
+				Result:       ResultLost,
+				Rating:       movement.NewRating,
+				Rank:         movement.NewRank,
+				RankChange:   movement.RankChange,
+			}
+			if point.OpponentId == filmId {
+				point.OpponentId = comparison.FilmBId
+			}
+			if comparison.WasEqual {
+				point.Result = ResultDrew
+			} else if comparison.WinningFilmId == filmId {
+				point.Result = ResultWon
+			}
+			filmHistory.Points = append(filmHistory.Points, point)
+		}
+	}
+
+	return filmHistory
+}
+
+// FilmRankChange is how far a film moved in the user's ranking over a period
+type FilmRankChange struct {
+	FilmId          uuid.UUID `json:"filmId"`
+	FilmTitle       string    `json:"filmTitle"`
+	FilmPosterURL   string    `json:"filmPosterUrl"`
+	OldRating       float64   `json:"oldRating"`
+	NewRating       float64   `json:"newRating"`
+	OldRank         int       `json:"oldRank"`
+	NewRank         int       `json:"newRank"`
+	RankChange      int       `json:"rankChange"`      // positive means the film moved up the ranking
+	Comparisons     int       `json:"comparisons"`     // in the period
+	FirstComparison bool      `json:"firstComparison"` // the film hadn't been compared before the period
+}
+
+// rankChangesSince replays history and compares the whole ranking as it stood at since with how it
+// stands now. Films that moved or were compared since are returned, biggest movers first. History
+// must be oldest first.
+func rankChangesSince(engine RatingEngine, current []domain.UserFilmRatingDetail, history []domain.ComparisonHistory, since time.Time) []FilmRankChange {
+	engine = timelineEngine(engine)
+	replayed := seedReplay(engine, current)
+
+	rate := func(comparison domain.ComparisonHistory) bool {
+		_, okA := replayed[comparison.FilmAId]
+		_, okB := replayed[comparison.FilmBId]
+		if okA && okB {
+			engine.Rate(replayed, []domain.ComparisonHistory{comparison})
+		}
+		return okA && okB
+	}
+
+	i := 0
+	for ; i < len(history) && history[i].ComparisonDate.Before(since); i++ {
+		rate(history[i])
+	}
+
+	oldRanks := rankings(replayed)
+	changes := make(map[uuid.UUID]*FilmRankChange, len(current))
+	for _, detail := range current {
+		rating := replayed[detail.Rating.FilmId]
+		changes[rating.FilmId] = &FilmRankChange{
+			FilmId:          rating.FilmId,
+			FilmTitle:       detail.FilmTitle,
+			FilmPosterURL:   detail.FilmPosterURL,
+			OldRating:       rating.EloRating,
+			OldRank:         oldRanks[rating.FilmId],
+			FirstComparison: rating.NumberOfComparisons == 0,
+		}
+	}
+
+	for ; i < len(history); i++ {
+		if rate(history[i]) {
+			changes[history[i].FilmAId].Comparisons++
+			changes[history[i].FilmBId].Comparisons++
+		}
+	}
+
+	newRanks := rankings(replayed)
+	moved := []FilmRankChange{}
+	for filmId, change := range changes {
+		change.NewRating = replayed[filmId].EloRating
+		change.NewRank = newRanks[filmId]
+		change.RankChange = change.OldRank - change.NewRank
+		if change.RankChange == 0 && change.Comparisons == 0 {
+			continue
+		}
+		if change.Comparisons == 0 {
+			change.FirstComparison = false
+		}
+		moved = append(moved, *change)
+	}
+
+	slices.SortFunc(moved, func(a, b FilmRankChange) int {
+		if abs(a.RankChange) != abs(b.RankChange) {
+			return abs(b.RankChange) - abs(a.RankChange)
+		}
+		return a.NewRank - b.NewRank
+	})
+	return moved
+}
+
+// rankings ranks every replayed film, films with the same rating share a rank
+func rankings(replayed map[uuid.UUID]*domain.UserFilmRating) map[uuid.UUID]int {
+	ratings := make([]*domain.UserFilmRating, 0, len(replayed))
+	for _, rating := range replayed {
+		ratings = append(ratings, rating)
+	}
+	slices.SortFunc(ratings, func(a, b *domain.UserFilmRating) int {
+		return cmp.Compare(b.EloRating, a.EloRating)
+	})
+
+	ranks := make(map[uuid.UUID]int, len(ratings))
+	for i, rating := range ratings {
+		if i > 0 && rating.EloRating == ratings[i-1].EloRating {
+			ranks[rating.FilmId] = ranks[ratings[i-1].FilmId]
+		} else {
+			ranks[rating.FilmId] = i + 1
+		}
+	}
+	return ranks
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// GetFilmRatingHistory replays the user's comparisons and returns how filmId's rating and rank moved with each of its own
+func (s Service) GetFilmRatingHistory(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (*FilmRatingHistory, error) {
+	engine, err := s.engineFor(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	current, history, err := s.loadRatingsAndHistory(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.IndexFunc(current, func(detail domain.UserFilmRatingDetail) bool {
+		return detail.Rating.FilmId == filmId
+	})
+	if index < 0 {
+		return nil, ErrRatingNotFound
+	}
+
+	return filmRatingHistory(engine, current, history, current[index]), nil
+}
+
+// GetRankChanges replays the user's comparisons and returns how far films moved in their ranking since a time, biggest movers first
+func (s Service) GetRankChanges(ctx context.Context, userId uuid.UUID, since time.Time) ([]FilmRankChange, error) {
+	engine, err := s.engineFor(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	current, history, err := s.loadRatingsAndHistory(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankChangesSince(engine, current, history, since), nil
+}
+
+// GetTopRankingHistory replays the user's comparisons and returns their top n films at the end of every month they changed in
+func (s Service) GetTopRankingHistory(ctx context.Context, userId uuid.UUID, n int) ([]TopRanking, error) {
+	engine, err := s.engineFor(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	current, history, err := s.loadRatingsAndHistory(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return topRankingTimeline(engine, current, history, n), nil
+}
+
+// GetRankMovements replays the user's comparisons and returns how each one moved its two films, keyed by comparison id
+func (s Service) GetRankMovements(ctx context.Context, userId uuid.UUID) (map[uuid.UUID][]RankMovement, error) {
+	engine, err := s.engineFor(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	current, history, err := s.loadRatingsAndHistory(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankTimeline(engine, current, history), nil
+}
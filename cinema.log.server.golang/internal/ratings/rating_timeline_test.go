@@ -0,0 +1,169 @@
+package ratings
+
+import (
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestRankTimeline(t *testing.T) {
+	// Seeded at 1050, 1000 and 950, so the last film starts at the bottom
+	ratings := []domain.UserFilmRatingDetail{
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 3}},
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 2}},
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 1}},
+	}
+	top, middle, bottom := ratings[0].Rating.FilmId, ratings[1].Rating.FilmId, ratings[2].Rating.FilmId
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	history := []domain.ComparisonHistory{
+		win(bottom, middle, start),
+		win(bottom, top, start.Add(time.Hour)),
+		win(bottom, top, start.Add(2*time.Hour)),
+	}
+	timeline := rankTimeline(eloEngine{}, ratings, history)
+
+	first := timeline[history[0].ID]
+	if len(first) != 2 || first[0].FilmId != bottom || first[1].FilmId != middle {
+		t.Fatalf("expected a movement for both films, got %+v", first)
+	}
+	if !first[0].FirstComparison || !first[1].FirstComparison {
+		t.Error("expected both films to be on their first comparison")
+	}
+	if first[0].OldRating != 950 || first[0].NewRating <= first[0].OldRating || first[0].RankChange != 0 {
+		t.Errorf("expected the winner to gain rating without passing anyone, got %+v", first[0])
+	}
+
+	second := timeline[history[1].ID]
+	if second[0].FirstComparison || !second[1].FirstComparison {
+		t.Error("expected only the top film to be on its first comparison")
+	}
+	if second[0].OldRank != 3 || second[0].NewRank != 2 || second[0].RankChange != 1 {
+		t.Errorf("expected the winner to pass the middle film, got %+v", second[0])
+	}
+
+	third := timeline[history[2].ID]
+	if third[0].NewRank != 1 || third[1].NewRank != 2 || third[1].RankChange != -1 {
+		t.Errorf("expected the films to swap places at the top, got %+v", third)
+	}
+}
+
+func TestRankTimeline_SkipsDeletedFilms(t *testing.T) {
+	ratings := []domain.UserFilmRatingDetail{{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 3}}}
+	comparison := win(ratings[0].Rating.FilmId, uuid.New(), time.Now())
+
+	timeline := rankTimeline(bradleyTerryEngine{}, ratings, []domain.ComparisonHistory{comparison})
+
+	if _, ok := timeline[comparison.ID]; ok {
+		t.Error("expected a comparison against a deleted rating to be skipped")
+	}
+}
+
+func TestTopRankingTimeline(t *testing.T) {
+	ratings := []domain.UserFilmRatingDetail{
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 3}},
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 2}},
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 1}},
+	}
+	top, middle, bottom := ratings[0].Rating.FilmId, ratings[1].Rating.FilmId, ratings[2].Rating.FilmId
+	january := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	february, march := january.AddDate(0, 1, 0), january.AddDate(0, 2, 0)
+
+	history := []domain.ComparisonHistory{
+		win(top, middle, january),
+		win(top, bottom, january.Add(time.Hour)),
+		// The bottom film passes the middle one in February and nothing changes in March
+		win(bottom, middle, february),
+		win(bottom, middle, february.Add(time.Hour)),
+		win(top, middle, march),
+	}
+	timeline := topRankingTimeline(eloEngine{}, ratings, history, 2)
+
+	if len(timeline) != 2 {
+		t.Fatalf("expected a snapshot for January and February only, got %+v", timeline)
+	}
+	if !timeline[0].Date.Equal(january.Add(time.Hour)) || timeline[0].FilmIds[0] != top || timeline[0].FilmIds[1] != middle {
+		t.Errorf("expected January to end with the seeded order, got %+v", timeline[0])
+	}
+	if !timeline[1].Date.Equal(february.Add(time.Hour)) || timeline[1].FilmIds[1] != bottom {
+		t.Errorf("expected the bottom film in the top 2 by the end of February, got %+v", timeline[1])
+	}
+}
+
+func TestFilmRatingHistory(t *testing.T) {
+	ratings := []domain.UserFilmRatingDetail{
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 3}},
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 2}},
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 1, EloRating: 1010}, FilmTitle: "Bottom"},
+	}
+	top, middle, bottom := ratings[0].Rating.FilmId, ratings[1].Rating.FilmId, ratings[2].Rating.FilmId
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tie := win(middle, top, start.Add(time.Hour))
+	tie.WasEqual = true
+	history := []domain.ComparisonHistory{
+		win(bottom, middle, start),
+		tie,
+		win(top, bottom, start.Add(2*time.Hour)),
+	}
+	filmHistory := filmRatingHistory(eloEngine{}, ratings, history, ratings[2])
+
+	if filmHistory.StartRating != 950 || filmHistory.CurrentRating != 1010 || filmHistory.FilmTitle != "Bottom" {
+		t.Errorf("unexpected history %+v", filmHistory)
+	}
+	if len(filmHistory.Points) != 2 {
+		t.Fatalf("expected a point for each of the film's comparisons, got %+v", filmHistory.Points)
+	}
+	first, second := filmHistory.Points[0], filmHistory.Points[1]
+	if first.Result != ResultWon || first.OpponentId != middle || first.Rating <= 950 {
+		t.Errorf("expected a win over the middle film, got %+v", first)
+	}
+	if second.Result != ResultLost || second.OpponentId != top || second.Rating >= first.Rating {
+		t.Errorf("expected a loss to the top film, got %+v", second)
+	}
+}
+
+func TestRankChangesSince(t *testing.T) {
+	ratings := []domain.UserFilmRatingDetail{
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 3}},
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 2}},
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 1}},
+		{Rating: domain.UserFilmRating{FilmId: uuid.New(), InitialRating: 5}},
+	}
+	top, middle, bottom, unwatched := ratings[0].Rating.FilmId, ratings[1].Rating.FilmId, ratings[2].Rating.FilmId, ratings[3].Rating.FilmId
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	since := start.AddDate(0, 1, 0)
+
+	history := []domain.ComparisonHistory{
+		win(top, middle, start),
+		// The bottom film passes the middle one after since, the never compared film stays first
+		win(bottom, middle, since),
+		win(bottom, top, since.Add(time.Hour)),
+		win(bottom, top, since.Add(2*time.Hour)),
+	}
+	changes := rankChangesSince(eloEngine{}, ratings, history, since)
+
+	if len(changes) != 3 {
+		t.Fatalf("expected the three compared films, got %+v", changes)
+	}
+	if changes[0].FilmId != bottom || changes[0].OldRank != 4 || changes[0].NewRank != 3 || changes[0].RankChange != 1 {
+		t.Errorf("expected the bottom film to be the biggest mover, got %+v", changes[0])
+	}
+	if !changes[0].FirstComparison || changes[0].Comparisons != 3 {
+		t.Errorf("expected three first comparisons for the bottom film, got %+v", changes[0])
+	}
+	for _, change := range changes {
+		if change.FilmId == unwatched {
+			t.Errorf("expected a film that wasn't compared and didn't move to be left out, got %+v", change)
+		}
+		if change.FilmId == top && change.FirstComparison {
+			t.Error("expected the top film to have been compared before since")
+		}
+	}
+
+	if changes := rankChangesSince(eloEngine{}, ratings, history, since.AddDate(1, 0, 0)); len(changes) != 0 {
+		t.Errorf("expected nothing to change after the last comparison, got %+v", changes)
+	}
+}
@@ -0,0 +1,209 @@
+package recommender
+
+import (
+	"math"
+	"sort"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+/*
+Item-item collaborative filtering
+---
+Every user's elo ratings are on their own scale, so each user's ratings are turned into z-scores
+(how many standard deviations above or below their own average a film sits) before anything else.
+A z-score of +1.5 means "one of my favourites" whoever the user is.
+
+Two films are similar when the users who rated both put them on the same side of their average by
+similar amounts, which is the cosine of the two films' z-score vectors over the users who rated both.
+Similarities from only a few shared raters are shrunk towards zero, so two films that happen to be
+loved by the same two people don't outrank films that fifty people agree on.
+
+To recommend for a user, every film they haven't rated is scored from the similar films they have
+rated: a weighted average of the user's z-scores for those films, weighted by similarity. Films that
+sit next to the user's favourites score high, films next to the ones they ranked low score negative.
+*/
+
+const (
+	// Two films need at least this many users in common before their similarity counts
+	minCoRaters = 2
+	// Similarity is scaled by coRaters / (coRaters + similarityShrinkage)
+	similarityShrinkage = 5.0
+	// Only the most similar films are kept for each film
+	neighboursPerFilm = 30
+)
+
+type neighbour struct {
+	filmId     uuid.UUID
+	similarity float64
+}
+
+// model is a trained snapshot of which films are similar to which
+type model struct {
+	neighbours map[uuid.UUID][]neighbour
+	users      int
+	films      int
+}
+
+type pairKey struct {
+	a, b uuid.UUID
+}
+
+type pairSums struct {
+	dot, squaresA, squaresB float64
+	coRaters                int
+}
+
+// zScores turns one user's elo ratings into z-scores. Users with fewer than two ratings, or every
+// film on the same rating, say nothing about preference and get nothing back.
+func zScores(ratings []domain.UserFilmRating) map[uuid.UUID]float64 {
+	if len(ratings) < 2 {
+		return nil
+	}
+
+	var mean float64
+	for _, rating := range ratings {
+		mean += rating.EloRating
+	}
+	mean /= float64(len(ratings))
+
+	var variance float64
+	for _, rating := range ratings {
+		variance += (rating.EloRating - mean) * (rating.EloRating - mean)
+	}
+	deviation := math.Sqrt(variance / float64(len(ratings)))
+	if deviation == 0 {
+		return nil
+	}
+
+	scores := make(map[uuid.UUID]float64, len(ratings))
+	for _, rating := range ratings {
+		scores[rating.FilmId] = (rating.EloRating - mean) / deviation
+	}
+	return scores
+}
+
+// trainModel builds the film similarities from every user's ratings
+func trainModel(ratings []domain.UserFilmRating) *model {
+	byUser := map[uuid.UUID][]domain.UserFilmRating{}
+	for _, rating := range ratings {
+		byUser[rating.UserId] = append(byUser[rating.UserId], rating)
+	}
+
+	pairs := map[pairKey]*pairSums{}
+	films := map[uuid.UUID]bool{}
+	users := 0
+	for _, userRatings := range byUser {
+		scores := zScores(userRatings)
+		if scores == nil {
+			continue
+		}
+		users++
+
+		filmIds := make([]uuid.UUID, 0, len(scores))
+		for filmId := range scores {
+			filmIds = append(filmIds, filmId)
+			films[filmId] = true
+		}
+		for i := range filmIds {
+			for j := i + 1; j < len(filmIds); j++ {
+				a, b := filmIds[i], filmIds[j]
+				if a.String() > b.String() {
+					a, b = b, a
+				}
+				sums, ok := pairs[pairKey{a, b}]
+				if !ok {
+					sums = &pairSums{}
+					pairs[pairKey{a, b}] = sums
+				}
+				sums.dot += scores[a] * scores[b]
+				sums.squaresA += scores[a] * scores[a]
+				sums.squaresB += scores[b] * scores[b]
+				sums.coRaters++
+			}
+		}
+	}
+
+	neighbours := map[uuid.UUID][]neighbour{}
+	for key, sums := range pairs {
+		if sums.coRaters < minCoRaters || sums.squaresA == 0 || sums.squaresB == 0 {
+			continue
+		}
+		similarity := sums.dot / math.Sqrt(sums.squaresA*sums.squaresB)
+		similarity *= float64(sums.coRaters) / (float64(sums.coRaters) + similarityShrinkage)
+		if similarity <= 0 {
+			continue // only films people like together are useful for recommending
+		}
+		neighbours[key.a] = append(neighbours[key.a], neighbour{filmId: key.b, similarity: similarity})
+		neighbours[key.b] = append(neighbours[key.b], neighbour{filmId: key.a, similarity: similarity})
+	}
+
+	for filmId, filmNeighbours := range neighbours {
+		sort.Slice(filmNeighbours, func(i, j int) bool {
+			return filmNeighbours[i].similarity > filmNeighbours[j].similarity
+		})
+		if len(filmNeighbours) > neighboursPerFilm {
+			neighbours[filmId] = filmNeighbours[:neighboursPerFilm]
+		}
+	}
+
+	return &model{neighbours: neighbours, users: users, films: len(films)}
+}
+
+// contribution is how much one of the user's rated films pushed a candidate's score
+type contribution struct {
+	filmId uuid.UUID
+	weight float64 // similarity times the user's z-score for the rated film
+}
+
+type prediction struct {
+	filmId        uuid.UUID
+	score         float64
+	contributions []contribution
+}
+
+// predict scores the films the user hasn't rated from the similar films they have. Only films
+// predicted above the user's average are returned, best first, at most limit of them.
+func (m *model) predict(scores map[uuid.UUID]float64, limit int) []prediction {
+	weighted := map[uuid.UUID]float64{}
+	similarityTotals := map[uuid.UUID]float64{}
+	contributions := map[uuid.UUID][]contribution{}
+
+	for ratedId, score := range scores {
+		for _, n := range m.neighbours[ratedId] {
+			if _, rated := scores[n.filmId]; rated {
+				continue
+			}
+			weighted[n.filmId] += n.similarity * score
+			similarityTotals[n.filmId] += n.similarity
+			contributions[n.filmId] = append(contributions[n.filmId], contribution{filmId: ratedId, weight: n.similarity * score})
+		}
+	}
+
+	predictions := []prediction{}
+	for filmId, total := range similarityTotals {
+		// The weighted average is weighted / total, on its own that favours films next to a single
+		// liked film. Scaling it by total / (total + 1) lets films backed by several similar films come first.
+		score := weighted[filmId] / (total + 1)
+		if score <= 0 {
+			continue
+		}
+		filmContributions := contributions[filmId]
+		sort.Slice(filmContributions, func(i, j int) bool {
+			return filmContributions[i].weight > filmContributions[j].weight
+		})
+		predictions = append(predictions, prediction{filmId: filmId, score: score, contributions: filmContributions})
+	}
+
+	sort.Slice(predictions, func(i, j int) bool {
+		if predictions[i].score != predictions[j].score {
+			return predictions[i].score > predictions[j].score
+		}
+		return predictions[i].filmId.String() < predictions[j].filmId.String()
+	})
+	if len(predictions) > limit {
+		predictions = predictions[:limit]
+	}
+	return predictions
+}
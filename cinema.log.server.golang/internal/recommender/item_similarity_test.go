@@ -0,0 +1,98 @@
+package recommender
+
+import (
+	"math"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+func rate(userId uuid.UUID, filmId uuid.UUID, elo float64) domain.UserFilmRating {
+	return domain.UserFilmRating{UserId: userId, FilmId: filmId, EloRating: elo}
+}
+
+func TestZScores(t *testing.T) {
+	userId, a, b := uuid.New(), uuid.New(), uuid.New()
+
+	scores := zScores([]domain.UserFilmRating{rate(userId, a, 1100), rate(userId, b, 900)})
+	if math.Abs(scores[a]-1) > 1e-9 || math.Abs(scores[b]+1) > 1e-9 {
+		t.Errorf("expected z-scores of 1 and -1, got %v", scores)
+	}
+
+	if scores := zScores([]domain.UserFilmRating{rate(userId, a, 1100)}); scores != nil {
+		t.Errorf("expected nothing from a single rating, got %v", scores)
+	}
+	if scores := zScores([]domain.UserFilmRating{rate(userId, a, 1000), rate(userId, b, 1000)}); scores != nil {
+		t.Errorf("expected nothing when every film is tied, got %v", scores)
+	}
+}
+
+func TestTrainModel_AndPredict(t *testing.T) {
+	// Three users love the two heist films and dislike the romcom. A fourth has only seen one heist
+	// film and the romcom, the other heist film should be recommended and nothing else.
+	heistA, heistB, romcom, filler := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	ratings := []domain.UserFilmRating{}
+	for _, elo := range []float64{0, 30, 60} {
+		userId := uuid.New()
+		ratings = append(ratings,
+			rate(userId, heistA, 1200+elo),
+			rate(userId, heistB, 1180+elo),
+			rate(userId, romcom, 850),
+			rate(userId, filler, 1000),
+		)
+	}
+
+	trained := trainModel(ratings)
+	if trained.users != 3 || trained.films != 4 {
+		t.Errorf("expected 3 users and 4 films, got %d and %d", trained.users, trained.films)
+	}
+
+	similarToHeist := trained.neighbours[heistA]
+	if len(similarToHeist) == 0 || similarToHeist[0].filmId != heistB {
+		t.Fatalf("expected the other heist film to be the most similar, got %+v", similarToHeist)
+	}
+	for _, n := range trained.neighbours[heistA] {
+		if n.filmId == romcom {
+			t.Error("expected films liked in opposite ways not to be neighbours")
+		}
+	}
+
+	newcomer := uuid.New()
+	scores := zScores([]domain.UserFilmRating{rate(newcomer, heistA, 1150), rate(newcomer, romcom, 900)})
+	predictions := trained.predict(scores, 10)
+
+	if len(predictions) == 0 || predictions[0].filmId != heistB {
+		t.Fatalf("expected the other heist film first, got %+v", predictions)
+	}
+	for _, p := range predictions {
+		if p.filmId == heistA || p.filmId == romcom {
+			t.Errorf("expected rated films not to be recommended, got %v", p.filmId)
+		}
+		if p.score <= 0 {
+			t.Errorf("expected only positive predictions, got %f", p.score)
+		}
+	}
+	if predictions[0].contributions[0].filmId != heistA {
+		t.Errorf("expected the liked heist film to be the reason, got %+v", predictions[0].contributions)
+	}
+}
+
+func TestTrainModel_ShrinksSmallOverlaps(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	ratings := []domain.UserFilmRating{}
+	// a and b are liked together by two users, c and d by six
+	for i := range 6 {
+		userId := uuid.New()
+		ratings = append(ratings, rate(userId, c, 1200), rate(userId, d, 1150), rate(userId, uuid.New(), 900))
+		if i < 2 {
+			ratings = append(ratings, rate(userId, a, 1200), rate(userId, b, 1150))
+		}
+	}
+
+	trained := trainModel(ratings)
+	if trained.neighbours[a][0].similarity >= trained.neighbours[c][0].similarity {
+		t.Errorf("expected the similarity from two users to be shrunk below the one from six, got %f and %f",
+			trained.neighbours[a][0].similarity, trained.neighbours[c][0].similarity)
+	}
+}
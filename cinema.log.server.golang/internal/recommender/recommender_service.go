@@ -0,0 +1,193 @@
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+const (
+	// How often the similarities are rebuilt from everyone's latest ratings
+	RetrainInterval = 6 * time.Hour
+	// How many of the user's films a reason names
+	reasonFilms = 2
+)
+
+type Service struct {
+	RecommenderStore RecommenderStore
+
+	mu    sync.RWMutex
+	model *model
+}
+
+type RecommenderStore interface {
+	GetAllRatings(ctx context.Context) ([]domain.UserFilmRating, error)
+	GetUserRatings(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRating, error)
+	GetFilmsByIds(ctx context.Context, filmIds []uuid.UUID) ([]domain.Film, error)
+}
+
+func NewService(recommenderStore RecommenderStore) *Service {
+	return &Service{
+		RecommenderStore: recommenderStore,
+	}
+}
+
+// Train rebuilds the film similarities from every user's ratings. Recommendations keep using the
+// previous model until the new one is ready.
+func (s *Service) Train(ctx context.Context) error {
+	ratings, err := s.RecommenderStore.GetAllRatings(ctx)
+	if err != nil {
+		return err
+	}
+
+	trained := trainModel(ratings)
+
+	s.mu.Lock()
+	s.model = trained
+	s.mu.Unlock()
+
+	log.Printf("Trained recommender on %d users and %d films", trained.users, trained.films)
+	return nil
+}
+
+// Start trains the model straight away and then every interval until ctx is done
+func (s *Service) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.Train(ctx); err != nil {
+				log.Printf("Failed to train recommender: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Recommend returns up to limit films the user hasn't rated, best first, each with the films they
+// rated that led to it. Users who haven't rated enough films to have a taste get nothing back.
+func (s *Service) Recommend(ctx context.Context, userId uuid.UUID, limit int) ([]domain.RecommendedFilm, error) {
+	trained, err := s.currentModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user's own ratings are read fresh so films rated since the last training aren't recommended
+	ratings, err := s.RecommenderStore.GetUserRatings(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	scores := zScores(ratings)
+	if scores == nil {
+		return []domain.RecommendedFilm{}, nil
+	}
+	eloRatings := make(map[uuid.UUID]float64, len(ratings))
+	for _, rating := range ratings {
+		eloRatings[rating.FilmId] = rating.EloRating
+	}
+
+	predictions := trained.predict(scores, limit)
+	if len(predictions) == 0 {
+		return []domain.RecommendedFilm{}, nil
+	}
+
+	filmIds := []uuid.UUID{}
+	for _, p := range predictions {
+		filmIds = append(filmIds, p.filmId)
+		for _, c := range topContributions(p.contributions) {
+			filmIds = append(filmIds, c.filmId)
+		}
+	}
+	films, err := s.RecommenderStore.GetFilmsByIds(ctx, filmIds)
+	if err != nil {
+		return nil, err
+	}
+	filmsById := make(map[uuid.UUID]domain.Film, len(films))
+	for _, film := range films {
+		filmsById[film.ID] = film
+	}
+
+	recommended := make([]domain.RecommendedFilm, 0, len(predictions))
+	for _, p := range predictions {
+		film, ok := filmsById[p.filmId]
+		if !ok {
+			continue // deleted since the model was trained
+		}
+
+		basedOn := []domain.RecommendedFrom{}
+		titles := []string{}
+		for _, c := range topContributions(p.contributions) {
+			if rated, ok := filmsById[c.filmId]; ok {
+				eloRating := eloRatings[rated.ID]
+				basedOn = append(basedOn, domain.RecommendedFrom{FilmID: rated.ID, Title: rated.Title, EloRating: &eloRating})
+				titles = append(titles, rated.Title)
+			}
+		}
+
+		recommended = append(recommended, domain.RecommendedFilm{
+			Film:  film,
+			Score: p.score,
+			Reasons: []domain.RecommendationReason{{
+				Source:      domain.RecommendationSourceCinemaLog,
+				Detail:      fmt.Sprintf("People who rank %s highly rank this highly too", strings.Join(titles, " and ")),
+				SeedsAgreed: agreeingFilms(p.contributions),
+				BasedOn:     basedOn,
+			}},
+		})
+	}
+
+	return recommended, nil
+}
+
+// currentModel returns the trained model, training one first if nothing has been trained yet
+func (s *Service) currentModel(ctx context.Context) (*model, error) {
+	s.mu.RLock()
+	trained := s.model
+	s.mu.RUnlock()
+	if trained != nil {
+		return trained, nil
+	}
+
+	if err := s.Train(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.model, nil
+}
+
+// agreeingFilms counts the films the user rated that pushed a prediction up
+func agreeingFilms(contributions []contribution) int {
+	agreeing := 0
+	for _, c := range contributions {
+		if c.weight > 0 {
+			agreeing++
+		}
+	}
+	return agreeing
+}
+
+// topContributions is the films the user liked that did most for a prediction, contributions must be sorted
+func topContributions(contributions []contribution) []contribution {
+	top := []contribution{}
+	for _, c := range contributions {
+		if c.weight <= 0 || len(top) == reasonFilms {
+			break
+		}
+		top = append(top, c)
+	}
+	return top
+}
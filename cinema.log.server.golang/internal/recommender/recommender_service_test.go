@@ -0,0 +1,100 @@
+package recommender
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type mockRecommenderStore struct {
+	ratings        []domain.UserFilmRating
+	films          map[uuid.UUID]domain.Film
+	getAllRatings  int
+	requestedFilms []uuid.UUID
+}
+
+func (m *mockRecommenderStore) GetAllRatings(ctx context.Context) ([]domain.UserFilmRating, error) {
+	m.getAllRatings++
+	return m.ratings, nil
+}
+
+func (m *mockRecommenderStore) GetUserRatings(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRating, error) {
+	ratings := []domain.UserFilmRating{}
+	for _, rating := range m.ratings {
+		if rating.UserId == userId {
+			ratings = append(ratings, rating)
+		}
+	}
+	return ratings, nil
+}
+
+func (m *mockRecommenderStore) GetFilmsByIds(ctx context.Context, filmIds []uuid.UUID) ([]domain.Film, error) {
+	m.requestedFilms = filmIds
+	films := []domain.Film{}
+	for _, filmId := range filmIds {
+		if film, ok := m.films[filmId]; ok {
+			films = append(films, film)
+		}
+	}
+	return films, nil
+}
+
+func newFilm(store *mockRecommenderStore, title string, externalId int) uuid.UUID {
+	filmId := uuid.New()
+	store.films[filmId] = domain.Film{ID: filmId, ExternalID: externalId, Title: title}
+	return filmId
+}
+
+func TestService_Recommend(t *testing.T) {
+	store := &mockRecommenderStore{films: map[uuid.UUID]domain.Film{}}
+	heat, ronin, notebook := newFilm(store, "Heat", 949), newFilm(store, "Ronin", 8195), newFilm(store, "The Notebook", 11036)
+	for _, elo := range []float64{0, 25, 50} {
+		userId := uuid.New()
+		store.ratings = append(store.ratings, rate(userId, heat, 1200+elo), rate(userId, ronin, 1150+elo), rate(userId, notebook, 900))
+	}
+	me := uuid.New()
+	store.ratings = append(store.ratings, rate(me, heat, 1150), rate(me, notebook, 950))
+	service := NewService(store)
+
+	recommended, err := service.Recommend(context.Background(), me, 5)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(recommended) != 1 || recommended[0].ID != ronin || recommended[0].ExternalID != 8195 {
+		t.Fatalf("expected only Ronin, got %+v", recommended)
+	}
+
+	reason := recommended[0].Reasons[0]
+	if reason.Source != domain.RecommendationSourceCinemaLog || len(reason.BasedOn) != 1 || reason.BasedOn[0].FilmID != heat {
+		t.Errorf("expected Heat as the reason, got %+v", reason)
+	}
+	if reason.SeedsAgreed != 1 || reason.BasedOn[0].EloRating == nil || *reason.BasedOn[0].EloRating != 1150 {
+		t.Errorf("expected Heat's elo with one agreeing film, got %+v", reason)
+	}
+	if !strings.Contains(reason.Detail, "Heat") || recommended[0].Score <= 0 {
+		t.Errorf("unexpected reason %q with score %f", reason.Detail, recommended[0].Score)
+	}
+
+	// The model is trained once and reused
+	if _, err := service.Recommend(context.Background(), me, 5); err != nil || store.getAllRatings != 1 {
+		t.Errorf("expected the model to be trained once, got %d trainings and %v", store.getAllRatings, err)
+	}
+}
+
+func TestService_Recommend_NoTaste(t *testing.T) {
+	store := &mockRecommenderStore{films: map[uuid.UUID]domain.Film{}}
+	me := uuid.New()
+	store.ratings = []domain.UserFilmRating{rate(me, newFilm(store, "Heat", 949), 1100)}
+	service := NewService(store)
+
+	recommended, err := service.Recommend(context.Background(), me, 5)
+	if err != nil || len(recommended) != 0 {
+		t.Errorf("expected no recommendations from a single rating, got %+v, %v", recommended, err)
+	}
+	if store.requestedFilms != nil {
+		t.Error("expected no films to be looked up")
+	}
+}
@@ -0,0 +1,99 @@
+package recommender
+
+import (
+	"context"
+	"database/sql"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) RecommenderStore {
+	return &store{
+		db: db,
+	}
+}
+
+// GetAllRatings returns every user's elo rating of every film, only the fields the recommender trains on are filled
+func (s *store) GetAllRatings(ctx context.Context) ([]domain.UserFilmRating, error) {
+	query := /* sql */ `
+		SELECT user_id, film_id, elo_rating
+		FROM user_film_ratings
+	`
+
+	return s.queryRatings(ctx, query)
+}
+
+func (s *store) GetUserRatings(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRating, error) {
+	query := /* sql */ `
+		SELECT user_id, film_id, elo_rating
+		FROM user_film_ratings
+		WHERE user_id = $1
+	`
+
+	return s.queryRatings(ctx, query, userId)
+}
+
+func (s *store) GetFilmsByIds(ctx context.Context, filmIds []uuid.UUID) ([]domain.Film, error) {
+	query := /* sql */ `
+		SELECT film_id, external_id, title, COALESCE(description, ''), COALESCE(poster_url, ''), COALESCE(release_year, '')
+		FROM films
+		WHERE film_id = ANY($1)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, filmIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	films := []domain.Film{}
+	for rows.Next() {
+		var film domain.Film
+		err := rows.Scan(
+			&film.ID,
+			&film.ExternalID,
+			&film.Title,
+			&film.Description,
+			&film.PosterUrl,
+			&film.ReleaseYear,
+		)
+		if err != nil {
+			return nil, err
+		}
+		films = append(films, film)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return films, nil
+}
+
+func (s *store) queryRatings(ctx context.Context, query string, args ...any) ([]domain.UserFilmRating, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []domain.UserFilmRating{}
+	for rows.Next() {
+		var rating domain.UserFilmRating
+		if err := rows.Scan(&rating.UserId, &rating.FilmId, &rating.EloRating); err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, rating)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ratings, nil
+}
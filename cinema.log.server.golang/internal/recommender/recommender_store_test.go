@@ -0,0 +1,100 @@
+package recommender
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	testDB      *sql.DB
+	testStore   RecommenderStore
+	testDbSetup *utils.TestDatabase
+)
+
+// Helper function to create test user
+func createTestUser(ctx context.Context, t *testing.T) uuid.UUID {
+	userID := uuid.New()
+	query := `INSERT INTO users (user_id, name, username, profile_pic_url)
+	          VALUES ($1, $2, $3, $4)`
+	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], "http://example.com/pic.jpg")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return userID
+}
+
+// Helper function to create test film
+func createTestFilm(ctx context.Context, t *testing.T) uuid.UUID {
+	filmID := uuid.New()
+	query := `INSERT INTO films (film_id, external_id, title, description, poster_url, release_year)
+	          VALUES ($1, $2, $3, $4, $5, $6)`
+	externalID := int(time.Now().UnixNano() % 2147483647) // Use nanoseconds for uniqueness
+	_, err := testDB.ExecContext(ctx, query, filmID, externalID, "Test Film "+filmID.String()[:8], "Description", "/poster.jpg", "2024")
+	if err != nil {
+		t.Fatalf("failed to create test film: %v", err)
+	}
+	return filmID
+}
+
+func createTestRating(ctx context.Context, t *testing.T, userId uuid.UUID, filmId uuid.UUID, elo float64) {
+	_, err := testDB.ExecContext(ctx, `INSERT INTO user_film_ratings (user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value)
+		VALUES ($1, $2, $3, $4, 0, NOW(), 3, 40)`, uuid.New(), userId, filmId, elo)
+	if err != nil {
+		t.Fatalf("failed to create rating: %v", err)
+	}
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	testDbSetup, err = utils.StartTestPostgres()
+	if err != nil {
+		log.Fatalf("could not start test database: %v", err)
+	}
+
+	testDB = testDbSetup.DB
+	testStore = NewStore(testDB)
+
+	code := m.Run()
+
+	testDbSetup.Close()
+	os.Exit(code)
+}
+
+func TestRecommenderStore_Ratings(t *testing.T) {
+	ctx := context.Background()
+	me, other := createTestUser(ctx, t), createTestUser(ctx, t)
+	filmA, filmB := createTestFilm(ctx, t), createTestFilm(ctx, t)
+	createTestRating(ctx, t, me, filmA, 1100)
+	createTestRating(ctx, t, other, filmA, 1000)
+	createTestRating(ctx, t, other, filmB, 1050)
+
+	mine, err := testStore.GetUserRatings(ctx, me)
+	if err != nil || len(mine) != 1 || mine[0].FilmId != filmA || mine[0].EloRating != 1100 {
+		t.Errorf("expected my one rating, got %+v, %v", mine, err)
+	}
+
+	all, err := testStore.GetAllRatings(ctx)
+	if err != nil || len(all) < 3 {
+		t.Errorf("expected every rating, got %d, %v", len(all), err)
+	}
+}
+
+func TestRecommenderStore_GetFilmsByIds(t *testing.T) {
+	ctx := context.Background()
+	filmA, filmB := createTestFilm(ctx, t), createTestFilm(ctx, t)
+
+	films, err := testStore.GetFilmsByIds(ctx, []uuid.UUID{filmA, filmB, uuid.New()})
+	if err != nil {
+		t.Fatalf("failed to get films: %v", err)
+	}
+	if len(films) != 2 || films[0].Title == "" || films[0].ExternalID == 0 {
+		t.Errorf("expected both films with their details, got %+v", films)
+	}
+}
@@ -13,10 +13,11 @@ import (
 )
 
 type Handler struct {
-	ReviewService ReviewService
-	RatingService RatingService
-	GraphService  GraphService
-	FilmService   FilmService
+	ReviewService    ReviewService
+	RatingService    RatingService
+	GraphService     GraphService
+	FilmService      FilmService
+	WatchlistService WatchlistService
 }
 
 type ReviewService interface {
@@ -41,12 +42,17 @@ type FilmService interface {
 	GetFilmsFromExternal(ctx context.Context, query string) ([]domain.Film, error)
 }
 
-func NewHandler(reviewService ReviewService, ratingService RatingService, graphService GraphService, filmService FilmService) *Handler {
+type WatchlistService interface {
+	MarkWatched(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (bool, error)
+}
+
+func NewHandler(reviewService ReviewService, ratingService RatingService, graphService GraphService, filmService FilmService, watchlistService WatchlistService) *Handler {
 	return &Handler{
-		ReviewService: reviewService,
-		RatingService: ratingService,
-		GraphService:  graphService,
-		FilmService:   filmService,
+		ReviewService:    reviewService,
+		RatingService:    ratingService,
+		GraphService:     graphService,
+		FilmService:      filmService,
+		WatchlistService: watchlistService,
 	}
 }
 
@@ -113,6 +119,11 @@ func (h *Handler) CreateReview(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// The film has been seen, so it comes off the watchlist
+	if _, err := h.WatchlistService.MarkWatched(r.Context(), user.ID, req.FilmId); err != nil {
+		fmt.Printf("Failed to mark film as watched: %v\n", err)
+	}
+
 	// Get the film details to add to graph
 	film, err := h.FilmService.GetFilmById(r.Context(), req.FilmId)
 	if err != nil {
@@ -107,12 +107,23 @@ func (m *mockFilmService) GetFilmsFromExternal(ctx context.Context, query string
 	return []domain.Film{}, nil
 }
 
+type mockWatchlistService struct {
+	markWatchedFunc func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (bool, error)
+}
+
+func (m *mockWatchlistService) MarkWatched(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (bool, error) {
+	if m.markWatchedFunc != nil {
+		return m.markWatchedFunc(ctx, userId, filmId)
+	}
+	return false, nil
+}
+
 func TestNewHandler(t *testing.T) {
 	mockReviewSvc := &mockReviewService{}
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	if handler == nil {
 		t.Fatal("expected non-nil handler")
@@ -127,7 +138,7 @@ func TestHandler_GetAllReviews_Success(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	userId := uuid.New()
 	req := httptest.NewRequest(http.MethodGet, "/reviews/"+userId.String(), nil)
@@ -146,7 +157,7 @@ func TestHandler_GetAllReviews_InvalidUserId(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	req := httptest.NewRequest(http.MethodGet, "/reviews/invalid", nil)
 	req.SetPathValue("userId", "invalid")
@@ -168,7 +179,7 @@ func TestHandler_GetAllReviews_ServiceError(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	userId := uuid.New()
 	req := httptest.NewRequest(http.MethodGet, "/reviews/"+userId.String(), nil)
@@ -187,7 +198,7 @@ func TestHandler_CreateReview_Success(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	userId := uuid.New()
 	filmId := uuid.New()
@@ -213,12 +224,44 @@ func TestHandler_CreateReview_Success(t *testing.T) {
 	}
 }
 
+func TestHandler_CreateReview_MarksFilmWatched(t *testing.T) {
+	userId := uuid.New()
+	filmId := uuid.New()
+	var watchedUser, watchedFilm uuid.UUID
+	mockWatchlistSvc := &mockWatchlistService{
+		markWatchedFunc: func(ctx context.Context, u uuid.UUID, f uuid.UUID) (bool, error) {
+			watchedUser, watchedFilm = u, f
+			return true, nil
+		},
+	}
+	handler := NewHandler(&mockReviewService{}, &mockRatingService{}, &mockGraphService{}, &mockFilmService{}, mockWatchlistSvc)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"content": "Finally watched it",
+		"rating":  4,
+		"filmId":  filmId.String(),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/reviews", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, &domain.User{ID: userId}))
+	w := httptest.NewRecorder()
+
+	handler.CreateReview(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d, body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if watchedUser != userId || watchedFilm != filmId {
+		t.Errorf("expected film %v to be marked watched for %v, got %v for %v", filmId, userId, watchedFilm, watchedUser)
+	}
+}
+
 func TestHandler_CreateReview_Unauthorized(t *testing.T) {
 	mockReviewSvc := &mockReviewService{}
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	reviewReq := map[string]interface{}{
 		"content": "Great movie!",
@@ -243,7 +286,7 @@ func TestHandler_CreateReview_InvalidJSON(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	userId := uuid.New()
 	user := &domain.User{ID: userId, Name: "Test User", Username: "testuser"}
@@ -266,7 +309,7 @@ func TestHandler_CreateReview_EmptyContent(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	userId := uuid.New()
 	filmId := uuid.New()
@@ -297,7 +340,7 @@ func TestHandler_UpdateReview_Success(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	userId := uuid.New()
 	reviewId := uuid.New()
@@ -333,7 +376,7 @@ func TestHandler_UpdateReview_EmptyContent(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	userId := uuid.New()
 	reviewId := uuid.New()
@@ -369,7 +412,7 @@ func TestHandler_DeleteReview_Success(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	reviewId := uuid.New()
 	req := httptest.NewRequest(http.MethodDelete, "/reviews?id="+reviewId.String(), nil)
@@ -387,7 +430,7 @@ func TestHandler_DeleteReview_MissingReviewId(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	req := httptest.NewRequest(http.MethodDelete, "/reviews", nil)
 	w := httptest.NewRecorder()
@@ -404,7 +447,7 @@ func TestHandler_DeleteReview_InvalidReviewId(t *testing.T) {
 	mockRatingSvc := &mockRatingService{}
 	mockGraphSvc := &mockGraphService{}
 	mockFilmSvc := &mockFilmService{}
-	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc)
+	handler := NewHandler(mockReviewSvc, mockRatingSvc, mockGraphSvc, mockFilmSvc, &mockWatchlistService{})
 
 	req := httptest.NewRequest(http.MethodDelete, "/reviews?id=invalid", nil)
 	w := httptest.NewRecorder()
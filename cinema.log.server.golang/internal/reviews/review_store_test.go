@@ -22,10 +22,9 @@ var (
 // Helper function to create test user
 func createTestUser(ctx context.Context, t *testing.T) uuid.UUID {
 	userID := uuid.New()
-	query := `INSERT INTO users (user_id, name, username, github_id, profile_pic_url) 
-	          VALUES ($1, $2, $3, $4, $5)`
-	githubID := int(time.Now().UnixNano() % 2147483647) // Use nanoseconds for uniqueness
-	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], githubID, "http://example.com/pic.jpg")
+	query := `INSERT INTO users (user_id, name, username, profile_pic_url)
+	          VALUES ($1, $2, $3, $4)`
+	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], "http://example.com/pic.jpg")
 	if err != nil {
 		t.Fatalf("failed to create test user: %v", err)
 	}
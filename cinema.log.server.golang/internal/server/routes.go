@@ -4,7 +4,10 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
 
+	"cinema.log.server.golang/internal/auth"
 	"cinema.log.server.golang/internal/middleware"
 )
 
@@ -18,46 +21,103 @@ func isAuthExempt(path string) bool {
 		"/auth/refresh-token",
 		"/auth/dev/login",
 		"/auth/dev/google-login",
+		"/.well-known/jwks.json",
 	}
 	for _, exemptPath := range exemptPaths {
 		if path == exemptPath {
 			return true
 		}
 	}
+	// Public pages are readable without signing in
+	exemptPrefixes := []string{
+		"/public/",
+	}
+	for _, exemptPrefix := range exemptPrefixes {
+		if strings.HasPrefix(path, exemptPrefix) {
+			return true
+		}
+	}
 	return false
 }
 
+// oidcExemptPaths are the login routes of the OpenID Connect providers in config, which bypass
+// authentication like the GitHub and Google ones. Linking needs the logged in user so it doesn't.
+func oidcExemptPaths(providers []*auth.OIDCProvider) []string {
+	var paths []string
+	for _, provider := range providers {
+		paths = append(paths, provider.LoginPath(), provider.CallbackPath())
+	}
+	return paths
+}
+
 func (s *Server) RegisterRoutes() http.Handler {
 	mux := http.NewServeMux()
 
 	// Register routes
 
 	// User routes
+	mux.HandleFunc("GET /users/me/export", s.archiveHandler.ExportUserData)   // zip of json + csv files
+	mux.HandleFunc("POST /users/me/import", s.archiveHandler.RestoreUserData) // multipart form file: archive, query param: dryRun
+	mux.HandleFunc("GET /users/me/stats", s.statsHandler.GetStats)
+	mux.HandleFunc("GET /users/me/identities", s.userHandler.GetIdentities)
+	mux.HandleFunc("DELETE /users/me/identities/{provider}", s.userHandler.UnlinkIdentity) // refused for the last identity
 	mux.HandleFunc("GET /users/{id}", s.userHandler.GetUserById)
 	mux.HandleFunc("GET /users", s.userHandler.GetAllUsers)
 	mux.HandleFunc("POST /users", s.userHandler.CreateUser)
 	mux.HandleFunc("PUT /users", s.userHandler.UpdateUser)
 	mux.HandleFunc("DELETE /users/{id}", s.userHandler.DeleteUser)
 
-	// Auth routes
+	// Follow routes
+	mux.HandleFunc("POST /users/{id}/follow", s.followHandler.Follow)
+	mux.HandleFunc("DELETE /users/{id}/follow", s.followHandler.Unfollow)
+	mux.HandleFunc("GET /users/{id}/followers", s.followHandler.GetFollowers)
+	mux.HandleFunc("GET /users/{id}/following", s.followHandler.GetFollowing)
+	mux.HandleFunc("GET /users/{id}/compatibility", s.compatHandler.GetCompatibility) // compared with the authenticated user
+
+	// Feed routes
+	mux.HandleFunc("GET /feed", s.feedHandler.GetFeed) // query params: cursor, limit
+
+	// Auth routes, OpenID Connect providers are checked against these in auth.fixedAuthPaths
 	mux.Handle("GET /auth/github-login", s.authHandler.Login())
 	mux.Handle("GET /auth/github-callback", s.authHandler.Callback())
 	mux.Handle("GET /auth/google-login", s.authHandler.GoogleLogin())
 	mux.Handle("GET /auth/google-callback", s.authHandler.GoogleCallback())
+	mux.Handle("GET /auth/github-link", s.authHandler.LinkGithub()) // links another login to the logged in user
+	mux.Handle("GET /auth/github-link-callback", s.authHandler.LinkGithubCallback())
+	mux.Handle("GET /auth/google-link", s.authHandler.LinkGoogle())
+	mux.Handle("GET /auth/google-link-callback", s.authHandler.LinkGoogleCallback())
+	mux.Handle("POST /auth/merge", s.authHandler.Merge()) // merges the account from the merge token cookie into the logged in user
 	mux.Handle("GET /auth/logout", s.authHandler.Logout())
+	mux.Handle("POST /auth/logout-all", s.authHandler.LogoutEverywhere()) // revokes every refresh token the user has
 	mux.Handle("GET /auth/refresh-token", s.authHandler.RefreshToken())
 	mux.Handle("GET /auth/me", s.authHandler.Me())
 	mux.Handle("GET /auth/dev/login", s.authHandler.DevLogin())               // only in dev environment
 	mux.Handle("POST /auth/dev/google-login", s.authHandler.DevGoogleLogin()) // only in dev environment
+	mux.Handle("GET /.well-known/jwks.json", s.authHandler.JWKS())            // public keys for other services to verify our tokens
+
+	// OpenID Connect providers from OIDC_PROVIDERS get the same routes as GitHub and Google, named after the provider
+	for _, provider := range s.authHandler.OIDCProviders() {
+		mux.Handle("GET "+provider.LoginPath(), s.authHandler.OIDCLogin(provider))
+		mux.Handle("GET "+provider.CallbackPath(), s.authHandler.OIDCCallback(provider))
+		mux.Handle("GET "+provider.LinkPath(), s.authHandler.OIDCLink(provider))
+		mux.Handle("GET "+provider.LinkCallbackPath(), s.authHandler.OIDCLinkCallback(provider))
+	}
 
 	// Film routes
 	mux.HandleFunc("GET /films/{id}", s.filmHandler.GetFilmById)
 	mux.HandleFunc("POST /films", s.filmHandler.CreateFilm)
 	mux.HandleFunc("GET /films/search", s.filmHandler.GetFilmsFromExternal)                           // query param name = "f"
 	mux.HandleFunc("GET /films/for-comparison", s.filmHandler.GetFilmsForComparison)                  // query params: userId, filmId
-	mux.HandleFunc("POST /films/generate-recommendations", s.filmHandler.GenerateFilmRecommendations) // query param: userId
+	mux.HandleFunc("POST /films/generate-recommendations", s.filmHandler.GenerateFilmRecommendations) // query params: userId, source (tmdb, cinemalog or blend)
 	mux.HandleFunc("GET /films/seen-unrated/{userId}", s.filmHandler.GetSeenUnratedFilms)
 
+	// Recommendation routes
+	mux.HandleFunc("GET /recommendations", s.filmHandler.GetRecommendations) // query params: cursor, limit
+	mux.HandleFunc("POST /recommendations/{filmId}/dismiss", s.filmHandler.DismissRecommendation)
+	mux.HandleFunc("POST /recommendations/{filmId}/snooze", s.filmHandler.SnoozeRecommendation) // body: until
+	mux.HandleFunc("POST /recommendations/{filmId}/seen", s.filmHandler.MarkRecommendationSeen)
+	mux.HandleFunc("POST /recommendations/{filmId}/watchlist", s.filmHandler.AddRecommendationToWatchlist)
+
 	// Review routes
 	mux.HandleFunc("GET /reviews/{userId}", s.reviewHandler.GetAllReviews)
 	mux.HandleFunc("POST /reviews", s.reviewHandler.CreateReview)
@@ -69,10 +129,43 @@ func (s *Server) RegisterRoutes() http.Handler {
 	mux.HandleFunc("GET /ratings", s.ratingHandler.GetRating) // query params: userId, filmId
 	mux.HandleFunc("POST /ratings/compare-films", s.ratingHandler.CompareFilms)
 	mux.HandleFunc("POST /ratings/compare-films-batch", s.ratingHandler.CompareBatch)
+	mux.HandleFunc("POST /ratings/replay", s.ratingHandler.ReplayRatings) // query param: dryRun
+	mux.HandleFunc("GET /ratings/engine", s.ratingHandler.GetRatingEngine)
+	mux.HandleFunc("PUT /ratings/engine", s.ratingHandler.SetRatingEngine)
+	mux.HandleFunc("GET /ratings/next-comparison", s.ratingHandler.GetNextComparison) // query param: filmId
+	mux.HandleFunc("DELETE /ratings/comparisons/{id}", s.ratingHandler.DeleteComparison)
+	mux.HandleFunc("PUT /ratings/comparisons/{id}", s.ratingHandler.UpdateComparison)
+	mux.HandleFunc("GET /ratings/conflicts", s.ratingHandler.GetConflicts)
+	mux.HandleFunc("GET /ratings/{filmId}/history", s.ratingHandler.GetFilmRatingHistory)
+	mux.HandleFunc("GET /ratings/rank-changes", s.ratingHandler.GetRankChanges) // query param: since (date or RFC 3339 time)
+
+	// Watchlist routes
+	mux.HandleFunc("GET /watchlist", s.watchlistHandler.GetWatchlist)
+	mux.HandleFunc("POST /watchlist", s.watchlistHandler.AddToWatchlist)
+	mux.HandleFunc("DELETE /watchlist/{filmId}", s.watchlistHandler.RemoveFromWatchlist)
+
+	// Diary routes
+	mux.HandleFunc("GET /diary", s.diaryHandler.GetDiary) // query params: year, month
+	mux.HandleFunc("POST /diary", s.diaryHandler.LogWatch)
+	mux.HandleFunc("DELETE /diary/{id}", s.diaryHandler.DeleteDiaryEntry)
+
+	// List routes
+	mux.HandleFunc("GET /lists", s.listHandler.GetLists)
+	mux.HandleFunc("POST /lists", s.listHandler.CreateList)
+	mux.HandleFunc("GET /lists/{id}", s.listHandler.GetList)
+	mux.HandleFunc("PUT /lists/{id}", s.listHandler.UpdateList)
+	mux.HandleFunc("DELETE /lists/{id}", s.listHandler.DeleteList)
+	mux.HandleFunc("POST /lists/{id}/films", s.listHandler.AddFilm)
+	mux.HandleFunc("DELETE /lists/{id}/films/{filmId}", s.listHandler.RemoveFilm)
+	mux.HandleFunc("PUT /lists/{id}/films", s.listHandler.ReorderFilms)
+	mux.HandleFunc("GET /public/lists/{id}", s.listHandler.GetPublicList) // no auth, public lists only
 
 	// Graph routes
 	mux.HandleFunc("GET /graph", s.graphHandler.GetUserGraph)
 
+	// Import routes
+	mux.HandleFunc("POST /imports/letterboxd", s.importHandler.ImportLetterboxd) // multipart form files: ratings, reviews, diary
+
 	// Wrap the mux with middleware
 	return s.corsMiddleware(s.authMiddleware(mux))
 }
@@ -105,7 +198,7 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Allow certain auth paths to bypass token validation
-		if isAuthExempt(r.URL.Path) {
+		if isAuthExempt(r.URL.Path) || slices.Contains(s.oidcExemptPaths, r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -116,8 +209,9 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Only access tokens are accepted here, a refresh token in the cookie is rejected
 		authTokenString := authToken.Value
-		user, err := s.authService.ValidateJWT(authTokenString)
+		user, err := s.authService.ValidateAccessToken(authTokenString)
 		if err != nil {
 			http.Error(w, "jwt invalid", http.StatusUnauthorized)
 			return
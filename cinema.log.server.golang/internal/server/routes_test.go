@@ -24,6 +24,9 @@ func TestIsAuthExempt(t *testing.T) {
 		{"/films", false},
 		{"/ratings", false},
 		{"/auth/logout", false},
+		{"/.well-known/jwks.json", true},
+		{"/public/lists/6c8e8f5e-2f4a-4a39-9d43-1d2c6a8b9e01", true},
+		{"/lists/6c8e8f5e-2f4a-4a39-9d43-1d2c6a8b9e01", false},
 	}
 
 	for _, tt := range tests {
@@ -154,7 +157,7 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 	os.Setenv("TOKEN_SECRET", "test-secret")
 	defer os.Setenv("TOKEN_SECRET", oldSecret)
 
-	authService := auth.NewService(mockUserService)
+	authService := auth.NewService(mockUserService, nil, nil, auth.NewHMACKeySet([]byte("test-secret")))
 
 	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -208,3 +211,66 @@ func (m *mockUserServiceForAuth) UpdateUser(ctx context.Context, user *domain.Us
 func (m *mockUserServiceForAuth) DeleteUser(ctx context.Context, userId uuid.UUID) error {
 	return nil
 }
+
+func (m *mockUserServiceForAuth) GetOrCreateUserByIdentity(ctx context.Context, identity *domain.UserIdentity, name, username, profilePicURL string) (*domain.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserServiceForAuth) GetUserByIdentity(ctx context.Context, provider string, subject string) (*domain.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserServiceForAuth) GetIdentities(ctx context.Context, userId uuid.UUID) ([]domain.UserIdentity, error) {
+	return nil, nil
+}
+
+func (m *mockUserServiceForAuth) LinkIdentity(ctx context.Context, userId uuid.UUID, identity *domain.UserIdentity) error {
+	return nil
+}
+
+func (m *mockUserServiceForAuth) UnlinkIdentity(ctx context.Context, userId uuid.UUID, provider string) error {
+	return nil
+}
+
+func (m *mockUserServiceForAuth) MergeUsers(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error {
+	return nil
+}
+
+func TestRegisterRoutes_OIDCProviders(t *testing.T) {
+	authService := auth.NewService(&mockUserServiceForAuth{}, nil, nil, auth.NewHMACKeySet([]byte("test-secret")))
+	// Nothing listens on the issuer, logging in fails but the routes are there
+	provider, err := auth.NewOIDCProvider(auth.OIDCProviderConfig{Name: "corp", Issuer: "http://127.0.0.1:1", ClientID: "cinema-log"}, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+
+	providers := []*auth.OIDCProvider{provider}
+	server := &Server{authService: authService, authHandler: auth.NewHandler(authService, providers), oidcExemptPaths: oidcExemptPaths(providers)}
+	// Registering the routes again doesn't change which paths are exempt
+	server.RegisterRoutes()
+	handler := server.RegisterRoutes()
+	if len(server.oidcExemptPaths) != 2 {
+		t.Errorf("expected 2 exempt paths, got %v", server.oidcExemptPaths)
+	}
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/auth/corp-login", http.StatusTemporaryRedirect},
+		{"/auth/corp-callback", http.StatusTemporaryRedirect},
+		{"/auth/corp-link", http.StatusUnauthorized},
+		{"/auth/corp-link-callback", http.StatusUnauthorized},
+		{"/auth/other-login", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -10,26 +12,49 @@ import (
 
 	_ "github.com/joho/godotenv/autoload"
 
+	"cinema.log.server.golang/internal/archive"
 	"cinema.log.server.golang/internal/auth"
+	"cinema.log.server.golang/internal/compatibility"
 	"cinema.log.server.golang/internal/database"
+	"cinema.log.server.golang/internal/diary"
+	"cinema.log.server.golang/internal/feed"
 	"cinema.log.server.golang/internal/films"
+	"cinema.log.server.golang/internal/follows"
 	"cinema.log.server.golang/internal/graph"
+	"cinema.log.server.golang/internal/imports"
+	"cinema.log.server.golang/internal/lists"
+	"cinema.log.server.golang/internal/metadata"
 	"cinema.log.server.golang/internal/ratings"
+	"cinema.log.server.golang/internal/recommender"
 	"cinema.log.server.golang/internal/reviews"
+	"cinema.log.server.golang/internal/stats"
 	"cinema.log.server.golang/internal/users"
+	"cinema.log.server.golang/internal/watchlist"
 )
 
 type Server struct {
 	port int
 
-	db            *sql.DB
-	userHandler   *users.Handler
-	authHandler   *auth.Handler
-	authService   *auth.AuthService
-	filmHandler   *films.Handler
-	reviewHandler *reviews.Handler
-	ratingHandler *ratings.Handler
-	graphHandler  *graph.Handler
+	db               *sql.DB
+	userHandler      *users.Handler
+	authHandler      *auth.Handler
+	authService      *auth.AuthService
+	filmHandler      *films.Handler
+	reviewHandler    *reviews.Handler
+	ratingHandler    *ratings.Handler
+	graphHandler     *graph.Handler
+	importHandler    *imports.Handler
+	archiveHandler   *archive.Handler
+	watchlistHandler *watchlist.Handler
+	diaryHandler     *diary.Handler
+	listHandler      *lists.Handler
+	followHandler    *follows.Handler
+	feedHandler      *feed.Handler
+	compatHandler    *compatibility.Handler
+	statsHandler     *stats.Handler
+
+	// Login routes of the OpenID Connect providers in config, which are only known at startup
+	oidcExemptPaths []string
 }
 
 func NewServer() *http.Server {
@@ -43,35 +68,98 @@ func NewServer() *http.Server {
 	userService := users.NewService(userStore)
 	userHandler := users.NewHandler(userService)
 
-	authService := auth.NewService(userService)
-	authHandler := auth.NewHandler(authService)
-
+	tokenKeys, err := auth.NewKeySetFromEnv()
+	if err != nil {
+		log.Fatalf("could not load token keys: %v", err)
+	}
 	ratingStore := ratings.NewStore(db)
 	ratingService := ratings.NewService(ratingStore)
 	ratingHandler := ratings.NewHandler(ratingService)
 
+	refreshTokenStore := auth.NewStore(db)
+	authService := auth.NewService(userService, refreshTokenStore, ratingService, tokenKeys)
+	oidcProviders, err := auth.NewOIDCProvidersFromEnv()
+	if err != nil {
+		log.Fatalf("could not load OIDC providers: %v", err)
+	}
+	authHandler := auth.NewHandler(authService, oidcProviders)
+
 	filmStore := films.NewStore(db)
 	graphStore := graph.NewStore(db)
 	graphService := graph.NewService(graphStore, filmStore)
 	graphHandler := graph.NewHandler(graphService)
-	filmService := films.NewService(filmStore, graphService)
-	filmHandler := films.NewHandler(filmService, ratingService)
+	recommenderStore := recommender.NewStore(db)
+	recommenderService := recommender.NewService(recommenderStore)
+	// The trainer and metadata worker run until the server is shut down
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	recommenderService.Start(backgroundCtx, recommender.RetrainInterval)
+	metadataProvider, err := metadata.NewFromEnv()
+	if err != nil {
+		log.Fatalf("could not set up film metadata: %v", err)
+	}
+	filmService := films.NewService(filmStore, graphService, recommenderService, metadataProvider)
+	filmService.StartMetadataWorker(backgroundCtx)
+
+	watchlistStore := watchlist.NewStore(db)
+	watchlistService := watchlist.NewService(watchlistStore)
+	watchlistHandler := watchlist.NewHandler(watchlistService)
+	filmHandler := films.NewHandler(filmService, ratingService, watchlistService)
+
+	diaryStore := diary.NewStore(db)
+	diaryService := diary.NewService(diaryStore, ratingService)
+	diaryHandler := diary.NewHandler(diaryService)
+
+	listStore := lists.NewStore(db)
+	listService := lists.NewService(listStore)
+	listHandler := lists.NewHandler(listService)
+
+	followStore := follows.NewStore(db)
+	followService := follows.NewService(followStore)
+	followHandler := follows.NewHandler(followService)
+
+	feedStore := feed.NewStore(db)
+	feedService := feed.NewService(feedStore, ratingService)
+	feedHandler := feed.NewHandler(feedService)
+
+	compatService := compatibility.NewService(ratingService, userService)
+	compatHandler := compatibility.NewHandler(compatService)
+
+	statsStore := stats.NewStore(db)
+	statsService := stats.NewService(statsStore, ratingService)
+	statsHandler := stats.NewHandler(statsService)
 
 	reviewStore := reviews.NewStore(db)
 	reviewService := reviews.NewService(reviewStore)
 
-	reviewHandler := reviews.NewHandler(reviewService, ratingService, graphService, filmService)
+	reviewHandler := reviews.NewHandler(reviewService, ratingService, graphService, filmService, watchlistService)
+
+	importService := imports.NewService(filmService, reviewService, ratingService, graphService)
+	importHandler := imports.NewHandler(importService)
+
+	archiveStore := archive.NewStore(db)
+	archiveService := archive.NewService(archiveStore, userService, filmService, reviewService, ratingService, graphService)
+	archiveHandler := archive.NewHandler(archiveService)
 
 	NewServer := &Server{
-		port:          port,
-		db:            db,
-		userHandler:   userHandler,
-		authHandler:   authHandler,
-		authService:   authService,
-		filmHandler:   filmHandler,
-		reviewHandler: reviewHandler,
-		ratingHandler: ratingHandler,
-		graphHandler:  graphHandler,
+		port:             port,
+		db:               db,
+		userHandler:      userHandler,
+		authHandler:      authHandler,
+		authService:      authService,
+		filmHandler:      filmHandler,
+		reviewHandler:    reviewHandler,
+		ratingHandler:    ratingHandler,
+		graphHandler:     graphHandler,
+		importHandler:    importHandler,
+		archiveHandler:   archiveHandler,
+		watchlistHandler: watchlistHandler,
+		diaryHandler:     diaryHandler,
+		listHandler:      listHandler,
+		followHandler:    followHandler,
+		feedHandler:      feedHandler,
+		compatHandler:    compatHandler,
+		statsHandler:     statsHandler,
+		oidcExemptPaths:  oidcExemptPaths(oidcProviders),
 	}
 
 	// Declare Server config
@@ -82,6 +170,7 @@ func NewServer() *http.Server {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
+	server.RegisterOnShutdown(stopBackground)
 
 	return server
 }
@@ -0,0 +1,43 @@
+package stats
+
+import (
+	"context"
+	"net/http"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	StatsService StatsService
+}
+
+type StatsService interface {
+	GetStats(ctx context.Context, userId uuid.UUID) (*domain.UserStats, error)
+}
+
+func NewHandler(statsService StatsService) *Handler {
+	return &Handler{
+		StatsService: statsService,
+	}
+}
+
+// GetStats returns the authenticated user's statistics
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	stats, err := h.StatsService.GetStats(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get stats", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, stats)
+}
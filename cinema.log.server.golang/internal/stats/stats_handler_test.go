@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"github.com/google/uuid"
+)
+
+type mockStatsService struct {
+	getStatsFunc func(ctx context.Context, userId uuid.UUID) (*domain.UserStats, error)
+}
+
+func (m *mockStatsService) GetStats(ctx context.Context, userId uuid.UUID) (*domain.UserStats, error) {
+	return m.getStatsFunc(ctx, userId)
+}
+
+func TestHandler_GetStats(t *testing.T) {
+	me := uuid.New()
+	handler := NewHandler(&mockStatsService{
+		getStatsFunc: func(ctx context.Context, userId uuid.UUID) (*domain.UserStats, error) {
+			if userId != me {
+				t.Errorf("expected stats for %v, got %v", me, userId)
+			}
+			return &domain.UserStats{FilmsPerYear: []domain.PeriodCount{{Year: 2025, Count: 12}}}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me/stats", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, &domain.User{ID: me}))
+	w := httptest.NewRecorder()
+	handler.GetStats(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var stats domain.UserStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(stats.FilmsPerYear) != 1 || stats.FilmsPerYear[0].Count != 12 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestHandler_GetStats_Errors(t *testing.T) {
+	handler := NewHandler(&mockStatsService{
+		getStatsFunc: func(ctx context.Context, userId uuid.UUID) (*domain.UserStats, error) {
+			return nil, errors.New("database error")
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.GetStats(w, httptest.NewRequest(http.MethodGet, "/users/me/stats", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without a user, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me/stats", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, &domain.User{ID: uuid.New()}))
+	w = httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
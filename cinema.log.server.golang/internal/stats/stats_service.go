@@ -0,0 +1,225 @@
+package stats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/ratings"
+	"github.com/google/uuid"
+)
+
+const (
+	// How many films the top ten history and most compared list show
+	TopTenSize        = 10
+	MostComparedCount = 10
+	// Directors need this many rated films to get an average, one film says nothing about a director
+	MinDirectorFilms = 2
+	// How many users' stats are kept, the least recently asked for are dropped first
+	CacheSize = 1000
+)
+
+type Service struct {
+	StatsStore    StatsStore
+	RatingService RatingService
+
+	// Stats are cached per user along with the version they were computed from, uses counts every
+	// time they're read so the least recently used can be found
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedStats
+	uses  uint64
+}
+
+type cachedStats struct {
+	version  string
+	stats    *domain.UserStats
+	lastUsed uint64
+}
+
+type StatsStore interface {
+	GetStatsVersion(ctx context.Context, userId uuid.UUID) (string, error)
+	GetFilmsPerMonth(ctx context.Context, userId uuid.UUID) ([]domain.PeriodCount, error)
+	GetRatingDistribution(ctx context.Context, userId uuid.UUID) ([]domain.RatingCount, error)
+	GetEloByGenre(ctx context.Context, userId uuid.UUID) ([]domain.EloAverage, error)
+	GetEloByDecade(ctx context.Context, userId uuid.UUID) ([]domain.EloAverage, error)
+	GetEloByDirector(ctx context.Context, userId uuid.UUID, minFilms int) ([]domain.EloAverage, error)
+	GetMostComparedFilms(ctx context.Context, userId uuid.UUID, limit int) ([]domain.ComparedFilm, error)
+	GetComparisonDays(ctx context.Context, userId uuid.UUID) ([]time.Time, error)
+}
+
+type RatingService interface {
+	GetRatingsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error)
+	GetTopRankingHistory(ctx context.Context, userId uuid.UUID, n int) ([]ratings.TopRanking, error)
+}
+
+func NewService(statsStore StatsStore, ratingService RatingService) *Service {
+	return &Service{
+		StatsStore:    statsStore,
+		RatingService: ratingService,
+		cache:         map[uuid.UUID]cachedStats{},
+	}
+}
+
+// GetStats returns the user's stats. They're only computed again once a review, comparison or rating
+// has been added, changed or removed since the last time, or when they've been dropped from the cache.
+func (s *Service) GetStats(ctx context.Context, userId uuid.UUID) (*domain.UserStats, error) {
+	version, err := s.StatsStore.GetStatsVersion(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	cached, ok := s.cache[userId]
+	if ok && cached.version == version {
+		s.uses++
+		cached.lastUsed = s.uses
+		s.cache[userId] = cached
+		s.mu.Unlock()
+		return cached.stats, nil
+	}
+	s.mu.Unlock()
+
+	stats, err := s.computeStats(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if _, ok := s.cache[userId]; !ok && len(s.cache) >= CacheSize {
+		s.evictLeastRecentlyUsed()
+	}
+	s.uses++
+	s.cache[userId] = cachedStats{version: version, stats: stats, lastUsed: s.uses}
+	s.mu.Unlock()
+
+	return stats, nil
+}
+
+// evictLeastRecentlyUsed drops the stats that were asked for longest ago to make room for another
+// user's. Callers must hold s.mu, going through the whole cache is cheap next to computing stats.
+func (s *Service) evictLeastRecentlyUsed() {
+	var oldest uuid.UUID
+	oldestUsed := s.uses + 1
+	for userId, cached := range s.cache {
+		if cached.lastUsed < oldestUsed {
+			oldest, oldestUsed = userId, cached.lastUsed
+		}
+	}
+	delete(s.cache, oldest)
+}
+
+func (s *Service) computeStats(ctx context.Context, userId uuid.UUID) (*domain.UserStats, error) {
+	stats := &domain.UserStats{ComputedAt: time.Now().UTC()}
+
+	var err error
+	if stats.FilmsPerMonth, err = s.StatsStore.GetFilmsPerMonth(ctx, userId); err != nil {
+		return nil, err
+	}
+	stats.FilmsPerYear = perYear(stats.FilmsPerMonth)
+
+	if stats.RatingDistribution, err = s.StatsStore.GetRatingDistribution(ctx, userId); err != nil {
+		return nil, err
+	}
+	if stats.EloByGenre, err = s.StatsStore.GetEloByGenre(ctx, userId); err != nil {
+		return nil, err
+	}
+	if stats.EloByDecade, err = s.StatsStore.GetEloByDecade(ctx, userId); err != nil {
+		return nil, err
+	}
+	if stats.EloByDirector, err = s.StatsStore.GetEloByDirector(ctx, userId, MinDirectorFilms); err != nil {
+		return nil, err
+	}
+	if stats.MostCompared, err = s.StatsStore.GetMostComparedFilms(ctx, userId, MostComparedCount); err != nil {
+		return nil, err
+	}
+
+	days, err := s.StatsStore.GetComparisonDays(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	stats.ComparisonStreaks = comparisonStreaks(days, stats.ComputedAt)
+
+	if stats.TopTenHistory, err = s.topTenHistory(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// topTenHistory fills in the films for the user's top ten at the end of every month it changed in
+func (s *Service) topTenHistory(ctx context.Context, userId uuid.UUID) ([]domain.TopTenSnapshot, error) {
+	rankings, err := s.RatingService.GetTopRankingHistory(ctx, userId, TopTenSize)
+	if err != nil {
+		return nil, err
+	}
+	details, err := s.RatingService.GetRatingsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	films := make(map[uuid.UUID]domain.UserFilmRatingDetail, len(details))
+	for _, detail := range details {
+		films[detail.Rating.FilmId] = detail
+	}
+
+	history := make([]domain.TopTenSnapshot, 0, len(rankings))
+	for _, ranking := range rankings {
+		snapshot := domain.TopTenSnapshot{Date: ranking.Date, Films: make([]domain.RankedFilm, 0, len(ranking.FilmIds))}
+		for i, filmId := range ranking.FilmIds {
+			detail := films[filmId]
+			snapshot.Films = append(snapshot.Films, domain.RankedFilm{
+				Rank:          i + 1,
+				FilmID:        filmId,
+				FilmTitle:     detail.FilmTitle,
+				FilmPosterURL: detail.FilmPosterURL,
+			})
+		}
+		history = append(history, snapshot)
+	}
+
+	return history, nil
+}
+
+// perYear adds up monthly counts, which are oldest first, into yearly ones
+func perYear(months []domain.PeriodCount) []domain.PeriodCount {
+	years := []domain.PeriodCount{}
+	for _, month := range months {
+		if len(years) == 0 || years[len(years)-1].Year != month.Year {
+			years = append(years, domain.PeriodCount{Year: month.Year})
+		}
+		years[len(years)-1].Count += month.Count
+	}
+	return years
+}
+
+// comparisonStreaks finds runs of consecutive days in days, which are distinct and oldest first. A
+// streak is still current when its last day is today or yesterday, the user may not have compared yet today.
+func comparisonStreaks(days []time.Time, now time.Time) domain.ComparisonStreaks {
+	streaks := domain.ComparisonStreaks{}
+	if len(days) == 0 {
+		return streaks
+	}
+
+	run := 0
+	for i, day := range days {
+		if i > 0 && day.Sub(days[i-1]) == 24*time.Hour {
+			run++
+		} else {
+			run = 1
+		}
+		if run > streaks.Longest {
+			streaks.Longest = run
+			streaks.LongestEnd = &days[i]
+		}
+	}
+
+	last := days[len(days)-1]
+	streaks.LastComparison = &last
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !last.Before(today.AddDate(0, 0, -1)) {
+		streaks.Current = run
+	}
+
+	return streaks
+}
@@ -0,0 +1,179 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/ratings"
+	"github.com/google/uuid"
+)
+
+type mockStatsStore struct {
+	version      string
+	months       []domain.PeriodCount
+	days         []time.Time
+	computations int
+	err          error
+}
+
+func (m *mockStatsStore) GetStatsVersion(ctx context.Context, userId uuid.UUID) (string, error) {
+	return m.version, nil
+}
+
+func (m *mockStatsStore) GetFilmsPerMonth(ctx context.Context, userId uuid.UUID) ([]domain.PeriodCount, error) {
+	m.computations++
+	return m.months, m.err
+}
+
+func (m *mockStatsStore) GetRatingDistribution(ctx context.Context, userId uuid.UUID) ([]domain.RatingCount, error) {
+	return []domain.RatingCount{}, nil
+}
+
+func (m *mockStatsStore) GetEloByGenre(ctx context.Context, userId uuid.UUID) ([]domain.EloAverage, error) {
+	return []domain.EloAverage{}, nil
+}
+
+func (m *mockStatsStore) GetEloByDecade(ctx context.Context, userId uuid.UUID) ([]domain.EloAverage, error) {
+	return []domain.EloAverage{}, nil
+}
+
+func (m *mockStatsStore) GetEloByDirector(ctx context.Context, userId uuid.UUID, minFilms int) ([]domain.EloAverage, error) {
+	return []domain.EloAverage{}, nil
+}
+
+func (m *mockStatsStore) GetMostComparedFilms(ctx context.Context, userId uuid.UUID, limit int) ([]domain.ComparedFilm, error) {
+	return []domain.ComparedFilm{}, nil
+}
+
+func (m *mockStatsStore) GetComparisonDays(ctx context.Context, userId uuid.UUID) ([]time.Time, error) {
+	return m.days, nil
+}
+
+type mockRatingService struct {
+	details  []domain.UserFilmRatingDetail
+	rankings []ratings.TopRanking
+}
+
+func (m *mockRatingService) GetRatingsByUserId(ctx context.Context, userId uuid.UUID) ([]domain.UserFilmRatingDetail, error) {
+	return m.details, nil
+}
+
+func (m *mockRatingService) GetTopRankingHistory(ctx context.Context, userId uuid.UUID, n int) ([]ratings.TopRanking, error) {
+	return m.rankings, nil
+}
+
+func TestService_GetStats(t *testing.T) {
+	heat, ronin := uuid.New(), uuid.New()
+	store := &mockStatsStore{
+		version: "1",
+		months: []domain.PeriodCount{
+			{Year: 2024, Month: 11, Count: 2},
+			{Year: 2024, Month: 12, Count: 3},
+			{Year: 2025, Month: 1, Count: 1},
+		},
+	}
+	ratingService := &mockRatingService{
+		details: []domain.UserFilmRatingDetail{
+			{Rating: domain.UserFilmRating{FilmId: heat}, FilmTitle: "Heat"},
+			{Rating: domain.UserFilmRating{FilmId: ronin}, FilmTitle: "Ronin"},
+		},
+		rankings: []ratings.TopRanking{{Date: time.Now(), FilmIds: []uuid.UUID{ronin, heat}}},
+	}
+	service := NewService(store, ratingService)
+
+	stats, err := service.GetStats(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(stats.FilmsPerYear) != 2 || stats.FilmsPerYear[0].Count != 5 || stats.FilmsPerYear[1].Count != 1 {
+		t.Errorf("expected 5 films in 2024 and 1 in 2025, got %+v", stats.FilmsPerYear)
+	}
+	top := stats.TopTenHistory[0].Films
+	if len(top) != 2 || top[0].FilmTitle != "Ronin" || top[0].Rank != 1 || top[1].FilmTitle != "Heat" {
+		t.Errorf("expected Ronin above Heat, got %+v", top)
+	}
+}
+
+func TestService_GetStats_Cache(t *testing.T) {
+	store := &mockStatsStore{version: "1"}
+	service := NewService(store, &mockRatingService{})
+	userId := uuid.New()
+
+	first, _ := service.GetStats(context.Background(), userId)
+	second, _ := service.GetStats(context.Background(), userId)
+	if store.computations != 1 || first != second {
+		t.Errorf("expected the cached stats to be reused, computed %d times", store.computations)
+	}
+
+	// A new review or comparison changes the version
+	store.version = "2"
+	if _, err := service.GetStats(context.Background(), userId); err != nil || store.computations != 2 {
+		t.Errorf("expected stale stats to be computed again, computed %d times with %v", store.computations, err)
+	}
+
+	// Other users have their own stats
+	if _, err := service.GetStats(context.Background(), uuid.New()); err != nil || store.computations != 3 {
+		t.Errorf("expected another user's stats to be computed, computed %d times with %v", store.computations, err)
+	}
+}
+
+func TestService_GetStats_CacheEviction(t *testing.T) {
+	store := &mockStatsStore{version: "1"}
+	service := NewService(store, &mockRatingService{})
+	ctx := context.Background()
+
+	first := uuid.New()
+	service.GetStats(ctx, first)
+	for range CacheSize {
+		service.GetStats(ctx, uuid.New())
+		// Keep asking for the first user's stats so they're never the least recently used
+		service.GetStats(ctx, first)
+	}
+	if len(service.cache) != CacheSize {
+		t.Errorf("expected the cache to hold %d users, got %d", CacheSize, len(service.cache))
+	}
+	if _, ok := service.cache[first]; !ok {
+		t.Error("expected stats that are still being asked for to be kept")
+	}
+	if store.computations != CacheSize+1 {
+		t.Errorf("expected every user's stats to be computed once, computed %d times", store.computations)
+	}
+}
+
+func TestService_GetStats_Error(t *testing.T) {
+	store := &mockStatsStore{version: "1", err: errors.New("database error")}
+	service := NewService(store, &mockRatingService{})
+
+	if _, err := service.GetStats(context.Background(), uuid.New()); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// Failures aren't cached
+	store.err = nil
+	if _, err := service.GetStats(context.Background(), uuid.New()); err != nil {
+		t.Errorf("expected the stats to be computed once the store recovers, got %v", err)
+	}
+}
+
+func TestComparisonStreaks(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+	days := []time.Time{day(1), day(2), day(3), day(5), day(9), day(10)}
+
+	streaks := comparisonStreaks(days, day(11).Add(15*time.Hour))
+	if streaks.Longest != 3 || !streaks.LongestEnd.Equal(day(3)) {
+		t.Errorf("expected a longest streak of 3 ending on the 3rd, got %d ending %v", streaks.Longest, streaks.LongestEnd)
+	}
+	if streaks.Current != 2 || !streaks.LastComparison.Equal(day(10)) {
+		t.Errorf("expected a current streak of 2, got %+v", streaks)
+	}
+
+	if streaks := comparisonStreaks(days, day(12)); streaks.Current != 0 {
+		t.Errorf("expected the streak to be broken after a day off, got %d", streaks.Current)
+	}
+	if streaks := comparisonStreaks(nil, day(12)); streaks.Longest != 0 || streaks.LastComparison != nil {
+		t.Errorf("expected no streaks without comparisons, got %+v", streaks)
+	}
+}
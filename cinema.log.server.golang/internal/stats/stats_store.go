@@ -0,0 +1,220 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) StatsStore {
+	return &store{
+		db: db,
+	}
+}
+
+// GetStatsVersion fingerprints everything the stats are computed from: every review, comparison and
+// Elo rating along with the metadata of the rated films. Editing a comparison replays the user's
+// ratings without changing how many there are or when they were made, so counts and latest dates
+// aren't enough, the rows themselves are hashed.
+func (s *store) GetStatsVersion(ctx context.Context, userId uuid.UUID) (string, error) {
+	query := /* sql */ `
+		SELECT md5(concat_ws('|',
+			(SELECT string_agg(concat_ws(':', review_id, rating, date, film_id), ',' ORDER BY review_id)
+				FROM reviews WHERE user_id = $1),
+			(SELECT string_agg(concat_ws(':', comparison_history_id, winning_film_film_id, was_equal, comparison_date), ',' ORDER BY comparison_history_id)
+				FROM comparison_histories WHERE user_id = $1),
+			(SELECT string_agg(concat_ws(':', r.film_id, r.elo_rating, r.number_of_comparisons, f.metadata_updated_at), ',' ORDER BY r.film_id)
+				FROM user_film_ratings r JOIN films f ON f.film_id = r.film_id WHERE r.user_id = $1)
+		))
+	`
+
+	var version string
+	if err := s.db.QueryRowContext(ctx, query, userId).Scan(&version); err != nil {
+		return "", err
+	}
+
+	return version, nil
+}
+
+// GetFilmsPerMonth counts the user's reviews in every month they wrote one, oldest first
+func (s *store) GetFilmsPerMonth(ctx context.Context, userId uuid.UUID) ([]domain.PeriodCount, error) {
+	query := /* sql */ `
+		SELECT EXTRACT(YEAR FROM date)::int AS year, EXTRACT(MONTH FROM date)::int AS month, COUNT(*)
+		FROM reviews
+		WHERE user_id = $1
+		GROUP BY year, month
+		ORDER BY year, month
+	`
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []domain.PeriodCount{}
+	for rows.Next() {
+		var count domain.PeriodCount
+		if err := rows.Scan(&count.Year, &count.Month, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// GetRatingDistribution counts the user's reviews by star rating, lowest first
+func (s *store) GetRatingDistribution(ctx context.Context, userId uuid.UUID) ([]domain.RatingCount, error) {
+	query := /* sql */ `
+		SELECT rating, COUNT(*)
+		FROM reviews
+		WHERE user_id = $1
+		GROUP BY rating
+		ORDER BY rating
+	`
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []domain.RatingCount{}
+	for rows.Next() {
+		var count domain.RatingCount
+		if err := rows.Scan(&count.Rating, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// GetEloByGenre averages the user's ratings in each genre, best first
+func (s *store) GetEloByGenre(ctx context.Context, userId uuid.UUID) ([]domain.EloAverage, error) {
+	query := /* sql */ `
+		SELECT g.name, AVG(r.elo_rating), COUNT(*)
+		FROM user_film_ratings r
+		JOIN film_genres fg ON fg.film_id = r.film_id
+		JOIN genres g ON g.genre_id = fg.genre_id
+		WHERE r.user_id = $1
+		GROUP BY g.genre_id, g.name
+		ORDER BY AVG(r.elo_rating) DESC, g.name
+	`
+	return s.getEloAverages(ctx, query, userId)
+}
+
+// GetEloByDecade averages the user's ratings by the decade films were released in, oldest first.
+// Films without a release date are left out.
+func (s *store) GetEloByDecade(ctx context.Context, userId uuid.UUID) ([]domain.EloAverage, error) {
+	query := /* sql */ `
+		SELECT LEFT(f.release_year, 3) || '0s' AS decade, AVG(r.elo_rating), COUNT(*)
+		FROM user_film_ratings r
+		JOIN films f ON f.film_id = r.film_id
+		WHERE r.user_id = $1
+		AND f.release_year ~ '^[0-9]{4}'
+		GROUP BY decade
+		ORDER BY decade
+	`
+	return s.getEloAverages(ctx, query, userId)
+}
+
+// GetEloByDirector averages the user's ratings by director, best first. Directors with fewer than
+// minFilms rated films are left out.
+func (s *store) GetEloByDirector(ctx context.Context, userId uuid.UUID, minFilms int) ([]domain.EloAverage, error) {
+	query := /* sql */ `
+		SELECT p.name, AVG(r.elo_rating), COUNT(*)
+		FROM user_film_ratings r
+		JOIN film_people fp ON fp.film_id = r.film_id AND fp.role = 'director'
+		JOIN people p ON p.person_id = fp.person_id
+		WHERE r.user_id = $1
+		GROUP BY p.person_id, p.name
+		HAVING COUNT(*) >= $2
+		ORDER BY AVG(r.elo_rating) DESC, p.name
+	`
+	return s.getEloAverages(ctx, query, userId, minFilms)
+}
+
+func (s *store) getEloAverages(ctx context.Context, query string, args ...any) ([]domain.EloAverage, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	averages := []domain.EloAverage{}
+	for rows.Next() {
+		var average domain.EloAverage
+		if err := rows.Scan(&average.Name, &average.AverageElo, &average.Films); err != nil {
+			return nil, err
+		}
+		averages = append(averages, average)
+	}
+
+	return averages, rows.Err()
+}
+
+// GetMostComparedFilms returns the limit films the user has compared most, most first
+func (s *store) GetMostComparedFilms(ctx context.Context, userId uuid.UUID, limit int) ([]domain.ComparedFilm, error) {
+	query := /* sql */ `
+		SELECT f.film_id, f.title, COALESCE(f.poster_url, ''), COUNT(*) AS comparisons
+		FROM (
+			SELECT film_a_film_id AS film_id FROM comparison_histories WHERE user_id = $1
+			UNION ALL
+			SELECT film_b_film_id FROM comparison_histories WHERE user_id = $1
+		) c
+		JOIN films f ON f.film_id = c.film_id
+		GROUP BY f.film_id, f.title, f.poster_url
+		ORDER BY comparisons DESC, f.title
+		LIMIT $2
+	`
+	rows, err := s.db.QueryContext(ctx, query, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	films := []domain.ComparedFilm{}
+	for rows.Next() {
+		var film domain.ComparedFilm
+		if err := rows.Scan(&film.FilmID, &film.FilmTitle, &film.FilmPosterURL, &film.Comparisons); err != nil {
+			return nil, err
+		}
+		films = append(films, film)
+	}
+
+	return films, rows.Err()
+}
+
+// GetComparisonDays returns every day the user made a comparison on, oldest first
+func (s *store) GetComparisonDays(ctx context.Context, userId uuid.UUID) ([]time.Time, error) {
+	query := /* sql */ `
+		SELECT DISTINCT comparison_date::date AS day
+		FROM comparison_histories
+		WHERE user_id = $1
+		ORDER BY day
+	`
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []time.Time{}
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		days = append(days, day.UTC())
+	}
+
+	return days, rows.Err()
+}
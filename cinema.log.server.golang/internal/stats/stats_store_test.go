@@ -0,0 +1,221 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	testDB      *sql.DB
+	testStore   StatsStore
+	testDbSetup *utils.TestDatabase
+)
+
+// Helper function to create test user
+func createTestUser(ctx context.Context, t *testing.T) uuid.UUID {
+	userID := uuid.New()
+	query := `INSERT INTO users (user_id, name, username, profile_pic_url)
+	          VALUES ($1, $2, $3, $4)`
+	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], "http://example.com/pic.jpg")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return userID
+}
+
+// Helper function to create a rated test film directed by directorId
+func createRatedFilm(ctx context.Context, t *testing.T, userId uuid.UUID, releaseYear string, elo float64, directorId int) uuid.UUID {
+	filmID := uuid.New()
+	externalID := int(time.Now().UnixNano() % 2147483647) // Use nanoseconds for uniqueness
+	_, err := testDB.ExecContext(ctx, `INSERT INTO films (film_id, external_id, title, description, poster_url, release_year)
+		VALUES ($1, $2, $3, 'Description', '/poster.jpg', $4)`, filmID, externalID, "Test Film "+filmID.String()[:8], releaseYear)
+	if err != nil {
+		t.Fatalf("failed to create test film: %v", err)
+	}
+
+	_, err = testDB.ExecContext(ctx, `INSERT INTO film_genres (film_id, genre_id) VALUES ($1, 18)`, filmID)
+	if err != nil {
+		t.Fatalf("failed to add genre: %v", err)
+	}
+	_, err = testDB.ExecContext(ctx, `INSERT INTO film_people (film_id, person_id, role) VALUES ($1, $2, 'director')`, filmID, directorId)
+	if err != nil {
+		t.Fatalf("failed to add director: %v", err)
+	}
+
+	_, err = testDB.ExecContext(ctx, `INSERT INTO user_film_ratings (user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating, k_constant_value)
+		VALUES ($1, $2, $3, $4, 0, NOW(), 3, 40)`, uuid.New(), userId, filmID, elo)
+	if err != nil {
+		t.Fatalf("failed to create rating: %v", err)
+	}
+	return filmID
+}
+
+func createTestReview(ctx context.Context, t *testing.T, userId uuid.UUID, filmId uuid.UUID, date time.Time, rating float32) {
+	_, err := testDB.ExecContext(ctx, `INSERT INTO reviews (review_id, content, date, rating, film_id, user_id) VALUES ($1, '', $2, $3, $4, $5)`,
+		uuid.New(), date, rating, filmId, userId)
+	if err != nil {
+		t.Fatalf("failed to create review: %v", err)
+	}
+}
+
+func createTestComparison(ctx context.Context, t *testing.T, userId uuid.UUID, filmA uuid.UUID, filmB uuid.UUID, date time.Time) {
+	_, err := testDB.ExecContext(ctx, `INSERT INTO comparison_histories (comparison_history_id, user_id, film_a_film_id, film_b_film_id, winning_film_film_id, comparison_date, was_equal)
+		VALUES ($1, $2, $3, $4, $3, $5, false)`, uuid.New(), userId, filmA, filmB, date)
+	if err != nil {
+		t.Fatalf("failed to create comparison: %v", err)
+	}
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	testDbSetup, err = utils.StartTestPostgres()
+	if err != nil {
+		log.Fatalf("could not start test database: %v", err)
+	}
+
+	testDB = testDbSetup.DB
+	testStore = NewStore(testDB)
+
+	if _, err := testDB.Exec(`INSERT INTO genres (genre_id, name) VALUES (18, 'Drama');
+		INSERT INTO people (person_id, name) VALUES (1, 'Agnès Varda'), (2, 'Chantal Akerman')`); err != nil {
+		log.Fatalf("could not create test metadata: %v", err)
+	}
+
+	code := m.Run()
+
+	testDbSetup.Close()
+	os.Exit(code)
+}
+
+func TestStatsStore_Aggregates(t *testing.T) {
+	ctx := context.Background()
+	userID := createTestUser(ctx, t)
+
+	cleo := createRatedFilm(ctx, t, userID, "1962-04-11", 1100, 1)
+	vagabond := createRatedFilm(ctx, t, userID, "1985-12-04", 1000, 1)
+	jeanne := createRatedFilm(ctx, t, userID, "1975-05-14", 900, 2)
+
+	january := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	createTestReview(ctx, t, userID, cleo, january, 4.5)
+	createTestReview(ctx, t, userID, vagabond, january.Add(24*time.Hour), 4.5)
+	createTestReview(ctx, t, userID, jeanne, january.AddDate(1, 0, 0), 3)
+
+	months, err := testStore.GetFilmsPerMonth(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get films per month: %v", err)
+	}
+	if len(months) != 2 || months[0].Year != 2025 || months[0].Month != 1 || months[0].Count != 2 || months[1].Year != 2026 {
+		t.Errorf("expected two films in January 2025 and one in 2026, got %+v", months)
+	}
+
+	distribution, err := testStore.GetRatingDistribution(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get rating distribution: %v", err)
+	}
+	if len(distribution) != 2 || distribution[0].Rating != 3 || distribution[1].Rating != 4.5 || distribution[1].Count != 2 {
+		t.Errorf("unexpected rating distribution %+v", distribution)
+	}
+
+	genres, err := testStore.GetEloByGenre(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get elo by genre: %v", err)
+	}
+	if len(genres) != 1 || genres[0].Name != "Drama" || genres[0].AverageElo != 1000 || genres[0].Films != 3 {
+		t.Errorf("unexpected genre averages %+v", genres)
+	}
+
+	decades, err := testStore.GetEloByDecade(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get elo by decade: %v", err)
+	}
+	if len(decades) != 3 || decades[0].Name != "1960s" || decades[2].Name != "1980s" {
+		t.Errorf("expected the 60s, 70s and 80s oldest first, got %+v", decades)
+	}
+
+	// Only Varda has enough films
+	directors, err := testStore.GetEloByDirector(ctx, userID, 2)
+	if err != nil {
+		t.Fatalf("failed to get elo by director: %v", err)
+	}
+	if len(directors) != 1 || directors[0].Name != "Agnès Varda" || directors[0].AverageElo != 1050 {
+		t.Errorf("unexpected director averages %+v", directors)
+	}
+
+	createTestComparison(ctx, t, userID, cleo, vagabond, january)
+	createTestComparison(ctx, t, userID, cleo, jeanne, january.Add(time.Hour))
+	createTestComparison(ctx, t, userID, vagabond, jeanne, january.Add(48*time.Hour))
+
+	mostCompared, err := testStore.GetMostComparedFilms(ctx, userID, 1)
+	if err != nil {
+		t.Fatalf("failed to get most compared films: %v", err)
+	}
+	if len(mostCompared) != 1 || mostCompared[0].Comparisons != 2 {
+		t.Errorf("expected one film compared twice, got %+v", mostCompared)
+	}
+
+	days, err := testStore.GetComparisonDays(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get comparison days: %v", err)
+	}
+	if len(days) != 2 || !days[0].Equal(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected two distinct days, got %v", days)
+	}
+}
+
+func TestStatsStore_GetStatsVersion(t *testing.T) {
+	ctx := context.Background()
+	userID := createTestUser(ctx, t)
+	film := createRatedFilm(ctx, t, userID, "1962-04-11", 1000, 1)
+
+	before, err := testStore.GetStatsVersion(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get stats version: %v", err)
+	}
+	if again, _ := testStore.GetStatsVersion(ctx, userID); again != before {
+		t.Errorf("expected the version to be stable, got %q then %q", before, again)
+	}
+
+	createTestReview(ctx, t, userID, film, time.Now(), 4)
+	after, err := testStore.GetStatsVersion(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get stats version: %v", err)
+	}
+	if after == before {
+		t.Error("expected a new review to change the version")
+	}
+
+	// Changing a review's rating changes it too
+	if _, err := testDB.ExecContext(ctx, `UPDATE reviews SET rating = 2 WHERE user_id = $1`, userID); err != nil {
+		t.Fatalf("failed to update review: %v", err)
+	}
+	if edited, _ := testStore.GetStatsVersion(ctx, userID); edited == after {
+		t.Error("expected an edited rating to change the version")
+	}
+
+	// Editing a comparison keeps the number of comparisons and their dates, but not the winner
+	other := createRatedFilm(ctx, t, userID, "1971-12-19", 1000, 2)
+	createTestComparison(ctx, t, userID, film, other, time.Now())
+	compared, _ := testStore.GetStatsVersion(ctx, userID)
+	if _, err := testDB.ExecContext(ctx, `UPDATE comparison_histories SET winning_film_film_id = $2 WHERE user_id = $1`, userID, other); err != nil {
+		t.Fatalf("failed to update comparison: %v", err)
+	}
+	edited, _ := testStore.GetStatsVersion(ctx, userID)
+	if edited == compared {
+		t.Error("expected an edited comparison to change the version")
+	}
+
+	// So does a replay that only moves the Elo ratings
+	if _, err := testDB.ExecContext(ctx, `UPDATE user_film_ratings SET elo_rating = elo_rating + 10 WHERE user_id = $1 AND film_id = $2`, userID, film); err != nil {
+		t.Fatalf("failed to update rating: %v", err)
+	}
+	if replayed, _ := testStore.GetStatsVersion(ctx, userID); replayed == edited {
+		t.Error("expected a changed Elo rating to change the version")
+	}
+}
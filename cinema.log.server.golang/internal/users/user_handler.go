@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
 	"cinema.log.server.golang/internal/utils"
 	"github.com/google/uuid"
 )
@@ -21,9 +22,16 @@ type UserService interface {
 		username string, avatarUrl string) (*domain.User, error)
 	GetOrCreateUserByGoogleId(ctx context.Context, googleId string, name string,
 		username string, avatarUrl string) (*domain.User, error)
+	GetOrCreateUserByIdentity(ctx context.Context, identity *domain.UserIdentity, name string,
+		username string, avatarUrl string) (*domain.User, error)
+	GetUserByIdentity(ctx context.Context, provider string, subject string) (*domain.User, error)
 	CreateUser(ctx context.Context, user *domain.User) (*domain.User, error)
 	UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	GetIdentities(ctx context.Context, userId uuid.UUID) ([]domain.UserIdentity, error)
+	LinkIdentity(ctx context.Context, userId uuid.UUID, identity *domain.UserIdentity) error
+	UnlinkIdentity(ctx context.Context, userId uuid.UUID, provider string) error
+	MergeUsers(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error
 }
 
 func NewHandler(s UserService) *Handler {
@@ -157,3 +165,44 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *Handler) GetIdentities(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := h.service.GetIdentities(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, ErrServer.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, identities)
+}
+
+func (h *Handler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.UnlinkIdentity(r.Context(), user.ID, r.PathValue("provider")); err != nil {
+		if err == ErrIdentityNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err == ErrLastIdentity {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, ErrServer.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,182 @@
+package users
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"github.com/google/uuid"
+)
+
+func TestLinkIdentityIntegration(t *testing.T) {
+	ctx := context.Background()
+	user, err := testService.GetOrCreateUserByGithubId(ctx, 310001, "Link User", "linkuser", "")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if user.GithubId == nil || *user.GithubId != 310001 {
+		t.Fatalf("expected the GitHub id to be read from the identity, got %v", user.GithubId)
+	}
+
+	google := &domain.UserIdentity{Provider: ProviderGoogle, Subject: "google-310001", ProviderUsername: "link@example.com"}
+	if err := testService.LinkIdentity(ctx, user.ID, google); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Logging in with either provider finds the same user
+	viaGoogle, err := testService.GetOrCreateUserByGoogleId(ctx, "google-310001", "Someone Else", "someone", "")
+	if err != nil || viaGoogle.ID != user.ID {
+		t.Fatalf("expected the Google login to find %v, got %v with %v", user.ID, viaGoogle, err)
+	}
+	if viaGoogle.GoogleId == nil || *viaGoogle.GoogleId != "google-310001" {
+		t.Errorf("expected the Google id to be read from the identity, got %v", viaGoogle.GoogleId)
+	}
+
+	// Linking again does nothing, a different login for the same provider has to replace the first
+	if err := testService.LinkIdentity(ctx, user.ID, google); err != nil {
+		t.Errorf("expected relinking to do nothing, got %v", err)
+	}
+	other := &domain.UserIdentity{Provider: ProviderGoogle, Subject: "google-other"}
+	if err := testService.LinkIdentity(ctx, user.ID, other); err != ErrProviderAlreadyLinked {
+		t.Errorf("expected %v, got %v", ErrProviderAlreadyLinked, err)
+	}
+
+	// A login that belongs to someone else needs a merge
+	someoneElse, err := testService.GetOrCreateUserByGithubId(ctx, 310002, "Other User", "otheruser", "")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := testService.LinkIdentity(ctx, someoneElse.ID, google); err != ErrIdentityLinkedToOtherUser {
+		t.Errorf("expected %v, got %v", ErrIdentityLinkedToOtherUser, err)
+	}
+
+	identities, err := testService.GetIdentities(ctx, user.ID)
+	if err != nil || len(identities) != 2 {
+		t.Errorf("expected 2 identities, got %+v with %v", identities, err)
+	}
+}
+
+func TestUnlinkIdentityIntegration(t *testing.T) {
+	ctx := context.Background()
+	user, err := testService.GetOrCreateUserByGithubId(ctx, 320001, "Unlink User", "unlinkuser", "")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	err = testService.LinkIdentity(ctx, user.ID, &domain.UserIdentity{Provider: ProviderGoogle, Subject: "google-320001"})
+	if err != nil {
+		t.Fatalf("failed to link identity: %v", err)
+	}
+
+	unlink := func(provider string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/users/me/identities/"+provider, nil)
+		req.SetPathValue("provider", provider)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.KeyUser, user))
+		w := httptest.NewRecorder()
+		testHandler.UnlinkIdentity(w, req)
+		return w.Code
+	}
+
+	if code := unlink(ProviderGithub); code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, code)
+	}
+	if code := unlink(ProviderGithub); code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unlinked provider, got %d", http.StatusNotFound, code)
+	}
+	if code := unlink(ProviderGoogle); code != http.StatusConflict {
+		t.Errorf("expected status %d for the last identity, got %d", http.StatusConflict, code)
+	}
+
+	identities, err := testService.GetIdentities(ctx, user.ID)
+	if err != nil || len(identities) != 1 || identities[0].Provider != ProviderGoogle {
+		t.Errorf("expected only the Google identity to be left, got %+v with %v", identities, err)
+	}
+}
+
+func TestMergeUsersIntegration(t *testing.T) {
+	ctx := context.Background()
+	target, err := testService.GetOrCreateUserByGoogleId(ctx, "google-330001", "Merge Target", "mergetarget", "")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	source, err := testService.GetOrCreateUserByGithubId(ctx, 330002, "Merge Source", "mergesource", "")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	follower, err := testService.GetOrCreateUserByGithubId(ctx, 330003, "Follower", "follower", "")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	shared, onlySource := uuid.New(), uuid.New()
+	mustExec(t, `INSERT INTO films (film_id, external_id, title) VALUES ($1, 330001, 'Shared'), ($2, 330002, 'Only Source')`, shared, onlySource)
+
+	now := time.Now()
+	for _, rating := range []struct {
+		userId, filmId uuid.UUID
+	}{{target.ID, shared}, {source.ID, shared}, {source.ID, onlySource}} {
+		mustExec(t, `INSERT INTO user_film_ratings (user_film_rating_id, user_id, film_id, elo_rating, number_of_comparisons, last_updated, initial_rating)
+			VALUES ($1, $2, $3, 1000, 0, $4, 3)`, uuid.New(), rating.userId, rating.filmId, now)
+	}
+	mustExec(t, `INSERT INTO reviews (review_id, content, date, rating, film_id, user_id) VALUES ($1, 'Great', $2, 4, $3, $4)`,
+		uuid.New(), now, onlySource, source.ID)
+	mustExec(t, `INSERT INTO comparison_histories (comparison_history_id, user_id, film_a_film_id, film_b_film_id, winning_film_film_id, comparison_date, was_equal)
+		VALUES ($1, $2, $3, $4, $3, $5, FALSE)`, uuid.New(), source.ID, shared, onlySource, now)
+	mustExec(t, `INSERT INTO watchlist (watchlist_entry_id, user_id, film_id) VALUES ($1, $2, $4), ($3, $5, $4)`,
+		uuid.New(), target.ID, uuid.New(), shared, source.ID)
+	mustExec(t, `INSERT INTO film_graph_nodes (user_id, external_film_id, title) VALUES ($1, 330001, 'Shared'), ($2, 330001, 'Shared'), ($2, 330002, 'Only Source')`,
+		target.ID, source.ID)
+	mustExec(t, `INSERT INTO film_recommendation (film_recommendation_id, user_id, external_film_id, has_seen, has_been_recommended, recommendations_generated)
+		VALUES ($1, $2, 330001, FALSE, TRUE, FALSE), ($3, $4, 330001, TRUE, FALSE, TRUE)`, uuid.New(), target.ID, uuid.New(), source.ID)
+	mustExec(t, `INSERT INTO user_follows (follower_id, followee_id) VALUES ($1, $2), ($3, $1), ($3, $2)`,
+		target.ID, source.ID, follower.ID)
+
+	if err := testService.MergeUsers(ctx, source.ID, target.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := testService.GetUserById(ctx, source.ID); err != ErrUserNotFound {
+		t.Errorf("expected the source user to be deleted, got %v", err)
+	}
+	merged, err := testService.GetOrCreateUserByGithubId(ctx, 330002, "Merge Source", "mergesource", "")
+	if err != nil || merged.ID != target.ID {
+		t.Errorf("expected the source's GitHub login to find the target, got %v with %v", merged, err)
+	}
+
+	counts := map[string]int{
+		`SELECT COUNT(*) FROM user_film_ratings WHERE user_id = $1`:                                         2,
+		`SELECT COUNT(*) FROM reviews WHERE user_id = $1`:                                                   1,
+		`SELECT COUNT(*) FROM comparison_histories WHERE user_id = $1`:                                      1,
+		`SELECT COUNT(*) FROM watchlist WHERE user_id = $1`:                                                 1,
+		`SELECT COUNT(*) FROM film_graph_nodes WHERE user_id = $1`:                                          2,
+		`SELECT COUNT(*) FROM film_recommendation WHERE user_id = $1`:                                       1,
+		`SELECT COUNT(*) FROM film_recommendation WHERE user_id = $1 AND has_seen AND has_been_recommended`: 1,
+		`SELECT COUNT(*) FROM user_follows WHERE follower_id = $1 OR followee_id = $1`:                      1,
+	}
+	for query, want := range counts {
+		var got int
+		if err := testDB.QueryRowContext(ctx, query, target.ID).Scan(&got); err != nil {
+			t.Fatalf("failed to count %q: %v", query, err)
+		}
+		if got != want {
+			t.Errorf("%s = %d, want %d", query, got, want)
+		}
+	}
+
+	if err := testService.MergeUsers(ctx, target.ID, target.ID); err != ErrMergeSameUser {
+		t.Errorf("expected %v, got %v", ErrMergeSameUser, err)
+	}
+	if err := testService.MergeUsers(ctx, source.ID, target.ID); err != ErrUserNotFound {
+		t.Errorf("expected %v for a user that's already been merged, got %v", ErrUserNotFound, err)
+	}
+}
+
+func mustExec(t *testing.T, query string, args ...any) {
+	t.Helper()
+	if _, err := testDB.Exec(query, args...); err != nil {
+		t.Fatalf("failed to run %q: %v", query, err)
+	}
+}
@@ -3,6 +3,7 @@ package users
 import (
 	"context"
 	"errors"
+	"strconv"
 
 	"cinema.log.server.golang/internal/domain"
 	"github.com/google/uuid"
@@ -14,6 +15,10 @@ var (
 	ErrNoId                  = errors.New("user ID is required")
 	ErrInvalidId             = errors.New("invalid user ID format")
 	ErrInvalidJson           = errors.New("invalid JSON format")
+	// identity errors
+	ErrProviderAlreadyLinked     = errors.New("a login for this provider is already linked, unlink it first")
+	ErrIdentityLinkedToOtherUser = errors.New("this login belongs to another account")
+	ErrMergeSameUser             = errors.New("can't merge an account into itself")
 	//server errors
 	ErrEncoding = errors.New("error encoding response")
 	ErrServer   = errors.New("internal server error")
@@ -26,13 +31,16 @@ type service struct {
 type Store interface {
 	GetAllUsers(ctx context.Context) ([]*domain.User, error)
 	GetUserById(ctx context.Context, id uuid.UUID) (*domain.User, error)
-	GetOrCreateUserByGithubId(ctx context.Context, githubId int64, name string,
-		username string, avatarUrl string) (*domain.User, error)
-	GetOrCreateUserByGoogleId(ctx context.Context, googleId string, name string,
+	GetUserByIdentity(ctx context.Context, provider string, subject string) (*domain.User, error)
+	GetOrCreateUserByIdentity(ctx context.Context, identity *domain.UserIdentity, name string,
 		username string, avatarUrl string) (*domain.User, error)
 	CreateUser(ctx context.Context, user *domain.User) (*domain.User, error)
 	UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	GetIdentities(ctx context.Context, userId uuid.UUID) ([]domain.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error
+	DeleteIdentity(ctx context.Context, userId uuid.UUID, provider string) error
+	MergeUsers(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error
 }
 
 func NewService(store Store) UserService {
@@ -51,12 +59,68 @@ func (s *service) GetUserById(ctx context.Context, id uuid.UUID) (*domain.User,
 
 func (s *service) GetOrCreateUserByGithubId(ctx context.Context, githubId int64,
 	name string, username string, avatarUrl string) (*domain.User, error) {
-	return s.store.GetOrCreateUserByGithubId(ctx, githubId, name, username, avatarUrl)
+	identity := &domain.UserIdentity{Provider: ProviderGithub, Subject: strconv.FormatInt(githubId, 10), ProviderUsername: username}
+	return s.store.GetOrCreateUserByIdentity(ctx, identity, name, username, avatarUrl)
 }
 
 func (s *service) GetOrCreateUserByGoogleId(ctx context.Context, googleId string,
 	name string, username string, avatarUrl string) (*domain.User, error) {
-	return s.store.GetOrCreateUserByGoogleId(ctx, googleId, name, username, avatarUrl)
+	identity := &domain.UserIdentity{Provider: ProviderGoogle, Subject: googleId, ProviderUsername: username}
+	return s.store.GetOrCreateUserByIdentity(ctx, identity, name, username, avatarUrl)
+}
+
+func (s *service) GetOrCreateUserByIdentity(ctx context.Context, identity *domain.UserIdentity,
+	name string, username string, avatarUrl string) (*domain.User, error) {
+	return s.store.GetOrCreateUserByIdentity(ctx, identity, name, username, avatarUrl)
+}
+
+func (s *service) GetUserByIdentity(ctx context.Context, provider string, subject string) (*domain.User, error) {
+	return s.store.GetUserByIdentity(ctx, provider, subject)
+}
+
+func (s *service) GetIdentities(ctx context.Context, userId uuid.UUID) ([]domain.UserIdentity, error) {
+	return s.store.GetIdentities(ctx, userId)
+}
+
+// LinkIdentity adds another way for the user to log in. Linking a login the user already has does nothing,
+// a login that belongs to someone else is ErrIdentityLinkedToOtherUser and the accounts have to be merged.
+func (s *service) LinkIdentity(ctx context.Context, userId uuid.UUID, identity *domain.UserIdentity) error {
+	identities, err := s.store.GetIdentities(ctx, userId)
+	if err != nil {
+		return err
+	}
+	for _, linked := range identities {
+		if linked.Provider != identity.Provider {
+			continue
+		}
+		if linked.Subject == identity.Subject {
+			return nil
+		}
+		return ErrProviderAlreadyLinked
+	}
+
+	_, err = s.store.GetUserByIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return ErrIdentityLinkedToOtherUser
+	}
+	if err != ErrUserNotFound {
+		return err
+	}
+
+	identity.UserID = userId
+	return s.store.CreateIdentity(ctx, identity)
+}
+
+func (s *service) UnlinkIdentity(ctx context.Context, userId uuid.UUID, provider string) error {
+	return s.store.DeleteIdentity(ctx, userId, provider)
+}
+
+// MergeUsers moves everything the source user has to the target user and deletes the source user
+func (s *service) MergeUsers(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error {
+	if sourceId == targetId {
+		return ErrMergeSameUser
+	}
+	return s.store.MergeUsers(ctx, sourceId, targetId)
 }
 
 func (s *service) CreateUser(ctx context.Context, user *domain.User) (*domain.User, error) {
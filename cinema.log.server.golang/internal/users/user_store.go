@@ -4,16 +4,31 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strconv"
 
 	"cinema.log.server.golang/internal/domain"
 	"github.com/google/uuid"
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
-	ErrUserExists   = errors.New("user already exists")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrUserExists       = errors.New("user already exists")
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrLastIdentity     = errors.New("can't unlink the only way to log in")
 )
 
+const (
+	ProviderGithub = "github"
+	ProviderGoogle = "google"
+)
+
+// userColumns selects a user aliased as u, the GitHub and Google ids come from their identities
+const userColumns = /* sql */ `
+	u.user_id,
+	(SELECT CAST(subject AS BIGINT) FROM user_identities WHERE user_id = u.user_id AND provider = 'github'),
+	(SELECT subject FROM user_identities WHERE user_id = u.user_id AND provider = 'google'),
+	u.name, u.username, u.profile_pic_url, u.created_at, u.updated_at`
+
 type store struct {
 	db *sql.DB
 }
@@ -24,10 +39,23 @@ func NewStore(db *sql.DB) Store {
 	}
 }
 
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*domain.User, error) {
+	user := &domain.User{}
+	err := row.Scan(&user.ID, &user.GithubId, &user.GoogleId, &user.Name, &user.Username, &user.ProfilePicURL, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (s *store) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
 	var users []*domain.User
 
-	query := `SELECT user_id, github_id, google_id, name, username, profile_pic_url, created_at, updated_at FROM users`
+	query := `SELECT ` + userColumns + ` FROM users u`
 	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
@@ -35,8 +63,8 @@ func (s *store) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		user := &domain.User{}
-		if err := rows.Scan(&user.ID, &user.GithubId, &user.GoogleId, &user.Name, &user.Username, &user.ProfilePicURL, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -50,13 +78,9 @@ func (s *store) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
 }
 
 func (s *store) GetUserById(ctx context.Context, id uuid.UUID) (*domain.User, error) {
-	query := `SELECT user_id, github_id, name, username, profile_pic_url, created_at, updated_at 
-	          FROM users WHERE user_id = $1`
-
-	user := &domain.User{}
-	row := s.db.QueryRowContext(ctx, query, id)
+	query := `SELECT ` + userColumns + ` FROM users u WHERE u.user_id = $1`
 
-	err := row.Scan(&user.ID, &user.GithubId, &user.Name, &user.Username, &user.ProfilePicURL, &user.CreatedAt, &user.UpdatedAt)
+	user, err := scanUser(s.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserNotFound
@@ -67,35 +91,69 @@ func (s *store) GetUserById(ctx context.Context, id uuid.UUID) (*domain.User, er
 	return user, nil
 }
 
+// CreateUser creates the user along with identities for their GitHub and Google ids, if they have them
 func (s *store) CreateUser(ctx context.Context, user *domain.User) (*domain.User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var identities []*domain.UserIdentity
+	if user.GithubId != nil {
+		identities = append(identities, &domain.UserIdentity{Provider: ProviderGithub, Subject: strconv.FormatInt(*user.GithubId, 10), ProviderUsername: user.Username})
+	}
+	if user.GoogleId != nil {
+		identities = append(identities, &domain.UserIdentity{Provider: ProviderGoogle, Subject: *user.GoogleId, ProviderUsername: user.Username})
+	}
+
+	if err := createUser(ctx, tx, user, identities...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func createUser(ctx context.Context, tx *sql.Tx, user *domain.User, identities ...*domain.UserIdentity) error {
 	// Generate a new UUID if not provided
 	if user.ID == uuid.Nil {
 		user.ID = uuid.New()
 	}
 
 	query := `
-		INSERT INTO users (user_id, github_id, google_id, name, username, profile_pic_url, created_at, updated_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW()) 
+		INSERT INTO users (user_id, name, username, profile_pic_url, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
 		RETURNING created_at, updated_at`
 
-	err := s.db.QueryRowContext(ctx, query, user.ID, user.GithubId, user.GoogleId, user.Name, user.Username, user.ProfilePicURL).
+	err := tx.QueryRowContext(ctx, query, user.ID, user.Name, user.Username, user.ProfilePicURL).
 		Scan(&user.CreatedAt, &user.UpdatedAt)
-
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return user, nil
+	for _, identity := range identities {
+		identity.UserID = user.ID
+		if err := createIdentity(ctx, tx, identity); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
+// UpdateUser updates the user's profile, their identities are changed by linking and unlinking
 func (s *store) UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error) {
 	query := `
-		UPDATE users 
-		SET github_id = $2, google_id = $3, name = $4, username = $5, profile_pic_url = $6, updated_at = NOW()
+		UPDATE users
+		SET name = $2, username = $3, profile_pic_url = $4, updated_at = NOW()
 		WHERE user_id = $1
 		RETURNING updated_at`
 
-	err := s.db.QueryRowContext(ctx, query, user.ID, user.GithubId, user.GoogleId, user.Name, user.Username, user.ProfilePicURL).Scan(&user.UpdatedAt)
+	err := s.db.QueryRowContext(ctx, query, user.ID, user.Name, user.Username, user.ProfilePicURL).Scan(&user.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserNotFound
@@ -126,26 +184,17 @@ func (s *store) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func (s *store) GetOrCreateUserByGithubId(ctx context.Context, githubID int64,
-	name string, username string, avatarUrl string) (*domain.User, error) {
-	query := `SELECT user_id, github_id, google_id, name, username, profile_pic_url, created_at, updated_at 
-			  FROM users WHERE github_id = $1`
-
-	user := &domain.User{}
-	row := s.db.QueryRowContext(ctx, query, githubID)
+func (s *store) GetUserByIdentity(ctx context.Context, provider string, subject string) (*domain.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users u
+		JOIN user_identities ui ON ui.user_id = u.user_id
+		WHERE ui.provider = $1 AND ui.subject = $2`
 
-	err := row.Scan(&user.ID, &user.GithubId, &user.GoogleId, &user.Name, &user.Username, &user.ProfilePicURL, &user.CreatedAt, &user.UpdatedAt)
+	user, err := scanUser(s.db.QueryRowContext(ctx, query, provider, subject))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			// If not found, create a new user
-			githubIDPtr := &githubID
-			user = &domain.User{
-				GithubId:      githubIDPtr,
-				Name:          name,
-				Username:      username,
-				ProfilePicURL: avatarUrl,
-			}
-			return s.CreateUser(ctx, user)
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -153,29 +202,217 @@ func (s *store) GetOrCreateUserByGithubId(ctx context.Context, githubID int64,
 	return user, nil
 }
 
-func (s *store) GetOrCreateUserByGoogleId(ctx context.Context, googleID string,
+// GetOrCreateUserByIdentity finds the user who logs in with the identity, creating them if it's the first time
+func (s *store) GetOrCreateUserByIdentity(ctx context.Context, identity *domain.UserIdentity,
 	name string, username string, avatarUrl string) (*domain.User, error) {
-	query := `SELECT user_id, github_id, google_id, name, username, profile_pic_url, created_at, updated_at 
-			  FROM users WHERE google_id = $1`
+	user, err := s.GetUserByIdentity(ctx, identity.Provider, identity.Subject)
+	if err != ErrUserNotFound {
+		return user, err
+	}
 
-	user := &domain.User{}
-	row := s.db.QueryRowContext(ctx, query, googleID)
+	// If not found, create a new user
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-	err := row.Scan(&user.ID, &user.GithubId, &user.GoogleId, &user.Name, &user.Username, &user.ProfilePicURL, &user.CreatedAt, &user.UpdatedAt)
+	user = &domain.User{
+		Name:          name,
+		Username:      username,
+		ProfilePicURL: avatarUrl,
+	}
+	if err := createUser(ctx, tx, user, identity); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Read back so the GitHub and Google ids are filled in
+	return s.GetUserById(ctx, user.ID)
+}
+
+func (s *store) GetIdentities(ctx context.Context, userId uuid.UUID) ([]domain.UserIdentity, error) {
+	query := /* sql */ `
+		SELECT user_identity_id, user_id, provider, subject, COALESCE(provider_username, ''), linked_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY linked_at, provider
+	`
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []domain.UserIdentity{}
+	for rows.Next() {
+		var identity domain.UserIdentity
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.ProviderUsername, &identity.LinkedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+func (s *store) CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createIdentity(ctx, tx, identity); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func createIdentity(ctx context.Context, tx *sql.Tx, identity *domain.UserIdentity) error {
+	if identity.ID == uuid.Nil {
+		identity.ID = uuid.New()
+	}
+
+	query := /* sql */ `
+		INSERT INTO user_identities (user_identity_id, user_id, provider, subject, provider_username, linked_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING linked_at
+	`
+	return tx.QueryRowContext(ctx, query, identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.ProviderUsername).
+		Scan(&identity.LinkedAt)
+}
+
+// DeleteIdentity unlinks the user's identity for the provider, as long as they have another way to log in
+func (s *store) DeleteIdentity(ctx context.Context, userId uuid.UUID, provider string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the user so two unlinks at once can't leave them with no identities
+	var locked uuid.UUID
+	err = tx.QueryRowContext(ctx, `SELECT user_id FROM users WHERE user_id = $1 FOR UPDATE`, userId).Scan(&locked)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			// If not found, create a new user
-			googleIDPtr := &googleID
-			user = &domain.User{
-				GoogleId:      googleIDPtr,
-				Name:          name,
-				Username:      username,
-				ProfilePicURL: avatarUrl,
-			}
-			return s.CreateUser(ctx, user)
+			return ErrUserNotFound
 		}
-		return nil, err
+		return err
 	}
 
-	return user, nil
+	var count int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_identities WHERE user_id = $1`, userId).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`, userId, provider)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrIdentityNotFound
+	}
+	if count <= 1 {
+		return ErrLastIdentity
+	}
+
+	return tx.Commit()
+}
+
+// mergeStatements move everything from the source user ($1) to the target user ($2). Rows that would clash
+// with one the target already has are dropped, keeping the target's.
+var mergeStatements = []string{
+	// Nothing in these can clash
+	`UPDATE reviews SET user_id = $2 WHERE user_id = $1`,
+	`UPDATE comparison_histories SET user_id = $2 WHERE user_id = $1`,
+	`UPDATE diary_entries SET user_id = $2 WHERE user_id = $1`,
+	`UPDATE film_lists SET user_id = $2 WHERE user_id = $1`,
+
+	// One rating per film, ratings are replayed from the combined comparisons afterwards
+	`DELETE FROM user_film_ratings s WHERE s.user_id = $1
+		AND EXISTS (SELECT 1 FROM user_film_ratings t WHERE t.user_id = $2 AND t.film_id = s.film_id)`,
+	`UPDATE user_film_ratings SET user_id = $2 WHERE user_id = $1`,
+
+	`DELETE FROM watchlist s WHERE s.user_id = $1
+		AND EXISTS (SELECT 1 FROM watchlist t WHERE t.user_id = $2 AND t.film_id = s.film_id)`,
+	`UPDATE watchlist SET user_id = $2 WHERE user_id = $1`,
+
+	// A film either account has seen or been recommended stays that way
+	`UPDATE film_recommendation t
+		SET has_seen = t.has_seen OR s.has_seen,
+			has_been_recommended = t.has_been_recommended OR s.has_been_recommended,
+			recommendations_generated = t.recommendations_generated OR s.recommendations_generated,
+			recommended_at = GREATEST(t.recommended_at, s.recommended_at)
+		FROM film_recommendation s
+		WHERE t.user_id = $2 AND s.user_id = $1 AND s.external_film_id = t.external_film_id`,
+	`DELETE FROM film_recommendation s WHERE s.user_id = $1
+		AND EXISTS (SELECT 1 FROM film_recommendation t WHERE t.user_id = $2 AND t.external_film_id = s.external_film_id)`,
+	`UPDATE film_recommendation SET user_id = $2 WHERE user_id = $1`,
+
+	`DELETE FROM recommendation_reasons s WHERE s.user_id = $1
+		AND EXISTS (SELECT 1 FROM recommendation_reasons t WHERE t.user_id = $2 AND t.external_film_id = s.external_film_id AND t.source = s.source)`,
+	`UPDATE recommendation_reasons SET user_id = $2 WHERE user_id = $1`,
+
+	`DELETE FROM film_graph_nodes s WHERE s.user_id = $1
+		AND EXISTS (SELECT 1 FROM film_graph_nodes t WHERE t.user_id = $2 AND t.external_film_id = s.external_film_id)`,
+	`UPDATE film_graph_nodes SET user_id = $2 WHERE user_id = $1`,
+	`DELETE FROM film_graph_edges s WHERE s.user_id = $1
+		AND EXISTS (SELECT 1 FROM film_graph_edges t WHERE t.user_id = $2 AND t.from_film_id = s.from_film_id AND t.to_film_id = s.to_film_id)`,
+	`UPDATE film_graph_edges SET user_id = $2 WHERE user_id = $1`,
+
+	// Following each other would become following yourself
+	`DELETE FROM user_follows WHERE (follower_id = $1 AND followee_id = $2) OR (follower_id = $2 AND followee_id = $1)`,
+	`DELETE FROM user_follows s WHERE s.follower_id = $1
+		AND EXISTS (SELECT 1 FROM user_follows t WHERE t.follower_id = $2 AND t.followee_id = s.followee_id)`,
+	`UPDATE user_follows SET follower_id = $2 WHERE follower_id = $1`,
+	`DELETE FROM user_follows s WHERE s.followee_id = $1
+		AND EXISTS (SELECT 1 FROM user_follows t WHERE t.followee_id = $2 AND t.follower_id = s.follower_id)`,
+	`UPDATE user_follows SET followee_id = $2 WHERE followee_id = $1`,
+
+	// The target keeps its own login for a provider they both have
+	`UPDATE user_identities s SET user_id = $2 WHERE s.user_id = $1
+		AND NOT EXISTS (SELECT 1 FROM user_identities t WHERE t.user_id = $2 AND t.provider = s.provider)`,
+}
+
+// MergeUsers moves the source user's reviews, ratings, comparisons, recommendations, graph and everything else
+// to the target user, then deletes the source user along with their refresh tokens
+func (s *store) MergeUsers(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock both users so neither changes underneath the merge
+	var count int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM (SELECT user_id FROM users WHERE user_id IN ($1, $2) FOR UPDATE) locked`,
+		sourceId, targetId).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != 2 {
+		return ErrUserNotFound
+	}
+
+	for _, statement := range mergeStatements {
+		if _, err := tx.ExecContext(ctx, statement, sourceId, targetId); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE user_id = $1`, sourceId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,112 @@
+package watchlist
+
+import (
+	"context"
+	"net/http"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	WatchlistService WatchlistService
+}
+
+type WatchlistService interface {
+	GetWatchlist(ctx context.Context, userId uuid.UUID) ([]domain.WatchlistEntryDetail, error)
+	AddToWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, priority int, note string, source string) (*domain.WatchlistEntry, error)
+	RemoveFromWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
+}
+
+func NewHandler(watchlistService WatchlistService) *Handler {
+	return &Handler{
+		WatchlistService: watchlistService,
+	}
+}
+
+type AddToWatchlistRequest struct {
+	FilmId   uuid.UUID `json:"filmId"`
+	Priority int       `json:"priority"` // 1 to 5, defaults to 3
+	Note     string    `json:"note"`
+	Source   string    `json:"source"` // search, recommendation or friend, defaults to search
+}
+
+func (h *Handler) GetWatchlist(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	entries, err := h.WatchlistService.GetWatchlist(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get watchlist", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSON(w, entries)
+}
+
+func (h *Handler) AddToWatchlist(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req AddToWatchlistRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.FilmId == uuid.Nil {
+		http.Error(w, "filmId is required", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.WatchlistService.AddToWatchlist(r.Context(), user.ID, req.FilmId, req.Priority, req.Note, req.Source)
+	if err != nil {
+		switch err {
+		case ErrInvalidPriority, ErrInvalidSource:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case ErrFilmNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to add film to watchlist", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	utils.SendJSON(w, entry)
+}
+
+func (h *Handler) RemoveFromWatchlist(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user from context
+	user, ok := r.Context().Value(middleware.KeyUser).(*domain.User)
+	if !ok || user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filmId, err := utils.ParseUUID(r.PathValue("filmId"))
+	if err != nil {
+		http.Error(w, "Invalid film ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.WatchlistService.RemoveFromWatchlist(r.Context(), user.ID, filmId); err != nil {
+		if err == ErrEntryNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to remove film from watchlist", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,190 @@
+package watchlist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/middleware"
+	"github.com/google/uuid"
+)
+
+type mockWatchlistService struct {
+	getWatchlistFunc        func(ctx context.Context, userId uuid.UUID) ([]domain.WatchlistEntryDetail, error)
+	addToWatchlistFunc      func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, priority int, note string, source string) (*domain.WatchlistEntry, error)
+	removeFromWatchlistFunc func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
+}
+
+func (m *mockWatchlistService) GetWatchlist(ctx context.Context, userId uuid.UUID) ([]domain.WatchlistEntryDetail, error) {
+	if m.getWatchlistFunc != nil {
+		return m.getWatchlistFunc(ctx, userId)
+	}
+	return []domain.WatchlistEntryDetail{}, nil
+}
+
+func (m *mockWatchlistService) AddToWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, priority int, note string, source string) (*domain.WatchlistEntry, error) {
+	if m.addToWatchlistFunc != nil {
+		return m.addToWatchlistFunc(ctx, userId, filmId, priority, note, source)
+	}
+	return &domain.WatchlistEntry{ID: uuid.New(), UserID: userId, FilmID: filmId, Priority: priority, Note: note, Source: source}, nil
+}
+
+func (m *mockWatchlistService) RemoveFromWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	if m.removeFromWatchlistFunc != nil {
+		return m.removeFromWatchlistFunc(ctx, userId, filmId)
+	}
+	return nil
+}
+
+func withUser(r *http.Request, userId uuid.UUID) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), middleware.KeyUser, &domain.User{ID: userId}))
+}
+
+func TestHandler_GetWatchlist(t *testing.T) {
+	userId := uuid.New()
+	handler := NewHandler(&mockWatchlistService{
+		getWatchlistFunc: func(ctx context.Context, id uuid.UUID) ([]domain.WatchlistEntryDetail, error) {
+			return []domain.WatchlistEntryDetail{{Entry: domain.WatchlistEntry{UserID: id, Priority: 1}, FilmTitle: "Stalker"}}, nil
+		},
+	})
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/watchlist", nil), userId)
+	w := httptest.NewRecorder()
+	handler.GetWatchlist(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var entries []domain.WatchlistEntryDetail
+	if err := json.NewDecoder(w.Body).Decode(&entries); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Entry.UserID != userId {
+		t.Errorf("expected the user's watchlist, got %+v", entries)
+	}
+}
+
+func TestHandler_GetWatchlist_Unauthorized(t *testing.T) {
+	handler := NewHandler(&mockWatchlistService{})
+
+	w := httptest.NewRecorder()
+	handler.GetWatchlist(w, httptest.NewRequest(http.MethodGet, "/watchlist", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_AddToWatchlist(t *testing.T) {
+	filmId := uuid.New()
+	handler := NewHandler(&mockWatchlistService{})
+
+	body, _ := json.Marshal(AddToWatchlistRequest{FilmId: filmId, Priority: 2, Note: "Everyone says so", Source: domain.WatchlistSourceFriend})
+	req := withUser(httptest.NewRequest(http.MethodPost, "/watchlist", bytes.NewReader(body)), uuid.New())
+	w := httptest.NewRecorder()
+	handler.AddToWatchlist(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d, body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var entry domain.WatchlistEntry
+	if err := json.NewDecoder(w.Body).Decode(&entry); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if entry.FilmID != filmId || entry.Priority != 2 || entry.Source != domain.WatchlistSourceFriend {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
+
+func TestHandler_AddToWatchlist_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		expected int
+	}{
+		{"invalid json", "{", nil, http.StatusBadRequest},
+		{"missing film", `{"priority": 1}`, nil, http.StatusBadRequest},
+		{"invalid priority", `{"filmId": "` + uuid.NewString() + `", "priority": 9}`, ErrInvalidPriority, http.StatusBadRequest},
+		{"invalid source", `{"filmId": "` + uuid.NewString() + `", "source": "radio"}`, ErrInvalidSource, http.StatusBadRequest},
+		{"unknown film", `{"filmId": "` + uuid.NewString() + `"}`, ErrFilmNotFound, http.StatusNotFound},
+		{"store failure", `{"filmId": "` + uuid.NewString() + `"}`, errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockWatchlistService{
+				addToWatchlistFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, priority int, note string, source string) (*domain.WatchlistEntry, error) {
+					return nil, tt.err
+				},
+			})
+
+			req := withUser(httptest.NewRequest(http.MethodPost, "/watchlist", bytes.NewBufferString(tt.body)), uuid.New())
+			w := httptest.NewRecorder()
+			handler.AddToWatchlist(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_RemoveFromWatchlist(t *testing.T) {
+	filmId := uuid.New()
+	var removed uuid.UUID
+	handler := NewHandler(&mockWatchlistService{
+		removeFromWatchlistFunc: func(ctx context.Context, userId uuid.UUID, id uuid.UUID) error {
+			removed = id
+			return nil
+		},
+	})
+
+	req := withUser(httptest.NewRequest(http.MethodDelete, "/watchlist/"+filmId.String(), nil), uuid.New())
+	req.SetPathValue("filmId", filmId.String())
+	w := httptest.NewRecorder()
+	handler.RemoveFromWatchlist(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if removed != filmId {
+		t.Errorf("expected %v to be removed, got %v", filmId, removed)
+	}
+}
+
+func TestHandler_RemoveFromWatchlist_NotFound(t *testing.T) {
+	handler := NewHandler(&mockWatchlistService{
+		removeFromWatchlistFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+			return ErrEntryNotFound
+		},
+	})
+
+	filmId := uuid.NewString()
+	req := withUser(httptest.NewRequest(http.MethodDelete, "/watchlist/"+filmId, nil), uuid.New())
+	req.SetPathValue("filmId", filmId)
+	w := httptest.NewRecorder()
+	handler.RemoveFromWatchlist(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandler_RemoveFromWatchlist_InvalidFilmId(t *testing.T) {
+	handler := NewHandler(&mockWatchlistService{})
+
+	req := withUser(httptest.NewRequest(http.MethodDelete, "/watchlist/abc", nil), uuid.New())
+	req.SetPathValue("filmId", "abc")
+	w := httptest.NewRecorder()
+	handler.RemoveFromWatchlist(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,82 @@
+package watchlist
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEntryNotFound   = errors.New("film is not on the watchlist")
+	ErrFilmNotFound    = errors.New("film not found")
+	ErrInvalidPriority = errors.New("priority must be between 1 and 5")
+	ErrInvalidSource   = errors.New("source must be one of search, recommendation or friend")
+)
+
+const (
+	HighestPriority = 1
+	LowestPriority  = 5
+	DefaultPriority = 3
+)
+
+type Service struct {
+	WatchlistStore WatchlistStore
+}
+
+type WatchlistStore interface {
+	GetWatchlist(ctx context.Context, userId uuid.UUID) ([]domain.WatchlistEntryDetail, error)
+	AddToWatchlist(ctx context.Context, entry domain.WatchlistEntry) (*domain.WatchlistEntry, error)
+	RemoveFromWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
+	MarkWatched(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (bool, error)
+}
+
+func NewService(watchlistStore WatchlistStore) *Service {
+	return &Service{
+		WatchlistStore: watchlistStore,
+	}
+}
+
+// GetWatchlist returns the user's watchlist, most wanted first
+func (s *Service) GetWatchlist(ctx context.Context, userId uuid.UUID) ([]domain.WatchlistEntryDetail, error) {
+	return s.WatchlistStore.GetWatchlist(ctx, userId)
+}
+
+// AddToWatchlist adds a film to the user's watchlist, a zero priority and an empty source get the defaults
+func (s *Service) AddToWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID, priority int, note string, source string) (*domain.WatchlistEntry, error) {
+	if priority == 0 {
+		priority = DefaultPriority
+	}
+	if priority < HighestPriority || priority > LowestPriority {
+		return nil, ErrInvalidPriority
+	}
+
+	switch source {
+	case "":
+		source = domain.WatchlistSourceSearch
+	case domain.WatchlistSourceSearch, domain.WatchlistSourceRecommendation, domain.WatchlistSourceFriend:
+	default:
+		return nil, ErrInvalidSource
+	}
+
+	return s.WatchlistStore.AddToWatchlist(ctx, domain.WatchlistEntry{
+		ID:       uuid.New(),
+		UserID:   userId,
+		FilmID:   filmId,
+		Priority: priority,
+		Note:     note,
+		Source:   source,
+		AddedAt:  time.Now(),
+	})
+}
+
+func (s *Service) RemoveFromWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	return s.WatchlistStore.RemoveFromWatchlist(ctx, userId, filmId)
+}
+
+// MarkWatched is called once the user has seen a film, it reports whether the film was on their watchlist
+func (s *Service) MarkWatched(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (bool, error) {
+	return s.WatchlistStore.MarkWatched(ctx, userId, filmId)
+}
@@ -0,0 +1,101 @@
+package watchlist
+
+import (
+	"context"
+	"testing"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type mockWatchlistStore struct {
+	getWatchlistFunc        func(ctx context.Context, userId uuid.UUID) ([]domain.WatchlistEntryDetail, error)
+	addToWatchlistFunc      func(ctx context.Context, entry domain.WatchlistEntry) (*domain.WatchlistEntry, error)
+	removeFromWatchlistFunc func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error
+	markWatchedFunc         func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (bool, error)
+}
+
+func (m *mockWatchlistStore) GetWatchlist(ctx context.Context, userId uuid.UUID) ([]domain.WatchlistEntryDetail, error) {
+	if m.getWatchlistFunc != nil {
+		return m.getWatchlistFunc(ctx, userId)
+	}
+	return []domain.WatchlistEntryDetail{}, nil
+}
+
+func (m *mockWatchlistStore) AddToWatchlist(ctx context.Context, entry domain.WatchlistEntry) (*domain.WatchlistEntry, error) {
+	if m.addToWatchlistFunc != nil {
+		return m.addToWatchlistFunc(ctx, entry)
+	}
+	return &entry, nil
+}
+
+func (m *mockWatchlistStore) RemoveFromWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	if m.removeFromWatchlistFunc != nil {
+		return m.removeFromWatchlistFunc(ctx, userId, filmId)
+	}
+	return nil
+}
+
+func (m *mockWatchlistStore) MarkWatched(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (bool, error) {
+	if m.markWatchedFunc != nil {
+		return m.markWatchedFunc(ctx, userId, filmId)
+	}
+	return false, nil
+}
+
+func TestService_AddToWatchlist_Defaults(t *testing.T) {
+	service := NewService(&mockWatchlistStore{})
+	userId, filmId := uuid.New(), uuid.New()
+
+	entry, err := service.AddToWatchlist(context.Background(), userId, filmId, 0, "", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if entry.Priority != DefaultPriority || entry.Source != domain.WatchlistSourceSearch {
+		t.Errorf("expected default priority and source, got %d and %s", entry.Priority, entry.Source)
+	}
+	if entry.UserID != userId || entry.FilmID != filmId || entry.AddedAt.IsZero() {
+		t.Errorf("expected entry for the user and film with a date added, got %+v", entry)
+	}
+}
+
+func TestService_AddToWatchlist_Validation(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority int
+		source   string
+		err      error
+	}{
+		{"most wanted", 1, domain.WatchlistSourceRecommendation, nil},
+		{"least wanted", 5, domain.WatchlistSourceFriend, nil},
+		{"priority too high", 6, "", ErrInvalidPriority},
+		{"negative priority", -1, "", ErrInvalidPriority},
+		{"unknown source", 2, "newsletter", ErrInvalidSource},
+	}
+
+	service := NewService(&mockWatchlistStore{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.AddToWatchlist(context.Background(), uuid.New(), uuid.New(), tt.priority, "", tt.source)
+			if err != tt.err {
+				t.Errorf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestService_MarkWatched(t *testing.T) {
+	service := NewService(&mockWatchlistStore{
+		markWatchedFunc: func(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (bool, error) {
+			return true, nil
+		},
+	})
+
+	removed, err := service.MarkWatched(context.Background(), uuid.New(), uuid.New())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !removed {
+		t.Error("expected the film to come off the watchlist")
+	}
+}
@@ -0,0 +1,169 @@
+package watchlist
+
+import (
+	"context"
+	"database/sql"
+
+	"cinema.log.server.golang/internal/domain"
+	"github.com/google/uuid"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) WatchlistStore {
+	return &store{
+		db: db,
+	}
+}
+
+func (s *store) GetWatchlist(ctx context.Context, userId uuid.UUID) ([]domain.WatchlistEntryDetail, error) {
+	query := /* sql */ `
+		SELECT w.watchlist_entry_id, w.user_id, w.film_id, w.priority, w.note, w.source, w.added_at, f.title, f.release_year, f.poster_url
+		FROM watchlist w
+		JOIN films f ON w.film_id = f.film_id
+		WHERE w.user_id = $1
+		ORDER BY w.priority ASC, w.added_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.WatchlistEntryDetail{}
+	for rows.Next() {
+		var entry domain.WatchlistEntryDetail
+		err := rows.Scan(
+			&entry.Entry.ID,
+			&entry.Entry.UserID,
+			&entry.Entry.FilmID,
+			&entry.Entry.Priority,
+			&entry.Entry.Note,
+			&entry.Entry.Source,
+			&entry.Entry.AddedAt,
+			&entry.FilmTitle,
+			&entry.FilmReleaseYear,
+			&entry.FilmPosterURL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// AddToWatchlist adds a film to the user's watchlist, adding a film that's already there updates
+// its priority, note and source but keeps the date it was first added
+func (s *store) AddToWatchlist(ctx context.Context, entry domain.WatchlistEntry) (*domain.WatchlistEntry, error) {
+	// Selecting from films means nothing is inserted when the film doesn't exist
+	query := /* sql */ `
+		INSERT INTO watchlist (watchlist_entry_id, user_id, film_id, priority, note, source, added_at)
+		SELECT $1, $2, f.film_id, $4, $5, $6, $7
+		FROM films f
+		WHERE f.film_id = $3
+		ON CONFLICT (user_id, film_id) DO UPDATE
+		SET priority = EXCLUDED.priority,
+		    note = EXCLUDED.note,
+		    source = EXCLUDED.source
+		RETURNING watchlist_entry_id, user_id, film_id, priority, note, source, added_at
+	`
+
+	var created domain.WatchlistEntry
+	err := s.db.QueryRowContext(ctx, query,
+		entry.ID,
+		entry.UserID,
+		entry.FilmID,
+		entry.Priority,
+		entry.Note,
+		entry.Source,
+		entry.AddedAt,
+	).Scan(
+		&created.ID,
+		&created.UserID,
+		&created.FilmID,
+		&created.Priority,
+		&created.Note,
+		&created.Source,
+		&created.AddedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrFilmNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (s *store) RemoveFromWatchlist(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) error {
+	query := /* sql */ `
+		DELETE FROM watchlist
+		WHERE user_id = $1
+		AND film_id = $2
+	`
+
+	result, err := s.db.ExecContext(ctx, query, userId, filmId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEntryNotFound
+	}
+
+	return nil
+}
+
+// MarkWatched takes the film off the user's watchlist and marks any recommendation of it as seen
+func (s *store) MarkWatched(ctx context.Context, userId uuid.UUID, filmId uuid.UUID) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	deleteQuery := /* sql */ `
+		DELETE FROM watchlist
+		WHERE user_id = $1
+		AND film_id = $2
+	`
+	result, err := tx.ExecContext(ctx, deleteQuery, userId, filmId)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	// Recommendations are tracked by the film's external id
+	seenQuery := /* sql */ `
+		UPDATE film_recommendation
+		SET has_seen = TRUE
+		WHERE user_id = $1
+		AND external_film_id = (SELECT external_id FROM films WHERE film_id = $2)
+	`
+	if _, err := tx.ExecContext(ctx, seenQuery, userId, filmId); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return removed > 0, nil
+}
@@ -0,0 +1,180 @@
+package watchlist
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"cinema.log.server.golang/internal/domain"
+	"cinema.log.server.golang/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	testDB      *sql.DB
+	testStore   WatchlistStore
+	testDbSetup *utils.TestDatabase
+)
+
+// Helper function to create test user
+func createTestUser(ctx context.Context, t *testing.T) uuid.UUID {
+	userID := uuid.New()
+	query := `INSERT INTO users (user_id, name, username, profile_pic_url)
+	          VALUES ($1, $2, $3, $4)`
+	_, err := testDB.ExecContext(ctx, query, userID, "Test User", "testuser"+userID.String()[:8], "http://example.com/pic.jpg")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return userID
+}
+
+// Helper function to create test film, returns the film id and external id
+func createTestFilm(ctx context.Context, t *testing.T) (uuid.UUID, int) {
+	filmID := uuid.New()
+	query := `INSERT INTO films (film_id, external_id, title, description, poster_url, release_year)
+	          VALUES ($1, $2, $3, $4, $5, $6)`
+	externalID := int(time.Now().UnixNano() % 2147483647) // Use nanoseconds for uniqueness
+	_, err := testDB.ExecContext(ctx, query, filmID, externalID, "Test Film "+filmID.String()[:8], "Description", "/poster.jpg", "2024")
+	if err != nil {
+		t.Fatalf("failed to create test film: %v", err)
+	}
+	return filmID, externalID
+}
+
+func newEntry(userId, filmId uuid.UUID, priority int) domain.WatchlistEntry {
+	return domain.WatchlistEntry{
+		ID:       uuid.New(),
+		UserID:   userId,
+		FilmID:   filmId,
+		Priority: priority,
+		Source:   domain.WatchlistSourceSearch,
+		AddedAt:  time.Now(),
+	}
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	testDbSetup, err = utils.StartTestPostgres()
+	if err != nil {
+		log.Fatalf("could not start test database: %v", err)
+	}
+
+	testDB = testDbSetup.DB
+	testStore = NewStore(testDB)
+
+	code := m.Run()
+
+	testDbSetup.Close()
+	os.Exit(code)
+}
+
+func TestWatchlistStore_AddAndGet(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	someday, _ := createTestFilm(ctx, t)
+	tonight, _ := createTestFilm(ctx, t)
+
+	if _, err := testStore.AddToWatchlist(ctx, newEntry(userId, someday, 5)); err != nil {
+		t.Fatalf("failed to add film: %v", err)
+	}
+	if _, err := testStore.AddToWatchlist(ctx, newEntry(userId, tonight, 1)); err != nil {
+		t.Fatalf("failed to add film: %v", err)
+	}
+
+	entries, err := testStore.GetWatchlist(ctx, userId)
+	if err != nil {
+		t.Fatalf("failed to get watchlist: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Entry.FilmID != tonight || entries[0].FilmTitle == "" {
+		t.Errorf("expected the most wanted film first with its details, got %+v", entries[0])
+	}
+}
+
+func TestWatchlistStore_AddToWatchlist_UpdatesExisting(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	filmId, _ := createTestFilm(ctx, t)
+
+	first, err := testStore.AddToWatchlist(ctx, newEntry(userId, filmId, 3))
+	if err != nil {
+		t.Fatalf("failed to add film: %v", err)
+	}
+
+	again := newEntry(userId, filmId, 1)
+	again.Note = "Bumped up"
+	updated, err := testStore.AddToWatchlist(ctx, again)
+	if err != nil {
+		t.Fatalf("failed to add film again: %v", err)
+	}
+	if updated.ID != first.ID || updated.Priority != 1 || updated.Note != "Bumped up" {
+		t.Errorf("expected the existing entry to be updated, got %+v", updated)
+	}
+}
+
+func TestWatchlistStore_AddToWatchlist_FilmNotFound(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+
+	_, err := testStore.AddToWatchlist(ctx, newEntry(userId, uuid.New(), 3))
+	if err != ErrFilmNotFound {
+		t.Errorf("expected ErrFilmNotFound, got %v", err)
+	}
+}
+
+func TestWatchlistStore_RemoveFromWatchlist(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	filmId, _ := createTestFilm(ctx, t)
+
+	if _, err := testStore.AddToWatchlist(ctx, newEntry(userId, filmId, 3)); err != nil {
+		t.Fatalf("failed to add film: %v", err)
+	}
+	if err := testStore.RemoveFromWatchlist(ctx, userId, filmId); err != nil {
+		t.Fatalf("failed to remove film: %v", err)
+	}
+	if err := testStore.RemoveFromWatchlist(ctx, userId, filmId); err != ErrEntryNotFound {
+		t.Errorf("expected ErrEntryNotFound, got %v", err)
+	}
+}
+
+func TestWatchlistStore_MarkWatched(t *testing.T) {
+	ctx := context.Background()
+	userId := createTestUser(ctx, t)
+	filmId, externalId := createTestFilm(ctx, t)
+
+	if _, err := testStore.AddToWatchlist(ctx, newEntry(userId, filmId, 3)); err != nil {
+		t.Fatalf("failed to add film: %v", err)
+	}
+	_, err := testDB.ExecContext(ctx, `INSERT INTO film_recommendation (film_recommendation_id, user_id, external_film_id, has_seen, has_been_recommended, recommendations_generated)
+		VALUES ($1, $2, $3, FALSE, TRUE, FALSE)`, uuid.New(), userId, externalId)
+	if err != nil {
+		t.Fatalf("failed to create recommendation: %v", err)
+	}
+
+	removed, err := testStore.MarkWatched(ctx, userId, filmId)
+	if err != nil {
+		t.Fatalf("failed to mark film watched: %v", err)
+	}
+	if !removed {
+		t.Error("expected the film to come off the watchlist")
+	}
+
+	var hasSeen bool
+	if err := testDB.QueryRowContext(ctx, `SELECT has_seen FROM film_recommendation WHERE user_id = $1`, userId).Scan(&hasSeen); err != nil {
+		t.Fatalf("failed to read recommendation: %v", err)
+	}
+	if !hasSeen {
+		t.Error("expected the recommendation to be marked seen")
+	}
+
+	removed, err = testStore.MarkWatched(ctx, userId, filmId)
+	if err != nil || removed {
+		t.Errorf("expected nothing left to remove, got %v, %v", removed, err)
+	}
+}